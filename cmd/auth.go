@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/keychain"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Store provider API keys in the system keychain",
+	Long: `Auth keeps provider API keys in the operating system's credential store (the macOS
+Keychain, the Secret Service on Linux or the Windows Credential Manager) instead of
+config files or .env. A stored key is used when the provider's key in the
+configuration is empty, e.g. because its environment variable is not set.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login <provider>",
+	Short: "Store the API key of a provider",
+	Long: `Login asks for the API key of a provider, such as openai or anthropic, and stores it
+in the keychain. The key is read from stdin when it is not a terminal:
+
+  echo "$KEY" | magikarp auth login openai`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProviders,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := strings.ToLower(args[0])
+		if err := checkProvider(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfigError)
+		}
+		key, err := readKey(provider)
+		if err == nil {
+			err = keychain.Set(provider, key)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Fprintf(os.Stderr, "Stored the %s key in the keychain\n", provider)
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:               "logout <provider>",
+	Short:             "Remove the stored API key of a provider",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProviders,
+	Run: func(cmd *cobra.Command, args []string) {
+		provider := strings.ToLower(args[0])
+		if err := keychain.Delete(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", provider, err)
+			os.Exit(exitError)
+		}
+		fmt.Fprintf(os.Stderr, "Removed the %s key from the keychain\n", provider)
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where each provider's API key comes from",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := cfg.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+			os.Exit(exitConfigError)
+		}
+		names := make([]string, 0, len(conf.Providers))
+		for name := range conf.Providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			source := "not set"
+			if conf.Providers[name].Key != "" {
+				source = "config or environment"
+			} else if _, err := keychain.Get(name); err == nil {
+				source = "keychain"
+			} else if !errors.Is(err, keychain.ErrNotFound) {
+				source = "not set; " + err.Error()
+			}
+			fmt.Printf("%-12s %s\n", name, source)
+		}
+	},
+}
+
+// checkProvider reports whether provider is configured, so that keys are not stored
+// under misspelt names
+func checkProvider(provider string) error {
+	conf, err := cfg.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, ok := conf.Providers[provider]; !ok {
+		return fmt.Errorf("provider %s is not configured", provider)
+	}
+	return nil
+}
+
+// readKey reads the key from the terminal without echoing it, or from stdin
+func readKey(provider string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading the key: %w", err)
+		}
+		return line, nil
+	}
+	fmt.Fprintf(os.Stderr, "%s API key: ", provider)
+	key, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading the key: %w", err)
+	}
+	return string(key), nil
+}
+
+func init() {
+	authCmd.AddCommand(authLoginCmd, authLogoutCmd, authStatusCmd)
+	rootCmd.AddCommand(authCmd)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate the shell completion script",
+	Long: `Completion writes the completion script of a shell to stdout. Besides subcommands
+and flags it completes the models and providers in your configuration, config keys,
+--output formats and, for review, git branches and tags.
+
+Bash (needs the bash-completion package):
+  source <(magikarp completion bash)
+  magikarp completion bash > /etc/bash_completion.d/magikarp   # every session
+
+Zsh:
+  magikarp completion zsh > "${fpath[1]}/_magikarp"
+  # if completion is not set up yet, add "autoload -U compinit; compinit" to ~/.zshrc
+
+Fish:
+  magikarp completion fish > ~/.config/fish/completions/magikarp.fish
+
+PowerShell:
+  magikarp completion powershell | Out-String | Invoke-Expression
+  # add that line to $PROFILE for every session`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// completeFlags registers the completion of the values of cmd's flags
+func completeFlags(cmd *cobra.Command, funcs map[string]cobra.CompletionFunc) {
+	for flag, fn := range funcs {
+		if err := cmd.RegisterFlagCompletionFunc(flag, fn); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// completionConfig loads the configuration for completing values, or returns nil.
+// Completion must stay quiet, so errors are not reported.
+func completionConfig() *cfg.Config {
+	conf, err := cfg.Load()
+	if err != nil {
+		return nil
+	}
+	return conf
+}
+
+// completeModels completes the models of the configured providers, described by
+// their provider, and "auto" when routing is configured
+func completeModels(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	conf := completionConfig()
+	if conf == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var models []cobra.Completion
+	seen := map[string]bool{}
+	for name, p := range conf.Providers {
+		for _, model := range p.Models {
+			if !seen[model] {
+				seen[model] = true
+				models = append(models, cobra.CompletionWithDesc(model, name))
+			}
+		}
+	}
+	sort.Strings(models)
+	if len(conf.Routing.Models) > 0 {
+		models = append(models, cobra.CompletionWithDesc(cfg.AutoModel, "picks a model for each prompt"))
+	}
+	return models, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProviders completes the names of the configured providers
+func completeProviders(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	conf := completionConfig()
+	if conf == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []cobra.Completion
+	for name := range conf.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKeys completes the first argument with the dotted keys of the settings
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	conf := completionConfig()
+	if conf == nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.Keys(conf), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSetting completes --set key=value with the keys of the settings, up to the =
+func completeSetting(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	conf := completionConfig()
+	if conf == nil || strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	keys := cfg.Keys(conf)
+	for i, key := range keys {
+		keys[i] = key + "="
+	}
+	return keys, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeRefs completes the branches, remote branches and tags of the repository
+func completeRefs(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	out, err := gitexec.Run(context.Background(), "", "for-each-ref", "--format=%(refname:short)", "refs/heads", "refs/remotes", "refs/tags")
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return strings.Fields(out), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFiles completes the files with one of exts, and directories
+func completeFiles(exts ...string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		return exts, cobra.ShellCompDirectiveFilterFileExt
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// configPathAll lists every config file instead of the one written to (config path --all)
+var configPathAll bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change the configuration",
+	Long: `Config reads and changes settings without starting a session. Keys are dotted
+paths such as default_model, tools.enabled or providers.openai.temperature.
+
+Values are read from all config layers merged, as a session sees them; set writes
+to the config file that takes precedence (see "config path"), keeping its comments.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:               "get <key>",
+	Short:             "Print a setting",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(printSetting(args[0]))
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Print the merged configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(printSetting(""))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:               "set <key> <value>",
+	Short:             "Change a setting in the config file",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeConfigKeys,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cfg.Set(args[0], args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfigError)
+		}
+		fmt.Fprintf(os.Stderr, "Saved %s = %s to %s\n", args[0], args[1], path)
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file that set writes to",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !configPathAll {
+			path, err := cfg.SettingsFile()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(exitConfigError)
+			}
+			fmt.Println(path)
+			return
+		}
+		files, err := cfg.LayerFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitConfigError)
+		}
+		for _, path := range files {
+			fmt.Println(path)
+		}
+	},
+}
+
+// printSetting prints the setting at key, or the whole configuration when key is
+// empty, and returns the process exit code
+func printSetting(key string) int {
+	value, err := cfg.Lookup(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitConfigError
+	}
+	fmt.Println(value)
+	return exitOK
+}
+
+func init() {
+	configPathCmd.Flags().BoolVar(&configPathAll, "all", false, "list every config file loaded, from lowest to highest precedence")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd, configPathCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/keychain"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/providers/openai"
+	"github.com/pprunty/magikarp/internal/speech"
+	"github.com/spf13/cobra"
+)
+
+// doctorOffline skips the checks that send requests (doctor --offline)
+var doctorOffline bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration, API keys and providers",
+	Long: `Doctor validates the configuration, checks that each provider has an API key and
+sends it a tiny request to measure its latency, and checks that speech mode can
+record and transcribe. Each problem is listed with how to fix it. The exit code is 1
+when a check failed and 2 when the configuration cannot be loaded.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(runDoctor())
+	},
+}
+
+// doctorReport prints the outcome of each check
+type doctorReport struct {
+	failed bool
+}
+
+func (r *doctorReport) section(title string) {
+	fmt.Printf("\n%s\n", title)
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Printf("  ✓ %-12s %s\n", name, detail)
+}
+
+// warn reports a problem that only affects optional features
+func (r *doctorReport) warn(name, detail, fix string) {
+	fmt.Printf("  ! %-12s %s\n", name, detail)
+	r.fix(fix)
+}
+
+func (r *doctorReport) fail(name, detail, fix string) {
+	r.failed = true
+	fmt.Printf("  ✗ %-12s %s\n", name, detail)
+	r.fix(fix)
+}
+
+func (r *doctorReport) fix(fix string) {
+	if fix != "" {
+		fmt.Printf("    %-12s → %s\n", "", fix)
+	}
+}
+
+// runDoctor runs every check and returns the process exit code
+func runDoctor() int {
+	report := &doctorReport{}
+	conf, ok := checkConfig(report)
+	if !ok {
+		return exitConfigError
+	}
+	checkKeys(report, conf)
+	if !doctorOffline {
+		checkProviders(report, conf)
+	}
+	checkSpeech(report, conf)
+
+	fmt.Println()
+	if report.failed {
+		return exitError
+	}
+	return exitOK
+}
+
+// checkConfig loads and validates the configuration
+func checkConfig(report *doctorReport) (*cfg.Config, bool) {
+	report.section("Configuration")
+	files, err := cfg.LayerFiles()
+	if err != nil {
+		report.fail("files", err.Error(), "")
+		return nil, false
+	}
+	if len(files) == 0 {
+		report.warn("files", "no config file found; using the built-in defaults", "run magikarp once to create a starter config at ~/.magikarp/config.yaml")
+	} else {
+		report.ok("files", strings.Join(files, ", "))
+	}
+
+	conf, err := cfg.Load()
+	if err != nil {
+		report.fail("syntax", err.Error(), "fix the YAML of the file named in the error")
+		return nil, false
+	}
+	if err := conf.ValidateConfig(); err != nil {
+		report.fail("settings", err.Error(), "correct the setting, e.g. with magikarp config set <key> <value>")
+		return nil, false
+	}
+	if _, err := permissions.New(conf.Tools, ""); err != nil {
+		report.fail("permissions", err.Error(), "correct tools.permissions")
+		return nil, false
+	}
+	report.ok("settings", "valid")
+	return conf, true
+}
+
+// checkKeys reports where the API key of each provider comes from
+func checkKeys(report *doctorReport, conf *cfg.Config) {
+	report.section("API keys")
+	for _, name := range providerNames(conf) {
+		pCfg := conf.Providers[name]
+		switch {
+		case name == "ollama":
+			report.ok(name, "no key needed")
+		case name == "azure" && pCfg.Auth == "azure_ad":
+			report.ok(name, "Azure AD sign-in")
+		case pCfg.Key != "" && !strings.HasPrefix(pCfg.Key, "${"):
+			report.ok(name, "from config or environment")
+		default:
+			if _, err := keychain.Get(name); err == nil {
+				report.ok(name, "from the keychain")
+			} else if !errors.Is(err, keychain.ErrNotFound) {
+				report.warn(name, "not set, and the keychain is unavailable", keyFix(name))
+			} else {
+				report.warn(name, "not set; its models are unavailable", keyFix(name))
+			}
+		}
+	}
+}
+
+// keyFix tells how to set the API key of provider
+func keyFix(provider string) string {
+	env := strings.ToUpper(provider) + "_API_KEY"
+	if provider == "azure" {
+		env = "AZURE_OPENAI_API_KEY"
+	}
+	return fmt.Sprintf("export %s=..., or run magikarp auth login %s", env, provider)
+}
+
+// checkProviders sends a tiny request to a model of each provider and reports how
+// long it took to answer
+func checkProviders(report *doctorReport, conf *cfg.Config) {
+	report.section("Providers")
+	if _, err := orchestration.Reload(conf); err != nil {
+		report.fail("providers", "none could be set up", "set the API key of at least one provider")
+		return
+	}
+
+	byProvider := orchestration.ModelsByProvider(conf)
+	names := make([]string, 0, len(byProvider))
+	for name := range byProvider {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	probes := make([]orchestration.Probe, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		model := byProvider[name][0]
+		for _, m := range byProvider[name] {
+			if m == conf.DefaultModel {
+				model = m
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probes[i] = orchestration.ProbeModel(context.Background(), model)
+		}()
+	}
+	wg.Wait()
+
+	for i, probe := range probes {
+		switch {
+		case probe.Err != nil && names[i] == "ollama":
+			// Local models are optional; the sample config lists them for those who run Ollama
+			report.warn(names[i], probe.Model+": "+firstLine(probe.Err.Error()), probe.Hint())
+			continue
+		case probe.Err != nil:
+			report.fail(names[i], probe.Model+": "+firstLine(probe.Err.Error()), probe.Hint())
+			continue
+		}
+		report.ok(names[i], fmt.Sprintf("%s answered in %s", probe.Model, probe.Latency.Round(time.Millisecond)))
+	}
+
+	if conf.DefaultModel != "" && conf.DefaultModel != orchestration.AutoModel {
+		if _, err := orchestration.ProviderFor(conf.DefaultModel); err != nil {
+			model, _ := orchestration.DefaultModel(conf)
+			report.warn("default", fmt.Sprintf("default_model %s is unavailable; sessions start with %s", conf.DefaultModel, model),
+				"set the key of its provider, or change default_model")
+		}
+	}
+}
+
+// checkSpeech checks that speech mode can record and, unless offline, transcribe
+func checkSpeech(report *doctorReport, conf *cfg.Config) {
+	report.section("Speech mode")
+	if args, err := speech.RecorderCommand(conf.Speech.Recorder); err != nil {
+		report.warn("recorder", err.Error(), "")
+	} else {
+		report.ok("recorder", args[0])
+	}
+	if doctorOffline {
+		return
+	}
+
+	backend := conf.Speech.Backend
+	if backend == "" {
+		backend = speech.RecognizerOpenAI
+	}
+	model := conf.Speech.Model
+	switch {
+	case model != "":
+	case backend == speech.RecognizerOpenAI:
+		model = openai.DefaultTranscriptionModel
+	default:
+		model = "the default model"
+	}
+	recognizer, err := speech.NewRecognizer(conf.Speech)
+	if err != nil {
+		report.warn(backend, err.Error(), "set the openai API key, or set speech.backend to deepgram")
+		return
+	}
+	// Half a second of silence checks the key and the model without transcribing anything
+	silence := make([]byte, speech.SampleRate)
+	start := time.Now()
+	if _, err := recognizer.Transcribe(context.Background(), silence); err != nil {
+		report.warn(backend, model+": "+firstLine(err.Error()), "check speech.model and the key of the backend")
+		return
+	}
+	report.ok(backend, fmt.Sprintf("%s transcribed in %s", model, time.Since(start).Round(time.Millisecond)))
+}
+
+// providerNames returns the configured providers in alphabetical order
+func providerNames(conf *cfg.Config) []string {
+	names := make([]string, 0, len(conf.Providers))
+	for name := range conf.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// firstLine returns the first line of an error message, shortened for the report
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if len(s) > 160 {
+		s = s[:157] + "..."
+	}
+	return s
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "only check the configuration and local tools, without sending requests")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/spf13/cobra"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Build or update the semantic index of the working directory",
+	Long: `Index embeds the files of the working directory with the provider set in the
+index section of config.yaml, so the semantic_search tool and automatic retrieval
+start from an up-to-date index. Only new and changed files are embedded again.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(runIndex())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
+
+// runIndex updates the index and returns the process exit code
+func runIndex() int {
+	conf, err := cfg.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return exitConfigError
+	}
+	if err := conf.ValidateConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	if err := index.Configure(conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	ix := index.Default()
+	if ix == nil {
+		fmt.Fprintln(os.Stderr, "Error: the index is not configured; set index.provider in config.yaml")
+		return exitConfigError
+	}
+	if err := orchestration.Init(conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: initialising providers: %v\n", err)
+		return exitConfigError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	stats, err := ix.Update(ctx)
+	fmt.Fprintf(os.Stderr, "Indexed %s: %d files, %d chunks (%d embedded, %d removed) in %s\n",
+		ix.Root(), stats.Files, stats.Chunks, stats.Embedded, stats.Removed, time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
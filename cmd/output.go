@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormats are the values accepted for --output
+var outputFormats = []string{"text", "json", "yaml"}
+
+// usageSummary is the token usage and estimated cost of a run in --output payloads
+type usageSummary struct {
+	InputTokens      int     `json:"input_tokens" yaml:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens" yaml:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens" yaml:"cache_read_tokens"`
+	CacheWriteTokens int     `json:"cache_write_tokens" yaml:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd" yaml:"cost_usd"`
+}
+
+// failure is the payload written instead of a result when a run with a structured
+// --output fails
+type failure struct {
+	Error    string `json:"error" yaml:"error"`
+	ExitCode int    `json:"exit_code" yaml:"exit_code"`
+}
+
+// checkOutput validates an --output flag
+func checkOutput(format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return fmt.Errorf("--output must be one of %s", strings.Join(outputFormats, ", "))
+	}
+	return nil
+}
+
+// structured reports whether format is written as a payload rather than as text
+func structured(format string) bool {
+	return format == "json" || format == "yaml"
+}
+
+// sessionUsage summarises the usage of every request made so far
+func sessionUsage() usageSummary {
+	usage, cost := orchestration.Session().Totals()
+	return usageSummary{
+		InputTokens:      usage.InputTokens(),
+		OutputTokens:     usage.CompletionTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+		CostUSD:          cost,
+	}
+}
+
+// writeOutput writes v to w as JSON or YAML
+func writeOutput(w io.Writer, format string, v interface{}) error {
+	if format == "yaml" {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// fail reports err on stderr and, with a structured format, as the payload on stdout
+// so that CI jobs always get one. It returns code, the exit code.
+func fail(format string, code int, err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if structured(format) {
+		writeOutput(os.Stdout, format, failure{Error: err.Error(), ExitCode: code})
+	}
+	return code
+}
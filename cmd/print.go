@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/session"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/search"
+	"github.com/pprunty/magikarp/internal/tools/wasm"
+)
+
+// Exit codes of print mode and the subcommands
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitConfigError = 2
+	// exitFindings means review found problems at or above --fail-on
+	exitFindings = 3
+)
+
+// printResult is the payload of print mode with --output json or yaml
+type printResult struct {
+	Model     string          `json:"model" yaml:"model"`
+	Content   string          `json:"content" yaml:"content"`
+	ToolCalls []printToolCall `json:"tool_calls" yaml:"tool_calls"`
+	HitLimit  bool            `json:"hit_limit" yaml:"hit_limit"`
+	Usage     usageSummary    `json:"usage" yaml:"usage"`
+	ExitCode  int             `json:"exit_code" yaml:"exit_code"`
+}
+
+// printToolCall is a tool call in a printResult; its output is left out, as it may be
+// large
+type printToolCall struct {
+	Name    string `json:"name" yaml:"name"`
+	IsError bool   `json:"is_error" yaml:"is_error"`
+	Denied  bool   `json:"denied" yaml:"denied"`
+}
+
+// runPrint sends a single prompt non-interactively, prints the final answer to stdout
+// and returns the process exit code. Diagnostics are written to stderr.
+func runPrint(prompt string) int {
+	if err := checkOutput(printOutput); err != nil {
+		return fail("text", exitError, err)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return fail(printOutput, exitError, errors.New("--prompt must not be empty"))
+	}
+	var piped *pipedInput
+	if !printNoStdin {
+		var err error
+		if piped, err = readStdin(); err != nil {
+			return fail(printOutput, exitError, err)
+		}
+	}
+	var schema *orchestration.Schema
+	if printSchema != "" {
+		var err error
+		if schema, err = loadSchema(printSchema); err != nil {
+			return fail(printOutput, exitError, fmt.Errorf("--json-schema: %w", err))
+		}
+	}
+
+	conf, err := cfg.Load()
+	if err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("failed to load config: %w", err))
+	}
+	if err := conf.ValidateConfig(); err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	policy, err := permissions.New(conf.Tools, "")
+	if err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	if err := wasm.Register(conf.Tools); err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	if err := index.Configure(conf); err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	search.Register()
+	tools.Configure(conf.Tools)
+	hooks.Configure(conf.Hooks)
+	github.Configure(conf.GitHub)
+	if err := telemetry.Start(conf.Telemetry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing is off: %v\n", err)
+	}
+	if err := orchestration.Init(conf); err != nil {
+		return fail(printOutput, exitConfigError, fmt.Errorf("initialising providers: %w", err))
+	}
+
+	model := printModel
+	if model == "" {
+		if model, err = orchestration.DefaultModel(conf); err != nil {
+			return fail(printOutput, exitConfigError, err)
+		}
+	}
+
+	toolDefs := tools.GetCoreTools()
+	if conf.Tools.Enabled {
+		toolDefs = tools.GetAllTools()
+	}
+
+	message := prompt
+	if ix := index.Default(); ix != nil && conf.Index.AutoContext > 0 {
+		var found []index.Result
+		message, found, err = ix.Augment(context.Background(), prompt, conf.Index.AutoContext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: project index: %v\n", err)
+		} else if printVerbose {
+			fmt.Fprintf(os.Stderr, "Added %d snippets from the project index\n", len(found))
+		}
+	}
+	if piped != nil {
+		message = withPipedInput(message, piped)
+		if piped.dropped > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: stdin is larger than %d KB; only its end is sent\n", maxStdinBytes/1024)
+		} else if printVerbose {
+			fmt.Fprintf(os.Stderr, "Attached %d bytes from stdin\n", len(piped.text))
+		}
+	}
+
+	result, err := orchestration.RunTurn(context.Background(), orchestration.Turn{
+		Model:   model,
+		System:  conf.System,
+		Message: message,
+		Tools:   toolDefs,
+		// Keep the tool loop bounded; conf.Tools.MaxIterations of 0 uses the default
+		MaxIterations: conf.Tools.MaxIterations,
+		Policy:        policy,
+		DryRun:        printDryRun,
+		Approve: func(name string, _ map[string]interface{}) bool {
+			// There is no one to ask in print mode: only allowed tools run unless --yes is set
+			if printYes {
+				return true
+			}
+			fmt.Fprintf(os.Stderr, "Denied tool %s (not allowed by tools.auto_approve or tools.permissions; pass --yes to allow)\n", name)
+			return false
+		},
+		OnRetry: func(status orchestration.RetryStatus) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", status, status.Err)
+		},
+		Fallbacks:        conf.FallbackModels,
+		Thinking:         orchestration.ThinkingFromConfig(conf),
+		SubAgentThinking: conf.Thinking.Background,
+		Schema:           schema,
+		OnFailover: func(f orchestration.Failover) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", f, f.Err)
+		},
+		OnRoute: func(r orchestration.Route) {
+			if printVerbose {
+				fmt.Fprintf(os.Stderr, "Routed to %s\n", r)
+			}
+		},
+	})
+	if err != nil {
+		return fail(printOutput, exitError, err)
+	}
+
+	for _, call := range result.ToolCalls {
+		if call.Result.IsError && !call.Denied {
+			fmt.Fprintf(os.Stderr, "Tool %s failed: %s\n", call.Name, call.Result.Content)
+		}
+	}
+
+	if result.Route != nil {
+		model = result.Route.Model
+	}
+	if result.Model != model {
+		fmt.Fprintf(os.Stderr, "Answered by %s\n", result.Model)
+	}
+
+	if printVerbose {
+		printUsage(orchestration.Session().Totals())
+	}
+	if err := hooks.Run(context.Background(), hooks.Event{Event: hooks.SessionEnd, Model: result.Model, Messages: 1}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if exportFile != "" {
+		if err := exportPrintTurn(model, prompt, result); err != nil {
+			return fail(printOutput, exitError, err)
+		}
+	}
+
+	if !structured(printOutput) {
+		fmt.Fprintln(os.Stdout, result.Text())
+		return exitOK
+	}
+	payload := printResult{
+		Model:     result.Model,
+		Content:   result.Text(),
+		ToolCalls: []printToolCall{},
+		HitLimit:  result.HitLimit,
+		Usage:     sessionUsage(),
+		ExitCode:  exitOK,
+	}
+	for _, call := range result.ToolCalls {
+		payload.ToolCalls = append(payload.ToolCalls, printToolCall{Name: call.Name, IsError: call.Result.IsError, Denied: call.Denied})
+	}
+	if err := writeOutput(os.Stdout, printOutput, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// loadSchema reads the --json-schema argument: inline JSON, or the path of a file
+// holding the schema, which names it
+func loadSchema(arg string) (*orchestration.Schema, error) {
+	if strings.HasPrefix(strings.TrimSpace(arg), "{") {
+		return orchestration.CompileSchema("", []byte(arg))
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+	return orchestration.CompileSchema(strings.TrimSuffix(name, ".schema"), data)
+}
+
+// exportPrintTurn writes the prompt, tool calls and answer to the --export file
+func exportPrintTurn(model, prompt string, result *orchestration.TurnResult) error {
+	sess := session.New(model)
+	ex := session.Exchange{User: prompt, Assistant: result.Text(), Model: result.Model, Time: sess.CreatedAt}
+	for _, call := range result.ToolCalls {
+		ex.ToolCalls = append(ex.ToolCalls, session.ToolCall{
+			Name:    call.Name,
+			Input:   call.Input,
+			Output:  call.Result.Content,
+			IsError: call.Result.IsError,
+			Denied:  call.Denied,
+		})
+	}
+	sess.Exchanges = []session.Exchange{ex}
+	if err := sess.Export(exportFile); err != nil {
+		return fmt.Errorf("failed to export conversation: %w", err)
+	}
+	return nil
+}
+
+// printUsage writes the session token usage to stderr when --verbose is set
+func printUsage(usage providers.Usage, cost float64) {
+	fmt.Fprintf(os.Stderr, "Tokens: %d in / %d out, estimated cost $%.4f\n", usage.InputTokens(), usage.CompletionTokens, cost)
+	if usage.CacheReadTokens+usage.CacheWriteTokens > 0 {
+		fmt.Fprintf(os.Stderr, "Prompt cache: %d read / %d written\n", usage.CacheReadTokens, usage.CacheWriteTokens)
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/codereview"
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+	"github.com/spf13/cobra"
+)
+
+// Review flags
+var (
+	reviewModel  string
+	reviewJSON   bool
+	reviewOutput string
+	reviewFailOn string
+)
+
+// reviewResult is the payload of review with --output json or yaml
+type reviewResult struct {
+	Model             string `json:"model" yaml:"model"`
+	codereview.Report `yaml:",inline"`
+	Usage             usageSummary `json:"usage" yaml:"usage"`
+	// Blocking is the number of findings at or above --fail-on
+	Blocking int `json:"blocking" yaml:"blocking"`
+	ExitCode int `json:"exit_code" yaml:"exit_code"`
+}
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [ref]",
+	Short: "Review a git diff and report the problems found",
+	Long: `Review sends a git diff to the model in chunks and reports its findings, each
+with a file, line, severity and suggestion. Without a ref the uncommitted changes
+are reviewed (git diff HEAD). A ref such as main reviews what the current branch
+adds to it (git diff main...HEAD), as in a pull request; a range such as
+HEAD~3..HEAD is passed to git diff as it is.
+
+For CI, --output json or yaml writes the report with token usage and cost, and the
+exit code is 3 when there are findings at or above --fail-on (error by default), 1
+when the review failed and 2 when the configuration cannot be loaded.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeRefs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ref := ""
+		if len(args) > 0 {
+			ref = args[0]
+		}
+		code := runReview(ref)
+		telemetry.Shutdown()
+		os.Exit(code)
+	},
+}
+
+func init() {
+	reviewCmd.Flags().StringVarP(&reviewModel, "model", "m", "", "model to use (defaults to default_model from config.yaml)")
+	reviewCmd.Flags().StringVarP(&reviewOutput, "output", "o", "text", "write the report as text, json or yaml")
+	reviewCmd.Flags().BoolVar(&reviewJSON, "json", false, "same as --output json")
+	reviewCmd.Flags().StringVar(&reviewFailOn, "fail-on", "error", "exit with 3 when there are findings of this severity or worse: error, warning, info or none")
+	completeFlags(reviewCmd, map[string]cobra.CompletionFunc{
+		"model":   completeModels,
+		"output":  cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp),
+		"fail-on": cobra.FixedCompletions(append(slices.Clone(codereview.Severities), "none"), cobra.ShellCompDirectiveNoFileComp),
+	})
+	rootCmd.AddCommand(reviewCmd)
+}
+
+// runReview reviews the diff selected by ref and returns the process exit code
+func runReview(ref string) int {
+	if reviewJSON {
+		reviewOutput = "json"
+	}
+	if err := checkOutput(reviewOutput); err != nil {
+		return fail("text", exitError, err)
+	}
+	format := reviewOutput
+	// Findings of the severities up to threshold block; none blocks nothing
+	threshold := -1
+	if reviewFailOn != "none" {
+		if threshold = slices.Index(codereview.Severities, reviewFailOn); threshold < 0 {
+			return fail(format, exitError, fmt.Errorf("--fail-on must be one of %s or none", strings.Join(codereview.Severities, ", ")))
+		}
+	}
+
+	conf, err := cfg.Load()
+	if err != nil {
+		return fail(format, exitConfigError, fmt.Errorf("failed to load config: %w", err))
+	}
+	if err := conf.ValidateConfig(); err != nil {
+		return fail(format, exitConfigError, fmt.Errorf("configuration error: %w", err))
+	}
+	if err := telemetry.Start(conf.Telemetry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing is off: %v\n", err)
+	}
+	if err := orchestration.Init(conf); err != nil {
+		return fail(format, exitConfigError, fmt.Errorf("initialising providers: %w", err))
+	}
+	model := reviewModel
+	if model == "" {
+		if model, err = orchestration.DefaultModel(conf); err != nil {
+			return fail(format, exitConfigError, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	diff, err := gitexec.Run(ctx, "", reviewDiffArgs(ref)...)
+	if err != nil {
+		return fail(format, exitError, err)
+	}
+
+	opts := codereview.Options{Model: model, Thinking: orchestration.ThinkingFromConfig(conf)}
+	opts.OnChunk = func(done, total int) {
+		if total > 1 {
+			fmt.Fprintf(os.Stderr, "Reviewed %d of %d parts of the diff\n", done, total)
+		}
+	}
+	report, err := codereview.Review(ctx, diff, opts)
+	if err != nil {
+		return fail(format, exitError, err)
+	}
+
+	blocking := 0
+	for _, f := range report.Findings {
+		if i := slices.Index(codereview.Severities, f.Severity); i >= 0 && i <= threshold {
+			blocking++
+		}
+	}
+	code := exitOK
+	if blocking > 0 {
+		code = exitFindings
+	}
+
+	if !structured(format) {
+		printReview(os.Stdout, report)
+		return code
+	}
+	payload := reviewResult{Model: model, Report: *report, Usage: sessionUsage(), Blocking: blocking, ExitCode: code}
+	if err := writeOutput(os.Stdout, format, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return code
+}
+
+// reviewDiffArgs returns the git diff invocation that selects the changes of ref
+func reviewDiffArgs(ref string) []string {
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	switch {
+	case ref == "":
+		return append(args, "HEAD")
+	case strings.Contains(ref, ".."):
+		return append(args, ref)
+	}
+	return append(args, ref+"...HEAD")
+}
+
+// printReview writes the findings of report grouped by file, followed by their counts
+func printReview(w io.Writer, report *codereview.Report) {
+	if report.Files == 0 {
+		fmt.Fprintln(w, "No changes to review")
+		return
+	}
+	file := ""
+	for _, f := range report.Findings {
+		if f.File != file {
+			if file != "" {
+				fmt.Fprintln(w)
+			}
+			file = f.File
+			fmt.Fprintln(w, file)
+		}
+		location := "     -"
+		if f.Line > 0 {
+			location = fmt.Sprintf("%6d", f.Line)
+		}
+		fmt.Fprintf(w, "%s  %-7s  %s\n", location, f.Severity, f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(w, "%s  %-7s  → %s\n", strings.Repeat(" ", 6), "", f.Suggestion)
+		}
+	}
+	if file != "" {
+		fmt.Fprintln(w)
+	}
+
+	counts := report.Counts()
+	var parts []string
+	for _, severity := range codereview.Severities {
+		if n := counts[severity]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, severity))
+		}
+	}
+	summary := "no findings"
+	if len(parts) > 0 {
+		summary = fmt.Sprintf("%d findings (%s)", len(report.Findings), strings.Join(parts, ", "))
+	}
+	fmt.Fprintf(w, "Reviewed %d files: %s\n", report.Files, summary)
+}
@@ -4,17 +4,52 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/telemetry"
 	"github.com/pprunty/magikarp/internal/terminal"
+	"github.com/pprunty/magikarp/internal/tools/exec/process"
 	"github.com/spf13/cobra"
 )
 
+// Print mode flags
+var (
+	printPrompt  string
+	printModel   string
+	printYes     bool
+	printVerbose bool
+	printDryRun  bool
+	printSchema  string
+	printOutput  string
+	printNoStdin bool
+)
+
+// exportFile receives the conversation when the session ends (--export)
+var exportFile string
+
+// Configuration flags, which override the config files
+var (
+	configFile     string
+	configSettings []string
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "magikarp",
 	Short: "Magikarp - AI Coding Assistant CLI",
 	Long: `Magikarp is an open-source coding assistant CLI tool built with Go. 
 It provides an interactive terminal interface for AI-powered coding assistance 
 with support for multiple LLM providers including Claude, GPT, and Gemini.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		config.SetCommandLine(configFile, configSettings)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		// Non-interactive print mode: answer a single prompt and exit
+		if cmd.Flags().Changed("prompt") {
+			code := runPrint(printPrompt)
+			process.StopAll()
+			telemetry.Shutdown()
+			os.Exit(code)
+		}
+
 		// Check terminal capabilities before starting UI
 		if err := terminal.CheckTerminalCapabilities(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -22,7 +57,12 @@ with support for multiple LLM providers including Claude, GPT, and Gemini.`,
 		}
 
 		// Start the interactive UI
-		if err := terminal.StartUI(); err != nil {
+		terminal.SetExportPath(exportFile)
+		err := terminal.StartUI()
+		// Background processes started by the agent must not outlive the session
+		process.StopAll()
+		telemetry.Shutdown()
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error starting UI: %v\n", err)
 			os.Exit(1)
 		}
@@ -37,6 +77,24 @@ func Execute() {
 }
 
 func init() {
-	// Global flags can be added here
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.magikarp.yaml)")
+	rootCmd.Flags().StringVarP(&printPrompt, "prompt", "p", "", "run a single prompt non-interactively and print the answer")
+	rootCmd.Flags().StringVarP(&printModel, "model", "m", "", "model to use (defaults to default_model from config.yaml)")
+	rootCmd.Flags().BoolVarP(&printYes, "yes", "y", false, "in print mode, allow every tool call without approval")
+	rootCmd.Flags().BoolVar(&printVerbose, "verbose", false, "in print mode, report token usage on stderr")
+	rootCmd.Flags().BoolVar(&printDryRun, "dry-run", false, "in print mode, report the file changes and commands the model asks for without making them")
+	rootCmd.Flags().StringVar(&printSchema, "json-schema", "", "in print mode, answer with JSON conforming to this schema (a file or inline JSON)")
+	rootCmd.Flags().StringVarP(&printOutput, "output", "o", "text", "in print mode, write the answer as text, or as json or yaml with the tool calls, token usage and cost")
+	rootCmd.Flags().BoolVar(&printNoStdin, "no-stdin", false, "in print mode, do not read input piped to stdin")
+	rootCmd.Flags().StringVar(&exportFile, "export", "", "write the conversation to this file on exit (.md, .json or .html)")
+
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file taking precedence over ~/.magikarp/config.yaml, ./config.yaml and .magikarp.yaml")
+	rootCmd.PersistentFlags().StringArrayVar(&configSettings, "set", nil, "override a config value, e.g. --set tools.enabled=false (repeatable)")
+	completeFlags(rootCmd, map[string]cobra.CompletionFunc{
+		"model":       completeModels,
+		"output":      cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp),
+		"json-schema": completeFiles("json"),
+		"export":      completeFiles("md", "json", "html"),
+		"config":      completeFiles("yaml", "yml"),
+		"set":         completeSetting,
+	})
 }
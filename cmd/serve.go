@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/server"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/exec/process"
+	"github.com/pprunty/magikarp/internal/tools/search"
+	"github.com/pprunty/magikarp/internal/tools/wasm"
+	"github.com/spf13/cobra"
+)
+
+// Serve mode flags
+var (
+	serveAddr    string
+	serveYes     bool
+	serveToken   string
+	serveOrigins []string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the provider registry and tools over an HTTP/JSON API",
+	Long: `Serve starts an HTTP server so editors and other frontends can use Magikarp's
+configured providers and tools:
+
+  GET  /models         list the available models
+  POST /chat           run a turn; set "stream": true for server-sent events
+  POST /tools/execute  run a single tool
+
+Every request must send "Authorization: Bearer <token>" with the token of --token
+(or MAGIKARP_SERVER_TOKEN); without one, a token is generated and printed at start.
+Request bodies must be sent as application/json. The server listens on localhost by
+default and only answers requests addressed to the host it listens on; browser
+pages are refused unless their origin is allowed with --allow-origin.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		code := runServe()
+		process.StopAll()
+		telemetry.Shutdown()
+		os.Exit(code)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:8787", "address to listen on")
+	serveCmd.Flags().BoolVarP(&serveYes, "yes", "y", false, "allow every tool call without approval")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "require this bearer token (defaults to $MAGIKARP_SERVER_TOKEN, or one generated at start)")
+	serveCmd.Flags().StringArrayVar(&serveOrigins, "allow-origin", nil, "allow browser pages from this origin, e.g. http://localhost:3000 (repeatable)")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// runServe serves the API until interrupted and returns the process exit code
+func runServe() int {
+	conf, err := cfg.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", err)
+		return exitConfigError
+	}
+	if err := conf.ValidateConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	policy, err := permissions.New(conf.Tools, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	if err := wasm.Register(conf.Tools); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	if err := index.Configure(conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuration error: %v\n", err)
+		return exitConfigError
+	}
+	search.Register()
+	tools.Configure(conf.Tools)
+	hooks.Configure(conf.Hooks)
+	github.Configure(conf.GitHub)
+	if err := telemetry.Start(conf.Telemetry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing is off: %v\n", err)
+	}
+	if err := orchestration.Init(conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: initialising providers: %v\n", err)
+		return exitConfigError
+	}
+
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("MAGIKARP_SERVER_TOKEN")
+	}
+	if token == "" {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: generating a token: %v\n", err)
+			return exitError
+		}
+		token = hex.EncodeToString(b)
+		fmt.Fprintf(os.Stderr, "Token: %s\n", token)
+	}
+	opts := server.Options{Token: token, Hosts: serveHosts(serveAddr), Origins: serveOrigins, AllowTools: serveYes}
+
+	srv := &http.Server{
+		Addr:              serveAddr,
+		Handler:           server.New(conf, policy, opts).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Serving on http://%s\n", serveAddr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	case <-stop:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// serveHosts returns the Host headers accepted by a server listening on addr: the
+// address itself and, on a loopback address, its other local names. Any host is
+// accepted when listening on every interface, where the token still applies.
+func serveHosts(addr string) []string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || net.ParseIP(host) != nil && net.ParseIP(host).IsUnspecified() {
+		return nil
+	}
+	hosts := []string{strings.ToLower(addr)}
+	if isLoopback(host) {
+		for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+			hosts = append(hosts, net.JoinHostPort(name, port))
+		}
+	}
+	return hosts
+}
+
+// isLoopback reports whether host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
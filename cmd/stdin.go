@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxStdinBytes bounds the input piped to print mode that is sent with the prompt.
+// Longer input keeps its end, where logs usually say what failed.
+const maxStdinBytes = 100 * 1024
+
+// pipedInput is the text piped to print mode
+type pipedInput struct {
+	text string
+	// dropped is the number of bytes cut from the start of the input
+	dropped int
+}
+
+// readStdin reads the input piped or redirected to magikarp. It returns nil when stdin
+// is a terminal or a device such as /dev/null, so that an idle stdin never blocks.
+func readStdin() (*pipedInput, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeNamedPipe == 0 && !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	var data []byte
+	total := 0
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := os.Stdin.Read(buf)
+		data = append(data, buf[:n]...)
+		total += n
+		if len(data) > 2*maxStdinBytes {
+			data = append([]byte(nil), data[len(data)-maxStdinBytes:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+	if len(data) > maxStdinBytes {
+		data = data[len(data)-maxStdinBytes:]
+		// Start at a whole line rather than in the middle of one, or at least at a
+		// whole character when there is no line break left
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		} else {
+			for len(data) > 0 && !utf8.RuneStart(data[0]) {
+				data = data[1:]
+			}
+		}
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, errors.New("stdin is not text")
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	return &pipedInput{text: string(data), dropped: total - len(data)}, nil
+}
+
+// withPipedInput puts in before message as a block of context
+func withPipedInput(message string, in *pipedInput) string {
+	note := ""
+	if in.dropped > 0 {
+		note = fmt.Sprintf(" (the first %d bytes were cut)", in.dropped)
+	}
+	return fmt.Sprintf("Input piped to magikarp%s:\n<stdin>\n%s\n</stdin>\n\n%s", note, strings.TrimRight(in.text, "\n"), message)
+}
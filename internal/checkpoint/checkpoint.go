@@ -0,0 +1,248 @@
+// Package checkpoint snapshots the working tree of a git repository so that a run of
+// agent edits can be reverted as a whole. Snapshots are commits kept under
+// refs/magikarp/checkpoints; the branch, the index and the stash are never touched.
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refPrefix is where checkpoint commits are kept
+const refPrefix = "refs/magikarp/checkpoints/"
+
+// MaxCheckpoints is how many checkpoints are kept; older ones are dropped
+const MaxCheckpoints = 50
+
+// gitTimeout bounds every git invocation
+const gitTimeout = time.Minute
+
+// Checkpoint is a snapshot of the working tree
+type Checkpoint struct {
+	ID     int
+	Label  string
+	Commit string
+	Time   time.Time
+}
+
+// Rollback describes the files changed by restoring a checkpoint
+type Rollback struct {
+	Checkpoint Checkpoint
+	// Backup is the checkpoint of the working tree taken before it was rolled back
+	Backup   Checkpoint
+	Restored []string
+	Removed  []string
+}
+
+// Repo takes and restores the checkpoints of one repository
+type Repo struct {
+	// Root is the top-level directory of the working tree
+	Root   string
+	gitDir string
+}
+
+// Open returns the repository containing dir
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	out, err := git(ctx, dir, nil, "", "rev-parse", "--show-toplevel", "--absolute-git-dir")
+	if err != nil {
+		return nil, fmt.Errorf("checkpoints need a git repository: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("checkpoints need a git working tree")
+	}
+	return &Repo{Root: lines[0], gitDir: lines[1]}, nil
+}
+
+// Create snapshots the working tree, including untracked files that are not ignored.
+// When it is unchanged since the latest checkpoint and skipUnchanged is set, that
+// checkpoint is returned instead and created is false.
+func (r *Repo) Create(ctx context.Context, label string, skipUnchanged bool) (cp Checkpoint, created bool, err error) {
+	tree, err := r.snapshotTree(ctx)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	list, err := r.List(ctx)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+	if n := len(list); skipUnchanged && n > 0 {
+		if latest, err := r.treeOf(ctx, list[n-1].Commit); err == nil && latest == tree {
+			return list[n-1], false, nil
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", label}
+	if head, err := git(ctx, r.Root, nil, "", "rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		args = append(args, "-p", strings.TrimSpace(head))
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=magikarp", "GIT_AUTHOR_EMAIL=magikarp@localhost",
+		"GIT_COMMITTER_NAME=magikarp", "GIT_COMMITTER_EMAIL=magikarp@localhost",
+	}
+	commit, err := git(ctx, r.Root, env, "", args...)
+	if err != nil {
+		return Checkpoint{}, false, err
+	}
+
+	cp = Checkpoint{ID: 1, Label: label, Commit: strings.TrimSpace(commit), Time: time.Now()}
+	if n := len(list); n > 0 {
+		cp.ID = list[n-1].ID + 1
+	}
+	if _, err := git(ctx, r.Root, nil, "", "update-ref", refPrefix+strconv.Itoa(cp.ID), cp.Commit); err != nil {
+		return Checkpoint{}, false, err
+	}
+	for i := 0; i+MaxCheckpoints < len(list)+1; i++ {
+		_, _ = git(ctx, r.Root, nil, "", "update-ref", "-d", refPrefix+strconv.Itoa(list[i].ID))
+	}
+	return cp, true, nil
+}
+
+// snapshotTree writes the working tree to a tree object through a scratch index, so the
+// real index is left alone
+func (r *Repo) snapshotTree(ctx context.Context) (string, error) {
+	index := filepath.Join(r.gitDir, "magikarp-checkpoint-index")
+	defer os.Remove(index)
+	// Starting from the real index lets git skip hashing files that did not change
+	if data, err := os.ReadFile(filepath.Join(r.gitDir, "index")); err == nil {
+		if err := os.WriteFile(index, data, 0600); err != nil {
+			return "", err
+		}
+	}
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if _, err := git(ctx, r.Root, env, "", "add", "-A", "--", "."); err != nil {
+		return "", err
+	}
+	tree, err := git(ctx, r.Root, env, "", "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// treeOf returns the tree of a commit
+func (r *Repo) treeOf(ctx context.Context, commit string) (string, error) {
+	tree, err := git(ctx, r.Root, nil, "", "rev-parse", commit+"^{tree}")
+	return strings.TrimSpace(tree), err
+}
+
+// List returns the checkpoints of the repository, oldest first
+func (r *Repo) List(ctx context.Context) ([]Checkpoint, error) {
+	out, err := git(ctx, r.Root, nil, "", "for-each-ref",
+		"--format=%(refname:lstrip=3)%00%(objectname)%00%(committerdate:unix)%00%(subject)", refPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var list []Checkpoint
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[2], 10, 64)
+		list = append(list, Checkpoint{ID: id, Commit: fields[1], Time: time.Unix(unix, 0), Label: fields[3]})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Get returns checkpoint id, or the latest one when id is 0
+func (r *Repo) Get(ctx context.Context, id int) (Checkpoint, error) {
+	list, err := r.List(ctx)
+	if err != nil {
+		return Checkpoint{}, err
+	}
+	if len(list) == 0 {
+		return Checkpoint{}, fmt.Errorf("there are no checkpoints")
+	}
+	if id == 0 {
+		return list[len(list)-1], nil
+	}
+	for _, cp := range list {
+		if cp.ID == id {
+			return cp, nil
+		}
+	}
+	return Checkpoint{}, fmt.Errorf("no checkpoint #%d", id)
+}
+
+// Rollback restores the working tree to checkpoint id (the latest when 0): files changed
+// or deleted since are restored and files created since are removed. Ignored files are
+// left alone. The working tree is checkpointed first, so a rollback can be rolled back.
+func (r *Repo) Rollback(ctx context.Context, id int) (*Rollback, error) {
+	target, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	backup, _, err := r.Create(ctx, fmt.Sprintf("before rollback to #%d", target.ID), true)
+	if err != nil {
+		return nil, fmt.Errorf("checkpointing the working tree: %w", err)
+	}
+	result := &Rollback{Checkpoint: target, Backup: backup}
+	if backup.Commit == target.Commit {
+		return result, nil
+	}
+
+	out, err := git(ctx, r.Root, nil, "", "diff", "--name-status", "--no-renames", "-z", target.Commit, backup.Commit)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status, path := fields[i], fields[i+1]
+		if status == "A" {
+			result.Removed = append(result.Removed, path)
+		} else {
+			result.Restored = append(result.Restored, path)
+		}
+	}
+
+	if len(result.Restored) > 0 {
+		stdin := strings.Join(result.Restored, "\x00")
+		if _, err := git(ctx, r.Root, nil, stdin, "restore", "--source="+target.Commit, "--worktree",
+			"--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range result.Removed {
+		if err := os.Remove(filepath.Join(r.Root, path)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// git runs git in dir with extra environment variables and returns its stdout
+func git(ctx context.Context, dir string, env []string, stdin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
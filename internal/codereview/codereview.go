@@ -0,0 +1,169 @@
+// Package codereview reviews a git diff with a model (magikarp review): the diff is
+// sent in chunks and the structured findings of each are gathered into one report.
+package codereview
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+const (
+	// maxChunkBytes bounds the part of the diff reviewed in one request
+	maxChunkBytes = 24000
+	// maxConcurrent bounds the chunks reviewed at the same time
+	maxConcurrent = 4
+)
+
+//go:embed schema.json
+var findingsSchema []byte
+
+// Severities are the severities of findings, from the most to the least severe
+var Severities = []string{"error", "warning", "info"}
+
+const systemPrompt = `You are reviewing a change to a code base, as in a pull request review. You are shown part of its diff: each file starts with "File: <path>", and each line of a hunk is prefixed with its line number in the new file (removed lines have none).
+
+Report the problems the change introduces: bugs, security issues, race conditions, missing error handling, broken edge cases, and code that is misleading or much harder to maintain than it needs to be. Point each finding at the line of the new file it is about. Use "error" for defects that will break something, "warning" for likely problems, and "info" for minor improvements. Do not report style nits a formatter would fix, do not praise the change, and do not comment on code the diff does not touch. When the change looks correct, report no findings.`
+
+// Finding is a problem found in the diff
+type Finding struct {
+	File string `json:"file" yaml:"file"`
+	// Line is the line in the new version of File, or 0 for the file as a whole
+	Line       int    `json:"line" yaml:"line"`
+	Severity   string `json:"severity" yaml:"severity"`
+	Message    string `json:"message" yaml:"message"`
+	Suggestion string `json:"suggestion,omitempty" yaml:"suggestion,omitempty"`
+}
+
+// Report is the outcome of a review
+type Report struct {
+	// Files is the number of files reviewed
+	Files int `json:"files" yaml:"files"`
+	// Chunks is the number of requests the diff was reviewed in
+	Chunks   int       `json:"chunks" yaml:"chunks"`
+	Findings []Finding `json:"findings" yaml:"findings"`
+}
+
+// Counts returns the number of findings of each severity
+func (r *Report) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	return counts
+}
+
+// Options configures a review
+type Options struct {
+	Model    string
+	Thinking providers.Thinking
+	// OnChunk is called as each chunk is reviewed, from several goroutines
+	OnChunk func(done, total int)
+}
+
+// Review sends diff, the output of git diff, to the model in chunks and returns the
+// findings ordered by file and line. It fails if any chunk cannot be reviewed.
+func Review(ctx context.Context, diff string, opts Options) (*Report, error) {
+	schema, err := orchestration.CompileSchema("review_findings", findingsSchema)
+	if err != nil {
+		return nil, err
+	}
+	files := parseDiff(diff)
+	chunks := chunkDiff(files, maxChunkBytes)
+	report := &Report{Files: len(files), Chunks: len(chunks), Findings: []Finding{}}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([][]Finding, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, maxConcurrent)
+	var mu sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = reviewChunk(ctx, c, schema, opts)
+			if errs[i] != nil {
+				// The report would be incomplete: stop the other requests
+				cancel()
+			}
+			if opts.OnChunk != nil {
+				mu.Lock()
+				done++
+				opts.OnChunk(done, len(chunks))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that cancelled the other chunks, not their cancellation
+	var firstErr error
+	for i, err := range errs {
+		if err != nil && (firstErr == nil || errors.Is(firstErr, context.Canceled)) {
+			firstErr = fmt.Errorf("reviewing %s: %w", strings.Join(chunks[i].files, ", "), err)
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	for _, found := range results {
+		report.Findings = append(report.Findings, found...)
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return report, nil
+}
+
+// reviewChunk asks the model for the findings in c. Findings about files that are not
+// part of c are dropped.
+func reviewChunk(ctx context.Context, c chunk, schema *orchestration.Schema, opts Options) ([]Finding, error) {
+	result, err := orchestration.RunTurn(ctx, orchestration.Turn{
+		Model:    opts.Model,
+		System:   systemPrompt,
+		Message:  c.text,
+		Thinking: opts.Thinking,
+		Schema:   schema,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var answer struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(result.Text()), &answer); err != nil {
+		return nil, fmt.Errorf("the model did not answer with findings: %w", err)
+	}
+
+	var findings []Finding
+	for _, f := range answer.Findings {
+		f.File = strings.TrimPrefix(f.File, "b/")
+		if !slices.Contains(c.files, f.File) || strings.TrimSpace(f.Message) == "" {
+			continue
+		}
+		if !slices.Contains(Severities, f.Severity) {
+			f.Severity = "info"
+		}
+		f.Line = max(0, f.Line)
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
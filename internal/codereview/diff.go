@@ -0,0 +1,118 @@
+package codereview
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fileDiff is the diff of one file, its hunks annotated with line numbers
+type fileDiff struct {
+	path  string
+	hunks []string
+}
+
+// chunk is a part of the diff reviewed in one request
+type chunk struct {
+	files []string
+	text  string
+}
+
+// parseDiff splits the output of git diff into files. Deleted and binary files have
+// nothing to review and are left out. Each line of a hunk is prefixed with its line
+// number in the new file, so that findings can point at it.
+func parseDiff(diff string) []fileDiff {
+	var files []fileDiff
+	var cur *fileDiff
+	var hunk strings.Builder
+	newLine := 0
+	flush := func() {
+		if cur != nil && hunk.Len() > 0 {
+			cur.hunks = append(cur.hunks, hunk.String())
+		}
+		hunk.Reset()
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			files = append(files, fileDiff{})
+			cur = &files[len(files)-1]
+		case cur == nil:
+		case strings.HasPrefix(line, "+++ ") && hunk.Len() == 0 && len(cur.hunks) == 0:
+			cur.path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ "):
+			flush()
+			newLine = hunkStart(line)
+			hunk.WriteString(line + "\n")
+		case hunk.Len() == 0:
+			// The header of the file: index, mode and --- lines
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintf(&hunk, "%6s %s\n", "", line)
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, " "):
+			fmt.Fprintf(&hunk, "%6d %s\n", newLine, line)
+			newLine++
+		default:
+			// "\ No newline at end of file"
+			hunk.WriteString(line + "\n")
+		}
+	}
+	flush()
+
+	reviewable := files[:0]
+	for _, f := range files {
+		if f.path != "" && f.path != "/dev/null" && len(f.hunks) > 0 {
+			reviewable = append(reviewable, f)
+		}
+	}
+	return reviewable
+}
+
+// hunkStart returns the first line in the new file of the hunk with header line, e.g.
+// 12 for "@@ -10,4 +12,6 @@ func main() {"
+func hunkStart(line string) int {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 1
+	}
+	start, _, _ := strings.Cut(strings.TrimPrefix(fields[2], "+"), ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// chunkDiff groups files into chunks of about maxBytes. A file larger than that is
+// split between its hunks.
+func chunkDiff(files []fileDiff, maxBytes int) []chunk {
+	var chunks []chunk
+	var cur chunk
+	add := func(path, text string) {
+		if len(cur.text) > 0 && len(cur.text)+len(text) > maxBytes {
+			chunks = append(chunks, cur)
+			cur = chunk{}
+		}
+		if len(cur.files) == 0 || cur.files[len(cur.files)-1] != path {
+			cur.files = append(cur.files, path)
+		}
+		cur.text += text
+	}
+
+	for _, f := range files {
+		header := "File: " + f.path + "\n"
+		body := strings.Join(f.hunks, "")
+		if len(header)+len(body) <= maxBytes {
+			add(f.path, header+body+"\n")
+			continue
+		}
+		for _, h := range f.hunks {
+			add(f.path, header+h+"\n")
+		}
+	}
+	if len(cur.text) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
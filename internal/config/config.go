@@ -2,13 +2,27 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
-	"gopkg.in/yaml.v3"
+	"github.com/pprunty/magikarp/internal/redact"
 )
 
+// DefaultMaxHistory is the number of previous exchanges sent to the model when
+// max_history is not configured.
+const DefaultMaxHistory = 20
+
+// CatalogProviders lists providers whose models are discovered from their API at
+// startup, so their model list may be left empty
+var CatalogProviders = map[string]bool{"openrouter": true}
+
 // Config represents the application configuration
 type Config struct {
 	Name   string `yaml:"name"`
@@ -19,9 +33,63 @@ type Config struct {
 	// DefaultTemperature is the global default temperature for all providers.
 	// Individual providers can override this by specifying their own temperature.
 	DefaultTemperature float64 `yaml:"default_temperature"`
+	// FallbackModels are tried in order when the active model's provider fails with
+	// an authentication, rate limit or availability error.
+	FallbackModels []string `yaml:"fallback_models"`
+	// MaxHistory limits how many previous user/assistant exchanges are sent
+	// with each request. Zero or negative values use DefaultMaxHistory.
+	MaxHistory int `yaml:"max_history"`
+	// Streaming renders responses incrementally as they arrive. With tools on, only
+	// the OpenAI and Anthropic providers stream; the others answer once done.
+	// Individual providers can fall back to blocking requests with `stream: false`.
+	Streaming bool `yaml:"streaming"`
 	// Tools groups all tool related configuration (enabled/visibility)
-	Tools     ToolsConfig         `yaml:"tools"`
+	Tools ToolsConfig `yaml:"tools"`
+	// Context controls automatic compression of long conversations
+	Context ContextConfig `yaml:"context"`
+	// Thinking controls extended thinking of the models that support it (/think)
+	Thinking ThinkingConfig `yaml:"thinking"`
+	// Retry controls how failed provider requests are retried
+	Retry RetryConfig `yaml:"retry"`
+	// Terminal controls the interactive input
+	Terminal TerminalConfig `yaml:"terminal"`
+	// Index configures the semantic index of the project's files
+	Index IndexConfig `yaml:"index"`
+	// Routing configures the "auto" model, which picks a model for each prompt
+	Routing RoutingConfig `yaml:"routing"`
+	// Hooks run user commands on lifecycle events
+	Hooks HooksConfig `yaml:"hooks"`
+	// Voice configures reading responses aloud (/voice)
+	Voice VoiceConfig `yaml:"voice"`
+	// Speech configures speech recognition in speech mode (/speech)
+	Speech SpeechConfig `yaml:"speech"`
+	// Telemetry exports OpenTelemetry traces of turns, provider requests and tools
+	Telemetry TelemetryConfig `yaml:"telemetry"`
+	// GitHub configures access to the GitHub API
+	GitHub GitHubConfig `yaml:"github"`
+	// Share configures where /share uploads conversations
+	Share ShareConfig `yaml:"share"`
+	// Commit configures the messages /commit writes
+	Commit CommitConfig `yaml:"commit"`
+	// Redaction masks credentials in prompts, tool results, sessions and debug logs
+	Redaction RedactionConfig     `yaml:"redaction"`
 	Providers map[string]Provider `yaml:"providers"`
+	// Models describes models, or model families by name prefix, that the built-in
+	// capability table does not know or gets wrong
+	Models map[string]ModelConfig `yaml:"models"`
+
+	// Path is the config file settings are saved to: the loaded one that takes
+	// precedence, or the global one when none was found
+	Path string `yaml:"-"`
+	// Files are the config files loaded, from lowest to highest precedence
+	Files []string `yaml:"-"`
+	// BuiltinProviders are the providers that only the built-in defaults configure
+	BuiltinProviders map[string]bool `yaml:"-"`
+	// InstructionsPath is the project instruction file (MAGIKARP.md) appended to System
+	InstructionsPath string `yaml:"-"`
+	// baseSystem is System as configured, before project instructions were appended
+	baseSystem         string
+	instructionsLoaded bool
 }
 
 // Provider represents an LLM provider configuration
@@ -29,15 +97,426 @@ type Provider struct {
 	Models      []string `yaml:"models"`
 	Temperature float64  `yaml:"temperature"`
 	Key         string   `yaml:"key"`
+	// Stream disables streaming for this provider when set to false.
+	Stream *bool `yaml:"stream"`
+	// BaseURL overrides the provider endpoint (e.g. a local Ollama server).
+	BaseURL string `yaml:"base_url"`
+	// APIVersion selects the REST API version for providers that require one (Azure OpenAI).
+	APIVersion string `yaml:"api_version"`
+	// Auth selects the authentication mode for providers that support several (Azure OpenAI).
+	Auth string `yaml:"auth"`
+	// Deployments maps model names to deployment names (Azure OpenAI).
+	Deployments map[string]string `yaml:"deployments"`
+	// MaxTokens limits the length of each response. Zero uses the provider default.
+	MaxTokens int `yaml:"max_tokens"`
+	// Stop are sequences at which models stop generating a response.
+	Stop []string `yaml:"stop"`
+	// EmbeddingModel selects the model used for embeddings (OpenAI, Gemini and Ollama).
+	// Empty uses the provider default.
+	EmbeddingModel string `yaml:"embedding_model"`
+}
+
+// ModelConfig describes the capabilities of a model. Unset fields keep the values
+// known for the model or its family.
+type ModelConfig struct {
+	// ContextWindow is the number of tokens the model accepts per request
+	ContextWindow int `yaml:"context_window,omitempty"`
+	// Tools reports whether the model can call tools
+	Tools *bool `yaml:"tools,omitempty"`
+	// Vision reports whether the model can read images
+	Vision *bool `yaml:"vision,omitempty"`
+	// Thinking reports whether the model can think before it answers (/think)
+	Thinking *bool `yaml:"thinking,omitempty"`
+	// Pricing is the list price of the model
+	Pricing *ModelPricing `yaml:"pricing,omitempty"`
+	// MaxTokens limits the length of each response, overriding the provider's
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+	// Stop are sequences at which the model stops generating, overriding the provider's
+	Stop []string `yaml:"stop,omitempty"`
+}
+
+// ModelPricing is the cost of a model in USD per million tokens
+type ModelPricing struct {
+	Prompt     float64 `yaml:"prompt"`
+	Completion float64 `yaml:"completion"`
 }
 
 // ToolsConfig represents configuration for tool usage and UI output.
 type ToolsConfig struct {
 	Enabled bool `yaml:"enabled"`
 	Output  bool `yaml:"output"`
+	// MaxIterations limits how many rounds of tool calls the agent runs per message.
+	// Zero uses the orchestration default.
+	MaxIterations int `yaml:"max_iterations"`
+	// AutoApprove lists tools that run without asking the user first.
+	// Use "*" to approve every tool.
+	AutoApprove []string `yaml:"auto_approve"`
+	// Permissions are checked in order before every tool call; the first matching
+	// rule decides. Calls no rule matches are auto-approved or asked about.
+	Permissions []PermissionRule `yaml:"permissions"`
+	// Settings adjust how tools run, keyed by tool name, toolbox name or "*" for every
+	// tool. A tool's own entry overrides its toolbox's, which overrides "*".
+	Settings map[string]ToolSettings `yaml:"settings"`
+	// Wasm declares tools implemented as WebAssembly modules, run in a sandbox
+	Wasm []WasmTool `yaml:"wasm"`
+	// Checkpoints snapshots the working tree before the first tool call of a message
+	// that may change it, so /rollback can revert the run. Unset enables it.
+	Checkpoints *bool `yaml:"checkpoints"`
+	// Bash configures the shell the bash tool runs scripts in
+	Bash BashConfig `yaml:"bash"`
+	// Notes configures the scratchpad of the note_write and note_read tools
+	Notes NotesConfig `yaml:"notes"`
+}
+
+// NotesConfig configures the scratchpad the agent keeps notes in during a session
+type NotesConfig struct {
+	// Persist saves the notes with the session, so /resume restores them. Otherwise
+	// they last until Magikarp exits.
+	Persist bool `yaml:"persist"`
+}
+
+// Shells the bash tool can run scripts in
+var Shells = []string{"bash", "zsh", "sh", "pwsh"}
+
+// BashConfig selects the shell and environment of the bash tool
+type BashConfig struct {
+	// Shell is bash (the default), zsh, sh or pwsh. Scripts for zsh and pwsh cannot be
+	// checked for risky commands, so every call needs approval.
+	Shell string `yaml:"shell"`
+	// Login runs a login shell, which reads the user's profile
+	Login bool `yaml:"login"`
+	// EnvAllowlist limits the environment variables passed to scripts to these names,
+	// which may use "*" wildcards (e.g. "GO*"). PATH, HOME and the locale are always
+	// passed. Empty passes the whole environment.
+	EnvAllowlist []string `yaml:"env_allowlist"`
+	// PersistCwd keeps the directory a script ends in (e.g. after cd) as the working
+	// directory of the next script in the session, unless it is outside the working
+	// directory of magikarp
+	PersistCwd bool `yaml:"persist_cwd"`
+}
+
+// WasmTool is a WASI module used as a tool. It reads the tool input as JSON on stdin
+// and writes the result to stdout; it has no network access and sees only Mounts.
+type WasmTool struct {
+	// Module is the path of the .wasm file
+	Module string `yaml:"module"`
+	// Manifest is the tool.json with name, description and input_schema; it defaults
+	// to the module path with a .json extension
+	Manifest string `yaml:"manifest"`
+	// Mounts are host directories the module may read, at the same paths as on the
+	// host; "." is the working directory
+	Mounts []string `yaml:"mounts"`
+	// Writable lets the module write to its mounts
+	Writable bool `yaml:"writable"`
+	// Env sets environment variables for the module
+	Env map[string]string `yaml:"env"`
+	// Timeout cancels calls that run longer; it defaults to 30s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ToolSettings limit how a tool runs. Unset fields fall back to the less specific entry.
+type ToolSettings struct {
+	// Enabled hides the tool from the model when set to false
+	Enabled *bool `yaml:"enabled"`
+	// Timeout cancels calls that run longer (e.g. "2m")
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxOutputBytes truncates longer results before they reach the model
+	MaxOutputBytes int `yaml:"max_output_bytes"`
+	// MaxConcurrent limits the calls of the tool running at the same time
+	MaxConcurrent int `yaml:"max_concurrent"`
+}
+
+// PermissionRule allows, denies or asks about the tool calls that match all of its
+// conditions. Tool and Args patterns may use "*" wildcards.
+type PermissionRule struct {
+	Tool   string `yaml:"tool"`
+	Action string `yaml:"action"` // allow, deny or ask
+	// Args maps argument names to patterns their values must match
+	Args map[string]string `yaml:"args"`
+	// OutsideWorkDir matches calls with a path argument outside the working directory
+	OutsideWorkDir bool `yaml:"outside_workdir"`
+	// Network matches shell commands that access the network
+	Network bool `yaml:"network"`
+	// Reason is reported to the model when the call is denied
+	Reason string `yaml:"reason"`
+}
+
+// ContextConfig controls when older exchanges are summarised to stay within the
+// model's context window. Unset values use the context package defaults.
+type ContextConfig struct {
+	// CompressAt is the fraction of the context window (0-1) at which history is compressed
+	CompressAt float64 `yaml:"compress_at"`
+	// KeepRecent is the number of most recent exchanges that are never summarised
+	KeepRecent int `yaml:"keep_recent"`
+	// Window overrides the model's context window size in tokens
+	Window int `yaml:"window"`
+	// Disabled turns automatic compression off
+	Disabled bool `yaml:"disabled"`
+	// DocumentTokens limits how much of an attached document is sent with a prompt;
+	// larger documents are cut down to the chunks most relevant to the prompt
+	DocumentTokens int `yaml:"document_tokens"`
+}
+
+// ThinkingConfig controls extended thinking: Anthropic thinking blocks and the
+// reasoning effort of OpenAI reasoning models
+type ThinkingConfig struct {
+	// Effort is off, low, medium or high; empty is off
+	Effort string `yaml:"effort"`
+	// BudgetTokens caps the tokens Anthropic models spend thinking; zero derives it
+	// from Effort
+	BudgetTokens int `yaml:"budget_tokens"`
+	// Background lets sub-agents and history compression think too. They do not by
+	// default, as thinking makes them slower and costlier.
+	Background bool `yaml:"background"`
+}
+
+// ThinkingEfforts are the accepted values of thinking.effort
+var ThinkingEfforts = []string{"off", "low", "medium", "high"}
+
+// IndexConfig configures the semantic index of the project's files, used by the
+// semantic_search tool and to add relevant code to prompts
+type IndexConfig struct {
+	// Provider computes the embeddings: "openai", "gemini" or "ollama". The index is
+	// disabled when it is empty. The key and embedding_model come from the provider's
+	// entry in the providers section.
+	Provider string `yaml:"provider"`
+	// AutoContext is the number of relevant snippets added to every prompt; zero turns
+	// automatic retrieval off
+	AutoContext int `yaml:"auto_context"`
+}
+
+// AutoModel is the model name that routes each prompt to a model chosen by Routing
+const AutoModel = "auto"
+
+// RoutingTasks are the kinds of task a prompt is classified as for routing
+var RoutingTasks = []string{"code_edit", "explanation", "long_context", "chatter"}
+
+// RoutingConfig configures the "auto" model, which classifies each prompt by the kind of
+// task it asks for and sends it to the model configured for that task
+type RoutingConfig struct {
+	// Models maps tasks (code_edit, explanation, long_context, chatter) to the model that
+	// handles them. The "auto" model is offered when it is not empty.
+	Models map[string]string `yaml:"models"`
+	// Default answers prompts whose task has no available model; empty uses default_model
+	Default string `yaml:"default"`
+	// Classifier is "rules" (the default) to classify prompts with Rules and built-in
+	// keyword rules, or the name of a (small, fast) model asked to classify each prompt
+	Classifier string `yaml:"classifier"`
+	// LongContextTokens is the size of the history and prompt from which a prompt is a
+	// long_context task. Zero uses the orchestration default.
+	LongContextTokens int `yaml:"long_context_tokens"`
+	// Rules are checked in order before the built-in rules; the first whose pattern
+	// matches the prompt decides its task
+	Rules []RoutingRule `yaml:"rules"`
+}
+
+// RoutingRule assigns prompts matching Pattern, a regular expression, to Task
+type RoutingRule struct {
+	Pattern string `yaml:"pattern"`
+	Task    string `yaml:"task"`
+}
+
+// Enabled reports whether the "auto" model is configured
+func (r RoutingConfig) Enabled() bool {
+	return len(r.Models) > 0
+}
+
+// isRoutingTask reports whether task is one of RoutingTasks
+func isRoutingTask(task string) bool {
+	for _, t := range RoutingTasks {
+		if t == task {
+			return true
+		}
+	}
+	return false
+}
+
+// HooksConfig lists the commands run on each lifecycle event. Every command gets the
+// event as JSON on stdin.
+type HooksConfig struct {
+	// PreTool runs before a tool call; a failing command blocks the call
+	PreTool []Hook `yaml:"pre_tool"`
+	// PostTool runs after a tool call with its output
+	PostTool []Hook `yaml:"post_tool"`
+	// PrePrompt runs before a message is sent to the model; a failing command blocks it
+	PrePrompt []Hook `yaml:"pre_prompt"`
+	// PostResponse runs after the model has answered a message
+	PostResponse []Hook `yaml:"post_response"`
+	// SessionEnd runs when an interactive session or a print mode run ends
+	SessionEnd []Hook `yaml:"session_end"`
+}
+
+// Hook is a shell command run on an event
+type Hook struct {
+	Command string `yaml:"command"`
+	// Tool restricts tool hooks to the tools matching the pattern ("*" wildcards);
+	// empty matches every tool
+	Tool string `yaml:"tool"`
+	// Timeout bounds the command; zero uses the hooks default
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// VoiceConfig selects the text-to-speech backend that reads responses aloud
+type VoiceConfig struct {
+	// Enabled reads responses aloud from the start of a session
+	Enabled bool `yaml:"enabled"`
+	// Backend is "auto", "say", "espeak", "openai" or "elevenlabs"; auto uses say on
+	// macOS and espeak elsewhere
+	Backend string `yaml:"backend"`
+	// Voice is a say or espeak voice name, an OpenAI voice or an ElevenLabs voice ID
+	Voice string `yaml:"voice"`
+	// Model selects the speech model of the openai and elevenlabs backends
+	Model string `yaml:"model"`
+	// Rate is the speaking rate of say and espeak in words per minute; zero uses theirs
+	Rate int `yaml:"rate"`
+	// Key is the ElevenLabs API key; the openai backend uses the openai provider's key
+	Key string `yaml:"key"`
+	// Player plays the WAV audio of the openai and elevenlabs backends, which is passed
+	// as its last argument; empty picks afplay, paplay, aplay, ffplay or mpv
+	Player string `yaml:"player"`
+}
+
+// RedactionConfig controls the masking of credentials before text leaves the process
+// or is written to disk
+type RedactionConfig struct {
+	// Enabled turns redaction off when set to false
+	Enabled *bool `yaml:"enabled"`
+	// Patterns are regular expressions masked in addition to the built-in ones; when a
+	// pattern has a capture group only the group is masked
+	Patterns []string `yaml:"patterns"`
+}
+
+// IsEnabled reports whether credentials are masked, which they are unless disabled
+func (r RedactionConfig) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// SpeechConfig selects how speech mode records and transcribes what the user says
+type SpeechConfig struct {
+	// Backend is "openai" (Whisper, the default) or "deepgram"
+	Backend string `yaml:"backend"`
+	// Model selects the recognition model; empty uses the backend default
+	Model string `yaml:"model"`
+	// Language is an optional ISO-639-1 hint such as "en"
+	Language string `yaml:"language"`
+	// Key is the Deepgram API key; the openai backend uses the openai provider's key
+	Key string `yaml:"key"`
+	// Recorder writes raw 16 kHz mono 16-bit little-endian PCM from the microphone to
+	// stdout; empty picks rec (SoX), arecord or ffmpeg
+	Recorder string `yaml:"recorder"`
+	// Pause is the silence that ends an utterance; zero uses 1s
+	Pause time.Duration `yaml:"pause"`
+	// Threshold is the lowest loudness (RMS of 16-bit samples) counted as speech; zero
+	// uses 500. Sounds must also stand out from the measured background noise.
+	Threshold float64 `yaml:"threshold"`
+	// Preroll is the audio kept before speech is detected so the first syllable is
+	// not cut off; zero uses 300ms
+	Preroll time.Duration `yaml:"preroll"`
+	// Postroll is the silence kept after the end of speech; zero uses 300ms
+	Postroll time.Duration `yaml:"postroll"`
+	// PushToTalk is the key that starts and stops a recording whose transcript is put
+	// in the input box; empty uses ctrl+space and "off" disables it
+	PushToTalk string `yaml:"push_to_talk"`
+	// WakeWord, when set, makes speech mode act only on what is addressed to it, such
+	// as "hey magikarp, run the tests"
+	WakeWord string `yaml:"wake_word"`
+	// Sensitivity from 0 to 1 sets how loosely the wake word is matched; zero uses 0.5
+	Sensitivity float64 `yaml:"sensitivity"`
+	// AutoSend sends a transcript as a message when the input box is empty and no
+	// response is running (the default); false always puts it in the input box
+	AutoSend *bool `yaml:"auto_send"`
+}
+
+// RetryConfig controls retries of rate-limited and transient provider failures.
+// Unset values use the orchestration defaults.
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt; 0 disables retrying
+	MaxRetries *int `yaml:"max_retries"`
+	// InitialBackoff is the delay before the first retry (e.g. "1s"); it doubles each retry
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff caps the delay between retries, including server Retry-After hints
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// TelemetryConfig exports OpenTelemetry traces over OTLP/HTTP. Tracing is off unless
+// Endpoint or the standard OTEL_EXPORTER_OTLP_ENDPOINT variables are set.
+type TelemetryConfig struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver, e.g. http://localhost:4318
+	Endpoint string `yaml:"endpoint"`
+	// Headers are sent with each export, e.g. the API key of a hosted backend
+	Headers map[string]string `yaml:"headers"`
+	// ServiceName names the traces; defaults to magikarp
+	ServiceName string `yaml:"service_name"`
+}
+
+// GitHubConfig configures access to the GitHub API
+type GitHubConfig struct {
+	// Token authenticates requests; defaults to GITHUB_TOKEN, GH_TOKEN or the token
+	// of the gh CLI
+	Token string `yaml:"token"`
+	// APIURL is the API of GitHub Enterprise Server, e.g. https://github.example.com/api/v3;
+	// defaults to GITHUB_API_URL or https://api.github.com
+	APIURL string `yaml:"api_url"`
 }
 
-// LoadConfig loads configuration from the specified file path
+// ShareServices are the values accepted for share.service
+var ShareServices = []string{"gist", "paste"}
+
+// ShareConfig configures where /share uploads conversations
+type ShareConfig struct {
+	// Service is "gist" (the default) for a GitHub gist, or "paste" to POST the
+	// transcript to URL
+	Service string `yaml:"service"`
+	// Public makes gists public; by default they are secret, visible to those with the link
+	Public bool `yaml:"public"`
+	// URL receives the transcript as Markdown in the body of a POST when Service is
+	// "paste"; the response is the link, as text or as JSON with a url field
+	URL string `yaml:"url"`
+	// Headers are sent with paste uploads, e.g. an API key
+	Headers map[string]string `yaml:"headers"`
+}
+
+// CommitStyles are the values accepted for commit.style
+var CommitStyles = []string{"conventional", "plain"}
+
+// CommitConfig configures the messages /commit writes
+type CommitConfig struct {
+	// Style is "conventional" (the default) for Conventional Commits subjects such as
+	// "fix(parser): ...", or "plain" for a capitalised imperative subject
+	Style string `yaml:"style"`
+	// Signoff adds a Signed-off-by trailer, as git commit --signoff does
+	Signoff bool `yaml:"signoff"`
+}
+
+// TerminalConfig controls the interactive input
+type TerminalConfig struct {
+	// Keymap selects the input key bindings: "default" or "vim"
+	Keymap string `yaml:"keymap"`
+	// Notify signals that a long request finished while the terminal was in the background
+	Notify NotifyConfig `yaml:"notify"`
+}
+
+// NotifyConfig controls the notification sent when a long request finishes
+type NotifyConfig struct {
+	// Method is "auto" (an OSC 777 notification and the bell), "bell", "osc777",
+	// "system" (notify-send or osascript) or "off"; empty means auto
+	Method string `yaml:"method"`
+	// After is how long a request must run to be notified; zero uses 30s
+	After time.Duration `yaml:"after"`
+}
+
+// IsAutoApproved reports whether the named tool may run without user approval.
+func (t ToolsConfig) IsAutoApproved(name string) bool {
+	for _, allowed := range t.AutoApprove {
+		if allowed == "*" || allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig loads the layered configuration described at Load, with the file at
+// configPath, when set, above the config files found
 func LoadConfig(configPath string) (*Config, error) {
 	// Try to load .env file from multiple locations
 	envPaths := []string{".env", "./.env", filepath.Join(".", ".env")}
@@ -59,28 +538,39 @@ func LoadConfig(configPath string) (*Config, error) {
 		}
 	}
 
-	// If no config path specified, look for config.yaml in current directory
-	if configPath == "" {
-		configPath = "config.yaml"
+	files, err := ConfigFiles(configPath)
+	if err != nil {
+		return nil, err
 	}
-
-	data, err := os.ReadFile(configPath)
+	values, err := overrides()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, err
+	}
+	merged, err := mergeLayers(files, values)
+	if err != nil {
+		return nil, err
 	}
-
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	if err := merged.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	config.Files = files
+	config.Path = savePath(files)
+	config.BuiltinProviders = builtinProviders(files, values)
 
 	// Expand environment variables in system prompt
 	config.System = os.ExpandEnv(config.System)
 
+	// Append project instructions (MAGIKARP.md) to the system prompt
+	if err := config.LoadInstructions(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	// Expand environment variables in API keys
 	for name, provider := range config.Providers {
 		originalKey := provider.Key
 		provider.Key = os.ExpandEnv(provider.Key)
+		provider.BaseURL = os.ExpandEnv(provider.BaseURL)
 		config.Providers[name] = provider
 
 		// Debug: check if environment variable expansion worked
@@ -91,17 +581,29 @@ func LoadConfig(configPath string) (*Config, error) {
 			// This is expected behavior - the validation happens elsewhere
 		}
 	}
+	config.Voice.Key = os.ExpandEnv(config.Voice.Key)
+	config.Speech.Key = os.ExpandEnv(config.Speech.Key)
+	config.Telemetry.Endpoint = os.ExpandEnv(config.Telemetry.Endpoint)
+	for name, value := range config.Telemetry.Headers {
+		config.Telemetry.Headers[name] = os.ExpandEnv(value)
+	}
+	config.GitHub.Token = os.ExpandEnv(config.GitHub.Token)
+	config.Share.URL = os.ExpandEnv(config.Share.URL)
+	for name, value := range config.Share.Headers {
+		config.Share.Headers[name] = os.ExpandEnv(value)
+	}
 
 	return &config, nil
 }
 
-// GetDefaultConfigPath returns the default configuration file path
+// GetDefaultConfigPath returns the path of the global configuration file,
+// ~/.magikarp/config.yaml
 func GetDefaultConfigPath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return "config.yaml"
+		return LocalConfigFile
 	}
-	return filepath.Join(homeDir, ".magikarp.yaml")
+	return filepath.Join(homeDir, ".magikarp", "config.yaml")
 }
 
 // ValidateConfig validates the configuration
@@ -115,13 +617,20 @@ func (c *Config) ValidateConfig() error {
 	}
 
 	for name, provider := range c.Providers {
-		if len(provider.Models) == 0 {
+		if provider.MaxTokens < 0 {
+			return fmt.Errorf("provider %s: max_tokens must not be negative", name)
+		}
+		// Catalog providers fetch their models at startup when none are listed
+		if len(provider.Models) == 0 && !CatalogProviders[name] {
 			return fmt.Errorf("provider %s must have at least one model", name)
 		}
 		// Don't require API keys in validation - they'll be checked during provider initialization
 	}
 
-	if c.DefaultModel != "" {
+	if c.DefaultModel == AutoModel && !c.Routing.Enabled() {
+		return fmt.Errorf("default_model auto needs models under routing")
+	}
+	if c.DefaultModel != "" && c.DefaultModel != AutoModel {
 		// Ensure the default model has a registered provider entry.
 		found := false
 		for _, provider := range c.Providers {
@@ -140,6 +649,143 @@ func (c *Config) ValidateConfig() error {
 		}
 	}
 
+	if _, err := redact.Compile(c.Redaction.Patterns); err != nil {
+		return err
+	}
+
+	if c.Tools.Bash.Shell != "" && !slices.Contains(Shells, c.Tools.Bash.Shell) {
+		return fmt.Errorf("tools.bash.shell must be one of %s", strings.Join(Shells, ", "))
+	}
+	for _, pattern := range c.Tools.Bash.EnvAllowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("tools.bash.env_allowlist: invalid pattern %q", pattern)
+		}
+	}
+
+	for name, s := range c.Tools.Settings {
+		if s.Timeout < 0 || s.MaxOutputBytes < 0 || s.MaxConcurrent < 0 {
+			return fmt.Errorf("tools.settings.%s: timeout, max_output_bytes and max_concurrent must not be negative", name)
+		}
+	}
+
+	for name, m := range c.Models {
+		if m.ContextWindow < 0 {
+			return fmt.Errorf("models.%s.context_window must not be negative", name)
+		}
+		if m.MaxTokens < 0 {
+			return fmt.Errorf("models.%s.max_tokens must not be negative", name)
+		}
+		if p := m.Pricing; p != nil && (p.Prompt < 0 || p.Completion < 0) {
+			return fmt.Errorf("models.%s.pricing must not be negative", name)
+		}
+	}
+
+	if c.Thinking.Effort != "" && !slices.Contains(ThinkingEfforts, c.Thinking.Effort) {
+		return fmt.Errorf("thinking.effort must be one of %s", strings.Join(ThinkingEfforts, ", "))
+	}
+	if c.Thinking.BudgetTokens < 0 {
+		return fmt.Errorf("thinking.budget_tokens must not be negative")
+	}
+
+	if e := c.Telemetry.Endpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("telemetry.endpoint must be an http or https URL, got %q", e)
+		}
+	}
+
+	if c.Share.Service != "" && !slices.Contains(ShareServices, c.Share.Service) {
+		return fmt.Errorf("share.service must be one of %s", strings.Join(ShareServices, ", "))
+	}
+	if c.Share.Service == "paste" {
+		if u, err := url.Parse(c.Share.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("share.url must be an http or https URL when share.service is paste")
+		}
+	}
+
+	if c.Commit.Style != "" && !slices.Contains(CommitStyles, c.Commit.Style) {
+		return fmt.Errorf("commit.style must be one of %s", strings.Join(CommitStyles, ", "))
+	}
+
+	switch c.Index.Provider {
+	case "", "openai", "gemini", "ollama":
+	default:
+		return fmt.Errorf("index.provider must be \"openai\", \"gemini\" or \"ollama\", got %q", c.Index.Provider)
+	}
+	if c.Index.AutoContext < 0 {
+		return fmt.Errorf("index.auto_context must not be negative")
+	}
+
+	for task := range c.Routing.Models {
+		if !isRoutingTask(task) {
+			return fmt.Errorf("routing.models: unknown task %q (expected one of %v)", task, RoutingTasks)
+		}
+	}
+	for i, rule := range c.Routing.Rules {
+		if !isRoutingTask(rule.Task) {
+			return fmt.Errorf("routing.rules[%d]: unknown task %q (expected one of %v)", i, rule.Task, RoutingTasks)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("routing.rules[%d]: invalid pattern: %w", i, err)
+		}
+	}
+	for event, hooks := range map[string][]Hook{
+		"pre_tool": c.Hooks.PreTool, "post_tool": c.Hooks.PostTool, "pre_prompt": c.Hooks.PrePrompt,
+		"post_response": c.Hooks.PostResponse, "session_end": c.Hooks.SessionEnd,
+	} {
+		for i, h := range hooks {
+			if strings.TrimSpace(h.Command) == "" {
+				return fmt.Errorf("hooks.%s[%d]: command is required", event, i)
+			}
+		}
+	}
+	if c.Routing.LongContextTokens < 0 {
+		return fmt.Errorf("routing.long_context_tokens must not be negative")
+	}
+
+	switch c.Terminal.Keymap {
+	case "", "default", "vim":
+	default:
+		return fmt.Errorf("terminal.keymap must be \"default\" or \"vim\", got %q", c.Terminal.Keymap)
+	}
+	switch c.Speech.Backend {
+	case "", "openai":
+	case "deepgram":
+		if c.Speech.Key == "" {
+			return fmt.Errorf("speech.key is required by the deepgram backend")
+		}
+	default:
+		return fmt.Errorf("speech.backend must be openai or deepgram, got %q", c.Speech.Backend)
+	}
+	if c.Speech.Pause < 0 || c.Speech.Preroll < 0 || c.Speech.Postroll < 0 {
+		return fmt.Errorf("speech.pause, speech.preroll and speech.postroll must not be negative")
+	}
+	if c.Speech.Threshold < 0 {
+		return fmt.Errorf("speech.threshold must not be negative")
+	}
+	if c.Speech.Sensitivity < 0 || c.Speech.Sensitivity > 1 {
+		return fmt.Errorf("speech.sensitivity must be between 0 and 1")
+	}
+	switch c.Voice.Backend {
+	case "", "auto", "say", "espeak", "openai":
+	case "elevenlabs":
+		if c.Voice.Key == "" {
+			return fmt.Errorf("voice.key is required by the elevenlabs backend")
+		}
+	default:
+		return fmt.Errorf("voice.backend must be auto, say, espeak, openai or elevenlabs, got %q", c.Voice.Backend)
+	}
+	if c.Voice.Rate < 0 {
+		return fmt.Errorf("voice.rate must not be negative")
+	}
+	switch c.Terminal.Notify.Method {
+	case "", "auto", "bell", "osc777", "system", "off":
+	default:
+		return fmt.Errorf("terminal.notify.method must be auto, bell, osc777, system or off, got %q", c.Terminal.Notify.Method)
+	}
+	if c.Terminal.Notify.After < 0 {
+		return fmt.Errorf("terminal.notify.after must not be negative")
+	}
+
 	return nil
 }
 
@@ -152,3 +798,14 @@ func (c *Config) GetEffectiveTemperature(providerName string) float64 {
 	return c.DefaultTemperature
 }
 
+// StreamingEnabled reports whether responses from the given provider should be streamed.
+// Streaming must be enabled globally and not disabled for the provider.
+func (c *Config) StreamingEnabled(providerName string) bool {
+	if !c.Streaming {
+		return false
+	}
+	if provider, ok := c.Providers[providerName]; ok && provider.Stream != nil {
+		return *provider.Stream
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InstructionFiles are the project instruction files looked up in each directory,
+// in order of preference
+var InstructionFiles = []string{"MAGIKARP.md", filepath.Join(".magikarp", "instructions.md")}
+
+// maxInstructionBytes bounds how much of an instruction file is added to the system prompt
+const maxInstructionBytes = 64 * 1024
+
+// FindInstructions searches dir and its parents for a project instruction file and
+// returns its path, or "" when there is none
+func FindInstructions(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		for _, name := range InstructionFiles {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadInstructions appends the project instruction file found from the working
+// directory to the system prompt. It can be called again after the file changes;
+// the previously loaded instructions are replaced.
+func (c *Config) LoadInstructions() error {
+	if !c.instructionsLoaded {
+		c.baseSystem = c.System
+		c.instructionsLoaded = true
+	}
+	c.System = c.baseSystem
+	c.InstructionsPath = ""
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	path := FindInstructions(wd)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	content := strings.TrimSpace(string(data))
+	if len(content) > maxInstructionBytes {
+		content = content[:maxInstructionBytes] + "\n[truncated]"
+	}
+	c.InstructionsPath = path
+	if content == "" {
+		return nil
+	}
+
+	section := fmt.Sprintf("Project instructions from %s:\n%s\n", filepath.Base(path), content)
+	if base := strings.TrimRight(c.System, "\n"); base != "" {
+		section = base + "\n\n" + section
+	}
+	c.System = section
+	return nil
+}
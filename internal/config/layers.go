@@ -0,0 +1,299 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaults is the lowest configuration layer, so magikarp starts without any config file
+//
+//go:embed defaults.yaml
+var defaults []byte
+
+// starter is written to ~/.magikarp/config.yaml on first run as a place to start
+// editing the configuration
+//
+//go:embed starter.yaml
+var starter []byte
+
+// Configuration files, looked up in this order; later ones take precedence
+const (
+	// ProjectConfigFile is the per-project configuration, found in the working directory
+	// or its parents
+	ProjectConfigFile = ".magikarp.yaml"
+	// LocalConfigFile in the working directory is read between the global and the
+	// project configuration
+	LocalConfigFile = "config.yaml"
+)
+
+// Settings from the command line, which take precedence over every file
+var (
+	flagFile   string
+	flagValues []string
+)
+
+// SetCommandLine records the --config file and the --set key=value settings of the
+// command line for Load
+func SetCommandLine(file string, values []string) {
+	flagFile, flagValues = file, values
+}
+
+// Load reads the configuration in layers, each overriding the ones before it:
+//
+//  1. the built-in defaults
+//  2. ~/.magikarp/config.yaml
+//  3. config.yaml in the working directory
+//  4. .magikarp.yaml in the working directory or its nearest parent
+//  5. the file of --config, or of MAGIKARP_CONFIG
+//  6. MAGIKARP_MODEL, which sets default_model
+//  7. --set key=value settings, such as --set tools.enabled=false
+//
+// Mappings are merged key by key; lists and scalars replace those of earlier layers,
+// and a null value removes the key.
+func Load() (*Config, error) {
+	return LoadConfig(commandLineFile())
+}
+
+// commandLineFile returns the file of --config, or of MAGIKARP_CONFIG
+func commandLineFile() string {
+	if flagFile != "" {
+		return flagFile
+	}
+	return os.Getenv("MAGIKARP_CONFIG")
+}
+
+// savePath returns the file settings are saved to: the one of files that takes
+// precedence, or the global one when there are none
+func savePath(files []string) string {
+	if len(files) > 0 {
+		return files[len(files)-1]
+	}
+	return GetDefaultConfigPath()
+}
+
+// ConfigFiles returns the configuration files that exist, from lowest to highest
+// precedence. explicit, when set, is the last one and must exist.
+func ConfigFiles(explicit string) ([]string, error) {
+	var files []string
+	if path := GetDefaultConfigPath(); path != "" {
+		files = append(files, path)
+	}
+	files = append(files, LocalConfigFile)
+	if wd, err := os.Getwd(); err == nil {
+		if path := findUp(wd, ProjectConfigFile); path != "" {
+			files = append(files, path)
+		}
+	}
+
+	var existing []string
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			existing = append(existing, path)
+		}
+	}
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		existing = append(existing, explicit)
+	}
+	return existing, nil
+}
+
+// CreateStarter writes a starter configuration to ~/.magikarp/config.yaml when no config
+// file exists, and returns its path; "" when there is a config file already. Magikarp
+// runs on the built-in defaults either way.
+func CreateStarter() (string, error) {
+	files, err := ConfigFiles(commandLineFile())
+	if err != nil || len(files) > 0 {
+		return "", err
+	}
+	if _, err := os.UserHomeDir(); err != nil {
+		return "", nil // nowhere to put it
+	}
+	path := GetDefaultConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// O_EXCL so a file created in the meantime is never overwritten
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create starter config: %w", err)
+	}
+	if _, err := f.Write(starter); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write starter config: %w", err)
+	}
+	return path, f.Close()
+}
+
+// findUp returns the path of name in dir or its nearest parent, or ""
+func findUp(dir, name string) string {
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mergeLayers reads the defaults, files and overrides into one YAML mapping. Nodes
+// rather than decoded values are merged so that scalars keep their types, such as a
+// date-like api_version staying a string.
+func mergeLayers(files []string, overrides map[string]string) (*yaml.Node, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode}
+	if err := mergeYAML(merged, defaults); err != nil {
+		return nil, fmt.Errorf("built-in defaults: %w", err)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := mergeYAML(merged, data); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	for key, value := range overrides {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+			return nil, fmt.Errorf("setting %s: %w", key, err)
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		if len(doc.Content) > 0 {
+			node = doc.Content[0]
+		}
+		setPath(merged, strings.Split(key, "."), node)
+	}
+	return merged, nil
+}
+
+// builtinProviders returns the providers of the built-in defaults that none of files
+// and overrides mention, so that a missing key is not worth a warning for them
+func builtinProviders(files []string, overrides map[string]string) map[string]bool {
+	builtin := map[string]bool{}
+	for _, name := range providerNames(defaults) {
+		builtin[name] = true
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, name := range providerNames(data) {
+			delete(builtin, name)
+		}
+	}
+	for key := range overrides {
+		if keys := strings.Split(key, "."); keys[0] == "providers" {
+			if len(keys) == 1 {
+				return map[string]bool{}
+			}
+			delete(builtin, keys[1])
+		}
+	}
+	return builtin
+}
+
+// providerNames returns the keys of the providers mapping in the YAML document data
+func providerNames(data []byte) []string {
+	var doc struct {
+		Providers map[string]yaml.Node `yaml:"providers"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(doc.Providers))
+	for name := range doc.Providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// mergeYAML merges the YAML mapping in data into dst
+func mergeYAML(dst *yaml.Node, data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // an empty file
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("not a YAML mapping")
+	}
+	merge(dst, doc.Content[0])
+	return nil
+}
+
+// merge overrides the keys of the mapping dst with those of src, merging nested
+// mappings and removing the keys src sets to null
+func merge(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := indexOf(dst, key.Value)
+		switch {
+		case value.Tag == "!!null":
+			if j >= 0 {
+				dst.Content = append(dst.Content[:j], dst.Content[j+2:]...)
+			}
+		case j < 0:
+			dst.Content = append(dst.Content, key, value)
+		case value.Kind == yaml.MappingNode && dst.Content[j+1].Kind == yaml.MappingNode:
+			merge(dst.Content[j+1], value)
+		default:
+			dst.Content[j+1] = value
+		}
+	}
+}
+
+// indexOf returns the index of key in the mapping m, or -1
+func indexOf(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setPath sets the value at keys below the mapping m, creating the mappings on the way
+func setPath(m *yaml.Node, keys []string, value *yaml.Node) {
+	for _, key := range keys[:len(keys)-1] {
+		j := indexOf(m, key)
+		if j < 0 || m.Content[j+1].Kind != yaml.MappingNode {
+			sub := &yaml.Node{Kind: yaml.MappingNode}
+			merge(m, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: key}, sub}})
+			j = indexOf(m, key)
+		}
+		m = m.Content[j+1]
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: keys[len(keys)-1]}
+	merge(m, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}})
+}
+
+// overrides returns the settings of MAGIKARP_MODEL and --set, in order of precedence
+func overrides() (map[string]string, error) {
+	values := map[string]string{}
+	if model := os.Getenv("MAGIKARP_MODEL"); model != "" {
+		values["default_model"] = model
+	}
+	for _, setting := range flagValues {
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("--set %s: expected key=value", setting)
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, nil
+}
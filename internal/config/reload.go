@@ -0,0 +1,109 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Reloadable are the settings a running session takes over when the config files
+// change; changes to the others take effect after a restart. The tools and terminal
+// sections are compared key by key, as most of their settings are applied at startup.
+var Reloadable = []string{
+	"system",
+	"default_temperature",
+	"fallback_models",
+	"max_history",
+	"streaming",
+	"context",
+	"thinking",
+	"retry",
+	"routing",
+	"providers",
+	"models",
+	"redaction",
+	"tools.enabled",
+	"tools.output",
+	"tools.max_iterations",
+	"terminal.notify",
+}
+
+// splitSections are the sections whose keys are compared one by one
+var splitSections = []string{"tools", "terminal"}
+
+// IsReloadable reports whether a change of the setting key applies without a restart
+func IsReloadable(key string) bool {
+	return slices.Contains(Reloadable, key)
+}
+
+// Changes returns the settings that differ between c and next, as dotted keys such as
+// "system" or "tools.enabled"
+func (c *Config) Changes(next *Config) []string {
+	var keys []string
+	old, cur := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < old.NumField(); i++ {
+		key := yamlKey(old.Type().Field(i))
+		if key == "" {
+			continue
+		}
+		a, b := old.Field(i), cur.Field(i)
+		if !slices.Contains(splitSections, key) {
+			if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+				keys = append(keys, key)
+			}
+			continue
+		}
+		for j := 0; j < a.NumField(); j++ {
+			sub := yamlKey(a.Type().Field(j))
+			if sub != "" && !reflect.DeepEqual(a.Field(j).Interface(), b.Field(j).Interface()) {
+				keys = append(keys, key+"."+sub)
+			}
+		}
+	}
+	return keys
+}
+
+// Apply takes over the settings named by keys from next
+func (c *Config) Apply(next *Config, keys []string) {
+	dst, src := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for _, key := range keys {
+		if key == "system" {
+			// The prompt comes with the project instructions appended to it
+			c.System, c.baseSystem, c.instructionsLoaded = next.System, next.baseSystem, next.instructionsLoaded
+			c.InstructionsPath = next.InstructionsPath
+			continue
+		}
+		d, s := dst, src
+		for _, part := range strings.Split(key, ".") {
+			d, s = fieldByKey(d, part), fieldByKey(s, part)
+			if !d.IsValid() {
+				break
+			}
+		}
+		if d.IsValid() {
+			d.Set(s)
+		}
+	}
+}
+
+// fieldByKey returns the field of the struct v with the YAML key, or the zero Value
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlKey(v.Type().Field(i)) == key {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// yamlKey returns the YAML key of a struct field, or "" for fields not read from YAML
+func yamlKey(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LayerFiles returns the config files Load reads, from lowest to highest precedence
+func LayerFiles() ([]string, error) {
+	return ConfigFiles(commandLineFile())
+}
+
+// SettingsFile returns the config file Set writes to: the loaded one that takes
+// precedence, or the global one when none exists
+func SettingsFile() (string, error) {
+	files, err := LayerFiles()
+	if err != nil {
+		return "", err
+	}
+	return savePath(files), nil
+}
+
+// Lookup returns the setting at the dotted key as YAML, as merged from the layers of
+// Load; an empty key returns the whole configuration. Environment variables are not
+// expanded, so keys given as ${VAR} stay hidden.
+func Lookup(key string) (string, error) {
+	_, merged, err := layers(nil)
+	if err != nil {
+		return "", err
+	}
+	node := merged
+	if key != "" {
+		for _, part := range strings.Split(key, ".") {
+			if node.Kind != yaml.MappingNode {
+				node = nil
+				break
+			}
+			i := indexOf(node, part)
+			if i < 0 {
+				node = nil
+				break
+			}
+			node = node.Content[i+1]
+		}
+	}
+	if node == nil {
+		return "", fmt.Errorf("%s is not set", key)
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// Set writes a single value to the config file of SettingsFile, keeping its comments
+// and layout, and returns the file's path. The key must name a known setting, and the
+// configuration must stay valid with the value in place.
+func Set(key, value string) (string, error) {
+	if err := checkKey(reflect.TypeOf(Config{}), strings.Split(key, ".")); err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	if len(parsed.Content) > 0 && parsed.Content[0].Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("%s: only single values can be set; edit the file for lists and mappings", key)
+	}
+
+	files, merged, err := layers(map[string]string{key: value})
+	if err != nil {
+		return "", err
+	}
+	var config Config
+	if err := merged.Decode(&config); err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	if err := config.ValidateConfig(); err != nil {
+		return "", err
+	}
+	path := savePath(files)
+	return path, UpdateFile(path, map[string]string{key: value})
+}
+
+// layers merges the layers of Load, with extra settings on top
+func layers(extra map[string]string) ([]string, *yaml.Node, error) {
+	files, err := LayerFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := overrides()
+	if err != nil {
+		return nil, nil, err
+	}
+	maps.Copy(values, extra)
+	merged, err := mergeLayers(files, values)
+	return files, merged, err
+}
+
+// checkKey reports whether keys lead to a setting of the struct type t. Keys within
+// maps, such as provider names, may be anything.
+func checkKey(t reflect.Type, keys []string) error {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return checkKey(t.Elem(), keys)
+	case reflect.Map:
+		if len(keys) == 0 {
+			return fmt.Errorf("not a single value")
+		}
+		return checkKey(t.Elem(), keys[1:])
+	case reflect.Struct:
+		if len(keys) == 0 {
+			return fmt.Errorf("not a single value")
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if key := yamlKey(t.Field(i)); key != "" && key == keys[0] {
+				return checkKey(t.Field(i).Type, keys[1:])
+			}
+		}
+		return fmt.Errorf("unknown setting %q", keys[0])
+	}
+	if len(keys) > 0 {
+		return fmt.Errorf("%q is not a mapping", keys[0])
+	}
+	return nil
+}
+
+// Keys returns the dotted keys of the settings of conf that hold a value or a list,
+// such as tools.enabled or providers.openai.temperature, sorted. The keys within maps
+// are those conf sets.
+func Keys(conf *Config) []string {
+	var keys []string
+	collectKeys(reflect.ValueOf(conf).Elem(), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// collectKeys adds the keys of the settings in v, found at prefix, to keys
+func collectKeys(v reflect.Value, prefix string, keys *[]string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			if v.IsNil() {
+				v = reflect.New(v.Type().Elem())
+			}
+			collectKeys(v.Elem(), prefix, keys)
+			return
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if key := yamlKey(v.Type().Field(i)); key != "" {
+				collectKeys(v.Field(i), join(key), keys)
+			}
+		}
+		return
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			collectKeys(iter.Value(), join(fmt.Sprint(iter.Key().Interface())), keys)
+		}
+		return
+	}
+	*keys = append(*keys, prefix)
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateFile sets scalar values in the config file at path, keeping its comments and
+// layout. Keys are dotted paths such as "tools.enabled"; missing mappings are created.
+// Values are written as plain YAML scalars, so they keep their type when reloaded.
+// A missing file is created.
+func UpdateFile(path string, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		data, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file is not a YAML mapping")
+	}
+
+	out, ok := patchInPlace(data, doc.Content[0], values)
+	if !ok {
+		// Re-encoding loses blank lines, so it is only used when keys must be added
+		for key, value := range values {
+			if err := setScalar(doc.Content[0], strings.Split(key, "."), value); err != nil {
+				return fmt.Errorf("setting %s: %w", key, err)
+			}
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("failed to encode config file: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		out = buf.Bytes()
+	}
+
+	// Write through a temporary file so a failed write never truncates the config
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		_ = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// patchInPlace replaces existing plain scalar values directly in the source text so
+// the rest of the file is untouched. ok is false when a key is missing or a value
+// cannot be written as a plain scalar.
+func patchInPlace(data []byte, root *yaml.Node, values map[string]string) ([]byte, bool) {
+	lines := strings.Split(string(data), "\n")
+	for key, value := range values {
+		if value == "" || strings.TrimSpace(value) != value || strings.ContainsAny(value, ":#{}[],&*!|>'\"%@`\n") {
+			return nil, false
+		}
+		node := lookup(root, strings.Split(key, "."))
+		if node == nil || node.Kind != yaml.ScalarNode || node.Style != 0 || node.Line < 1 || node.Line > len(lines) {
+			return nil, false
+		}
+		line := lines[node.Line-1]
+		col := node.Column - 1
+		if col < 0 || !strings.HasPrefix(line[col:], node.Value) {
+			return nil, false
+		}
+		lines[node.Line-1] = line[:col] + value + line[col+len(node.Value):]
+	}
+	return []byte(strings.Join(lines, "\n")), true
+}
+
+// lookup returns the value node at keys below the mapping node m, or nil
+func lookup(m *yaml.Node, keys []string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != keys[0] {
+			continue
+		}
+		if len(keys) == 1 {
+			return m.Content[i+1]
+		}
+		if m.Content[i+1].Kind != yaml.MappingNode {
+			return nil
+		}
+		return lookup(m.Content[i+1], keys[1:])
+	}
+	return nil
+}
+
+// setScalar sets the value at keys below the mapping node m
+func setScalar(m *yaml.Node, keys []string, value string) error {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != keys[0] {
+			continue
+		}
+		node := m.Content[i+1]
+		if len(keys) == 1 {
+			if node.Kind != yaml.ScalarNode {
+				return fmt.Errorf("not a scalar value")
+			}
+			node.Value = value
+			node.Tag = ""
+			node.Style = 0
+			return nil
+		}
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", keys[0])
+		}
+		return setScalar(node, keys[1:], value)
+	}
+
+	// Key not present: append it, creating intermediate mappings
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: keys[0]}
+	if len(keys) == 1 {
+		m.Content = append(m.Content, key, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		return nil
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, key, child)
+	return setScalar(child, keys[1:], value)
+}
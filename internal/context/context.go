@@ -0,0 +1,167 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"github.com/pprunty/magikarp/internal/tokens"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Defaults used when the context section of config.yaml is unset
+const (
+	DefaultThreshold  = 0.8
+	DefaultKeepRecent = 4
+	DefaultWindow     = models.DefaultContextWindow
+)
+
+// SummaryPrefix marks the user message that carries a summary of earlier exchanges
+const SummaryPrefix = "[Summary of earlier conversation]"
+
+// Options controls when and how history is compressed
+type Options struct {
+	// Window is the model's context window in tokens; zero looks it up with WindowFor
+	Window int
+	// Threshold is the fraction of the window at which compression starts
+	Threshold float64
+	// KeepRecent is the number of most recent exchanges kept verbatim
+	KeepRecent int
+}
+
+// withDefaults fills unset options for model
+func (o Options) withDefaults(model string) Options {
+	if o.Window <= 0 {
+		o.Window = WindowFor(model)
+	}
+	if o.Threshold <= 0 || o.Threshold > 1 {
+		o.Threshold = DefaultThreshold
+	}
+	if o.KeepRecent <= 0 {
+		o.KeepRecent = DefaultKeepRecent
+	}
+	return o
+}
+
+// WindowFor returns the context window of model from the models table
+func WindowFor(model string) int {
+	return models.Lookup(model).ContextWindow
+}
+
+// EstimateTokens approximates the token count of text at roughly four characters per
+// token, for when the model is not known
+func EstimateTokens(text string) int {
+	return tokens.Estimate(text)
+}
+
+// EstimateMessages approximates the token count of a list of messages for when the
+// model is not known
+func EstimateMessages(messages []providers.ChatMessage) int {
+	return tokens.CountMessages("", messages)
+}
+
+// NeedsCompression reports whether messages, plus the pending prompt, cross the
+// compression threshold for model
+func NeedsCompression(model string, messages []providers.ChatMessage, pending string, opts Options) bool {
+	opts = opts.withDefaults(model)
+	if len(messages) <= opts.KeepRecent*2 {
+		return false
+	}
+	return UsedFraction(model, messages, pending, opts) >= opts.Threshold
+}
+
+// UsedFraction returns the share of model's context window taken by messages and the
+// pending prompt, counted with the model's tokenizer when available
+func UsedFraction(model string, messages []providers.ChatMessage, pending string, opts Options) float64 {
+	opts = opts.withDefaults(model)
+	used := tokens.CountMessages(model, messages) + tokens.Count(model, pending)
+	return float64(used) / float64(opts.Window)
+}
+
+// Result describes a compressed history
+type Result struct {
+	// Messages is the new history: the summary exchange followed by the recent messages
+	Messages []providers.ChatMessage
+	Summary  string
+	// Replaced is the number of original messages folded into the summary
+	Replaced int
+}
+
+// Compress summarises all but the most recent exchanges of history with p and returns
+// the shortened history. history must consist of alternating user/assistant messages.
+func Compress(ctx gocontext.Context, p providers.Provider, model string, history []providers.ChatMessage, opts Options) (result *Result, err error) {
+	ctx, span := telemetry.Tracer().Start(ctx, "compress", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("magikarp.compress.messages", len(history)),
+	))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.Int("magikarp.compress.replaced", result.Replaced))
+		}
+		telemetry.End(span, err)
+	}()
+	opts = opts.withDefaults(model)
+
+	cut := len(history) - opts.KeepRecent*2
+	if cut%2 != 0 {
+		cut-- // never split a user/assistant exchange
+	}
+	if cut <= 0 {
+		return &Result{Messages: history}, nil
+	}
+
+	prompt := []providers.ChatMessage{
+		{Role: providers.RoleSystem, Content: summarySystemPrompt},
+		{Role: providers.RoleUser, Content: transcript(history[:cut])},
+	}
+	replies, _, err := p.Chat(ctx, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing conversation: %w", err)
+	}
+
+	var parts []string
+	for _, reply := range replies {
+		if strings.TrimSpace(reply.Content) != "" {
+			parts = append(parts, strings.TrimSpace(reply.Content))
+		}
+	}
+	summary := strings.Join(parts, "\n")
+	if summary == "" {
+		return nil, fmt.Errorf("summarizing conversation: model returned an empty summary")
+	}
+
+	messages := append(SummaryMessages(summary), history[cut:]...)
+	return &Result{Messages: messages, Summary: summary, Replaced: cut}, nil
+}
+
+// SummaryMessages returns the exchange that carries summary at the start of a history.
+// A user/assistant pair keeps the roles alternating for providers that require it.
+func SummaryMessages(summary string) []providers.ChatMessage {
+	return []providers.ChatMessage{
+		{Role: providers.RoleUser, Content: SummaryPrefix + "\n" + summary},
+		{Role: providers.RoleAssistant, Content: "Understood. I'll use this summary as context for the rest of our conversation."},
+	}
+}
+
+const summarySystemPrompt = `You compress conversations between a user and a coding assistant.
+Write a concise summary of the transcript that preserves everything needed to continue the conversation:
+the user's goals, decisions made, important facts, file paths, commands, code identifiers and open questions.
+Use short bullet points. Do not add commentary or answer any questions yourself.`
+
+// transcript renders messages as plain text for the summarization request
+func transcript(messages []providers.ChatMessage) string {
+	var b strings.Builder
+	b.WriteString("Summarize this conversation:\n\n")
+	for _, msg := range messages {
+		role := "User"
+		if msg.Role == providers.RoleAssistant {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", role, msg.Content)
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+package loader
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	convctx "github.com/pprunty/magikarp/internal/context"
+)
+
+// piece is a paragraph, or part of one, that fits in a chunk
+type piece struct {
+	text        string
+	first, last int // line numbers, 1-based
+	// paragraph is set on the first piece of a paragraph
+	paragraph bool
+}
+
+// chunk splits sections into chunks of about ChunkTokens, breaking between
+// paragraphs where possible and never across sections
+func chunk(sections []section) []Chunk {
+	maxChars := ChunkTokens * 4
+	var chunks []Chunk
+	for _, s := range sections {
+		var cur strings.Builder
+		first, last := 0, 0
+		flush := func() {
+			text := strings.TrimSpace(cur.String())
+			if text != "" {
+				chunks = append(chunks, Chunk{
+					Index:    len(chunks),
+					Location: location(s.page, first, last),
+					Text:     text,
+					Tokens:   convctx.EstimateTokens(text),
+				})
+			}
+			cur.Reset()
+		}
+		for _, p := range pieces(s.lines, maxChars) {
+			sep := "\n"
+			if p.paragraph {
+				sep = "\n\n"
+			}
+			if cur.Len() > 0 && cur.Len()+len(sep)+len(p.text) > maxChars {
+				flush()
+			}
+			if cur.Len() == 0 {
+				first = p.first
+			} else {
+				cur.WriteString(sep)
+			}
+			cur.WriteString(p.text)
+			last = p.last
+		}
+		flush()
+	}
+	return chunks
+}
+
+// pieces groups lines into paragraphs, splitting those longer than maxChars into
+// lines and overlong lines at spaces
+func pieces(lines []string, maxChars int) []piece {
+	var out []piece
+	start := -1
+	emit := func(end int) {
+		para := lines[start:end]
+		if text := strings.Join(para, "\n"); len(text) <= maxChars {
+			out = append(out, piece{text, start + 1, end, true})
+			return
+		}
+		for i, line := range para {
+			n := start + i + 1
+			for j, part := range splitLine(line, maxChars) {
+				out = append(out, piece{part, n, n, i == 0 && j == 0})
+			}
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+		switch {
+		case lines[i] == "" && start >= 0:
+			emit(i)
+			start = -1
+		case lines[i] != "" && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		emit(len(lines))
+	}
+	return out
+}
+
+// splitLine cuts line into parts of at most maxChars bytes, at a space when there
+// is one in the second half of the part
+func splitLine(line string, maxChars int) []string {
+	var parts []string
+	for len(line) > maxChars {
+		cut := maxChars
+		for !isRuneStart(line, cut) {
+			cut--
+		}
+		if i := strings.LastIndexByte(line[:cut], ' '); i > maxChars/2 {
+			cut = i
+		}
+		parts = append(parts, line[:cut])
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	return append(parts, line)
+}
+
+func isRuneStart(s string, i int) bool {
+	return i <= 0 || i >= len(s) || s[i]&0xC0 != 0x80
+}
+
+// location describes the lines first to last of a section
+func location(page, first, last int) string {
+	switch {
+	case page > 0:
+		return fmt.Sprintf("page %d", page)
+	case first == last:
+		return fmt.Sprintf("line %d", first)
+	default:
+		return fmt.Sprintf("lines %d-%d", first, last)
+	}
+}
+
+// stopWords are left out when matching a prompt against chunks
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true,
+	"you": true, "all": true, "can": true, "was": true, "has": true, "have": true,
+	"this": true, "that": true, "with": true, "from": true, "what": true, "which": true,
+	"does": true, "how": true, "why": true, "when": true, "where": true, "who": true,
+	"about": true, "into": true, "their": true, "there": true, "these": true, "those": true,
+	"them": true, "they": true, "will": true, "would": true, "should": true, "could": true,
+	"please": true, "tell": true, "explain": true, "file": true, "document": true,
+}
+
+// words returns the lower-case words of text that are worth matching
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, w := range fields {
+		if len(w) >= 3 && !stopWords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Select returns the chunks of d to send with query within a budget of tokens, in
+// document order. Documents that fit are sent whole; otherwise chunks are ranked by
+// how often they use the words of query, weighted by how rare each word is in the
+// document, and the opening chunks fill what is left of the budget.
+func Select(d *Document, query string, budget int) []Chunk {
+	if d.Tokens() <= budget {
+		return d.Chunks
+	}
+
+	terms := map[string]bool{}
+	for _, w := range words(query) {
+		terms[w] = true
+	}
+	counts := make([]map[string]int, len(d.Chunks))
+	df := map[string]int{}
+	for i, c := range d.Chunks {
+		counts[i] = map[string]int{}
+		for _, w := range words(c.Text) {
+			if terms[w] {
+				if counts[i][w] == 0 {
+					df[w]++
+				}
+				counts[i][w]++
+			}
+		}
+	}
+
+	n := float64(len(d.Chunks))
+	scores := make([]float64, len(d.Chunks))
+	for i := range d.Chunks {
+		for w, tf := range counts[i] {
+			idf := math.Log(1 + n/float64(df[w]))
+			scores[i] += idf * float64(tf) / (float64(tf) + 1.2)
+		}
+	}
+
+	order := make([]int, len(d.Chunks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	var picked []Chunk
+	used := 0
+	for _, i := range order {
+		// The best chunk is always sent, even when the budget is smaller than a chunk
+		if c := d.Chunks[i]; used+c.Tokens <= budget || len(picked) == 0 {
+			picked = append(picked, c)
+			used += c.Tokens
+		}
+	}
+	sort.Slice(picked, func(a, b int) bool { return picked[a].Index < picked[b].Index })
+	return picked
+}
@@ -0,0 +1,67 @@
+package loader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readDocx extracts the paragraphs of a Word document, one line each
+func readDocx(path string) ([]section, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Word document: %w", err)
+	}
+	defer zr.Close()
+
+	f, err := zr.Open("word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Word document: %w", err)
+	}
+	defer f.Close()
+
+	var lines []string
+	var line strings.Builder
+	inText := false
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Word document: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				line.WriteByte('\t')
+			case "br", "cr":
+				lines = append(lines, line.String())
+				line.Reset()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				// A blank line after each paragraph lets chunking split between them
+				lines = append(lines, line.String(), "")
+				line.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				line.Write(t)
+			}
+		}
+	}
+	if line.Len() > 0 {
+		lines = append(lines, line.String())
+	}
+	return []section{{lines: lines}}, nil
+}
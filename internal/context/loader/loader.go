@@ -0,0 +1,143 @@
+// Package loader extracts the text of documents attached to a prompt (PDF, Word and
+// large text files), splits it into chunks and selects the chunks worth sending.
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFileBytes is the largest document that is loaded
+	MaxFileBytes = 50 * 1024 * 1024
+	// ChunkTokens is the approximate size of a chunk
+	ChunkTokens = 400
+)
+
+// Document is the extracted text of a file, split into chunks
+type Document struct {
+	Path string
+	// Kind is "pdf", "docx" or "text"
+	Kind string
+	// Pages is the number of pages of a PDF
+	Pages  int
+	Chunks []Chunk
+}
+
+// Chunk is a contiguous piece of a document
+type Chunk struct {
+	// Index is the position of the chunk in the document
+	Index int
+	// Location describes where the chunk is, e.g. "page 3" or "lines 120-161"
+	Location string
+	Text     string
+	Tokens   int
+}
+
+// section is a part of a document that chunks never span, such as a PDF page
+type section struct {
+	// page is the PDF page number, zero for other documents
+	page  int
+	lines []string
+}
+
+// IsDocument reports whether path has the extension of a binary document format
+// that Load can extract text from
+func IsDocument(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf", ".docx":
+		return true
+	}
+	return false
+}
+
+// Load extracts the text of the PDF, Word document or text file at path and splits
+// it into chunks
+func Load(path string) (*Document, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file")
+	}
+	if info.Size() > MaxFileBytes {
+		return nil, fmt.Errorf("file is larger than %d MB", MaxFileBytes/1024/1024)
+	}
+
+	doc := &Document{Path: path}
+	var sections []section
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		doc.Kind = "pdf"
+		sections, err = readPDF(path)
+		doc.Pages = len(sections)
+	case ".docx":
+		doc.Kind = "docx"
+		sections, err = readDocx(path)
+	default:
+		doc.Kind = "text"
+		sections, err = readText(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Chunks = chunk(sections)
+	if len(doc.Chunks) == 0 {
+		return nil, fmt.Errorf("no text found in %s", filepath.Base(path))
+	}
+	return doc, nil
+}
+
+// Tokens returns the approximate size of the whole document
+func (d *Document) Tokens() int {
+	total := 0
+	for _, c := range d.Chunks {
+		total += c.Tokens
+	}
+	return total
+}
+
+// readText reads a UTF-8 text file
+func readText(path string) ([]section, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, fmt.Errorf("binary file")
+	}
+	return []section{{lines: strings.Split(string(data), "\n")}}, nil
+}
+
+// Render formats chunks of d for a prompt, noting which parts were left out
+func (d *Document) Render(chunks []Chunk) string {
+	var b strings.Builder
+	attrs := fmt.Sprintf("path=%q", d.Path)
+	if d.Pages > 0 {
+		attrs += fmt.Sprintf(" pages=\"%d\"", d.Pages)
+	}
+	excerpt := len(chunks) < len(d.Chunks)
+	if excerpt {
+		attrs += fmt.Sprintf(" excerpt=\"%d of %d chunks\"", len(chunks), len(d.Chunks))
+	}
+	fmt.Fprintf(&b, "<document %s>\n", attrs)
+	for i, c := range chunks {
+		if excerpt && i > 0 && chunks[i-1].Index+1 < c.Index {
+			b.WriteString("…\n")
+		}
+		// Locations are only needed in excerpts and to mark PDF page breaks
+		if (excerpt || d.Pages > 0) && (i == 0 || chunks[i-1].Location != c.Location) {
+			fmt.Fprintf(&b, "[%s]\n", c.Location)
+		}
+		b.WriteString(c.Text)
+		b.WriteString("\n")
+	}
+	b.WriteString("</document>")
+	return b.String()
+}
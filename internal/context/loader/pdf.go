@@ -0,0 +1,33 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// readPDF extracts the text of every page of a PDF. Scanned pages without a text
+// layer come out empty.
+func readPDF(path string) ([]section, error) {
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	defer f.Close()
+
+	sections := make([]section, 0, r.NumPage())
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		s := section{page: i}
+		if !page.V.IsNull() {
+			text, err := page.GetPlainText(nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract text of page %d: %w", i, err)
+			}
+			s.lines = strings.Split(text, "\n")
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
@@ -0,0 +1,86 @@
+// Package debuglog keeps the recent provider requests, responses and tool calls of this
+// process in memory for the debug console (F12). Entries are redacted and shortened as
+// they are recorded, and nothing is written to disk.
+package debuglog
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pprunty/magikarp/internal/redact"
+)
+
+// MaxEntries bounds the entries kept; older ones are dropped
+const MaxEntries = 500
+
+// maxText bounds the text kept of each entry
+const maxText = 4000
+
+// Kind is what an entry records
+type Kind string
+
+const (
+	Request  Kind = "request"
+	Response Kind = "response"
+	Tool     Kind = "tool"
+)
+
+// Entry is a single event of the debug log
+type Entry struct {
+	Time time.Time
+	Kind Kind
+	// Source is the model of requests and responses, or the name of a tool
+	Source string
+	Text   string
+	// Duration is how long a response or tool call took
+	Duration time.Duration
+	IsError  bool
+}
+
+var log = struct {
+	sync.Mutex
+	entries []Entry
+}{}
+
+// Record stamps e with the time, masks credentials in its text and keeps it
+func Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Text = redact.String(e.Text)
+	e.Text = Shorten(e.Text, maxText)
+
+	log.Lock()
+	defer log.Unlock()
+	log.entries = append(log.entries, e)
+	if n := len(log.entries); n > MaxEntries {
+		log.entries = append([]Entry(nil), log.entries[n-MaxEntries:]...)
+	}
+}
+
+// Shorten cuts s to at most n bytes, at a character boundary, marking the cut with an
+// ellipsis
+func Shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
+
+// Entries returns the kept entries, oldest first
+func Entries() []Entry {
+	log.Lock()
+	defer log.Unlock()
+	return append([]Entry(nil), log.entries...)
+}
+
+// Clear drops every entry
+func Clear() {
+	log.Lock()
+	defer log.Unlock()
+	log.entries = nil
+}
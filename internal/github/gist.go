@@ -0,0 +1,39 @@
+package github
+
+import (
+	"context"
+	"net/http"
+)
+
+// Gist is a gist to create
+type Gist struct {
+	Description string
+	// Public gists are listed on the profile of their owner; secret ones are only
+	// visible to those with the link
+	Public bool
+	// Files maps file names to their contents
+	Files map[string]string
+}
+
+// CreateGist creates gist and returns its URL
+func (c *Client) CreateGist(ctx context.Context, gist Gist) (string, error) {
+	type file struct {
+		Content string `json:"content"`
+	}
+	body := struct {
+		Description string          `json:"description"`
+		Public      bool            `json:"public"`
+		Files       map[string]file `json:"files"`
+	}{Description: gist.Description, Public: gist.Public, Files: map[string]file{}}
+	for name, content := range gist.Files {
+		body.Files[name] = file{Content: content}
+	}
+
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/gists", body, &created); err != nil {
+		return "", err
+	}
+	return created.HTMLURL, nil
+}
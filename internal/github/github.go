@@ -0,0 +1,160 @@
+// Package github is a small client of the GitHub REST API, authenticated with the
+// configured token, GITHUB_TOKEN, GH_TOKEN or the token of the gh CLI.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// DefaultAPIURL is the API of github.com
+const DefaultAPIURL = "https://api.github.com"
+
+// requestTimeout bounds a single API request
+const requestTimeout = 30 * time.Second
+
+// ErrNoToken is returned when no GitHub token is configured
+var ErrNoToken = errors.New("no GitHub token: set github.token or GITHUB_TOKEN, or sign in with gh auth login")
+
+var (
+	mu         sync.RWMutex
+	configured config.GitHubConfig
+)
+
+// Configure sets the github section of config.yaml used by Default
+func Configure(conf config.GitHubConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	configured = conf
+}
+
+// Default returns a client for the configured GitHub account
+func Default() (*Client, error) {
+	mu.RLock()
+	conf := configured
+	mu.RUnlock()
+	return NewClient(conf)
+}
+
+// Client calls the GitHub REST API
+type Client struct {
+	token   string
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient returns a client authenticated with the token of conf, GITHUB_TOKEN,
+// GH_TOKEN or, failing those, gh auth token
+func NewClient(conf config.GitHubConfig) (*Client, error) {
+	token := Token(conf)
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	baseURL := conf.APIURL
+	if baseURL == "" {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{
+		token:   token,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// Token returns the GitHub token to use, or "" when none is available
+func Token(conf config.GitHubConfig) string {
+	if conf.Token != "" {
+		return conf.Token
+	}
+	for _, name := range []string{"GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return ""
+	}
+	out, err := exec.Command("gh", "auth", "token").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// APIError is an error response of the GitHub API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return "GitHub rejected the token (401): " + e.Message
+	case http.StatusForbidden, http.StatusNotFound:
+		return fmt.Sprintf("GitHub: %s (%d); check that the token has access", e.Message, e.StatusCode)
+	}
+	return fmt.Sprintf("GitHub: %s (%d)", e.Message, e.StatusCode)
+}
+
+// do sends a request with body encoded as JSON, if not nil, and decodes the response
+// into out, if not nil
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("GitHub: decoding the response: %w", err)
+	}
+	return nil
+}
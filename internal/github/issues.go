@@ -0,0 +1,113 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxPerPage is the largest page the API returns
+const maxPerPage = 100
+
+// User is the account that wrote an issue or comment
+type User struct {
+	Login string `json:"login"`
+}
+
+// Label is a label of an issue
+type Label struct {
+	Name string `json:"name"`
+}
+
+// Issue is an issue or, when PullRequest is set, a pull request
+type Issue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	Body        string    `json:"body"`
+	User        User      `json:"user"`
+	Labels      []Label   `json:"labels"`
+	Assignees   []User    `json:"assignees"`
+	Comments    int       `json:"comments"`
+	HTMLURL     string    `json:"html_url"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+// Comment is a comment on an issue or pull request
+type Comment struct {
+	User      User      `json:"user"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IssueFilter selects the issues ListIssues returns
+type IssueFilter struct {
+	// State is "open" (the default), "closed" or "all"
+	State string
+	// Labels only lists issues with all of these labels
+	Labels   []string
+	Assignee string
+	// Limit bounds the number of issues, at most 100
+	Limit int
+}
+
+// ListIssues lists the issues of repo, most recently updated first. Pull requests,
+// which the API lists among issues, are left out.
+func (c *Client) ListIssues(ctx context.Context, repo Repo, filter IssueFilter) ([]Issue, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > maxPerPage {
+		limit = maxPerPage
+	}
+	q := url.Values{}
+	q.Set("state", filter.State)
+	if filter.State == "" {
+		q.Set("state", "open")
+	}
+	q.Set("sort", "updated")
+	q.Set("per_page", fmt.Sprint(maxPerPage))
+	if len(filter.Labels) > 0 {
+		q.Set("labels", strings.Join(filter.Labels, ","))
+	}
+	if filter.Assignee != "" {
+		q.Set("assignee", filter.Assignee)
+	}
+
+	var page []Issue
+	if err := c.do(ctx, http.MethodGet, repo.path()+"/issues?"+q.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	issues := []Issue{}
+	for _, issue := range page {
+		if issue.PullRequest == nil && len(issues) < limit {
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// Issue fetches an issue or pull request by number
+func (c *Client) Issue(ctx context.Context, repo Repo, number int) (*Issue, error) {
+	var issue Issue
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/issues/%d", repo.path(), number), nil, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// IssueComments fetches the first limit comments of an issue or pull request
+func (c *Client) IssueComments(ctx context.Context, repo Repo, number, limit int) ([]Comment, error) {
+	if limit <= 0 || limit > maxPerPage {
+		limit = maxPerPage
+	}
+	var comments []Comment
+	path := fmt.Sprintf("%s/issues/%d/comments?per_page=%d", repo.path(), number, limit)
+	if err := c.do(ctx, http.MethodGet, path, nil, &comments); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
@@ -0,0 +1,34 @@
+package github
+
+import (
+	"context"
+	"net/http"
+)
+
+// NewPullRequest describes a pull request to open
+type NewPullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+	// Head is the branch with the changes; Base the branch they are merged into
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+// PullRequest is an opened pull request
+type PullRequest struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	Draft   bool   `json:"draft"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CreatePullRequest opens a pull request in repo
+func (c *Client) CreatePullRequest(ctx context.Context, repo Repo, pr NewPullRequest) (*PullRequest, error) {
+	var created PullRequest
+	if err := c.do(ctx, http.MethodPost, repo.path()+"/pulls", pr, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
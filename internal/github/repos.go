@@ -0,0 +1,66 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Repo names a repository
+type Repo struct {
+	Owner string
+	Name  string
+}
+
+func (r Repo) String() string {
+	return r.Owner + "/" + r.Name
+}
+
+// path returns the API path of the repository
+func (r Repo) path() string {
+	return "/repos/" + url.PathEscape(r.Owner) + "/" + url.PathEscape(r.Name)
+}
+
+// ParseRepo parses "owner/name"
+func ParseRepo(s string) (Repo, error) {
+	owner, name, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return Repo{}, fmt.Errorf("repository must be written as owner/name, got %q", s)
+	}
+	return Repo{Owner: owner, Name: strings.TrimSuffix(name, ".git")}, nil
+}
+
+// RepoFromRemote returns the repository a git remote URL points to, such as
+// git@github.com:owner/name.git or https://github.com/owner/name
+func RepoFromRemote(remote string) (Repo, bool) {
+	remote = strings.TrimSpace(remote)
+	var path string
+	if u, err := url.Parse(remote); err == nil && u.Host != "" {
+		path = u.Path
+	} else if _, rest, ok := strings.Cut(remote, ":"); ok && !strings.Contains(remote, "://") {
+		path = rest // scp-like syntax: git@host:owner/name.git
+	}
+	parts := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return Repo{}, false
+	}
+	return Repo{Owner: parts[len(parts)-2], Name: parts[len(parts)-1]}, true
+}
+
+// Repository describes a repository
+type Repository struct {
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+	HTMLURL       string `json:"html_url"`
+}
+
+// Repository fetches the repository, e.g. for its default branch
+func (c *Client) Repository(ctx context.Context, repo Repo) (*Repository, error) {
+	var r Repository
+	if err := c.do(ctx, http.MethodGet, repo.path(), nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
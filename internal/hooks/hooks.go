@@ -0,0 +1,183 @@
+// Package hooks runs the user's commands on lifecycle events configured in the hooks
+// section of config.yaml, e.g. to format files after edits, block tools or send
+// notifications. Each command gets the event as JSON on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// Lifecycle events
+const (
+	PreTool      = "pre_tool"
+	PostTool     = "post_tool"
+	PrePrompt    = "pre_prompt"
+	PostResponse = "post_response"
+	SessionEnd   = "session_end"
+)
+
+// DefaultTimeout bounds a hook command without a timeout of its own
+const DefaultTimeout = 30 * time.Second
+
+// outputWaitDelay is how long the output of a hook is still read after it exited
+const outputWaitDelay = 2 * time.Second
+
+// maxReasonBytes bounds the output of a blocking hook reported as the reason
+const maxReasonBytes = 4000
+
+// Event is the payload sent to hook commands; fields that do not apply to the event
+// are left out
+type Event struct {
+	Event   string                 `json:"event"`
+	Time    time.Time              `json:"time"`
+	Cwd     string                 `json:"cwd"`
+	Model   string                 `json:"model,omitempty"`
+	Tool    string                 `json:"tool,omitempty"`
+	Input   map[string]interface{} `json:"input,omitempty"`
+	Output  string                 `json:"output,omitempty"`
+	IsError bool                   `json:"is_error,omitempty"`
+	Prompt  string                 `json:"prompt,omitempty"`
+	// Response is the model's answer (post_response)
+	Response string `json:"response,omitempty"`
+	// Messages is the number of prompts answered in the session (session_end)
+	Messages int `json:"messages,omitempty"`
+}
+
+// BlockedError is returned when a pre_tool or pre_prompt hook fails, which blocks the
+// tool call or prompt
+type BlockedError struct {
+	Event   string
+	Command string
+	// Reason is the output of the command
+	Reason string
+}
+
+func (e *BlockedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("blocked by %s hook %q", e.Event, e.Command)
+	}
+	return fmt.Sprintf("blocked by %s hook: %s", e.Event, e.Reason)
+}
+
+var (
+	mu    sync.RWMutex
+	hooks = map[string][]config.Hook{}
+)
+
+// Configure sets the hooks from the hooks section of config.yaml
+func Configure(conf config.HooksConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	hooks = map[string][]config.Hook{
+		PreTool:      conf.PreTool,
+		PostTool:     conf.PostTool,
+		PrePrompt:    conf.PrePrompt,
+		PostResponse: conf.PostResponse,
+		SessionEnd:   conf.SessionEnd,
+	}
+}
+
+// ranKey marks in a context the events whose hooks already ran
+type ranKey struct{ event string }
+
+// WithRan returns a context in which Run skips the hooks of event, for callers that ran
+// them already and hand the work on to another path
+func WithRan(ctx context.Context, event string) context.Context {
+	return context.WithValue(ctx, ranKey{event}, true)
+}
+
+// Has reports whether any hook is configured for event
+func Has(event string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(hooks[event]) > 0
+}
+
+// Run runs the hooks of ev.Event in order. For pre_tool and pre_prompt the first
+// failing command stops the others and its BlockedError is returned; failures of other
+// hooks are returned once all of them ran.
+func Run(ctx context.Context, ev Event) error {
+	mu.RLock()
+	list := hooks[ev.Event]
+	mu.RUnlock()
+	if len(list) == 0 || ctx.Value(ranKey{ev.Event}) != nil {
+		return nil
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Cwd == "" {
+		ev.Cwd, _ = os.Getwd()
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	blocking := ev.Event == PreTool || ev.Event == PrePrompt
+	var failures []string
+	for _, h := range list {
+		if h.Tool != "" && ev.Tool != "" {
+			if ok, _ := path.Match(h.Tool, ev.Tool); !ok {
+				continue
+			}
+		}
+		output, err := run(ctx, h, ev.Event, payload)
+		if err == nil {
+			continue
+		}
+		if blocking {
+			return &BlockedError{Event: ev.Event, Command: h.Command, Reason: output}
+		}
+		failures = append(failures, fmt.Sprintf("%q: %v", h.Command, err))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s hooks failed: %s", ev.Event, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// run executes one hook with the payload on stdin and returns its combined output
+func run(ctx context.Context, h config.Hook, event string, payload []byte) (string, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(cmd.Environ(), "MAGIKARP_EVENT="+event)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	setProcessGroup(cmd)
+	// Children started in the background may keep the output open; stop reading it
+	// once the hook itself exited
+	cmd.WaitDelay = outputWaitDelay
+	err := cmd.Run()
+	if cmd.ProcessState != nil && cmd.ProcessState.Success() {
+		// The hook succeeded, even if it left children holding the output open
+		err = nil
+	} else if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	output := strings.TrimSpace(out.String())
+	if len(output) > maxReasonBytes {
+		output = output[:maxReasonBytes] + "..."
+	}
+	return output, err
+}
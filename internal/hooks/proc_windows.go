@@ -0,0 +1,8 @@
+//go:build windows
+
+package hooks
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows, where cancelling kills the hook process only
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,386 @@
+// Package index keeps a semantic index of the project's files: their text is split
+// into chunks, embedded and stored under ~/.magikarp/index so that the chunks most
+// related to a question can be found by meaning rather than by exact words.
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/context/loader"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+const (
+	// DirName is the directory inside ~/.magikarp that holds the indexes
+	DirName = "index"
+	// MaxFiles bounds the number of files indexed in a project
+	MaxFiles = 5000
+	// MaxFileBytes is the largest file indexed
+	MaxFileBytes = 256 * 1024
+	// batchSize is the number of chunks embedded per request
+	batchSize = 64
+)
+
+// skippedDirs are never indexed, along with hidden directories
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// Result is a chunk of a file found by Search
+type Result struct {
+	Path     string
+	Location string
+	Text     string
+	// Score is the cosine similarity between the chunk and the query
+	Score float64
+}
+
+// Stats describes the outcome of Update
+type Stats struct {
+	Files  int
+	Chunks int
+	// Embedded is the number of new or changed files that were embedded
+	Embedded int
+	// Removed is the number of files dropped because they no longer exist
+	Removed int
+}
+
+// Index is the semantic index of the files under a directory. Safe for concurrent use.
+type Index struct {
+	root string
+	path string
+	// embedder returns the embedder, which may not exist yet when the index is created
+	embedder func() (providers.Embedder, error)
+
+	mu   sync.Mutex
+	data *store
+}
+
+// New returns the index of root, stored in ~/.magikarp/index. Nothing is read or
+// embedded until the first Update or Search.
+func New(root string, e providers.Embedder) (*Index, error) {
+	return newIndex(root, func() (providers.Embedder, error) { return e, nil })
+}
+
+func newIndex(root string, embedder func() (providers.Embedder, error)) (*Index, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(root))
+	name := filepath.Base(root) + "-" + hex.EncodeToString(sum[:8]) + ".gob"
+	return &Index{
+		root:     root,
+		path:     filepath.Join(homeDir, ".magikarp", DirName, name),
+		embedder: embedder,
+	}, nil
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultIndex *Index
+)
+
+// Configure sets up the index of the working directory from the index section of
+// conf. The index stays disabled when no embeddings provider is configured. The
+// embedder is looked up in the provider registry on first use, so Configure may run
+// before orchestration.Init.
+func Configure(conf *config.Config) error {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultIndex = nil
+	name := conf.Index.Provider
+	if name == "" {
+		return nil
+	}
+	if _, ok := conf.Providers[name]; !ok {
+		return fmt.Errorf("index.provider: %s is not configured under providers", name)
+	}
+	ix, err := newIndex(".", func() (providers.Embedder, error) {
+		return orchestration.EmbedderFor(name)
+	})
+	if err != nil {
+		return fmt.Errorf("index: %w", err)
+	}
+	defaultIndex = ix
+	return nil
+}
+
+// Default returns the index set up by Configure, or nil when it is disabled
+func Default() *Index {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultIndex
+}
+
+// Root returns the indexed directory
+func (ix *Index) Root() string {
+	return ix.root
+}
+
+// Path returns the file the index is stored in
+func (ix *Index) Path() string {
+	return ix.path
+}
+
+// Update brings the index in line with the files under its root: new and changed files
+// are embedded and deleted ones dropped. When embedding fails part way, the files
+// embedded so far are kept.
+func (ix *Index) Update(ctx context.Context) (Stats, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	e, err := ix.embedder()
+	if err != nil {
+		return Stats{}, err
+	}
+	if ix.data == nil || ix.data.Model != e.EmbeddingModel() {
+		ix.data = loadStore(ix.path, e.EmbeddingModel())
+	}
+
+	var stats Stats
+	var changed []string
+	seen := map[string]bool{}
+	for _, f := range ix.files() {
+		seen[f.path] = true
+		if entry, ok := ix.data.Files[f.path]; ok && entry.Size == f.size && entry.ModTime.Equal(f.modTime) {
+			continue
+		}
+		changed = append(changed, f.path)
+	}
+	for path := range ix.data.Files {
+		if !seen[path] {
+			delete(ix.data.Files, path)
+			stats.Removed++
+		}
+	}
+
+	embedded, err := ix.embedFiles(ctx, e, changed)
+	stats.Embedded = embedded
+	for _, entry := range ix.data.Files {
+		if len(entry.Chunks) > 0 {
+			stats.Files++
+			stats.Chunks += len(entry.Chunks)
+		}
+	}
+	if len(changed) > 0 || stats.Removed > 0 {
+		if saveErr := ix.data.save(ix.path); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return stats, err
+}
+
+// fileInfo is a file found under the root
+type fileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// files lists the files to index, relative to the root, skipping hidden and dependency
+// directories, documents and large files
+func (ix *Index) files() []fileInfo {
+	var files []fileInfo
+	_ = filepath.WalkDir(ix.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != ix.root && (skippedDirs[name] || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(name, ".") || loader.IsDocument(name) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() == 0 || info.Size() > MaxFileBytes {
+			return nil
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, fileInfo{filepath.ToSlash(rel), info.Size(), info.ModTime()})
+		if len(files) >= MaxFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return files
+}
+
+// pendingFile is a file whose chunks are being embedded
+type pendingFile struct {
+	path  string
+	entry *fileEntry
+	left  int // chunks still to be embedded
+}
+
+// chunkSlot locates the chunk a text of a batch belongs to
+type chunkSlot struct {
+	file  *pendingFile
+	chunk int
+}
+
+// embedFiles chunks and embeds the files at paths, relative to the root, and stores
+// them in the index; it returns the number of files stored
+func (ix *Index) embedFiles(ctx context.Context, e providers.Embedder, paths []string) (int, error) {
+	var texts []string
+	var slots []chunkSlot
+	stored := 0
+	flush := func() error {
+		if len(texts) == 0 {
+			return nil
+		}
+		vectors, err := e.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed project files: %w", err)
+		}
+		for i, slot := range slots {
+			slot.file.entry.Chunks[slot.chunk].Vector = normalize(vectors[i])
+			slot.file.left--
+			if slot.file.left == 0 {
+				ix.data.Files[slot.file.path] = slot.file.entry
+				stored++
+			}
+		}
+		texts, slots = texts[:0], slots[:0]
+		return nil
+	}
+
+	for _, path := range paths {
+		abs := filepath.Join(ix.root, filepath.FromSlash(path))
+		info, err := os.Stat(abs)
+		if err != nil {
+			continue
+		}
+		entry := &fileEntry{Size: info.Size(), ModTime: info.ModTime()}
+		doc, err := loader.Load(abs)
+		if err != nil {
+			// Binary and empty files are remembered so they are not read again
+			ix.data.Files[path] = entry
+			continue
+		}
+		p := &pendingFile{path: path, entry: entry, left: len(doc.Chunks)}
+		for i, c := range doc.Chunks {
+			entry.Chunks = append(entry.Chunks, storedChunk{Location: c.Location, Text: c.Text})
+			// The path helps to match questions that name a file or package
+			texts = append(texts, fmt.Sprintf("%s (%s)\n%s", path, c.Location, c.Text))
+			slots = append(slots, chunkSlot{p, i})
+			if len(texts) == batchSize {
+				if err := flush(); err != nil {
+					return stored, err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stored, err
+	}
+	return stored, nil
+}
+
+// Search updates the index and returns the k chunks most similar to query, best first
+func (ix *Index) Search(ctx context.Context, query string, k int) ([]Result, error) {
+	if _, err := ix.Update(ctx); err != nil {
+		return nil, err
+	}
+	e, err := ix.embedder()
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := e.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	q := normalize(vectors[0])
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var results []Result
+	for path, entry := range ix.data.Files {
+		for _, c := range entry.Chunks {
+			if len(c.Vector) != len(q) {
+				continue
+			}
+			results = append(results, Result{Path: path, Location: c.Location, Text: c.Text, Score: dot(q, c.Vector)})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Path < results[j].Path
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Augment appends the k chunks most related to message to it, for sending to the
+// model as context. message is returned unchanged when nothing is found.
+func (ix *Index) Augment(ctx context.Context, message string, k int) (string, []Result, error) {
+	results, err := ix.Search(ctx, message, k)
+	if err != nil || len(results) == 0 {
+		return message, nil, err
+	}
+	var b strings.Builder
+	b.WriteString(message)
+	b.WriteString("\n\nPossibly relevant code from the project index:")
+	for _, r := range results {
+		fmt.Fprintf(&b, "\n\n<snippet path=%q location=%q>\n%s\n</snippet>", r.Path, r.Location, r.Text)
+	}
+	return b.String(), results, nil
+}
+
+// normalize scales v to unit length so that the dot product is the cosine similarity
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x * norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
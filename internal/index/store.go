@@ -0,0 +1,69 @@
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// store is the on-disk form of an index
+type store struct {
+	// Model is the embedding model of the vectors; a different model starts afresh
+	Model string
+	Files map[string]*fileEntry
+}
+
+// fileEntry holds the chunks of a file as it was when it was embedded
+type fileEntry struct {
+	Size    int64
+	ModTime time.Time
+	Chunks  []storedChunk
+}
+
+type storedChunk struct {
+	Location string
+	Text     string
+	// Vector is the embedding of the chunk, scaled to unit length
+	Vector []float32
+}
+
+// loadStore reads the index at path. A missing or unreadable index, or one built with
+// another model, is replaced by an empty one.
+func loadStore(path, model string) *store {
+	empty := &store{Model: model, Files: map[string]*fileEntry{}}
+	f, err := os.Open(path)
+	if err != nil {
+		return empty
+	}
+	defer f.Close()
+	var s store
+	if err := gob.NewDecoder(f).Decode(&s); err != nil || s.Model != model || s.Files == nil {
+		return empty
+	}
+	return &s
+}
+
+// save writes the index to path, replacing the previous file atomically
+func (s *store) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*")
+	if err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(s); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	return nil
+}
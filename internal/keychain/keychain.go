@@ -0,0 +1,76 @@
+// Package keychain keeps provider API keys in the operating system's credential store:
+// the macOS Keychain, the Secret Service (libsecret, e.g. GNOME Keyring or KWallet) on
+// Linux, and the Windows Credential Manager. Keys stored with "magikarp auth login"
+// fill in the ones the configuration leaves empty, so they need not be written to
+// config files or .env.
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/zalando/go-keyring"
+)
+
+// service is the name keys are stored under in the credential store
+const service = "magikarp"
+
+// ErrNotFound is returned when no key is stored for a provider
+var ErrNotFound = errors.New("no key stored")
+
+// keyless providers run locally and take no API key
+var keyless = map[string]bool{"ollama": true}
+
+// Get returns the key stored for provider
+func Get(provider string) (string, error) {
+	key, err := keyring.Get(service, provider)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the keychain: %w", err)
+	}
+	return key, nil
+}
+
+// Set stores the key of provider, replacing any stored before
+func Set(provider, key string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("the key is empty")
+	}
+	if err := keyring.Set(service, provider, key); err != nil {
+		return fmt.Errorf("writing to the keychain: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the key stored for provider
+func Delete(provider string) error {
+	err := keyring.Delete(service, provider)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("writing to the keychain: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns a copy of providers with the keys the configuration leaves empty
+// taken from the keychain. Keys set in the configuration or the environment win. A
+// keychain that cannot be read is treated as empty.
+func Resolve(providers map[string]config.Provider) map[string]config.Provider {
+	resolved := make(map[string]config.Provider, len(providers))
+	for name, p := range providers {
+		if p.Key == "" && !keyless[name] {
+			if key, err := Get(name); err == nil {
+				p.Key = key
+			}
+		}
+		resolved[name] = p
+	}
+	return resolved
+}
@@ -0,0 +1,239 @@
+// Package models describes the capabilities of the models Magikarp talks to: their
+// context window, whether they can call tools and read images, and their price. The
+// built-in table in models.yaml is extended by provider catalogs and overridden by the
+// models section of the config.
+package models
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultContextWindow is assumed for models of no known family
+const DefaultContextWindow = 32_000
+
+//go:embed models.yaml
+var builtinYAML []byte
+
+// Pricing is the cost in USD per million prompt and completion tokens
+type Pricing struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Info is what is known about a model
+type Info struct {
+	Model string
+	// Known is set when the model or its family is described anywhere
+	Known         bool
+	ContextWindow int
+	Tools         bool
+	Vision        bool
+	Thinking      bool
+	Pricing       Pricing
+	// Priced is set when the price is known; other models (e.g. local ones) are free
+	Priced bool
+	// MaxTokens and Stop limit the replies of the model; unset leaves the limits of
+	// its provider
+	MaxTokens int
+	Stop      []string
+}
+
+// Feature is a capability a request may need from its model
+type Feature string
+
+// ToolCalling is needed by requests that offer tools
+const ToolCalling Feature = "tool calling"
+
+// UnsupportedError reports a request that needs a feature its model lacks
+type UnsupportedError struct {
+	Model   string
+	Feature Feature
+}
+
+func (e *UnsupportedError) Error() string {
+	hint := "choose another model with /model"
+	if e.Feature == ToolCalling {
+		hint = "turn tools off with /tools or " + hint
+	}
+	return fmt.Sprintf("%s does not support %s; %s", e.Model, e.Feature, hint)
+}
+
+var (
+	mu sync.RWMutex
+	// builtin is the table of models.yaml, by name prefix
+	builtin = mustParse(builtinYAML)
+	// registered holds what provider catalogs report about their models, by exact name
+	registered = map[string]config.ModelConfig{}
+	// overrides is the models section of the config, by name prefix
+	overrides map[string]config.ModelConfig
+	// window is context.window, which overrides the context window of every model
+	// the models section does not set one for
+	window int
+)
+
+// mustParse reads the built-in table; it is embedded, so an error is a bug
+func mustParse(data []byte) map[string]config.ModelConfig {
+	var table map[string]config.ModelConfig
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		panic(fmt.Sprintf("models: invalid built-in table: %v", err))
+	}
+	return lowerKeys(table)
+}
+
+func lowerKeys(table map[string]config.ModelConfig) map[string]config.ModelConfig {
+	out := make(map[string]config.ModelConfig, len(table))
+	for name, m := range table {
+		out[strings.ToLower(name)] = m
+	}
+	return out
+}
+
+// Configure applies the models section and context.window of cfg
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	overrides = lowerKeys(cfg.Models)
+	window = cfg.Context.Window
+}
+
+// Register records what a provider reports about model, e.g. from its catalog. The
+// config still takes precedence.
+func Register(model string, m config.ModelConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered[strings.ToLower(model)] = m
+}
+
+// Lookup returns what is known about model. Each field comes from the most specific
+// source that sets it: the models section of the config, context.window, the
+// provider's catalog, then the built-in table, longer name prefixes first. Unknown
+// models are assumed to call tools, but neither to read images nor to think.
+func Lookup(model string) Info {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	name := strings.ToLower(model)
+	layers := matching(overrides, name)
+	if window > 0 {
+		layers = append(layers, config.ModelConfig{ContextWindow: window})
+	}
+	if m, ok := registered[name]; ok {
+		layers = append(layers, m)
+	}
+	layers = append(layers, matching(builtin, name)...)
+
+	info := Info{Model: model, Known: len(layers) > 0, Tools: true}
+	var tools, vision, thinking *bool
+	for _, m := range layers {
+		if info.ContextWindow == 0 {
+			info.ContextWindow = m.ContextWindow
+		}
+		if tools == nil {
+			tools = m.Tools
+		}
+		if vision == nil {
+			vision = m.Vision
+		}
+		if thinking == nil {
+			thinking = m.Thinking
+		}
+		if info.MaxTokens == 0 {
+			info.MaxTokens = m.MaxTokens
+		}
+		if info.Stop == nil {
+			info.Stop = m.Stop
+		}
+		if !info.Priced && m.Pricing != nil {
+			info.Pricing = Pricing{Prompt: m.Pricing.Prompt, Completion: m.Pricing.Completion}
+			info.Priced = true
+		}
+	}
+	if info.ContextWindow == 0 {
+		info.ContextWindow = DefaultContextWindow
+	}
+	if tools != nil {
+		info.Tools = *tools
+	}
+	if vision != nil {
+		info.Vision = *vision
+	}
+	if thinking != nil {
+		info.Thinking = *thinking
+	}
+	return info
+}
+
+// Require returns an *UnsupportedError when model lacks feature
+func Require(model string, feature Feature) error {
+	info := Lookup(model)
+	if feature == ToolCalling && !info.Tools {
+		return &UnsupportedError{Model: model, Feature: feature}
+	}
+	return nil
+}
+
+// matching returns the entries of table whose name is a prefix of model, longest
+// first. Models served through aggregators, such as "anthropic/claude-sonnet-4", also
+// match the entries of their own name.
+func matching(table map[string]config.ModelConfig, model string) []config.ModelConfig {
+	names := []string{model}
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		names = append(names, model[i+1:])
+	}
+	var keys []string
+	for key := range table {
+		for _, name := range names {
+			if strings.HasPrefix(name, key) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	out := make([]config.ModelConfig, len(keys))
+	for i, key := range keys {
+		out[i] = table[key]
+	}
+	return out
+}
+
+// Summary describes info in a few words for model lists, e.g.
+// "200K context · tools · vision · thinking · $3/$15"
+func (info Info) Summary() string {
+	parts := []string{formatWindow(info.ContextWindow) + " context"}
+	if info.Tools {
+		parts = append(parts, "tools")
+	}
+	if info.Vision {
+		parts = append(parts, "vision")
+	}
+	if info.Thinking {
+		parts = append(parts, "thinking")
+	}
+	if info.Priced {
+		parts = append(parts, "$"+formatPrice(info.Pricing.Prompt)+"/$"+formatPrice(info.Pricing.Completion))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func formatWindow(tokens int) string {
+	switch {
+	case tokens >= 1_000_000 && tokens%1_000_000 == 0:
+		return fmt.Sprintf("%dM", tokens/1_000_000)
+	case tokens >= 1000:
+		return fmt.Sprintf("%dK", tokens/1000)
+	default:
+		return fmt.Sprint(tokens)
+	}
+}
+
+func formatPrice(usd float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", usd), "0"), ".")
+}
@@ -0,0 +1,127 @@
+// Package notes is the scratchpad the agent records findings, plans and todo lists in
+// during a session, instead of repeating them in the conversation
+package notes
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultName is the note written and read when the model names none
+	DefaultName = "scratch"
+	// MaxNoteBytes bounds the size of a single note
+	MaxNoteBytes = 64 * 1024
+	// MaxNotes bounds how many notes the scratchpad holds
+	MaxNotes = 50
+)
+
+// namePattern restricts note names to short, readable labels
+var namePattern = regexp.MustCompile(`^[\w][\w .-]{0,63}$`)
+
+// Note is a named entry of the scratchpad
+type Note struct {
+	Name string
+	Text string
+}
+
+var (
+	mu  sync.Mutex
+	pad = map[string]string{}
+)
+
+// CheckName reports whether name can be used for a note
+func CheckName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid note name %q: use up to 64 letters, digits, spaces, '.', '-' or '_'", name)
+	}
+	return nil
+}
+
+// Write replaces the note name with text, or appends text to it, and returns the
+// resulting note
+func Write(name, text string, appendText bool) (string, error) {
+	if err := CheckName(name); err != nil {
+		return "", err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	old, exists := pad[name]
+	if !exists && len(pad) >= MaxNotes {
+		return "", fmt.Errorf("the scratchpad already holds %d notes; delete one first", MaxNotes)
+	}
+	if appendText && old != "" {
+		text = strings.TrimRight(old, "\n") + "\n" + text
+	}
+	if len(text) > MaxNoteBytes {
+		return "", fmt.Errorf("note %q would exceed %d KB", name, MaxNoteBytes/1024)
+	}
+	pad[name] = text
+	return text, nil
+}
+
+// Read returns the note name
+func Read(name string) (string, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	text, ok := pad[name]
+	return text, ok
+}
+
+// Delete removes the note name and reports whether it existed
+func Delete(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := pad[name]
+	delete(pad, name)
+	return ok
+}
+
+// List returns the notes sorted by name
+func List() []Note {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]Note, 0, len(pad))
+	for name, text := range pad {
+		list = append(list, Note{Name: name, Text: text})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Names returns the names of the notes, sorted
+func Names() []string {
+	var names []string
+	for _, n := range List() {
+		names = append(names, n.Name)
+	}
+	return names
+}
+
+// Snapshot returns a copy of the notes, e.g. for saving them with the session; nil
+// when there are none
+func Snapshot() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pad) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(pad))
+	for name, text := range pad {
+		out[name] = text
+	}
+	return out
+}
+
+// Restore replaces the notes with saved ones; nil clears the scratchpad
+func Restore(saved map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	pad = make(map[string]string, len(saved))
+	for name, text := range saved {
+		pad[name] = text
+	}
+}
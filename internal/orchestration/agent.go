@@ -0,0 +1,379 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/debuglog"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
+	"github.com/pprunty/magikarp/internal/stats"
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+// ApproveFunc decides whether a tool call requested by the model may run
+type ApproveFunc func(name string, input map[string]interface{}) bool
+
+// ToolCall records a single tool invocation made during a turn
+type ToolCall struct {
+	Name   string
+	Input  map[string]interface{}
+	Result providers.ToolResult
+	Denied bool
+}
+
+// ReadOnlyTools are the tools that read and search the workspace, or inspect its
+// containers, without changing anything. The note and task tools only touch the
+// session's scratchpad and checklist.
+var ReadOnlyTools = []string{"read_file", "tree", "code_outline", "find_references", "semantic_search", "git_status", "git_diff", "git_log", "github_list_issues", "github_read_issue", "docker_list", "docker_logs", "note_read", "note_write", "update_tasks"}
+
+// dryRunSafeTools are the tools besides ReadOnlyTools and tools.DryRunTools that run in
+// a dry run, as they change nothing in the workspace. Sub-agents inherit the dry run.
+var dryRunSafeTools = []string{"list_tools", "get_model_version", "process_output", "spawn_task", "read_clipboard"}
+
+// DefaultMaxIterations bounds the number of tool rounds in a turn when Turn.MaxIterations is unset
+const DefaultMaxIterations = 10
+
+// Turn describes one user message sent to a model together with its context
+type Turn struct {
+	Model   string
+	System  string
+	History []providers.ChatMessage
+	Message string
+	// Tools are offered to the model; only these can be executed during the turn
+	Tools []providers.ToolDefinition
+	// Policy is checked before each tool call: denied calls never run, allowed calls
+	// skip Approve. Nil leaves every call to Approve.
+	Policy *permissions.Policy
+	// Approve is consulted before each tool call the policy asks about; nil approves every call
+	Approve ApproveFunc
+	// MaxIterations limits how many rounds of tool calls are executed
+	MaxIterations int
+	// OnRound is called after each round of tool calls completes
+	OnRound func(round int, calls []ToolCall)
+	// OnText receives the text of each reply as it is generated, from providers that
+	// can stream requests with tools. A reply may still end in tool calls.
+	OnText func(delta string)
+	// OnRetry is called before a failed provider request is retried
+	OnRetry RetryNotifier
+	// Fallbacks are models tried in order when a request to the current model fails
+	// with an error another provider may not have (see ShouldFailover)
+	Fallbacks []string
+	// OnFailover is called when the turn switches to the next fallback model
+	OnFailover func(Failover)
+	// OnRoute is called with the model chosen for the message when Model is "auto"
+	OnRoute func(Route)
+	// BeforeTool is called before each approved tool call runs
+	BeforeTool func(name string)
+	// DryRun makes file edits and commands report what they would do without doing
+	// it; other tools that change state are not run
+	DryRun bool
+	// Thinking asks the model to think before it answers, if it can
+	Thinking providers.Thinking
+	// SubAgentThinking passes Thinking on to sub-agents, which otherwise answer
+	// without thinking to save time and tokens
+	SubAgentThinking bool
+	// Schema, when set, makes the final answer a JSON document conforming to it.
+	// Providers with structured output enforce it natively; answers that still do not
+	// conform are sent back for correction up to MaxSchemaRetries times.
+	Schema *Schema
+}
+
+// TurnResult holds the assistant replies and tool calls produced by a turn
+type TurnResult struct {
+	Messages  []providers.ChatMessage
+	ToolCalls []ToolCall
+	// Rounds is the number of tool rounds that were executed
+	Rounds int
+	// HitLimit is set when the model still wanted tools after MaxIterations rounds
+	HitLimit bool
+	// Model is the model that produced the final answer; it differs from Turn.Model
+	// after a failover
+	Model string
+	// Route is how the model was chosen when Turn.Model was "auto"
+	Route *Route
+	// JSON is the validated answer of a turn with a Schema
+	JSON json.RawMessage
+}
+
+// Text joins the non-empty assistant messages into a single response
+func (r *TurnResult) Text() string {
+	var b strings.Builder
+	for _, msg := range r.Messages {
+		if msg.Content == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(msg.Content)
+	}
+	return b.String()
+}
+
+// RunTurn sends the message to the turn's model and keeps executing requested tools,
+// feeding their results back, until the model answers without tools or the iteration
+// limit is reached. Token usage is recorded in the session accumulator. The pre_prompt
+// and post_response hooks run around the turns of the main agent, not of sub-agents.
+func RunTurn(ctx context.Context, turn Turn) (*TurnResult, error) {
+	ctx, span := startTurnSpan(ctx, turn)
+	result, err := runTurn(ctx, turn)
+	endTurnSpan(span, result, err)
+	return result, err
+}
+
+func runTurn(ctx context.Context, turn Turn) (*TurnResult, error) {
+	var route *Route
+	if turn.Model == AutoModel {
+		r, err := ResolveModel(ctx, turn.Model, turn.History, turn.Message)
+		if err != nil {
+			return nil, err
+		}
+		route, turn.Model = &r, r.Model
+		if turn.OnRoute != nil {
+			turn.OnRoute(r)
+		}
+	}
+
+	p, err := ProviderFor(turn.Model)
+	if err != nil {
+		return nil, fmt.Errorf("getting provider: %w", err)
+	}
+
+	_, subAgent := ctx.Value(subAgentKey{}).(subAgentParent)
+	if !subAgent {
+		if err := hooks.Run(ctx, hooks.Event{Event: hooks.PrePrompt, Model: turn.Model, Prompt: turn.Message}); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx = WithRetryNotifier(ctx, turn.OnRetry)
+	if turn.DryRun {
+		ctx = tools.WithDryRun(ctx)
+	}
+	chain := newFailoverChain(turn.Model, turn.Fallbacks)
+
+	maxIterations := turn.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+
+	// Build messages: system prompt, prior exchanges, then the new user message
+	system := turn.System
+	if turn.Schema != nil {
+		system = strings.TrimSpace(system + "\n\n" + turn.Schema.instruction())
+		ctx = providers.WithResponseFormat(ctx, turn.Schema.format())
+	}
+	messages := []providers.ChatMessage{{Role: providers.RoleSystem, Content: system}}
+	messages = append(messages, turn.History...)
+	messages = append(messages, providers.ChatMessage{Role: providers.RoleUser, Content: turn.Message})
+
+	providerTools := make([]providers.Tool, len(turn.Tools))
+	for i, tool := range turn.Tools {
+		providerTools[i] = providers.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		}
+	}
+
+	// Refuse tools to a model that cannot call them, and a request that cannot fit the
+	// model's context window, before sending it
+	if len(providerTools) > 0 {
+		if err := models.Require(turn.Model, models.ToolCalling); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckContextWindow(ctx, turn.Model, messages, providerTools); err != nil {
+		return nil, err
+	}
+
+	result := &TurnResult{Model: turn.Model, Route: route}
+	schemaRetries := 0
+	for {
+		// Once the limit is reached, ask for a final answer without offering tools
+		offered := providerTools
+		if result.Rounds >= maxIterations {
+			offered = nil
+		}
+
+		chatCtx := WithThinking(WithSessionUsage(ctx, result.Model), result.Model, turn.Thinking)
+		if turn.OnText != nil {
+			chatCtx = providers.WithTextRecorder(chatCtx, providers.TextRecorder(turn.OnText))
+		}
+		assistantMsgs, toolUses, err := p.Chat(chatCtx, messages, offered)
+		if err != nil && ctx.Err() == nil && ShouldFailover(err) {
+			if next, nextProvider, ok := chain.next(); ok {
+				if turn.OnFailover != nil {
+					turn.OnFailover(Failover{From: result.Model, To: next, Err: err})
+				}
+				p, result.Model = nextProvider, next
+				continue
+			}
+		}
+		if err != nil {
+			if result.Rounds == 0 {
+				return nil, fmt.Errorf("chat error: %w", err)
+			}
+			return nil, fmt.Errorf("tool result error: %w", err)
+		}
+		result.Messages = assistantMsgs
+
+		if offered == nil || len(toolUses) == 0 {
+			if turn.Schema != nil {
+				doc, err := turn.Schema.Validate(result.Text())
+				if err != nil && schemaRetries < MaxSchemaRetries {
+					schemaRetries++
+					messages = append(messages, schemaCorrection(result.Text(), err)...)
+					continue
+				}
+				if err != nil {
+					return nil, &SchemaError{Model: result.Model, Reply: result.Text(), Err: err}
+				}
+				result.JSON = doc
+				result.Messages = []providers.ChatMessage{{Role: providers.RoleAssistant, Content: string(doc)}}
+			}
+			result.HitLimit = offered == nil
+			if !subAgent {
+				_ = hooks.Run(ctx, hooks.Event{Event: hooks.PostResponse, Model: result.Model, Prompt: turn.Message, Response: result.Text()})
+			}
+			return result, nil
+		}
+
+		// Execute this round's tools and feed the results back as tool messages. Tools
+		// may spawn sub-agents of this turn (see SpawnTasks).
+		messages = append(messages, withToolCalls(assistantMsgs, toolUses)...)
+		toolCtx := withSubAgentParent(ctx, turn, result.Model)
+		var round []ToolCall
+		for _, use := range toolUses {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			call := executeToolUse(toolCtx, turn, use)
+			round = append(round, call)
+			messages = append(messages, providers.ChatMessage{
+				Role:       providers.RoleTool,
+				Content:    call.Result.Content,
+				ToolCallID: use.ID,
+				ToolName:   use.Name,
+				IsError:    call.Result.IsError,
+			})
+		}
+		result.ToolCalls = append(result.ToolCalls, round...)
+		result.Rounds++
+
+		if turn.OnRound != nil {
+			turn.OnRound(result.Rounds, round)
+		}
+	}
+}
+
+// withToolCalls attaches the requested tool uses to the last assistant message, adding
+// an empty one when the model replied with tool calls only, so providers with native
+// function calling can replay them before the results
+func withToolCalls(assistantMsgs []providers.ChatMessage, toolUses []providers.ToolUse) []providers.ChatMessage {
+	msgs := append([]providers.ChatMessage(nil), assistantMsgs...)
+	if n := len(msgs); n > 0 && msgs[n-1].Role == providers.RoleAssistant {
+		msgs[n-1].ToolCalls = toolUses
+		return msgs
+	}
+	return append(msgs, providers.ChatMessage{Role: providers.RoleAssistant, ToolCalls: toolUses})
+}
+
+// executeToolUse runs a single tool requested by the model, honouring the permission
+// policy and the approval hook
+func executeToolUse(ctx context.Context, turn Turn, use providers.ToolUse) ToolCall {
+	call := ToolCall{Name: use.Name}
+
+	var def *providers.ToolDefinition
+	for i := range turn.Tools {
+		if turn.Tools[i].Name == use.Name {
+			def = &turn.Tools[i]
+			break
+		}
+	}
+	if def == nil || def.Function == nil {
+		call.Result = providers.ToolResult{ID: use.ID, Content: "tool not found", IsError: true}
+		return call
+	}
+
+	if len(use.Input) > 0 {
+		if err := json.Unmarshal(use.Input, &call.Input); err != nil {
+			call.Result = providers.ToolResult{ID: use.ID, Content: fmt.Sprintf("Invalid input for %s: %v", use.Name, err), IsError: true}
+			return call
+		}
+	}
+
+	dryRun := tools.IsDryRun(ctx)
+	if dryRun && !dryRunAllowed(use.Name) {
+		call.Result = providers.ToolResult{ID: use.ID, Content: "Dry run: " + use.Name + " was not run, as it cannot report its changes without making them", IsError: true}
+		return call
+	}
+
+	decision := permissions.Decision{Action: permissions.Ask}
+	if turn.Policy != nil {
+		decision = turn.Policy.Evaluate(use.Name, call.Input)
+	}
+	if decision.Action == permissions.Deny {
+		call.Denied = true
+		content := "Tool call denied by permission policy"
+		if decision.Reason != "" {
+			content += ": " + decision.Reason
+		}
+		call.Result = providers.ToolResult{ID: use.ID, Content: content, IsError: true}
+		return call
+	}
+
+	// Dry runs change nothing, so there is nothing to approve
+	previewOnly := dryRun && slices.Contains(tools.DryRunTools, use.Name)
+	if decision.Action == permissions.Ask && turn.Approve != nil && !previewOnly && !turn.Approve(use.Name, call.Input) {
+		call.Denied = true
+		call.Result = providers.ToolResult{ID: use.ID, Content: "Tool call denied by the user", IsError: true}
+		return call
+	}
+
+	if err := hooks.Run(ctx, hooks.Event{Event: hooks.PreTool, Tool: use.Name, Input: call.Input}); err != nil {
+		call.Denied = true
+		call.Result = providers.ToolResult{ID: use.ID, Content: "Tool call " + err.Error(), IsError: true}
+		return call
+	}
+
+	if turn.BeforeTool != nil {
+		turn.BeforeTool(use.Name)
+	}
+	start := time.Now()
+	spanCtx, span := startToolSpan(ctx, use.Name)
+	res, err := def.Function(spanCtx, call.Input)
+	if err != nil || res == nil {
+		res = providers.NewToolResult(use.Name, fmt.Sprintf("Tool execution error: %v", err), true)
+	}
+	endToolSpan(span, res)
+	res.ID = use.ID
+	// Credentials read by tools, e.g. from a .env file, are masked before the result is
+	// shown, stored or sent back to the model
+	res.Content = redact.String(res.Content)
+	call.Result = *res
+	debuglog.Record(debuglog.Entry{
+		Kind:     debuglog.Tool,
+		Source:   use.Name,
+		Text:     string(use.Input) + "\n" + res.Content,
+		Duration: time.Since(start),
+		IsError:  res.IsError,
+	})
+	// Failing post_tool hooks do not change the result
+	_ = hooks.Run(ctx, hooks.Event{Event: hooks.PostTool, Tool: use.Name, Input: call.Input, Output: res.Content, IsError: res.IsError})
+	_ = stats.Record(stats.Event{Tool: use.Name, IsError: res.IsError})
+	return call
+}
+
+// dryRunAllowed reports whether the tool may be called in a dry run
+func dryRunAllowed(name string) bool {
+	return slices.Contains(ReadOnlyTools, name) || slices.Contains(tools.DryRunTools, name) || slices.Contains(dryRunSafeTools, name)
+}
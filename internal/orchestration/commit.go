@@ -0,0 +1,98 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// maxCommitDiff bounds the staged diff sent to the model, in bytes; the stat still
+// lists every file
+const maxCommitDiff = 60000
+
+// CommitRequest describes the staged changes to write a commit message for
+type CommitRequest struct {
+	// Diff is the output of git diff --staged
+	Diff string
+	// Stat is the output of git diff --staged --stat
+	Stat string
+	// Recent are the subjects of the latest commits, so that the message follows the
+	// conventions of the repository
+	Recent []string
+	// Style is "conventional" or "plain"
+	Style string
+	// Hint is what the user said about the change, if anything
+	Hint string
+}
+
+// CommitMessage asks model for a commit message for the staged changes of req. The
+// "auto" model uses the model routed to explanations. Usage counts towards the
+// session totals.
+func CommitMessage(ctx context.Context, model string, req CommitRequest) (string, error) {
+	if router := Routing(); model == AutoModel && router != nil {
+		model = router.ModelFor(TaskExplanation)
+	}
+	p, err := ProviderFor(model)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []providers.ChatMessage{
+		{Role: providers.RoleSystem, Content: commitSystemPrompt(req.Style)},
+		{Role: providers.RoleUser, Content: commitPrompt(req)},
+	}
+	replies, _, err := p.Chat(WithSessionUsage(ctx, model), messages, nil)
+	if err != nil {
+		return "", err
+	}
+	message := cleanCommitMessage((&TurnResult{Messages: replies}).Text())
+	if message == "" {
+		return "", errors.New("the model answered with an empty message")
+	}
+	return message, nil
+}
+
+// commitSystemPrompt describes the message to write in style
+func commitSystemPrompt(style string) string {
+	subject := "Start with a subject line in the Conventional Commits format, type(scope): summary, where type is one of feat, fix, refactor, perf, docs, test, build, ci, style or chore, the scope is optional, and the summary is lowercase in the imperative mood. Mark breaking changes with ! after the type or scope and a BREAKING CHANGE: footer."
+	if style == "plain" {
+		subject = "Start with a capitalised subject line in the imperative mood, such as \"Fix crash when the config is empty\", without a type prefix."
+	}
+	return "You write git commit messages for staged changes. " + subject + " Keep the subject under 72 characters, with no trailing period. " +
+		"If the change is not obvious from the subject, add a blank line and a short body, wrapped at 72 characters, explaining what changed and why. " +
+		"Describe only the changes in the diff. Answer with the commit message alone: no code fences, quotes or commentary."
+}
+
+// commitPrompt presents the staged changes of req
+func commitPrompt(req CommitRequest) string {
+	var b strings.Builder
+	if req.Hint != "" {
+		fmt.Fprintf(&b, "What the change is about, in the author's words: %s\n\n", req.Hint)
+	}
+	if len(req.Recent) > 0 {
+		fmt.Fprintf(&b, "Recent commit subjects in this repository:\n%s\n\n", strings.Join(req.Recent, "\n"))
+	}
+	fmt.Fprintf(&b, "Files changed:\n%s\n\n", strings.TrimRight(req.Stat, "\n"))
+	diff := req.Diff
+	if len(diff) > maxCommitDiff {
+		diff = diff[:maxCommitDiff] + "\n... (diff truncated)"
+	}
+	fmt.Fprintf(&b, "Staged diff:\n%s", diff)
+	return b.String()
+}
+
+// cleanCommitMessage removes the code fences and surrounding blank lines models add
+// to messages despite being asked not to
+func cleanCommitMessage(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		if _, rest, ok := strings.Cut(text, "\n"); ok {
+			text = rest
+		}
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,75 @@
+package orchestration
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// Comparison is the answer of one model to a prompt sent to several models
+type Comparison struct {
+	Model   string
+	Text    string
+	Err     error
+	Latency time.Duration
+	Usage   providers.Usage
+	Cost    float64
+}
+
+// Compare sends the same conversation to every model concurrently and returns their
+// answers in the order of models. Tools are not offered, so that the models cannot act
+// on the workspace at the same time. Usage also counts towards the session totals.
+func Compare(ctx context.Context, models []string, system string, history []providers.ChatMessage, message string) []Comparison {
+	messages := []providers.ChatMessage{{Role: providers.RoleSystem, Content: system}}
+	messages = append(messages, history...)
+	messages = append(messages, providers.ChatMessage{Role: providers.RoleUser, Content: message})
+
+	results := make([]Comparison, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = compareOne(ctx, model, messages)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// compareOne sends messages to model and measures the answer
+func compareOne(ctx context.Context, model string, messages []providers.ChatMessage) Comparison {
+	c := Comparison{Model: model}
+	p, err := ProviderFor(model)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+
+	var mu sync.Mutex
+	ctx = providers.WithUsageRecorder(ctx, func(u providers.Usage) {
+		session.Record(model, u)
+		mu.Lock()
+		defer mu.Unlock()
+		c.Usage.PromptTokens += u.PromptTokens
+		c.Usage.CompletionTokens += u.CompletionTokens
+		c.Usage.CacheReadTokens += u.CacheReadTokens
+		c.Usage.CacheWriteTokens += u.CacheWriteTokens
+	})
+
+	start := time.Now()
+	replies, _, err := p.Chat(ctx, messages, nil)
+	c.Latency = time.Since(start)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	c.Text = (&TurnResult{Messages: replies}).Text()
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Cost = EstimateCost(model, c.Usage)
+	return c
+}
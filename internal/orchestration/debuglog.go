@@ -0,0 +1,102 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/debuglog"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// debugPreview bounds the text of the last message shown for a request
+const debugPreview = 300
+
+// debugProvider records each request to a provider and its response in the debug log
+type debugProvider struct {
+	providers.Provider
+	model string
+}
+
+// withDebugLog wraps p, which serves model, so that its traffic shows in the debug console
+func withDebugLog(p providers.Provider, model string) providers.Provider {
+	return &debugProvider{Provider: p, model: model}
+}
+
+func (d *debugProvider) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	d.request(messages, len(tools))
+	start := time.Now()
+	msgs, uses, err := d.Provider.Chat(ctx, messages, tools)
+	d.response(start, msgs, uses, err)
+	return msgs, uses, err
+}
+
+func (d *debugProvider) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	d.request(messages, 0)
+	start := time.Now()
+	msgs, uses, err := d.Provider.SendToolResult(ctx, messages, toolResults)
+	d.response(start, msgs, uses, err)
+	return msgs, uses, err
+}
+
+// StreamChat records the streamed reply once the stream ends. Chunks are passed on as
+// they arrive; when ctx is cancelled the rest of the stream is drained unread.
+func (d *debugProvider) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	d.request(messages, 0)
+	start := time.Now()
+	in, err := d.Provider.StreamChat(ctx, model, messages, temperature)
+	if err != nil {
+		d.response(start, nil, nil, err)
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		var reply strings.Builder
+		for chunk := range in {
+			reply.WriteString(chunk)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		d.response(start, []providers.ChatMessage{{Role: providers.RoleAssistant, Content: reply.String()}}, nil, ctx.Err())
+	}()
+	return out, nil
+}
+
+// request records the size of a request and the start of its last message
+func (d *debugProvider) request(messages []providers.ChatMessage, tools int) {
+	text := fmt.Sprintf("%d messages", len(messages))
+	if tools > 0 {
+		text += fmt.Sprintf(", %d tools", tools)
+	}
+	if n := len(messages); n > 0 {
+		last := messages[n-1]
+		text += "\n" + last.Role + ": " + debuglog.Shorten(last.Content, debugPreview)
+	}
+	debuglog.Record(debuglog.Entry{Kind: debuglog.Request, Source: d.model, Text: text})
+}
+
+// response records the reply to a request, the tools it asks for, or its error
+func (d *debugProvider) response(start time.Time, msgs []providers.ChatMessage, uses []providers.ToolUse, err error) {
+	entry := debuglog.Entry{Kind: debuglog.Response, Source: d.model, Duration: time.Since(start)}
+	if err != nil {
+		entry.Text, entry.IsError = err.Error(), true
+		debuglog.Record(entry)
+		return
+	}
+	var lines []string
+	for _, msg := range msgs {
+		if msg.Content != "" {
+			lines = append(lines, msg.Content)
+		}
+	}
+	for _, use := range uses {
+		lines = append(lines, "→ "+use.Name+" "+string(use.Input))
+	}
+	entry.Text = strings.Join(lines, "\n")
+	debuglog.Record(entry)
+}
@@ -0,0 +1,65 @@
+package orchestration
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// Failover describes a switch to the next fallback model after a failed request
+type Failover struct {
+	From string
+	To   string
+	Err  error
+}
+
+// String renders the failover for display next to the spinner
+func (f Failover) String() string {
+	return fmt.Sprintf("%s failed, trying %s", f.From, f.To)
+}
+
+// ShouldFailover reports whether a request that failed with err may succeed on another
+// provider: authentication and billing errors, rate limits, outages and network
+// failures. Errors caused by the request itself, such as invalid input, are not.
+func ShouldFailover(err error) bool {
+	if err == nil {
+		return false
+	}
+	if retryable, _, _ := classifyError(err); retryable {
+		return true
+	}
+	status, _ := statusFromError(err)
+	switch status {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden, http.StatusNotFound:
+		return true
+	}
+	return false
+}
+
+// failoverChain hands out the fallback models of a turn in order, skipping the
+// original model, duplicates and models without a registered provider
+type failoverChain struct {
+	models []string
+	tried  map[string]bool
+}
+
+func newFailoverChain(model string, fallbacks []string) *failoverChain {
+	return &failoverChain{models: fallbacks, tried: map[string]bool{model: true}}
+}
+
+// next returns the next untried fallback model and its provider
+func (c *failoverChain) next() (string, providers.Provider, bool) {
+	for len(c.models) > 0 {
+		model := c.models[0]
+		c.models = c.models[1:]
+		if c.tried[model] {
+			continue
+		}
+		c.tried[model] = true
+		if p, err := ProviderFor(model); err == nil {
+			return model, p, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,89 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// probeTimeout bounds a probe, which is not retried
+const probeTimeout = 30 * time.Second
+
+// Probe is the outcome of a minimal request sent to a model to check that its provider
+// answers
+type Probe struct {
+	Provider string
+	Model    string
+	Latency  time.Duration
+	Err      error
+}
+
+// ProbeModel asks model for a one-word reply and measures how long the answer takes.
+// Failures are reported straight away rather than retried.
+func ProbeModel(ctx context.Context, model string) Probe {
+	probe := Probe{Model: model}
+	p, err := ProviderFor(model)
+	if err != nil {
+		probe.Err = err
+		return probe
+	}
+	probe.Provider = p.Name()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	var retryErr error
+	ctx = WithRetryNotifier(ctx, func(status RetryStatus) {
+		retryErr = status.Err
+		cancel()
+	})
+	ctx = providers.WithOutputLimits(ctx, providers.OutputLimits{MaxTokens: 16})
+
+	messages := []providers.ChatMessage{{Role: providers.RoleUser, Content: "Reply with the word OK."}}
+	start := time.Now()
+	_, _, err = p.Chat(ctx, messages, nil)
+	probe.Latency = time.Since(start)
+	if err != nil && retryErr != nil {
+		err = retryErr
+	}
+	probe.Err = err
+	return probe
+}
+
+// Hint suggests how to fix the error of a failed probe, or returns "" when there is
+// nothing to suggest
+func (p Probe) Hint() string {
+	if p.Err == nil {
+		return ""
+	}
+	provider, err := p.Provider, p.Err
+	status, _ := statusFromError(err)
+	switch {
+	case provider == "ollama" && status == http.StatusNotFound:
+		return "pull the model with ollama pull " + p.Model
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Sprintf("the API key was rejected; check it, or store a new one with magikarp auth login %s", provider)
+	case status == http.StatusPaymentRequired:
+		return "the account has no credit; check its billing settings"
+	case status == http.StatusNotFound:
+		return fmt.Sprintf("the model is not available to this key; remove it from providers.%s.models", provider)
+	case status == http.StatusTooManyRequests:
+		return "rate limited or out of quota; wait a minute or check the plan of the account"
+	case status >= 500:
+		return "the provider is having problems; try again later"
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("no answer within %s; check your connection, proxy or base_url", probeTimeout)
+	case provider == "ollama" && errors.Is(err, syscall.ECONNREFUSED):
+		return "Ollama is not running; start it with ollama serve, or set OLLAMA_BASE_URL"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return "cannot reach the provider; check your connection, proxy or base_url"
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package orchestration
+
+import (
+	"context"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// limitingProvider attaches the output limits of its model to every request
+type limitingProvider struct {
+	providers.Provider
+	model string
+	// defaults are the limits of the model's provider
+	defaults providers.OutputLimits
+}
+
+// withOutputLimits wraps p so that the replies of model are bounded by the max_tokens
+// and stop sequences configured for it, or else for its provider
+func withOutputLimits(p providers.Provider, model string, pCfg config.Provider) providers.Provider {
+	return &limitingProvider{
+		Provider: p,
+		model:    model,
+		defaults: providers.OutputLimits{MaxTokens: pCfg.MaxTokens, Stop: pCfg.Stop},
+	}
+}
+
+// limit attaches the output limits to ctx. The models section is read on every
+// request, so that its changes apply without rebuilding the registry; limits already
+// attached by the caller take precedence.
+func (l *limitingProvider) limit(ctx context.Context) context.Context {
+	limits := providers.OutputLimitsFrom(ctx)
+	info := models.Lookup(l.model)
+	if limits.MaxTokens == 0 {
+		limits.MaxTokens = info.MaxTokens
+	}
+	if limits.MaxTokens == 0 {
+		limits.MaxTokens = l.defaults.MaxTokens
+	}
+	if limits.Stop == nil {
+		limits.Stop = info.Stop
+	}
+	if limits.Stop == nil {
+		limits.Stop = l.defaults.Stop
+	}
+	return providers.WithOutputLimits(ctx, limits)
+}
+
+func (l *limitingProvider) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	return l.Provider.Chat(l.limit(ctx), messages, tools)
+}
+
+func (l *limitingProvider) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	return l.Provider.StreamChat(l.limit(ctx), model, messages, temperature)
+}
+
+func (l *limitingProvider) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	return l.Provider.SendToolResult(l.limit(ctx), messages, toolResults)
+}
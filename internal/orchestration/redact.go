@@ -0,0 +1,71 @@
+package orchestration
+
+import (
+	"context"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
+)
+
+// configureRedaction applies the redaction section of the config and registers the
+// providers' API keys, so that they are masked wherever they turn up
+func configureRedaction(cfg *config.Config, providerConfigs map[string]config.Provider) error {
+	if err := redact.Configure(cfg.Redaction.IsEnabled(), cfg.Redaction.Patterns); err != nil {
+		return err
+	}
+	var secrets []string
+	for _, p := range providerConfigs {
+		secrets = append(secrets, p.Key)
+	}
+	secrets = append(secrets, cfg.Speech.Key, cfg.Voice.Key, cfg.GitHub.Token)
+	redact.SetSecrets(secrets...)
+	return nil
+}
+
+// redactingProvider masks credentials in every message before it is sent to the provider
+type redactingProvider struct {
+	providers.Provider
+}
+
+// withRedaction wraps p so that no credential leaves the process in a prompt
+func withRedaction(p providers.Provider) providers.Provider {
+	return &redactingProvider{Provider: p}
+}
+
+func (r *redactingProvider) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	return r.Provider.Chat(ctx, redactMessages(messages), tools)
+}
+
+func (r *redactingProvider) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	return r.Provider.StreamChat(ctx, model, redactMessages(messages), temperature)
+}
+
+func (r *redactingProvider) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	results := make([]providers.ToolResult, len(toolResults))
+	for i, res := range toolResults {
+		res.Content = redact.String(res.Content)
+		results[i] = res
+	}
+	return r.Provider.SendToolResult(ctx, redactMessages(messages), results)
+}
+
+// redactingCounter masks credentials in the messages whose tokens are counted, as they
+// are sent to the provider too
+type redactingCounter struct {
+	providers.TokenCounter
+}
+
+func (r redactingCounter) CountTokens(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) (int, error) {
+	return r.TokenCounter.CountTokens(ctx, redactMessages(messages), tools)
+}
+
+// redactMessages returns a copy of messages with credentials masked in their content
+func redactMessages(messages []providers.ChatMessage) []providers.ChatMessage {
+	out := make([]providers.ChatMessage, len(messages))
+	for i, msg := range messages {
+		msg.Content = redact.String(msg.Content)
+		out[i] = msg
+	}
+	return out
+}
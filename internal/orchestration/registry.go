@@ -1,69 +1,155 @@
 package orchestration
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/keychain"
+	"github.com/pprunty/magikarp/internal/models"
 	"github.com/pprunty/magikarp/internal/providers"
 	"github.com/pprunty/magikarp/internal/providers/alibaba"
 	"github.com/pprunty/magikarp/internal/providers/anthropic"
+	"github.com/pprunty/magikarp/internal/providers/azureopenai"
+	"github.com/pprunty/magikarp/internal/providers/deepseek"
 	"github.com/pprunty/magikarp/internal/providers/gemini"
+	"github.com/pprunty/magikarp/internal/providers/groq"
 	"github.com/pprunty/magikarp/internal/providers/mistral"
+	"github.com/pprunty/magikarp/internal/providers/ollama"
 	"github.com/pprunty/magikarp/internal/providers/openai"
+	"github.com/pprunty/magikarp/internal/providers/openrouter"
 )
 
+// catalogTimeout bounds how long startup waits for a provider's model catalog when
+// it is not cached
+const catalogTimeout = 3 * time.Second
+
 var (
-	modelToProvider   = make(map[string]providers.Provider)
+	modelToProvider = make(map[string]providers.Provider)
+	// catalogModels holds the models of providers whose model list is fetched at startup
+	catalogModels = make(map[string][]string)
+	// embedders holds a client of each provider that can compute embeddings
+	embedders = make(map[string]providers.Embedder)
+	// synthesizers holds a client of each provider that can speak text
+	synthesizers = make(map[string]providers.Synthesizer)
+	// transcribers holds a client of each provider that can transcribe speech
+	transcribers = make(map[string]providers.Transcriber)
+	// counters holds the exact token counter of each model whose provider has one
+	counters          = make(map[string]providers.TokenCounter)
 	registryInitOnce  sync.Once
 	registryInitError error
 )
 
+// buildWarnings are the providers that could not be set up by the last build
+var buildWarnings []string
+
 // Init builds the provider registry from configuration. Safe for concurrent use.
 func Init(cfg *config.Config) error {
 	registryInitOnce.Do(func() {
-		registryInitError = build(cfg)
+		var warnings []string
+		warnings, registryInitError = build(cfg)
+		// Written to stderr so print mode output stays clean
+		if registryInitError == nil && len(warnings) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: Some providers not initialized:\n")
+			for _, w := range warnings {
+				fmt.Fprintf(os.Stderr, "  - %s\n", w)
+			}
+			fmt.Fprintf(os.Stderr, "\n")
+		}
 	})
 	return registryInitError
 }
 
-func build(cfg *config.Config) error {
+// Reload rebuilds the registry after the configuration changed and returns the
+// providers that could not be set up. The registry is not guarded against concurrent
+// use, so Reload must not run while a request is in flight. On error the previous
+// registry is kept.
+func Reload(cfg *config.Config) ([]string, error) {
+	registryInitOnce.Do(func() {}) // a later Init keeps the reloaded registry
+
+	prevModels, prevCatalog, prevRouter := modelToProvider, catalogModels, router
+	prevEmbedders, prevSynthesizers, prevTranscribers := embedders, synthesizers, transcribers
+	prevCounters := counters
+	modelToProvider = make(map[string]providers.Provider)
+	catalogModels = make(map[string][]string)
+	embedders = make(map[string]providers.Embedder)
+	synthesizers = make(map[string]providers.Synthesizer)
+	transcribers = make(map[string]providers.Transcriber)
+	counters = make(map[string]providers.TokenCounter)
+	router = nil
+
+	warnings, err := build(cfg)
+	if err != nil {
+		modelToProvider, catalogModels, router = prevModels, prevCatalog, prevRouter
+		embedders, synthesizers, transcribers = prevEmbedders, prevSynthesizers, prevTranscribers
+		counters = prevCounters
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// build fills the registry from cfg and returns the providers that could not be set up
+func build(cfg *config.Config) ([]string, error) {
 	if cfg == nil {
-		return fmt.Errorf("nil config passed to registry")
+		return nil, fmt.Errorf("nil config passed to registry")
+	}
+	// Keys stored with "magikarp auth login" fill in those the config leaves empty
+	providerConfigs := keychain.Resolve(cfg.Providers)
+	if err := configureRedaction(cfg, providerConfigs); err != nil {
+		return nil, err
 	}
 
 	var initErrors []string
+	// Providers that only the built-in defaults configure are skipped quietly when
+	// their key is not set, unless no provider can be set up at all
+	var unset []string
+	keyNotSet := func(name, msg string) {
+		if cfg.BuiltinProviders[name] {
+			unset = append(unset, msg)
+		} else {
+			initErrors = append(initErrors, msg)
+		}
+	}
 
 	// OpenAI provider
-	if pCfg, ok := cfg.Providers["openai"]; ok {
+	if pCfg, ok := providerConfigs["openai"]; ok {
 		if pCfg.Key != "" && pCfg.Key != "${OPENAI_API_KEY}" {
 			temperature := cfg.GetEffectiveTemperature("openai")
 			for _, m := range pCfg.Models {
 				client := openai.New(pCfg.Key, []string{m}, temperature, cfg.System)
 				modelToProvider[m] = client
 			}
+			embedder := openai.New(pCfg.Key, nil, temperature, cfg.System)
+			embedder.SetEmbeddingModel(pCfg.EmbeddingModel)
+			embedders["openai"] = embedder
+			synthesizers["openai"] = embedder
+			transcribers["openai"] = embedder
 		} else {
-			initErrors = append(initErrors, "OpenAI: API key not set (OPENAI_API_KEY environment variable)")
+			keyNotSet("openai", "OpenAI: API key not set (OPENAI_API_KEY environment variable)")
 		}
 	}
 
 	// Anthropic provider
-	if pCfg, ok := cfg.Providers["anthropic"]; ok {
+	if pCfg, ok := providerConfigs["anthropic"]; ok {
 		if pCfg.Key != "" && pCfg.Key != "${ANTHROPIC_API_KEY}" {
 			temperature := cfg.GetEffectiveTemperature("anthropic")
 			for _, m := range pCfg.Models {
-				client := anthropic.New(pCfg.Key, []string{m}, temperature, cfg.System)
+				client := anthropic.New(pCfg.Key, []string{m}, temperature, pCfg.MaxTokens, cfg.System)
 				modelToProvider[m] = client
+				counters[m] = client
 			}
 		} else {
-			initErrors = append(initErrors, "Anthropic: API key not set (ANTHROPIC_API_KEY environment variable)")
+			keyNotSet("anthropic", "Anthropic: API key not set (ANTHROPIC_API_KEY environment variable)")
 		}
 	}
 
 	// Gemini provider
-	if pCfg, ok := cfg.Providers["gemini"]; ok {
+	if pCfg, ok := providerConfigs["gemini"]; ok {
 		if pCfg.Key != "" && pCfg.Key != "${GEMINI_API_KEY}" {
 			temperature := cfg.GetEffectiveTemperature("gemini")
 			client, err := gemini.New(pCfg.Key, pCfg.Models, temperature, cfg.System)
@@ -73,14 +159,16 @@ func build(cfg *config.Config) error {
 				for _, m := range pCfg.Models {
 					modelToProvider[m] = client
 				}
+				client.SetEmbeddingModel(pCfg.EmbeddingModel)
+				embedders["gemini"] = client
 			}
 		} else {
-			initErrors = append(initErrors, "Gemini: API key not set (GEMINI_API_KEY environment variable)")
+			keyNotSet("gemini", "Gemini: API key not set (GEMINI_API_KEY environment variable)")
 		}
 	}
 
 	// Mistral provider
-	if pCfg, ok := cfg.Providers["mistral"]; ok {
+	if pCfg, ok := providerConfigs["mistral"]; ok {
 		if pCfg.Key != "" && pCfg.Key != "${MISTRAL_API_KEY}" {
 			temperature := cfg.GetEffectiveTemperature("mistral")
 			client, err := mistral.New(pCfg.Key, pCfg.Models, temperature, cfg.System)
@@ -92,12 +180,12 @@ func build(cfg *config.Config) error {
 				}
 			}
 		} else {
-			initErrors = append(initErrors, "Mistral: API key not set (MISTRAL_API_KEY environment variable)")
+			keyNotSet("mistral", "Mistral: API key not set (MISTRAL_API_KEY environment variable)")
 		}
 	}
 
 	// Alibaba provider
-	if pCfg, ok := cfg.Providers["alibaba"]; ok {
+	if pCfg, ok := providerConfigs["alibaba"]; ok {
 		if pCfg.Key != "" && pCfg.Key != "${ALIBABA_API_KEY}" {
 			temperature := cfg.GetEffectiveTemperature("alibaba")
 			client, err := alibaba.New(pCfg.Key, pCfg.Models, temperature, cfg.System)
@@ -109,31 +197,126 @@ func build(cfg *config.Config) error {
 				}
 			}
 		} else {
-			initErrors = append(initErrors, "Alibaba: API key not set (ALIBABA_API_KEY environment variable)")
+			keyNotSet("alibaba", "Alibaba: API key not set (ALIBABA_API_KEY environment variable)")
+		}
+	}
+
+	// Groq provider
+	if pCfg, ok := providerConfigs["groq"]; ok {
+		if pCfg.Key != "" && pCfg.Key != "${GROQ_API_KEY}" {
+			temperature := cfg.GetEffectiveTemperature("groq")
+			for _, m := range pCfg.Models {
+				client, err := groq.New(pCfg.Key, pCfg.BaseURL, []string{m}, temperature, cfg.System)
+				if err != nil {
+					initErrors = append(initErrors, fmt.Sprintf("Groq: failed to create client: %v", err))
+					break
+				}
+				modelToProvider[m] = client
+			}
+		} else {
+			keyNotSet("groq", "Groq: API key not set (GROQ_API_KEY environment variable)")
+		}
+	}
+
+	// DeepSeek provider
+	if pCfg, ok := providerConfigs["deepseek"]; ok {
+		if pCfg.Key != "" && pCfg.Key != "${DEEPSEEK_API_KEY}" {
+			temperature := cfg.GetEffectiveTemperature("deepseek")
+			for _, m := range pCfg.Models {
+				client, err := deepseek.New(pCfg.Key, pCfg.BaseURL, []string{m}, temperature, cfg.System)
+				if err != nil {
+					initErrors = append(initErrors, fmt.Sprintf("DeepSeek: failed to create client: %v", err))
+					break
+				}
+				modelToProvider[m] = client
+			}
+		} else {
+			keyNotSet("deepseek", "DeepSeek: API key not set (DEEPSEEK_API_KEY environment variable)")
+		}
+	}
+
+	// Azure OpenAI provider
+	if pCfg, ok := providerConfigs["azure"]; ok {
+		key := pCfg.Key
+		if key == "${AZURE_OPENAI_API_KEY}" {
+			key = ""
+		}
+		temperature := cfg.GetEffectiveTemperature("azure")
+		azureCfg := azureopenai.Config{
+			Endpoint:    pCfg.BaseURL,
+			APIVersion:  pCfg.APIVersion,
+			Auth:        pCfg.Auth,
+			Key:         key,
+			Deployments: pCfg.Deployments,
+		}
+		for _, m := range pCfg.Models {
+			client, err := azureopenai.New(azureCfg, []string{m}, temperature, cfg.System)
+			if err != nil {
+				initErrors = append(initErrors, fmt.Sprintf("Azure OpenAI: %v", err))
+				break
+			}
+			modelToProvider[m] = client
+		}
+	}
+
+	// OpenRouter provider; models and prices come from its catalog
+	if pCfg, ok := providerConfigs["openrouter"]; ok {
+		if pCfg.Key != "" && pCfg.Key != "${OPENROUTER_API_KEY}" {
+			if err := registerOpenRouter(cfg, pCfg); err != nil {
+				initErrors = append(initErrors, fmt.Sprintf("OpenRouter: %v", err))
+			}
+		} else {
+			keyNotSet("openrouter", "OpenRouter: API key not set (OPENROUTER_API_KEY environment variable)")
+		}
+	}
+
+	// Ollama provider (local models, no API key required)
+	if pCfg, ok := providerConfigs["ollama"]; ok {
+		temperature := cfg.GetEffectiveTemperature("ollama")
+		for _, m := range pCfg.Models {
+			client, err := ollama.New(pCfg.BaseURL, []string{m}, temperature, cfg.System)
+			if err != nil {
+				initErrors = append(initErrors, fmt.Sprintf("Ollama: failed to create client for %s: %v", m, err))
+				continue
+			}
+			modelToProvider[m] = client
+		}
+		if embedder, err := ollama.New(pCfg.BaseURL, nil, temperature, cfg.System); err == nil {
+			embedder.SetEmbeddingModel(pCfg.EmbeddingModel)
+			embedders["ollama"] = embedder
 		}
 	}
 
 	if len(modelToProvider) == 0 {
-		msg := "No providers initialized. Please set at least one API key:\n"
-		for _, e := range initErrors {
+		msg := "No providers initialized. Please set at least one API key, or store one with magikarp auth login <provider>:\n"
+		for _, e := range append(initErrors, unset...) {
 			msg += "  - " + e + "\n"
 		}
-		return errors.New(msg)
+		return nil, errors.New(msg)
 	}
 
-	// Print info about initialized providers
-	if len(initErrors) > 0 {
-		fmt.Printf("Warning: Some providers not initialized:\n")
-		for _, err := range initErrors {
-			fmt.Printf("  - %s\n", err)
-		}
-		fmt.Printf("\n")
+	// Retry rate limits and transient failures for every provider, mask credentials
+	// in what is sent, bound the replies as configured, and record each attempt for
+	// the debug console and in traces
+	policy := RetryPolicyFromConfig(cfg)
+	for m, p := range modelToProvider {
+		limited := withOutputLimits(p, m, providerConfigs[p.Name()])
+		modelToProvider[m] = withRedaction(withRetry(withDebugLog(withTracing(limited, m), m), policy))
+	}
+	for m, c := range counters {
+		counters[m] = redactingCounter{c}
+	}
+	models.Configure(cfg)
+
+	// Route the "auto" model to the models configured for each task
+	if err := configureRouter(cfg); err != nil {
+		return nil, err
 	}
 
 	initializedCount := 0
-	for providerName := range cfg.Providers {
+	for providerName := range providerConfigs {
 		hasModels := false
-		for _, m := range cfg.Providers[providerName].Models {
+		for _, m := range modelsOf(providerName, providerConfigs[providerName]) {
 			if _, exists := modelToProvider[m]; exists {
 				hasModels = true
 				break
@@ -144,7 +327,71 @@ func build(cfg *config.Config) error {
 		}
 	}
 
-	return nil
+	buildWarnings = initErrors
+	return initErrors, nil
+}
+
+// RegistryWarnings returns the providers that could not be set up when the registry
+// was last built
+func RegistryWarnings() []string {
+	return buildWarnings
+}
+
+// registerOpenRouter registers OpenRouter models from its catalog. Configured models
+// restrict the catalog; with none, every model in the catalog is registered. Context
+// lengths and prices from the catalog feed the models table. The catalog is cached for a
+// day; if it cannot be fetched, the configured models are registered without pricing.
+func registerOpenRouter(cfg *config.Config, pCfg config.Provider) error {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+	defer cancel()
+	catalog, catalogErr := openrouter.CachedModels(ctx, pCfg.Key, pCfg.BaseURL)
+
+	ids := pCfg.Models
+	if catalogErr == nil {
+		known := make(map[string]bool, len(catalog))
+		for _, m := range catalog {
+			known[m.ID] = true
+			info := config.ModelConfig{ContextWindow: m.ContextLength}
+			if m.Priced {
+				info.Pricing = &config.ModelPricing{Prompt: m.PromptPrice, Completion: m.CompletionPrice}
+			}
+			models.Register(m.ID, info)
+		}
+		if len(ids) == 0 {
+			for _, m := range catalog {
+				ids = append(ids, m.ID)
+			}
+		} else {
+			for _, m := range ids {
+				if !known[m] {
+					fmt.Fprintf(os.Stderr, "Warning: OpenRouter model %s is not in the catalog\n", m)
+				}
+			}
+		}
+	} else if len(ids) == 0 {
+		return catalogErr
+	}
+
+	temperature := cfg.GetEffectiveTemperature("openrouter")
+	for _, m := range ids {
+		client, err := openrouter.New(pCfg.Key, pCfg.BaseURL, []string{m}, temperature, cfg.System)
+		if err != nil {
+			return err
+		}
+		modelToProvider[m] = client
+	}
+
+	catalogModels["openrouter"] = ids
+	return catalogErr
+}
+
+// modelsOf returns the models of a configured provider, preferring the
+// catalog fetched at startup over the configured list
+func modelsOf(name string, pCfg config.Provider) []string {
+	if models, ok := catalogModels[name]; ok {
+		return models
+	}
+	return pCfg.Models
 }
 
 // ProviderFor returns the provider responsible for the specified model.
@@ -156,6 +403,49 @@ func ProviderFor(model string) (providers.Provider, error) {
 	return p, nil
 }
 
+// EmbedderFor returns the embeddings client of the named provider ("openai", "gemini"
+// or "ollama"). The provider must be configured and initialised.
+func EmbedderFor(provider string) (providers.Embedder, error) {
+	e, ok := embedders[provider]
+	if !ok {
+		return nil, fmt.Errorf("no embeddings available from provider %s (it supports none, or it is not configured with an API key)", provider)
+	}
+	return e, nil
+}
+
+// SynthesizerFor returns the text-to-speech client of the named provider ("openai")
+func SynthesizerFor(provider string) (providers.Synthesizer, error) {
+	s, ok := synthesizers[provider]
+	if !ok {
+		return nil, fmt.Errorf("no text-to-speech available from provider %s (it supports none, or it is not configured with an API key)", provider)
+	}
+	return s, nil
+}
+
+// TranscriberFor returns the speech-to-text client of the named provider ("openai")
+func TranscriberFor(provider string) (providers.Transcriber, error) {
+	t, ok := transcribers[provider]
+	if !ok {
+		return nil, fmt.Errorf("no speech recognition available from provider %s (it supports none, or it is not configured with an API key)", provider)
+	}
+	return t, nil
+}
+
+// DefaultModel resolves the model to start with: the configured default_model when it has
+// a registered provider (or is "auto" with routing configured), otherwise the first
+// registered model.
+func DefaultModel(cfg *config.Config) (string, error) {
+	if cfg != nil && cfg.DefaultModel == AutoModel && router != nil {
+		return AutoModel, nil
+	}
+	if cfg != nil && cfg.DefaultModel != "" {
+		if _, err := ProviderFor(cfg.DefaultModel); err == nil {
+			return cfg.DefaultModel, nil
+		}
+	}
+	return FirstModel()
+}
+
 // FirstModel returns an arbitrary model that has a registered provider.
 func FirstModel() (string, error) {
 	if len(modelToProvider) == 0 {
@@ -187,7 +477,7 @@ func ModelsByProvider(cfg *config.Config) map[string][]string {
 		availableModels := make([]string, 0)
 
 		// Check which models from this provider are actually available (have initialized clients)
-		for _, model := range providerCfg.Models {
+		for _, model := range modelsOf(providerName, providerCfg) {
 			if _, exists := modelToProvider[model]; exists {
 				availableModels = append(availableModels, model)
 			}
@@ -206,21 +496,21 @@ func ModelsByProvider(cfg *config.Config) map[string][]string {
 // Returns true if the provider has at least one successfully initialized model client.
 func GetInitializedProviders(cfg *config.Config) map[string]bool {
 	providerStatus := make(map[string]bool)
-	
+
 	// Check all configured providers
 	for providerName, providerCfg := range cfg.Providers {
 		hasInitializedClient := false
-		
+
 		// Check if any model from this provider has an initialized client
-		for _, model := range providerCfg.Models {
+		for _, model := range modelsOf(providerName, providerCfg) {
 			if _, exists := modelToProvider[model]; exists {
 				hasInitializedClient = true
 				break
 			}
 		}
-		
+
 		providerStatus[providerName] = hasInitializedClient
 	}
-	
+
 	return providerStatus
 }
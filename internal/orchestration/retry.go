@@ -0,0 +1,308 @@
+package orchestration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/gage-technologies/mistral-go"
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Retry defaults used when the retry section of config.yaml is unset
+const (
+	DefaultMaxRetries     = 4
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// RetryPolicy controls how failed provider requests are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt; zero disables retrying
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// RetryPolicyFromConfig builds a RetryPolicy from the retry section of the config,
+// filling in defaults for unset values
+func RetryPolicyFromConfig(cfg *config.Config) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries:     DefaultMaxRetries,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+	if cfg == nil {
+		return policy
+	}
+	if cfg.Retry.MaxRetries != nil {
+		policy.MaxRetries = *cfg.Retry.MaxRetries
+	}
+	if cfg.Retry.InitialBackoff > 0 {
+		policy.InitialBackoff = cfg.Retry.InitialBackoff
+	}
+	if cfg.Retry.MaxBackoff > 0 {
+		policy.MaxBackoff = cfg.Retry.MaxBackoff
+	}
+	return policy
+}
+
+// backoff returns the jittered delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff << (retry - 1)
+	if delay <= 0 || delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	// Full jitter between half and the whole delay spreads out concurrent retries
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// RetryStatus describes a retry that is about to happen
+type RetryStatus struct {
+	Provider string
+	// Attempt is the retry number, starting at 1
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error
+	RateLimited bool
+}
+
+// String renders the status for display next to the spinner
+func (s RetryStatus) String() string {
+	reason := "Request failed"
+	if s.RateLimited {
+		reason = "Rate limited"
+	}
+	return fmt.Sprintf("%s by %s, retrying in %s (attempt %d/%d)",
+		reason, s.Provider, s.Delay.Round(100*time.Millisecond), s.Attempt, s.MaxAttempts)
+}
+
+// RetryNotifier receives a RetryStatus before each retry
+type RetryNotifier func(RetryStatus)
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier returns a context that reports provider retries to notify
+func WithRetryNotifier(ctx context.Context, notify RetryNotifier) context.Context {
+	if notify == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryNotifierKey{}, notify)
+}
+
+func notifyRetry(ctx context.Context, status RetryStatus) {
+	if notify, ok := ctx.Value(retryNotifierKey{}).(RetryNotifier); ok {
+		notify(status)
+	}
+}
+
+// retryProvider decorates a Provider so transient failures are retried with
+// exponential backoff
+type retryProvider struct {
+	providers.Provider
+	policy RetryPolicy
+}
+
+// withRetry wraps p in the retry decorator unless retries are disabled
+func withRetry(p providers.Provider, policy RetryPolicy) providers.Provider {
+	if policy.MaxRetries <= 0 {
+		return p
+	}
+	return &retryProvider{Provider: p, policy: policy}
+}
+
+func (r *retryProvider) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	var msgs []providers.ChatMessage
+	var uses []providers.ToolUse
+	err := r.do(ctx, func() error {
+		var err error
+		msgs, uses, err = r.Provider.Chat(ctx, messages, tools)
+		return err
+	})
+	return msgs, uses, err
+}
+
+// StreamChat retries opening the stream; errors after the first chunk are delivered
+// in-band by the provider and are not retried
+func (r *retryProvider) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	var ch <-chan string
+	err := r.do(ctx, func() error {
+		var err error
+		ch, err = r.Provider.StreamChat(ctx, model, messages, temperature)
+		return err
+	})
+	return ch, err
+}
+
+func (r *retryProvider) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	var msgs []providers.ChatMessage
+	var uses []providers.ToolUse
+	err := r.do(ctx, func() error {
+		var err error
+		msgs, uses, err = r.Provider.SendToolResult(ctx, messages, toolResults)
+		return err
+	})
+	return msgs, uses, err
+}
+
+// do runs call, retrying retryable errors until it succeeds, the policy is exhausted
+// or ctx is cancelled
+func (r *retryProvider) do(ctx context.Context, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt > r.policy.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		retryable, rateLimited, retryAfter := classifyError(err)
+		if !retryable {
+			return err
+		}
+
+		delay := r.policy.backoff(attempt)
+		if retryAfter > 0 {
+			// The server knows best; still cap it so the UI never hangs for minutes
+			delay = min(retryAfter, r.policy.MaxBackoff)
+		}
+
+		notifyRetry(ctx, RetryStatus{
+			Provider:    r.Name(),
+			Attempt:     attempt,
+			MaxAttempts: r.policy.MaxRetries,
+			Delay:       delay,
+			Err:         err,
+			RateLimited: rateLimited,
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+var (
+	httpStatusRe = regexp.MustCompile(`\b(?:status code|HTTP Error|status)[: ]+(\d{3})\b`)
+	grpcCodeRe   = regexp.MustCompile(`code = (ResourceExhausted|Unavailable|DeadlineExceeded|Internal)`)
+)
+
+// classifyError reports whether err is worth retrying, whether it was a rate limit
+// and how long the server asked us to wait (zero when unknown)
+func classifyError(err error) (retryable, rateLimited bool, retryAfter time.Duration) {
+	if errors.Is(err, context.Canceled) {
+		return false, false, 0
+	}
+
+	status, header := statusFromError(err)
+	if status != 0 {
+		retryAfter = parseRetryAfter(header)
+		switch {
+		case status == http.StatusTooManyRequests:
+			return true, true, retryAfter
+		case status == http.StatusRequestTimeout, status >= 500:
+			return true, false, retryAfter
+		default:
+			return false, false, 0
+		}
+	}
+
+	// Transient network failures
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true, false, 0
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true, false, 0
+	}
+	var mistralConnErr *mistral.MistralConnectionError
+	if errors.As(err, &mistralConnErr) {
+		return true, false, 0
+	}
+
+	// gRPC backed providers (Gemini) only expose the code in the message
+	if m := grpcCodeRe.FindStringSubmatch(err.Error()); m != nil {
+		return true, m[1] == "ResourceExhausted", 0
+	}
+	return false, false, 0
+}
+
+// statusFromError extracts the HTTP status code, and response headers when available,
+// from the error types returned by the provider SDKs
+func statusFromError(err error) (int, http.Header) {
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		var header http.Header
+		if anthropicErr.Response != nil {
+			header = anthropicErr.Response.Header
+		}
+		return anthropicErr.StatusCode, header
+	}
+
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) && openaiErr.HTTPStatusCode != 0 {
+		return openaiErr.HTTPStatusCode, nil
+	}
+	var openaiReqErr *openai.RequestError
+	if errors.As(err, &openaiReqErr) && openaiReqErr.HTTPStatusCode != 0 {
+		return openaiReqErr.HTTPStatusCode, nil
+	}
+
+	var mistralErr *mistral.MistralAPIError
+	if errors.As(err, &mistralErr) {
+		return mistralErr.HTTPStatus, http.Header(mistralErr.Headers)
+	}
+
+	// Google API errors expose HTTPCode(); -1 means the call went over gRPC
+	var httpCoder interface{ HTTPCode() int }
+	if errors.As(err, &httpCoder) && httpCoder.HTTPCode() > 0 {
+		return httpCoder.HTTPCode(), nil
+	}
+
+	// Fall back to the status embedded in the message, e.g. "(HTTP Error 429)"
+	if m := httpStatusRe.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code, nil
+	}
+	return 0, nil
+}
+
+// parseRetryAfter reads the Retry-After (seconds or HTTP date) or retry-after-ms header
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
@@ -0,0 +1,213 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	convctx "github.com/pprunty/magikarp/internal/context"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// AutoModel is the model name that routes each prompt to the model configured for its task
+const AutoModel = config.AutoModel
+
+// Task is the kind of work a prompt asks for, which decides the model that answers it
+type Task string
+
+const (
+	TaskCodeEdit    Task = "code_edit"
+	TaskExplanation Task = "explanation"
+	TaskLongContext Task = "long_context"
+	TaskChatter     Task = "chatter"
+)
+
+// DefaultLongContextTokens is the size of the history and prompt from which a prompt is
+// a long-context task when routing.long_context_tokens is unset
+const DefaultLongContextTokens = 30000
+
+// classifyTimeout bounds how long a classifier model may take to label a prompt
+const classifyTimeout = 10 * time.Second
+
+// chatterWords is the length under which a prompt without other signals is small talk
+const chatterWords = 8
+
+// Route is the model chosen for a prompt and why
+type Route struct {
+	Model  string
+	Task   Task
+	Reason string
+}
+
+// String renders the route for display, e.g. "gpt-4o (code_edit: asks for a code change)"
+func (r Route) String() string {
+	return fmt.Sprintf("%s (%s: %s)", r.Model, r.Task, r.Reason)
+}
+
+// routingRule assigns prompts matching pattern to task
+type routingRule struct {
+	pattern *regexp.Regexp
+	task    Task
+}
+
+// Router picks a model for each prompt by classifying it as a task
+type Router struct {
+	models            map[Task]string
+	fallback          string
+	classifier        string
+	longContextTokens int
+	rules             []routingRule
+}
+
+// Built-in rules, checked after the configured ones
+var (
+	codeEditPattern = regexp.MustCompile("(?i)```|\\b(fix|refactor|implement|rename|add|remove|delete|change|update|write|edit|patch|rewrite|convert|migrate)\\b.*\\b(code|function|method|test|tests|file|bug|class|struct|module|package|handler|endpoint)s?\\b|\\.(go|py|js|ts|rs|java|c|cpp|rb|sh)\\b")
+	explainPattern  = regexp.MustCompile(`(?i)^(why|how|what|explain|describe|compare|summari[sz]e)\b|\b(explain|what does|how does|why does|difference between)\b|\?\s*$`)
+)
+
+// NewRouter builds a router from the routing configuration. Tasks without a model, and
+// models that are not registered, fall back to routing.default, then to fallback.
+func NewRouter(conf config.RoutingConfig, fallback string) (*Router, error) {
+	r := &Router{
+		models:            make(map[Task]string, len(conf.Models)),
+		fallback:          conf.Default,
+		classifier:        conf.Classifier,
+		longContextTokens: conf.LongContextTokens,
+	}
+	if _, err := ProviderFor(r.fallback); err != nil {
+		r.fallback = fallback
+	}
+	if r.classifier == "rules" {
+		r.classifier = ""
+	}
+	if r.longContextTokens <= 0 {
+		r.longContextTokens = DefaultLongContextTokens
+	}
+	for task, model := range conf.Models {
+		r.models[Task(task)] = model
+	}
+	for i, rule := range conf.Rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %d: %w", i, err)
+		}
+		r.rules = append(r.rules, routingRule{pattern: pattern, task: Task(rule.Task)})
+	}
+	return r, nil
+}
+
+// Route classifies the prompt and returns the model for its task
+func (r *Router) Route(ctx context.Context, history []providers.ChatMessage, message string) Route {
+	task, reason := r.Classify(ctx, history, message)
+	return Route{Model: r.ModelFor(task), Task: task, Reason: reason}
+}
+
+// Classify returns the task of a prompt and the reason for it. A long history makes
+// any prompt a long-context task; otherwise the configured rules are tried, then the
+// classifier model when one is set, then the built-in keyword rules.
+func (r *Router) Classify(ctx context.Context, history []providers.ChatMessage, message string) (Task, string) {
+	if tokens := convctx.EstimateMessages(history) + convctx.EstimateTokens(message); tokens >= r.longContextTokens {
+		return TaskLongContext, fmt.Sprintf("about %d tokens of context", tokens)
+	}
+	for _, rule := range r.rules {
+		if rule.pattern.MatchString(message) {
+			return rule.task, "matches " + rule.pattern.String()
+		}
+	}
+	if r.classifier != "" {
+		if task, err := r.classifyWithModel(ctx, message); err == nil {
+			return task, "classified by " + r.classifier
+		}
+	}
+	switch {
+	case codeEditPattern.MatchString(message):
+		return TaskCodeEdit, "asks for a code change"
+	case explainPattern.MatchString(message):
+		return TaskExplanation, "asks a question"
+	case len(strings.Fields(message)) < chatterWords:
+		return TaskChatter, "short message"
+	}
+	return TaskExplanation, "no other signal"
+}
+
+// classifyWithModel asks the classifier model to label the prompt with one task
+func (r *Router) classifyWithModel(ctx context.Context, message string) (Task, error) {
+	p, err := ProviderFor(r.classifier)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, classifyTimeout)
+	defer cancel()
+
+	prompt := "Classify the user's request as exactly one of: code_edit (change or write code), " +
+		"explanation (explain or answer a question), long_context (work across many files or a large text), " +
+		"chatter (greetings and small talk). Answer with the label only."
+	messages := []providers.ChatMessage{
+		{Role: providers.RoleSystem, Content: prompt},
+		{Role: providers.RoleUser, Content: message},
+	}
+	replies, _, err := p.Chat(WithSessionUsage(ctx, r.classifier), messages, nil)
+	if err != nil {
+		return "", err
+	}
+	answer := strings.ToLower((&TurnResult{Messages: replies}).Text())
+	for _, task := range config.RoutingTasks {
+		if strings.Contains(answer, task) {
+			return Task(task), nil
+		}
+	}
+	return "", fmt.Errorf("classifier answered %q", answer)
+}
+
+// ModelFor returns the model configured for task, or the fallback model when the task
+// has none or its model is not registered
+func (r *Router) ModelFor(task Task) string {
+	if model, ok := r.models[task]; ok {
+		if _, err := ProviderFor(model); err == nil {
+			return model
+		}
+	}
+	return r.fallback
+}
+
+// router routes the "auto" model; nil when routing is not configured
+var router *Router
+
+// configureRouter sets up the router once the providers are registered
+func configureRouter(cfg *config.Config) error {
+	if !cfg.Routing.Enabled() {
+		return nil
+	}
+	fallback := cfg.DefaultModel
+	if _, err := ProviderFor(fallback); err != nil {
+		if fallback, err = FirstModel(); err != nil {
+			return err
+		}
+	}
+	r, err := NewRouter(cfg.Routing, fallback)
+	if err != nil {
+		return err
+	}
+	router = r
+	return nil
+}
+
+// Routing returns the router of the "auto" model, or nil when routing is not configured
+func Routing() *Router {
+	return router
+}
+
+// ResolveModel returns the route of a prompt sent to model. Models other than "auto"
+// are returned as they are.
+func ResolveModel(ctx context.Context, model string, history []providers.ChatMessage, message string) (Route, error) {
+	if model != AutoModel {
+		return Route{Model: model}, nil
+	}
+	if router == nil {
+		return Route{}, fmt.Errorf("model auto needs models under routing in the config")
+	}
+	return router.Route(ctx, history, message), nil
+}
@@ -0,0 +1,160 @@
+package orchestration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// MaxSchemaRetries bounds how often a reply that does not conform to Turn.Schema is
+// sent back to the model to be corrected
+const MaxSchemaRetries = 2
+
+// maxSchemaProblems bounds the validation errors reported back to the model
+const maxSchemaProblems = 10
+
+// schemaNameRe matches the characters OpenAI accepts in schema names
+var schemaNameRe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Schema is a JSON schema the final answer of a turn must conform to
+type Schema struct {
+	// Name identifies the schema to providers with structured output
+	Name     string
+	Raw      json.RawMessage
+	compiled *jsonschema.Schema
+}
+
+// CompileSchema parses the JSON schema in data. Name is sanitised for providers that
+// restrict schema names; empty names become "response".
+func CompileSchema(name string, data []byte) (*Schema, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("invalid JSON schema: must be an object")
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	compiled, err := compiler.Compile("schema.json")
+	var compileErr *jsonschema.SchemaError
+	if errors.As(err, &compileErr) {
+		// The URL is made up; the error itself says what is wrong
+		err = compileErr.Err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	name = strings.Trim(schemaNameRe.ReplaceAllString(name, "_"), "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	if name == "" {
+		name = "response"
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return &Schema{Name: name, Raw: compact.Bytes(), compiled: compiled}, nil
+}
+
+// format returns the response format asking providers for replies in the schema
+func (s *Schema) format() providers.ResponseFormat {
+	return providers.ResponseFormat{Name: s.Name, Schema: s.Raw}
+}
+
+// instruction is appended to the system prompt of turns with a schema, as providers
+// without structured output only see the schema there
+func (s *Schema) instruction() string {
+	return "Your final answer must be a single JSON document conforming to this JSON schema, with no other text and no code fences:\n" + string(s.Raw)
+}
+
+// Validate checks that reply is a JSON document conforming to the schema and returns
+// it compacted. Code fences around the document are ignored.
+func (s *Schema) Validate(reply string) (json.RawMessage, error) {
+	text := strings.TrimSpace(reply)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal([]byte(text), &doc); err != nil {
+		return nil, fmt.Errorf("the reply is not valid JSON: %v", err)
+	}
+	if err := s.compiled.Validate(doc); err != nil {
+		return nil, fmt.Errorf("the reply does not conform to the schema:\n%s", describeSchemaError(err))
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(text)); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+// describeSchemaError lists the failed constraints of a validation error, one per line
+func describeSchemaError(err error) string {
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err.Error()
+	}
+	var lines []string
+	var walk func(*jsonschema.ValidationError)
+	walk = func(ve *jsonschema.ValidationError) {
+		if len(ve.Causes) == 0 {
+			at := ve.InstanceLocation
+			if at == "" {
+				at = "/"
+			}
+			lines = append(lines, fmt.Sprintf("- at %s: %s", at, ve.Message))
+			return
+		}
+		for _, cause := range ve.Causes {
+			walk(cause)
+		}
+	}
+	walk(ve)
+	if len(lines) > maxSchemaProblems {
+		lines = append(lines[:maxSchemaProblems], fmt.Sprintf("- and %d more", len(lines)-maxSchemaProblems))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SchemaError reports a final answer that still did not conform to Turn.Schema after
+// MaxSchemaRetries corrections
+type SchemaError struct {
+	Model string
+	// Reply is the last answer of the model
+	Reply string
+	Err   error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s did not answer in the requested JSON schema after %d retries: %v", e.Model, MaxSchemaRetries, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// schemaCorrection returns the rejected reply and a request to correct it
+func schemaCorrection(reply string, err error) []providers.ChatMessage {
+	if strings.TrimSpace(reply) == "" {
+		reply = "(empty answer)"
+	}
+	return []providers.ChatMessage{
+		{Role: providers.RoleAssistant, Content: reply},
+		{Role: providers.RoleUser, Content: "Your answer was rejected: " + err.Error() + "\nReply again with only the corrected JSON document."},
+	}
+}
@@ -0,0 +1,148 @@
+package orchestration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+const (
+	// MaxSubTasks bounds how many sub-agents one spawn can start
+	MaxSubTasks = 8
+	// subAgentConcurrency bounds how many sub-agents of a spawn run at once
+	subAgentConcurrency = 4
+)
+
+// subAgentPrompt is appended to the parent's system prompt for sub-agents
+const subAgentPrompt = "You are a sub-agent working on one part of a larger task for another agent. " +
+	"Work only on the task you are given, and finish with a concise report of what you found or did; " +
+	"it is passed back to the agent that delegated the task, not shown to the user."
+
+// SubTask is a piece of work delegated to a sub-agent
+type SubTask struct {
+	Prompt string
+	// Tools names the tools the sub-agent may use, from those offered to the parent;
+	// empty offers ReadOnlyTools
+	Tools []string
+	// Model answers the task; empty uses the parent's model
+	Model string
+}
+
+// SubResult is the outcome of a sub-agent
+type SubResult struct {
+	Task      SubTask
+	Model     string
+	Text      string
+	ToolCalls []ToolCall
+	Err       error
+}
+
+type subAgentKey struct{}
+
+// subAgentParent is the turn whose tools are running, so that spawned sub-agents can
+// inherit its model, tools and approvals; depth is 0 for the main agent
+type subAgentParent struct {
+	turn  Turn
+	model string
+	depth int
+}
+
+// withSubAgentParent returns a context for the tools of turn, currently answered by model
+func withSubAgentParent(ctx context.Context, turn Turn, model string) context.Context {
+	depth := 0
+	if p, ok := ctx.Value(subAgentKey{}).(subAgentParent); ok {
+		depth = p.depth + 1
+	}
+	return context.WithValue(ctx, subAgentKey{}, subAgentParent{turn: turn, model: model, depth: depth})
+}
+
+// SpawnTasks runs each task in a sub-agent of the turn whose tool is calling it, at most
+// subAgentConcurrency at a time, and returns their results in the order of tasks. Each
+// sub-agent starts with an empty history and may only use its task's tools; the
+// parent's permission policy and approvals apply to them. Sub-agents cannot spawn
+// sub-agents of their own.
+func SpawnTasks(ctx context.Context, tasks []SubTask) ([]SubResult, error) {
+	parent, ok := ctx.Value(subAgentKey{}).(subAgentParent)
+	if !ok {
+		return nil, fmt.Errorf("sub-agents can only be spawned by a running agent")
+	}
+	if parent.depth > 0 {
+		return nil, fmt.Errorf("sub-agents cannot spawn sub-agents of their own")
+	}
+	if len(tasks) == 0 || len(tasks) > MaxSubTasks {
+		return nil, fmt.Errorf("between 1 and %d tasks can be spawned at once", MaxSubTasks)
+	}
+
+	results := make([]SubResult, len(tasks))
+	sem := make(chan struct{}, subAgentConcurrency)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = SubResult{Task: task, Err: ctx.Err()}
+				return
+			}
+			results[i] = runSubAgent(ctx, parent, task)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// runSubAgent runs task as a turn of its own with the parent's settings
+func runSubAgent(ctx context.Context, parent subAgentParent, task SubTask) SubResult {
+	res := SubResult{Task: task, Model: task.Model}
+	if res.Model == "" {
+		res.Model = parent.model
+	}
+	names := task.Tools
+	if len(names) == 0 {
+		names = ReadOnlyTools
+	}
+
+	var thinking providers.Thinking
+	if parent.turn.SubAgentThinking {
+		thinking = parent.turn.Thinking
+	}
+	turn, err := RunTurn(ctx, Turn{
+		Model:            res.Model,
+		System:           parent.turn.System + "\n\n" + subAgentPrompt,
+		Message:          task.Prompt,
+		Tools:            SelectTools(parent.turn.Tools, names),
+		Policy:           parent.turn.Policy,
+		Approve:          parent.turn.Approve,
+		MaxIterations:    parent.turn.MaxIterations,
+		OnRetry:          parent.turn.OnRetry,
+		Fallbacks:        parent.turn.Fallbacks,
+		Thinking:         thinking,
+		SubAgentThinking: parent.turn.SubAgentThinking,
+	})
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Model, res.Text, res.ToolCalls = turn.Model, turn.Text(), turn.ToolCalls
+	return res
+}
+
+// SelectTools returns the tools of offered whose names are listed, in the order offered
+func SelectTools(offered []providers.ToolDefinition, names []string) []providers.ToolDefinition {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	var out []providers.ToolDefinition
+	for _, t := range offered {
+		if wanted[t.Name] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
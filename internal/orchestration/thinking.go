@@ -0,0 +1,33 @@
+package orchestration
+
+import (
+	"context"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// ThinkingFromConfig returns the thinking configured in cfg; disabled when
+// thinking.effort is off or unset
+func ThinkingFromConfig(cfg *config.Config) providers.Thinking {
+	if cfg == nil || cfg.Thinking.Effort == "off" {
+		return providers.Thinking{}
+	}
+	return providers.Thinking{Effort: cfg.Thinking.Effort, BudgetTokens: cfg.Thinking.BudgetTokens}
+}
+
+// SupportsThinking reports whether model can think before it answers
+func SupportsThinking(model string) bool {
+	return models.Lookup(model).Thinking
+}
+
+// WithThinking returns a context whose requests to model ask it to think, when
+// thinking is enabled and model supports it. Otherwise thinking requested by ctx is
+// turned off, as models that cannot think reject requests that ask them to.
+func WithThinking(ctx context.Context, model string, thinking providers.Thinking) context.Context {
+	if !thinking.Enabled() || !SupportsThinking(model) {
+		thinking = providers.Thinking{}
+	}
+	return providers.WithThinking(ctx, thinking)
+}
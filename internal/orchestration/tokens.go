@@ -0,0 +1,84 @@
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tokens"
+)
+
+// confirmAt is the share of the context window above which the local token count of
+// a request is confirmed with the provider's counting endpoint, when it has one
+const confirmAt = 0.9
+
+// estimateSlack is how far an estimated count may exceed the context window before a
+// request is refused; estimates are not precise enough to refuse one closer than that
+const estimateSlack = 1.25
+
+// ContextWindowError reports a request that does not fit the model's context window
+type ContextWindowError struct {
+	Model  string
+	Tokens int
+	Window int
+	// Exact is set when Tokens was counted exactly rather than estimated
+	Exact bool
+}
+
+func (e *ContextWindowError) Error() string {
+	about := "about "
+	if e.Exact {
+		about = ""
+	}
+	return fmt.Sprintf("the request is too long for %s: %s%d tokens for a context window of %d; shorten the prompt or remove earlier messages",
+		e.Model, about, e.Tokens, e.Window)
+}
+
+// CounterFor returns the exact token counter of model's provider, which masks
+// credentials like the provider itself
+func CounterFor(model string) (providers.TokenCounter, error) {
+	c, ok := counters[model]
+	if !ok {
+		return nil, fmt.Errorf("provider of model %s has no token counting endpoint", model)
+	}
+	return c, nil
+}
+
+// WindowFor returns the context window of model from the models table
+func WindowFor(model string) int {
+	return models.Lookup(model).ContextWindow
+}
+
+// CheckContextWindow verifies before a request is sent that messages and tools fit
+// model's context window. Requests near the limit are counted exactly when the
+// provider can; it returns a *ContextWindowError for those that do not fit.
+func CheckContextWindow(ctx context.Context, model string, messages []providers.ChatMessage, tools []providers.Tool) error {
+	window := WindowFor(model)
+	n := tokens.CountMessages(model, messages) + toolTokens(model, tools)
+	if float64(n) < float64(window)*confirmAt {
+		return nil
+	}
+
+	exact := tokens.Exact(model)
+	if counter, err := CounterFor(model); err == nil {
+		if counted, err := counter.CountTokens(ctx, messages, tools); err == nil {
+			n, exact = counted, true
+		}
+	}
+	if n <= window || (!exact && float64(n) <= float64(window)*estimateSlack) {
+		return nil
+	}
+	return &ContextWindowError{Model: model, Tokens: n, Window: window, Exact: exact}
+}
+
+// toolTokens counts the tokens of the tool definitions sent with a request
+func toolTokens(model string, tools []providers.Tool) int {
+	total := 0
+	for _, tool := range tools {
+		schema, _ := json.Marshal(tool.InputSchema)
+		total += tokens.Count(model, tool.Name) + tokens.Count(model, tool.Description) + tokens.Count(model, string(schema))
+	}
+	return total
+}
@@ -0,0 +1,146 @@
+package orchestration
+
+import (
+	"context"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes follow the OpenTelemetry semantic conventions for generative AI
+// where they exist
+const (
+	attrSystem           = attribute.Key("gen_ai.system")
+	attrModel            = attribute.Key("gen_ai.request.model")
+	attrInputTokens      = attribute.Key("gen_ai.usage.input_tokens")
+	attrOutputTokens     = attribute.Key("gen_ai.usage.output_tokens")
+	attrCacheReadTokens  = attribute.Key("magikarp.usage.cache_read_tokens")
+	attrCacheWriteTokens = attribute.Key("magikarp.usage.cache_write_tokens")
+	attrMessages         = attribute.Key("magikarp.request.messages")
+	attrTools            = attribute.Key("magikarp.request.tools")
+	attrToolCalls        = attribute.Key("magikarp.response.tool_calls")
+	attrToolName         = attribute.Key("gen_ai.tool.name")
+)
+
+// tracingProvider starts a span for each request to a provider, carrying its model,
+// size and token usage. Retries show as one span per attempt.
+type tracingProvider struct {
+	providers.Provider
+	model string
+}
+
+// withTracing wraps p, which serves model, so that its requests show in traces
+func withTracing(p providers.Provider, model string) providers.Provider {
+	return &tracingProvider{Provider: p, model: model}
+}
+
+func (t *tracingProvider) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	ctx, span := t.start(ctx, len(messages), len(tools))
+	msgs, uses, err := t.Provider.Chat(ctx, messages, tools)
+	span.SetAttributes(attrToolCalls.Int(len(uses)))
+	telemetry.End(span, err)
+	return msgs, uses, err
+}
+
+func (t *tracingProvider) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	ctx, span := t.start(ctx, len(messages), 0)
+	msgs, uses, err := t.Provider.SendToolResult(ctx, messages, toolResults)
+	span.SetAttributes(attrToolCalls.Int(len(uses)))
+	telemetry.End(span, err)
+	return msgs, uses, err
+}
+
+// StreamChat ends the span when the stream ends
+func (t *tracingProvider) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	ctx, span := t.start(ctx, len(messages), 0)
+	in, err := t.Provider.StreamChat(ctx, model, messages, temperature)
+	if err != nil {
+		telemetry.End(span, err)
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+			}
+		}
+		telemetry.End(span, ctx.Err())
+	}()
+	return out, nil
+}
+
+// start starts the span of a request and adds the token usage the provider reports to
+// it, before passing the usage on to the recorder of ctx
+func (t *tracingProvider) start(ctx context.Context, messages, tools int) (context.Context, trace.Span) {
+	ctx, span := telemetry.Tracer().Start(ctx, "chat "+t.model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attrSystem.String(t.Name()),
+			attrModel.String(t.model),
+			attrMessages.Int(messages),
+			attrTools.Int(tools),
+		))
+	if !span.IsRecording() {
+		return ctx, span
+	}
+	outer := ctx
+	ctx = providers.WithUsageRecorder(ctx, func(usage providers.Usage) {
+		span.SetAttributes(
+			attrInputTokens.Int(usage.InputTokens()),
+			attrOutputTokens.Int(usage.CompletionTokens),
+			attrCacheReadTokens.Int(usage.CacheReadTokens),
+			attrCacheWriteTokens.Int(usage.CacheWriteTokens),
+		)
+		providers.RecordUsage(outer, usage)
+	})
+	return ctx, span
+}
+
+// startTurnSpan starts the span that the requests and tool calls of a turn nest in
+func startTurnSpan(ctx context.Context, turn Turn) (context.Context, trace.Span) {
+	_, subAgent := ctx.Value(subAgentKey{}).(subAgentParent)
+	return telemetry.Tracer().Start(ctx, "turn", trace.WithAttributes(
+		attrModel.String(turn.Model),
+		attrTools.Int(len(turn.Tools)),
+		attribute.Int("magikarp.turn.history", len(turn.History)),
+		attribute.Bool("magikarp.turn.sub_agent", subAgent),
+	))
+}
+
+// endTurnSpan records the outcome of a turn on its span and ends it
+func endTurnSpan(span trace.Span, result *TurnResult, err error) {
+	if result != nil {
+		span.SetAttributes(
+			attribute.String("gen_ai.response.model", result.Model),
+			attribute.Int("magikarp.turn.rounds", result.Rounds),
+			attrToolCalls.Int(len(result.ToolCalls)),
+			attribute.Bool("magikarp.turn.hit_limit", result.HitLimit),
+		)
+		if result.Route != nil {
+			span.SetAttributes(attribute.String("magikarp.turn.route", string(result.Route.Task)))
+		}
+	}
+	telemetry.End(span, err)
+}
+
+// startToolSpan starts the span of a tool call
+func startToolSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "execute_tool "+name, trace.WithAttributes(attrToolName.String(name)))
+}
+
+// endToolSpan records whether a tool call failed on its span and ends it. The result
+// is not exported, as it may hold file contents.
+func endToolSpan(span trace.Span, res *providers.ToolResult) {
+	span.SetAttributes(attribute.Int("magikarp.tool.result_bytes", len(res.Content)))
+	if res.IsError {
+		span.SetStatus(codes.Error, "the tool reported an error")
+	}
+	span.End()
+}
@@ -0,0 +1,127 @@
+package orchestration
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/stats"
+)
+
+// Pricing is the cost in USD per million prompt and completion tokens
+type Pricing = models.Pricing
+
+// PricingFor returns the pricing of model from the models table; models without one
+// (e.g. local Ollama models) are treated as free
+func PricingFor(model string) (Pricing, bool) {
+	info := models.Lookup(model)
+	return info.Pricing, info.Priced
+}
+
+// Prompt cache reads and writes are billed relative to the prompt price
+const (
+	cacheReadPriceFactor  = 0.1
+	cacheWritePriceFactor = 1.25
+)
+
+// EstimateCost returns the estimated cost in USD of usage on model
+func EstimateCost(model string, usage providers.Usage) float64 {
+	pricing, ok := PricingFor(model)
+	if !ok {
+		return 0
+	}
+	prompt := float64(usage.PromptTokens) +
+		float64(usage.CacheReadTokens)*cacheReadPriceFactor +
+		float64(usage.CacheWriteTokens)*cacheWritePriceFactor
+	return (prompt*pricing.Prompt + float64(usage.CompletionTokens)*pricing.Completion) / 1_000_000
+}
+
+// ModelUsage aggregates the usage of a single model during the session
+type ModelUsage struct {
+	Model    string
+	Requests int
+	Usage    providers.Usage
+	Cost     float64
+}
+
+// SessionUsage accumulates token usage across the session. Safe for concurrent use.
+type SessionUsage struct {
+	mu      sync.Mutex
+	byModel map[string]*ModelUsage
+}
+
+var session = &SessionUsage{byModel: make(map[string]*ModelUsage)}
+
+// Session returns the process-wide usage accumulator
+func Session() *SessionUsage {
+	return session
+}
+
+// Record adds usage reported for model to the session totals and the usage log
+func (s *SessionUsage) Record(model string, usage providers.Usage) {
+	cost := EstimateCost(model, usage)
+
+	s.mu.Lock()
+	entry, ok := s.byModel[model]
+	if !ok {
+		entry = &ModelUsage{Model: model}
+		s.byModel[model] = entry
+	}
+	entry.Requests++
+	entry.Usage.PromptTokens += usage.PromptTokens
+	entry.Usage.CompletionTokens += usage.CompletionTokens
+	entry.Usage.CacheReadTokens += usage.CacheReadTokens
+	entry.Usage.CacheWriteTokens += usage.CacheWriteTokens
+	entry.Cost += cost
+	s.mu.Unlock()
+
+	// The log only feeds /stats, so a failed write must not affect the request
+	_ = stats.Record(stats.Event{
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CacheReadTokens:  usage.CacheReadTokens,
+		CacheWriteTokens: usage.CacheWriteTokens,
+		Cost:             cost,
+	})
+}
+
+// Totals returns the cumulative usage and estimated cost across all models
+func (s *SessionUsage) Totals() (providers.Usage, float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total providers.Usage
+	var cost float64
+	for _, entry := range s.byModel {
+		total.PromptTokens += entry.Usage.PromptTokens
+		total.CompletionTokens += entry.Usage.CompletionTokens
+		total.CacheReadTokens += entry.Usage.CacheReadTokens
+		total.CacheWriteTokens += entry.Usage.CacheWriteTokens
+		cost += entry.Cost
+	}
+	return total, cost
+}
+
+// ByModel returns a snapshot of per-model usage sorted by model name
+func (s *SessionUsage) ByModel() []ModelUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]ModelUsage, 0, len(s.byModel))
+	for _, entry := range s.byModel {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
+// WithSessionUsage returns a context that records provider usage for model
+// into the session accumulator
+func WithSessionUsage(ctx context.Context, model string) context.Context {
+	return providers.WithUsageRecorder(ctx, func(u providers.Usage) {
+		session.Record(model, u)
+	})
+}
@@ -0,0 +1,27 @@
+package permissions
+
+// ShellArguments are the tool arguments holding shell scripts
+var ShellArguments = []string{"script", "command"}
+
+// networkCommands are programs whose main purpose is talking to other hosts
+var networkCommands = map[string]bool{
+	"curl": true, "wget": true, "ssh": true, "scp": true, "sftp": true, "rsync": true,
+	"ftp": true, "telnet": true, "nc": true, "ncat": true, "netcat": true, "socat": true,
+	"ping": true, "dig": true, "nslookup": true,
+}
+
+// networkSubcommands are subcommands that reach a remote for otherwise local programs;
+// git is handled separately
+var networkSubcommands = map[string]map[string]bool{
+	"go":     {"get": true, "install": true},
+	"npm":    {"install": true, "i": true, "publish": true},
+	"pip":    {"install": true, "download": true},
+	"pip3":   {"install": true, "download": true},
+	"docker": {"pull": true, "push": true, "login": true},
+}
+
+// UsesNetwork reports whether a shell script in the tool input runs a command that
+// accesses the network
+func UsesNetwork(input map[string]interface{}) bool {
+	return CheckShell(input).Network
+}
@@ -0,0 +1,246 @@
+// Package permissions decides whether a tool call may run, must be confirmed by the
+// user, or is refused, based on the rules in the tools section of config.yaml.
+package permissions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// Action is the outcome of evaluating a tool call against a policy
+type Action int
+
+const (
+	// Ask means the user has to confirm the call
+	Ask Action = iota
+	// Allow means the call runs without confirmation
+	Allow
+	// Deny means the call is refused
+	Deny
+)
+
+// String returns the name used for the action in config.yaml
+func (a Action) String() string {
+	switch a {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	default:
+		return "ask"
+	}
+}
+
+// ParseAction converts a config.yaml action name to an Action
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "allow":
+		return Allow, nil
+	case "deny":
+		return Deny, nil
+	case "ask":
+		return Ask, nil
+	}
+	return Ask, fmt.Errorf("unknown permission action %q (want allow, deny or ask)", s)
+}
+
+// PathArguments are the tool arguments treated as file system paths
+var PathArguments = []string{"path", "paths", "file_path", "work_dir"}
+
+// Decision is the result of evaluating a tool call
+type Decision struct {
+	Action Action
+	// Reason explains a denial or why a call needs approval; it may be empty
+	Reason string
+}
+
+// rule is a compiled config.PermissionRule
+type rule struct {
+	tool           *regexp.Regexp
+	action         Action
+	args           map[string]*regexp.Regexp
+	outsideWorkDir bool
+	network        bool
+	reason         string
+}
+
+// Policy evaluates tool calls against an ordered list of rules. The first matching
+// rule decides; calls no rule matches are allowed when the tool is auto-approved
+// and asked about otherwise.
+type Policy struct {
+	rules   []rule
+	tools   config.ToolsConfig
+	workDir string
+}
+
+// New compiles the permission rules of the tools configuration. Paths are checked
+// against workDir, or the current working directory when it is empty.
+func New(tools config.ToolsConfig, workDir string) (*Policy, error) {
+	if workDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		workDir = wd
+	}
+	p := &Policy{tools: tools, workDir: resolve(workDir)}
+
+	for i, r := range tools.Permissions {
+		if r.Tool == "" {
+			return nil, fmt.Errorf("tools.permissions[%d]: tool is required", i)
+		}
+		action, err := ParseAction(r.Action)
+		if err != nil {
+			return nil, fmt.Errorf("tools.permissions[%d]: %w", i, err)
+		}
+		compiled := rule{
+			tool:           compileGlob(r.Tool),
+			action:         action,
+			args:           make(map[string]*regexp.Regexp, len(r.Args)),
+			outsideWorkDir: r.OutsideWorkDir,
+			network:        r.Network,
+			reason:         r.Reason,
+		}
+		for name, pattern := range r.Args {
+			compiled.args[name] = compileGlob(pattern)
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// Evaluate decides what happens to a call of the named tool with the given input
+// Shell scripts in the input are analysed as well: forbidden scripts are denied and
+// risky ones are asked about even when a rule or the allowlist would allow them.
+func (p *Policy) Evaluate(tool string, input map[string]interface{}) Decision {
+	shell := p.CheckShell(tool, input)
+	if shell.Risk == Forbidden {
+		return Decision{Action: Deny, Reason: strings.Join(shell.Reasons, "; ")}
+	}
+
+	decision := Decision{Action: Ask}
+	matched := false
+	for _, r := range p.rules {
+		if p.matches(r, tool, input) {
+			decision, matched = Decision{Action: r.action, Reason: r.reason}, true
+			break
+		}
+	}
+	if !matched && p.tools.IsAutoApproved(tool) {
+		decision = Decision{Action: Allow}
+	}
+
+	if decision.Action != Deny && shell.Risk == Risky {
+		return Decision{Action: Ask, Reason: strings.Join(shell.Reasons, "; ")}
+	}
+	return decision
+}
+
+// CheckShell analyses the shell scripts in a call of tool; those of the bash tool are
+// checked for the shell in the tools configuration
+func (p *Policy) CheckShell(tool string, input map[string]interface{}) ShellCheck {
+	if tool != "bash" {
+		return CheckShell(input)
+	}
+	shell := ""
+	if p != nil {
+		shell = p.tools.Bash.Shell
+	}
+	return CheckBash(input, shell)
+}
+
+func (p *Policy) matches(r rule, tool string, input map[string]interface{}) bool {
+	if !r.tool.MatchString(tool) {
+		return false
+	}
+	for name, pattern := range r.args {
+		if !anyMatch(pattern, argStrings(input[name])) {
+			return false
+		}
+	}
+	if r.outsideWorkDir && !p.touchesOutsideWorkDir(input) {
+		return false
+	}
+	if r.network && !UsesNetwork(input) {
+		return false
+	}
+	return true
+}
+
+// touchesOutsideWorkDir reports whether any path argument resolves outside the working directory
+func (p *Policy) touchesOutsideWorkDir(input map[string]interface{}) bool {
+	for _, name := range PathArguments {
+		for _, path := range argStrings(input[name]) {
+			if path == "" {
+				continue
+			}
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(p.workDir, path)
+			}
+			rel, err := filepath.Rel(p.workDir, resolve(path))
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve cleans path and follows symlinks for the part of it that exists, so a link
+// inside the working directory cannot point a tool elsewhere
+func resolve(path string) string {
+	path = filepath.Clean(path)
+	var rest []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...)
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// argStrings returns the string values of a tool argument, which may be a list
+func argStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			out = append(out, argStrings(item)...)
+		}
+		return out
+	case []string:
+		return v
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func anyMatch(re *regexp.Regexp, values []string) bool {
+	for _, v := range values {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob turns a pattern where "*" matches any text into an anchored regexp
+func compileGlob(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?s)^" + strings.Join(parts, ".*") + "$")
+}
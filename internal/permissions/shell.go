@@ -0,0 +1,420 @@
+package permissions
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Risk classifies what a shell script may do
+type Risk int
+
+const (
+	// Safe scripts only run commands that are not known to be destructive
+	Safe Risk = iota
+	// Risky scripts need the user's approval even when the tool is auto-approved
+	Risky
+	// Forbidden scripts are never run
+	Forbidden
+)
+
+// ShellCheck is the result of analysing a shell script
+type ShellCheck struct {
+	Risk Risk
+	// Reasons describe each risky or forbidden part of the script
+	Reasons []string
+	// Network is set when the script runs a command that accesses the network
+	Network bool
+}
+
+// add records a finding, keeping the highest risk
+func (c *ShellCheck) add(risk Risk, reason string) {
+	if risk > c.Risk {
+		c.Risk = risk
+	}
+	for _, r := range c.Reasons {
+		if r == reason {
+			return
+		}
+	}
+	c.Reasons = append(c.Reasons, reason)
+}
+
+func (c *ShellCheck) merge(other ShellCheck) {
+	for _, reason := range other.Reasons {
+		c.add(Safe, reason)
+	}
+	if other.Risk > c.Risk {
+		c.Risk = other.Risk
+	}
+	c.Network = c.Network || other.Network
+}
+
+// forbiddenCommands are never run, whatever the approval
+var forbiddenCommands = map[string]string{
+	"shutdown": "shuts the machine down",
+	"reboot":   "reboots the machine",
+	"halt":     "halts the machine",
+	"poweroff": "powers the machine off",
+}
+
+// riskyCommands always need approval, with the reason shown to the user
+var riskyCommands = map[string]string{
+	"rm": "deletes files", "rmdir": "removes directories", "shred": "destroys files",
+	"truncate": "truncates files", "dd": "writes raw data",
+	"chmod": "changes file permissions", "chown": "changes file ownership", "chgrp": "changes file ownership",
+	"kill": "stops processes", "pkill": "stops processes", "killall": "stops processes",
+	"sudo": "runs with elevated privileges", "su": "runs with elevated privileges", "doas": "runs with elevated privileges",
+	"iptables": "changes firewall rules", "ip6tables": "changes firewall rules", "ufw": "changes firewall rules",
+	"passwd": "changes user accounts", "useradd": "changes user accounts", "userdel": "changes user accounts",
+	"usermod": "changes user accounts", "groupadd": "changes user accounts", "groupdel": "changes user accounts",
+	"systemctl": "manages system services", "crontab": "changes scheduled jobs",
+	"fdisk": "changes disk partitions", "parted": "changes disk partitions",
+	"eval": "runs dynamically built code", "source": "runs another script", ".": "runs another script",
+}
+
+// wrapperCommands run the command given in their arguments
+var wrapperCommands = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "time": true, "nice": true,
+	"timeout": true, "xargs": true, "exec": true, "command": true, "builtin": true, "watch": true,
+}
+
+// shells run the script passed with -c, or a script file or their input otherwise
+var shells = map[string]bool{"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true}
+
+// inlineCodeFlags are the short flags with which interpreters run code given on the
+// command line, by interpreter name without its version
+var inlineCodeFlags = map[string]string{"python": "c", "perl": "eE", "ruby": "e", "node": "ep", "nodejs": "ep", "php": "r"}
+
+// findCommandActions run the command that follows them, up to ";" or "+", for every
+// file find matches
+var findCommandActions = map[string]bool{"-exec": true, "-execdir": true, "-ok": true, "-okdir": true}
+
+// safeDevices can be written to without approval
+var safeDevices = map[string]bool{"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true}
+
+// systemDirs are locations outside a project that scripts should not write to unasked
+var systemDirs = []string{"/dev/", "/proc/", "/sys/", "/etc/", "/boot/", "/usr/", "/bin/", "/sbin/", "/lib/"}
+
+// CheckShell analyses the shell scripts in a tool input and returns the combined result
+func CheckShell(input map[string]interface{}) ShellCheck {
+	var check ShellCheck
+	for _, name := range ShellArguments {
+		for _, script := range argStrings(input[name]) {
+			check.merge(CheckScript(script))
+		}
+	}
+	return check
+}
+
+// startupEnv are variables that make a shell run code of their choosing as it starts
+var startupEnv = []string{"BASH_ENV", "ENV", "ZDOTDIR", "PROMPT_COMMAND"}
+
+// checkedShells are the shells whose scripts CheckScript understands; "" is bash
+var checkedShells = []string{"", "bash", "sh"}
+
+// CheckBash analyses a call of the bash tool whose script runs in shell, the configured
+// one. Scripts for other shells than bash and sh cannot be parsed and always need
+// approval, as do login shells; variables that run code at startup are refused.
+func CheckBash(input map[string]interface{}, shell string) ShellCheck {
+	check := CheckShell(input)
+	if !slices.Contains(checkedShells, shell) {
+		check.add(Risky, "runs in "+shell+", whose scripts cannot be checked")
+	}
+	if login, _ := input["login"].(bool); login {
+		check.add(Risky, "runs a login shell, which reads the user's profile")
+	}
+	env, _ := input["env"].(map[string]interface{})
+	for _, name := range startupEnv {
+		if _, ok := env[name]; ok {
+			check.add(Forbidden, "sets "+name+", which runs code as the shell starts")
+		}
+	}
+	return check
+}
+
+// CheckScript parses a shell script and classifies every command it would run,
+// including those in pipelines, lists, subshells and command substitutions
+func CheckScript(script string) ShellCheck {
+	var check ShellCheck
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		check.add(Risky, "script could not be parsed: "+err.Error())
+		return check
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			checkCall(&check, n.Args)
+		case *syntax.Redirect:
+			checkRedirect(&check, n)
+		}
+		return true
+	})
+	return check
+}
+
+// checkCall classifies a single simple command
+func checkCall(check *ShellCheck, args []*syntax.Word) {
+	if len(args) == 0 {
+		return // assignments only
+	}
+	name, ok := literal(args[0])
+	if !ok {
+		check.add(Risky, "runs a command chosen at run time")
+		return
+	}
+	operands := make([]string, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		// Non-literal arguments are kept as "$" so flags and targets stay in position
+		if lit, ok := literal(arg); ok {
+			operands = append(operands, lit)
+		} else {
+			operands = append(operands, "$")
+		}
+	}
+	checkCommand(check, name, operands)
+}
+
+// checkCommand classifies a command by name; args hold "$" for non-literal words
+func checkCommand(check *ShellCheck, name string, args []string) {
+	base := path.Base(name)
+
+	if reason, ok := forbiddenCommands[base]; ok {
+		check.add(Forbidden, base+" "+reason)
+		return
+	}
+	if strings.HasPrefix(base, "mkfs") {
+		check.add(Forbidden, base+" formats a file system")
+		return
+	}
+	if reason, ok := riskyCommands[base]; ok {
+		check.add(Risky, base+" "+reason)
+	}
+	if networkCommands[base] {
+		check.Network = true
+	}
+
+	switch {
+	case base == "rm":
+		checkRemove(check, args)
+	case base == "git":
+		checkGit(check, args)
+	case shells[base]:
+		checkShell(check, base, args)
+	case base == "find":
+		checkFind(check, args)
+	case inlineCodeFlags[strings.TrimRight(base, "0123456789.")] != "":
+		checkInterpreter(check, base, args)
+	case networkSubcommands[base] != nil:
+		if sub := firstOperand(args); sub >= 0 && networkSubcommands[base][args[sub]] {
+			check.Network = true
+		}
+	}
+
+	// Wrappers such as sudo, env and xargs run the command in their arguments
+	if wrapperCommands[base] {
+		if i := firstOperand(args); i >= 0 {
+			if args[i] == "$" {
+				check.add(Risky, base+" runs a command chosen at run time")
+				return
+			}
+			checkCommand(check, args[i], args[i+1:])
+		}
+	}
+}
+
+// checkRemove forbids recursive deletes of the root or home directory
+func checkRemove(check *ShellCheck, args []string) {
+	recursive := false
+	for _, arg := range args {
+		if arg == "--recursive" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "rR")) {
+			recursive = true
+		}
+	}
+	if !recursive {
+		return
+	}
+	for _, arg := range args {
+		switch arg {
+		case "/", "/*", "~", "~/", "~/*":
+			check.add(Forbidden, "rm -r "+arg+" deletes the whole system or home directory")
+		}
+	}
+}
+
+// checkGit flags git subcommands that reach a remote or discard local work
+func checkGit(check *ShellCheck, args []string) {
+	// Skip global options such as -C <dir> before the subcommand
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "-C" || args[i] == "-c" {
+			i++
+		}
+		i++
+	}
+	if i >= len(args) {
+		return
+	}
+	sub, rest := args[i], args[i+1:]
+	switch sub {
+	case "clone", "fetch", "pull", "ls-remote":
+		check.Network = true
+	case "push":
+		check.Network = true
+		check.add(Risky, "git push publishes commits")
+	case "reset":
+		if contains(rest, "--hard") {
+			check.add(Risky, "git reset --hard discards local changes")
+		}
+	case "clean":
+		check.add(Risky, "git clean deletes untracked files")
+	case "checkout", "restore":
+		if contains(rest, "--") || contains(rest, ".") || contains(rest, "-f") || contains(rest, "--force") {
+			check.add(Risky, "git "+sub+" may discard local changes")
+		}
+	}
+}
+
+// checkShell analyses the script of sh -c '...' and flags other nested shells
+func checkShell(check *ShellCheck, name string, args []string) {
+	for i, arg := range args {
+		if arg == "-c" && i+1 < len(args) {
+			if args[i+1] == "$" {
+				break
+			}
+			check.merge(CheckScript(args[i+1]))
+			return
+		}
+	}
+	check.add(Risky, name+" runs a script that cannot be checked")
+}
+
+// checkFind flags find -delete and classifies the commands of -exec and its kin
+func checkFind(check *ShellCheck, args []string) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-delete":
+			check.add(Risky, "find -delete deletes files")
+		case findCommandActions[args[i]] && i+1 < len(args):
+			end := i + 1
+			for end < len(args) && args[end] != ";" && args[end] != "+" {
+				end++
+			}
+			if args[i+1] == "$" {
+				check.add(Risky, "find "+args[i]+" runs a command chosen at run time")
+			} else {
+				checkCommand(check, args[i+1], args[i+2:end])
+			}
+			i = end
+		}
+	}
+}
+
+// checkInterpreter flags code passed on the command line of interpreters such as
+// python -c and perl -e, which cannot be checked like a shell script
+func checkInterpreter(check *ShellCheck, name string, args []string) {
+	flags := inlineCodeFlags[strings.TrimRight(name, "0123456789.")]
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return // the script file; the rest are its arguments
+		}
+		if arg == "--eval" || arg == "--print" ||
+			!strings.HasPrefix(arg, "--") && strings.ContainsAny(arg[1:], flags) {
+			check.add(Risky, name+" runs inline code that cannot be checked")
+			return
+		}
+	}
+}
+
+// checkRedirect flags output redirected into devices and system directories
+func checkRedirect(check *ShellCheck, r *syntax.Redirect) {
+	switch r.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrInOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+	default:
+		return
+	}
+	target, ok := literal(r.Word)
+	if !ok || safeDevices[target] || strings.HasPrefix(target, "/dev/fd/") {
+		return
+	}
+	for _, dir := range systemDirs {
+		if strings.HasPrefix(target, dir) {
+			check.add(Risky, "writes to "+target)
+			return
+		}
+	}
+}
+
+// literal returns the value of a word made only of literal and quoted text
+func literal(w *syntax.Word) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	var b strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			// Outside quotes a backslash escapes the next character, as in \; or \rm
+			b.WriteString(unescape(p.Value))
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, inner := range p.Parts {
+				lit, ok := inner.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				b.WriteString(lit.Value)
+			}
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// firstOperand returns the index of the first argument that is not an option,
+// assignment or duration, or -1
+func firstOperand(args []string) int {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+			continue
+		}
+		if arg != "" && strings.Trim(arg, "0123456789.smhd") == "" {
+			continue // timeout/nice values
+		}
+		return i
+	}
+	return -1
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unescape removes the backslashes of an unquoted word and the line continuations in it
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -122,12 +122,18 @@ func (c *AlibabaClient) Chat(ctx context.Context, messages []providers.ChatMessa
 		Tools:       openaiTools,
 		Temperature: float32(c.temperature),
 	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
 
 	// Send request to Alibaba Qwen via OpenAI-compatible API
 	resp, err := c.client.CreateChatCompletion(ctx, req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create chat completion: %w", err)
 	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
 
 	// Convert response to our format
 	resultMessages := make([]providers.ChatMessage, 0)
@@ -199,7 +205,11 @@ func (c *AlibabaClient) StreamChat(ctx context.Context, model string, messages [
 		Messages:    openaiMessages,
 		Temperature: float32(temperature),
 		Stream:      true,
+		// Ask for a final usage chunk so token counts can be tracked
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
 
 	// Create stream
 	stream, err := c.client.CreateChatCompletionStream(ctx, req)
@@ -220,10 +230,17 @@ func (c *AlibabaClient) StreamChat(ctx context.Context, model string, messages [
 				if err.Error() == "EOF" {
 					return
 				}
-				responseChan <- fmt.Sprintf("Error: %v", err)
+				providers.StreamFailed(ctx, responseChan, err)
 				return
 			}
 
+			if response.Usage != nil {
+				providers.RecordUsage(ctx, providers.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				})
+			}
+
 			if len(response.Choices) > 0 {
 				delta := response.Choices[0].Delta
 				if delta.Content != "" {
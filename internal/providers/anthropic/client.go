@@ -2,13 +2,17 @@ package anthropic
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
 	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
 )
 
 // Enable debug logs for Anthropic provider if MAGIKARP_DEBUG=1
@@ -16,7 +20,9 @@ var anthropicDebug = os.Getenv("MAGIKARP_DEBUG") == "1"
 var debugFile *os.File
 
 func init() {
-	// Always try to create debug file to test if this init runs
+	if !anthropicDebug {
+		return
+	}
 	var err error
 	debugFile, err = os.OpenFile("magikarp_debug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
@@ -34,32 +40,66 @@ func init() {
 func debugLog(format string, args ...interface{}) {
 	if anthropicDebug && debugFile != nil {
 		timestamp := time.Now().Format("2006/01/02 15:04:05")
-		fmt.Fprintf(debugFile, "%s [Anthropic] "+format+"\n", append([]interface{}{timestamp}, args...)...)
+		fmt.Fprintf(debugFile, "%s [Anthropic] %s\n", timestamp, redact.String(fmt.Sprintf(format, args...)))
 		debugFile.Sync() // Flush immediately so we can tail -f
 	}
 }
 
+// DefaultMaxTokens is the response length limit used when none is configured;
+// the Messages API requires one
+const DefaultMaxTokens = 4096
+
 // AnthropicClient implements the Provider interface for Anthropic
 type AnthropicClient struct {
 	client       *anthropic.Client
 	apiKey       string
 	models       []string
 	temperature  float64
+	maxTokens    int
 	systemPrompt string
 }
 
-// New creates a new Anthropic provider
-func New(apiKey string, models []string, temperature float64, systemPrompt string) *AnthropicClient {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+// New creates a new Anthropic provider. A maxTokens of zero uses DefaultMaxTokens.
+func New(apiKey string, models []string, temperature float64, maxTokens int, systemPrompt string) *AnthropicClient {
+	// Retries are handled by the orchestration retry decorator
+	client := anthropic.NewClient(option.WithAPIKey(apiKey), option.WithMaxRetries(0))
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
 	return &AnthropicClient{
 		client:       &client,
 		apiKey:       apiKey,
 		models:       models,
 		temperature:  temperature,
+		maxTokens:    maxTokens,
 		systemPrompt: systemPrompt,
 	}
 }
 
+// outputLimits returns the reply length limit and stop sequences of a request: those
+// attached to ctx, with the client's limit as the default
+func (c *AnthropicClient) outputLimits(ctx context.Context) (int64, []string) {
+	limits := providers.OutputLimitsFrom(ctx)
+	if limits.MaxTokens > 0 {
+		return int64(limits.MaxTokens), limits.Stop
+	}
+	return int64(c.maxTokens), limits.Stop
+}
+
+// applyThinking enables extended thinking on params when ctx asks for it. Thinking
+// runs at a fixed temperature, and its budget counts towards max_tokens, which is
+// raised so that the answer keeps its own limit.
+func applyThinking(ctx context.Context, params *anthropic.MessageNewParams) {
+	thinking := providers.ThinkingFrom(ctx)
+	if !thinking.Enabled() {
+		return
+	}
+	budget := int64(thinking.Budget())
+	params.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+	params.Temperature = param.Opt[float64]{}
+	params.MaxTokens += budget
+}
+
 // NewAnthropicClient creates a new Anthropic client (legacy)
 func NewAnthropicClient(model string, configPath string) (*AnthropicClient, error) {
 	// Check if API key is set
@@ -67,7 +107,7 @@ func NewAnthropicClient(model string, configPath string) (*AnthropicClient, erro
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is not set")
 	}
 
-	return New(os.Getenv("ANTHROPIC_API_KEY"), []string{model}, 0.0, ""), nil
+	return New(os.Getenv("ANTHROPIC_API_KEY"), []string{model}, 0.0, 0, ""), nil
 }
 
 // Name returns the name of the provider
@@ -78,81 +118,64 @@ func (c *AnthropicClient) Name() string {
 // Chat sends a message to Anthropic and returns its response
 func (c *AnthropicClient) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
 	debugLog("Chat call: model list=%v, user/assistant messages=%d, tools=%d", c.models, len(messages), len(tools))
-	// Convert messages to Anthropic format
-	anthropicMessages := make([]anthropic.MessageParam, 0)
-
-	systemPrompt := c.systemPrompt
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
-			continue
-		} else if msg.Role == providers.RoleUser {
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
-		} else if msg.Role == providers.RoleAssistant {
-			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)))
-		} else if msg.Role == providers.RoleTool {
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
+	system, anthropicMessages := c.convertMessages(messages)
+
+	// The API rejects tool blocks in requests that define no tools, so declare the
+	// tools already called and forbid new calls
+	var toolChoice anthropic.ToolChoiceUnionParam
+	if len(tools) == 0 {
+		if tools = calledTools(messages); len(tools) > 0 {
+			toolChoice = anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
 		}
 	}
 
-	// Convert tools to Anthropic format
-	anthropicTools := make([]anthropic.ToolUnionParam, len(tools))
-	for i, tool := range tools {
-		// tool.InputSchema is full schema map; extract standard fields
-		props := map[string]any{}
-		if p, ok := tool.InputSchema["properties"].(map[string]any); ok {
-			props = p
-		}
-		req := toStringSlice(tool.InputSchema["required"])
-
-		schema := anthropic.ToolInputSchemaParam{
-			Type:       "object", // plain string
-			Properties: props,
-			Required:   req,
-		}
-		anthropicTools[i] = anthropic.ToolUnionParam{
-			OfTool: &anthropic.ToolParam{
-				Name:        tool.Name,
-				Description: anthropic.String(tool.Description),
-				InputSchema: schema,
-			},
-		}
-	}
+	anthropicTools := toAnthropicTools(tools)
 
 	if len(c.models) == 0 {
 		return nil, nil, fmt.Errorf("anthropic client has no model configured")
 	}
 	model := c.models[0]
 
-	// Prepare system prompt parameter
-	var systemBlocks []anthropic.TextBlockParam
-	if systemPrompt != "" {
-		systemBlocks = []anthropic.TextBlockParam{{Type: "text", Text: systemPrompt}}
-	}
-
 	// Send request to Anthropic
-	message, err := c.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:       anthropic.Model(model),
-		MaxTokens:   1024,
-		Messages:    anthropicMessages,
-		Tools:       anthropicTools,
-		System:      systemBlocks,
-		Temperature: anthropic.Float(c.temperature),
-	})
+	maxTokens, stop := c.outputLimits(ctx)
+	params := anthropic.MessageNewParams{
+		Model:         anthropic.Model(model),
+		MaxTokens:     maxTokens,
+		StopSequences: stop,
+		Messages:      anthropicMessages,
+		Tools:         anthropicTools,
+		ToolChoice:    toolChoice,
+		System:        system,
+		Temperature:   anthropic.Float(c.temperature),
+	}
+	applyThinking(ctx, &params)
+	streamed := providers.TextRecorderFrom(ctx) != nil
+	message, err := c.send(ctx, params)
 	if err != nil {
 		debugLog("Chat error: %v", err)
 		return nil, nil, err
 	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     int(message.Usage.InputTokens),
+		CompletionTokens: int(message.Usage.OutputTokens),
+		CacheReadTokens:  int(message.Usage.CacheReadInputTokens),
+		CacheWriteTokens: int(message.Usage.CacheCreationInputTokens),
+	})
 
 	// Convert response to our format
 	resultMessages := make([]providers.ChatMessage, 0)
 	var toolUses []providers.ToolUse
+	var thinking []providers.ThinkingBlock
 
 	for _, content := range message.Content {
 		switch content.Type {
+		case "thinking":
+			if !streamed {
+				providers.RecordReasoning(ctx, content.Thinking)
+			}
+			thinking = append(thinking, providers.ThinkingBlock{Text: content.Thinking, Signature: content.Signature})
+		case "redacted_thinking":
+			thinking = append(thinking, providers.ThinkingBlock{Redacted: content.Data})
 		case "text":
 			resultMessages = append(resultMessages, providers.ChatMessage{
 				Role:    providers.RoleAssistant,
@@ -167,39 +190,99 @@ func (c *AnthropicClient) Chat(ctx context.Context, messages []providers.ChatMes
 		}
 	}
 
+	// The thinking that led to tool calls is sent back with their results
+	if len(toolUses) > 0 && len(thinking) > 0 {
+		if len(resultMessages) == 0 {
+			resultMessages = append(resultMessages, providers.ChatMessage{Role: providers.RoleAssistant})
+		}
+		resultMessages[0].Thinking = thinking
+	}
+
 	return resultMessages, toolUses, nil
 }
 
+// send sends params and returns the message. With a text recorder attached to ctx the
+// request is streamed, its text and thinking recorded as they arrive and the message
+// accumulated from the events.
+func (c *AnthropicClient) send(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	if providers.TextRecorderFrom(ctx) == nil {
+		return c.client.Messages.New(ctx, params)
+	}
+	stream := c.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+	message := &anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, err
+		}
+		if event.Type == "content_block_delta" {
+			switch event.Delta.Type {
+			case "text_delta":
+				providers.RecordText(ctx, event.Delta.Text)
+			case "thinking_delta":
+				providers.RecordReasoning(ctx, event.Delta.Thinking)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// CountTokens counts the input tokens of a request with Anthropic's counting endpoint
+func (c *AnthropicClient) CountTokens(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) (int, error) {
+	if len(c.models) == 0 {
+		return 0, fmt.Errorf("anthropic client has no model configured")
+	}
+	if len(tools) == 0 {
+		tools = calledTools(messages)
+	}
+	system, anthropicMessages := c.convertMessages(messages)
+	var countTools []anthropic.MessageCountTokensToolUnionParam
+	for _, tool := range toAnthropicTools(tools) {
+		countTools = append(countTools, anthropic.MessageCountTokensToolUnionParam{OfTool: tool.OfTool})
+	}
+	count, err := c.client.Messages.CountTokens(ctx, anthropic.MessageCountTokensParams{
+		Model:    anthropic.Model(c.models[0]),
+		Messages: anthropicMessages,
+		System:   anthropic.MessageCountTokensParamsSystemUnion{OfTextBlockArray: system},
+		Tools:    countTools,
+	})
+	if err != nil {
+		debugLog("CountTokens error: %v", err)
+		return 0, err
+	}
+	return int(count.InputTokens), nil
+}
+
 // StreamChat sends a message to Anthropic and returns a streaming response
 func (c *AnthropicClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
-	// Convert messages to Anthropic format
-	anthropicMessages := make([]anthropic.MessageParam, 0)
-	systemPrompt := c.systemPrompt
-
 	debugLog("StreamChat: model=%s, temperature=%f, total_messages=%d", model, temperature, len(messages))
+	system, anthropicMessages := c.convertMessages(messages)
 
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
-			continue
-		} else if msg.Role == providers.RoleUser {
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
-		} else if msg.Role == providers.RoleAssistant {
-			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)))
-		}
+	// Tool blocks in the history need their tools declared, as in Chat
+	var toolChoice anthropic.ToolChoiceUnionParam
+	called := calledTools(messages)
+	if len(called) > 0 {
+		toolChoice = anthropic.ToolChoiceUnionParam{OfNone: &anthropic.ToolChoiceNoneParam{}}
 	}
 
 	// Create stream
-	stream := c.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
-		Model:       anthropic.Model(model),
-		MaxTokens:   1024,
-		Messages:    anthropicMessages,
-		System:      []anthropic.TextBlockParam{{Type: "text", Text: systemPrompt}},
-		Temperature: anthropic.Float(temperature),
-	})
+	maxTokens, stop := c.outputLimits(ctx)
+	params := anthropic.MessageNewParams{
+		Model:         anthropic.Model(model),
+		Tools:         toAnthropicTools(called),
+		ToolChoice:    toolChoice,
+		MaxTokens:     maxTokens,
+		StopSequences: stop,
+		Messages:      anthropicMessages,
+		System:        system,
+		Temperature:   anthropic.Float(temperature),
+	}
+	applyThinking(ctx, &params)
+	stream := c.client.Messages.NewStreaming(ctx, params)
 
 	debugLog("StreamChat: stream created, waiting for events")
 
@@ -210,13 +293,25 @@ func (c *AnthropicClient) StreamChat(ctx context.Context, model string, messages
 		defer close(responseChan)
 		defer stream.Close()
 
+		var usage providers.Usage
+		defer func() { providers.RecordUsage(ctx, usage) }()
+
 		for stream.Next() {
 			event := stream.Current()
 			debugLog("StreamChat: received event type=%s", event.Type)
 			switch event.Type {
+			case "message_start":
+				usage.PromptTokens = int(event.Message.Usage.InputTokens)
+				usage.CacheReadTokens = int(event.Message.Usage.CacheReadInputTokens)
+				usage.CacheWriteTokens = int(event.Message.Usage.CacheCreationInputTokens)
+			case "message_delta":
+				usage.CompletionTokens = int(event.Usage.OutputTokens)
 			case "content_block_delta":
-				if event.Delta.Type == "text_delta" {
+				switch event.Delta.Type {
+				case "text_delta":
 					responseChan <- event.Delta.Text
+				case "thinking_delta":
+					providers.RecordReasoning(ctx, event.Delta.Thinking)
 				}
 			case "message_stop":
 				return
@@ -224,32 +319,175 @@ func (c *AnthropicClient) StreamChat(ctx context.Context, model string, messages
 		}
 
 		if err := stream.Err(); err != nil {
-			// Send error as final message
+			// Report the error that ended the stream
 			debugLog("StreamChat: stream error: %v", err)
-			responseChan <- fmt.Sprintf("Error: %v", err)
+			providers.StreamFailed(ctx, responseChan, err)
 		}
 	}()
 
 	return responseChan, nil
 }
 
-// SendToolResult sends a tool result back to Anthropic and returns its response
+// SendToolResult sends tool results back to Anthropic and returns its response.
+// messages must end with the assistant message that requested the tools.
 func (c *AnthropicClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
-	// Append each tool result as a ChatMessage with RoleTool so Chat() can convert.
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Name
+		}
+	}
+
 	augmented := make([]providers.ChatMessage, len(messages))
 	copy(augmented, messages)
-
 	for _, res := range toolResults {
 		augmented = append(augmented, providers.ChatMessage{
-			Role:    providers.RoleTool,
-			Content: res.Content,
+			Role:       providers.RoleTool,
+			Content:    res.Content,
+			ToolCallID: res.ID,
+			ToolName:   names[res.ID],
+			IsError:    res.IsError,
 		})
 	}
 
-	// Continue conversation without re-sending tool definitions (nil tools).
+	// Continue the conversation without offering tools
 	return c.Chat(ctx, augmented, nil)
 }
 
+// calledTools returns minimal definitions of the tools called in a conversation
+func calledTools(messages []providers.ChatMessage) []providers.Tool {
+	var tools []providers.Tool
+	seen := map[string]bool{}
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			if !seen[call.Name] {
+				seen[call.Name] = true
+				tools = append(tools, providers.Tool{Name: call.Name, InputSchema: map[string]any{"type": "object"}})
+			}
+		}
+	}
+	return tools
+}
+
+// convertMessages splits a conversation into the system prompt blocks and the
+// messages of the Messages API. System messages in the conversation replace the
+// configured prompt; when there are several, they are sent as separate blocks.
+// Assistant tool calls are replayed as tool_use blocks and tool results are sent as
+// tool_result blocks answering them. The last system block is marked as a prompt
+// cache breakpoint.
+func (c *AnthropicClient) convertMessages(messages []providers.ChatMessage) ([]anthropic.TextBlockParam, []anthropic.MessageParam) {
+	var system []anthropic.TextBlockParam
+	out := make([]anthropic.MessageParam, 0, len(messages))
+	// called holds the IDs of the tool_use blocks sent so far
+	called := map[string]bool{}
+	// results collects consecutive tool results into a single user message
+	var results []anthropic.ContentBlockParamUnion
+	flush := func() {
+		if len(results) > 0 {
+			out = append(out, anthropic.NewUserMessage(results...))
+			results = nil
+		}
+	}
+
+	for _, msg := range messages {
+		if msg.Role != providers.RoleTool {
+			flush()
+		}
+		switch msg.Role {
+		case providers.RoleSystem:
+			if strings.TrimSpace(msg.Content) != "" {
+				system = append(system, anthropic.TextBlockParam{Text: msg.Content})
+			}
+		case providers.RoleUser:
+			if msg.Content == "" {
+				continue
+			}
+			out = append(out, anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)))
+		case providers.RoleAssistant:
+			var blocks []anthropic.ContentBlockParamUnion
+			// Thinking comes first, as the model produced it
+			for _, block := range msg.Thinking {
+				if block.Redacted != "" {
+					blocks = append(blocks, anthropic.NewRedactedThinkingBlock(block.Redacted))
+				} else {
+					blocks = append(blocks, anthropic.NewThinkingBlock(block.Signature, block.Text))
+				}
+			}
+			// Text blocks must be non-empty; tool-call-only turns carry no text
+			if msg.Content != "" {
+				blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
+			}
+			for _, call := range msg.ToolCalls {
+				input := call.Input
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropic.NewToolUseBlock(call.ID, input, call.Name))
+				called[call.ID] = true
+			}
+			if len(blocks) > 0 {
+				out = append(out, anthropic.NewAssistantMessage(blocks...))
+			}
+		case providers.RoleTool:
+			// Results without a tool_use to answer can only be passed along as text
+			if !called[msg.ToolCallID] {
+				flush()
+				text := msg.Content
+				if msg.ToolName != "" {
+					text = fmt.Sprintf("[Result of %s]\n%s", msg.ToolName, msg.Content)
+				}
+				if text != "" {
+					out = append(out, anthropic.NewUserMessage(anthropic.NewTextBlock(text)))
+				}
+				continue
+			}
+			results = append(results, anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, msg.IsError))
+		}
+	}
+	flush()
+
+	if len(system) == 0 && strings.TrimSpace(c.systemPrompt) != "" {
+		system = []anthropic.TextBlockParam{{Text: c.systemPrompt}}
+	}
+	// The cache breakpoint on the last system block caches the tools and the whole
+	// system prompt, which are resent unchanged every turn
+	if len(system) > 0 {
+		system[len(system)-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	return system, out
+}
+
+// toAnthropicTools converts tool definitions to the Anthropic format, marking the
+// end of the list as a prompt cache breakpoint
+func toAnthropicTools(tools []providers.Tool) []anthropic.ToolUnionParam {
+	anthropicTools := make([]anthropic.ToolUnionParam, len(tools))
+	for i, tool := range tools {
+		// tool.InputSchema is full schema map; extract standard fields
+		props := map[string]any{}
+		if p, ok := tool.InputSchema["properties"].(map[string]any); ok {
+			props = p
+		}
+		req := toStringSlice(tool.InputSchema["required"])
+
+		schema := anthropic.ToolInputSchemaParam{
+			Type:       "object", // plain string
+			Properties: props,
+			Required:   req,
+		}
+		param := &anthropic.ToolParam{Name: tool.Name, InputSchema: schema}
+		if tool.Description != "" {
+			param.Description = anthropic.String(tool.Description)
+		}
+		anthropicTools[i] = anthropic.ToolUnionParam{OfTool: param}
+	}
+	// A breakpoint after the last tool keeps the tool schemas cached even when the
+	// system prompt changes
+	if len(anthropicTools) > 0 {
+		anthropicTools[len(anthropicTools)-1].OfTool.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	}
+	return anthropicTools
+}
+
 func toStringSlice(v any) []string {
 	if v == nil {
 		return nil
@@ -0,0 +1,79 @@
+package azureopenai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// cognitiveServicesScope is the Azure AD resource that Azure OpenAI tokens are issued for
+const cognitiveServicesScope = "https://cognitiveservices.azure.com"
+
+// tokenRefreshMargin refreshes tokens slightly before they expire
+const tokenRefreshMargin = 2 * time.Minute
+
+// azureCLIToken fetches Azure AD access tokens with the Azure CLI (`az login`) and caches
+// them until shortly before they expire.
+type azureCLIToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a valid access token, refreshing it through the Azure CLI when needed
+func (t *azureCLIToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.expires) > tokenRefreshMargin {
+		return t.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "az", "account", "get-access-token",
+		"--resource", cognitiveServicesScope, "--output", "json").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get Azure AD token (run `az login` or set key to an Azure AD token): %w", err)
+	}
+
+	var resp struct {
+		AccessToken string `json:"accessToken"`
+		ExpiresOn   int64  `json:"expires_on"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return "", fmt.Errorf("failed to parse Azure CLI token response: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("azure CLI returned an empty access token")
+	}
+
+	t.token = resp.AccessToken
+	t.expires = time.Unix(resp.ExpiresOn, 0)
+	if resp.ExpiresOn == 0 {
+		// Older CLI versions omit expires_on; assume the default one hour lifetime
+		t.expires = time.Now().Add(time.Hour)
+	}
+	return t.token, nil
+}
+
+// tokenTransport sets a fresh bearer token on every request
+type tokenTransport struct {
+	source *azureCLIToken
+	base   http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,313 @@
+package azureopenai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultAPIVersion is the Azure OpenAI REST API version used when none is configured
+const DefaultAPIVersion = "2024-10-21"
+
+// Supported authentication modes
+const (
+	// AuthAPIKey authenticates with the resource's api-key header
+	AuthAPIKey = "api_key"
+	// AuthAzureAD authenticates with an Azure AD (Entra ID) bearer token
+	AuthAzureAD = "azure_ad"
+)
+
+// Config describes how to reach an Azure OpenAI resource
+type Config struct {
+	// Endpoint is the resource URL, e.g. https://my-resource.openai.azure.com
+	Endpoint string
+	// APIVersion is the api-version query parameter. Empty uses DefaultAPIVersion.
+	APIVersion string
+	// Auth is AuthAPIKey (default) or AuthAzureAD.
+	Auth string
+	// Key is the API key, or a pre-issued Azure AD token when Auth is AuthAzureAD.
+	// With Azure AD and no key, tokens are fetched from the Azure CLI.
+	Key string
+	// Deployments maps model names to deployment names. Models without an entry
+	// are assumed to be deployed under their own name.
+	Deployments map[string]string
+}
+
+// AzureOpenAIClient implements the Provider interface for Azure OpenAI deployments
+type AzureOpenAIClient struct {
+	client       *openai.Client
+	endpoint     string
+	models       []string
+	temperature  float64
+	systemPrompt string
+}
+
+// New creates a new Azure OpenAI provider
+func New(cfg Config, models []string, temperature float64, systemPrompt string) (*AzureOpenAIClient, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(cfg.Endpoint), "/")
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required (set base_url to https://<resource>.openai.azure.com)")
+	}
+
+	auth := strings.ToLower(strings.TrimSpace(cfg.Auth))
+	if auth == "" {
+		auth = AuthAPIKey
+	}
+
+	var clientConfig openai.ClientConfig
+	switch auth {
+	case AuthAPIKey:
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("API key not set (AZURE_OPENAI_API_KEY environment variable)")
+		}
+		clientConfig = openai.DefaultAzureConfig(cfg.Key, endpoint)
+	case AuthAzureAD:
+		clientConfig = openai.DefaultAzureConfig(cfg.Key, endpoint)
+		clientConfig.APIType = openai.APITypeAzureAD
+		if cfg.Key == "" {
+			// No static token: fetch and refresh tokens through the Azure CLI
+			clientConfig.HTTPClient = &http.Client{
+				Transport: &tokenTransport{source: &azureCLIToken{}, base: http.DefaultTransport},
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q (expected %q or %q)", cfg.Auth, AuthAPIKey, AuthAzureAD)
+	}
+
+	clientConfig.APIVersion = cfg.APIVersion
+	if clientConfig.APIVersion == "" {
+		clientConfig.APIVersion = DefaultAPIVersion
+	}
+
+	deployments := cfg.Deployments
+	clientConfig.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := deployments[model]; ok && deployment != "" {
+			return deployment
+		}
+		return model
+	}
+
+	return &AzureOpenAIClient{
+		client:       openai.NewClientWithConfig(clientConfig),
+		endpoint:     endpoint,
+		models:       models,
+		temperature:  temperature,
+		systemPrompt: systemPrompt,
+	}, nil
+}
+
+// Name returns the name of the provider
+func (c *AzureOpenAIClient) Name() string {
+	return "azure"
+}
+
+// convertMessages converts provider messages to the OpenAI chat format
+func (c *AzureOpenAIClient) convertMessages(messages []providers.ChatMessage, includeTools bool) []openai.ChatCompletionMessage {
+	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+
+	systemPrompt := c.systemPrompt
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleSystem:
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+		case providers.RoleUser:
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "user", Content: msg.Content})
+		case providers.RoleAssistant:
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "assistant", Content: msg.Content})
+		case providers.RoleTool:
+			if includeTools {
+				openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "user", Content: msg.Content})
+			}
+		}
+	}
+
+	if systemPrompt != "" {
+		systemMsg := openai.ChatCompletionMessage{Role: "system", Content: systemPrompt}
+		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
+	}
+	return openaiMessages
+}
+
+// Chat sends a message to the Azure OpenAI deployment and returns its response
+func (c *AzureOpenAIClient) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	if len(c.models) == 0 {
+		return nil, nil, fmt.Errorf("azure client has no model configured")
+	}
+
+	// Convert tools to OpenAI format
+	var openaiTools []openai.Tool
+	if len(tools) > 0 {
+		openaiTools = make([]openai.Tool, len(tools))
+		for i, tool := range tools {
+			openaiTools[i] = openai.Tool{
+				Type: "function",
+				Function: &openai.FunctionDefinition{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			}
+		}
+	}
+
+	model := c.models[0]
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: c.convertMessages(messages, true),
+		Tools:    openaiTools,
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.Stop = limits.Stop
+	// o-series models take max_completion_tokens instead of max_tokens, and reason
+	// with the effort asked for
+	if isOSeriesModel(model) {
+		req.MaxCompletionTokens = limits.MaxTokens
+		req.ReasoningEffort = providers.ThinkingFrom(ctx).Effort
+	} else {
+		req.MaxTokens = limits.MaxTokens
+	}
+	if format, ok := providers.ResponseFormatFrom(ctx); ok {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   format.Name,
+				Schema: format.Schema,
+			},
+		}
+	}
+	// Only set temperature for non-o* models (o1, o3 series have fixed parameters)
+	if !isOSeriesModel(model) {
+		req.Temperature = float32(c.temperature)
+	}
+
+	resp, err := c.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
+
+	// Convert response to our format
+	resultMessages := make([]providers.ChatMessage, 0)
+	var toolUses []providers.ToolUse
+
+	for _, choice := range resp.Choices {
+		if choice.Message.Content != "" {
+			resultMessages = append(resultMessages, providers.ChatMessage{
+				Role:    providers.RoleAssistant,
+				Content: choice.Message.Content,
+			})
+		}
+
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function.Name == "" {
+				continue
+			}
+			toolUses = append(toolUses, providers.ToolUse{
+				ID:    toolCall.ID,
+				Name:  toolCall.Function.Name,
+				Input: json.RawMessage(toolCall.Function.Arguments),
+			})
+		}
+	}
+
+	return resultMessages, toolUses, nil
+}
+
+// StreamChat sends a message to the Azure OpenAI deployment and returns a streaming response
+func (c *AzureOpenAIClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	req := openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: c.convertMessages(messages, false),
+		Stream:   true,
+		// Ask for a final usage chunk so token counts can be tracked
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.Stop = limits.Stop
+	// o-series models take max_completion_tokens instead of max_tokens, and reason
+	// with the effort asked for
+	if isOSeriesModel(model) {
+		req.MaxCompletionTokens = limits.MaxTokens
+		req.ReasoningEffort = providers.ThinkingFrom(ctx).Effort
+	} else {
+		req.MaxTokens = limits.MaxTokens
+	}
+	if !isOSeriesModel(model) {
+		req.Temperature = float32(temperature)
+	}
+
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion stream: %w", err)
+	}
+
+	responseChan := make(chan string, 100)
+
+	go func() {
+		defer close(responseChan)
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				providers.StreamFailed(ctx, responseChan, err)
+				return
+			}
+
+			if response.Usage != nil {
+				providers.RecordUsage(ctx, providers.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				})
+			}
+
+			// Azure sends content filter results as chunks without choices
+			if len(response.Choices) > 0 {
+				if delta := response.Choices[0].Delta; delta.Content != "" {
+					responseChan <- delta.Content
+				}
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// SendToolResult sends a tool result back to Azure OpenAI and returns its response
+func (c *AzureOpenAIClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	// Append each tool result as a ChatMessage with RoleTool so Chat() can convert.
+	augmented := make([]providers.ChatMessage, len(messages))
+	copy(augmented, messages)
+
+	for _, res := range toolResults {
+		augmented = append(augmented, providers.ChatMessage{
+			Role:    providers.RoleTool,
+			Content: res.Content,
+		})
+	}
+
+	// Continue conversation without re-sending tool definitions (nil tools).
+	return c.Chat(ctx, augmented, nil)
+}
+
+// isOSeriesModel checks if the model is from the o-series (o1, o3) which have fixed parameters
+func isOSeriesModel(model string) bool {
+	model = strings.ToLower(model)
+	return strings.HasPrefix(model, "o1") || strings.HasPrefix(model, "o3")
+}
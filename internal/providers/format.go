@@ -0,0 +1,28 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ResponseFormat asks for a reply that is a JSON document conforming to Schema.
+// Providers with structured output constrain the reply natively; the agent validates
+// it either way (see orchestration.Turn.Schema).
+type ResponseFormat struct {
+	// Name identifies the schema to providers that want one
+	Name   string
+	Schema json.RawMessage
+}
+
+type responseFormatKey struct{}
+
+// WithResponseFormat returns a context whose requests ask for replies in format
+func WithResponseFormat(ctx context.Context, format ResponseFormat) context.Context {
+	return context.WithValue(ctx, responseFormatKey{}, format)
+}
+
+// ResponseFormatFrom returns the format attached to ctx and whether there is one
+func ResponseFormatFrom(ctx context.Context) (ResponseFormat, bool) {
+	format, ok := ctx.Value(responseFormatKey{}).(ResponseFormat)
+	return format, ok
+}
@@ -18,6 +18,8 @@ type GeminiClient struct {
 	models       []string
 	temperature  float64
 	systemPrompt string
+	// embeddingModel is the model used by Embed; empty uses DefaultEmbeddingModel
+	embeddingModel string
 }
 
 // New creates a new Gemini provider
@@ -64,30 +66,15 @@ func (c *GeminiClient) Chat(ctx context.Context, messages []providers.ChatMessag
 	model := c.client.GenerativeModel(modelName)
 	temp32 := float32(c.temperature)
 	model.Temperature = &temp32
-
-	// Convert messages to Gemini format
-	geminiMessages := make([]*genai.Content, 0)
-	systemPrompt := c.systemPrompt
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
-			continue
-		}
-
-		role := "user"
-		if msg.Role == providers.RoleAssistant {
-			role = "model"
-		}
-
-		geminiMessages = append(geminiMessages, &genai.Content{
-			Parts: []genai.Part{
-				genai.Text(msg.Content),
-			},
-			Role: role,
-		})
+	setOutputLimits(ctx, model)
+
+	// Convert messages and tools to Gemini format
+	geminiMessages, systemPrompt := c.toContents(messages)
+	if len(tools) > 0 {
+		model.Tools = []*genai.Tool{{FunctionDeclarations: toFunctionDeclarations(tools)}}
+	} else if _, ok := providers.ResponseFormatFrom(ctx); ok {
+		// JSON mode cannot be combined with function calling
+		model.ResponseMIMEType = "application/json"
 	}
 
 	// Attach system instruction if provided
@@ -107,38 +94,43 @@ func (c *GeminiClient) Chat(ctx context.Context, messages []providers.ChatMessag
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to send message to Gemini: %w", err)
 	}
+	if resp.UsageMetadata != nil {
+		providers.RecordUsage(ctx, providers.Usage{
+			PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+		})
+	}
 
 	// Convert response to our format
 	resultMessages := make([]providers.ChatMessage, 0)
 	var toolUses []providers.ToolUse
 
 	for _, candidate := range resp.Candidates {
-		if candidate.Content != nil {
-			for _, part := range candidate.Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					resultMessages = append(resultMessages, providers.ChatMessage{
-						Role:    providers.RoleAssistant,
-						Content: string(text),
-					})
-				}
-			}
+		if candidate.Content == nil {
+			continue
 		}
-
-		// Handle function calls (Gemini uses a custom JSON format)
-		if candidate.Content != nil {
-			for _, part := range candidate.Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					var functionCall struct {
-						Name      string          `json:"name"`
-						Arguments json.RawMessage `json:"arguments"`
-					}
-					if err := json.Unmarshal([]byte(text), &functionCall); err == nil && functionCall.Name != "" {
-						toolUses = append(toolUses, providers.ToolUse{
-							Name:  functionCall.Name,
-							Input: functionCall.Arguments,
-						})
-					}
+		for _, part := range candidate.Content.Parts {
+			switch part := part.(type) {
+			case genai.Text:
+				resultMessages = append(resultMessages, providers.ChatMessage{
+					Role:    providers.RoleAssistant,
+					Content: string(part),
+				})
+			case genai.FunctionCall:
+				args := part.Args
+				if args == nil {
+					args = map[string]any{}
+				}
+				input, err := json.Marshal(args)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to encode arguments of %s: %w", part.Name, err)
 				}
+				// Gemini does not assign call IDs; number them so results can be matched
+				toolUses = append(toolUses, providers.ToolUse{
+					ID:    fmt.Sprintf("call_%d_%s", len(toolUses), part.Name),
+					Name:  part.Name,
+					Input: input,
+				})
 			}
 		}
 	}
@@ -152,31 +144,10 @@ func (c *GeminiClient) StreamChat(ctx context.Context, model string, messages []
 	geminiModel := c.client.GenerativeModel(model)
 	temp32 := float32(temperature)
 	geminiModel.Temperature = &temp32
+	setOutputLimits(ctx, geminiModel)
 
 	// Convert messages to Gemini format
-	geminiMessages := make([]*genai.Content, 0)
-	systemPrompt := c.systemPrompt
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
-			continue
-		}
-
-		role := "user"
-		if msg.Role == providers.RoleAssistant {
-			role = "model"
-		}
-
-		geminiMessages = append(geminiMessages, &genai.Content{
-			Parts: []genai.Part{
-				genai.Text(msg.Content),
-			},
-			Role: role,
-		})
-	}
+	geminiMessages, systemPrompt := c.toContents(messages)
 
 	// attach system prompt to model
 	if systemPrompt != "" {
@@ -199,16 +170,27 @@ func (c *GeminiClient) StreamChat(ctx context.Context, model string, messages []
 		lastMsg := geminiMessages[len(geminiMessages)-1]
 		iter := cs.SendMessageStream(ctx, lastMsg.Parts...)
 
+		// Usage metadata is cumulative; report the last value seen once the stream ends
+		var usage providers.Usage
+		defer func() { providers.RecordUsage(ctx, usage) }()
+
 		for {
 			resp, err := iter.Next()
 			if err != nil {
 				if err.Error() == "no more items in iterator" {
 					return
 				}
-				responseChan <- fmt.Sprintf("Error: %v", err)
+				providers.StreamFailed(ctx, responseChan, err)
 				return
 			}
 
+			if resp.UsageMetadata != nil {
+				usage = providers.Usage{
+					PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+					CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+				}
+			}
+
 			for _, candidate := range resp.Candidates {
 				if candidate.Content != nil {
 					for _, part := range candidate.Content.Parts {
@@ -224,16 +206,208 @@ func (c *GeminiClient) StreamChat(ctx context.Context, model string, messages []
 	return responseChan, nil
 }
 
-// SendToolResult sends a tool result back to Gemini and returns its response
+// SendToolResult sends tool results back to Gemini as function responses and returns
+// its response. messages must end with the assistant message that requested the tools.
+// No tools are offered with the results, so tool use through SendToolResult is a single
+// round; the agent loop offers the tools on every round through Chat instead.
 func (c *GeminiClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
-	// Add tool results to messages
+	// Function responses are matched by name, so look up the call each result answers
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Name
+		}
+	}
+
 	for _, result := range toolResults {
 		messages = append(messages, providers.ChatMessage{
-			Role:    providers.RoleTool,
-			Content: result.Content,
+			Role:       providers.RoleTool,
+			Content:    result.Content,
+			ToolCallID: result.ID,
+			ToolName:   names[result.ID],
 		})
 	}
 
-	// Continue the conversation with all tools available
+	// The model answers from the results without calling tools again
 	return c.Chat(ctx, messages, nil)
 }
+
+// setOutputLimits applies the output limits attached to ctx to model
+func setOutputLimits(ctx context.Context, model *genai.GenerativeModel) {
+	limits := providers.OutputLimitsFrom(ctx)
+	if limits.MaxTokens > 0 {
+		model.SetMaxOutputTokens(int32(limits.MaxTokens))
+	}
+	model.StopSequences = limits.Stop
+}
+
+// toContents converts messages to Gemini contents and returns them with the system
+// prompt to use. Assistant tool calls become FunctionCall parts and consecutive tool
+// results are grouped into a single content of FunctionResponse parts.
+func (c *GeminiClient) toContents(messages []providers.ChatMessage) ([]*genai.Content, string) {
+	contents := make([]*genai.Content, 0, len(messages))
+	systemPrompt := c.systemPrompt
+	var prevTool bool
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleSystem:
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+			prevTool = false
+			continue
+
+		case providers.RoleAssistant:
+			content := &genai.Content{Role: "model"}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, genai.Text(msg.Content))
+			}
+			for _, call := range msg.ToolCalls {
+				var args map[string]any
+				if len(call.Input) > 0 {
+					_ = json.Unmarshal(call.Input, &args)
+				}
+				content.Parts = append(content.Parts, genai.FunctionCall{Name: call.Name, Args: args})
+			}
+			if len(content.Parts) > 0 {
+				contents = append(contents, content)
+			}
+			prevTool = false
+
+		case providers.RoleTool:
+			var part genai.Part = genai.Text(msg.Content)
+			if msg.ToolName != "" {
+				part = genai.FunctionResponse{
+					Name:     msg.ToolName,
+					Response: map[string]any{"content": msg.Content},
+				}
+			}
+			if prevTool {
+				last := contents[len(contents)-1]
+				last.Parts = append(last.Parts, part)
+			} else {
+				contents = append(contents, &genai.Content{Role: "user", Parts: []genai.Part{part}})
+			}
+			prevTool = true
+
+		default:
+			contents = append(contents, &genai.Content{Role: "user", Parts: []genai.Part{genai.Text(msg.Content)}})
+			prevTool = false
+		}
+	}
+	return contents, systemPrompt
+}
+
+// toFunctionDeclarations converts tool definitions to Gemini function declarations
+func toFunctionDeclarations(tools []providers.Tool) []*genai.FunctionDeclaration {
+	decls := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		decl := &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+		}
+		// Gemini rejects object parameters without properties, so omit them instead
+		if params := toSchema(tool.InputSchema); params != nil && len(params.Properties) > 0 {
+			decl.Parameters = params
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+// toSchema converts a JSON Schema object to the OpenAPI subset Gemini supports.
+// Keywords Gemini does not understand are dropped.
+func toSchema(m map[string]interface{}) *genai.Schema {
+	if m == nil {
+		return nil
+	}
+	schema := &genai.Schema{}
+
+	switch t := m["type"].(type) {
+	case string:
+		schema.Type = schemaType(t)
+	case []interface{}:
+		// ["string", "null"] style unions become nullable types
+		for _, v := range t {
+			if name, _ := v.(string); name == "null" {
+				schema.Nullable = true
+			} else if schema.Type == genai.TypeUnspecified {
+				schema.Type = schemaType(name)
+			}
+		}
+	}
+	if schema.Type == genai.TypeUnspecified {
+		if _, ok := m["properties"]; ok {
+			schema.Type = genai.TypeObject
+		} else {
+			schema.Type = genai.TypeString
+		}
+	}
+
+	if desc, ok := m["description"].(string); ok {
+		schema.Description = desc
+	}
+	if format, ok := m["format"].(string); ok && (format == "int32" || format == "int64" || format == "float" || format == "double") {
+		schema.Format = format
+	}
+	if enum, ok := m["enum"].([]interface{}); ok && schema.Type == genai.TypeString {
+		for _, v := range enum {
+			schema.Enum = append(schema.Enum, fmt.Sprint(v))
+		}
+		schema.Format = "enum"
+	}
+	// Gemini rejects objects without properties, such as maps described only by
+	// additionalProperties, so they are left out and the model cannot set them
+	if items, ok := m["items"].(map[string]interface{}); ok {
+		if item := toSchema(items); !emptyObject(item) {
+			schema.Items = item
+		}
+	}
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		schema.Properties = make(map[string]*genai.Schema, len(props))
+		for name, prop := range props {
+			if p, ok := prop.(map[string]interface{}); ok {
+				if prop := toSchema(p); !emptyObject(prop) {
+					schema.Properties[name] = prop
+				}
+			}
+		}
+	}
+	if required, ok := m["required"].([]interface{}); ok {
+		for _, v := range required {
+			if name, ok := v.(string); ok && (schema.Properties == nil || schema.Properties[name] != nil) {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+	if schema.Type == genai.TypeArray && schema.Items == nil {
+		schema.Items = &genai.Schema{Type: genai.TypeString}
+	}
+	return schema
+}
+
+// emptyObject reports whether s is an object schema without properties
+func emptyObject(s *genai.Schema) bool {
+	return s != nil && s.Type == genai.TypeObject && len(s.Properties) == 0
+}
+
+// schemaType maps a JSON Schema type name to a Gemini type
+func schemaType(name string) genai.Type {
+	switch name {
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
+	case "object":
+		return genai.TypeObject
+	}
+	return genai.TypeUnspecified
+}
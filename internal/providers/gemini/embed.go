@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// DefaultEmbeddingModel is used by Embed unless another model is configured
+const DefaultEmbeddingModel = "text-embedding-004"
+
+// maxEmbedBatch is the number of texts the API embeds per request
+const maxEmbedBatch = 100
+
+// SetEmbeddingModel selects the model used by Embed; empty keeps DefaultEmbeddingModel
+func (c *GeminiClient) SetEmbeddingModel(model string) {
+	c.embeddingModel = model
+}
+
+// EmbeddingModel returns the model used by Embed
+func (c *GeminiClient) EmbeddingModel() string {
+	if c.embeddingModel == "" {
+		return DefaultEmbeddingModel
+	}
+	return c.embeddingModel
+}
+
+// Embed computes embeddings of texts with Gemini
+func (c *GeminiClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	em := c.client.EmbeddingModel(c.EmbeddingModel())
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		batch := em.NewBatch()
+		for _, t := range texts[start:min(start+maxEmbedBatch, len(texts))] {
+			batch.AddContent(genai.Text(t))
+		}
+		resp, err := em.BatchEmbedContents(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, emb := range resp.Embeddings {
+			vectors = append(vectors, emb.Values)
+		}
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
@@ -0,0 +1,26 @@
+package providers
+
+import "context"
+
+// OutputLimits bound the reply to a request
+type OutputLimits struct {
+	// MaxTokens limits the length of the reply; zero uses the provider default
+	MaxTokens int
+	// Stop are sequences at which the model stops generating; they are not part of
+	// the reply
+	Stop []string
+}
+
+type outputLimitsKey struct{}
+
+// WithOutputLimits returns a context whose requests are bound by limits. The registry
+// attaches the limits configured for each model, so callers need not.
+func WithOutputLimits(ctx context.Context, limits OutputLimits) context.Context {
+	return context.WithValue(ctx, outputLimitsKey{}, limits)
+}
+
+// OutputLimitsFrom returns the limits attached to ctx; the zero value when none are
+func OutputLimitsFrom(ctx context.Context) OutputLimits {
+	limits, _ := ctx.Value(outputLimitsKey{}).(OutputLimits)
+	return limits
+}
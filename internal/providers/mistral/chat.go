@@ -0,0 +1,217 @@
+package mistral
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gage-technologies/mistral-go"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// chatMessage mirrors mistral.ChatMessage with the fields tool results need,
+// which the SDK's message type does not have
+type chatMessage struct {
+	Role       string             `json:"role"`
+	Content    string             `json:"content"`
+	ToolCalls  []mistral.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string             `json:"tool_call_id,omitempty"`
+	Name       string             `json:"name,omitempty"`
+}
+
+// chatRequest is the body of a chat completion request
+type chatRequest struct {
+	Model       string         `json:"model"`
+	Messages    []chatMessage  `json:"messages"`
+	Temperature float64        `json:"temperature"`
+	MaxTokens   int            `json:"max_tokens,omitempty"`
+	Stop        []string       `json:"stop,omitempty"`
+	Tools       []mistral.Tool `json:"tools,omitempty"`
+	ToolChoice  string         `json:"tool_choice,omitempty"`
+	Stream      bool           `json:"stream,omitempty"`
+	// ResponseFormat asks for a JSON object in reply
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat is the response_format of a chat completion request
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// newChatRequest returns a request for model with the output limits attached to ctx
+func newChatRequest(ctx context.Context, model string, messages []chatMessage, temperature float64) chatRequest {
+	limits := providers.OutputLimitsFrom(ctx)
+	return chatRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   limits.MaxTokens,
+		Stop:        limits.Stop,
+	}
+}
+
+// complete sends a chat completion request. The SDK's Chat cannot send tool results,
+// so non-streaming requests are made directly against the API.
+func (c *MistralClient) complete(ctx context.Context, req chatRequest) (*mistral.ChatCompletionResponse, error) {
+	resp, err := c.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, mistral.NewMistralConnectionError(err.Error())
+	}
+	var chatRes mistral.ChatCompletionResponse
+	if err := json.Unmarshal(data, &chatRes); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &chatRes, nil
+}
+
+// stream sends a streaming chat completion request and returns its chunks. The SDK's
+// ChatStream takes neither a context nor stop sequences, so streams are read directly
+// from the API too.
+func (c *MistralClient) stream(ctx context.Context, req chatRequest) (<-chan mistral.ChatCompletionStreamResponse, error) {
+	req.Stream = true
+	resp, err := c.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan mistral.ChatCompletionStreamResponse)
+	go func() {
+		defer close(chunks)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data = strings.TrimSpace(data); data == "[DONE]" {
+				return
+			}
+			var chunk mistral.ChatCompletionStreamResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				chunks <- mistral.ChatCompletionStreamResponse{Error: fmt.Errorf("error decoding stream response: %w", err)}
+				return
+			}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			chunks <- mistral.ChatCompletionStreamResponse{Error: fmt.Errorf("error reading stream response: %w", err)}
+		}
+	}()
+	return chunks, nil
+}
+
+// post sends req to the chat completions endpoint and returns the response of a
+// successful request
+func (c *MistralClient) post(ctx context.Context, req chatRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, mistral.NewMistralConnectionError(err.Error())
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, mistral.NewMistralAPIError(strings.TrimSpace(string(data)), resp.StatusCode, resp.Header)
+	}
+	return resp, nil
+}
+
+// toChatMessages converts messages to the Mistral format, replaying assistant tool calls
+// and sending tool results as role "tool" messages
+func (c *MistralClient) toChatMessages(messages []providers.ChatMessage) []chatMessage {
+	result := make([]chatMessage, 0, len(messages)+1)
+	hasSystemMessage := false
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleSystem:
+			hasSystemMessage = true
+			result = append(result, chatMessage{Role: mistral.RoleSystem, Content: msg.Content})
+		case providers.RoleUser:
+			result = append(result, chatMessage{Role: mistral.RoleUser, Content: msg.Content})
+		case providers.RoleAssistant:
+			if msg.Content == "" && len(msg.ToolCalls) == 0 {
+				continue
+			}
+			out := chatMessage{Role: mistral.RoleAssistant, Content: msg.Content}
+			for _, call := range msg.ToolCalls {
+				out.ToolCalls = append(out.ToolCalls, mistral.ToolCall{
+					Id:       call.ID,
+					Type:     mistral.ToolTypeFunction,
+					Function: mistral.FunctionCall{Name: call.Name, Arguments: string(call.Input)},
+				})
+			}
+			result = append(result, out)
+		case providers.RoleTool:
+			if msg.ToolCallID == "" {
+				// Results without a call to answer can only be passed along as text
+				result = append(result, chatMessage{Role: mistral.RoleUser, Content: msg.Content})
+				continue
+			}
+			result = append(result, chatMessage{
+				Role:       mistral.RoleTool,
+				Content:    msg.Content,
+				ToolCallID: msg.ToolCallID,
+				Name:       msg.ToolName,
+			})
+		}
+	}
+
+	// Add system message at the beginning if we have one from config and no system message in conversation
+	if c.systemPrompt != "" && !hasSystemMessage {
+		result = append([]chatMessage{{Role: mistral.RoleSystem, Content: c.systemPrompt}}, result...)
+	}
+	return result
+}
+
+// toMistralTools converts tool definitions to Mistral function tools
+func toMistralTools(tools []providers.Tool) []mistral.Tool {
+	result := make([]mistral.Tool, 0, len(tools))
+	for _, tool := range tools {
+		params := any(tool.InputSchema)
+		if tool.InputSchema == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		result = append(result, mistral.Tool{
+			Type: mistral.ToolTypeFunction,
+			Function: mistral.Function{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  params,
+			},
+		})
+	}
+	return result
+}
@@ -2,7 +2,9 @@ package mistral
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/gage-technologies/mistral-go"
@@ -11,7 +13,8 @@ import (
 
 // MistralClient implements the Provider interface for Mistral AI
 type MistralClient struct {
-	client       *mistral.MistralClient
+	httpClient   *http.Client
+	endpoint     string
 	apiKey       string
 	models       []string
 	temperature  float64
@@ -20,10 +23,9 @@ type MistralClient struct {
 
 // New creates a new Mistral provider
 func New(apiKey string, models []string, temperature float64, systemPrompt string) (*MistralClient, error) {
-	client := mistral.NewMistralClientDefault(apiKey)
-	
 	return &MistralClient{
-		client:       client,
+		httpClient:   &http.Client{Timeout: mistral.DefaultTimeout},
+		endpoint:     mistral.Endpoint,
 		apiKey:       apiKey,
 		models:       models,
 		temperature:  temperature,
@@ -55,56 +57,23 @@ func (c *MistralClient) Chat(ctx context.Context, messages []providers.ChatMessa
 		modelName = c.models[0]
 	}
 
-	// Convert messages to Mistral format
-	mistralMessages := make([]mistral.ChatMessage, 0)
-	
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleSystem,
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleUser {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleUser,
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleAssistant {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleAssistant,
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleTool {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleUser,
-				Content: msg.Content,
-			})
-		}
+	req := newChatRequest(ctx, modelName, c.toChatMessages(messages), c.temperature)
+	if len(tools) > 0 {
+		req.Tools = toMistralTools(tools)
+		req.ToolChoice = mistral.ToolChoiceAuto
 	}
-
-	// Add system message at the beginning if we have one from config and no system message in conversation
-	if c.systemPrompt != "" {
-		hasSystemMessage := false
-		for _, msg := range messages {
-			if msg.Role == providers.RoleSystem {
-				hasSystemMessage = true
-				break
-			}
-		}
-		if !hasSystemMessage {
-			systemMsg := mistral.ChatMessage{
-				Role:    mistral.RoleSystem,
-				Content: c.systemPrompt,
-			}
-			mistralMessages = append([]mistral.ChatMessage{systemMsg}, mistralMessages...)
-		}
+	if _, ok := providers.ResponseFormatFrom(ctx); ok {
+		req.ResponseFormat = &responseFormat{Type: "json_object"}
 	}
 
-	// Send request to Mistral using the API
-	chatRes, err := c.client.Chat(modelName, mistralMessages, nil)
+	chatRes, err := c.complete(ctx, req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create chat completion: %w", err)
 	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     chatRes.Usage.PromptTokens,
+		CompletionTokens: chatRes.Usage.CompletionTokens,
+	})
 
 	// Convert response to our format
 	resultMessages := make([]providers.ChatMessage, 0)
@@ -118,8 +87,17 @@ func (c *MistralClient) Chat(ctx context.Context, messages []providers.ChatMessa
 			})
 		}
 
-		// Handle tool calls (if supported by this version of the SDK)
-		// Note: Tool calling might not be available in all versions
+		for _, call := range choice.Message.ToolCalls {
+			input := json.RawMessage(call.Function.Arguments)
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			toolUses = append(toolUses, providers.ToolUse{
+				ID:    call.Id,
+				Name:  call.Function.Name,
+				Input: input,
+			})
+		}
 	}
 
 	return resultMessages, toolUses, nil
@@ -127,44 +105,10 @@ func (c *MistralClient) Chat(ctx context.Context, messages []providers.ChatMessa
 
 // StreamChat sends a message to Mistral and returns a streaming response
 func (c *MistralClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
-	// Convert messages to Mistral format
-	mistralMessages := make([]mistral.ChatMessage, 0)
-	
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleSystem,
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleUser {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleUser,
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleAssistant {
-			mistralMessages = append(mistralMessages, mistral.ChatMessage{
-				Role:    mistral.RoleAssistant,
-				Content: msg.Content,
-			})
-		}
-	}
-	
-	// Add system message at the beginning if we have one from config and no system message in conversation
-	if c.systemPrompt != "" {
-		hasSystemMessage := false
-		for _, msg := range messages {
-			if msg.Role == providers.RoleSystem {
-				hasSystemMessage = true
-				break
-			}
-		}
-		if !hasSystemMessage {
-			systemMsg := mistral.ChatMessage{
-				Role:    mistral.RoleSystem,
-				Content: c.systemPrompt,
-			}
-			mistralMessages = append([]mistral.ChatMessage{systemMsg}, mistralMessages...)
-		}
+	// Stream directly so that the temperature and output limits apply
+	chatResChan, err := c.stream(ctx, newChatRequest(ctx, model, c.toChatMessages(messages), temperature))
+	if err != nil {
+		return nil, err
 	}
 
 	// Create streaming channel
@@ -173,18 +117,20 @@ func (c *MistralClient) StreamChat(ctx context.Context, model string, messages [
 	go func() {
 		defer close(responseChan)
 
-		// Use the ChatStream method
-		chatResChan, err := c.client.ChatStream(model, mistralMessages, nil)
-		if err != nil {
-			responseChan <- fmt.Sprintf("Error: %v", err)
-			return
-		}
+		var usage providers.Usage
+		defer func() { providers.RecordUsage(ctx, usage) }()
 
 		for chatResChunk := range chatResChan {
 			if chatResChunk.Error != nil {
-				responseChan <- fmt.Sprintf("Error: %v", chatResChunk.Error)
+				providers.StreamFailed(ctx, responseChan, chatResChunk.Error)
 				break
 			}
+			if chatResChunk.Usage.PromptTokens > 0 {
+				usage = providers.Usage{
+					PromptTokens:     chatResChunk.Usage.PromptTokens,
+					CompletionTokens: chatResChunk.Usage.CompletionTokens,
+				}
+			}
 			
 			for _, choice := range chatResChunk.Choices {
 				if choice.Delta.Content != "" {
@@ -197,19 +143,29 @@ func (c *MistralClient) StreamChat(ctx context.Context, model string, messages [
 	return responseChan, nil
 }
 
-// SendToolResult sends a tool result back to Mistral and returns its response
+// SendToolResult sends tool results back to Mistral and returns its response.
+// messages must end with the assistant message that requested the tools.
 func (c *MistralClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Name
+		}
+	}
+
 	// Add tool results to messages
 	augmented := make([]providers.ChatMessage, len(messages))
 	copy(augmented, messages)
 
 	for _, result := range toolResults {
 		augmented = append(augmented, providers.ChatMessage{
-			Role:    providers.RoleTool,
-			Content: result.Content,
+			Role:       providers.RoleTool,
+			Content:    result.Content,
+			ToolCallID: result.ID,
+			ToolName:   names[result.ID],
 		})
 	}
 
 	// Continue the conversation with all tools available
 	return c.Chat(ctx, augmented, nil)
-}
\ No newline at end of file
+}
@@ -0,0 +1,228 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultBaseURL is the OpenAI-compatible endpoint exposed by a local Ollama server.
+const DefaultBaseURL = "http://localhost:11434/v1"
+
+// OllamaClient implements the Provider interface for locally hosted models served by Ollama
+type OllamaClient struct {
+	client       *openai.Client
+	baseURL      string
+	models       []string
+	temperature  float64
+	systemPrompt string
+	// embeddingModel is the model used by Embed; empty uses DefaultEmbeddingModel
+	embeddingModel string
+}
+
+// New creates a new Ollama provider. An empty baseURL falls back to DefaultBaseURL.
+func New(baseURL string, models []string, temperature float64, systemPrompt string) (*OllamaClient, error) {
+	baseURL = normalizeBaseURL(baseURL)
+
+	// Ollama ignores the API key, but the OpenAI client requires a non-empty one
+	config := openai.DefaultConfig("ollama")
+	config.BaseURL = baseURL
+	client := openai.NewClientWithConfig(config)
+
+	return &OllamaClient{
+		client:       client,
+		baseURL:      baseURL,
+		models:       models,
+		temperature:  temperature,
+		systemPrompt: systemPrompt,
+	}, nil
+}
+
+// normalizeBaseURL makes sure the base URL points at Ollama's /v1 compatibility layer
+func normalizeBaseURL(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return baseURL
+}
+
+// Name returns the name of the provider
+func (c *OllamaClient) Name() string {
+	return "ollama"
+}
+
+// convertMessages converts provider messages to the OpenAI-compatible format used by Ollama
+func (c *OllamaClient) convertMessages(messages []providers.ChatMessage, includeTools bool) []openai.ChatCompletionMessage {
+	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+
+	systemPrompt := c.systemPrompt
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleSystem:
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+		case providers.RoleUser:
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "user", Content: msg.Content})
+		case providers.RoleAssistant:
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "assistant", Content: msg.Content})
+		case providers.RoleTool:
+			if includeTools {
+				openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{Role: "user", Content: msg.Content})
+			}
+		}
+	}
+
+	if systemPrompt != "" {
+		systemMsg := openai.ChatCompletionMessage{Role: "system", Content: systemPrompt}
+		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
+	}
+	return openaiMessages
+}
+
+// Chat sends a message to the Ollama server and returns its response
+func (c *OllamaClient) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	if len(c.models) == 0 {
+		return nil, nil, fmt.Errorf("ollama client has no model configured")
+	}
+
+	// Convert tools to OpenAI format
+	var openaiTools []openai.Tool
+	if len(tools) > 0 {
+		openaiTools = make([]openai.Tool, len(tools))
+		for i, tool := range tools {
+			openaiTools[i] = openai.Tool{
+				Type: "function",
+				Function: &openai.FunctionDefinition{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			}
+		}
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:       c.models[0],
+		Messages:    c.convertMessages(messages, true),
+		Tools:       openaiTools,
+		Temperature: float32(c.temperature),
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
+
+	resp, err := c.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create chat completion (is Ollama running at %s?): %w", c.baseURL, err)
+	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
+
+	// Convert response to our format
+	resultMessages := make([]providers.ChatMessage, 0)
+	var toolUses []providers.ToolUse
+
+	for _, choice := range resp.Choices {
+		if choice.Message.Content != "" {
+			resultMessages = append(resultMessages, providers.ChatMessage{
+				Role:    providers.RoleAssistant,
+				Content: choice.Message.Content,
+			})
+		}
+
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function.Name == "" {
+				continue
+			}
+			toolUses = append(toolUses, providers.ToolUse{
+				ID:    toolCall.ID,
+				Name:  toolCall.Function.Name,
+				Input: json.RawMessage(toolCall.Function.Arguments),
+			})
+		}
+	}
+
+	return resultMessages, toolUses, nil
+}
+
+// StreamChat sends a message to the Ollama server and returns a streaming response
+func (c *OllamaClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	req := openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    c.convertMessages(messages, false),
+		Temperature: float32(temperature),
+		Stream:      true,
+		// Ask for a final usage chunk so token counts can be tracked
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
+
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion stream (is Ollama running at %s?): %w", c.baseURL, err)
+	}
+
+	responseChan := make(chan string, 100)
+
+	go func() {
+		defer close(responseChan)
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return
+				}
+				providers.StreamFailed(ctx, responseChan, err)
+				return
+			}
+
+			if response.Usage != nil {
+				providers.RecordUsage(ctx, providers.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				})
+			}
+
+			if len(response.Choices) > 0 {
+				if delta := response.Choices[0].Delta; delta.Content != "" {
+					responseChan <- delta.Content
+				}
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// SendToolResult sends a tool result back to the Ollama server and returns its response
+func (c *OllamaClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	// Append each tool result as a ChatMessage with RoleTool so Chat() can convert.
+	augmented := make([]providers.ChatMessage, len(messages))
+	copy(augmented, messages)
+
+	for _, res := range toolResults {
+		augmented = append(augmented, providers.ChatMessage{
+			Role:    providers.RoleTool,
+			Content: res.Content,
+		})
+	}
+
+	// Continue conversation without re-sending tool definitions (nil tools).
+	return c.Chat(ctx, augmented, nil)
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -36,7 +37,7 @@ func init() {
 func debugLog(format string, args ...interface{}) {
 	if openaiDebug && debugFile != nil {
 		timestamp := time.Now().Format("2006/01/02 15:04:05")
-		fmt.Fprintf(debugFile, "%s [OpenAI] "+format+"\n", append([]interface{}{timestamp}, args...)...)
+		fmt.Fprintf(debugFile, "%s [OpenAI] %s\n", timestamp, redact.String(fmt.Sprintf(format, args...)))
 		debugFile.Sync() // Flush immediately so we can tail -f
 	}
 }
@@ -48,6 +49,8 @@ type OpenAIClient struct {
 	models       []string
 	temperature  float64
 	systemPrompt string
+	// embeddingModel is the model used by Embed; empty uses DefaultEmbeddingModel
+	embeddingModel string
 }
 
 // New creates a new OpenAI provider
@@ -85,44 +88,7 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []providers.ChatMessag
 		return nil, nil, fmt.Errorf("openai client has no model configured")
 	}
 	
-	// Convert messages to OpenAI format
-	openaiMessages := make([]openai.ChatCompletionMessage, 0)
-	
-	// Add system prompt if configured
-	systemPrompt := c.systemPrompt
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
-			continue
-		} else if msg.Role == providers.RoleUser {
-			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-				Role:    "user",
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleAssistant {
-			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-				Role:    "assistant",
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleTool {
-			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-				Role:    "user",
-				Content: msg.Content,
-			})
-		}
-	}
-	
-	// Add system message at the beginning if we have one
-	if systemPrompt != "" {
-		systemMsg := openai.ChatCompletionMessage{
-			Role:    "system",
-			Content: systemPrompt,
-		}
-		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
-	}
+	openaiMessages := c.toOpenAIMessages(messages)
 
 	// Convert tools to OpenAI format
 	var openaiTools []openai.Tool
@@ -149,6 +115,25 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []providers.ChatMessag
 		Messages: openaiMessages,
 		Tools:    openaiTools,
 	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.Stop = limits.Stop
+	// o-series models take max_completion_tokens instead of max_tokens, and reason
+	// with the effort asked for
+	if isOSeriesModel(model) {
+		req.MaxCompletionTokens = limits.MaxTokens
+		req.ReasoningEffort = providers.ThinkingFrom(ctx).Effort
+	} else {
+		req.MaxTokens = limits.MaxTokens
+	}
+	if format, ok := providers.ResponseFormatFrom(ctx); ok {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   format.Name,
+				Schema: format.Schema,
+			},
+		}
+	}
 
 	// Only set temperature for non-o* models (o1, o3 series have fixed parameters)
 	if !isOSeriesModel(model) {
@@ -156,11 +141,15 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []providers.ChatMessag
 	}
 
 	// Send request to OpenAI
-	resp, err := c.client.CreateChatCompletion(ctx, req)
+	resp, err := c.complete(ctx, req)
 	if err != nil {
 		debugLog("Chat error: %v", err)
 		return nil, nil, fmt.Errorf("failed to create chat completion: %w", err)
 	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
 
 	// Convert response to our format
 	resultMessages := make([]providers.ChatMessage, 0)
@@ -191,48 +180,93 @@ func (c *OpenAIClient) Chat(ctx context.Context, messages []providers.ChatMessag
 	return resultMessages, toolUses, nil
 }
 
-// StreamChat sends a message to OpenAI and returns a streaming response
-func (c *OpenAIClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
-	debugLog("StreamChat: model=%s, temperature=%f, total_messages=%d", model, temperature, len(messages))
-	
-	// Convert messages to OpenAI format
-	openaiMessages := make([]openai.ChatCompletionMessage, 0)
-	systemPrompt := c.systemPrompt
-	
-	for _, msg := range messages {
-		if msg.Role == providers.RoleSystem {
-			// Use system message from conversation if provided, otherwise use config
-			if msg.Content != "" {
-				systemPrompt = msg.Content
-			}
+// complete sends req and returns the response. With a text recorder attached to ctx
+// the request is streamed, its text recorded as it arrives and the response assembled
+// from the chunks.
+func (c *OpenAIClient) complete(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	if providers.TextRecorderFrom(ctx) == nil {
+		return c.client.CreateChatCompletion(ctx, req)
+	}
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	defer stream.Close()
+
+	var resp openai.ChatCompletionResponse
+	var content strings.Builder
+	var calls []openai.ToolCall
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return openai.ChatCompletionResponse{}, err
+		}
+		if chunk.Usage != nil {
+			resp.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
 			continue
-		} else if msg.Role == providers.RoleUser {
-			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-				Role:    "user",
-				Content: msg.Content,
-			})
-		} else if msg.Role == providers.RoleAssistant {
-			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
-				Role:    "assistant",
-				Content: msg.Content,
-			})
 		}
-	}
-	
-	// Add system message at the beginning if we have one
-	if systemPrompt != "" {
-		systemMsg := openai.ChatCompletionMessage{
-			Role:    "system",
-			Content: systemPrompt,
+		delta := chunk.Choices[0].Delta
+		content.WriteString(delta.Content)
+		providers.RecordText(ctx, delta.Content)
+		// A tool call arrives in pieces: the first has its ID and name, the others
+		// continue its arguments
+		for _, call := range delta.ToolCalls {
+			i := len(calls)
+			if call.Index != nil {
+				i = *call.Index
+			} else if call.ID == "" && i > 0 {
+				i--
+			}
+			for len(calls) <= i {
+				calls = append(calls, openai.ToolCall{Type: openai.ToolTypeFunction})
+			}
+			if call.ID != "" {
+				calls[i].ID = call.ID
+			}
+			calls[i].Function.Name += call.Function.Name
+			calls[i].Function.Arguments += call.Function.Arguments
 		}
-		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
 	}
+	resp.Choices = []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{
+			Role:      openai.ChatMessageRoleAssistant,
+			Content:   content.String(),
+			ToolCalls: calls,
+		},
+	}}
+	return resp, nil
+}
+
+// StreamChat sends a message to OpenAI and returns a streaming response
+func (c *OpenAIClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	debugLog("StreamChat: model=%s, temperature=%f, total_messages=%d", model, temperature, len(messages))
+	
+	openaiMessages := c.toOpenAIMessages(messages)
 
 	// Create streaming chat completion request
 	req := openai.ChatCompletionRequest{
 		Model:    model,
 		Messages: openaiMessages,
 		Stream:   true,
+		// Ask for a final usage chunk so token counts can be tracked
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.Stop = limits.Stop
+	// o-series models take max_completion_tokens instead of max_tokens, and reason
+	// with the effort asked for
+	if isOSeriesModel(model) {
+		req.MaxCompletionTokens = limits.MaxTokens
+		req.ReasoningEffort = providers.ThinkingFrom(ctx).Effort
+	} else {
+		req.MaxTokens = limits.MaxTokens
 	}
 
 	// Only set temperature for non-o* models (o1, o3 series have fixed parameters)
@@ -262,10 +296,17 @@ func (c *OpenAIClient) StreamChat(ctx context.Context, model string, messages []
 					return
 				}
 				debugLog("StreamChat: stream error: %v", err)
-				responseChan <- fmt.Sprintf("Error: %v", err)
+				providers.StreamFailed(ctx, responseChan, err)
 				return
 			}
 
+			if response.Usage != nil {
+				providers.RecordUsage(ctx, providers.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				})
+			}
+
 			if len(response.Choices) > 0 {
 				delta := response.Choices[0].Delta
 				if delta.Content != "" {
@@ -279,16 +320,25 @@ func (c *OpenAIClient) StreamChat(ctx context.Context, model string, messages []
 	return responseChan, nil
 }
 
-// SendToolResult sends a tool result back to OpenAI and returns its response
+// SendToolResult sends tool results back to OpenAI and returns its response.
+// messages must end with the assistant message that requested the tools.
 func (c *OpenAIClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
-	// Append each tool result as a ChatMessage with RoleTool so Chat() can convert.
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Name
+		}
+	}
+
 	augmented := make([]providers.ChatMessage, len(messages))
 	copy(augmented, messages)
-
 	for _, res := range toolResults {
 		augmented = append(augmented, providers.ChatMessage{
-			Role:    providers.RoleTool,
-			Content: res.Content,
+			Role:       providers.RoleTool,
+			Content:    res.Content,
+			ToolCallID: res.ID,
+			ToolName:   names[res.ID],
+			IsError:    res.IsError,
 		})
 	}
 
@@ -296,6 +346,76 @@ func (c *OpenAIClient) SendToolResult(ctx context.Context, messages []providers.
 	return c.Chat(ctx, augmented, nil)
 }
 
+// toOpenAIMessages converts messages to the OpenAI format, replaying assistant tool
+// calls and sending tool results as role "tool" messages answering them by ID
+func (c *OpenAIClient) toOpenAIMessages(messages []providers.ChatMessage) []openai.ChatCompletionMessage {
+	openaiMessages := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+	// called holds the IDs of the tool calls replayed so far
+	called := map[string]bool{}
+
+	systemPrompt := c.systemPrompt
+	for _, msg := range messages {
+		switch msg.Role {
+		case providers.RoleSystem:
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+		case providers.RoleUser:
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: msg.Content,
+			})
+		case providers.RoleAssistant:
+			if msg.Content == "" && len(msg.ToolCalls) == 0 {
+				continue
+			}
+			assistantMsg := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: msg.Content,
+			}
+			for _, call := range msg.ToolCalls {
+				args := string(call.Input)
+				if args == "" {
+					args = "{}"
+				}
+				assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, openai.ToolCall{
+					ID:       call.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: call.Name, Arguments: args},
+				})
+				called[call.ID] = true
+			}
+			openaiMessages = append(openaiMessages, assistantMsg)
+		case providers.RoleTool:
+			if !called[msg.ToolCallID] {
+				// Results without a call to answer can only be passed along as text
+				openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleUser,
+					Content: msg.Content,
+				})
+				continue
+			}
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    msg.Content,
+				Name:       msg.ToolName,
+				ToolCallID: msg.ToolCallID,
+			})
+		}
+	}
+
+	// Add system message at the beginning if we have one
+	if systemPrompt != "" {
+		systemMsg := openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: systemPrompt,
+		}
+		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
+	}
+	return openaiMessages
+}
+
 // isOSeriesModel checks if the model is from the o-series (o1, o3) which have fixed parameters
 func isOSeriesModel(model string) bool {
 	model = strings.ToLower(model)
@@ -0,0 +1,48 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultEmbeddingModel is used by Embed unless another model is configured
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// SetEmbeddingModel selects the model used by Embed; empty keeps DefaultEmbeddingModel
+func (c *OpenAIClient) SetEmbeddingModel(model string) {
+	c.embeddingModel = model
+}
+
+// EmbeddingModel returns the model used by Embed
+func (c *OpenAIClient) EmbeddingModel() string {
+	if c.embeddingModel == "" {
+		return DefaultEmbeddingModel
+	}
+	return c.embeddingModel
+}
+
+// Embed computes embeddings of texts with OpenAI
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := c.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(c.EmbeddingModel()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	providers.RecordUsage(ctx, providers.Usage{PromptTokens: resp.Usage.PromptTokens})
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
@@ -0,0 +1,61 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Default speech model and voice of Synthesize, and model of Transcribe
+const (
+	DefaultSpeechModel        = "gpt-4o-mini-tts"
+	DefaultSpeechVoice        = "alloy"
+	DefaultTranscriptionModel = openai.Whisper1
+)
+
+// Synthesize speaks text with OpenAI's text-to-speech and returns WAV audio
+func (c *OpenAIClient) Synthesize(ctx context.Context, req providers.SpeechRequest) ([]byte, error) {
+	model, voice := req.Model, req.Voice
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	if voice == "" {
+		voice = DefaultSpeechVoice
+	}
+	resp, err := c.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          openai.SpeechModel(model),
+		Input:          req.Text,
+		Voice:          openai.SpeechVoice(voice),
+		ResponseFormat: openai.SpeechResponseFormatWav,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	return io.ReadAll(resp)
+}
+
+// Transcribe returns the text of a WAV recording with OpenAI's speech recognition
+// (Whisper)
+func (c *OpenAIClient) Transcribe(ctx context.Context, req providers.TranscriptionRequest) (string, error) {
+	model := req.Model
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+	resp, err := c.client.CreateTranscription(ctx, openai.AudioRequest{
+		Model:    model,
+		FilePath: "speech.wav", // names the format of Reader
+		Reader:   bytes.NewReader(req.Audio),
+		Language: req.Language,
+		Prompt:   req.Prompt,
+		Format:   openai.AudioResponseFormatJSON,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Text), nil
+}
@@ -0,0 +1,136 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// cacheTTL is how long a fetched catalog is used before it is fetched again
+const cacheTTL = 24 * time.Hour
+
+// Model describes a model in the OpenRouter catalog
+type Model struct {
+	ID            string
+	Name          string
+	ContextLength int
+	// PromptPrice and CompletionPrice are in USD per million tokens
+	PromptPrice     float64
+	CompletionPrice float64
+	// Priced is false when OpenRouter does not publish a fixed price (e.g. routers)
+	Priced bool
+}
+
+// catalogResponse is the body returned by GET /models
+type catalogResponse struct {
+	Data []struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		ContextLength int    `json:"context_length"`
+		Pricing       struct {
+			// Prices are decimal strings in USD per token
+			Prompt     string `json:"prompt"`
+			Completion string `json:"completion"`
+		} `json:"pricing"`
+	} `json:"data"`
+}
+
+// ListModels fetches the models available through OpenRouter, sorted by ID.
+// An empty baseURL uses DefaultBaseURL.
+func ListModels(ctx context.Context, apiKey, baseURL string) ([]Model, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resolveBaseURL(baseURL)+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch model catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to fetch model catalog: status %d: %s", resp.StatusCode, body)
+	}
+
+	var catalog catalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return nil, fmt.Errorf("failed to decode model catalog: %w", err)
+	}
+
+	models := make([]Model, 0, len(catalog.Data))
+	for _, m := range catalog.Data {
+		if m.ID == "" {
+			continue
+		}
+		model := Model{ID: m.ID, Name: m.Name, ContextLength: m.ContextLength}
+		prompt, errPrompt := strconv.ParseFloat(m.Pricing.Prompt, 64)
+		completion, errCompletion := strconv.ParseFloat(m.Pricing.Completion, 64)
+		// Routers such as openrouter/auto report -1 because the price depends on the model picked
+		if errPrompt == nil && errCompletion == nil && prompt >= 0 && completion >= 0 {
+			model.PromptPrice = prompt * 1_000_000
+			model.CompletionPrice = completion * 1_000_000
+			model.Priced = true
+		}
+		models = append(models, model)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// catalogCache is the last catalog fetched, kept in ~/.magikarp/cache
+type catalogCache struct {
+	BaseURL string    `json:"base_url"`
+	Fetched time.Time `json:"fetched"`
+	Models  []Model   `json:"models"`
+}
+
+// CachedModels returns the catalog fetched within the last day, or fetches it with
+// ListModels and caches it. A stale catalog is returned when fetching fails, so that
+// startup does not depend on OpenRouter being reachable.
+func CachedModels(ctx context.Context, apiKey, baseURL string) ([]Model, error) {
+	path := cachePath()
+	var cached catalogCache
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &cached) == nil && cached.BaseURL == resolveBaseURL(baseURL) {
+		if time.Since(cached.Fetched) < cacheTTL && len(cached.Models) > 0 {
+			return cached.Models, nil
+		}
+	} else {
+		cached = catalogCache{}
+	}
+
+	models, err := ListModels(ctx, apiKey, baseURL)
+	if err != nil {
+		if len(cached.Models) > 0 {
+			return cached.Models, nil
+		}
+		return nil, err
+	}
+	if path != "" {
+		data, _ := json.Marshal(catalogCache{BaseURL: resolveBaseURL(baseURL), Fetched: time.Now(), Models: models})
+		if os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+			_ = os.WriteFile(path, data, 0o644)
+		}
+	}
+	return models, nil
+}
+
+// cachePath returns the location of the cached catalog, or "" without a home directory
+func cachePath() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".magikarp", "cache", "openrouter-models.json")
+}
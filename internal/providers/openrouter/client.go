@@ -0,0 +1,314 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultBaseURL is OpenRouter's OpenAI-compatible API endpoint
+const DefaultBaseURL = "https://openrouter.ai/api/v1"
+
+// OpenRouterClient implements the Provider interface for OpenRouter using its OpenAI-compatible API
+type OpenRouterClient struct {
+	client       *openai.Client
+	apiKey       string
+	models       []string
+	temperature  float64
+	systemPrompt string
+}
+
+// New creates a new OpenRouter provider. An empty baseURL uses DefaultBaseURL.
+func New(apiKey, baseURL string, models []string, temperature float64, systemPrompt string) (*OpenRouterClient, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key not set (OPENROUTER_API_KEY environment variable)")
+	}
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = resolveBaseURL(baseURL)
+	// OpenRouter uses these headers to attribute requests to the app
+	config.HTTPClient = &http.Client{Transport: &attributionTransport{base: http.DefaultTransport}}
+	client := openai.NewClientWithConfig(config)
+
+	return &OpenRouterClient{
+		client:       client,
+		apiKey:       apiKey,
+		models:       models,
+		temperature:  temperature,
+		systemPrompt: systemPrompt,
+	}, nil
+}
+
+// resolveBaseURL returns baseURL without a trailing slash, or DefaultBaseURL when empty
+func resolveBaseURL(baseURL string) string {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	return baseURL
+}
+
+// attributionTransport adds OpenRouter's app attribution headers to each request
+type attributionTransport struct {
+	base http.RoundTripper
+}
+
+func (t *attributionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("HTTP-Referer", "https://github.com/pprunty/magikarp")
+	req.Header.Set("X-Title", "Magikarp")
+	return t.base.RoundTrip(req)
+}
+
+// Name returns the name of the provider
+func (c *OpenRouterClient) Name() string {
+	return "openrouter"
+}
+
+// Chat sends a message to OpenRouter and returns its response
+func (c *OpenRouterClient) Chat(ctx context.Context, messages []providers.ChatMessage, tools []providers.Tool) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	if len(c.models) == 0 {
+		return nil, nil, fmt.Errorf("openrouter client has no model configured")
+	}
+
+	// Convert messages to OpenAI format (since we're using OpenAI-compatible API)
+	openaiMessages := make([]openai.ChatCompletionMessage, 0)
+
+	// Add system prompt if configured
+	systemPrompt := c.systemPrompt
+	for _, msg := range messages {
+		if msg.Role == providers.RoleSystem {
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+			continue
+		} else if msg.Role == providers.RoleUser {
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:    "user",
+				Content: msg.Content,
+			})
+		} else if msg.Role == providers.RoleAssistant {
+			if msg.Content == "" && len(msg.ToolCalls) == 0 {
+				continue
+			}
+			assistantMsg := openai.ChatCompletionMessage{
+				Role:    "assistant",
+				Content: msg.Content,
+			}
+			for _, call := range msg.ToolCalls {
+				assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, openai.ToolCall{
+					ID:       call.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: call.Name, Arguments: string(call.Input)},
+				})
+			}
+			openaiMessages = append(openaiMessages, assistantMsg)
+		} else if msg.Role == providers.RoleTool {
+			if msg.ToolCallID == "" {
+				// Results without a call to answer can only be passed along as text
+				openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+					Role:    "user",
+					Content: msg.Content,
+				})
+				continue
+			}
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:       "tool",
+				Content:    msg.Content,
+				ToolCallID: msg.ToolCallID,
+			})
+		}
+	}
+
+	// Add system message at the beginning if we have one
+	if systemPrompt != "" {
+		systemMsg := openai.ChatCompletionMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		}
+		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
+	}
+
+	// Convert tools to OpenAI format
+	var openaiTools []openai.Tool
+	if len(tools) > 0 {
+		openaiTools = make([]openai.Tool, len(tools))
+		for i, tool := range tools {
+			openaiTools[i] = openai.Tool{
+				Type: "function",
+				Function: &openai.FunctionDefinition{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
+				},
+			}
+		}
+	}
+
+	// Use first available model
+	model := c.models[0]
+
+	// Create chat completion request
+	req := openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    openaiMessages,
+		Tools:       openaiTools,
+		Temperature: float32(c.temperature),
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
+
+	// Send request to OpenRouter via OpenAI-compatible API
+	resp, err := c.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	providers.RecordUsage(ctx, providers.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	})
+
+	// Convert response to our format
+	resultMessages := make([]providers.ChatMessage, 0)
+	var toolUses []providers.ToolUse
+
+	for _, choice := range resp.Choices {
+		if choice.Message.Content != "" {
+			resultMessages = append(resultMessages, providers.ChatMessage{
+				Role:    choice.Message.Role,
+				Content: choice.Message.Content,
+			})
+		}
+
+		// Handle tool calls
+		for _, toolCall := range choice.Message.ToolCalls {
+			if toolCall.Function.Name == "" {
+				continue
+			}
+
+			input := json.RawMessage(toolCall.Function.Arguments)
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			toolUses = append(toolUses, providers.ToolUse{
+				ID:    toolCall.ID,
+				Name:  toolCall.Function.Name,
+				Input: input,
+			})
+		}
+	}
+
+	return resultMessages, toolUses, nil
+}
+
+// StreamChat sends a message to OpenRouter and returns a streaming response
+func (c *OpenRouterClient) StreamChat(ctx context.Context, model string, messages []providers.ChatMessage, temperature float64) (<-chan string, error) {
+	// Convert messages to OpenAI format
+	openaiMessages := make([]openai.ChatCompletionMessage, 0)
+	systemPrompt := c.systemPrompt
+
+	for _, msg := range messages {
+		if msg.Role == providers.RoleSystem {
+			// Use system message from conversation if provided, otherwise use config
+			if msg.Content != "" {
+				systemPrompt = msg.Content
+			}
+			continue
+		} else if msg.Role == providers.RoleUser {
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:    "user",
+				Content: msg.Content,
+			})
+		} else if msg.Role == providers.RoleAssistant {
+			openaiMessages = append(openaiMessages, openai.ChatCompletionMessage{
+				Role:    "assistant",
+				Content: msg.Content,
+			})
+		}
+	}
+
+	// Add system message at the beginning if we have one
+	if systemPrompt != "" {
+		systemMsg := openai.ChatCompletionMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		}
+		openaiMessages = append([]openai.ChatCompletionMessage{systemMsg}, openaiMessages...)
+	}
+
+	// Create streaming chat completion request
+	req := openai.ChatCompletionRequest{
+		Model:       model,
+		Messages:    openaiMessages,
+		Temperature: float32(temperature),
+		Stream:      true,
+		// Ask for a final usage chunk so token counts can be tracked
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	limits := providers.OutputLimitsFrom(ctx)
+	req.MaxTokens, req.Stop = limits.MaxTokens, limits.Stop
+
+	// Create stream
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion stream: %w", err)
+	}
+
+	// Create channel for streaming response
+	responseChan := make(chan string, 100)
+
+	go func() {
+		defer close(responseChan)
+		defer stream.Close()
+
+		for {
+			response, err := stream.Recv()
+			if err != nil {
+				if err.Error() == "EOF" {
+					return
+				}
+				providers.StreamFailed(ctx, responseChan, err)
+				return
+			}
+
+			if response.Usage != nil {
+				providers.RecordUsage(ctx, providers.Usage{
+					PromptTokens:     response.Usage.PromptTokens,
+					CompletionTokens: response.Usage.CompletionTokens,
+				})
+			}
+
+			if len(response.Choices) > 0 {
+				delta := response.Choices[0].Delta
+				if delta.Content != "" {
+					responseChan <- delta.Content
+				}
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// SendToolResult sends a tool result back to OpenRouter and returns its response
+func (c *OpenRouterClient) SendToolResult(ctx context.Context, messages []providers.ChatMessage, toolResults []providers.ToolResult) ([]providers.ChatMessage, []providers.ToolUse, error) {
+	// Append each tool result as a ChatMessage with RoleTool so Chat() can convert.
+	augmented := make([]providers.ChatMessage, len(messages))
+	copy(augmented, messages)
+
+	for _, res := range toolResults {
+		augmented = append(augmented, providers.ChatMessage{
+			Role:       providers.RoleTool,
+			Content:    res.Content,
+			ToolCallID: res.ID,
+		})
+	}
+
+	// Continue conversation without re-sending tool definitions (nil tools).
+	return c.Chat(ctx, augmented, nil)
+}
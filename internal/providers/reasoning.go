@@ -0,0 +1,88 @@
+package providers
+
+import "context"
+
+// ReasoningRecorder receives reasoning text from models that expose their thinking
+// separately from the answer. It is called with successive deltas when streaming.
+type ReasoningRecorder func(delta string)
+
+type reasoningRecorderKey struct{}
+
+// WithReasoningRecorder returns a context that forwards reasoning reported by
+// providers to record. Reasoning is never part of the returned messages, so it
+// is not sent back to the model with the conversation history.
+func WithReasoningRecorder(ctx context.Context, record ReasoningRecorder) context.Context {
+	return context.WithValue(ctx, reasoningRecorderKey{}, record)
+}
+
+// RecordReasoning reports reasoning to the recorder attached to ctx, if any.
+// Empty deltas are ignored so providers can call it unconditionally.
+func RecordReasoning(ctx context.Context, delta string) {
+	if delta == "" {
+		return
+	}
+	if record, ok := ctx.Value(reasoningRecorderKey{}).(ReasoningRecorder); ok && record != nil {
+		record(delta)
+	}
+}
+
+// Effort levels of extended thinking
+const (
+	EffortLow    = "low"
+	EffortMedium = "medium"
+	EffortHigh   = "high"
+)
+
+// Efforts lists the effort levels from least to most thorough
+var Efforts = []string{EffortLow, EffortMedium, EffortHigh}
+
+// MinThinkingBudget is the smallest thinking budget Anthropic accepts
+const MinThinkingBudget = 1024
+
+// thinkingBudgets are the Anthropic thinking budgets of the effort levels
+var thinkingBudgets = map[string]int{
+	EffortLow:    2048,
+	EffortMedium: 8192,
+	EffortHigh:   24576,
+}
+
+// Thinking asks a model to reason before it answers. Providers apply it as they
+// support it: Anthropic models think within a token budget, OpenAI reasoning models
+// take the effort, and thinking is reported with RecordReasoning.
+type Thinking struct {
+	// Effort is low, medium or high; empty disables thinking
+	Effort string
+	// BudgetTokens caps the tokens spent thinking where the provider takes a budget;
+	// zero derives it from Effort
+	BudgetTokens int
+}
+
+// Enabled reports whether t asks for thinking
+func (t Thinking) Enabled() bool {
+	return t.Effort != ""
+}
+
+// Budget returns the tokens the model may spend thinking
+func (t Thinking) Budget() int {
+	if t.BudgetTokens > 0 {
+		return max(t.BudgetTokens, MinThinkingBudget)
+	}
+	if budget, ok := thinkingBudgets[t.Effort]; ok {
+		return budget
+	}
+	return thinkingBudgets[EffortMedium]
+}
+
+type thinkingKey struct{}
+
+// WithThinking returns a context whose requests ask the model to think. Callers
+// check first that the model supports it (see orchestration.WithThinking).
+func WithThinking(ctx context.Context, thinking Thinking) context.Context {
+	return context.WithValue(ctx, thinkingKey{}, thinking)
+}
+
+// ThinkingFrom returns the thinking requested by ctx; disabled when none is
+func ThinkingFrom(ctx context.Context) Thinking {
+	thinking, _ := ctx.Value(thinkingKey{}).(Thinking)
+	return thinking
+}
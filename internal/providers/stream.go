@@ -0,0 +1,54 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// TextRecorder receives the text of a reply to Chat as it is generated. Providers
+// that can stream requests with tools do so when one is attached; the returned
+// messages still hold the whole text.
+type TextRecorder func(delta string)
+
+type textRecorderKey struct{}
+
+// WithTextRecorder returns a context whose Chat requests stream their text to record
+func WithTextRecorder(ctx context.Context, record TextRecorder) context.Context {
+	return context.WithValue(ctx, textRecorderKey{}, record)
+}
+
+// TextRecorderFrom returns the text recorder attached to ctx, or nil
+func TextRecorderFrom(ctx context.Context) TextRecorder {
+	record, _ := ctx.Value(textRecorderKey{}).(TextRecorder)
+	return record
+}
+
+// RecordText reports a delta of the reply text to the recorder attached to ctx, if any.
+// Empty deltas are ignored so providers can call it unconditionally.
+func RecordText(ctx context.Context, delta string) {
+	if record := TextRecorderFrom(ctx); record != nil && delta != "" {
+		record(delta)
+	}
+}
+
+// StreamErrorRecorder receives the error that ended a stream returned by StreamChat
+type StreamErrorRecorder func(err error)
+
+type streamErrorRecorderKey struct{}
+
+// WithStreamErrorRecorder returns a context whose streams report the error that ends
+// them to record, so that it can be shown as an error rather than as answer text
+func WithStreamErrorRecorder(ctx context.Context, record StreamErrorRecorder) context.Context {
+	return context.WithValue(ctx, streamErrorRecorderKey{}, record)
+}
+
+// StreamFailed reports err, which ended the stream of chunks, to the recorder attached
+// to ctx. Without one it is sent as a last chunk of text, so callers that only read
+// the text still see it.
+func StreamFailed(ctx context.Context, chunks chan<- string, err error) {
+	if record, ok := ctx.Value(streamErrorRecorderKey{}).(StreamErrorRecorder); ok && record != nil {
+		record(err)
+		return
+	}
+	chunks <- fmt.Sprintf("Error: %v", err)
+}
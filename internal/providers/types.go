@@ -19,6 +19,24 @@ const (
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls holds the tools requested by an assistant message
+	ToolCalls []ToolUse `json:"tool_calls,omitempty"`
+	// ToolCallID and ToolName identify the call a RoleTool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	ToolName   string `json:"tool_name,omitempty"`
+	// IsError marks a RoleTool message reporting a failed call
+	IsError bool `json:"is_error,omitempty"`
+	// Thinking holds the signed thinking of an assistant message that called tools,
+	// which Anthropic needs replayed before the results. It is never kept in history.
+	Thinking []ThinkingBlock `json:"thinking,omitempty"`
+}
+
+// ThinkingBlock is a block of a model's thinking as the provider returned it
+type ThinkingBlock struct {
+	Text      string `json:"text,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	// Redacted holds the encrypted content of thinking the provider withheld
+	Redacted string `json:"redacted,omitempty"`
 }
 
 // Tool represents a tool that can be used by the LLM
@@ -70,10 +88,62 @@ type Provider interface {
 	// StreamChat sends a message to the LLM and returns a streaming response
 	StreamChat(ctx context.Context, model string, messages []ChatMessage, temperature float64) (<-chan string, error)
 
-	// SendToolResult sends a tool result back to the LLM and returns its response
+	// SendToolResult sends a tool result back to the LLM and returns its response.
+	// Tools are not offered again, so the response answers from the results.
 	SendToolResult(ctx context.Context, messages []ChatMessage, toolResults []ToolResult) ([]ChatMessage, []ToolUse, error)
 }
 
+// Embedder is implemented by providers that can compute text embeddings. Subsystems
+// get one from the registry with orchestration.EmbedderFor.
+type Embedder interface {
+	// EmbeddingModel names the model used by Embed; vectors of different models
+	// cannot be compared
+	EmbeddingModel() string
+	// Embed returns one embedding vector per text
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// TokenCounter is implemented by providers that count the tokens of a request exactly,
+// with a counting endpoint of their API. Subsystems get one from the registry with
+// orchestration.CounterFor.
+type TokenCounter interface {
+	// CountTokens returns the input tokens of a request with messages and tools
+	CountTokens(ctx context.Context, messages []ChatMessage, tools []Tool) (int, error)
+}
+
+// SpeechRequest asks a Synthesizer to speak text
+type SpeechRequest struct {
+	Text string
+	// Model and Voice select the speech model and voice; empty uses the provider default
+	Model string
+	Voice string
+}
+
+// Synthesizer is implemented by providers that can turn text into speech. Subsystems
+// get one from the registry with orchestration.SynthesizerFor.
+type Synthesizer interface {
+	// Synthesize returns the spoken text as WAV audio
+	Synthesize(ctx context.Context, req SpeechRequest) ([]byte, error)
+}
+
+// TranscriptionRequest asks a Transcriber for the text of recorded speech
+type TranscriptionRequest struct {
+	// Audio is a WAV recording
+	Audio []byte
+	// Model selects the speech recognition model; empty uses the provider default
+	Model string
+	// Language is an optional ISO-639-1 hint such as "en"
+	Language string
+	// Prompt optionally names words to expect, such as a wake word
+	Prompt string
+}
+
+// Transcriber is implemented by providers that can turn speech into text. Subsystems
+// get one from the registry with orchestration.TranscriberFor.
+type Transcriber interface {
+	Transcribe(ctx context.Context, req TranscriptionRequest) (string, error)
+}
+
 // Legacy Message type for backward compatibility - will be removed
 type Message = ChatMessage
 
@@ -168,6 +238,9 @@ func (a *ChatAgent) Run(ctx context.Context) error {
 			continue
 		}
 
+		// Record the calls so providers with native function calling can replay them
+		a.conversation = append(a.conversation, ChatMessage{Role: RoleAssistant, ToolCalls: toolCalls})
+
 		// Process tool calls and execute them
 		var toolResults []ChatMessage
 		for _, call := range toolCalls {
@@ -185,8 +258,11 @@ func (a *ChatAgent) Run(ctx context.Context) error {
 
 			// Create a tool result message
 			toolResults = append(toolResults, ChatMessage{
-				Role:    RoleTool,
-				Content: result.Content,
+				Role:       RoleTool,
+				Content:    result.Content,
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+				IsError:    result.IsError,
 			})
 		}
 
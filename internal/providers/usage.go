@@ -0,0 +1,52 @@
+package providers
+
+import "context"
+
+// Usage reports the tokens consumed by a single provider request. PromptTokens
+// excludes prompt tokens read from or written to the provider's prompt cache.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+}
+
+// TotalTokens returns the sum of prompt, cached prompt and completion tokens
+func (u Usage) TotalTokens() int {
+	return u.InputTokens() + u.CompletionTokens
+}
+
+// InputTokens returns all prompt tokens, cached or not
+func (u Usage) InputTokens() int {
+	return u.PromptTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// CacheHitRate returns the share of prompt tokens that were read from the cache
+func (u Usage) CacheHitRate() float64 {
+	if u.InputTokens() == 0 {
+		return 0
+	}
+	return float64(u.CacheReadTokens) / float64(u.InputTokens())
+}
+
+// UsageRecorder receives token usage reported by a provider
+type UsageRecorder func(Usage)
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a context that forwards token usage reported by
+// providers to record. Providers report usage via RecordUsage.
+func WithUsageRecorder(ctx context.Context, record UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, record)
+}
+
+// RecordUsage reports token usage to the recorder attached to ctx, if any.
+// Empty usage is ignored so providers can call it unconditionally.
+func RecordUsage(ctx context.Context, usage Usage) {
+	if usage.TotalTokens() == 0 {
+		return
+	}
+	if record, ok := ctx.Value(usageRecorderKey{}).(UsageRecorder); ok && record != nil {
+		record(usage)
+	}
+}
@@ -0,0 +1,170 @@
+// Package redact masks credentials in text before it leaves the process or is written
+// to disk: prompts sent to providers, tool results, saved sessions and debug logs.
+// Built-in patterns catch common key formats (AWS, GitHub, OpenAI, Anthropic, Slack,
+// Google), bearer tokens, JWTs, private key blocks and the values of secret-looking
+// .env assignments. Configure adds patterns from the configuration, and SetSecrets
+// the known key values, such as the providers' API keys.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every credential found
+const Mask = "[REDACTED]"
+
+// minSecretLength keeps short known values, which would mask ordinary words, out of
+// the exact matches
+const minSecretLength = 8
+
+// builtin are the patterns always checked while redaction is enabled. When a pattern
+// has a capture group only the group is masked, so "API_KEY=..." keeps its name.
+var builtin = []string{
+	// AWS access key IDs and secret access keys
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`(?i)aws_?secret_?(?:access_?)?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`,
+	// GitHub personal access, OAuth and app tokens
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+	`\bgithub_pat_[A-Za-z0-9_]{22,}\b`,
+	// OpenAI and Anthropic API keys
+	`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`,
+	// Slack tokens
+	`\bxox[abposr]-[A-Za-z0-9-]{10,}`,
+	// Google API keys
+	`\bAIza[0-9A-Za-z_-]{35}\b`,
+	// Authorization headers and JSON web tokens
+	`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`,
+	`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+	// PEM private keys
+	`-----BEGIN[A-Z ]*PRIVATE KEY-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY-----`,
+	// .env and shell assignments to secret-looking names, e.g. DB_PASSWORD=hunter22,
+	// leaving references to other variables such as ${OPENAI_API_KEY}
+	`(?m)^\s*(?:export\s+)?[A-Z0-9_]*(?:KEY|SECRET|TOKEN|PASSWORD|PASSWD|CREDENTIALS?)\s*[=:]\s*["']?([^\s"'#$][^\s"'#]{5,})`,
+}
+
+// filter is the redaction in effect
+type filter struct {
+	enabled  bool
+	patterns []*regexp.Regexp
+	secrets  []string
+}
+
+var (
+	builtinPatterns = mustCompile(builtin)
+
+	mu     sync.RWMutex
+	active = filter{enabled: true, patterns: builtinPatterns}
+)
+
+// Configure turns redaction on or off and replaces the configured patterns, which are
+// checked in addition to the built-in ones
+func Configure(enabled bool, patterns []string) error {
+	extra, err := Compile(patterns)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	active.enabled = enabled
+	active.patterns = append(slices.Clip(builtinPatterns), extra...)
+	return nil
+}
+
+// SetSecrets replaces the known secret values, which are masked wherever they appear
+func SetSecrets(values ...string) {
+	var secrets []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) >= minSecretLength {
+			secrets = append(secrets, v)
+		}
+	}
+	// Longest first, so that a secret containing another is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	mu.Lock()
+	defer mu.Unlock()
+	active.secrets = secrets
+}
+
+// Compile compiles the patterns, naming the first invalid one in the error
+func Compile(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func mustCompile(patterns []string) []*regexp.Regexp {
+	compiled, err := Compile(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// String returns s with every credential replaced by Mask
+func String(s string) string {
+	mu.RLock()
+	f := active
+	mu.RUnlock()
+	if !f.enabled {
+		return s
+	}
+	return f.apply(s)
+}
+
+// Force masks credentials in s even when redaction is turned off, for text that is
+// about to be published
+func Force(s string) string {
+	mu.RLock()
+	f := active
+	mu.RUnlock()
+	return f.apply(s)
+}
+
+// apply replaces the known secrets and the matches of the patterns of f in s
+func (f filter) apply(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, secret := range f.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	for _, re := range f.patterns {
+		s = mask(re, s)
+	}
+	return s
+}
+
+// mask replaces the matches of re in s, or only their first capture group when re has one
+func mask(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, Mask)
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[2], m[3]
+		if start < 0 {
+			// The group did not take part in the match; mask all of it
+			start, end = m[0], m[1]
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(Mask)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
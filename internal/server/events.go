@@ -0,0 +1,51 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/redact"
+)
+
+// eventStream writes server-sent events. Callbacks of a running turn may send from
+// other goroutines, so writes are serialised.
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// newEventStream starts an event stream response, or returns nil when the connection
+// cannot be flushed incrementally
+func newEventStream(w http.ResponseWriter) *eventStream {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventStream{w: w, flusher: flusher}
+}
+
+// send writes one event with data encoded as JSON
+func (e *eventStream) send(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(map[string]string{"error": err.Error()})
+		event = "error"
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, "event: %s\ndata: %s\n\n", event, payload)
+	e.flusher.Flush()
+}
+
+// sendError writes err as an "error" event, with credentials masked
+func (e *eventStream) sendError(err error) {
+	e.send("error", map[string]string{"error": redact.String(err.Error())})
+}
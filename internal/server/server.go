@@ -0,0 +1,397 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
+	"github.com/pprunty/magikarp/internal/stats"
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+// maxRequestBytes bounds the size of a request body
+const maxRequestBytes = 8 << 20
+
+// Options controls what clients of the server may do
+type Options struct {
+	// Token must be sent as "Authorization: Bearer <token>"; without one every request
+	// is rejected
+	Token string
+	// Hosts are the values of the Host header accepted, such as the address the server
+	// listens on, so that pages rebinding their DNS name to it are rejected. Any host is
+	// accepted when empty.
+	Hosts []string
+	// Origins are the browser origins allowed to call the API. Requests without an
+	// Origin header, as sent by editors and scripts, are not affected.
+	Origins []string
+	// AllowTools runs every tool call the permission policy would ask about; otherwise
+	// only calls the policy allows run, since there is no one to approve them
+	AllowTools bool
+}
+
+// Server exposes the provider registry and toolbox over HTTP
+type Server struct {
+	conf   *config.Config
+	policy *permissions.Policy
+	opts   Options
+}
+
+// New creates a server for the loaded configuration. The provider registry must
+// already be initialised with orchestration.Init.
+func New(conf *config.Config, policy *permissions.Policy, opts Options) *Server {
+	return &Server{conf: conf, policy: policy, opts: opts}
+}
+
+// Handler returns the HTTP routes of the API:
+//
+//	GET  /models         models with a registered provider
+//	POST /chat           run a turn, as JSON or as server-sent events
+//	POST /tools/execute  run a single tool
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /models", s.handleModels)
+	mux.HandleFunc("POST /chat", s.handleChat)
+	mux.HandleFunc("POST /tools/execute", s.handleToolExecute)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests from unexpected hosts or origins, without the bearer
+// token, or whose body is not JSON. Cross-site pages can send plain form posts
+// without a preflight, so the content type is checked as well as the token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(s.opts.Hosts) > 0 && !slices.Contains(s.opts.Hosts, strings.ToLower(r.Host)) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("unexpected host %q", r.Host))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(s.opts.Origins, origin) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("origin %q is not allowed", origin))
+			return
+		}
+		if s.opts.Token == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, "the request body must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// modelInfo describes a model in the GET /models response
+type modelInfo struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	var models []modelInfo
+	for _, name := range orchestration.Models() {
+		p, err := orchestration.ProviderFor(name)
+		if err != nil {
+			continue
+		}
+		models = append(models, modelInfo{ID: name, Provider: p.Name()})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	if orchestration.Routing() != nil {
+		models = append([]modelInfo{{ID: orchestration.AutoModel, Provider: "router"}}, models...)
+	}
+	defaultModel, _ := orchestration.DefaultModel(s.conf)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"models": models, "default": defaultModel})
+}
+
+// chatRequest is the body of POST /chat. The last message must come from the user;
+// earlier messages are sent as history.
+type chatRequest struct {
+	Model    string                  `json:"model"`
+	System   string                  `json:"system"`
+	Messages []providers.ChatMessage `json:"messages"`
+	// Tools offers the toolbox to the model; defaults to tools.enabled from config.yaml
+	Tools  *bool `json:"tools"`
+	Stream bool  `json:"stream"`
+	// DryRun reports the file changes and commands the model asks for without making them
+	DryRun bool `json:"dry_run"`
+	// Schema is a JSON schema the answer must conform to; the answer is then the JSON
+	// document alone
+	Schema json.RawMessage `json:"schema"`
+}
+
+// toolCallInfo describes a tool executed during a turn
+type toolCallInfo struct {
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input,omitempty"`
+	Output  string                 `json:"output"`
+	IsError bool                   `json:"is_error,omitempty"`
+	Denied  bool                   `json:"denied,omitempty"`
+}
+
+// chatResponse is the result of POST /chat, and the data of the final "done" event
+type chatResponse struct {
+	Model     string         `json:"model"`
+	Content   string         `json:"content"`
+	ToolCalls []toolCallInfo `json:"tool_calls,omitempty"`
+	HitLimit  bool           `json:"hit_limit,omitempty"`
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != providers.RoleUser {
+		writeError(w, http.StatusBadRequest, "messages must end with a user message")
+		return
+	}
+	if req.Model == "" {
+		model, err := orchestration.DefaultModel(s.conf)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		req.Model = model
+	}
+	if req.Model == orchestration.AutoModel {
+		last := len(req.Messages) - 1
+		route, err := orchestration.ResolveModel(r.Context(), req.Model, req.Messages[:last], req.Messages[last].Content)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		req.Model = route.Model
+	}
+	p, err := orchestration.ProviderFor(req.Model)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if req.System == "" {
+		req.System = s.conf.System
+	}
+	useTools := s.conf.Tools.Enabled
+	if req.Tools != nil {
+		useTools = *req.Tools
+	}
+	var schema *orchestration.Schema
+	if len(req.Schema) > 0 && string(req.Schema) != "null" {
+		if schema, err = orchestration.CompileSchema("", req.Schema); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var events *eventStream
+	if req.Stream {
+		if events = newEventStream(w); events == nil {
+			writeError(w, http.StatusInternalServerError, "streaming is not supported by this connection")
+			return
+		}
+	}
+
+	// Plain chat streams token by token; turns with tools report each tool round, and
+	// answers in a schema are only sent once validated
+	if events != nil && !useTools && schema == nil {
+		s.streamChat(r.Context(), events, p, req)
+		return
+	}
+	s.runTurn(r.Context(), w, events, req, useTools, schema)
+}
+
+// runTurn runs the request as an agent turn with tools
+func (s *Server) runTurn(ctx context.Context, w http.ResponseWriter, events *eventStream, req chatRequest, useTools bool, schema *orchestration.Schema) {
+	toolDefs := tools.GetCoreTools()
+	if useTools {
+		toolDefs = tools.GetAllTools()
+	}
+
+	turn := orchestration.Turn{
+		Model:            req.Model,
+		System:           req.System,
+		History:          req.Messages[:len(req.Messages)-1],
+		Message:          req.Messages[len(req.Messages)-1].Content,
+		Tools:            toolDefs,
+		Policy:           s.policy,
+		Approve:          func(string, map[string]interface{}) bool { return s.opts.AllowTools },
+		MaxIterations:    s.conf.Tools.MaxIterations,
+		Fallbacks:        s.conf.FallbackModels,
+		DryRun:           req.DryRun,
+		Thinking:         orchestration.ThinkingFromConfig(s.conf),
+		SubAgentThinking: s.conf.Thinking.Background,
+		Schema:           schema,
+	}
+	if events != nil {
+		turn.OnRound = func(round int, calls []orchestration.ToolCall) {
+			events.send("tool_round", map[string]interface{}{"round": round, "tool_calls": toolCallInfos(calls)})
+		}
+		turn.OnRetry = func(status orchestration.RetryStatus) {
+			events.send("status", map[string]string{"status": status.String()})
+		}
+		turn.OnFailover = func(f orchestration.Failover) {
+			events.send("status", map[string]string{"status": f.String()})
+		}
+		ctx = providers.WithReasoningRecorder(ctx, func(delta string) {
+			events.send("reasoning", map[string]string{"text": delta})
+		})
+	}
+
+	result, err := orchestration.RunTurn(ctx, turn)
+	if err != nil {
+		if events != nil {
+			events.sendError(err)
+			return
+		}
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	resp := chatResponse{
+		Model:     result.Model,
+		Content:   result.Text(),
+		ToolCalls: toolCallInfos(result.ToolCalls),
+		HitLimit:  result.HitLimit,
+	}
+	if events != nil {
+		events.send("done", resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// streamChat streams a plain chat reply as "delta" events followed by "done"
+func (s *Server) streamChat(ctx context.Context, events *eventStream, p providers.Provider, req chatRequest) {
+	messages := append([]providers.ChatMessage{{Role: providers.RoleSystem, Content: req.System}}, req.Messages...)
+	ctx = orchestration.WithSessionUsage(ctx, req.Model)
+	ctx = orchestration.WithThinking(ctx, req.Model, orchestration.ThinkingFromConfig(s.conf))
+	ctx = providers.WithReasoningRecorder(ctx, func(delta string) {
+		events.send("reasoning", map[string]string{"text": delta})
+	})
+	// The provider reports the error that ends the stream before closing it
+	var streamErr error
+	ctx = providers.WithStreamErrorRecorder(ctx, func(err error) { streamErr = err })
+
+	prompt := req.Messages[len(req.Messages)-1].Content
+	if err := hooks.Run(ctx, hooks.Event{Event: hooks.PrePrompt, Model: req.Model, Prompt: prompt}); err != nil {
+		events.sendError(err)
+		return
+	}
+	if err := orchestration.CheckContextWindow(ctx, req.Model, messages, nil); err != nil {
+		events.sendError(err)
+		return
+	}
+	chunks, err := p.StreamChat(ctx, req.Model, messages, s.conf.GetEffectiveTemperature(p.Name()))
+	if err != nil {
+		events.sendError(err)
+		return
+	}
+	var content strings.Builder
+	for delta := range chunks {
+		content.WriteString(delta)
+		events.send("delta", map[string]string{"text": delta})
+	}
+	if streamErr != nil {
+		events.sendError(streamErr)
+		return
+	}
+	events.send("done", chatResponse{Model: req.Model, Content: content.String()})
+	_ = hooks.Run(ctx, hooks.Event{Event: hooks.PostResponse, Model: req.Model, Prompt: prompt, Response: content.String()})
+}
+
+// toolExecuteRequest is the body of POST /tools/execute
+type toolExecuteRequest struct {
+	Name  string                 `json:"name"`
+	Input map[string]interface{} `json:"input"`
+}
+
+func (s *Server) handleToolExecute(w http.ResponseWriter, r *http.Request) {
+	var req toolExecuteRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	def, ok := tools.GetToolByName(req.Name)
+	if !ok || def.Function == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown tool %q", req.Name))
+		return
+	}
+
+	decision := permissions.Decision{Action: permissions.Ask}
+	if s.policy != nil {
+		decision = s.policy.Evaluate(req.Name, req.Input)
+	}
+	if decision.Action == permissions.Deny || (decision.Action == permissions.Ask && !s.opts.AllowTools) {
+		msg := "tool call denied by permission policy"
+		if decision.Action == permissions.Ask {
+			msg = "tool call needs approval; allow it in tools.permissions or start the server with --yes"
+		}
+		if decision.Reason != "" {
+			msg += ": " + decision.Reason
+		}
+		writeError(w, http.StatusForbidden, msg)
+		return
+	}
+
+	if err := hooks.Run(r.Context(), hooks.Event{Event: hooks.PreTool, Tool: req.Name, Input: req.Input}); err != nil {
+		writeError(w, http.StatusForbidden, "tool call "+err.Error())
+		return
+	}
+	result, err := def.Function(r.Context(), req.Input)
+	if err != nil || result == nil {
+		result = providers.NewToolResult(req.Name, fmt.Sprintf("Tool execution error: %v", err), true)
+	}
+	// Credentials read by the tool are masked, as in agent turns
+	result.Content = redact.String(result.Content)
+	_ = hooks.Run(r.Context(), hooks.Event{Event: hooks.PostTool, Tool: req.Name, Input: req.Input, Output: result.Content, IsError: result.IsError})
+	_ = stats.Record(stats.Event{Tool: req.Name, IsError: result.IsError})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"content": result.Content, "is_error": result.IsError})
+}
+
+func toolCallInfos(calls []orchestration.ToolCall) []toolCallInfo {
+	infos := make([]toolCallInfo, 0, len(calls))
+	for _, call := range calls {
+		infos = append(infos, toolCallInfo{
+			Name:    call.Name,
+			Input:   call.Input,
+			Output:  call.Result.Content,
+			IsError: call.Result.IsError,
+			Denied:  call.Denied,
+		})
+	}
+	return infos
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes msg as the error of the response, with credentials masked
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": redact.String(msg)})
+}
@@ -0,0 +1,329 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Format is a conversation export format
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatHTML     Format = "html"
+)
+
+// Extension returns the file extension used for the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSON:
+		return ".json"
+	case FormatHTML:
+		return ".html"
+	default:
+		return ".md"
+	}
+}
+
+// ParseFormat converts a format name or file extension (e.g. "md", ".html") to a Format
+func ParseFormat(name string) (Format, bool) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "md", "markdown":
+		return FormatMarkdown, true
+	case "json":
+		return FormatJSON, true
+	case "html", "htm":
+		return FormatHTML, true
+	}
+	return "", false
+}
+
+// FormatForPath picks the export format from the file extension, defaulting to Markdown
+func FormatForPath(path string) Format {
+	if f, ok := ParseFormat(filepath.Ext(path)); ok {
+		return f
+	}
+	return FormatMarkdown
+}
+
+// Export writes the conversation, including tool calls and their results, to path
+// in the format given by its extension
+func (s *Session) Export(path string) error {
+	var data []byte
+	switch FormatForPath(path) {
+	case FormatJSON:
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode session: %w", err)
+		}
+		data = append(b, '\n')
+	case FormatHTML:
+		data = []byte(s.HTML())
+	default:
+		data = []byte(s.Markdown())
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// Markdown renders the conversation as a Markdown document
+func (s *Session) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", s.Title())
+	for _, line := range s.details() {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+
+	for _, ex := range s.Exchanges {
+		fmt.Fprintf(&b, "\n## User%s\n\n%s\n", timeSuffix(ex), strings.TrimSpace(ex.User))
+
+		fmt.Fprintf(&b, "\n## Assistant%s\n\n", modelSuffix(ex, s.Model))
+		if ex.Reasoning != "" {
+			b.WriteString("<details><summary>Reasoning</summary>\n\n")
+			b.WriteString(strings.TrimSpace(ex.Reasoning))
+			b.WriteString("\n\n</details>\n\n")
+		}
+		for _, call := range ex.ToolCalls {
+			fmt.Fprintf(&b, "**Tool `%s`**%s\n\n", call.Name, callStatus(call))
+			if len(call.Input) > 0 {
+				b.WriteString(codeFence(formatInput(call.Input), "json"))
+			}
+			if call.Output != "" {
+				b.WriteString(codeFence(call.Output, ""))
+			}
+		}
+		if ex.IsError {
+			b.WriteString("> **Error:** ")
+		}
+		b.WriteString(strings.TrimSpace(ex.Assistant))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// details returns the session metadata lines shown at the top of an export
+func (s *Session) details() []string {
+	lines := []string{"Model: " + s.Model}
+	if !s.CreatedAt.IsZero() {
+		lines = append(lines, "Started: "+s.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if s.Cwd != "" {
+		lines = append(lines, "Directory: "+s.Cwd)
+	}
+	return lines
+}
+
+func timeSuffix(ex Exchange) string {
+	if ex.Time.IsZero() {
+		return ""
+	}
+	return " · " + ex.Time.Format("15:04")
+}
+
+func modelSuffix(ex Exchange, fallback string) string {
+	if ex.Model != "" {
+		return " (" + ex.Model + ")"
+	}
+	if fallback != "" {
+		return " (" + fallback + ")"
+	}
+	return ""
+}
+
+func callStatus(call ToolCall) string {
+	switch {
+	case call.Denied:
+		return " (denied)"
+	case call.IsError:
+		return " (failed)"
+	}
+	return ""
+}
+
+func formatInput(input map[string]interface{}) string {
+	b, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		return fmt.Sprint(input)
+	}
+	return string(b)
+}
+
+// codeFence wraps text in a fence longer than any run of backticks inside it
+func codeFence(text, lang string) string {
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + fence + "\n\n"
+}
+
+// HTML renders the conversation as a standalone HTML page. Fenced code blocks,
+// tool inputs and tool output are syntax highlighted.
+func (s *Session) HTML() string {
+	var body strings.Builder
+	for _, ex := range s.Exchanges {
+		fmt.Fprintf(&body, "<section class=\"user\"><h2>User%s</h2>%s</section>\n",
+			html.EscapeString(timeSuffix(ex)), renderText(ex.User))
+
+		class := "assistant"
+		if ex.IsError {
+			class += " error"
+		}
+		fmt.Fprintf(&body, "<section class=\"%s\"><h2>Assistant%s</h2>\n", class, html.EscapeString(modelSuffix(ex, s.Model)))
+		if ex.Reasoning != "" {
+			fmt.Fprintf(&body, "<details class=\"reasoning\"><summary>Reasoning</summary>%s</details>\n", renderText(ex.Reasoning))
+		}
+		for _, call := range ex.ToolCalls {
+			fmt.Fprintf(&body, "<details class=\"tool\"><summary>Tool <code>%s</code>%s</summary>\n",
+				html.EscapeString(call.Name), html.EscapeString(callStatus(call)))
+			if len(call.Input) > 0 {
+				body.WriteString(highlight(formatInput(call.Input), "json"))
+			}
+			if call.Output != "" {
+				body.WriteString(highlight(call.Output, ""))
+			}
+			body.WriteString("</details>\n")
+		}
+		body.WriteString(renderText(ex.Assistant))
+		body.WriteString("</section>\n")
+	}
+
+	var details strings.Builder
+	for _, line := range s.details() {
+		details.WriteString("<li>" + html.EscapeString(line) + "</li>")
+	}
+
+	var css bytes.Buffer
+	_ = chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&css, highlightStyle())
+
+	return fmt.Sprintf(htmlPage, html.EscapeString(s.Title()), css.String(), html.EscapeString(s.Title()), details.String(), body.String())
+}
+
+// renderText converts message text to HTML, highlighting fenced code blocks and
+// keeping the rest as preformatted paragraphs
+func renderText(text string) string {
+	var b strings.Builder
+	var prose, code []string
+	lang, inCode := "", false
+
+	flushProse := func() {
+		if p := strings.TrimSpace(strings.Join(prose, "\n")); p != "" {
+			b.WriteString("<div class=\"text\">" + inlineCode(html.EscapeString(p)) + "</div>\n")
+		}
+		prose = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case !inCode && strings.HasPrefix(trimmed, "```"):
+			flushProse()
+			inCode, lang = true, strings.TrimSpace(strings.TrimLeft(trimmed, "`"))
+		case inCode && strings.HasPrefix(trimmed, "```"):
+			b.WriteString(highlight(strings.Join(code, "\n"), lang))
+			inCode, code = false, nil
+		case inCode:
+			code = append(code, line)
+		default:
+			prose = append(prose, line)
+		}
+	}
+	if inCode {
+		b.WriteString(highlight(strings.Join(code, "\n"), lang))
+	}
+	flushProse()
+	return b.String()
+}
+
+// inlineCode wraps `spans` of escaped text in <code> elements
+func inlineCode(escaped string) string {
+	parts := strings.Split(escaped, "`")
+	if len(parts) < 3 {
+		return escaped
+	}
+	var b strings.Builder
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			b.WriteString(part)
+		case i == len(parts)-1:
+			b.WriteString("`" + part) // unmatched backtick
+		default:
+			b.WriteString("<code>" + part + "</code>")
+		}
+	}
+	return b.String()
+}
+
+// highlight renders code as highlighted HTML, guessing the language when lang is empty
+func highlight(code, lang string) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "<pre>" + html.EscapeString(code) + "</pre>\n"
+	}
+	var b bytes.Buffer
+	if err := chromahtml.New(chromahtml.WithClasses(true)).Format(&b, highlightStyle(), tokens); err != nil {
+		return "<pre>" + html.EscapeString(code) + "</pre>\n"
+	}
+	return b.String() + "\n"
+}
+
+func highlightStyle() *chroma.Style {
+	return styles.Get("github")
+}
+
+// htmlPage is the standalone page template: title, highlight CSS, heading, details, body
+const htmlPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 860px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; line-height: 1.5; }
+header ul { color: #59636e; padding-left: 1.2rem; }
+section { border-left: 4px solid #d0d7de; padding: 0.2rem 1rem; margin: 1rem 0; }
+section.user { border-color: #9B59B6; }
+section.assistant { border-color: #04B575; }
+section.error { border-color: #E74C3C; }
+h2 { font-size: 0.95rem; color: #59636e; margin: 0.4rem 0; }
+.text { white-space: pre-wrap; margin: 0.5rem 0; }
+pre { padding: 0.75rem; overflow-x: auto; border-radius: 6px; background: #f6f8fa; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.9em; }
+details { margin: 0.5rem 0; }
+details.reasoning { color: #59636e; }
+summary { cursor: pointer; }
+%s
+</style>
+</head>
+<body>
+<header><h1>%s</h1><ul>%s</ul></header>
+%s</body>
+</html>
+`
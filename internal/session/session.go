@@ -0,0 +1,243 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/redact"
+)
+
+// ToolCall records a tool invocation made while answering a message
+type ToolCall struct {
+	Name    string                 `json:"name"`
+	Input   map[string]interface{} `json:"input,omitempty"`
+	Output  string                 `json:"output"`
+	IsError bool                   `json:"is_error,omitempty"`
+	Denied  bool                   `json:"denied,omitempty"`
+}
+
+// Exchange is a user message and the assistant's reply
+type Exchange struct {
+	User      string     `json:"user"`
+	Assistant string     `json:"assistant"`
+	Model     string     `json:"model,omitempty"`
+	Reasoning string     `json:"reasoning,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	IsError   bool       `json:"is_error,omitempty"`
+	Pinned    bool       `json:"pinned,omitempty"`
+	Time      time.Time  `json:"time"`
+}
+
+// Session is a persisted conversation
+type Session struct {
+	ID        string     `json:"id"`
+	Model     string     `json:"model"`
+	Cwd       string     `json:"cwd,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Exchanges []Exchange `json:"exchanges"`
+	// Notes is the agent's scratchpad, saved when tools.notes.persist is set
+	Notes map[string]string `json:"notes,omitempty"`
+	// Parent is the ID of the session a /fork branched off from, and Branch its name
+	Parent string `json:"parent,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// PinnedFiles are the files pinned with /pin
+	PinnedFiles []string `json:"pinned_files,omitempty"`
+}
+
+// New creates an empty session for model with a fresh ID
+func New(model string) *Session {
+	now := time.Now()
+	cwd, _ := os.Getwd()
+	return &Session{
+		ID:        newID(now),
+		Model:     model,
+		Cwd:       cwd,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// newID returns a sortable, unique session identifier
+func newID(t time.Time) string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Title returns a short human readable label (the first user message)
+func (s *Session) Title() string {
+	for _, ex := range s.Exchanges {
+		title := strings.TrimSpace(strings.ReplaceAll(ex.User, "\n", " "))
+		if title == "" {
+			continue
+		}
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		return title
+	}
+	return "(empty session)"
+}
+
+// Dir returns the directory sessions are stored in (~/.magikarp/sessions)
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".magikarp", "sessions"), nil
+}
+
+// Path returns the file path of the session with the given ID
+func Path(id string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// Save writes the session to disk, replacing any previous checkpoint atomically
+func (s *Session) Save() (string, error) {
+	path, err := Path(s.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s.redacted(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	return path, nil
+}
+
+// redacted returns a copy of the session with credentials masked, for writing to disk
+func (s *Session) redacted() *Session {
+	return s.rewritten(redact.String)
+}
+
+// Sanitized returns a copy of the session for publishing (/share): credentials are
+// masked even when redaction is turned off, and the home directory is shortened to ~
+// so that paths do not reveal the user's name
+func (s *Session) Sanitized() *Session {
+	home, _ := os.UserHomeDir()
+	clean := func(text string) string {
+		text = redact.Force(text)
+		if home != "" && home != "/" {
+			text = strings.ReplaceAll(text, home, "~")
+		}
+		return text
+	}
+	c := s.rewritten(clean)
+	c.Cwd = clean(c.Cwd)
+	return c
+}
+
+// rewritten returns a copy of the session with fix applied to the text of its
+// messages, tool calls and notes
+func (s *Session) rewritten(fix func(string) string) *Session {
+	c := *s
+	c.Exchanges = make([]Exchange, len(s.Exchanges))
+	for i, ex := range s.Exchanges {
+		ex.User = fix(ex.User)
+		ex.Assistant = fix(ex.Assistant)
+		ex.Reasoning = fix(ex.Reasoning)
+		calls := make([]ToolCall, len(ex.ToolCalls))
+		for j, call := range ex.ToolCalls {
+			input := make(map[string]interface{}, len(call.Input))
+			for k, v := range call.Input {
+				if str, ok := v.(string); ok {
+					v = fix(str)
+				}
+				input[k] = v
+			}
+			call.Input = input
+			call.Output = fix(call.Output)
+			calls[j] = call
+		}
+		ex.ToolCalls = calls
+		c.Exchanges[i] = ex
+	}
+	if s.Notes != nil {
+		c.Notes = make(map[string]string, len(s.Notes))
+		for name, text := range s.Notes {
+			c.Notes[name] = fix(text)
+		}
+	}
+	return &c
+}
+
+// Load reads the session with the given ID
+func Load(id string) (*Session, error) {
+	path, err := Path(id)
+	if err != nil {
+		return nil, err
+	}
+	return loadFile(path)
+}
+
+func loadFile(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", filepath.Base(path), err)
+	}
+	return &s, nil
+}
+
+// List returns up to limit saved sessions, most recently updated first.
+// A limit of zero or less returns every session. Unreadable files are skipped.
+func List(limit int) ([]*Session, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	var sessions []*Session
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		s, err := loadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt) })
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions, nil
+}
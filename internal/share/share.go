@@ -0,0 +1,127 @@
+// Package share uploads conversation transcripts (/share) to a GitHub gist or a
+// configurable paste service and returns the link.
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/github"
+)
+
+// uploadTimeout bounds an upload to a paste service
+const uploadTimeout = 30 * time.Second
+
+// maxResponse bounds the response of a paste service that is read
+const maxResponse = 64 << 10
+
+// Document is a transcript to share
+type Document struct {
+	Title string
+	// Name is the file name of the transcript, e.g. magikarp-20250101-120000.md
+	Name    string
+	Content string
+}
+
+// Service returns the service share.service selects, defaulting to gist
+func Service(conf *config.Config) string {
+	if conf == nil || conf.Share.Service == "" {
+		return "gist"
+	}
+	return conf.Share.Service
+}
+
+// Destination describes where Upload sends documents, for confirming a share
+func Destination(conf *config.Config) string {
+	if Service(conf) == "paste" {
+		if u, err := url.Parse(conf.Share.URL); err == nil && u.Host != "" {
+			return u.Host
+		}
+		return conf.Share.URL
+	}
+	if conf != nil && conf.Share.Public {
+		return "a public GitHub gist"
+	}
+	return "a secret GitHub gist"
+}
+
+// Upload shares doc with the configured service and returns its link
+func Upload(ctx context.Context, conf *config.Config, doc Document) (string, error) {
+	if Service(conf) == "paste" {
+		return uploadPaste(ctx, conf.Share, doc)
+	}
+	var gh config.GitHubConfig
+	var public bool
+	if conf != nil {
+		gh, public = conf.GitHub, conf.Share.Public
+	}
+	client, err := github.NewClient(gh)
+	if err != nil {
+		return "", err
+	}
+	return client.CreateGist(ctx, github.Gist{
+		Description: doc.Title,
+		Public:      public,
+		Files:       map[string]string{doc.Name: doc.Content},
+	})
+}
+
+// uploadPaste posts doc to the paste service and returns the link it answers with
+func uploadPaste(ctx context.Context, conf config.ShareConfig, doc Document) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, conf.URL, strings.NewReader(doc.Content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
+	for name, value := range conf.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("uploading the transcript: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("uploading the transcript: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	link := pasteLink(body)
+	if link == "" {
+		link = resp.Header.Get("Location")
+	}
+	if link == "" {
+		return "", fmt.Errorf("uploading the transcript: the service did not answer with a link")
+	}
+	return link, nil
+}
+
+// pasteLink finds the link in the response of a paste service: a JSON object with a
+// url, link or html_url field, or the URL as plain text
+func pasteLink(body []byte) string {
+	var fields map[string]interface{}
+	if json.Unmarshal(body, &fields) == nil {
+		for _, key := range []string{"url", "link", "html_url"} {
+			if s, ok := fields[key].(string); ok && s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "http://") || strings.HasPrefix(text, "https://") {
+		if line, _, _ := strings.Cut(text, "\n"); line != "" {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
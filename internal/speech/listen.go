@@ -0,0 +1,291 @@
+package speech
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// SampleRate is the rate of the recorded audio: 16 kHz mono 16-bit PCM
+const SampleRate = 16000
+
+// Utterance detection works on 30ms frames
+const (
+	frameDuration = 30 * time.Millisecond
+	frameBytes    = SampleRate * 2 * 30 / 1000
+	// defaultPause is the silence that ends an utterance unless speech.pause is set
+	defaultPause = time.Second
+	// defaultPreroll and defaultPostroll are the audio kept before and after the speech
+	// of an utterance unless speech.preroll and speech.postroll are set
+	defaultPreroll  = 300 * time.Millisecond
+	defaultPostroll = 300 * time.Millisecond
+	// speechFrames of loud frames in a row start an utterance
+	speechFrames = 3
+	// noiseFrames at the start of the recording are averaged as the initial noise floor
+	noiseFrames = 10
+	// minUtterance is the shortest utterance passed on; shorter ones are clicks and coughs
+	minUtterance = 300 * time.Millisecond
+	// maxUtterance cuts long monologues so they are transcribed as they go
+	maxUtterance = time.Minute
+	// defaultThreshold is the lowest RMS counted as speech, whatever the noise floor,
+	// unless speech.threshold is set
+	defaultThreshold = 500
+)
+
+// Calibration of speech.threshold
+const (
+	// CalibrationTime is how long Calibrate listens to the background noise
+	CalibrationTime = 3 * time.Second
+	// calibrationMargin is how much louder than the loudest background noise speech must be
+	calibrationMargin = 2.5
+	// minThreshold keeps a calibration in a silent room from counting every sound as speech
+	minThreshold = 100
+)
+
+// Listen records the microphone until ctx is cancelled and calls onUtterance with the
+// PCM samples of every utterance, a stretch of speech followed by a pause. Speech is
+// told from silence by its loudness over the background noise.
+func Listen(ctx context.Context, conf config.SpeechConfig, onUtterance func(pcm []byte)) error {
+	return record(ctx, conf, func(audio io.Reader) error {
+		d := newDetector(conf, onUtterance)
+		frame := make([]byte, frameBytes)
+		for {
+			if _, err := io.ReadFull(audio, frame); err != nil {
+				return err
+			}
+			d.add(frame)
+		}
+	})
+}
+
+// Calibrate records the background noise for CalibrationTime and returns a speech
+// threshold suited to it. The room should be quiet apart from its usual noise.
+func Calibrate(ctx context.Context, conf config.SpeechConfig) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, CalibrationTime)
+	defer cancel()
+	var levels []float64
+	err := record(ctx, conf, func(audio io.Reader) error {
+		frame := make([]byte, frameBytes)
+		for {
+			if _, err := io.ReadFull(audio, frame); err != nil {
+				return err
+			}
+			levels = append(levels, rms(frame))
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(levels) < noiseFrames {
+		return 0, fmt.Errorf("the recorder produced too little audio to calibrate")
+	}
+	// The threshold must clear the noise's louder moments, not just its average
+	sort.Float64s(levels)
+	noise := levels[len(levels)*9/10]
+	return math.Round(math.Max(noise*calibrationMargin, minThreshold)), nil
+}
+
+// Record records the microphone until ctx is cancelled and returns the PCM samples
+func Record(ctx context.Context, conf config.SpeechConfig) ([]byte, error) {
+	var pcm []byte
+	err := record(ctx, conf, func(audio io.Reader) error {
+		var err error
+		pcm, err = io.ReadAll(audio)
+		return err
+	})
+	return pcm, err
+}
+
+// Duration returns the length of recorded PCM samples
+func Duration(pcm []byte) time.Duration {
+	return time.Duration(len(pcm)/2) * time.Second / SampleRate
+}
+
+// WAV wraps recorded PCM samples in a WAV header
+func WAV(pcm []byte) []byte {
+	return wav(pcm, SampleRate)
+}
+
+// record runs the recorder and hands its output to read. Cancelling ctx stops the
+// recorder, which is not an error.
+func record(ctx context.Context, conf config.SpeechConfig, read func(io.Reader) error) error {
+	args, err := RecorderCommand(conf.Recorder)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting the recorder: %w", err)
+	}
+
+	readErr := read(stdout)
+	waitErr := cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("recorder %s: %s", args[0], msg)
+		}
+		return fmt.Errorf("recorder %s: %w", args[0], waitErr)
+	case readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF):
+		return readErr
+	}
+	return nil
+}
+
+// recorders are tried in order when speech.recorder is not set; each writes raw
+// 16 kHz mono 16-bit little-endian PCM to stdout
+var recorders = [][]string{
+	{"rec", "-q", "-t", "raw", "-r", "16000", "-c", "1", "-b", "16", "-e", "signed-integer", "-"},
+	{"arecord", "-q", "-t", "raw", "-f", "S16_LE", "-c", "1", "-r", "16000", "-"},
+}
+
+// RecorderCommand returns the command line of the configured recorder, or of the first
+// installed one
+func RecorderCommand(recorder string) ([]string, error) {
+	if fields := strings.Fields(recorder); len(fields) > 0 {
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return nil, fmt.Errorf("speech.recorder: %w", err)
+		}
+		return fields, nil
+	}
+	for _, r := range recorders {
+		if _, err := exec.LookPath(r[0]); err == nil {
+			return r, nil
+		}
+	}
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		input := []string{"-f", "pulse", "-i", "default"}
+		switch runtime.GOOS {
+		case "darwin":
+			input = []string{"-f", "avfoundation", "-i", ":0"}
+		case "windows":
+			input = []string{"-f", "dshow", "-i", "audio=default"}
+		}
+		args := append([]string{"ffmpeg", "-loglevel", "error", "-nostdin"}, input...)
+		return append(args, "-ac", "1", "-ar", "16000", "-f", "s16le", "-"), nil
+	}
+	return nil, fmt.Errorf("no audio recorder found; install SoX (rec), arecord or ffmpeg, or set speech.recorder")
+}
+
+// detector cuts a stream of frames into utterances
+type detector struct {
+	pauseFrames    int
+	prerollFrames  int
+	postrollFrames int
+	threshold      float64
+	onUtterance    func(pcm []byte)
+
+	floor   float64  // background noise level
+	frames  int      // frames seen, for the initial noise estimate
+	loud    int      // loud frames in a row before an utterance starts
+	recent  [][]byte // frames before the utterance, kept as preroll
+	speech  []byte   // the utterance so far; nil between utterances
+	silence int      // quiet frames in a row within the utterance
+	spoken  int      // loud frames within the utterance
+}
+
+// newDetector returns a detector with the settings of conf
+func newDetector(conf config.SpeechConfig, onUtterance func(pcm []byte)) *detector {
+	threshold := conf.Threshold
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+	return &detector{
+		pauseFrames:    frames(conf.Pause, defaultPause),
+		prerollFrames:  frames(conf.Preroll, defaultPreroll),
+		postrollFrames: frames(conf.Postroll, defaultPostroll),
+		threshold:      threshold,
+		onUtterance:    onUtterance,
+	}
+}
+
+// frames returns the number of frames in d, or in def when d is not set
+func frames(d, def time.Duration) int {
+	if d <= 0 {
+		d = def
+	}
+	return max(1, int(d/frameDuration))
+}
+
+// add processes the next frame
+func (d *detector) add(frame []byte) {
+	level := rms(frame)
+	d.frames++
+	if d.frames <= noiseFrames {
+		// Average the first frames as the initial noise floor
+		d.floor += (level - d.floor) / float64(d.frames)
+	}
+	loud := level > math.Max(d.floor*3, d.threshold)
+	f := append([]byte(nil), frame...)
+
+	if d.speech == nil {
+		if loud {
+			d.loud++
+		} else {
+			d.loud = 0
+			d.floor = 0.95*d.floor + 0.05*level
+		}
+		d.recent = append(d.recent, f)
+		if len(d.recent) > d.prerollFrames+speechFrames {
+			d.recent = d.recent[1:]
+		}
+		if d.loud >= speechFrames {
+			d.speech = []byte{}
+			for _, r := range d.recent {
+				d.speech = append(d.speech, r...)
+			}
+			d.recent, d.loud, d.silence, d.spoken = nil, 0, 0, speechFrames
+		}
+		return
+	}
+
+	d.speech = append(d.speech, f...)
+	if loud {
+		d.silence = 0
+		d.spoken++
+	} else {
+		d.silence++
+	}
+	length := time.Duration(len(d.speech)/frameBytes) * frameDuration
+	if d.silence >= d.pauseFrames || length >= maxUtterance {
+		if time.Duration(d.spoken)*frameDuration >= minUtterance {
+			if extra := d.silence - d.postrollFrames; extra > 0 {
+				d.speech = d.speech[:len(d.speech)-extra*frameBytes]
+			}
+			d.onUtterance(d.speech)
+		}
+		d.speech = nil
+	}
+}
+
+// rms returns the loudness of 16-bit little-endian samples
+func rms(frame []byte) float64 {
+	var sum float64
+	n := len(frame) / 2
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(frame[2*i:])))
+		sum += s * s
+	}
+	if n == 0 {
+		return 0
+	}
+	return math.Sqrt(sum / float64(n))
+}
@@ -0,0 +1,204 @@
+package speech
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// minSentence is the shortest text spoken on its own; shorter sentences are joined
+// with the next one, which also keeps list numbers and abbreviations together
+const minSentence = 24
+
+// Reader reads text aloud as it arrives. Complete sentences are queued and spoken one
+// after another by a background goroutine; fenced code blocks are skipped and markdown
+// is stripped. Methods are safe for concurrent use.
+type Reader struct {
+	speaker Speaker
+	// OnError is called with errors of the speaker, except cancellations
+	OnError func(error)
+
+	mu       sync.Mutex
+	buf      string // text of the current line not yet moved to sentence
+	sentence string // text of the sentence being collected
+	midLine  bool   // the start of the current line has been read
+	inCode   bool   // inside a fenced code block
+	queue    []string
+	speaking bool // a sentence is being spoken
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wake     chan struct{}
+}
+
+// NewReader returns a reader speaking through speaker
+func NewReader(speaker Speaker) *Reader {
+	r := &Reader{speaker: speaker, wake: make(chan struct{}, 1)}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run()
+	return r
+}
+
+// Write adds streamed text. Sentences it completes are queued to be spoken.
+func (r *Reader) Write(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf += text
+	r.scan()
+}
+
+// Flush queues the rest of the text, as at the end of a response, and resets the
+// markdown state for the next one
+func (r *Reader) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.inCode {
+		r.sentence += " " + r.buf
+	}
+	r.buf, r.midLine, r.inCode = "", false, false
+	r.emit(true)
+}
+
+// Stop drops the queued text and interrupts the sentence being spoken
+func (r *Reader) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancel()
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.queue = nil
+	r.buf, r.sentence, r.midLine, r.inCode = "", "", false, false
+}
+
+// Busy reports whether text is being spoken or waits to be
+func (r *Reader) Busy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.speaking || len(r.queue) > 0
+}
+
+// scan moves complete lines, and complete sentences of the current line, from buf to
+// the queue. It must be called with mu held.
+func (r *Reader) scan() {
+	for r.buf != "" {
+		nl := strings.IndexByte(r.buf, '\n')
+		if !r.midLine {
+			// A line's start decides whether it opens or closes a code block
+			start := strings.TrimLeft(r.buf, " \t")
+			if nl < 0 && len(start) < 3 && strings.HasPrefix("```", start) {
+				return // wait for more of the line
+			}
+			if strings.HasPrefix(start, "```") || r.inCode {
+				if nl < 0 {
+					return
+				}
+				if strings.HasPrefix(start, "```") {
+					r.inCode = !r.inCode
+					r.emit(true)
+				}
+				r.buf = r.buf[nl+1:]
+				continue
+			}
+			r.midLine = true
+		}
+		if nl < 0 {
+			r.sentence += r.buf
+			r.buf = ""
+			r.emit(false)
+			return
+		}
+		// Line breaks end headings and list items as well as paragraphs
+		r.sentence += r.buf[:nl]
+		r.buf, r.midLine = r.buf[nl+1:], false
+		r.emit(true)
+	}
+}
+
+// emit queues the complete sentences collected so far, and the incomplete rest when
+// all is set. It must be called with mu held.
+func (r *Reader) emit(all bool) {
+	for {
+		end := sentenceEnd(r.sentence)
+		if end < 0 {
+			break
+		}
+		r.push(r.sentence[:end])
+		r.sentence = r.sentence[end:]
+	}
+	if all {
+		r.push(r.sentence)
+		r.sentence = ""
+	}
+}
+
+// push queues text for the speaker once it is cleaned up. It must be called with mu held.
+func (r *Reader) push(text string) {
+	text = Speakable(text)
+	if text == "" {
+		return
+	}
+	r.queue = append(r.queue, text)
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run speaks queued text until the process exits
+func (r *Reader) run() {
+	for range r.wake {
+		for {
+			r.mu.Lock()
+			if len(r.queue) == 0 {
+				r.speaking = false
+				r.mu.Unlock()
+				break
+			}
+			text, ctx := r.queue[0], r.ctx
+			r.queue, r.speaking = r.queue[1:], true
+			r.mu.Unlock()
+
+			if err := r.speaker.Speak(ctx, text); err != nil && ctx.Err() == nil && r.OnError != nil {
+				r.OnError(err)
+			}
+		}
+	}
+}
+
+// sentenceEnd returns the index just after the first sentence of s that is long enough
+// to be spoken on its own, or -1
+func sentenceEnd(s string) int {
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i] {
+		case '.', '!', '?', ';':
+			if unicode.IsSpace(rune(s[i+1])) && len(strings.TrimSpace(s[:i+1])) >= minSentence {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+var (
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdURL        = regexp.MustCompile(`https?://\S+`)
+	mdLinePrefix = regexp.MustCompile(`(?m)^\s*(#{1,6}\s+|>\s*|[-*+]\s+|\d+[.)]\s+)`)
+	mdEmphasis   = regexp.MustCompile("\\*{1,3}|_{2,3}|~~|`+|\\|")
+	spaces       = regexp.MustCompile(`\s+`)
+)
+
+// Speakable strips markdown from text so that it reads naturally: link targets, URLs,
+// emphasis, backticks, headings, quotes and list markers are dropped
+func Speakable(text string) string {
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdURL.ReplaceAllString(text, "link")
+	text = mdLinePrefix.ReplaceAllString(text, "")
+	text = mdEmphasis.ReplaceAllString(text, "")
+	text = strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+	if strings.Trim(text, " -=:.") == "" {
+		return "" // table rules, horizontal rules and stray punctuation
+	}
+	return text
+}
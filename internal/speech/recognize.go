@@ -0,0 +1,122 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// Recognition backends of speech.backend
+const (
+	RecognizerOpenAI   = "openai"
+	RecognizerDeepgram = "deepgram"
+)
+
+// Deepgram defaults and endpoint
+const (
+	deepgramURL          = "https://api.deepgram.com/v1/listen"
+	deepgramDefaultModel = "nova-3"
+)
+
+// Recognizer turns recorded speech into text with the configured backend
+type Recognizer struct {
+	transcriber providers.Transcriber
+	conf        config.SpeechConfig
+}
+
+// NewRecognizer returns the recognizer of speech.backend. The openai backend needs the
+// openai provider to be configured.
+func NewRecognizer(conf config.SpeechConfig) (*Recognizer, error) {
+	switch conf.Backend {
+	case "", RecognizerOpenAI:
+		t, err := orchestration.TranscriberFor("openai")
+		if err != nil {
+			return nil, err
+		}
+		return &Recognizer{transcriber: t, conf: conf}, nil
+	case RecognizerDeepgram:
+		return &Recognizer{transcriber: &deepgram{key: conf.Key}, conf: conf}, nil
+	}
+	return nil, fmt.Errorf("unknown speech backend %q", conf.Backend)
+}
+
+// Transcribe returns the text of recorded PCM samples
+func (r *Recognizer) Transcribe(ctx context.Context, pcm []byte) (string, error) {
+	return r.transcribe(ctx, pcm, "")
+}
+
+// transcribe returns the text of recorded PCM samples, expecting the words of prompt
+func (r *Recognizer) transcribe(ctx context.Context, pcm []byte, prompt string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return r.transcriber.Transcribe(ctx, providers.TranscriptionRequest{
+		Audio:    WAV(pcm),
+		Model:    r.conf.Model,
+		Language: r.conf.Language,
+		Prompt:   prompt,
+	})
+}
+
+// deepgram transcribes speech with the Deepgram API
+type deepgram struct {
+	key string
+}
+
+func (d *deepgram) Transcribe(ctx context.Context, req providers.TranscriptionRequest) (string, error) {
+	model := req.Model
+	if model == "" {
+		model = deepgramDefaultModel
+	}
+	query := url.Values{"model": {model}, "smart_format": {"true"}}
+	if req.Language != "" {
+		query.Set("language", req.Language)
+	}
+	if req.Prompt != "" {
+		query.Set("keyterm", req.Prompt)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, deepgramURL+"?"+query.Encode(), bytes.NewReader(req.Audio))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "audio/wav")
+	httpReq.Header.Set("Authorization", "Token "+d.key)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("deepgram: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Results struct {
+			Channels []struct {
+				Alternatives []struct {
+					Transcript string `json:"transcript"`
+				} `json:"alternatives"`
+			} `json:"channels"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("deepgram: %w", err)
+	}
+	if len(result.Results.Channels) == 0 || len(result.Results.Channels[0].Alternatives) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(result.Results.Channels[0].Alternatives[0].Transcript), nil
+}
@@ -0,0 +1,255 @@
+// Package speech reads the assistant's responses aloud and turns what the user says into
+// text. Speakers wrap a text-to-speech backend (say, espeak, OpenAI or ElevenLabs) and a
+// Reader feeds them streamed text one sentence at a time; Listen cuts the microphone
+// recording into utterances, a Recognizer transcribes them (OpenAI or Deepgram) and a
+// WakeGate keeps those starting with the wake word.
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// Backends of voice.backend
+const (
+	BackendAuto       = "auto"
+	BackendSay        = "say"
+	BackendEspeak     = "espeak"
+	BackendOpenAI     = "openai"
+	BackendElevenLabs = "elevenlabs"
+)
+
+// ElevenLabs defaults and endpoint
+const (
+	elevenLabsURL          = "https://api.elevenlabs.io/v1/text-to-speech/"
+	elevenLabsDefaultVoice = "21m00Tcm4TlvDQ8ikWQT"
+	elevenLabsDefaultModel = "eleven_multilingual_v2"
+	// elevenLabsSampleRate matches the output_format requested, 16-bit mono PCM
+	elevenLabsSampleRate = 16000
+)
+
+// requestTimeout bounds a speech request to a remote backend
+const requestTimeout = time.Minute
+
+// Speaker speaks text aloud and returns once it has been spoken
+type Speaker interface {
+	Speak(ctx context.Context, text string) error
+}
+
+// NewSpeaker returns the speaker of the configured backend. It fails when the backend's
+// command, player or provider is unavailable.
+func NewSpeaker(conf config.VoiceConfig) (Speaker, error) {
+	backend := conf.Backend
+	if backend == "" || backend == BackendAuto {
+		backend = BackendEspeak
+		if runtime.GOOS == "darwin" {
+			backend = BackendSay
+		}
+	}
+
+	switch backend {
+	case BackendSay:
+		if _, err := exec.LookPath("say"); err != nil {
+			return nil, fmt.Errorf("the say backend needs the say command (macOS)")
+		}
+		return &commandSpeaker{name: "say", voiceFlag: "-v", rateFlag: "-r", conf: conf}, nil
+	case BackendEspeak:
+		for _, name := range []string{"espeak-ng", "espeak"} {
+			if _, err := exec.LookPath(name); err == nil {
+				return &commandSpeaker{name: name, voiceFlag: "-v", rateFlag: "-s", conf: conf}, nil
+			}
+		}
+		return nil, fmt.Errorf("the espeak backend needs espeak-ng or espeak installed")
+	case BackendOpenAI:
+		synth, err := orchestration.SynthesizerFor("openai")
+		if err != nil {
+			return nil, err
+		}
+		player, err := findPlayer(conf.Player)
+		if err != nil {
+			return nil, err
+		}
+		return &audioSpeaker{synth: synth, player: player, conf: conf}, nil
+	case BackendElevenLabs:
+		player, err := findPlayer(conf.Player)
+		if err != nil {
+			return nil, err
+		}
+		return &audioSpeaker{synth: &elevenLabs{key: conf.Key}, player: player, conf: conf}, nil
+	}
+	return nil, fmt.Errorf("unknown voice backend %q", conf.Backend)
+}
+
+// commandSpeaker speaks through a local command that takes the text as argument
+type commandSpeaker struct {
+	name                string
+	voiceFlag, rateFlag string
+	conf                config.VoiceConfig
+}
+
+func (s *commandSpeaker) Speak(ctx context.Context, text string) error {
+	var args []string
+	if s.conf.Voice != "" {
+		args = append(args, s.voiceFlag, s.conf.Voice)
+	}
+	if s.conf.Rate > 0 {
+		args = append(args, s.rateFlag, strconv.Itoa(s.conf.Rate))
+	}
+	// "--" keeps text starting with a dash from being read as a flag
+	args = append(args, "--", text)
+	return runQuiet(ctx, s.name, args...)
+}
+
+// audioSpeaker synthesizes WAV audio and plays it
+type audioSpeaker struct {
+	synth  providers.Synthesizer
+	player []string
+	conf   config.VoiceConfig
+}
+
+func (s *audioSpeaker) Speak(ctx context.Context, text string) error {
+	reqCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	audio, err := s.synth.Synthesize(reqCtx, providers.SpeechRequest{Text: text, Model: s.conf.Model, Voice: s.conf.Voice})
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", "magikarp-speech-*.wav")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(audio); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	args := append(append([]string{}, s.player[1:]...), f.Name())
+	return runQuiet(ctx, s.player[0], args...)
+}
+
+// players are tried in order when voice.player is not set
+var players = [][]string{
+	{"afplay"},
+	{"paplay"},
+	{"aplay", "-q"},
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "quiet"},
+	{"mpv", "--no-video", "--really-quiet"},
+}
+
+// findPlayer returns the command line of the configured player, or of the first
+// installed one
+func findPlayer(player string) ([]string, error) {
+	if fields := strings.Fields(player); len(fields) > 0 {
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return nil, fmt.Errorf("voice.player: %w", err)
+		}
+		return fields, nil
+	}
+	for _, p := range players {
+		if _, err := exec.LookPath(p[0]); err == nil {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no audio player found; install one of afplay, paplay, aplay, ffplay or mpv, or set voice.player")
+}
+
+// runQuiet runs a command without letting it write to the terminal
+func runQuiet(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// elevenLabs synthesizes speech with the ElevenLabs API
+type elevenLabs struct {
+	key string
+}
+
+func (e *elevenLabs) Synthesize(ctx context.Context, req providers.SpeechRequest) ([]byte, error) {
+	voice, model := req.Voice, req.Model
+	if voice == "" {
+		voice = elevenLabsDefaultVoice
+	}
+	if model == "" {
+		model = elevenLabsDefaultModel
+	}
+	body, err := json.Marshal(map[string]string{"text": req.Text, "model_id": model})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := elevenLabsURL + url.PathEscape(voice) + "?output_format=pcm_" + strconv.Itoa(elevenLabsSampleRate)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("xi-api-key", e.key)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("elevenlabs: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return wav(data, elevenLabsSampleRate), nil
+}
+
+// wav wraps 16-bit little-endian mono PCM samples in a WAV header
+func wav(pcm []byte, sampleRate int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	for _, v := range []any{
+		uint32(16),             // size of the fmt chunk
+		uint16(1),              // PCM
+		uint16(1),              // mono
+		uint32(sampleRate),     // samples per second
+		uint32(sampleRate * 2), // bytes per second
+		uint16(2),              // bytes per sample
+		uint16(16),             // bits per sample
+	} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
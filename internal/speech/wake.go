@@ -0,0 +1,173 @@
+package speech
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// Wake word detection
+const (
+	// wakeHead is how much of an utterance is transcribed to look for the wake word;
+	// the rest is only transcribed once the wake word is heard
+	wakeHead = 2500 * time.Millisecond
+	// AwakeWindow is how long after a wake word said on its own the next utterance is
+	// taken as the prompt
+	AwakeWindow = 8 * time.Second
+	// defaultSensitivity is used unless speech.sensitivity is set
+	defaultSensitivity = 0.5
+)
+
+// WakeGate passes on only the utterances addressed to the assistant: those starting
+// with the wake word, and the one following a wake word said on its own. Utterances
+// are transcribed in two steps, so that speech not addressed to the assistant is only
+// transcribed as far as needed to tell.
+type WakeGate struct {
+	recognizer *Recognizer
+	word       string
+	words      int
+	// similarity is the lowest similarity of the heard and the wake word that matches
+	similarity float64
+
+	mu         sync.Mutex
+	awakeUntil time.Time
+}
+
+// Heard is what a WakeGate made of an utterance
+type Heard struct {
+	// Prompt is what was said after the wake word; empty when the utterance was not
+	// addressed to the assistant or held only the wake word
+	Prompt string
+	// AwakeUntil is set when the wake word was said on its own: an utterance until then
+	// is the prompt
+	AwakeUntil time.Time
+}
+
+// NewWakeGate returns the gate of speech.wake_word, or nil when none is set
+func NewWakeGate(conf config.SpeechConfig, recognizer *Recognizer) *WakeGate {
+	word := normalizeWords(conf.WakeWord)
+	if word == "" {
+		return nil
+	}
+	sensitivity := conf.Sensitivity
+	if sensitivity <= 0 {
+		sensitivity = defaultSensitivity
+	}
+	return &WakeGate{
+		recognizer: recognizer,
+		word:       word,
+		words:      len(strings.Fields(word)),
+		// At sensitivity 0 the wake word must be heard exactly; at 1 half of it will do
+		similarity: 1 - sensitivity/2,
+	}
+}
+
+// Word returns the wake word
+func (g *WakeGate) Word() string {
+	return g.word
+}
+
+// Hear transcribes as much of an utterance as it takes to tell whether it is addressed
+// to the assistant
+func (g *WakeGate) Hear(ctx context.Context, pcm []byte) (Heard, error) {
+	g.mu.Lock()
+	awake := time.Now().Before(g.awakeUntil)
+	g.awakeUntil = time.Time{}
+	g.mu.Unlock()
+	if awake {
+		text, err := g.recognizer.Transcribe(ctx, pcm)
+		if err != nil {
+			return Heard{}, err
+		}
+		// "hey magikarp ... hey magikarp, do this" still leaves just the prompt
+		if prompt, ok := g.match(text); ok {
+			text = prompt
+		}
+		return Heard{Prompt: text}, nil
+	}
+
+	head := pcm
+	if n := int(wakeHead.Seconds()*SampleRate) * 2; len(pcm) > n+n/2 {
+		head = pcm[:n]
+	}
+	text, err := g.recognizer.transcribe(ctx, head, g.word)
+	if err != nil {
+		return Heard{}, err
+	}
+	prompt, ok := g.match(text)
+	if !ok {
+		return Heard{}, nil
+	}
+	if len(head) < len(pcm) {
+		if text, err = g.recognizer.transcribe(ctx, pcm, g.word); err != nil {
+			return Heard{}, err
+		}
+		if prompt, ok = g.match(text); !ok {
+			prompt = text
+		}
+	}
+	if prompt == "" {
+		g.mu.Lock()
+		g.awakeUntil = time.Now().Add(AwakeWindow)
+		heard := Heard{AwakeUntil: g.awakeUntil}
+		g.mu.Unlock()
+		return heard, nil
+	}
+	return Heard{Prompt: prompt}, nil
+}
+
+// match reports whether text starts with the wake word and returns the rest of it.
+// Transcriptions split or merge words ("magi carp"), so a few word counts around the
+// wake word's are tried.
+func (g *WakeGate) match(text string) (string, bool) {
+	words := strings.Fields(text)
+	for n := max(1, g.words-1); n <= g.words+1 && n <= len(words); n++ {
+		heard := normalizeWords(strings.Join(words[:n], " "))
+		if similarity(strings.ReplaceAll(heard, " ", ""), strings.ReplaceAll(g.word, " ", "")) >= g.similarity {
+			rest := strings.Join(words[n:], " ")
+			return strings.TrimLeftFunc(rest, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) }), true
+		}
+	}
+	return "", false
+}
+
+// normalizeWords lowercases text and drops its punctuation
+func normalizeWords(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// similarity returns 1 for equal strings down to 0 for entirely different ones, by
+// their edit distance
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
@@ -0,0 +1,271 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// FileName is the usage log inside ~/.magikarp
+const FileName = "usage.jsonl"
+
+// Event is a single entry of the usage log: a provider request when Model is set,
+// otherwise a tool invocation
+type Event struct {
+	Time             time.Time `json:"time"`
+	Session          string    `json:"session"`
+	Model            string    `json:"model,omitempty"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	CacheReadTokens  int       `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int       `json:"cache_write_tokens,omitempty"`
+	Cost             float64   `json:"cost,omitempty"`
+	Tool             string    `json:"tool,omitempty"`
+	IsError          bool      `json:"is_error,omitempty"`
+}
+
+// process holds the events of this process and serialises writes to the log file
+var process = struct {
+	sync.Mutex
+	session string
+	events  []Event
+}{session: fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())}
+
+// SessionID identifies the events recorded by this process
+func SessionID() string {
+	return process.session
+}
+
+// Path returns the location of the usage log (~/.magikarp/usage.jsonl)
+func Path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".magikarp", FileName), nil
+}
+
+// Record stamps e with the time and session and appends it to the usage log. The
+// event is kept for SessionEvents even when the log cannot be written.
+func Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Session = process.session
+
+	process.Lock()
+	defer process.Unlock()
+	process.events = append(process.events, e)
+
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create usage log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer f.Close()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage event: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// SessionEvents returns the events recorded by this process
+func SessionEvents() []Event {
+	process.Lock()
+	defer process.Unlock()
+	return append([]Event(nil), process.events...)
+}
+
+// Load reads every event in the usage log. A missing log is empty; lines that
+// cannot be parsed, e.g. from an interrupted write, are skipped.
+func Load() ([]Event, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			events = append(events, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage log: %w", err)
+	}
+	return events, nil
+}
+
+// ModelStats aggregates the requests made to a model
+type ModelStats struct {
+	Model    string
+	Requests int
+	Usage    providers.Usage
+	Cost     float64
+}
+
+// ToolStats aggregates the invocations of a tool
+type ToolStats struct {
+	Name   string
+	Calls  int
+	Errors int
+}
+
+// Summary aggregates a set of events
+type Summary struct {
+	Sessions  int
+	Requests  int
+	ToolCalls int
+	Usage     providers.Usage
+	Cost      float64
+	// Since is the time of the earliest event
+	Since time.Time
+	// Models is sorted by cost, then requests; Tools by number of calls
+	Models []ModelStats
+	Tools  []ToolStats
+}
+
+// Summarize aggregates events by model and tool
+func Summarize(events []Event) Summary {
+	var s Summary
+	sessions := map[string]bool{}
+	models := map[string]*ModelStats{}
+	tools := map[string]*ToolStats{}
+	for _, e := range events {
+		sessions[e.Session] = true
+		if s.Since.IsZero() || e.Time.Before(s.Since) {
+			s.Since = e.Time
+		}
+		if e.Model != "" {
+			m, ok := models[e.Model]
+			if !ok {
+				m = &ModelStats{Model: e.Model}
+				models[e.Model] = m
+			}
+			m.Requests++
+			m.Usage = add(m.Usage, e)
+			m.Cost += e.Cost
+			s.Requests++
+			s.Usage = add(s.Usage, e)
+			s.Cost += e.Cost
+		}
+		if e.Tool != "" {
+			t, ok := tools[e.Tool]
+			if !ok {
+				t = &ToolStats{Name: e.Tool}
+				tools[e.Tool] = t
+			}
+			t.Calls++
+			if e.IsError {
+				t.Errors++
+			}
+			s.ToolCalls++
+		}
+	}
+	s.Sessions = len(sessions)
+
+	for _, m := range models {
+		s.Models = append(s.Models, *m)
+	}
+	sort.Slice(s.Models, func(i, j int) bool {
+		a, b := s.Models[i], s.Models[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Model < b.Model
+	})
+	for _, t := range tools {
+		s.Tools = append(s.Tools, *t)
+	}
+	sort.Slice(s.Tools, func(i, j int) bool {
+		if s.Tools[i].Calls != s.Tools[j].Calls {
+			return s.Tools[i].Calls > s.Tools[j].Calls
+		}
+		return s.Tools[i].Name < s.Tools[j].Name
+	})
+	return s
+}
+
+// Usage returns the tokens of a provider request event
+func (e Event) Usage() providers.Usage {
+	return providers.Usage{
+		PromptTokens:     e.PromptTokens,
+		CompletionTokens: e.CompletionTokens,
+		CacheReadTokens:  e.CacheReadTokens,
+		CacheWriteTokens: e.CacheWriteTokens,
+	}
+}
+
+// add returns u plus the tokens of e
+func add(u providers.Usage, e Event) providers.Usage {
+	u.PromptTokens += e.PromptTokens
+	u.CompletionTokens += e.CompletionTokens
+	u.CacheReadTokens += e.CacheReadTokens
+	u.CacheWriteTokens += e.CacheWriteTokens
+	return u
+}
+
+// Day is the usage of a single calendar day
+type Day struct {
+	Date     time.Time
+	Requests int
+	Tokens   int
+	Cost     float64
+}
+
+// Daily buckets the requests of the last days days up to and including now, in local
+// time. Days without requests are included so the result always has days entries.
+func Daily(events []Event, days int, now time.Time) []Day {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	out := make([]Day, days)
+	for i := range out {
+		out[i].Date = today.AddDate(0, 0, i-days+1)
+	}
+	for _, e := range events {
+		if e.Model == "" {
+			continue
+		}
+		t := e.Time.In(now.Location())
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+		i := days - 1 - int(today.Sub(date).Hours()/24+0.5)
+		if i < 0 || i >= days {
+			continue
+		}
+		out[i].Requests++
+		out[i].Tokens += e.Usage().TotalTokens()
+		out[i].Cost += e.Cost
+	}
+	return out
+}
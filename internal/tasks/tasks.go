@@ -0,0 +1,159 @@
+// Package tasks is the checklist for the current objective, kept by the agent with the
+// update_tasks tool and by the user with /todo, and shown above the input
+package tasks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Status is the state of a task
+type Status string
+
+const (
+	Pending    Status = "pending"
+	InProgress Status = "in_progress"
+	Done       Status = "done"
+)
+
+// Statuses lists the valid task statuses
+var Statuses = []Status{Pending, InProgress, Done}
+
+// MaxTasks bounds the length of the checklist
+const MaxTasks = 50
+
+// Task is an item of the checklist
+type Task struct {
+	Title  string `json:"title"`
+	Status Status `json:"status"`
+}
+
+var (
+	mu   sync.Mutex
+	list []Task
+)
+
+// ParseStatus returns the status named s, accepting "in-progress" and "todo" as well
+func ParseStatus(s string) (Status, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "pending", "todo":
+		return Pending, nil
+	case "in_progress", "in-progress", "in progress", "active":
+		return InProgress, nil
+	case "done", "completed", "complete":
+		return Done, nil
+	}
+	return "", fmt.Errorf("unknown status %q; use pending, in_progress or done", s)
+}
+
+// List returns the checklist in order
+func List() []Task {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Task(nil), list...)
+}
+
+// Set replaces the checklist
+func Set(tasks []Task) error {
+	if len(tasks) > MaxTasks {
+		return fmt.Errorf("the checklist holds at most %d tasks", MaxTasks)
+	}
+	for i, t := range tasks {
+		if strings.TrimSpace(t.Title) == "" {
+			return fmt.Errorf("task %d has no title", i+1)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	list = append([]Task(nil), tasks...)
+	return nil
+}
+
+// Add appends a pending task and returns its number
+func Add(title string) (int, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return 0, fmt.Errorf("the task has no title")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(list) >= MaxTasks {
+		return 0, fmt.Errorf("the checklist holds at most %d tasks", MaxTasks)
+	}
+	list = append(list, Task{Title: title, Status: Pending})
+	return len(list), nil
+}
+
+// SetStatus changes the status of task n, counted from 1
+func SetStatus(n int, status Status) (Task, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if n < 1 || n > len(list) {
+		return Task{}, outOfRange(n)
+	}
+	list[n-1].Status = status
+	return list[n-1], nil
+}
+
+// Remove deletes task n, counted from 1
+func Remove(n int) (Task, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if n < 1 || n > len(list) {
+		return Task{}, outOfRange(n)
+	}
+	t := list[n-1]
+	list = append(list[:n-1], list[n:]...)
+	return t, nil
+}
+
+// Clear empties the checklist
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	list = nil
+}
+
+// outOfRange reports a task number that is not on the checklist; mu must be held
+func outOfRange(n int) error {
+	if len(list) == 0 {
+		return fmt.Errorf("the checklist is empty")
+	}
+	return fmt.Errorf("no task %d; the checklist has tasks 1 to %d", n, len(list))
+}
+
+// Counts returns how many tasks are done and how many there are
+func Counts() (done, total int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, t := range list {
+		if t.Status == Done {
+			done++
+		}
+	}
+	return done, len(list)
+}
+
+// Mark returns the checkbox a status is shown with
+func (s Status) Mark() string {
+	switch s {
+	case Done:
+		return "[x]"
+	case InProgress:
+		return "[~]"
+	}
+	return "[ ]"
+}
+
+// Format renders the checklist as numbered lines of text, e.g. for the model
+func Format(tasks []Task) string {
+	if len(tasks) == 0 {
+		return "The checklist is empty"
+	}
+	var b strings.Builder
+	for i, t := range tasks {
+		fmt.Fprintf(&b, "%d. %s %s\n", i+1, t.Status.Mark(), t.Title)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
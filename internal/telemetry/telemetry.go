@@ -0,0 +1,113 @@
+// Package telemetry exports OpenTelemetry traces of agent runs: a span for each turn,
+// each provider request and each tool call, so that time and tokens can be profiled in
+// any OTLP backend (Jaeger, Honeycomb, Grafana Tempo, ...). Prompts and replies are
+// never exported, only their sizes.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation in exported spans
+const tracerName = "github.com/pprunty/magikarp"
+
+// tracesPath is where OTLP/HTTP receivers accept traces
+const tracesPath = "/v1/traces"
+
+// shutdownTimeout bounds how long exiting waits for the last spans to be exported
+const shutdownTimeout = 5 * time.Second
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+)
+
+// Start exports traces to the OTLP/HTTP receiver configured in conf, or named by the
+// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables. Without
+// either, tracing stays off and spans cost next to nothing. Call Shutdown before exiting.
+func Start(conf config.TelemetryConfig) error {
+	if conf.Endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return nil
+	}
+
+	// Unset options fall back to the standard OTEL_EXPORTER_OTLP_* variables
+	var opts []otlptracehttp.Option
+	if conf.Endpoint != "" {
+		endpoint := strings.TrimSuffix(conf.Endpoint, "/")
+		if !strings.HasSuffix(endpoint, tracesPath) {
+			endpoint += tracesPath
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	if len(conf.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(conf.Headers))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return fmt.Errorf("telemetry: %w", err)
+	}
+
+	name := conf.ServiceName
+	if name == "" {
+		name = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if name == "" {
+		name = "magikarp"
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", name))),
+	)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if provider != nil {
+		_ = provider.Shutdown(context.Background())
+	}
+	provider = tp
+	otel.SetTracerProvider(tp)
+	// Export failures must not end up in the terminal UI
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+	return nil
+}
+
+// Shutdown exports the spans still buffered and stops tracing
+func Shutdown() {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = provider.Shutdown(ctx)
+	provider = nil
+}
+
+// Tracer returns the tracer spans of magikarp are started with
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// End records err, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,154 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DirName is the templates directory inside ~/.magikarp and a project's .magikarp
+const DirName = "templates"
+
+// Extension is the file extension of template files
+const Extension = ".md"
+
+// variableRe matches {{name}} placeholders; whitespace inside the braces is allowed
+var variableRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+
+// Template is a reusable prompt with {{variable}} placeholders
+type Template struct {
+	// Name is the file name without its extension
+	Name        string
+	Description string
+	Body        string
+	Path        string
+	// Variables lists the placeholders in the order they first appear in Body
+	Variables []string
+	// Defaults holds values offered for variables before the user types their own
+	Defaults map[string]string
+}
+
+// frontMatter is the optional YAML header of a template file
+type frontMatter struct {
+	Description string            `yaml:"description"`
+	Defaults    map[string]string `yaml:"defaults"`
+}
+
+// Dirs returns the template directories searched, user first: ~/.magikarp/templates and
+// the nearest .magikarp/templates found from the working directory upwards. Templates in
+// later directories replace earlier ones with the same name.
+func Dirs() []string {
+	var dirs []string
+	home, _ := os.UserHomeDir()
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".magikarp", DirName))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if dir := findProjectDir(wd); dir != "" && (len(dirs) == 0 || dir != dirs[0]) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// findProjectDir searches dir and its parents for .magikarp/templates
+func findProjectDir(dir string) string {
+	for {
+		path := filepath.Join(dir, ".magikarp", DirName)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the templates in dirs, sorted by name. Missing directories are skipped.
+func Load(dirs ...string) ([]Template, error) {
+	byName := map[string]Template{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read templates: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != Extension {
+				continue
+			}
+			t, err := Parse(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			byName[t.Name] = t
+		}
+	}
+
+	list := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Parse reads a single template file
+func Parse(path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	t := Template{
+		Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path: path,
+	}
+	body := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		if header, after, found := strings.Cut(rest, "\n---\n"); found {
+			var fm frontMatter
+			if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+				return Template{}, fmt.Errorf("failed to parse template %s: %w", filepath.Base(path), err)
+			}
+			t.Description, t.Defaults = fm.Description, fm.Defaults
+			body = after
+		}
+	}
+	t.Body = strings.TrimSpace(body)
+
+	seen := map[string]bool{}
+	for _, match := range variableRe.FindAllStringSubmatch(t.Body, -1) {
+		if name := match[1]; !seen[name] {
+			seen[name] = true
+			t.Variables = append(t.Variables, name)
+		}
+	}
+	if t.Description == "" {
+		// Fall back to the first line of the prompt
+		line, _, _ := strings.Cut(t.Body, "\n")
+		t.Description = strings.TrimSpace(line)
+	}
+	return t, nil
+}
+
+// Render replaces the placeholders with values, falling back to the defaults.
+// Placeholders without a value are left empty.
+func (t Template) Render(values map[string]string) string {
+	return variableRe.ReplaceAllStringFunc(t.Body, func(match string) string {
+		name := variableRe.FindStringSubmatch(match)[1]
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return t.Defaults[name]
+	})
+}
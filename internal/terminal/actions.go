@@ -0,0 +1,143 @@
+package terminal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// busyReply is shown when a message action is used while a response is running
+const busyReply = "System: Wait for the current response, or press Esc to cancel it"
+
+// isExchange reports whether pair is a message to the model rather than a slash command
+func isExchange(pair ConversationPair) bool {
+	return !strings.HasPrefix(pair.UserMessage, "/")
+}
+
+// exchangeIndex returns the conversation index of message n, counting only messages
+// sent to the model from 1; n of 0 is the last one
+func (m InputModel) exchangeIndex(n int) (int, error) {
+	var indices []int
+	for i, pair := range m.conversation {
+		if isExchange(pair) {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return 0, fmt.Errorf("there are no messages yet")
+	}
+	if n == 0 {
+		return indices[len(indices)-1], nil
+	}
+	if n < 0 || n > len(indices) {
+		return 0, fmt.Errorf("message numbers go from 1 to %d", len(indices))
+	}
+	return indices[n-1], nil
+}
+
+// parseMessageNumber reads the optional message number of a command, e.g. "/edit 3"
+func parseMessageNumber(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a message number", args[0])
+	}
+	return n, nil
+}
+
+// forgetSummary drops the summary of compressed exchanges when the exchange at index i is
+// part of it, so that a removed message does not linger in the model's context
+func (m *InputModel) forgetSummary(i int) {
+	if i < m.summaryUpTo {
+		m.contextSummary = ""
+		m.summaryUpTo = 0
+	}
+}
+
+// truncateConversation removes the exchange at index i and everything after it
+func (m *InputModel) truncateConversation(i int) {
+	if i < 0 || i > len(m.conversation) {
+		return
+	}
+	m.forgetSummary(i)
+	m.conversation = m.conversation[:i]
+	m.expandedTools = nil
+}
+
+// runRetryCommand answers the last message again, with model when one is given
+// (/retry [model]). The previous answer is replaced.
+func (m *InputModel) runRetryCommand(args []string) (string, tea.Cmd) {
+	if m.turnRunning() {
+		return busyReply, nil
+	}
+	i, err := m.exchangeIndex(0)
+	if err != nil {
+		return "System: Nothing to retry: " + err.Error(), nil
+	}
+	if len(args) > 0 {
+		if !modelAvailable(args[0]) {
+			return fmt.Sprintf("System: Unknown model %s; see /model for the available ones", args[0]), nil
+		}
+		m.provider = args[0]
+	}
+	userMessage := m.conversation[i].UserMessage
+	m.conversation = append(m.conversation[:i], m.conversation[i+1:]...)
+	m.forgetSummary(i)
+	m.expandedTools = nil
+	return "", m.send(userMessage)
+}
+
+// runEditCommand loads message n (/edit <n>, the last by default) into the input. Sending
+// it replaces that exchange and the ones after it with the edited message's answer.
+func (m *InputModel) runEditCommand(args []string) string {
+	if m.turnRunning() {
+		return busyReply
+	}
+	n, err := parseMessageNumber(args)
+	if err != nil {
+		return "System: Usage: /edit [message number]: " + err.Error()
+	}
+	i, err := m.exchangeIndex(n)
+	if err != nil {
+		return "System: Nothing to edit: " + err.Error()
+	}
+	m.editing, m.editIndex = true, i
+	m.textInput.SetValue(m.conversation[i].UserMessage)
+	m.textInput.EnterInsertMode()
+	return ""
+}
+
+// cancelEdit leaves the message being edited as it was and clears the input
+func (m *InputModel) cancelEdit() {
+	m.editing = false
+	m.textInput.SetValue("")
+}
+
+// runDeleteCommand removes message n and its answer from the conversation (/delete <n>,
+// the last by default), so they are no longer sent to the model
+func (m *InputModel) runDeleteCommand(args []string) string {
+	if m.turnRunning() {
+		return busyReply
+	}
+	n, err := parseMessageNumber(args)
+	if err != nil {
+		return "System: Usage: /delete [message number]: " + err.Error()
+	}
+	i, err := m.exchangeIndex(n)
+	if err != nil {
+		return "System: Nothing to delete: " + err.Error()
+	}
+	deleted := m.conversation[i].UserMessage
+	m.conversation = append(m.conversation[:i], m.conversation[i+1:]...)
+	m.forgetSummary(i)
+	m.expandedTools = nil
+	if m.editing && m.editIndex >= i {
+		m.cancelEdit()
+	}
+	m.autoSaveSession()
+	return fmt.Sprintf("System: Deleted %q and its answer from the conversation", truncateCell(strings.Join(strings.Fields(deleted), " "), 60))
+}
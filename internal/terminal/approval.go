@@ -0,0 +1,152 @@
+package terminal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/pprunty/magikarp/internal/permissions"
+)
+
+// approvalDecision is the user's answer to a tool approval prompt
+type approvalDecision int
+
+const (
+	approvalDeny approvalDecision = iota
+	approvalAllow
+	approvalAlways
+)
+
+// toolApprovalMsg asks the UI to confirm a tool call before it executes.
+// The running turn blocks until a decision is sent on reply.
+type toolApprovalMsg struct {
+	toolName string
+	params   string
+	warning  string // why a risky call needs approval
+	reply    chan<- approvalDecision
+	events   <-chan tea.Msg
+}
+
+// sessionApproved holds tools the user chose to "always" allow for this session
+var (
+	sessionApprovedMu sync.RWMutex
+	sessionApproved   = map[string]bool{}
+)
+
+// isToolAutoApproved reports whether the user already allowed a tool for this session.
+// The config allowlist is applied by the permission policy before approval is asked.
+func isToolAutoApproved(name string) bool {
+	sessionApprovedMu.RLock()
+	defer sessionApprovedMu.RUnlock()
+	return sessionApproved[name]
+}
+
+// approveToolCall blocks until the user approves or denies the tool call, or the
+// request is cancelled. Tools the user always allowed this session are approved immediately.
+func approveToolCall(ctx context.Context, events chan tea.Msg, toolName string, input map[string]interface{}) bool {
+	// Risky shell scripts are confirmed every time, even for tools allowed this session
+	shell := globalPolicy.CheckShell(toolName, input)
+	if isToolAutoApproved(toolName) && shell.Risk == permissions.Safe {
+		return true
+	}
+
+	params := ""
+	if len(input) > 0 {
+		if b, err := json.MarshalIndent(input, "", "  "); err == nil {
+			params = string(b)
+		}
+	}
+
+	reply := make(chan approvalDecision, 1)
+	sent := sendTurnEvent(ctx, events, toolApprovalMsg{
+		toolName: toolName,
+		params:   params,
+		warning:  strings.Join(shell.Reasons, "; "),
+		reply:    reply,
+		events:   events,
+	})
+	if !sent {
+		return false
+	}
+
+	var decision approvalDecision
+	select {
+	case decision = <-reply:
+	case <-ctx.Done():
+		return false
+	}
+	switch decision {
+	case approvalAlways:
+		sessionApprovedMu.Lock()
+		sessionApproved[toolName] = true
+		sessionApprovedMu.Unlock()
+		return true
+	case approvalAllow:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForTurnEvent blocks until the running turn emits its next event
+func waitForTurnEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// handleApprovalKey resolves the pending approval prompt from a key press
+func (m InputModel) handleApprovalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var decision approvalDecision
+	switch msg.String() {
+	case "y", "Y", "enter":
+		decision = approvalAllow
+	case "a", "A":
+		decision = approvalAlways
+	case "n", "N", "esc", "ctrl+c":
+		decision = approvalDeny
+	default:
+		return m, nil
+	}
+
+	pending := m.pendingApproval
+	m.pendingApproval = nil
+	pending.reply <- decision
+	return m, waitForTurnEvent(pending.events)
+}
+
+// renderApprovalPrompt renders the tool approval prompt shown above the input box
+func renderApprovalPrompt(req *toolApprovalMsg, width int) string {
+	s := approvalTitleStyle.Render(fmt.Sprintf("Allow tool %s to run?", req.toolName)) + "\n"
+	if req.warning != "" {
+		s += approvalTitleStyle.Render("⚠ "+wrapText(req.warning, width-6)) + "\n"
+	}
+	if req.params != "" {
+		params := req.params
+		if len(params) > maxToolOutputChars {
+			params = params[:maxToolOutputChars] + "\n... (parameters truncated)"
+		}
+		s += approvalParamsStyle.Render(wrapText(params, width-6)) + "\n"
+	}
+	s += helpStyle.Render("y: allow • n: deny • a: always allow this tool")
+	return s + "\n"
+}
+
+// Tool approval styles
+var (
+	approvalTitleStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF6B35")).
+				Bold(true)
+
+	approvalParamsStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#626262"))
+)
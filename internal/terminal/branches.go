@@ -0,0 +1,219 @@
+package terminal
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/session"
+)
+
+// mainBranch names the conversation a first /fork branches off from
+const mainBranch = "main"
+
+// conversationBranch is a line of the conversation kept by /fork. The active branch
+// lives in the InputModel itself; the others are parked here until switched to.
+type conversationBranch struct {
+	name           string
+	conversation   []ConversationPair
+	contextSummary string
+	summaryUpTo    int
+	session        *session.Session
+}
+
+// branchPicker is the state of the /branches picker
+type branchPicker struct {
+	cursor int
+}
+
+// runForkCommand handles "/fork [name]": it copies the conversation into a new branch
+// and switches to it, keeping the current branch to come back to with /branches
+func (m *InputModel) runForkCommand(args []string) string {
+	if m.turnRunning() {
+		return busyReply
+	}
+	name := strings.Join(args, " ")
+	for n := max(len(m.branches), 1); name == ""; n++ {
+		if m.checkBranchName(fmt.Sprintf("branch-%d", n)) == nil {
+			name = fmt.Sprintf("branch-%d", n)
+		}
+	}
+	if err := m.checkBranchName(name); err != nil {
+		return "System: " + err.Error()
+	}
+
+	if len(m.branches) == 0 {
+		m.branches = []*conversationBranch{{name: mainBranch}}
+		m.activeBranch = 0
+	}
+	m.autoSaveSession()
+	m.parkBranch()
+
+	from := m.branches[m.activeBranch]
+	fork := &conversationBranch{
+		name:           name,
+		conversation:   cloneConversation(from.conversation),
+		contextSummary: from.contextSummary,
+		summaryUpTo:    from.summaryUpTo,
+		session:        session.New(m.provider),
+	}
+	if from.session != nil {
+		fork.session.Parent = from.session.ID
+	}
+	fork.session.Branch = name
+	m.branches = append(m.branches, fork)
+	m.loadBranch(len(m.branches) - 1)
+	return fmt.Sprintf("System: Forked %s into branch %s. Use /branches to switch back.", from.name, name)
+}
+
+// runBranchesCommand handles "/branches [n|name]": without an argument it opens the
+// picker, otherwise it switches to the branch. It returns a system reply, or "" when
+// the picker was opened.
+func (m *InputModel) runBranchesCommand(args []string) string {
+	if len(m.branches) == 0 {
+		return "System: There is only one branch. Use /fork [name] to branch off the conversation."
+	}
+	if len(args) == 0 {
+		m.branchPicker = &branchPicker{cursor: m.activeBranch}
+		return ""
+	}
+	if m.turnRunning() {
+		return busyReply
+	}
+	i, err := m.findBranch(strings.Join(args, " "))
+	if err != nil {
+		return "System: " + err.Error()
+	}
+	return m.switchBranch(i)
+}
+
+// findBranch returns the index of the branch named or numbered by arg
+func (m *InputModel) findBranch(arg string) (int, error) {
+	for i, b := range m.branches {
+		if b.name == arg {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(arg, "#")); err == nil && n >= 1 && n <= len(m.branches) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("no branch %q; /branches lists them", arg)
+}
+
+// checkBranchName rejects names that are taken or would read as a branch number
+func (m *InputModel) checkBranchName(name string) error {
+	if _, err := strconv.Atoi(strings.TrimPrefix(name, "#")); err == nil {
+		return fmt.Errorf("branch names must not be numbers")
+	}
+	if name == mainBranch && len(m.branches) == 0 {
+		return fmt.Errorf("branch %s already exists", name)
+	}
+	for _, b := range m.branches {
+		if b.name == name {
+			return fmt.Errorf("branch %s already exists", name)
+		}
+	}
+	return nil
+}
+
+// switchBranch saves the active branch and continues the conversation of branch i
+func (m *InputModel) switchBranch(i int) string {
+	if i == m.activeBranch {
+		return "System: Already on branch " + m.branches[i].name
+	}
+	m.autoSaveSession()
+	m.parkBranch()
+	m.loadBranch(i)
+	b := m.branches[i]
+	return fmt.Sprintf("System: Switched to branch %s (%d messages)", b.name, len(b.conversation))
+}
+
+// parkBranch stores the live conversation in the active branch
+func (m *InputModel) parkBranch() {
+	b := m.branches[m.activeBranch]
+	b.conversation = m.conversation
+	b.contextSummary = m.contextSummary
+	b.summaryUpTo = m.summaryUpTo
+	b.session = m.session
+}
+
+// loadBranch makes branch i the live conversation
+func (m *InputModel) loadBranch(i int) {
+	b := m.branches[i]
+	m.activeBranch = i
+	m.conversation = b.conversation
+	m.contextSummary = b.contextSummary
+	m.summaryUpTo = b.summaryUpTo
+	m.session = b.session
+	m.expandedTools = nil
+	m.editing = false
+	m.transcript.scrolledUp = false
+}
+
+// branchName returns the name of the active branch; "" before the first /fork
+func (m InputModel) branchName() string {
+	if len(m.branches) == 0 {
+		return ""
+	}
+	return m.branches[m.activeBranch].name
+}
+
+// cloneConversation copies a conversation so that a fork and its origin can grow
+// apart without sharing tool calls
+func cloneConversation(conversation []ConversationPair) []ConversationPair {
+	out := make([]ConversationPair, len(conversation))
+	for i, pair := range conversation {
+		pair.ToolCalls = slices.Clone(pair.ToolCalls)
+		pair.Progress = slices.Clone(pair.Progress)
+		out[i] = pair
+	}
+	return out
+}
+
+// handleBranchPickerKey handles keys while the /branches picker is open
+func (m InputModel) handleBranchPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.branchPicker
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.branchPicker = nil
+	case "up", "ctrl+p":
+		picker.cursor = (picker.cursor - 1 + len(m.branches)) % len(m.branches)
+	case "down", "ctrl+n":
+		picker.cursor = (picker.cursor + 1) % len(m.branches)
+	case "enter":
+		m.branchPicker = nil
+		if m.turnRunning() {
+			m.AddConversationPair("/branches", busyReply)
+			return m, nil
+		}
+		if picker.cursor != m.activeBranch {
+			reply := m.switchBranch(picker.cursor)
+			m.AddConversationPair("/branches", reply)
+		}
+	}
+	return m, nil
+}
+
+// renderBranchPicker lists the branches with the number of messages in each
+func (m InputModel) renderBranchPicker() string {
+	s := "\n" + slashCommandActiveStyle.Render("  Branches:") + "\n"
+	for i, b := range m.branches {
+		messages := len(b.conversation)
+		if i == m.activeBranch {
+			messages = len(m.conversation)
+		}
+		name := fmt.Sprintf("%d. %s", i+1, b.name)
+		desc := fmt.Sprintf("%d messages", messages)
+		if i == m.activeBranch {
+			desc += " (current)"
+		}
+		if i == m.branchPicker.cursor {
+			s += formatSlashCommand(slashCommandActiveStyle.Render(name), slashCommandActiveStyle.Render(desc)) + "\n"
+		} else {
+			s += formatSlashCommand(slashCommandNormalStyle.Render(name), slashCommandNormalStyle.Render(desc)) + "\n"
+		}
+	}
+	return s + "\n"
+}
@@ -0,0 +1,73 @@
+package terminal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cancelledNote marks a response that was cut short with Esc
+const cancelledNote = "[Request cancelled]"
+
+// beginTurn creates the context of a new request; Esc cancels it until endTurn
+func (m *InputModel) beginTurn() context.Context {
+	m.endTurn()
+	m.turnCtx, m.cancelTurn = context.WithCancel(context.Background())
+	m.turnStarted = time.Now()
+	return m.turnCtx
+}
+
+// endTurn releases the context of a finished request
+func (m *InputModel) endTurn() {
+	if m.cancelTurn != nil {
+		m.cancelTurn()
+	}
+	m.turnCtx, m.cancelTurn = nil, nil
+	m.finishPendingReload()
+}
+
+// turnRunning reports whether a request is in flight. Events that arrive when no
+// request is running belong to a cancelled one and are dropped.
+func (m InputModel) turnRunning() bool {
+	return m.cancelTurn != nil
+}
+
+// cancelRequest aborts the running model call or tool execution and returns control
+// to the input. Text streamed so far is kept, marked as cancelled.
+func (m *InputModel) cancelRequest() {
+	m.endTurn()
+	SetApprovedPlan("")
+	stopLiveOutput()
+	silenceVoice()
+	m.pendingApproval = nil
+	m.pendingReview = nil
+	m.reviewError = ""
+
+	if len(m.conversation) == 0 {
+		return
+	}
+	last := &m.conversation[len(m.conversation)-1]
+	last.IsProcessing = false
+	last.Cancelled = true
+	last.IsError = true // an unfinished answer is not sent back as history
+	last.Status = ""
+	if partial := strings.TrimSpace(last.AIResponse); partial != "" {
+		last.AIResponse = partial + "\n\n" + cancelledNote
+	} else {
+		last.AIResponse = cancelledNote
+	}
+	m.autoSaveSession()
+}
+
+// sendTurnEvent delivers msg to the UI unless the request has been cancelled, so a
+// cancelled turn never blocks on an event nobody reads
+func sendTurnEvent(ctx context.Context, events chan<- tea.Msg, msg tea.Msg) bool {
+	select {
+	case events <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
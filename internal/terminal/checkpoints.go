@@ -0,0 +1,115 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/checkpoint"
+)
+
+// maxListedFiles is how many restored or removed files a rollback names
+const maxListedFiles = 10
+
+// autoCheckpoint snapshots the working tree before the agent changes it for message and
+// returns a progress line, or an empty string when nothing was saved
+func autoCheckpoint(ctx context.Context, message string) string {
+	repo, err := checkpoint.Open(ctx, ".")
+	if err != nil {
+		return "" // not a git repository
+	}
+	label := "before: " + truncateCell(strings.Join(strings.Fields(message), " "), 60)
+	cp, created, err := repo.Create(ctx, label, true)
+	if err != nil {
+		inputDebugLog("Checkpoint failed: %v", err)
+		return ""
+	}
+	if !created {
+		return ""
+	}
+	return fmt.Sprintf("Saved checkpoint #%d (/rollback reverts this run)", cp.ID)
+}
+
+// runCheckpointCommand saves a checkpoint of the working tree (/checkpoint [label]) or
+// lists the checkpoints (/checkpoint list)
+func runCheckpointCommand(args []string) string {
+	ctx := context.Background()
+	repo, err := checkpoint.Open(ctx, ".")
+	if err != nil {
+		return "System: " + err.Error()
+	}
+
+	if len(args) == 1 && args[0] == "list" {
+		list, err := repo.List(ctx)
+		if err != nil {
+			return "System: Cannot list checkpoints: " + err.Error()
+		}
+		if len(list) == 0 {
+			return "System: No checkpoints yet"
+		}
+		lines := []string{"System: Checkpoints, newest first:"}
+		for i := len(list) - 1; i >= 0; i-- {
+			cp := list[i]
+			lines = append(lines, fmt.Sprintf("  #%d %s %s", cp.ID, cp.Time.Format("Jan 2 15:04:05"), cp.Label))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	label := strings.Join(args, " ")
+	if label == "" {
+		label = "manual checkpoint"
+	}
+	cp, _, err := repo.Create(ctx, label, false)
+	if err != nil {
+		return "System: Cannot save checkpoint: " + err.Error()
+	}
+	return fmt.Sprintf("System: Saved checkpoint #%d (%s); /rollback %d restores it", cp.ID, cp.Label, cp.ID)
+}
+
+// runRollbackCommand restores the working tree to a checkpoint (/rollback [n], the
+// latest by default)
+func (m *InputModel) runRollbackCommand(args []string) string {
+	if m.turnRunning() {
+		return busyReply
+	}
+	id := 0
+	if len(args) > 0 {
+		var err error
+		if id, err = strconv.Atoi(strings.TrimPrefix(args[0], "#")); err != nil || id < 1 {
+			return "System: Usage: /rollback [checkpoint number]; /checkpoint list shows them"
+		}
+	}
+
+	ctx := context.Background()
+	repo, err := checkpoint.Open(ctx, ".")
+	if err != nil {
+		return "System: " + err.Error()
+	}
+	rb, err := repo.Rollback(ctx, id)
+	if err != nil {
+		return "System: Cannot roll back: " + err.Error()
+	}
+	if len(rb.Restored) == 0 && len(rb.Removed) == 0 {
+		return fmt.Sprintf("System: The working tree already matches checkpoint #%d", rb.Checkpoint.ID)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "System: Rolled back to checkpoint #%d (%s)", rb.Checkpoint.ID, rb.Checkpoint.Label)
+	if len(rb.Restored) > 0 {
+		fmt.Fprintf(&b, "\n  restored: %s", listFiles(rb.Restored))
+	}
+	if len(rb.Removed) > 0 {
+		fmt.Fprintf(&b, "\n  removed: %s", listFiles(rb.Removed))
+	}
+	fmt.Fprintf(&b, "\n/rollback %d undoes this rollback", rb.Backup.ID)
+	return b.String()
+}
+
+// listFiles joins paths, naming at most maxListedFiles of them
+func listFiles(paths []string) string {
+	if len(paths) <= maxListedFiles {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:maxListedFiles], ", "), len(paths)-maxListedFiles)
+}
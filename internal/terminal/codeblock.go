@@ -0,0 +1,203 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// codeBlock is a fenced code block in a response
+type codeBlock struct {
+	Lang string
+	Code string
+	// Closed is false while the closing fence has not been streamed yet
+	Closed bool
+}
+
+// responseSegment is a run of prose or a single code block of a response
+type responseSegment struct {
+	prose string
+	block *codeBlock
+}
+
+// splitResponse splits text into prose and fenced code blocks, in order
+func splitResponse(text string) []responseSegment {
+	var segments []responseSegment
+	var prose, code []string
+	var fence, lang string
+
+	flushProse := func() {
+		if len(prose) > 0 {
+			segments = append(segments, responseSegment{prose: strings.Join(prose, "\n")})
+			prose = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && strings.HasPrefix(trimmed, "```"):
+			flushProse()
+			rest := strings.TrimLeft(trimmed, "`")
+			fence = trimmed[:len(trimmed)-len(rest)]
+			lang = strings.TrimSpace(rest)
+		case fence != "" && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, "`") == "":
+			segments = append(segments, responseSegment{block: &codeBlock{Lang: lang, Code: strings.Join(code, "\n"), Closed: true}})
+			fence, code = "", nil
+		case fence != "":
+			code = append(code, line)
+		default:
+			prose = append(prose, line)
+		}
+	}
+	if fence != "" {
+		segments = append(segments, responseSegment{block: &codeBlock{Lang: lang, Code: strings.Join(code, "\n")}})
+	}
+	flushProse()
+	return segments
+}
+
+// codeBlocks returns every code block in the conversation, numbered from 1 in order
+func (m InputModel) codeBlocks() []codeBlock {
+	var blocks []codeBlock
+	for _, pair := range m.conversation {
+		for _, seg := range splitResponse(pair.AIResponse) {
+			if seg.block != nil {
+				blocks = append(blocks, *seg.block)
+			}
+		}
+	}
+	return blocks
+}
+
+// renderResponse renders an AI response with its code blocks highlighted and labelled.
+// Blocks are numbered from *blockNum+1, and *blockNum is advanced past them.
+func renderResponse(text string, width int, blockNum *int) string {
+	var b strings.Builder
+	prefix := "⏺ "
+	for _, seg := range splitResponse(text) {
+		if seg.block == nil {
+			prose := strings.Trim(seg.prose, "\n")
+			if strings.TrimSpace(prose) == "" {
+				continue
+			}
+			b.WriteString(aiResponseStyle.Render(prefix+wrapText(prose, width)) + "\n")
+			prefix = ""
+			continue
+		}
+
+		*blockNum++
+		if prefix != "" {
+			b.WriteString(aiResponseStyle.Render(strings.TrimSpace(prefix)) + "\n")
+			prefix = ""
+		}
+		label := fmt.Sprintf("[%d]", *blockNum)
+		if seg.block.Lang != "" {
+			label += " " + seg.block.Lang
+		}
+		b.WriteString(codeLabelStyle.Render("  "+label) + codeHintStyle.Render(fmt.Sprintf(" · /copy %d", *blockNum)) + "\n")
+		for _, line := range strings.Split(highlightCode(*seg.block), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// highlightCache holds highlighted blocks that are complete, since View runs on every key
+var highlightCache = map[codeBlock]string{}
+
+// maxHighlightCache bounds highlightCache; it is cleared when full
+const maxHighlightCache = 256
+
+// highlightCode returns the block's code with terminal syntax highlighting,
+// guessing the language when the fence does not name one
+func highlightCode(block codeBlock) string {
+	code := strings.ReplaceAll(strings.TrimRight(block.Code, "\n"), "\t", "    ")
+	if disableBeautify {
+		return code
+	}
+	if cached, ok := highlightCache[block]; ok {
+		return cached
+	}
+
+	lexer := lexers.Get(block.Lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	// Format line by line so colours never carry over into the indentation
+	var lines []string
+	for _, tokens := range chroma.SplitTokensIntoLines(iterator.Tokens()) {
+		if last := len(tokens) - 1; last >= 0 {
+			tokens[last].Value = strings.TrimSuffix(tokens[last].Value, "\n")
+		}
+		var out bytes.Buffer
+		if err := formatters.TTY256.Format(&out, styles.Get("monokai"), chroma.Literator(tokens...)); err != nil {
+			return code
+		}
+		lines = append(lines, out.String())
+	}
+	highlighted := strings.Join(lines, "\n")
+
+	if block.Closed {
+		if len(highlightCache) >= maxHighlightCache {
+			highlightCache = map[codeBlock]string{}
+		}
+		highlightCache[block] = highlighted
+	}
+	return highlighted
+}
+
+// runCopyCommand handles "/copy [n]", copying code block n (the last one by default)
+// to the clipboard, and returns the system reply
+func (m *InputModel) runCopyCommand(args []string) string {
+	blocks := m.codeBlocks()
+	if len(blocks) == 0 {
+		return "System: No code blocks to copy yet"
+	}
+
+	n := len(blocks)
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil || parsed < 1 || parsed > len(blocks) {
+			return fmt.Sprintf("System: Usage: /copy [n], where n is a code block from 1 to %d", len(blocks))
+		}
+		n = parsed
+	}
+
+	copyToClipboard(blocks[n-1].Code)
+	lines := strings.Count(strings.TrimRight(blocks[n-1].Code, "\n"), "\n") + 1
+	return fmt.Sprintf("System: Copied code block %d (%d lines) to the clipboard", n, lines)
+}
+
+// copyToClipboard writes text to the system clipboard. Without a clipboard utility
+// (e.g. over SSH) it falls back to the terminal's OSC 52 clipboard sequence.
+func copyToClipboard(text string) {
+	if clipboard.Unsupported || clipboard.WriteAll(text) != nil {
+		termenv.Copy(text)
+	}
+}
+
+var (
+	codeLabelStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#7D56F4")).
+			Bold(true)
+
+	codeHintStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262"))
+)
@@ -0,0 +1,240 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+const (
+	// commitRecentSubjects is how many recent commit subjects show the model the
+	// conventions of the repository
+	commitRecentSubjects = 10
+	// commitPreviewLines bounds the lines of the message shown in the commit prompt
+	commitPreviewLines = 12
+)
+
+// pendingCommit is a generated commit message waiting for the user to commit, edit or
+// discard it
+type pendingCommit struct {
+	message string
+	signoff bool
+}
+
+// commitMessageMsg carries the message written for the staged changes
+type commitMessageMsg struct {
+	message string
+	// stat summarises the staged changes, e.g. "3 files changed, 20 insertions(+)"
+	stat string
+	err  error
+}
+
+// commitEditedMsg is sent when the editor opened from the commit prompt exits
+type commitEditedMsg struct {
+	path string
+	err  error
+}
+
+// commitDoneMsg carries the commit created by /commit, or why git refused it
+type commitDoneMsg struct {
+	summary string
+	err     error
+}
+
+// runCommitCommand handles "/commit [hint]": the current model writes a message for
+// git diff --staged, which is shown for approval before committing. The reply is set
+// when the command cannot run.
+func (m *InputModel) runCommitCommand(args []string) (string, tea.Cmd) {
+	if m.turnRunning() {
+		return busyReply, nil
+	}
+	ctx := context.Background()
+	diff, err := gitexec.Run(ctx, "", "diff", "--staged", "--no-color", "--no-ext-diff")
+	if err != nil {
+		return "System: /commit needs a git repository: " + err.Error(), nil
+	}
+	if strings.TrimSpace(diff) == "" {
+		return "System: Nothing is staged; stage changes with git add first", nil
+	}
+	stat, _ := gitexec.Run(ctx, "", "diff", "--staged", "--no-color", "--stat")
+	// A repository without commits has no log
+	log, _ := gitexec.Run(ctx, "", "log", "-n", fmt.Sprint(commitRecentSubjects), "--format=%s")
+
+	req := orchestration.CommitRequest{
+		Diff: diff,
+		Stat: stat,
+		Hint: strings.Join(args, " "),
+	}
+	if log = strings.TrimSpace(log); log != "" {
+		req.Recent = strings.Split(log, "\n")
+	}
+	if globalConfig != nil {
+		req.Style = globalConfig.Commit.Style
+	}
+
+	model := m.provider
+	m.AddConversationPair(strings.TrimSpace("/commit "+req.Hint), "")
+	m.conversation[len(m.conversation)-1].Status = "Writing a commit message"
+	m.transcript.scrolledUp = false
+	turnCtx := m.beginTurn()
+	return "", tea.Batch(
+		func() tea.Msg {
+			message, err := orchestration.CommitMessage(turnCtx, model, req)
+			return commitMessageMsg{message: message, stat: lastLine(stat), err: err}
+		},
+		spinnerTickCmd(),
+	)
+}
+
+// handleCommitMessage shows the written message for approval
+func (m *InputModel) handleCommitMessage(msg commitMessageMsg) {
+	if !m.turnRunning() {
+		return // writing the message was cancelled
+	}
+	m.endTurn()
+	if msg.err != nil {
+		m.SetAIResponse("System: Failed to write a commit message: " + msg.err.Error())
+		return
+	}
+	m.pendingCommit = &pendingCommit{
+		message: msg.message,
+		signoff: globalConfig != nil && globalConfig.Commit.Signoff,
+	}
+	m.SetAIResponse(fmt.Sprintf("System: Wrote a commit message for the staged changes (%s):\n%s", msg.stat, msg.message))
+}
+
+// handleCommitKey commits, edits or discards the pending commit message
+func (m InputModel) handleCommitKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.pendingCommit
+	switch msg.String() {
+	case "y", "Y", "enter":
+	case "e", "E":
+		return m, editCommitMessage(p.message)
+	case "n", "N", "esc", "ctrl+c":
+		m.pendingCommit = nil
+		m.AddConversationPair("/commit", "System: Discarded the commit message; nothing was committed")
+		return m, nil
+	default:
+		return m, nil
+	}
+	m.pendingCommit = nil
+
+	m.AddConversationPair("/commit", "")
+	m.conversation[len(m.conversation)-1].Status = "Committing"
+	m.transcript.scrolledUp = false
+	ctx := m.beginTurn()
+	return m, tea.Batch(
+		func() tea.Msg {
+			args := []string{"commit", "--file=-"}
+			if p.signoff {
+				args = append(args, "--signoff")
+			}
+			if _, err := gitexec.RunWithStdin(ctx, "", p.message+"\n", args...); err != nil {
+				return commitDoneMsg{err: err}
+			}
+			summary, err := gitexec.Run(ctx, "", "log", "-1", "--format=%h %s")
+			return commitDoneMsg{summary: strings.TrimSpace(summary), err: err}
+		},
+		spinnerTickCmd(),
+	)
+}
+
+// handleCommitDone reports the commit that was created
+func (m *InputModel) handleCommitDone(msg commitDoneMsg) {
+	if !m.turnRunning() {
+		return // the commit was cancelled
+	}
+	m.endTurn()
+	if msg.err != nil {
+		m.SetAIResponse("System: Failed to commit: " + msg.err.Error())
+		return
+	}
+	m.SetAIResponse("System: Committed " + msg.summary)
+}
+
+// editCommitMessage opens message in the user's editor
+func editCommitMessage(message string) tea.Cmd {
+	tmp, err := os.CreateTemp("", "magikarp-*-COMMIT_EDITMSG")
+	if err != nil {
+		return func() tea.Msg { return commitEditedMsg{err: err} }
+	}
+	_, err = tmp.WriteString(message + "\n\n# Lines starting with # are ignored. An empty message discards the commit.\n")
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return func() tea.Msg { return commitEditedMsg{err: err} }
+	}
+
+	args := strings.Fields(editorCommand())
+	cmd := exec.Command(args[0], append(args[1:], tmp.Name())...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return commitEditedMsg{path: tmp.Name(), err: err}
+	})
+}
+
+// handleCommitEdited replaces the pending message with the edited one, which is
+// committed once confirmed
+func (m InputModel) handleCommitEdited(msg commitEditedMsg) (tea.Model, tea.Cmd) {
+	if msg.path != "" {
+		defer os.Remove(msg.path)
+	}
+	if m.pendingCommit == nil {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.AddConversationPair("/commit", "System: Editor failed: "+msg.err.Error())
+		return m, nil
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.AddConversationPair("/commit", "System: Failed to read the edited message: "+err.Error())
+		return m, nil
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	message := strings.TrimSpace(strings.Join(lines, "\n"))
+	if message == "" {
+		m.pendingCommit = nil
+		m.AddConversationPair("/commit", "System: The commit message is empty; nothing was committed")
+		return m, nil
+	}
+	m.pendingCommit.message = message
+	return m, nil
+}
+
+// renderCommitPrompt renders the commit message awaiting approval above the input box
+func renderCommitPrompt(p *pendingCommit, width int) string {
+	title := "Commit the staged changes with this message?"
+	if p.signoff {
+		title = "Commit the staged changes with this message, signed off?"
+	}
+	s := approvalTitleStyle.Render(wrapText(title, width-6)) + "\n"
+	lines := strings.Split(p.message, "\n")
+	for i, line := range lines {
+		if i == commitPreviewLines {
+			s += helpDisplayStyle.Render(fmt.Sprintf("... %d more lines (e to see all)", len(lines)-i)) + "\n"
+			break
+		}
+		s += approvalParamsStyle.Render("│ "+truncateCell(line, max(10, width-8))) + "\n"
+	}
+	return s + helpStyle.Render("y/enter: commit • e: edit • n/esc: discard") + "\n"
+}
+
+// lastLine returns the last non-empty line of s, trimmed
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// maxCompareModels bounds how many models one /compare asks
+const maxCompareModels = 6
+
+const compareUsage = "System: Usage: /compare <model>,<model>[,...] <prompt>"
+
+// compareDoneMsg carries the answers of a /compare
+type compareDoneMsg struct {
+	results []orchestration.Comparison
+}
+
+// runCompareCommand sends the prompt of "/compare <model>,<model> <prompt>" to each
+// model at once. line is the whole input, so the prompt keeps its line breaks. The
+// reply is set when the command cannot run.
+func (m *InputModel) runCompareCommand(line string) (string, tea.Cmd) {
+	if m.turnRunning() {
+		return busyReply, nil
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return compareUsage, nil
+	}
+	var models []string
+	seen := map[string]bool{}
+	for _, model := range strings.Split(fields[1], ",") {
+		if model = strings.TrimSpace(model); model == "" || seen[model] {
+			continue
+		}
+		if _, err := orchestration.ProviderFor(model); err != nil {
+			return fmt.Sprintf("System: Unknown model %s; see /model for the available ones", model), nil
+		}
+		seen[model] = true
+		models = append(models, model)
+	}
+	if len(models) < 2 || len(models) > maxCompareModels {
+		return fmt.Sprintf("System: /compare needs between 2 and %d models", maxCompareModels), nil
+	}
+	rest := strings.TrimSpace(line)
+	for _, f := range fields[:2] {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, f))
+	}
+
+	history := m.history()
+	prompt, _ := attachMentionedFiles(rest)
+	m.AddConversationPair(fmt.Sprintf("/compare %s %s", strings.Join(models, ","), rest), "")
+	m.conversation[len(m.conversation)-1].Status = fmt.Sprintf("Asking %d models", len(models))
+	m.transcript.scrolledUp = false
+
+	ctx := m.beginTurn()
+	return "", tea.Batch(
+		func() tea.Msg {
+			return compareDoneMsg{orchestration.Compare(ctx, models, systemPrompt(), history, prompt)}
+		},
+		spinnerTickCmd(),
+	)
+}
+
+// formatComparison renders the answers of a /compare one after another, each under a
+// heading with its latency, tokens and cost, followed by the fastest and cheapest model
+func formatComparison(results []orchestration.Comparison) string {
+	var b strings.Builder
+	var fastest, cheapest *orchestration.Comparison
+	for i := range results {
+		r := &results[i]
+		if r.Err != nil {
+			fmt.Fprintf(&b, "── %s · failed after %s ──\n%s\n\n", r.Model, formatLatency(r.Latency), r.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "── %s · %s · %s in / %s out · %s ──\n%s\n\n", r.Model, formatLatency(r.Latency),
+			formatTokens(r.Usage.InputTokens()), formatTokens(r.Usage.CompletionTokens), formatCost(r.Cost),
+			strings.TrimSpace(r.Text))
+		if fastest == nil || r.Latency < fastest.Latency {
+			fastest = r
+		}
+		if cheapest == nil || r.Cost < cheapest.Cost {
+			cheapest = r
+		}
+	}
+	if fastest != nil && len(results) > 1 {
+		fmt.Fprintf(&b, "Fastest: %s (%s) · Cheapest: %s (%s)", fastest.Model, formatLatency(fastest.Latency),
+			cheapest.Model, formatCost(cheapest.Cost))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// formatLatency renders a request duration, e.g. 850ms or 2.4s
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
@@ -0,0 +1,124 @@
+package terminal
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxComposerHeight is the most lines the input box grows to before it scrolls
+const maxComposerHeight = 10
+
+// composer is the multi-line message input. Enter is left to the caller to submit
+// the message; Alt+Enter and Ctrl+J insert a newline. Positions are
+// rune offsets into the whole value so callers can treat it like a single string.
+type composer struct {
+	textarea.Model
+	vim vimState
+}
+
+// newComposer creates a focused, empty composer; vim enables vi editing keys
+func newComposer(vim bool) composer {
+	ta := textarea.New()
+	ta.Prompt = ""
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0 // pasted code blocks may be long; the view scrolls instead
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.FocusedStyle.Base = lipgloss.NewStyle()
+	ta.BlurredStyle.Base = lipgloss.NewStyle()
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	ta.SetWidth(76)
+	ta.SetHeight(1)
+	ta.Focus()
+	return composer{Model: ta, vim: vimState{enabled: vim}}
+}
+
+// Update passes msg to the textarea and resizes it to fit its content
+func (c composer) Update(msg tea.Msg) (composer, tea.Cmd) {
+	var cmd tea.Cmd
+	c.Model, cmd = c.Model.Update(msg)
+	c.fitHeight()
+	return c, cmd
+}
+
+// SetValue replaces the content and moves the cursor to its end
+func (c *composer) SetValue(s string) {
+	c.Model.SetValue(s)
+	c.fitHeight()
+}
+
+// SetWidth sets the width of the text area, re-wrapping the content
+func (c *composer) SetWidth(w int) {
+	c.Model.SetWidth(w)
+	c.fitHeight()
+}
+
+// fitHeight grows the box with the content up to maxComposerHeight lines
+func (c *composer) fitHeight() {
+	lines := 0
+	for _, line := range strings.Split(c.Value(), "\n") {
+		// Long lines wrap onto several rows
+		lines += max(1, (lipgloss.Width(line)+c.Width()-1)/max(1, c.Width()))
+	}
+	c.SetHeight(min(max(lines, 1), maxComposerHeight))
+}
+
+// Position returns the cursor position as a rune offset into Value
+func (c composer) Position() int {
+	pos := 0
+	lines := strings.Split(c.Value(), "\n")
+	for i := 0; i < c.Line() && i < len(lines); i++ {
+		pos += len([]rune(lines[i])) + 1
+	}
+	info := c.LineInfo()
+	return pos + info.StartColumn + info.ColumnOffset
+}
+
+// SetCursor moves the cursor to the rune offset pos of Value
+func (c *composer) SetCursor(pos int) {
+	lines := strings.Split(c.Value(), "\n")
+	row, col := len(lines)-1, len([]rune(lines[len(lines)-1]))
+	for i, line := range lines {
+		n := len([]rune(line))
+		if pos <= n {
+			row, col = i, pos
+			break
+		}
+		pos -= n + 1
+	}
+
+	// The textarea only moves between rows one (wrapped) line at a time
+	for guard := 0; c.Line() > row && guard < c.Length(); guard++ {
+		c.CursorUp()
+	}
+	for guard := 0; c.Line() < row && guard < c.Length(); guard++ {
+		c.CursorDown()
+	}
+	c.Model.SetCursor(col)
+}
+
+// CursorEnd moves the cursor to the end of the content
+func (c *composer) CursorEnd() {
+	c.SetCursor(len([]rune(c.Value())))
+}
+
+// OnFirstLine reports whether the cursor is on the first line, so Up can browse history
+func (c composer) OnFirstLine() bool {
+	return c.Line() == 0 && c.LineInfo().RowOffset == 0
+}
+
+// OnLastLine reports whether the cursor is on the last line, so Down can browse history
+func (c composer) OnLastLine() bool {
+	info := c.LineInfo()
+	return c.Line() == c.LineCount()-1 && info.RowOffset == info.Height-1
+}
+
+// InsertString inserts s at the cursor
+func (c *composer) InsertString(s string) {
+	c.Model.InsertString(s)
+	c.fitHeight()
+}
@@ -0,0 +1,79 @@
+package terminal
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	convctx "github.com/pprunty/magikarp/internal/context"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// contextCompressedMsg is sent once older exchanges have been summarised, or summarising
+// failed; the pending user message is sent afterwards
+type contextCompressedMsg struct {
+	userMessage string
+	history     []providers.ChatMessage // uncompressed history, used when err is set
+	result      *convctx.Result
+	err         error
+}
+
+// needsCompression reports whether history plus the pending message is close enough
+// to model's context window to be summarised first
+func needsCompression(model string, history []providers.ChatMessage, userMessage string) bool {
+	opts, enabled := GetContextOptions()
+	if !enabled {
+		return false
+	}
+	return convctx.NeedsCompression(model, history, userMessage, opts)
+}
+
+// compressHistoryAsync summarises the older part of compressible, the history without
+// its pinned parts, with model. history is sent as it is when summarising fails.
+func compressHistoryAsync(ctx context.Context, userMessage, model string, history, compressible []providers.ChatMessage) tea.Cmd {
+	return func() tea.Msg {
+		msg := contextCompressedMsg{userMessage: userMessage, history: history}
+
+		// Summaries are small talk for the router: use its cheapest model
+		if router := orchestration.Routing(); model == orchestration.AutoModel && router != nil {
+			model = router.ModelFor(orchestration.TaskChatter)
+		}
+
+		p, err := orchestration.ProviderFor(model)
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+
+		opts, _ := GetContextOptions()
+		ctx := orchestration.WithSessionUsage(ctx, model)
+		if backgroundThinking() {
+			ctx = orchestration.WithThinking(ctx, model, sessionThinking())
+		}
+		msg.result, msg.err = convctx.Compress(ctx, p, model, compressible, opts)
+		return msg
+	}
+}
+
+// applyCompression records the summary and moves summaryUpTo past the conversation
+// pairs that were folded into it
+func (m *InputModel) applyCompression(result *convctx.Result) {
+	if result == nil || result.Summary == "" {
+		return
+	}
+
+	replacedPairs := result.Replaced / 2
+	if m.contextSummary != "" {
+		replacedPairs-- // the previous summary exchange was summarised again
+	}
+
+	indices := unpinnedPairs(m.conversation, historyPairs(m.conversation, m.summaryUpTo, GetMaxHistory()))
+	if replacedPairs > len(indices) {
+		replacedPairs = len(indices)
+	}
+	if replacedPairs > 0 {
+		m.summaryUpTo = indices[replacedPairs-1] + 1
+	}
+	m.contextSummary = result.Summary
+	inputDebugLog("Compressed %d exchanges into a summary (%d tokens)", replacedPairs, convctx.EstimateTokens(result.Summary))
+}
@@ -0,0 +1,295 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// settingKind selects how a setting is edited in the config editor
+type settingKind int
+
+const (
+	settingBool settingKind = iota
+	settingInt
+	settingFloat
+	settingChoice
+)
+
+// configSetting is a single editable entry of the config editor. Key is the dotted
+// path of the value in config.yaml.
+type configSetting struct {
+	Key     string
+	Label   string
+	Kind    settingKind
+	Restart bool // the change only takes effect after restarting Magikarp
+	Get     func(c *cfg.Config) string
+	Set     func(c *cfg.Config, value string) error
+}
+
+// configSettings returns the settings shown by /config
+func configSettings() []configSetting {
+	return []configSetting{
+		{
+			Key: "default_model", Label: "Default model", Kind: settingChoice, Restart: true,
+			Get: func(c *cfg.Config) string { return c.DefaultModel },
+			Set: func(c *cfg.Config, v string) error { c.DefaultModel = v; return nil },
+		},
+		{
+			Key: "default_temperature", Label: "Temperature", Kind: settingFloat, Restart: true,
+			Get: func(c *cfg.Config) string { return formatFloat(c.DefaultTemperature) },
+			Set: func(c *cfg.Config, v string) error {
+				f, err := parseFloatIn(v, 0, 2)
+				if err == nil {
+					c.DefaultTemperature = f
+				}
+				return err
+			},
+		},
+		{
+			Key: "streaming", Label: "Stream responses", Kind: settingBool,
+			Get: func(c *cfg.Config) string { return strconv.FormatBool(c.Streaming) },
+			Set: func(c *cfg.Config, v string) error { c.Streaming = v == "true"; return nil },
+		},
+		{
+			Key: "max_history", Label: "History sent per message", Kind: settingInt,
+			Get: func(c *cfg.Config) string { return strconv.Itoa(c.MaxHistory) },
+			Set: func(c *cfg.Config, v string) error { return setNonNegativeInt(&c.MaxHistory, v) },
+		},
+		{
+			Key: "tools.enabled", Label: "Tools enabled", Kind: settingBool,
+			Get: func(c *cfg.Config) string { return strconv.FormatBool(c.Tools.Enabled) },
+			Set: func(c *cfg.Config, v string) error { c.Tools.Enabled = v == "true"; return nil },
+		},
+		{
+			Key: "tools.output", Label: "Show tool output", Kind: settingBool,
+			Get: func(c *cfg.Config) string { return strconv.FormatBool(c.Tools.Output) },
+			Set: func(c *cfg.Config, v string) error { c.Tools.Output = v == "true"; return nil },
+		},
+		{
+			Key: "tools.max_iterations", Label: "Tool rounds per message", Kind: settingInt,
+			Get: func(c *cfg.Config) string { return strconv.Itoa(c.Tools.MaxIterations) },
+			Set: func(c *cfg.Config, v string) error { return setNonNegativeInt(&c.Tools.MaxIterations, v) },
+		},
+		{
+			Key: "context.compress_at", Label: "Compress history at", Kind: settingFloat,
+			Get: func(c *cfg.Config) string { return formatFloat(c.Context.CompressAt) },
+			Set: func(c *cfg.Config, v string) error {
+				f, err := parseFloatIn(v, 0, 1)
+				if err == nil {
+					c.Context.CompressAt = f
+				}
+				return err
+			},
+		},
+		{
+			Key: "context.keep_recent", Label: "Exchanges kept uncompressed", Kind: settingInt,
+			Get: func(c *cfg.Config) string { return strconv.Itoa(c.Context.KeepRecent) },
+			Set: func(c *cfg.Config, v string) error { return setNonNegativeInt(&c.Context.KeepRecent, v) },
+		},
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseFloatIn(v string, lo, hi float64) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < lo || f > hi {
+		return 0, fmt.Errorf("enter a number between %s and %s", formatFloat(lo), formatFloat(hi))
+	}
+	return f, nil
+}
+
+func setNonNegativeInt(dst *int, v string) error {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fmt.Errorf("enter a whole number of 0 or more")
+	}
+	*dst = n
+	return nil
+}
+
+// ConfigEditorModel is the full-screen settings editor shown by /config. Changes
+// apply to the running session immediately and are written back to config.yaml.
+type ConfigEditorModel struct {
+	width     int
+	height    int
+	cursor    int
+	settings  []configSetting
+	models    []string
+	editing   bool
+	input     textinput.Model
+	status    string
+	statusErr bool
+	quitting  bool
+}
+
+// NewConfigEditorModel creates the config editor for the global configuration
+func NewConfigEditorModel() ConfigEditorModel {
+	models := orchestration.Models()
+	sort.Strings(models)
+
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.CharLimit = 16
+
+	return ConfigEditorModel{
+		width:    80,
+		height:   24,
+		settings: configSettings(),
+		models:   models,
+		input:    ti,
+	}
+}
+
+// Init initializes the config editor
+func (m ConfigEditorModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the config editor
+func (m ConfigEditorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if m.editing {
+			switch msg.String() {
+			case "enter":
+				m.editing = false
+				m.input.Blur()
+				m.apply(m.input.Value())
+				return m, nil
+			case "esc":
+				m.editing = false
+				m.input.Blur()
+				m.status = ""
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+
+		setting := m.settings[m.cursor]
+		switch msg.String() {
+		case "up", "k":
+			m.cursor = (m.cursor - 1 + len(m.settings)) % len(m.settings)
+		case "down", "j":
+			m.cursor = (m.cursor + 1) % len(m.settings)
+		case "left", "h":
+			m.cycle(setting, -1)
+		case "right", "l":
+			m.cycle(setting, 1)
+		case "enter", " ":
+			switch setting.Kind {
+			case settingBool, settingChoice:
+				m.cycle(setting, 1)
+			default:
+				m.editing = true
+				m.input.SetValue(setting.Get(globalConfig))
+				m.input.CursorEnd()
+				m.input.Focus()
+				m.status = ""
+				return m, textinput.Blink
+			}
+		case "esc", "q":
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// cycle toggles a bool setting or moves a choice setting by step
+func (m *ConfigEditorModel) cycle(setting configSetting, step int) {
+	current := setting.Get(globalConfig)
+	switch setting.Kind {
+	case settingBool:
+		m.apply(strconv.FormatBool(current != "true"))
+	case settingChoice:
+		if len(m.models) == 0 {
+			return
+		}
+		i := sort.SearchStrings(m.models, current)
+		if i >= len(m.models) || m.models[i] != current {
+			i = 0
+			step = 0
+		}
+		m.apply(m.models[(i+step+len(m.models))%len(m.models)])
+	}
+}
+
+// apply sets the selected setting in the running config and saves it to config.yaml
+func (m *ConfigEditorModel) apply(value string) {
+	setting := m.settings[m.cursor]
+	if err := setting.Set(globalConfig, value); err != nil {
+		m.status, m.statusErr = setting.Label+": "+err.Error(), true
+		return
+	}
+
+	value = setting.Get(globalConfig)
+	if err := cfg.UpdateFile(globalConfig.Path, map[string]string{setting.Key: value}); err != nil {
+		m.status, m.statusErr = "Applied to this session, but saving failed: "+err.Error(), true
+		return
+	}
+
+	m.status, m.statusErr = fmt.Sprintf("Saved %s = %s to %s", setting.Key, value, displayPath(globalConfig.Path)), false
+	if setting.Restart {
+		m.status += " (takes effect after restart)"
+	}
+}
+
+// View renders the config editor
+func (m ConfigEditorModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	s := renderWelcomeBox() + "\n\n"
+	s += " " + versionStyle.Render("Settings") + "\n\n"
+
+	for i, setting := range m.settings {
+		value := setting.Get(globalConfig)
+		if i == m.cursor && m.editing {
+			value = m.input.View()
+		} else if setting.Kind == settingChoice {
+			value = "‹ " + value + " ›"
+		}
+		line := fmt.Sprintf("  %-30s %s", setting.Label, value)
+		if i == m.cursor {
+			s += modelSelectActiveStyle.Render(line) + "\n"
+		} else {
+			s += modelSelectNormalStyle.Render(line) + "\n"
+		}
+	}
+
+	s += "\n"
+	if m.status != "" {
+		if m.statusErr {
+			s += configErrorStyle.Render(" "+m.status) + "\n"
+		} else {
+			s += modelSelectHelpStyle.Render(" "+m.status) + "\n"
+		}
+	}
+
+	s += "\n"
+	if m.editing {
+		s += modelSelectHelpStyle.Render(" enter: save • esc: cancel") + "\n"
+	} else {
+		s += modelSelectHelpStyle.Render(" ↑/↓: navigate • enter: toggle/edit • ←/→: change • esc: close") + "\n"
+	}
+	return s
+}
+
+var configErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#E74C3C"))
@@ -0,0 +1,227 @@
+package terminal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/debuglog"
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// debugConsoleKey opens and closes the debug console
+const debugConsoleKey = "f12"
+
+// debugEntryLines bounds the lines of text shown for each debug log entry
+const debugEntryLines = 8
+
+// Tabs of the debug console
+const (
+	debugTabAll = iota
+	debugTabProviders
+	debugTabTools
+	debugTabRegistry
+	debugTabCount
+)
+
+var debugTabNames = []string{"All", "Providers", "Tools", "Registry"}
+
+// debugConsole is the state of the debug console overlay (F12 or /debug), which shows
+// the recent provider traffic, tool calls and the state of the provider registry
+type debugConsole struct {
+	tab int
+	// scroll is how many lines the view is scrolled up from the latest entries
+	scroll int
+}
+
+// toggleDebugConsole opens or closes the debug console
+func (m *InputModel) toggleDebugConsole() {
+	if m.debugConsole != nil {
+		m.debugConsole = nil
+		return
+	}
+	m.showingSlashCommands = false
+	m.showingFileMentions = false
+	m.debugConsole = &debugConsole{}
+}
+
+// handleDebugConsoleKey handles keys while the debug console is open
+func (m InputModel) handleDebugConsoleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.debugConsole
+	page := max(1, m.height-4)
+	switch msg.String() {
+	case debugConsoleKey, "esc", "q":
+		m.debugConsole = nil
+	case "tab", "right", "l":
+		c.tab, c.scroll = (c.tab+1)%debugTabCount, 0
+	case "shift+tab", "left", "h":
+		c.tab, c.scroll = (c.tab+debugTabCount-1)%debugTabCount, 0
+	case "up", "k":
+		c.scroll++
+	case "down", "j":
+		c.scroll = max(0, c.scroll-1)
+	case "pgup":
+		c.scroll += page
+	case "pgdown":
+		c.scroll = max(0, c.scroll-page)
+	case "home":
+		c.scroll = 1 << 30 // clamped when rendered
+	case "end":
+		c.scroll = 0
+	case "c":
+		debuglog.Clear()
+		c.scroll = 0
+	case "ctrl+c":
+		m.debugConsole = nil
+		return m.Update(msg)
+	}
+	return m, nil
+}
+
+// renderDebugConsole renders the console to fill the screen above the status bar
+func (m InputModel) renderDebugConsole(height int) string {
+	c := m.debugConsole
+	width := max(20, m.width-2)
+
+	tabs := make([]string, len(debugTabNames))
+	for i, name := range debugTabNames {
+		if i == c.tab {
+			tabs[i] = modelSelectActiveStyle.Render("[ " + name + " ]")
+		} else {
+			tabs[i] = modelSelectNormalStyle.Render("  " + name + "  ")
+		}
+	}
+	header := modelSelectHeaderStyle.Render("Debug console") + "  " + strings.Join(tabs, " ")
+
+	var lines []string
+	if c.tab == debugTabRegistry {
+		lines = m.registryLines(width)
+	} else {
+		lines = debugEntryLinesFor(c.tab, width)
+	}
+
+	// Show the latest lines unless scrolled up
+	body := max(1, height-2)
+	maxScroll := max(0, len(lines)-body)
+	c.scroll = min(c.scroll, maxScroll)
+	end := len(lines) - c.scroll
+	start := max(0, end-body)
+	visible := lines[start:end]
+	for len(visible) < body {
+		visible = append(visible, "")
+	}
+	return header + "\n\n" + strings.Join(visible, "\n")
+}
+
+// debugEntryLinesFor renders the debug log entries shown on tab, oldest first
+func debugEntryLinesFor(tab, width int) []string {
+	var lines []string
+	for _, e := range debuglog.Entries() {
+		switch {
+		case tab == debugTabProviders && e.Kind == debuglog.Tool:
+			continue
+		case tab == debugTabTools && e.Kind != debuglog.Tool:
+			continue
+		}
+		lines = append(lines, renderDebugEntry(e, width)...)
+	}
+	if len(lines) == 0 {
+		return []string{helpStyle.Render("Nothing recorded yet. Provider requests and tool calls show here as they happen.")}
+	}
+	return lines
+}
+
+// renderDebugEntry renders the heading of an entry and the start of its text
+func renderDebugEntry(e debuglog.Entry, width int) []string {
+	arrow := map[debuglog.Kind]string{debuglog.Request: "→", debuglog.Response: "←", debuglog.Tool: "⚙"}[e.Kind]
+	heading := fmt.Sprintf("%s %s %-8s %s", e.Time.Format("15:04:05.000"), arrow, e.Kind, e.Source)
+	if e.Duration > 0 {
+		heading += "  " + e.Duration.Round(time.Millisecond).String()
+	}
+	style := slashCommandActiveStyle
+	if e.IsError {
+		style = configErrorStyle
+		heading += "  error"
+	}
+	lines := []string{style.Render(truncateCell(heading, width))}
+
+	text := strings.Split(strings.TrimSpace(wrapText(e.Text, width-4)), "\n")
+	if len(text) > debugEntryLines {
+		text = append(text[:debugEntryLines], fmt.Sprintf("… %d more lines", len(text)-debugEntryLines))
+	}
+	for _, line := range text {
+		if line != "" {
+			lines = append(lines, helpStyle.Render("    "+line))
+		}
+	}
+	return lines
+}
+
+// registryLines describes the providers and models registered for this session
+func (m InputModel) registryLines(width int) []string {
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, truncateCell(fmt.Sprintf(format, args...), width))
+	}
+
+	add("Model        %s", m.provider)
+	if globalConfig != nil {
+		add("Default      %s", globalConfig.DefaultModel)
+	}
+	routing := "off"
+	if orchestration.Routing() != nil {
+		routing = "on"
+	}
+	add("Routing      %s", routing)
+	policy := orchestration.RetryPolicyFromConfig(globalConfig)
+	add("Retries      %d, backoff %s to %s", policy.MaxRetries, policy.InitialBackoff, policy.MaxBackoff)
+	lines = append(lines, "")
+
+	if globalConfig != nil {
+		lines = append(lines, slashCommandActiveStyle.Render("Providers"))
+		status := orchestration.GetInitializedProviders(globalConfig)
+		byProvider := orchestration.ModelsByProvider(globalConfig)
+		names := make([]string, 0, len(status))
+		for name := range status {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			mark := "✗"
+			if status[name] {
+				mark = "✓"
+			}
+			add("  %s %-12s %s", mark, name, strings.Join(byProvider[name], ", "))
+		}
+		lines = append(lines, "")
+	}
+
+	var services []string
+	for _, name := range []string{"openai", "gemini", "ollama"} {
+		if _, err := orchestration.EmbedderFor(name); err == nil {
+			services = append(services, "embeddings ("+name+")")
+		}
+	}
+	if _, err := orchestration.SynthesizerFor("openai"); err == nil {
+		services = append(services, "speech (openai)")
+	}
+	if _, err := orchestration.TranscriberFor("openai"); err == nil {
+		services = append(services, "transcription (openai)")
+	}
+	if len(services) > 0 {
+		add("Services     %s", strings.Join(services, ", "))
+	}
+
+	if warnings := orchestration.RegistryWarnings(); len(warnings) > 0 {
+		lines = append(lines, "", slashCommandActiveStyle.Render("Not set up"))
+		for _, w := range warnings {
+			lines = append(lines, helpStyle.Render(truncateCell("  "+w, width)))
+		}
+	}
+	return lines
+}
+
+// debugConsoleHelp lists the keys of the debug console
+const debugConsoleHelp = "tab: next view • ↑/↓/pgup/pgdn: scroll • home/end: oldest/latest • c: clear • f12/esc: close"
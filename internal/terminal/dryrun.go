@@ -0,0 +1,26 @@
+package terminal
+
+import "strings"
+
+// runDryRunCommand turns dry runs on or off (/dryrun, /dryrun on, /dryrun off)
+func runDryRunCommand(args []string) string {
+	action := "toggle"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	switch action {
+	case "toggle":
+		SetDryRunEnabled(!DryRunEnabled())
+	case "on":
+		SetDryRunEnabled(true)
+	case "off":
+		SetDryRunEnabled(false)
+	default:
+		return "System: Usage: /dryrun [on|off]"
+	}
+	if DryRunEnabled() {
+		return "System: Dry run on: file edits show their diff and commands what they would run, without " +
+			"changing anything. Other tools that change state are not run."
+	}
+	return "System: Dry run off"
+}
@@ -0,0 +1,63 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/session"
+)
+
+// exportPath is where the conversation is written when the UI exits (--export)
+var exportPath string
+
+// SetExportPath makes the interactive UI export the conversation to path on exit.
+// The format follows the file extension (.md, .json or .html).
+func SetExportPath(path string) {
+	exportPath = path
+}
+
+// runExportCommand handles "/export [path|md|json|html]" and returns the system reply.
+// Without a path the conversation is written to magikarp-<session id> in the working
+// directory, as Markdown unless a format is named.
+func (m *InputModel) runExportCommand(args []string) string {
+	path := strings.TrimSpace(strings.Join(args, " "))
+	if format, ok := session.ParseFormat(path); ok || path == "" {
+		if !ok {
+			format = session.FormatMarkdown
+		}
+		path = ""
+		if m.syncSession() {
+			path = "magikarp-" + m.session.ID + format.Extension()
+		}
+	}
+
+	written, err := m.exportConversation(path)
+	switch {
+	case err != nil:
+		return "System: Failed to export conversation: " + err.Error()
+	case written == "":
+		return "System: Nothing to export yet"
+	}
+	return "System: Conversation exported to " + displayPath(written)
+}
+
+// exportConversation writes the completed exchanges to path and returns the absolute
+// path written, or "" when there is nothing to export
+func (m *InputModel) exportConversation(path string) (string, error) {
+	if !m.syncSession() {
+		return "", nil
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if err := m.session.Export(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
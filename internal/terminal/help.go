@@ -61,6 +61,7 @@ func (m HelpModel) View() string {
 
 	s += helpSectionStyle.Render(" Usage Modes:") + "\n"
 	s += helpItemStyle.Render(" • Interactive: magikarp (start chat session)") + "\n"
+	s += helpItemStyle.Render(" • Print mode: magikarp -p \"prompt\" (answer once and exit)") + "\n"
 	s += helpItemStyle.Render(" • Command line: magikarp --help") + "\n\n"
 
 	s += helpSectionStyle.Render(" Common Tasks:") + "\n"
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -107,6 +108,10 @@ func (hm *HistoryManager) SaveToFile() error {
 	defer file.Close()
 
 	for _, message := range hm.history {
+		// Multi-line messages are stored quoted so each entry stays on one line
+		if strings.ContainsAny(message, "\r\n") || strings.HasPrefix(message, `"`) {
+			message = strconv.Quote(message)
+		}
 		if _, err := fmt.Fprintln(file, message); err != nil {
 			return fmt.Errorf("failed to write to history file: %w", err)
 		}
@@ -129,9 +134,15 @@ func (hm *HistoryManager) LoadFromFile() error {
 
 	hm.history = make([]string, 0)
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) // pasted messages can be long
 	
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, `"`) {
+			if unquoted, err := strconv.Unquote(line); err == nil {
+				line = unquoted
+			}
+		}
 		if line != "" {
 			hm.history = append(hm.history, line)
 		}
@@ -153,4 +164,23 @@ func (hm *HistoryManager) LoadFromFile() error {
 func (hm *HistoryManager) ClearHistory() error {
 	hm.history = make([]string, 0)
 	return hm.SaveToFile()
-}
\ No newline at end of file
+}
+// Search returns up to limit history entries matching query, most recent first.
+// Entries containing query verbatim come before fuzzy (subsequence) matches.
+func (hm *HistoryManager) Search(query string, limit int) []string {
+	var exact, fuzzy []string
+	lower := strings.ToLower(query)
+	for i := len(hm.history) - 1; i >= 0; i-- {
+		entry := hm.history[i]
+		if strings.Contains(strings.ToLower(entry), lower) {
+			exact = append(exact, entry)
+		} else if _, ok := fuzzyScore(query, entry); ok {
+			fuzzy = append(fuzzy, entry)
+		}
+	}
+	matches := append(exact, fuzzy...)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
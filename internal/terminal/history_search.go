@@ -0,0 +1,95 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxHistorySearchResults is the number of matches listed by the Ctrl+R search
+const maxHistorySearchResults = 8
+
+// historySearch is the state of the Ctrl+R reverse history search
+type historySearch struct {
+	query   string
+	matches []string
+	cursor  int
+}
+
+// startHistorySearch opens the Ctrl+R search over previous inputs
+func (m *InputModel) startHistorySearch() {
+	m.exitHistoryMode()
+	m.showingSlashCommands = false
+	m.showingFileMentions = false
+	m.historySearch = &historySearch{}
+	m.updateHistorySearch()
+}
+
+// updateHistorySearch refreshes the matches for the current query
+func (m *InputModel) updateHistorySearch() {
+	m.historySearch.matches = m.historyManager.Search(m.historySearch.query, maxHistorySearchResults)
+	m.historySearch.cursor = 0
+}
+
+// handleHistorySearchKey handles keys while the Ctrl+R search is open. Typing filters
+// the matches, Ctrl+R and the arrows move between them and Enter inserts the selection.
+func (m InputModel) handleHistorySearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	search := m.historySearch
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		search.query += string(msg.Runes)
+		m.updateHistorySearch()
+		return m, nil
+	case tea.KeyBackspace:
+		if runes := []rune(search.query); len(runes) > 0 {
+			search.query = string(runes[:len(runes)-1])
+			m.updateHistorySearch()
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+r", "up":
+		// Older matches are further down the list, like repeated ctrl+r in a shell
+		if search.cursor < len(search.matches)-1 {
+			search.cursor++
+		}
+	case "down":
+		if search.cursor > 0 {
+			search.cursor--
+		}
+	case "enter", "tab":
+		if len(search.matches) > 0 {
+			m.textInput.SetValue(search.matches[search.cursor])
+			m.textInput.CursorEnd()
+		}
+		m.historySearch = nil
+	case "esc", "ctrl+c", "ctrl+g":
+		m.historySearch = nil
+	}
+	return m, nil
+}
+
+// renderHistorySearch renders the search prompt and matches below the input box
+func (m InputModel) renderHistorySearch() string {
+	search := m.historySearch
+	s := "\n" + slashCommandActiveStyle.Render(fmt.Sprintf("  (reverse-i-search)`%s':", search.query)) + "\n"
+	if len(search.matches) == 0 {
+		return s + slashCommandNormalStyle.Render("  No matching history") + "\n\n"
+	}
+	width := max(20, m.width-6)
+	for i, entry := range search.matches {
+		// History entries can be long; show a single line of each
+		line := strings.ReplaceAll(entry, "\n", " ")
+		if runes := []rune(line); len(runes) > width {
+			line = string(runes[:width-1]) + "…"
+		}
+		if i == search.cursor {
+			s += "  " + slashCommandActiveStyle.Render(line) + "\n"
+		} else {
+			s += "  " + slashCommandNormalStyle.Render(line) + "\n"
+		}
+	}
+	return s + "\n"
+}
@@ -5,15 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	convctx "github.com/pprunty/magikarp/internal/context"
+	"github.com/pprunty/magikarp/internal/index"
 	"github.com/pprunty/magikarp/internal/orchestration"
 	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/redact"
+	"github.com/pprunty/magikarp/internal/session"
 	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+	"github.com/pprunty/magikarp/internal/worktree"
 )
 
 // wrapText wraps text to the specified width on word boundaries
@@ -84,7 +91,7 @@ func init() {
 func inputDebugLog(format string, args ...interface{}) {
 	if inputDebug && inputDebugFile != nil {
 		timestamp := time.Now().Format("2006/01/02 15:04:05")
-		fmt.Fprintf(inputDebugFile, "%s [Input] "+format+"\n", append([]interface{}{timestamp}, args...)...)
+		fmt.Fprintf(inputDebugFile, "%s [Input] %s\n", timestamp, redact.String(fmt.Sprintf(format, args...)))
 		inputDebugFile.Sync()
 	}
 }
@@ -94,6 +101,16 @@ type ConversationPair struct {
 	UserMessage  string
 	AIResponse   string
 	IsProcessing bool // Whether this conversation is currently being processed
+	IsError      bool // Whether the AI response is an error (excluded from model context)
+	Model        string                   // Model that answered
+	Route        string                   // How the "auto" model chose the model that answered
+	ToolCalls    []orchestration.ToolCall // Tools executed while answering
+	Time         time.Time                // When the message was sent
+	Progress     []string                 // Tool rounds completed while processing
+	Status       string                   // Transient status shown on the spinner line (e.g. retries)
+	Reasoning    string                   // Model reasoning shown dimmed; never sent back as history
+	Cancelled    bool                     // Whether the request was cancelled with Esc
+	Pinned       bool                     // Whether /pin keeps the exchange out of compression and trimming
 }
 
 // Spinner state
@@ -105,7 +122,7 @@ type spinnerTickMsg struct{}
 
 // InputModel represents the text input state
 type InputModel struct {
-	textInput            textinput.Model
+	textInput            composer
 	provider             string
 	quitting             bool
 	message              string
@@ -124,18 +141,55 @@ type InputModel struct {
 	slashCommandCursor   int            // Current position in slash command menu
 	availableCommands    []SlashCommand // Available slash commands
 	filteredCommands     []SlashCommand // Filtered slash commands based on input
+	showingFileMentions  bool           // Whether the @ file picker is visible
+	historySearch        *historySearch // Ctrl+R reverse history search, when open
+	templatePicker       *templatePicker // /template picker, when open
+	branchPicker         *branchPicker   // /branches picker, when open
+	debugConsole         *debugConsole   // F12 or /debug console, when open
+	pinnedFiles          []string              // Files pinned with /pin, sent with every message
+	branches             []*conversationBranch // Branches made with /fork; empty until the first fork
+	activeBranch         int                   // Index in branches of the live conversation
+	fileMentionCursor    int            // Current position in the @ file picker
+	fileMentionMatches   []string       // Files matching the current @ query
+	pendingPaste         *pastedFile    // Pasted file path waiting to be attached or inserted
+	pendingShare         *pendingShare  // /share transcript waiting to be uploaded
+	pendingCommit        *pendingCommit // /commit message waiting to be committed
+	pastedClipboard      string         // Clipboard text from /paste, sent with the next message
+	transcript           transcript     // Scrollable view of the conversation
 	triggerHelpScreen    bool           // Whether to trigger help screen
 	triggerModelSelect   bool           // Whether to trigger model selection screen
 	speechMode           bool           // Whether speech mode is enabled
+	pendingApproval      *toolApprovalMsg // Tool call waiting for user approval
+	pendingReview        *fileReviewMsg   // File change waiting for user review
+	reviewError          string           // Error from editing the pending file change
+	session              *session.Session // Persisted record of this conversation
+	triggerResume        bool             // Whether to trigger the session picker
+	triggerConfig        bool             // Whether to trigger the config editor
+	triggerStats         bool             // Whether to trigger the usage dashboard
+	contextSummary       string           // Summary of exchanges compressed out of the history
+	summaryUpTo          int              // Conversation pairs before this index are covered by contextSummary
+	showReasoning        bool             // Whether finished reasoning is expanded
+	expandAllTools       bool             // Whether ctrl+t flipped the default state of tool blocks
+	liveCollapsed        bool             // Whether ctrl+t folded the output of the running command
+	expandedTools        map[int]bool     // Tool blocks toggled with /expand, by number
+	editing              bool             // Whether the input holds an earlier message being edited
+	editIndex            int              // Conversation index of the message being edited
+	gitBranch            string           // Branch of the working directory shown in the status bar
+	worktree             *worktree.Worktree // Worktree of the isolated run; nil when not isolated
+	worktreeOrigin       string             // Working directory to return to when the isolated run ends
+	turnCtx              context.Context    // Context of the running request
+	cancelTurn           context.CancelFunc // Cancels the running request; nil when idle
+	turnStarted          time.Time          // When the last request started
+	blurred              bool               // Whether the terminal reported losing focus
+	ptt                  pushToTalk         // Push-to-talk recording, when one runs or is transcribed
+	calibrating          bool               // /speech calibrate is measuring the background noise
+	reloadRegistry       bool               // The config changed the models during a request; rebuild them after it
+	awakeUntil           time.Time          // Speech mode heard the wake word and takes the prompt until then
 }
 
 // NewInputModel creates a new input model for the selected provider
 func NewInputModel(provider string) InputModel {
-	ti := textinput.New()
-	ti.Placeholder = ""
-	ti.Focus()
-	ti.CharLimit = 500
-	ti.Width = 76
+	ti := newComposer(GetVimKeymap())
 
 	// Initialize history manager
 	histManager, err := NewHistoryManager()
@@ -161,13 +215,29 @@ func NewInputModel(provider string) InputModel {
 		triggerHelpScreen:    false,
 		triggerModelSelect:   false,
 		speechMode:           false, // Speech mode starts disabled
+		session:              session.New(provider),
 	}
 }
 
 // aiResponseMsg is sent when we receive an AI response
 type aiResponseMsg struct {
-	response string
-	isError  bool
+	response  string
+	isError   bool
+	toolCalls []orchestration.ToolCall
+	model     string // model that answered, when a fallback model took over
+	route     string // how the "auto" model chose the model, when it was used
+}
+
+// turnProgressMsg reports a completed tool round while a turn is still running
+type turnProgressMsg struct {
+	progress string
+	events   <-chan tea.Msg
+}
+
+// turnStatusMsg updates the spinner line while a turn is waiting, e.g. before a retry
+type turnStatusMsg struct {
+	status string
+	events <-chan tea.Msg
 }
 
 // processingMsg is sent when we start processing a message
@@ -177,7 +247,7 @@ type processingMsg struct{}
 type timeoutMsg struct{}
 
 func (m InputModel) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textarea.Blink, readGitBranch)
 }
 
 // timeoutCmd returns a command that sends a timeout message after 2 seconds
@@ -196,21 +266,189 @@ func spinnerTickCmd() tea.Cmd {
 
 func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
+	browsedHistory := false // up/down loaded a history entry instead of moving the cursor
 
 	inputDebugLog("Update called with msg type: %T", msg)
 
 	switch msg := msg.(type) {
 	case aiResponseMsg:
+		if !m.turnRunning() {
+			return m, nil // the request was cancelled
+		}
+		m.endTurn()
+		// The turn that carried out an approved plan is over
+		SetApprovedPlan("")
 		// Received AI response, update the conversation
 		if msg.isError {
 			m.SetAIResponse(fmt.Sprintf("Error: %s", msg.response))
+			if len(m.conversation) > 0 {
+				m.conversation[len(m.conversation)-1].IsError = true
+			}
 		} else {
 			m.SetAIResponse(msg.response)
+			speak(msg.response, true)
+			if len(m.conversation) > 0 {
+				m.conversation[len(m.conversation)-1].ToolCalls = msg.toolCalls
+				if msg.model != "" {
+					m.conversation[len(m.conversation)-1].Model = msg.model
+				}
+				m.conversation[len(m.conversation)-1].Route = msg.route
+			}
+		}
+		// The control_state tool may have toggled speech mode during the turn
+		if SpeechModeEnabled() != m.speechMode {
+			if reply := m.setSpeechMode(SpeechModeEnabled()); reply != "" {
+				m.AddConversationPair("/speech", reply)
+			}
+		}
+		m.autoSaveSession()
+		return m, m.notifyTurnDone()
+	case speechMsg:
+		return m, m.handleSpeech(msg)
+	case configReloadMsg:
+		m.handleConfigReload(msg)
+		return m, nil
+	case speechCalibratedMsg:
+		m.handleSpeechCalibrated(msg)
+		return m, nil
+	case pushToTalkMsg:
+		m.handlePushToTalk(msg)
+		return m, nil
+	case pushToTalkTickMsg:
+		return m, m.checkPushToTalkRelease(msg)
+	case shareDoneMsg:
+		m.handleShareDone(msg)
+		return m, nil
+	case commitMessageMsg:
+		m.handleCommitMessage(msg)
+		return m, nil
+	case commitDoneMsg:
+		m.handleCommitDone(msg)
+		return m, m.notifyTurnDone()
+	case commitEditedMsg:
+		return m.handleCommitEdited(msg)
+	case compareDoneMsg:
+		if !m.turnRunning() {
+			return m, nil // the comparison was cancelled
+		}
+		m.endTurn()
+		m.SetAIResponse(formatComparison(msg.results))
+		return m, m.notifyTurnDone()
+	case turnProgressMsg:
+		// A tool round finished; show it under the spinner and keep listening
+		if m.turnRunning() && len(m.conversation) > 0 {
+			last := &m.conversation[len(m.conversation)-1]
+			last.Progress = append(last.Progress, msg.progress)
+			last.Status = ""
+			last.AIResponse = "" // the streamed reply led to the tools
+		}
+		return m, waitForTurnEvent(msg.events)
+	case contextCompressedMsg:
+		// History was summarised (or compression failed); continue with the pending message
+		if !m.turnRunning() {
+			return m, nil // cancelled while compressing
+		}
+		if len(m.conversation) > 0 {
+			m.conversation[len(m.conversation)-1].Status = ""
+		}
+		history := msg.history
+		if msg.err != nil {
+			inputDebugLog("Context compression failed, sending full history: %v", msg.err)
+		} else {
+			m.applyCompression(msg.result)
+			history = m.history()
+		}
+		return m, m.startProcessing(msg.userMessage, history)
+	case turnStatusMsg:
+		if m.turnRunning() && len(m.conversation) > 0 {
+			m.conversation[len(m.conversation)-1].Status = msg.status
+			// A retried or rerouted request streams its reply again
+			m.conversation[len(m.conversation)-1].AIResponse = ""
+		}
+		return m, waitForTurnEvent(msg.events)
+	case turnTextMsg:
+		if m.turnRunning() && len(m.conversation) > 0 {
+			last := &m.conversation[len(m.conversation)-1]
+			if msg.reset {
+				last.AIResponse = ""
+			} else {
+				last.AIResponse += msg.delta
+			}
+		}
+		return m, waitForTurnEvent(msg.events)
+	case turnReasoningMsg:
+		if m.turnRunning() {
+			m.AppendReasoning(msg.delta)
+		}
+		return m, waitForTurnEvent(msg.events)
+	case fileReviewMsg:
+		if !m.turnRunning() {
+			return m, waitForTurnEvent(msg.events)
+		}
+		// A running turn wants to write a file; show the diff for review
+		m.pendingReview = &msg
+		m.reviewError = ""
+		return m, nil
+	case editorFinishedMsg:
+		return m.handleEditorFinished(msg)
+	case memoryEditedMsg:
+		return m.handleMemoryEdited(msg)
+	case toolApprovalMsg:
+		if !m.turnRunning() {
+			return m, waitForTurnEvent(msg.events)
 		}
+		// A running turn wants to execute a tool that is not auto-approved
+		m.pendingApproval = &msg
+		return m, nil
+	case streamChunkMsg:
+		if !m.turnRunning() {
+			return m, waitForStreamChunk(msg.stream) // drain the cancelled stream
+		}
+		// Append the delta to the pair being streamed and wait for the next one
+		if msg.reasoning {
+			m.AppendReasoning(msg.delta)
+		} else {
+			m.AppendAIResponse(msg.delta)
+			speak(msg.delta, false)
+		}
+		return m, waitForStreamChunk(msg.stream)
+	case streamDoneMsg:
+		if !m.turnRunning() {
+			return m, nil
+		}
+		m.endTurn()
+		SetApprovedPlan("")
+		speak("", true)
+		var cmd tea.Cmd
+		if len(m.conversation) > 0 {
+			last := &m.conversation[len(m.conversation)-1]
+			last.IsProcessing = false
+			if msg.err != nil {
+				// Keep what arrived before the error, but not in the model's context
+				note := "Error: " + msg.err.Error()
+				if partial := strings.TrimSpace(last.AIResponse); partial != "" {
+					note = partial + "\n\n" + note
+				}
+				last.AIResponse = note
+				last.IsError = true
+			} else {
+				cmd = runPostResponseHooks(m.provider, *last)
+			}
+		}
+		m.autoSaveSession()
+		return m, tea.Batch(cmd, m.notifyTurnDone())
+	case tea.FocusMsg:
+		m.blurred = false
+		return m, nil
+	case tea.BlurMsg:
+		m.blurred = true
 		return m, nil
 	case processingMsg:
 		// Start processing - this is just for UI feedback
 		return m, nil
+	case gitBranchMsg:
+		m.gitBranch = string(msg)
+		return m, refreshGitBranch()
 	case timeoutMsg:
 		// Timeout expired, reset Ctrl+C state
 		m.ctrlCPressed = false
@@ -241,10 +479,69 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		// Update text input width to fit the new terminal width
 		// Account for border (2 chars) + padding (2 chars) + margin (2 chars)
-		m.textInput.Width = max(18, m.width-6)
-	// Remove mouse scroll handling - let terminal handle it naturally
+		m.textInput.SetWidth(max(18, m.width-6))
+	case tea.MouseMsg:
+		m.handleMouse(msg)
+		return m, nil
 	case tea.KeyMsg:
 		inputDebugLog("KeyMsg received: %s", msg.String())
+		// PgUp/PgDn scroll the transcript, even while a prompt is waiting for an answer
+		if m.handleScrollKey(msg) {
+			return m, nil
+		}
+		// A pending tool approval captures all keys until answered
+		if m.pendingApproval != nil {
+			return m.handleApprovalKey(msg)
+		}
+		if m.pendingReview != nil {
+			return m.handleReviewKey(msg)
+		}
+		// F12 opens the debug console, which captures keys until closed
+		if m.debugConsole != nil {
+			return m.handleDebugConsoleKey(msg)
+		}
+		if msg.String() == debugConsoleKey {
+			m.toggleDebugConsole()
+			return m, nil
+		}
+		// Ctrl+R searches previous inputs; the search captures keys until closed
+		if m.historySearch != nil {
+			return m.handleHistorySearchKey(msg)
+		}
+		if m.templatePicker != nil {
+			return m.handleTemplatePickerKey(msg)
+		}
+		if m.branchPicker != nil {
+			return m.handleBranchPickerKey(msg)
+		}
+		if m.pendingPaste != nil {
+			return m.handlePasteKey(msg)
+		}
+		if m.pendingShare != nil {
+			return m.handleShareKey(msg)
+		}
+		if m.pendingCommit != nil {
+			return m.handleCommitKey(msg)
+		}
+		// A pasted or dropped file path offers to attach the file instead
+		if text, ok := pastedText(msg); ok {
+			if p := newPastedFile(text); p != nil {
+				m.pendingPaste = p
+				return m, nil
+			}
+		}
+		// The push-to-talk key starts and stops a recording; esc drops it
+		if key := GetPushToTalkKey(); key != "" && msg.String() == key {
+			return m, m.pressPushToTalk()
+		}
+		if msg.String() == "esc" && m.ptt.state != pushToTalkIdle {
+			m.cancelPushToTalk()
+			return m, nil
+		}
+		if msg.String() == "ctrl+r" && m.historyManager != nil {
+			m.startHistorySearch()
+			return m, nil
+		}
 		// Handle specific slash command navigation keys
 		if m.showingSlashCommands {
 			switch msg.String() {
@@ -274,10 +571,21 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.historyManager.AddMessage(selectedCommand.Name)
 					}
 					
+					// Arguments typed after the command, e.g. "/undo 2"
+					line := m.textInput.Value()
+					args := strings.Fields(line)
+					if len(args) > 0 {
+						args = args[1:]
+					}
+
 					m.showingSlashCommands = false
 					m.textInput.SetValue("")
+					m.editing = false // the edited text is gone
 
 					switch selectedCommand.Name {
+					case "/config":
+						m.triggerConfig = true
+						return m, tea.Quit
 					case "/exit":
 						m.quitting = true
 						return m, tea.Quit
@@ -287,16 +595,29 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					case "/model":
 						m.triggerModelSelect = true
 						return m, tea.Quit
-					case "/speech":
-						m.speechMode = !m.speechMode
-						SetSpeechModeEnabled(m.speechMode)
-						// Update placeholder based on speech mode
-						if m.speechMode {
-							m.textInput.Placeholder = "Listening..."
-						} else {
-							m.textInput.Placeholder = ""
+					case "/resume":
+						m.triggerResume = true
+						return m, tea.Quit
+					case "/stats":
+						m.triggerStats = true
+						return m, tea.Quit
+					case "/save":
+						path, err := m.persistSession()
+						switch {
+						case err != nil:
+							m.AddConversationPair("/save", "System: Failed to save session: "+err.Error())
+						case path == "":
+							m.AddConversationPair("/save", "System: Nothing to save yet")
+						default:
+							m.AddConversationPair("/save", "System: Session saved to "+path)
 						}
 						return m, nil
+					case "/speech":
+						reply, cmd := m.runSpeechCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/speech "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
 					case "/tools":
 						// Toggle tools globally - call via exported function
 						ToggleTools()
@@ -307,6 +628,123 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.AddConversationPair("/tools", "System: Tools disabled")
 						}
 						return m, nil
+					case "/voice":
+						m.AddConversationPair(strings.TrimSpace("/voice "+strings.Join(args, " ")), runVoiceCommand(args))
+						return m, nil
+					case "/undo":
+						m.AddConversationPair(strings.TrimSpace("/undo "+strings.Join(args, " ")), runUndoCommand(args))
+						return m, nil
+					case "/copy":
+						m.AddConversationPair(strings.TrimSpace("/copy "+strings.Join(args, " ")), m.runCopyCommand(args))
+						return m, nil
+					case "/template":
+						if reply := m.runTemplateCommand(args); reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/template "+strings.Join(args, " ")), reply)
+						}
+						return m, nil
+					case "/retry":
+						reply, cmd := m.runRetryCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/retry "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
+					case "/edit":
+						if reply := m.runEditCommand(args); reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/edit "+strings.Join(args, " ")), reply)
+						}
+						return m, nil
+					case "/delete":
+						m.AddConversationPair(strings.TrimSpace("/delete "+strings.Join(args, " ")), m.runDeleteCommand(args))
+						return m, nil
+					case "/compare":
+						reply, cmd := m.runCompareCommand(line)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace(line), reply)
+						}
+						return m, cmd
+					case "/expand":
+						if reply := m.runExpandCommand(args); reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/expand "+strings.Join(args, " ")), reply)
+						}
+						return m, nil
+					case "/export":
+						m.AddConversationPair(strings.TrimSpace("/export "+strings.Join(args, " ")), m.runExportCommand(args))
+						return m, nil
+					case "/checkpoint":
+						m.AddConversationPair(strings.TrimSpace("/checkpoint "+strings.Join(args, " ")), runCheckpointCommand(args))
+						return m, nil
+					case "/rollback":
+						m.AddConversationPair(strings.TrimSpace("/rollback "+strings.Join(args, " ")), m.runRollbackCommand(args))
+						return m, nil
+					case "/isolate":
+						m.AddConversationPair("/isolate", m.runIsolateCommand())
+						m.gitBranch = currentGitBranch()
+						return m, nil
+					case "/merge":
+						m.AddConversationPair(strings.TrimSpace("/merge "+strings.Join(args, " ")), m.runMergeCommand(args))
+						m.gitBranch = currentGitBranch()
+						return m, nil
+					case "/think":
+						m.AddConversationPair(strings.TrimSpace("/think "+strings.Join(args, " ")), m.runThinkCommand(args))
+						return m, nil
+					case "/dryrun":
+						m.AddConversationPair(strings.TrimSpace("/dryrun "+strings.Join(args, " ")), runDryRunCommand(args))
+						return m, nil
+					case "/plan":
+						reply, cmd := m.runPlanCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/plan "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
+					case "/todo":
+						m.AddConversationPair(strings.TrimSpace("/todo "+strings.Join(args, " ")), runTodoCommand(args))
+						return m, nil
+					case "/fork":
+						m.AddConversationPair(strings.TrimSpace("/fork "+strings.Join(args, " ")), m.runForkCommand(args))
+						return m, nil
+					case "/debug":
+						m.toggleDebugConsole()
+						return m, nil
+					case "/branches":
+						if reply := m.runBranchesCommand(args); reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/branches "+strings.Join(args, " ")), reply)
+						}
+						return m, nil
+					case "/pin":
+						m.AddConversationPair(strings.TrimSpace("/pin "+strings.Join(args, " ")), m.runPinCommand(args))
+						return m, nil
+					case "/unpin":
+						m.AddConversationPair(strings.TrimSpace("/unpin "+strings.Join(args, " ")), m.runUnpinCommand(args))
+						return m, nil
+					case "/pins":
+						m.AddConversationPair("/pins", m.runPinsCommand())
+						return m, nil
+					case "/notes":
+						m.AddConversationPair(strings.TrimSpace("/notes "+strings.Join(args, " ")), runNotesCommand(args))
+						return m, nil
+					case "/share":
+						if reply := m.runShareCommand(); reply != "" {
+							m.AddConversationPair("/share", reply)
+						}
+						return m, nil
+					case "/commit":
+						reply, cmd := m.runCommitCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/commit "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
+					case "/paste":
+						reply, cmd := m.runPasteCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/paste "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
+					case "/memory":
+						reply, cmd := runMemoryCommand(args)
+						if reply != "" {
+							m.AddConversationPair(strings.TrimSpace("/memory "+strings.Join(args, " ")), reply)
+						}
+						return m, cmd
 					}
 				}
 				return m, nil
@@ -317,9 +755,68 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			// For all other keys, continue to normal input processing
 		}
+		// Handle @ file picker navigation keys
+		if m.showingFileMentions {
+			if model, cmd, handled := m.handleFileMentionKey(msg); handled {
+				return model, cmd
+			}
+		}
+		// The abort key stops a running command; the model is told and carries on
+		if msg.String() == abortKey && m.turnRunning() && abortLiveCommand() {
+			return m, nil
+		}
+		// Esc cancels the running request, unless it leaves vim insert mode
+		if msg.Type == tea.KeyEsc && m.turnRunning() && m.textInput.VimMode() != "INSERT" {
+			m.cancelRequest()
+			return m, nil
+		}
+		// Esc abandons editing an earlier message, unless it leaves vim insert mode
+		if msg.Type == tea.KeyEsc && m.editing && m.textInput.VimMode() != "INSERT" {
+			m.cancelEdit()
+			return m, nil
+		}
+		// In vim normal mode letters are commands rather than text
+		if m.textInput.HandleVimKey(msg) {
+			m.ctrlCPressed = false
+			m.showExitPrompt = false
+			return m, nil
+		}
 
 		// Handle regular input
 		switch msg.String() {
+		case "ctrl+o":
+			m.showReasoning = !m.showReasoning
+			return m, nil
+		case "ctrl+t":
+			// The output of a running command folds on its own
+			if liveRunning() {
+				m.liveCollapsed = !m.liveCollapsed
+				return m, nil
+			}
+			m.toggleAllTools()
+			return m, nil
+		case "alt+r", "alt+e", "alt+d":
+			// Retry, edit or delete the last message when the input is empty
+			if m.textInput.Value() != "" {
+				break
+			}
+			var command, reply string
+			var cmd tea.Cmd
+			switch msg.String() {
+			case "alt+r":
+				command = "/retry"
+				reply, cmd = m.runRetryCommand(nil)
+			case "alt+e":
+				command = "/edit"
+				reply = m.runEditCommand(nil)
+			case "alt+d":
+				command = "/delete"
+				reply = m.runDeleteCommand(nil)
+			}
+			if reply != "" {
+				m.AddConversationPair(command, reply)
+			}
+			return m, cmd
 		case "ctrl+c":
 			if m.ctrlCPressed && time.Since(m.ctrlCTime) <= 2*time.Second {
 				// Second Ctrl+C within timeout window - exit
@@ -328,6 +825,7 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				// First Ctrl+C or timeout expired - clear input and show prompt
 				m.textInput.SetValue("")
+				m.editing = false
 				m.ctrlCPressed = true
 				m.ctrlCTime = time.Now()
 				m.showExitPrompt = true
@@ -362,12 +860,14 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Add message to conversation history
 				m.messages = append(m.messages, m.textInput.Value())
 				userMessage := m.textInput.Value()
-
-				// Add conversation pair with empty AI response initially
-				m.AddConversationPair(userMessage, "")
-
 				inputDebugLog("Message set to: '%s'", userMessage)
 
+				// An edited message replaces the exchange it came from and all later ones
+				if m.editing {
+					m.truncateConversation(m.editIndex)
+					m.editing = false
+				}
+
 				// Save to input history
 				if m.historyManager != nil {
 					m.historyManager.AddMessage(userMessage)
@@ -380,23 +880,20 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				// Clear the input for next message
 				m.textInput.SetValue("")
+				m.textInput.EnterInsertMode()
 				inputDebugLog("Input cleared, starting AI processing")
-
-				// Start async AI processing and spinner
-				return m, tea.Batch(
-					func() tea.Msg { return processingMsg{} },
-					processMessageAsync(userMessage, m.provider),
-					spinnerTickCmd(),
-				)
+				return m, m.send(userMessage)
 			}
 		case "up":
 			// Reset Ctrl+C state on any other action
 			m.ctrlCPressed = false
 			m.showExitPrompt = false
 
-			// Navigate to previous message in history
-			if m.historyManager != nil {
+			// Navigate to previous message in history; inside a multi-line message
+			// up moves the cursor until it reaches the first line
+			if m.historyManager != nil && m.textInput.OnFirstLine() {
 				m.navigateHistory(-1)
+				browsedHistory = true
 			}
 		case "down":
 			// Reset Ctrl+C state on any other action
@@ -404,8 +901,9 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.showExitPrompt = false
 
 			// Navigate to next message in history (only if in history mode)
-			if m.historyManager != nil && m.inHistoryMode {
+			if m.historyManager != nil && m.inHistoryMode && m.textInput.OnLastLine() {
 				m.navigateHistory(1)
+				browsedHistory = true
 			}
 		default:
 			// Reset Ctrl+C state on any other key press
@@ -430,7 +928,9 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	// Update text input first to allow continued typing
-	m.textInput, cmd = m.textInput.Update(msg)
+	if !browsedHistory {
+		m.textInput, cmd = m.textInput.Update(msg)
+	}
 
 	inputValue := m.textInput.Value()
 
@@ -452,6 +952,9 @@ func (m InputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.showingSlashCommands = false
 	}
 
+	// Show the file picker while the cursor is on an @ mention
+	m.updateFileMentions()
+
 	return m, cmd
 }
 
@@ -460,6 +963,21 @@ func (m InputModel) ShouldTriggerHelp() bool {
 	return m.triggerHelpScreen
 }
 
+// ShouldTriggerResume returns true if the session picker should be triggered
+func (m InputModel) ShouldTriggerResume() bool {
+	return m.triggerResume
+}
+
+// ShouldTriggerConfig returns true if the config editor should be triggered
+func (m InputModel) ShouldTriggerConfig() bool {
+	return m.triggerConfig
+}
+
+// ShouldTriggerStats returns true if the usage dashboard should be triggered
+func (m InputModel) ShouldTriggerStats() bool {
+	return m.triggerStats
+}
+
 // ShouldTriggerModelSelect returns true if model selection screen should be triggered
 func (m InputModel) ShouldTriggerModelSelect() bool {
 	return m.triggerModelSelect
@@ -471,6 +989,8 @@ func (m *InputModel) AddConversationPair(userMsg, aiResponse string) {
 		UserMessage:  userMsg,
 		AIResponse:   aiResponse,
 		IsProcessing: aiResponse == "", // If no AI response yet, it's processing
+		Model:        m.provider,
+		Time:         time.Now(),
 	})
 }
 
@@ -482,6 +1002,14 @@ func (m *InputModel) SetAIResponse(aiResponse string) {
 	}
 }
 
+// AppendAIResponse appends a streamed delta to the most recent conversation pair,
+// keeping it marked as processing until the stream completes
+func (m *InputModel) AppendAIResponse(delta string) {
+	if len(m.conversation) > 0 {
+		m.conversation[len(m.conversation)-1].AIResponse += delta
+	}
+}
+
 // formatSlashCommand formats a slash command with aligned description
 func formatSlashCommand(command, description string) string {
 	// Define the width for command alignment (like Claude Code)
@@ -584,7 +1112,7 @@ func (m *InputModel) exitHistoryMode() {
 }
 
 func (m InputModel) View() string {
-	if m.triggerHelpScreen || m.triggerModelSelect {
+	if m.triggerHelpScreen || m.triggerModelSelect || m.triggerResume || m.triggerConfig || m.triggerStats {
 		// Don't show anything when triggering help or model selection screen
 		return ""
 	}
@@ -594,41 +1122,89 @@ func (m InputModel) View() string {
 		s := "\n"
 		// Display all conversation pairs
 		if len(m.conversation) > 0 {
+			blockNum, toolNum := 0, 0
 			for _, pair := range m.conversation {
 				// Wrap user message
 				userMsg := wrapText(pair.UserMessage, m.width-6) // Account for "> " prefix and margins
 				s += messageStyle.Render(fmt.Sprintf("> %s", userMsg)) + "\n"
+				if pair.Route != "" {
+				s += codeHintStyle.Render("  auto → "+truncateCell(pair.Route, max(20, m.width-12))) + "\n"
+			}
+			s += m.renderToolCalls(pair.ToolCalls, m.width-4, &toolNum)
 
 				if pair.AIResponse != "" {
-					// Wrap AI response
-					aiMsg := wrapText(pair.AIResponse, m.width-6) // Account for "⏺ " prefix and margins
-					s += aiResponseStyle.Render(fmt.Sprintf("⏺ %s", aiMsg)) + "\n"
+					// Wrap AI response, highlighting its code blocks
+					s += renderResponse(pair.AIResponse, m.width-6, &blockNum) + "\n" // Account for "⏺ " prefix and margins
 				} else if pair.IsProcessing {
 					s += aiResponseStyle.Render("Processing interrupted...") + "\n"
 				}
 				s += "\n" // Blank line between exchanges
 			}
 		}
+		s += renderUsageSummary()
 		return s
 	}
 
+	// Tool approvals are shown over the debug console so they are not missed
+	if m.debugConsole != nil && m.pendingApproval == nil && m.pendingReview == nil {
+		footer := m.renderStatusBar() + "\n" + helpStyle.Render(debugConsoleHelp)
+		return m.renderDebugConsole(m.height-lipgloss.Height(footer)) + "\n" + footer
+	}
+
+	chrome := m.renderChrome()
+	t := m.transcript
+	t.layout(m.renderConversation(), m.width, m.height-lipgloss.Height(chrome))
+	return t.View() + "\n" + chrome
+}
+
+// renderConversation renders the exchanges of the session for the transcript viewport
+func (m InputModel) renderConversation() string {
 	s := ""
 
-	// Display conversation history (natural terminal flow)
+	// Display conversation history
 	if len(m.conversation) > 0 {
 		s += "\n"
-		// Display all conversation pairs
-		for _, pair := range m.conversation {
+		// Display all conversation pairs; code blocks are numbered for /copy and
+		// tool blocks for /expand
+		// Messages are numbered for /edit and /delete
+		blockNum, toolNum, msgNum := 0, 0, 0
+		for i, pair := range m.conversation {
 			// Wrap user message
 			userMsg := wrapText(pair.UserMessage, m.width-6) // Account for "> " prefix and margins
-			s += messageStyle.Render(fmt.Sprintf("> %s", userMsg)) + "\n"
+			s += messageStyle.Render(fmt.Sprintf("> %s", userMsg))
+			if isExchange(pair) {
+				msgNum++
+				label := fmt.Sprintf(" #%d", msgNum)
+				if pair.Pinned {
+					label += " (pinned)"
+				}
+				if m.editing && m.editIndex == i {
+					label += " (editing)"
+				}
+				s += codeHintStyle.Render(label)
+			}
+			s += "\n"
+			s += renderReasoning(pair, m.showReasoning, m.width)
+			s += m.renderToolCalls(pair.ToolCalls, m.width-4, &toolNum)
 
 			if pair.AIResponse != "" {
-				// Wrap AI response
-				aiMsg := wrapText(pair.AIResponse, m.width-6) // Account for "⏺ " prefix and margins
-				s += aiResponseStyle.Render(fmt.Sprintf("⏺ %s", aiMsg)) + "\n"
+				// Wrap AI response, highlighting its code blocks
+				s += renderResponse(pair.AIResponse, m.width-6, &blockNum) + "\n" // Account for "⏺ " prefix and margins
 			} else if pair.IsProcessing {
-				s += aiResponseStyle.Render(fmt.Sprintf("%s Processing...", spinnerChars[currentSpinnerIndex])) + "\n"
+				for _, progress := range pair.Progress {
+					s += helpDisplayStyle.Render("  "+wrapText(progress, m.width-8)) + "\n"
+				}
+				// A running command shows its elapsed time and output instead of the spinner
+				if running := renderLiveOutput(m.liveCollapsed, m.width-4); running != "" {
+					s += running
+					s += "\n"
+					continue
+				}
+				status := "Processing..."
+				if pair.Status != "" {
+					status = pair.Status + "..."
+				}
+				s += aiResponseStyle.Render(fmt.Sprintf("%s %s", spinnerChars[currentSpinnerIndex], status)) + "\n"
 			}
 			s += "\n" // Blank line between exchanges
 		}
@@ -636,6 +1212,34 @@ func (m InputModel) View() string {
 		s += "\n"
 	}
 
+	return strings.TrimSuffix(s, "\n")
+}
+
+// renderChrome renders everything below the transcript: prompts, the input box, menus,
+// the status bar and the help line
+func (m InputModel) renderChrome() string {
+	s := ""
+
+	// Ask for tool approval before showing the input box
+	if m.pendingReview != nil {
+		s += renderFileReview(m.pendingReview, m.reviewError) + "\n"
+	}
+	if m.pendingApproval != nil {
+		s += renderApprovalPrompt(m.pendingApproval, m.width) + "\n"
+	}
+	if m.pendingPaste != nil {
+		s += renderPastePrompt(m.pendingPaste, m.width) + "\n"
+	}
+	if m.pendingShare != nil {
+		s += renderSharePrompt(m.pendingShare, m.width) + "\n"
+	}
+	if m.pendingCommit != nil {
+		s += renderCommitPrompt(m.pendingCommit, m.width) + "\n"
+	}
+	if panel := renderTaskPanel(m.width); panel != "" {
+		s += panel + "\n"
+	}
+
 	// Add border around text input with dynamic width
 	// Calculate exact width to prevent double borders
 	availableWidth := max(20, m.width-4) // Account for border chars and margins
@@ -649,8 +1253,14 @@ func (m InputModel) View() string {
 	s += inputWithBorder
 	s += "\n"
 
-	// Show slash command menu if active
-	if m.showingSlashCommands && len(m.filteredCommands) > 0 {
+	// Show the history search, slash command menu or file picker if active
+	if m.historySearch != nil {
+		s += m.renderHistorySearch()
+	} else if m.templatePicker != nil {
+		s += m.renderTemplatePicker()
+	} else if m.branchPicker != nil {
+		s += m.renderBranchPicker()
+	} else if m.showingSlashCommands && len(m.filteredCommands) > 0 {
 		s += "\n"
 		for i, command := range m.filteredCommands {
 			if i == m.slashCommandCursor {
@@ -666,40 +1276,45 @@ func (m InputModel) View() string {
 			}
 		}
 		s += "\n"
+	} else if m.showingFileMentions {
+		s += m.renderFileMentions()
 	}
 
 	s += "\n"
 
-	// Show specific model name based on provider with speech mode indicator
-	modelName := GetModelDisplayName(m.provider)
-
-	speechIndicator := ""
-	if SpeechModeEnabled() {
-		speechIndicator = " " + speechModeOnStyle.Render("•") + " " + modelRunningStyle.Render("speech-to-text on")
-	} else {
-		speechIndicator = " " + speechModeOffStyle.Render("•") + " " + modelRunningStyle.Render("speech-to-text off")
-	}
-
-	// Tools indicator (reuse the same color styles)
-	toolsIndicator := ""
-	if GetToolsEnabled() {
-		toolsIndicator = " " + speechModeOnStyle.Render("•") + " " + modelRunningStyle.Render("tools on")
-	} else {
-		toolsIndicator = " " + speechModeOffStyle.Render("•") + " " + modelRunningStyle.Render("tools off")
-	}
-
-	s += modelRunningStyle.Render("• "+modelName) + speechIndicator + toolsIndicator
+	// Status bar: model, context window, cost, git branch and toggles
+	s += m.renderStatusBar()
 	s += "\n"
 
 	// Show help text or exit prompt
 	if m.showExitPrompt {
 		s += exitPromptStyle.Render("Press Ctrl+C again to exit")
+	} else if m.historySearch != nil {
+		s += helpStyle.Render("type to search • ctrl+r/↑: older • ↓: newer • enter: insert • esc: cancel")
+	} else if m.templatePicker != nil && m.templatePicker.selected != nil {
+		s += helpStyle.Render("enter: next (empty uses the default) • esc: cancel")
+	} else if m.templatePicker != nil {
+		s += helpStyle.Render("type to filter • ↑/↓: navigate • enter: select • esc: cancel")
+	} else if m.branchPicker != nil {
+		s += helpStyle.Render("↑/↓: navigate • enter: switch branch • esc: cancel")
 	} else if m.showingSlashCommands {
 		s += helpStyle.Render("↑/↓: navigate • enter: select • esc: cancel")
+	} else if m.showingFileMentions {
+		s += helpStyle.Render("↑/↓: navigate • tab/enter: insert file • esc: cancel")
+	} else if m.editing {
+		s += helpStyle.Render("enter: send the edited message and answer again from there • esc: cancel edit")
+	} else if m.transcript.scrolledUp {
+		s += helpStyle.Render("pgup/pgdn/wheel: scroll • ctrl+end: jump to latest")
+	} else if m.turnRunning() && m.pendingApproval == nil && m.pendingReview == nil && liveRunning() {
+		s += helpStyle.Render(abortKey + ": abort command • esc: cancel request • ctrl+t: fold output • ctrl+c: clear")
+	} else if m.turnRunning() && m.pendingApproval == nil && m.pendingReview == nil {
+		s += helpStyle.Render("esc: cancel request • ctrl+o: reasoning • ctrl+t: tool output • ctrl+c: clear")
+	} else if PlanModeEnabled() && !m.turnRunning() {
+		s += helpStyle.Render("plan mode: describe or refine the task • /plan run: execute the plan • /plan off: leave plan mode")
 	} else if m.inHistoryMode && m.historyManager != nil {
 		s += helpStyle.Render("↑/↓: navigate • any key: exit history • ctrl+c: clear")
 	} else {
-		s += helpStyle.Render("↑/↓: history • /: commands • ctrl+c: clear")
+		s += helpStyle.Render("↑/↓: history • ctrl+r: search • alt+enter/ctrl+j: newline • /: commands • @: files • ctrl+o: reasoning • ctrl+t: tool output • ctrl+c: clear")
 	}
 	s += "\n"
 
@@ -771,166 +1386,295 @@ func init() {
 	}
 }
 
-// processMessageAsync processes a user message with the AI provider asynchronously
-func processMessageAsync(userMessage, provider string) tea.Cmd {
-	return func() tea.Msg {
-		// Get provider instance
-		p, err := orchestration.ProviderFor(provider)
-		if err != nil {
-			return aiResponseMsg{
-				response: fmt.Sprintf("Error getting provider: %v", err),
-				isError:  true,
+// historyPairs returns the indices of conversation pairs from index from onwards that are
+// sent as history, keeping at most maxPairs of the most recent exchanges. Pinned exchanges
+// are always kept, wherever they are. Slash commands and errored exchanges are skipped.
+func historyPairs(conversation []ConversationPair, from, maxPairs int) []int {
+	var eligible []int
+	kept := 0
+	for i := len(conversation) - 1; i >= 0; i-- {
+		pair := conversation[i]
+		if pair.IsProcessing || pair.IsError || pair.AIResponse == "" {
+			continue
+		}
+		if strings.HasPrefix(pair.UserMessage, "/") {
+			continue
+		}
+		if !pair.Pinned {
+			if i < from || (maxPairs > 0 && kept >= maxPairs) {
+				continue
 			}
+			kept++
 		}
+		eligible = append(eligible, i)
+	}
+	slices.Reverse(eligible)
+	return eligible
+}
 
-		// Load system prompt – prefer value from loaded config.yaml
-		sysPrompt := "You are a helpful coding assistant."
-		if globalConfig != nil && globalConfig.System != "" {
-			sysPrompt = globalConfig.System
+// buildHistory converts the given conversation pairs into chat messages
+func buildHistory(conversation []ConversationPair, indices []int) []providers.ChatMessage {
+	history := make([]providers.ChatMessage, 0, len(indices)*2)
+	for _, i := range indices {
+		answer := conversation[i].AIResponse
+		if calls := conversation[i].ToolCalls; len(calls) > 0 {
+			answer = formatToolCalls(calls) + "\n" + answer
 		}
+		history = append(history,
+			providers.ChatMessage{Role: providers.RoleUser, Content: conversation[i].UserMessage},
+			providers.ChatMessage{Role: providers.RoleAssistant, Content: answer},
+		)
+	}
+	return history
+}
+
+// history returns the messages sent with the next turn: the pinned files and the summary
+// of compressed exchanges, if any, followed by the pinned and recent exchanges
+func (m *InputModel) history() []providers.ChatMessage {
+	history := buildHistory(m.conversation, historyPairs(m.conversation, m.summaryUpTo, GetMaxHistory()))
+	if m.contextSummary != "" {
+		history = append(convctx.SummaryMessages(m.contextSummary), history...)
+	}
+	return append(pinnedFileMessages(m.pinnedFiles), history...)
+}
 
-		inputDebugLog("System prompt used: %s", sysPrompt)
+// compressibleHistory is the part of history that compression may fold into the
+// summary: everything but the pinned files and exchanges
+func (m *InputModel) compressibleHistory() []providers.ChatMessage {
+	indices := historyPairs(m.conversation, m.summaryUpTo, GetMaxHistory())
+	history := buildHistory(m.conversation, unpinnedPairs(m.conversation, indices))
+	if m.contextSummary != "" {
+		history = append(convctx.SummaryMessages(m.contextSummary), history...)
+	}
+	return history
+}
 
-		// Build messages
-		messages := []providers.ChatMessage{
-			{Role: providers.RoleSystem, Content: sysPrompt},
-			{Role: providers.RoleUser, Content: userMessage},
-		}
+// send adds userMessage to the conversation and starts answering it with the current
+// model, showing the newest output
+func (m *InputModel) send(userMessage string) tea.Cmd {
+	// Capture prior exchanges before adding the new pair so the model has memory
+	history := m.history()
+
+	// Add conversation pair with empty AI response initially
+	m.AddConversationPair(userMessage, "")
+	m.transcript.scrolledUp = false
+
+	// Send the contents of @-mentioned files along with the message
+	prompt, attached := attachMentionedFiles(userMessage)
+	if m.pastedClipboard != "" {
+		prompt += clipboardBlock(m.pastedClipboard)
+		lines := strings.Count(strings.TrimRight(m.pastedClipboard, "\n"), "\n") + 1
+		attached = append(attached, fmt.Sprintf("clipboard (%d lines)", lines))
+		m.pastedClipboard = ""
+	}
+	if len(attached) > 0 {
+		last := &m.conversation[len(m.conversation)-1]
+		last.Progress = append(last.Progress, "Attached "+strings.Join(attached, ", "))
+	}
 
-		// Get tools if enabled
-		var providerTools []providers.Tool
-		if GetToolsEnabled() {
-			allTools := tools.GetAllTools()
-			providerTools = make([]providers.Tool, len(allTools))
-			for i, tool := range allTools {
-				providerTools[i] = providers.Tool{
-					Name:        tool.Name,
-					Description: tool.Description,
-					InputSchema: tool.InputSchema,
-				}
-			}
-		} else {
-			// Always expose core tools even when general tools are disabled
-			core := tools.GetCoreTools()
-			providerTools = make([]providers.Tool, len(core))
-			for i, tool := range core {
-				providerTools[i] = providers.Tool{
-					Name:        tool.Name,
-					Description: tool.Description,
-					InputSchema: tool.InputSchema,
-				}
-			}
-		}
+	// Summarise older exchanges first when the history nears the context window
+	ctx := m.beginTurn()
+	if needsCompression(m.provider, history, prompt) {
+		m.conversation[len(m.conversation)-1].Status = "Compressing conversation history"
+		return tea.Batch(
+			compressHistoryAsync(ctx, prompt, m.provider, history, m.compressibleHistory()),
+			spinnerTickCmd(),
+		)
+	}
 
-		// update global current model for query tools
-		SetCurrentModel(provider)
+	// Start async AI processing (streamed when possible) and spinner
+	return tea.Batch(
+		func() tea.Msg { return processingMsg{} },
+		m.startProcessing(prompt, history),
+		spinnerTickCmd(),
+	)
+}
 
-		// Call the provider
-		assistantMsgs, toolCalls, err := p.Chat(context.Background(), messages, providerTools)
+// startProcessing sends userMessage with history to the current model, streamed when
+// possible. The request runs in the context from beginTurn.
+func (m *InputModel) startProcessing(userMessage string, history []providers.ChatMessage) tea.Cmd {
+	ctx, model := m.turnCtx, m.provider
+	send := processMessageAsync
+	if shouldStream(model) {
+		send = streamMessageAsync
+	}
+	ix, k := index.Default(), GetAutoContext()
+	if ix == nil || k <= 0 {
+		return send(ctx, userMessage, model, history)
+	}
+	return func() tea.Msg {
+		// Retrieval embeds the prompt, so it runs here rather than in Update
+		prompt, found, err := ix.Augment(ctx, userMessage, k)
 		if err != nil {
-			return aiResponseMsg{
-				response: fmt.Sprintf("Chat error: %v", err),
-				isError:  true,
-			}
+			inputDebugLog("Project index retrieval failed: %v", err)
+		} else {
+			inputDebugLog("Added %d snippets from the project index", len(found))
 		}
+		return send(ctx, prompt, model, history)()
+	}
+}
 
-		// If tools requested, execute them
-		if len(toolCalls) > 0 {
-			var results []providers.ToolResult
-			var used []string
-			for _, call := range toolCalls {
-				def, ok := tools.GetToolByName(call.Name)
-				if !ok {
-					results = append(results, providers.ToolResult{ID: call.ID, Content: "tool not found", IsError: true})
-					continue
-				}
-				// parse input json
-				var inputMap map[string]interface{}
-				_ = json.Unmarshal(call.Input, &inputMap)
-				res, _ := def.Function(context.Background(), inputMap)
-				res.ID = call.ID
-				results = append(results, *res)
-
-				// Build display name with parameters, truncate if too long
-				paramPreview := ""
-				if len(inputMap) > 0 {
-					if b, err := json.Marshal(inputMap); err == nil {
-						s := string(b)
-						if len(s) > 60 {
-							s = s[:57] + "..."
-						}
-						paramPreview = "(" + s + ")"
-					}
-				}
-				used = append(used, call.Name+paramPreview)
-			}
+// processMessageAsync processes a user message with the AI provider asynchronously.
+// history holds earlier exchanges of the session so follow-up questions keep their context.
+// Cancelling ctx aborts the model call and any running tool.
+func processMessageAsync(ctx context.Context, userMessage, provider string, history []providers.ChatMessage) tea.Cmd {
+	return func() tea.Msg {
+		// Run the turn in the background so it can pause for tool approval; intermediate
+		// events and the final aiResponseMsg are delivered through the events channel.
+		events := make(chan tea.Msg)
+		go func() {
+			defer close(events)
+			sendTurnEvent(ctx, events, runTurn(ctx, userMessage, provider, history, events))
+		}()
+		return waitForTurnEvent(events)()
+	}
+}
 
-			assistantMsgs, _, err = p.SendToolResult(context.Background(), append(messages, assistantMsgs...), results)
-			if err != nil {
-				return aiResponseMsg{response: fmt.Sprintf("Tool result error: %v", err), isError: true}
-			}
-			// Build summary line always
-			summary := fmt.Sprintf("[Used tools: %s]", strings.Join(used, ", "))
-
-			content := summary
-
-			if GetToolsOutputEnabled() {
-				// Build tool outputs string
-				var toolOutputs []string
-				for _, r := range results {
-					prefix := ""
-					if r.IsError {
-						prefix = "(tool error) "
-					} else {
-						prefix = "(tool result) "
-					}
-					// Ensure multi-line content is indented nicely
-					lines := strings.Split(strings.TrimSpace(r.Content), "\n")
-					for i, l := range lines {
-						if i == 0 {
-							toolOutputs = append(toolOutputs, prefix+l)
-						} else {
-							toolOutputs = append(toolOutputs, "              "+l)
-						}
-					}
-				}
+// runTurn sends the user message to the provider, executes any requested tools and
+// returns the final aiResponseMsg
+func runTurn(ctx context.Context, userMessage, provider string, history []providers.ChatMessage, events chan tea.Msg) tea.Msg {
+	// Load system prompt – prefer value from loaded config.yaml
+	sysPrompt := systemPrompt()
 
-				// Trim overly long outputs for better UI experience
-				if len(toolOutputs) > maxToolOutputLines {
-					trimmed := toolOutputs[:maxToolOutputLines]
-					trimmed = append(trimmed, fmt.Sprintf("... (%d more lines truncated)", len(toolOutputs)-maxToolOutputLines))
-					toolOutputs = trimmed
-				}
-				combined := strings.Join(toolOutputs, "\n")
-				if len(combined) > maxToolOutputChars {
-					combined = combined[:maxToolOutputChars] + "\n... (output truncated)"
-				}
+	inputDebugLog("System prompt used: %s", sysPrompt)
 
-				content = summary + "\n" + combined
+	// update global current model for query tools
+	SetCurrentModel(provider)
+
+	// File-modifying tools show their diff for review before writing
+	ctx = fsedit.WithReviewer(ctx, reviewFileChanges(events))
+	ctx = providers.WithReasoningRecorder(ctx, func(delta string) {
+		sendTurnEvent(ctx, events, turnReasoningMsg{delta: delta, events: events})
+	})
+	// Commands show their output while they run
+	ctx = tools.WithLiveOutput(ctx, liveOutput())
+
+	// The working tree is checkpointed once, before the first tool that may change it;
+	// a dry run changes nothing
+	dryRun := DryRunEnabled()
+	checkpointed := !GetAutoCheckpoints() || dryRun
+
+	result, err := orchestration.RunTurn(ctx, orchestration.Turn{
+		Model:   provider,
+		System:  sysPrompt,
+		History: history,
+		Message: userMessage,
+		Tools:   availableTools(),
+		Policy:  globalPolicy,
+		Approve: func(name string, input map[string]interface{}) bool {
+			// Reviewed tools are confirmed on their diff instead
+			if fsedit.ReviewedTools[name] {
+				return true
+			}
+			// Ask the user before running tools that are not auto-approved
+			return approveToolCall(ctx, events, name, input)
+		},
+		MaxIterations:    GetMaxToolIterations(),
+		DryRun:           dryRun,
+		Thinking:         sessionThinking(),
+		SubAgentThinking: backgroundThinking(),
+		OnRound: func(round int, calls []orchestration.ToolCall) {
+			sendTurnEvent(ctx, events, turnProgressMsg{
+				progress: fmt.Sprintf("Round %d: %s", round, summarizeToolCalls(calls)),
+				events:   events,
+			})
+		},
+		OnRetry: func(status orchestration.RetryStatus) {
+			inputDebugLog("Retrying after error: %v", status.Err)
+			sendTurnEvent(ctx, events, turnStatusMsg{status: status.String(), events: events})
+		},
+		Fallbacks: GetFallbackModels(),
+		OnFailover: func(f orchestration.Failover) {
+			inputDebugLog("Failing over after error: %v", f.Err)
+			sendTurnEvent(ctx, events, turnStatusMsg{status: f.String(), events: events})
+		},
+		OnText: streamTurnText(ctx, provider, events),
+		BeforeTool: func(name string) {
+			// The streamed reply gives way to the progress of the tools it called
+			sendTurnEvent(ctx, events, turnTextMsg{reset: true, events: events})
+			if checkpointed || slices.Contains(orchestration.ReadOnlyTools, name) {
+				return
+			}
+			checkpointed = true
+			if progress := autoCheckpoint(ctx, userMessage); progress != "" {
+				sendTurnEvent(ctx, events, turnProgressMsg{progress: progress, events: events})
 			}
+		},
+		OnRoute: func(r orchestration.Route) {
+			inputDebugLog("Routed to %s", r)
+			SetCurrentModel(r.Model)
+			sendTurnEvent(ctx, events, turnStatusMsg{status: "Routed to " + r.String(), events: events})
+		},
+	})
+	if err != nil {
+		return aiResponseMsg{response: err.Error(), isError: true}
+	}
 
-			assistantMsgs = append([]providers.ChatMessage{{Role: providers.RoleAssistant, Content: content}}, assistantMsgs...)
+	response := result.Text()
+	if result.HitLimit {
+		response = fmt.Sprintf("[Stopped after %d tool rounds]\n", result.Rounds) + response
+	}
+	route := ""
+	if result.Route != nil {
+		provider, route = result.Route.Model, result.Route.String()
+	}
+	if result.Model != provider {
+		response = fmt.Sprintf("[Answered by %s: %s was unavailable]\n", result.Model, provider) + response
+	}
+
+	return aiResponseMsg{response: strings.TrimRight(response, "\n"), isError: false, toolCalls: result.ToolCalls, model: result.Model, route: route}
+}
+
+// availableTools returns the tools offered to the model: everything when tools are
+// enabled, otherwise only the core tools
+func availableTools() []providers.ToolDefinition {
+	// Plan mode only looks around
+	if PlanModeEnabled() {
+		return orchestration.SelectTools(tools.GetAllTools(), orchestration.ReadOnlyTools)
+	}
+	if GetToolsEnabled() {
+		return tools.GetAllTools()
+	}
+	// Always expose core tools even when general tools are disabled
+	return tools.GetCoreTools()
+}
+
+// summarizeToolCalls lists tool names with a short parameter preview
+func summarizeToolCalls(calls []orchestration.ToolCall) string {
+	var used []string
+	for _, call := range calls {
+		if call.Denied {
+			used = append(used, call.Name+" (denied)")
+			continue
 		}
 
-		// Combine assistant messages into a single response
-		var responseText strings.Builder
-		for _, msg := range assistantMsgs {
-			if msg.Content != "" {
-				if responseText.Len() > 0 {
-					responseText.WriteString("\n")
+		// Build display name with parameters, truncate if too long
+		paramPreview := ""
+		if len(call.Input) > 0 {
+			if b, err := json.Marshal(call.Input); err == nil {
+				s := string(b)
+				if len(s) > 60 {
+					s = s[:57] + "..."
 				}
-				responseText.WriteString(msg.Content)
+				paramPreview = "(" + s + ")"
 			}
 		}
-
-		return aiResponseMsg{response: responseText.String(), isError: false}
+		used = append(used, call.Name+paramPreview)
 	}
+	return strings.Join(used, ", ")
+}
+
+// formatToolCalls builds the "[Used tools: ...]" summary that precedes an answer in
+// the history, so the model remembers which tools it ran; their output is shown in the
+// transcript as tool blocks
+func formatToolCalls(calls []orchestration.ToolCall) string {
+	return fmt.Sprintf("[Used tools: %s]", summarizeToolCalls(calls))
 }
 
 // Feature toggle: disable text beautification (colors/wrapping) when MAGIKARP_PLAIN=1
 var disableBeautify = os.Getenv("MAGIKARP_PLAIN") == "1"
 
 const (
-	maxToolOutputLines = 40   // show at most 40 lines from any combined tool output
-	maxToolOutputChars = 4000 // and at most 4000 characters overall
+	maxToolOutputLines = 200  // show at most 200 lines of an expanded tool block
+	maxToolOutputChars = 4000 // show at most 4000 characters of tool parameters for approval
 )
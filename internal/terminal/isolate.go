@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/worktree"
+)
+
+// maxMergeDiffLines is how much of the combined diff /merge shows in the transcript
+const maxMergeDiffLines = 400
+
+// runIsolateCommand starts an isolated run (/isolate): a worktree on a new branch becomes
+// the working directory, so the agent's edits stay out of the user's checkout
+func (m *InputModel) runIsolateCommand() string {
+	if m.worktree != nil {
+		return fmt.Sprintf("System: Already isolated on branch %s in %s; /merge reviews the changes", m.worktree.Branch, m.worktree.Path)
+	}
+	if m.turnRunning() {
+		return busyReply
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "System: Cannot start an isolated run: " + err.Error()
+	}
+	w, err := worktree.Create(context.Background(), cwd)
+	if err != nil {
+		return "System: Cannot start an isolated run: " + err.Error()
+	}
+	if err := os.Chdir(w.Dir(cwd)); err != nil {
+		_ = w.Remove(context.Background())
+		return "System: Cannot start an isolated run: " + err.Error()
+	}
+	m.worktree, m.worktreeOrigin = w, cwd
+	return fmt.Sprintf("System: Isolated run started on branch %s in %s. Edits stay there until you apply them: "+
+		"/merge shows the combined diff, /merge apply copies it into your checkout and /merge discard drops it. "+
+		"Uncommitted changes in your checkout are not part of the run.", w.Branch, w.Path)
+}
+
+// runMergeCommand reviews and ends an isolated run: /merge shows the combined diff,
+// /merge apply applies it to the user's checkout and /merge discard drops it
+func (m *InputModel) runMergeCommand(args []string) string {
+	if m.worktree == nil {
+		return "System: No isolated run; /isolate starts one"
+	}
+	if m.turnRunning() {
+		return busyReply
+	}
+	ctx := context.Background()
+	action := "diff"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+
+	switch action {
+	case "diff":
+		diff, stat, err := m.worktree.Diff(ctx)
+		if err != nil {
+			return "System: Cannot diff the isolated run: " + err.Error()
+		}
+		if strings.TrimSpace(diff) == "" {
+			return "System: The isolated run has no changes yet; /merge discard ends it"
+		}
+		lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+		more := ""
+		if len(lines) > maxMergeDiffLines {
+			more = fmt.Sprintf("\n… %d more lines (git diff %s %s in %s)", len(lines)-maxMergeDiffLines,
+				m.worktree.Base[:min(12, len(m.worktree.Base))], m.worktree.Branch, m.worktree.Origin)
+			lines = lines[:maxMergeDiffLines]
+		}
+		return fmt.Sprintf("System: Changes on %s:\n%s\n```diff\n%s\n```%s\n/merge apply copies them into your checkout; /merge discard drops them",
+			m.worktree.Branch, strings.TrimRight(stat, "\n"), strings.Join(lines, "\n"), more)
+	case "apply":
+		if err := m.worktree.Apply(ctx); err != nil {
+			return "System: Cannot apply the isolated run: " + err.Error()
+		}
+		branch := m.worktree.Branch
+		if err := m.leaveWorktree(ctx); err != nil {
+			return fmt.Sprintf("System: Applied the changes of %s to your checkout, but removing the worktree failed: %v", branch, err)
+		}
+		return fmt.Sprintf("System: Applied the changes of %s to your checkout (uncommitted) and removed the worktree", branch)
+	case "discard":
+		branch := m.worktree.Branch
+		if err := m.leaveWorktree(ctx); err != nil {
+			return "System: Cannot remove the worktree: " + err.Error()
+		}
+		return fmt.Sprintf("System: Discarded the isolated run and branch %s", branch)
+	}
+	return "System: Usage: /merge [diff|apply|discard]"
+}
+
+// leaveWorktree returns to the user's checkout and removes the worktree of the run
+func (m *InputModel) leaveWorktree(ctx context.Context) error {
+	if err := os.Chdir(m.worktreeOrigin); err != nil {
+		return err
+	}
+	w := m.worktree
+	m.worktree, m.worktreeOrigin = nil, ""
+	return w.Remove(ctx)
+}
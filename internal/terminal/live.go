@@ -0,0 +1,110 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+// liveLines is how many of the latest output lines of a running command are shown
+const liveLines = 10
+
+// abortKey stops the running command; the model is told and carries on
+const abortKey = "ctrl+x"
+
+// live is the command a tool is running, whose output is shown as it arrives. Tools
+// write to it from their own goroutine and the spinner tick redraws it.
+var live struct {
+	sync.Mutex
+	running bool
+	tool    string
+	started time.Time
+	lines   []string
+	total   int
+	abort   func()
+}
+
+// liveOutput follows the commands of a turn in the transcript
+func liveOutput() tools.LiveOutput {
+	return tools.LiveOutput{
+		Start: func(tool string, abort func()) {
+			live.Lock()
+			defer live.Unlock()
+			live.running, live.tool, live.started = true, tool, time.Now()
+			live.lines, live.total, live.abort = nil, 0, abort
+		},
+		Line: func(line string) {
+			live.Lock()
+			defer live.Unlock()
+			live.total++
+			live.lines = append(live.lines, line)
+			if len(live.lines) > liveLines {
+				live.lines = live.lines[len(live.lines)-liveLines:]
+			}
+		},
+		Done: stopLiveOutput,
+	}
+}
+
+// stopLiveOutput clears the running command, e.g. when it finished or the turn ended
+func stopLiveOutput() {
+	live.Lock()
+	defer live.Unlock()
+	live.running, live.abort, live.lines, live.total = false, nil, nil, 0
+}
+
+// liveRunning reports whether a tool is running a command
+func liveRunning() bool {
+	live.Lock()
+	defer live.Unlock()
+	return live.running
+}
+
+// abortLiveCommand stops the running command and reports whether there was one
+func abortLiveCommand() bool {
+	live.Lock()
+	abort := live.abort
+	live.abort = nil
+	live.Unlock()
+	if abort == nil {
+		return false
+	}
+	abort()
+	return true
+}
+
+// renderLiveOutput renders the running command with its elapsed time and, unless
+// collapsed, its latest output; "" when no command runs
+func renderLiveOutput(collapsed bool, width int) string {
+	live.Lock()
+	defer live.Unlock()
+	if !live.running {
+		return ""
+	}
+	marker := "▼"
+	if collapsed {
+		marker = "▶"
+	}
+	elapsed := time.Since(live.started).Truncate(time.Second)
+	header := fmt.Sprintf("  %s %s running for %s — %d lines", marker, live.tool, elapsed, live.total)
+	if live.abort == nil {
+		header += " (aborting)"
+	}
+
+	var b strings.Builder
+	b.WriteString(toolHeaderStyle.Render(truncateCell(header, max(20, width))) + "\n")
+	if collapsed {
+		return b.String()
+	}
+	if hidden := live.total - len(live.lines); hidden > 0 {
+		b.WriteString(helpDisplayStyle.Render(fmt.Sprintf("    … %d earlier lines", hidden)) + "\n")
+	}
+	for _, line := range live.lines {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		b.WriteString(toolOutputStyle.Render("    │ "+truncateCell(line, max(10, width-6))) + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// maxMemoryLines bounds how much of the instruction file /memory prints
+const maxMemoryLines = 60
+
+// memoryEditedMsg is sent when the editor opened by "/memory edit" exits
+type memoryEditedMsg struct {
+	path string
+	err  error
+}
+
+// runMemoryCommand handles "/memory", which shows the project instructions, and
+// "/memory edit", which opens them in the user's editor. It returns the system reply
+// to show, or a command when the editor is opened.
+func runMemoryCommand(args []string) (string, tea.Cmd) {
+	path := ""
+	if globalConfig != nil {
+		path = globalConfig.InstructionsPath
+	}
+
+	if len(args) > 0 && args[0] == "edit" {
+		if path == "" {
+			// Start a new instruction file in the working directory
+			path = config.InstructionFiles[0]
+		}
+		args := strings.Fields(editorCommand())
+		cmd := exec.Command(args[0], append(args[1:], path)...)
+		return "", tea.ExecProcess(cmd, func(err error) tea.Msg {
+			return memoryEditedMsg{path: path, err: err}
+		})
+	}
+	if len(args) > 0 {
+		return "System: Usage: /memory [edit]", nil
+	}
+
+	if path == "" {
+		return fmt.Sprintf("System: No project instructions found. Create %s with /memory edit; it is added to the system prompt.", config.InstructionFiles[0]), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "System: Failed to read instructions: " + err.Error(), nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	more := ""
+	if len(lines) > maxMemoryLines {
+		more = fmt.Sprintf("\n... %d more lines (/memory edit to view all)", len(lines)-maxMemoryLines)
+		lines = lines[:maxMemoryLines]
+	}
+	return fmt.Sprintf("System: Project instructions from %s:\n%s%s", displayPath(path), strings.Join(lines, "\n"), more), nil
+}
+
+// handleMemoryEdited reloads the project instructions into the system prompt after editing
+func (m InputModel) handleMemoryEdited(msg memoryEditedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.AddConversationPair("/memory edit", "System: Editor failed: "+msg.err.Error())
+		return m, nil
+	}
+	if globalConfig == nil {
+		return m, nil
+	}
+	if err := globalConfig.LoadInstructions(); err != nil {
+		m.AddConversationPair("/memory edit", "System: Failed to reload instructions: "+err.Error())
+		return m, nil
+	}
+	if globalConfig.InstructionsPath == "" {
+		m.AddConversationPair("/memory edit", "System: No instructions saved")
+		return m, nil
+	}
+	m.AddConversationPair("/memory edit", "System: Reloaded project instructions from "+displayPath(globalConfig.InstructionsPath))
+	return m, nil
+}
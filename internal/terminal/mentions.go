@@ -0,0 +1,314 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/context/loader"
+)
+
+const (
+	// maxMentionFiles bounds how many files are indexed for the @ picker
+	maxMentionFiles = 5000
+	// maxMentionResults is the number of matches shown in the picker
+	maxMentionResults = 8
+	// maxAttachmentBytes is the largest file attached to a prompt in full; larger
+	// files and PDF and Word documents go through the document loader
+	maxAttachmentBytes = 100 * 1024
+	// defaultDocumentTokens is the token budget of a document unless context.document_tokens is set
+	defaultDocumentTokens = 8000
+	// fileIndexTTL controls how long the working tree listing is reused
+	fileIndexTTL = 10 * time.Second
+)
+
+// skippedDirs are never indexed for @ mentions
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"__pycache__":  true,
+}
+
+var fileIndex struct {
+	sync.Mutex
+	files   []string
+	builtAt time.Time
+}
+
+// workspaceFiles lists files under the working directory relative to it, skipping hidden
+// and dependency directories. The listing is cached for fileIndexTTL.
+func workspaceFiles() []string {
+	fileIndex.Lock()
+	defer fileIndex.Unlock()
+
+	if fileIndex.files != nil && time.Since(fileIndex.builtAt) < fileIndexTTL {
+		return fileIndex.files
+	}
+
+	var files []string
+	_ = filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != "." && (skippedDirs[name] || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(path))
+		if len(files) >= maxMentionFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+
+	fileIndex.files = files
+	fileIndex.builtAt = time.Now()
+	return files
+}
+
+// fuzzyScore scores how well query matches path as a case-insensitive subsequence.
+// Consecutive matches and matches in the file name score higher; ok is false when
+// query is not a subsequence of path.
+func fuzzyScore(query, path string) (score int, ok bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := strings.ToLower(query)
+	p := strings.ToLower(path)
+	base := strings.LastIndex(p, "/") + 1
+
+	qi := 0
+	prev := -2
+	for pi := 0; pi < len(p) && qi < len(q); pi++ {
+		if p[pi] != q[qi] {
+			continue
+		}
+		score++
+		if pi == prev+1 {
+			score += 3
+		}
+		if pi >= base {
+			score += 2
+		}
+		if pi == 0 || p[pi-1] == '/' || p[pi-1] == '_' || p[pi-1] == '-' || p[pi-1] == '.' {
+			score += 2
+		}
+		prev = pi
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	// Prefer shorter paths when scores tie
+	return score*100 - len(p), true
+}
+
+// FilterFiles returns the files best matching query, best first
+func FilterFiles(query string, files []string) []string {
+	type match struct {
+		path  string
+		score int
+	}
+	var matches []match
+	for _, f := range files {
+		if score, ok := fuzzyScore(query, f); ok {
+			matches = append(matches, match{f, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	if len(matches) > maxMentionResults {
+		matches = matches[:maxMentionResults]
+	}
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.path
+	}
+	return result
+}
+
+// currentMention returns the @ token ending at the cursor and the rune index where it
+// starts, or ok=false when the cursor is not inside a mention
+func currentMention(value string, cursor int) (query string, start int, ok bool) {
+	runes := []rune(value)
+	if cursor > len(runes) {
+		cursor = len(runes)
+	}
+	before := string(runes[:cursor])
+	token := before[strings.LastIndexAny(before, " \t")+1:]
+	if !strings.HasPrefix(token, "@") {
+		return "", 0, false
+	}
+	return token[1:], cursor - utf8.RuneCountInString(token), true
+}
+
+// updateFileMentions shows, filters or hides the @ file picker for the current input
+func (m *InputModel) updateFileMentions() {
+	query, _, ok := currentMention(m.textInput.Value(), m.textInput.Position())
+	if !ok || m.showingSlashCommands {
+		m.showingFileMentions = false
+		return
+	}
+	m.showingFileMentions = true
+	m.fileMentionMatches = FilterFiles(query, workspaceFiles())
+	if m.fileMentionCursor >= len(m.fileMentionMatches) {
+		m.fileMentionCursor = 0
+	}
+}
+
+// handleFileMentionKey handles navigation and selection while the @ picker is open.
+// handled is false for keys that should fall through to normal input handling.
+func (m InputModel) handleFileMentionKey(msg tea.KeyMsg) (model tea.Model, cmd tea.Cmd, handled bool) {
+	switch msg.String() {
+	case "up":
+		if len(m.fileMentionMatches) > 0 {
+			m.fileMentionCursor--
+			if m.fileMentionCursor < 0 {
+				m.fileMentionCursor = len(m.fileMentionMatches) - 1
+			}
+		}
+		return m, nil, true
+	case "down":
+		if len(m.fileMentionMatches) > 0 {
+			m.fileMentionCursor++
+			if m.fileMentionCursor >= len(m.fileMentionMatches) {
+				m.fileMentionCursor = 0
+			}
+		}
+		return m, nil, true
+	case "tab", "enter":
+		if len(m.fileMentionMatches) == 0 {
+			if msg.String() == "tab" {
+				return m, nil, true
+			}
+			m.showingFileMentions = false
+			return m, nil, false
+		}
+		m.insertFileMention(m.fileMentionMatches[m.fileMentionCursor])
+		return m, nil, true
+	case "esc":
+		m.showingFileMentions = false
+		return m, nil, true
+	}
+	return m, nil, false
+}
+
+// insertFileMention replaces the @ token at the cursor with the selected path
+func (m *InputModel) insertFileMention(path string) {
+	runes := []rune(m.textInput.Value())
+	cursor := min(m.textInput.Position(), len(runes))
+	_, start, ok := currentMention(string(runes), cursor)
+	if !ok {
+		return
+	}
+	inserted := "@" + path + " "
+	rest := strings.TrimLeft(string(runes[cursor:]), " ")
+	m.textInput.SetValue(string(runes[:start]) + inserted + rest)
+	m.textInput.SetCursor(start + utf8.RuneCountInString(inserted))
+	m.showingFileMentions = false
+	m.fileMentionCursor = 0
+}
+
+// renderFileMentions renders the @ picker below the input box
+func (m InputModel) renderFileMentions() string {
+	if len(m.fileMentionMatches) == 0 {
+		return "\n" + slashCommandNormalStyle.Render("  No matching files") + "\n\n"
+	}
+	s := "\n"
+	for i, path := range m.fileMentionMatches {
+		if i == m.fileMentionCursor {
+			s += "  " + slashCommandActiveStyle.Render(path) + "\n"
+		} else {
+			s += "  " + slashCommandNormalStyle.Render(path) + "\n"
+		}
+	}
+	return s + "\n"
+}
+
+// attachMentionedFiles appends the contents of files mentioned with @path, or @"path" for
+// paths with spaces, to message so the model receives them as context. Documents over the
+// token budget are reduced to the chunks most relevant to message. Mentions that cannot
+// be read are left as is.
+func attachMentionedFiles(message string) (string, []string) {
+	var attached []string
+	var b strings.Builder
+
+	for _, path := range mentionedPaths(message) {
+		if isLargeDocument(path) {
+			doc, err := loader.Load(path)
+			if err != nil {
+				inputDebugLog("Not attaching @%s: %v", path, err)
+				continue
+			}
+			chunks := loader.Select(doc, message, GetDocumentTokens())
+			fmt.Fprintf(&b, "\n\n%s", doc.Render(chunks))
+			if len(chunks) < len(doc.Chunks) {
+				path = fmt.Sprintf("%s (%d of %d chunks)", path, len(chunks), len(doc.Chunks))
+			}
+			attached = append(attached, path)
+			continue
+		}
+
+		content, err := readAttachment(path)
+		if err != nil {
+			inputDebugLog("Not attaching @%s: %v", path, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n<file path=%q>\n%s\n</file>", path, content)
+		attached = append(attached, path)
+	}
+
+	if len(attached) == 0 {
+		return message, nil
+	}
+	return message + "\n\nContents of the mentioned files:" + b.String(), attached
+}
+
+// isLargeDocument reports whether path is a PDF or Word document, or a file too large
+// to attach whole
+func isLargeDocument(path string) bool {
+	if loader.IsDocument(path) {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Size() > maxAttachmentBytes
+}
+
+// readAttachment reads a text file for attaching to a prompt
+func readAttachment(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file")
+	}
+	if info.Size() > maxAttachmentBytes {
+		return "", fmt.Errorf("file is larger than %d KB", maxAttachmentBytes/1024)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("binary file")
+	}
+	return string(data), nil
+}
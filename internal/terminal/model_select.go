@@ -1,10 +1,13 @@
 package terminal
 
 import (
+	"fmt"
 	"sort"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/orchestration"
 )
 
 // TreeItem represents an item in the tree structure
@@ -13,6 +16,7 @@ type TreeItem struct {
 	Value     string // Actual value (model name or empty for providers)
 	IsProvider bool   // True if this is a provider header
 	IsLast     bool   // True if this is the last model in a provider group
+	Details    string // Capabilities of the model, e.g. "200K context · tools · vision"
 }
 
 // ModelSelectModel represents the full-screen model selection interface
@@ -52,6 +56,14 @@ func NewModelSelectModel() ModelSelectModel {
 func buildTreeItems() []TreeItem {
 	providerModels := GetAvailableModelsByProvider()
 	var items []TreeItem
+
+	// The "auto" model routes each prompt to the model configured for its task
+	if orchestration.Routing() != nil {
+		items = append(items, TreeItem{
+			Text:  "auto (picks a model for each prompt)",
+			Value: orchestration.AutoModel,
+		})
+	}
 	
 	// Sort provider names for consistent display
 	providerNames := make([]string, 0, len(providerModels))
@@ -61,7 +73,7 @@ func buildTreeItems() []TreeItem {
 	sort.Strings(providerNames)
 	
 	for _, providerName := range providerNames {
-		models := providerModels[providerName]
+		names := providerModels[providerName]
 		
 		// Add provider header
 		items = append(items, TreeItem{
@@ -72,11 +84,11 @@ func buildTreeItems() []TreeItem {
 		})
 		
 		// Sort models within provider
-		sort.Strings(models)
+		sort.Strings(names)
 		
 		// Add models under provider
-		for i, model := range models {
-			isLast := i == len(models)-1
+		for i, model := range names {
+			isLast := i == len(names)-1
 			var prefix string
 			if isLast {
 				prefix = "└── "
@@ -89,6 +101,7 @@ func buildTreeItems() []TreeItem {
 				Value:      model,
 				IsProvider: false,
 				IsLast:     isLast,
+				Details:    models.Lookup(model).Summary(),
 			})
 		}
 	}
@@ -172,8 +185,13 @@ func (m ModelSelectModel) View() string {
 	// Version display
 	s += " " + versionStyle.Render(GetVersionDisplay()) + "\n\n"
 
-	// Tree structure
-	for i, item := range m.treeItems {
+	// Tree structure, scrolled around the cursor when it does not fit the screen
+	start, end := m.visibleRange()
+	if start > 0 {
+		s += modelSelectHelpStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n"
+	}
+	for i := start; i < end; i++ {
+		item := m.treeItems[i]
 		if item.IsProvider {
 			// Provider header (always white, never highlighted)
 			s += modelSelectProviderStyle.Render("  "+item.Text) + "\n"
@@ -181,14 +199,22 @@ func (m ModelSelectModel) View() string {
 			// Model item
 			if i == m.cursor {
 				// Highlighted/selected model
-				s += modelSelectActiveStyle.Render("  "+item.Text) + "\n"
+				s += modelSelectActiveStyle.Render("  "+item.Text)
 			} else {
 				// Normal model
-				s += modelSelectNormalStyle.Render("  "+item.Text) + "\n"
+				s += modelSelectNormalStyle.Render("  "+item.Text)
+			}
+			if item.Details != "" {
+				s += modelSelectHelpStyle.Render("  " + item.Details)
 			}
+			s += "\n"
 		}
 	}
 
+	if end < len(m.treeItems) {
+		s += modelSelectHelpStyle.Render(fmt.Sprintf("  ↓ %d more", len(m.treeItems)-end)) + "\n"
+	}
+
 	s += "\n"
 
 	// Help text
@@ -201,6 +227,19 @@ func (m ModelSelectModel) View() string {
 	return s
 }
 
+// modelSelectChrome is the number of lines used by everything except the tree
+const modelSelectChrome = 20
+
+// visibleRange returns the slice of tree items that fits the terminal, keeping the cursor in view
+func (m ModelSelectModel) visibleRange() (start, end int) {
+	rows := max(m.height-modelSelectChrome, 5)
+	if len(m.treeItems) <= rows {
+		return 0, len(m.treeItems)
+	}
+	start = min(max(m.cursor-rows/2, 0), len(m.treeItems)-rows)
+	return start, start + rows
+}
+
 // Model selection specific styles
 var (
 	modelSelectHeaderStyle = lipgloss.NewStyle().
@@ -0,0 +1,41 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/notes"
+)
+
+// runNotesCommand shows the agent's scratchpad (/notes, /notes <name>) or clears it
+// (/notes clear)
+func runNotesCommand(args []string) string {
+	if len(args) == 1 && strings.EqualFold(args[0], "clear") {
+		n := len(notes.List())
+		notes.Restore(nil)
+		if n == 0 {
+			return "System: The scratchpad is already empty"
+		}
+		return fmt.Sprintf("System: Cleared %d notes from the scratchpad", n)
+	}
+
+	if len(args) > 0 {
+		name := strings.Join(args, " ")
+		text, ok := notes.Read(name)
+		if !ok {
+			return fmt.Sprintf("System: No note named %q. Usage: /notes [name|clear]", name)
+		}
+		return fmt.Sprintf("System: Note %q:\n\n%s", name, strings.TrimRight(text, "\n"))
+	}
+
+	list := notes.List()
+	if len(list) == 0 {
+		return "System: The scratchpad is empty. The model keeps notes there with the note_write tool."
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "System: %d notes in the scratchpad:", len(list))
+	for _, n := range list {
+		fmt.Fprintf(&b, "\n\n%s:\n%s", n.Name, strings.TrimRight(n.Text, "\n"))
+	}
+	return b.String()
+}
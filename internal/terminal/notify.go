@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultNotifyAfter is how long a request runs before its completion is notified
+// unless terminal.notify.after says otherwise
+const defaultNotifyAfter = 30 * time.Second
+
+// maxNotifyBody bounds the excerpt of the answer shown in a notification
+const maxNotifyBody = 100
+
+// notifyTurnDone notifies the user that the last request finished, when it ran for at
+// least terminal.notify.after while the terminal was in the background
+func (m *InputModel) notifyTurnDone() tea.Cmd {
+	method, after := GetNotifySettings()
+	elapsed := time.Since(m.turnStarted)
+	if method == "off" || !m.blurred || m.turnStarted.IsZero() || elapsed < after {
+		return nil
+	}
+
+	body := fmt.Sprintf("Finished after %s", elapsed.Round(time.Second))
+	if n := len(m.conversation); n > 0 {
+		last := m.conversation[n-1]
+		if last.IsError {
+			body = fmt.Sprintf("Failed after %s", elapsed.Round(time.Second))
+		} else if excerpt := firstLine(last.AIResponse); excerpt != "" {
+			body += ": " + excerpt
+		}
+	}
+	return func() tea.Msg {
+		if err := notify(method, "Magikarp", body); err != nil {
+			inputDebugLog("Notification failed: %v", err)
+		}
+		return nil
+	}
+}
+
+// notify sends a notification with the given method of terminal.notify
+func notify(method, title, body string) error {
+	switch method {
+	case "bell":
+		_, err := os.Stdout.WriteString("\a")
+		return err
+	case "osc777":
+		_, err := os.Stdout.WriteString(osc777(title, body))
+		return err
+	case "system":
+		if err := systemNotify(title, body); err != nil {
+			_, _ = os.Stdout.WriteString("\a")
+			return err
+		}
+		return nil
+	}
+	// Terminals that do not know OSC 777 ignore it and still ring the bell
+	_, err := os.Stdout.WriteString(osc777(title, body) + "\a")
+	return err
+}
+
+// osc777 returns the escape sequence of a desktop notification understood by terminals
+// such as WezTerm, Ghostty, foot and urxvt
+func osc777(title, body string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r == ';' || r < ' ' || r == 0x7f {
+				return ' '
+			}
+			return r
+		}, s)
+	}
+	return fmt.Sprintf("\x1b]777;notify;%s;%s\a", clean(title), clean(body))
+}
+
+// systemNotify shows a notification through the desktop: notify-send on Linux and the
+// BSDs, osascript on macOS
+func systemNotify(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		return fmt.Errorf("system notifications are not supported on windows")
+	default:
+		cmd = exec.Command("notify-send", "--app-name=magikarp", title, body)
+	}
+	return cmd.Run()
+}
+
+// firstLine returns the first non-empty line of s, shortened for a notification
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if r := []rune(line); len(r) > maxNotifyBody {
+			line = string(r[:maxNotifyBody]) + "…"
+		}
+		return line
+	}
+	return ""
+}
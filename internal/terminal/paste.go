@@ -0,0 +1,310 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/context/loader"
+)
+
+const (
+	// pastePreviewLines is the number of lines of a pasted file shown before attaching it
+	pastePreviewLines = 6
+	// pastePreviewBytes is how much of a pasted text file is read for the preview
+	pastePreviewBytes = 4096
+)
+
+// pastedFile is a file path that was pasted or dropped into the input, waiting for the
+// user to attach the file or insert the path as typed
+type pastedFile struct {
+	text    string // the pasted text, inserted when the file is not attached
+	path    string // the file, relative to the working directory when inside it
+	size    int64
+	preview []string
+	// excerpt is set when only the chunks relevant to the message will be sent
+	excerpt bool
+	// reason explains why the file cannot be attached; empty when it can
+	reason string
+}
+
+// pastedText returns the text a key message pastes: the content of a bracketed paste,
+// which is also how terminals deliver dropped files, or the clipboard for Ctrl+V
+func pastedText(msg tea.KeyMsg) (string, bool) {
+	if msg.Paste {
+		return string(msg.Runes), true
+	}
+	if msg.String() == "ctrl+v" && !clipboard.Unsupported {
+		if text, err := clipboard.ReadAll(); err == nil {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// pastedPath returns the file named by a pasted text. Dropped files arrive as paths
+// that may be quoted, have backslash-escaped spaces or be file:// URLs.
+func pastedPath(text string) (string, bool) {
+	s := strings.TrimSpace(text)
+	if s == "" || strings.ContainsAny(s, "\r\n") {
+		return "", false
+	}
+	switch {
+	case strings.HasPrefix(s, "file://"):
+		u, err := url.Parse(s)
+		if err != nil {
+			return "", false
+		}
+		s = u.Path
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		s = s[1 : len(s)-1]
+	default:
+		s = unescapePath(s)
+	}
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		s = filepath.Join(home, rest)
+	}
+
+	info, err := os.Stat(s)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	// Files in the project are mentioned by their relative path
+	if abs, err := filepath.Abs(s); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+				return filepath.ToSlash(rel), true
+			}
+		}
+		return abs, true
+	}
+	return s, true
+}
+
+// unescapePath removes the backslashes shells put before spaces and other special
+// characters of a path
+func unescapePath(s string) string {
+	if filepath.Separator == '\\' || !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// newPastedFile returns the pasted file text names, or nil when it is not a file path
+func newPastedFile(text string) *pastedFile {
+	path, ok := pastedPath(text)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	p := &pastedFile{text: text, path: path, size: info.Size()}
+
+	switch {
+	case info.Size() == 0:
+		p.reason = "the file is empty"
+	case info.Size() > loader.MaxFileBytes:
+		p.reason = fmt.Sprintf("files larger than %d MB are not attached", loader.MaxFileBytes/1024/1024)
+	case loader.IsDocument(path):
+		// Documents are read when the message is sent; only the first chunk is previewed
+		doc, err := loader.Load(path)
+		if err != nil {
+			p.reason = err.Error()
+			break
+		}
+		p.preview = previewLines(doc.Chunks[0].Text)
+		p.excerpt = doc.Tokens() > GetDocumentTokens()
+	default:
+		head, err := readHead(path, pastePreviewBytes)
+		if err != nil {
+			p.reason = err.Error()
+			break
+		}
+		if bytes.IndexByte(head, 0) >= 0 || !utf8.Valid(trimPartialRune(head)) {
+			p.reason = "binary file"
+			break
+		}
+		p.preview = previewLines(string(head))
+		p.excerpt = info.Size() > maxAttachmentBytes
+	}
+	return p
+}
+
+// readHead reads up to n bytes from the start of a file
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// trimPartialRune drops a UTF-8 sequence cut off at the end of data
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// previewLines returns the first lines of text
+func previewLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if len(lines) == pastePreviewLines {
+			lines = append(lines, "…")
+			break
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return lines
+}
+
+// mentionFor returns the @ mention of path, quoted when it contains spaces
+func mentionFor(path string) string {
+	if strings.ContainsAny(path, " \t") {
+		return `@"` + path + `"`
+	}
+	return "@" + path
+}
+
+// mentionPattern matches @path and @"path with spaces" at the start of a word
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@(?:"([^"]+)"|(\S+))`)
+
+// mentionedPaths returns the paths mentioned with @ in message, in order and without
+// repeats
+func mentionedPaths(message string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		path := match[1]
+		if path == "" {
+			path = strings.TrimRight(match[2], ".,;:!?)")
+		}
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// handlePasteKey attaches the pasted file or inserts the pasted text as typed
+func (m InputModel) handlePasteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.pendingPaste
+	switch msg.String() {
+	case "y", "Y", "enter":
+		if p.reason != "" {
+			m.textInput.InsertString(p.text)
+		} else {
+			m.textInput.InsertString(mentionFor(p.path) + " ")
+		}
+	case "n", "N", "esc":
+		m.textInput.InsertString(p.text)
+	case "ctrl+c":
+	default:
+		return m, nil
+	}
+	m.pendingPaste = nil
+	m.updateFileMentions()
+	return m, nil
+}
+
+// renderPastePrompt renders the prompt for a pasted file path shown above the input box
+func renderPastePrompt(p *pastedFile, width int) string {
+	if p.reason != "" {
+		s := approvalTitleStyle.Render(wrapText(fmt.Sprintf("Cannot attach %s: %s", p.path, p.reason), width-6)) + "\n"
+		return s + helpStyle.Render("enter: insert path • ctrl+c: discard") + "\n"
+	}
+	s := approvalTitleStyle.Render(fmt.Sprintf("Attach %s (%s)?", p.path, formatBytes(p.size))) + "\n"
+	for _, line := range p.preview {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		s += approvalParamsStyle.Render("│ "+truncateCell(line, max(10, width-8))) + "\n"
+	}
+	if p.excerpt {
+		s += helpDisplayStyle.Render("Large file: only the parts most relevant to your message are sent") + "\n"
+	}
+	return s + helpStyle.Render("y/enter: attach contents • n/esc: insert path • ctrl+c: discard") + "\n"
+}
+
+// formatBytes renders a file size for the paste prompt
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
+
+// runPasteCommand handles "/paste [message]": the clipboard is sent as context with the
+// given message, or with the next message when none is given
+func (m *InputModel) runPasteCommand(args []string) (string, tea.Cmd) {
+	if m.turnRunning() {
+		return "System: Wait for the current answer before pasting", nil
+	}
+	if clipboard.Unsupported {
+		return "System: No clipboard is available: install xclip, xsel or wl-clipboard", nil
+	}
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return "System: Failed to read the clipboard: " + err.Error(), nil
+	}
+	text = strings.TrimRight(text, " \t\r\n")
+	if strings.TrimSpace(text) == "" {
+		return "System: The clipboard is empty", nil
+	}
+	if len(text) > maxAttachmentBytes {
+		return fmt.Sprintf("System: The clipboard holds %s; at most %s can be pasted", formatBytes(int64(len(text))), formatBytes(maxAttachmentBytes)), nil
+	}
+
+	m.pastedClipboard = text
+	if len(args) > 0 {
+		return "", m.send(strings.Join(args, " "))
+	}
+	lines := strings.Count(text, "\n") + 1
+	return fmt.Sprintf("System: The clipboard (%d lines) will be sent with your next message", lines), nil
+}
+
+// clipboardBlock renders clipboard text pasted with /paste as context for the prompt
+func clipboardBlock(text string) string {
+	return "\n\nContents of the clipboard:\n\n<clipboard>\n" + text + "\n</clipboard>"
+}
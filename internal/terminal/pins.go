@@ -0,0 +1,174 @@
+package terminal
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// pinnedFilesPrefix marks the user message that carries the pinned files in a history
+const pinnedFilesPrefix = "[Pinned files]"
+
+// pinsUsage lists the forms of /pin and /unpin
+const pinsUsage = "System: Usage: /pin [message number | file], /unpin [message number | file | all], /pins"
+
+// runPinCommand pins message n (/pin <n>, the last by default) or a file (/pin <file>)
+// so that it is sent verbatim with every turn, whatever compression and the history
+// limit drop
+func (m *InputModel) runPinCommand(args []string) string {
+	if len(args) > 1 {
+		return pinsUsage
+	}
+	if path, ok := pinnedFileArg(args); ok {
+		if slices.Contains(m.pinnedFiles, path) {
+			return "System: " + path + " is already pinned"
+		}
+		content, err := readAttachment(path)
+		if err != nil {
+			return fmt.Sprintf("System: Cannot pin %s: %v", path, err)
+		}
+		m.pinnedFiles = append(m.pinnedFiles, path)
+		return fmt.Sprintf("System: Pinned %s (%d lines); its current contents are sent with every message", path, strings.Count(content, "\n")+1)
+	}
+
+	n, i, err := m.pinTarget(args)
+	if err != nil {
+		return "System: Nothing to pin: " + err.Error()
+	}
+	if n == 0 {
+		n = m.messageNumber(i)
+	}
+	if m.conversation[i].Pinned {
+		return fmt.Sprintf("System: Message #%d is already pinned", n)
+	}
+	m.conversation[i].Pinned = true
+	return fmt.Sprintf("System: Pinned message #%d; it is kept verbatim when the history is compressed or trimmed", n)
+}
+
+// runUnpinCommand unpins message n (/unpin <n>, the last by default), a file (/unpin
+// <file>) or everything (/unpin all)
+func (m *InputModel) runUnpinCommand(args []string) string {
+	if len(args) > 1 {
+		return pinsUsage
+	}
+	if len(args) == 1 && args[0] == "all" {
+		count := len(m.pinnedFiles)
+		for i := range m.conversation {
+			if m.conversation[i].Pinned {
+				m.conversation[i].Pinned = false
+				count++
+			}
+		}
+		m.pinnedFiles = nil
+		return fmt.Sprintf("System: Unpinned %d items", count)
+	}
+	if path, ok := pinnedFileArg(args); ok {
+		i := slices.Index(m.pinnedFiles, path)
+		if i < 0 {
+			return "System: " + path + " is not pinned"
+		}
+		m.pinnedFiles = slices.Delete(m.pinnedFiles, i, i+1)
+		return "System: Unpinned " + path
+	}
+
+	n, i, err := m.pinTarget(args)
+	if err != nil {
+		return "System: Nothing to unpin: " + err.Error()
+	}
+	if n == 0 {
+		n = m.messageNumber(i)
+	}
+	if !m.conversation[i].Pinned {
+		return fmt.Sprintf("System: Message #%d is not pinned", n)
+	}
+	m.conversation[i].Pinned = false
+	return fmt.Sprintf("System: Unpinned message #%d", n)
+}
+
+// runPinsCommand lists the pinned messages and files
+func (m *InputModel) runPinsCommand() string {
+	var lines []string
+	for i, pair := range m.conversation {
+		if pair.Pinned {
+			lines = append(lines, fmt.Sprintf("  #%d %s", m.messageNumber(i), truncateCell(firstLine(pair.UserMessage), 70)))
+		}
+	}
+	for _, path := range m.pinnedFiles {
+		lines = append(lines, "  "+path)
+	}
+	if len(lines) == 0 {
+		return "System: Nothing is pinned. /pin <n> pins a message and /pin <file> a file."
+	}
+	return "System: Pinned, and kept verbatim when the history is compressed or trimmed:\n" + strings.Join(lines, "\n")
+}
+
+// pinnedFileArg returns the file named by the argument of /pin or /unpin; ok is false
+// when the argument is a message number, or missing. "@path" always names a file.
+func pinnedFileArg(args []string) (string, bool) {
+	if len(args) == 0 {
+		return "", false
+	}
+	if path, ok := strings.CutPrefix(args[0], "@"); ok {
+		return filepath.Clean(path), true
+	}
+	if _, err := parseMessageNumber(args); err == nil {
+		return "", false
+	}
+	return filepath.Clean(args[0]), true
+}
+
+// pinTarget returns the message number given to /pin or /unpin, 0 for the last
+// message, and its conversation index
+func (m *InputModel) pinTarget(args []string) (int, int, error) {
+	n, err := parseMessageNumber(args)
+	if err != nil {
+		return 0, 0, err
+	}
+	i, err := m.exchangeIndex(n)
+	return n, i, err
+}
+
+// messageNumber returns the number shown in the transcript for the message at
+// conversation index i
+func (m InputModel) messageNumber(i int) int {
+	n := 0
+	for _, pair := range m.conversation[:i+1] {
+		if isExchange(pair) {
+			n++
+		}
+	}
+	return n
+}
+
+// unpinnedPairs drops the pinned pairs from indices: compression only folds the
+// others into the summary
+func unpinnedPairs(conversation []ConversationPair, indices []int) []int {
+	return slices.DeleteFunc(slices.Clone(indices), func(i int) bool {
+		return conversation[i].Pinned
+	})
+}
+
+// pinnedFileMessages returns the exchange that carries the current contents of the
+// pinned files at the start of a history; nil when no file is pinned
+func pinnedFileMessages(paths []string) []providers.ChatMessage {
+	if len(paths) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString(pinnedFilesPrefix + "\nThe user pinned these files; these are their current contents:")
+	for _, path := range paths {
+		content, err := readAttachment(path)
+		if err != nil {
+			fmt.Fprintf(&b, "\n\n<file path=%q>\n(could not be read: %v)\n</file>", path, err)
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n<file path=%q>\n%s\n</file>", path, content)
+	}
+	return []providers.ChatMessage{
+		{Role: providers.RoleUser, Content: b.String()},
+		{Role: providers.RoleAssistant, Content: "Understood. I'll use these files as context for the rest of our conversation."},
+	}
+}
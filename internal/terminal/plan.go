@@ -0,0 +1,76 @@
+package terminal
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// planModePrompt is added to the system prompt in plan mode
+const planModePrompt = "You are in plan mode. Investigate with the read-only tools you have, but do not change " +
+	"anything: files, processes and settings must stay as they are. Answer with a numbered, step-by-step plan " +
+	"for the request that names the files, functions and commands involved, followed by any open questions. " +
+	"The user will refine the plan with you and then approve it for execution."
+
+// approvedPlanPrompt introduces the approved plan in the system prompt while it is executed
+const approvedPlanPrompt = "The user approved the following plan. Carry it out step by step with your tools, " +
+	"and say which step you are on as you go:\n\n"
+
+// executePlanMessage is sent once a plan is approved
+const executePlanMessage = "Execute the approved plan."
+
+// withPlanPrompt adds the plan mode instructions, or the plan being executed, to the
+// system prompt
+func withPlanPrompt(system string) string {
+	if PlanModeEnabled() {
+		return system + "\n\n" + planModePrompt
+	}
+	if plan := ApprovedPlan(); plan != "" {
+		return system + "\n\n" + approvedPlanPrompt + plan
+	}
+	return system
+}
+
+// runPlanCommand turns plan mode on or off (/plan, /plan on, /plan off) or approves the
+// last plan (/plan run): plan mode ends, the plan is kept in the system prompt for the
+// turn that executes it, and the model is asked to execute it. The reply is set unless
+// a message is sent.
+func (m *InputModel) runPlanCommand(args []string) (string, tea.Cmd) {
+	action := "toggle"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	if action == "toggle" {
+		action = "on"
+		if PlanModeEnabled() {
+			action = "off"
+		}
+	}
+
+	switch action {
+	case "on":
+		SetPlanModeEnabled(true)
+		SetApprovedPlan("")
+		return "System: Plan mode on: tools are read-only and the model answers with a plan. " +
+			"Refine it, then /plan run to execute it, or /plan off to leave plan mode.", nil
+	case "off":
+		SetPlanModeEnabled(false)
+		SetApprovedPlan("")
+		return "System: Plan mode off", nil
+	case "run", "execute", "approve":
+		if !PlanModeEnabled() {
+			return "System: Not in plan mode; /plan starts planning", nil
+		}
+		if m.turnRunning() {
+			return busyReply, nil
+		}
+		i, err := m.exchangeIndex(0)
+		if err != nil || m.conversation[i].IsError || strings.TrimSpace(m.conversation[i].AIResponse) == "" {
+			return "System: There is no plan to run yet; describe the task first", nil
+		}
+		SetPlanModeEnabled(false)
+		SetApprovedPlan(m.conversation[i].AIResponse)
+		return "", m.send(executePlanMessage)
+	}
+	return "System: Usage: /plan [on|off|run]", nil
+}
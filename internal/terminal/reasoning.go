@@ -0,0 +1,58 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxLiveReasoningLines is how much of the reasoning is shown while the model is thinking
+const maxLiveReasoningLines = 3
+
+// turnReasoningMsg carries reasoning reported while a turn is running
+type turnReasoningMsg struct {
+	delta  string
+	events <-chan tea.Msg
+}
+
+// AppendReasoning appends reasoning to the most recent conversation pair
+func (m *InputModel) AppendReasoning(delta string) {
+	if len(m.conversation) > 0 {
+		m.conversation[len(m.conversation)-1].Reasoning += delta
+	}
+}
+
+// renderReasoning renders the reasoning of pair dimmed above its answer. While the model
+// is still thinking the latest lines are shown; afterwards the reasoning is collapsed to
+// one line unless expanded is set.
+func renderReasoning(pair ConversationPair, expanded bool, width int) string {
+	reasoning := strings.TrimSpace(pair.Reasoning)
+	if reasoning == "" {
+		return ""
+	}
+	lines := strings.Split(wrapText(reasoning, width-8), "\n")
+
+	thinking := pair.IsProcessing && pair.AIResponse == ""
+	switch {
+	case expanded:
+	case thinking:
+		if len(lines) > maxLiveReasoningLines {
+			lines = lines[len(lines)-maxLiveReasoningLines:]
+		}
+	default:
+		words := len(strings.Fields(reasoning))
+		return reasoningStyle.Render(fmt.Sprintf("  ▸ Reasoned for %d words (ctrl+o to expand)", words)) + "\n"
+	}
+
+	s := reasoningStyle.Render("  Reasoning:") + "\n"
+	for _, line := range lines {
+		s += reasoningStyle.Render("  │ "+line) + "\n"
+	}
+	return s
+}
+
+var reasoningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#626262")).
+	Italic(true)
@@ -0,0 +1,160 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	cfg "github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/models"
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// reloadDelay lets an editor finish writing the config file before it is read
+const reloadDelay = 300 * time.Millisecond
+
+// registrySettings are the reloadable settings the provider registry is built from
+var registrySettings = []string{"default_temperature", "retry", "routing", "providers", "redaction"}
+
+// loadedConfig is the configuration as last read from the files. Reloads compare
+// against it, so that settings changed in the session (e.g. /tools) are only replaced
+// when the files change them.
+var loadedConfig *cfg.Config
+
+// configReloadMsg carries the configuration read after a config file changed
+type configReloadMsg struct {
+	conf *cfg.Config
+	err  error
+}
+
+// watchConfig reloads the configuration whenever one of its files changes and
+// returns a function that stops watching. Directories rather than files are watched,
+// since editors often replace a file instead of writing to it, and a file may be
+// created later, such as a project's .magikarp.yaml.
+func watchConfig(conf *cfg.Config) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]bool{}
+	candidates := append([]string{cfg.GetDefaultConfigPath(), cfg.LocalConfigFile, cfg.ProjectConfigFile}, conf.Files...)
+	for _, path := range candidates {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		files[abs] = true
+		if _, err := os.Stat(filepath.Dir(abs)); err == nil {
+			_ = watcher.Add(filepath.Dir(abs))
+		}
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !files[event.Name] || event.Op == fsnotify.Chmod {
+					continue
+				}
+				// An editor's save comes as several events; read the file once they are over
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, func() {
+					next, err := cfg.Load()
+					if err == nil {
+						err = next.ValidateConfig()
+					}
+					sendToUI(configReloadMsg{conf: next, err: err})
+				})
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return func() { watcher.Close() }, nil
+}
+
+// handleConfigReload applies the settings changed in the config files that can change
+// in a running session, and reports what was reloaded and what needs a restart
+func (m *InputModel) handleConfigReload(msg configReloadMsg) {
+	if globalConfig == nil || loadedConfig == nil {
+		return
+	}
+	if msg.err != nil {
+		m.AddConversationPair("/config", "System: Config not reloaded: "+msg.err.Error())
+		return
+	}
+
+	var applied, restart []string
+	current := globalConfig.Changes(msg.conf)
+	for _, key := range loadedConfig.Changes(msg.conf) {
+		switch {
+		case !slices.Contains(current, key):
+			// already in effect, e.g. saved by /config
+		case cfg.IsReloadable(key):
+			applied = append(applied, key)
+		default:
+			restart = append(restart, key)
+		}
+	}
+	loadedConfig = msg.conf
+	if len(applied) == 0 && len(restart) == 0 {
+		return
+	}
+	globalConfig.Apply(msg.conf, applied)
+	if slices.Contains(applied, "models") || slices.Contains(applied, "context") {
+		models.Configure(globalConfig)
+	}
+
+	var lines []string
+	if len(applied) > 0 {
+		lines = append(lines, "Reloaded "+strings.Join(applied, ", "))
+	}
+	if slices.ContainsFunc(applied, func(key string) bool { return slices.Contains(registrySettings, key) }) {
+		if m.turnRunning() {
+			m.reloadRegistry = true
+			lines = append(lines, "Models are updated once the current response finishes")
+		} else if line := reloadRegistry(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(restart) > 0 {
+		lines = append(lines, "Restart Magikarp to apply "+strings.Join(restart, ", "))
+	}
+	m.AddConversationPair("/config", "System: Config changed. "+strings.Join(lines, ". "))
+}
+
+// reloadRegistry rebuilds the provider registry from the running configuration and
+// returns a note on providers that failed, or ""
+func reloadRegistry() string {
+	warnings, err := orchestration.Reload(globalConfig)
+	if err != nil {
+		return "Models not reloaded: " + strings.TrimSpace(err.Error())
+	}
+	if len(warnings) > 0 {
+		return "Not set up: " + strings.Join(warnings, "; ")
+	}
+	return ""
+}
+
+// finishPendingReload rebuilds the registry once the request that held it up finished
+func (m *InputModel) finishPendingReload() {
+	if !m.reloadRegistry || m.turnRunning() {
+		return
+	}
+	m.reloadRegistry = false
+	if line := reloadRegistry(); line != "" {
+		m.AddConversationPair("/config", "System: "+line)
+	}
+}
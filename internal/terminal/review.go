@@ -0,0 +1,184 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+// maxReviewDiffLines bounds how much of a diff is shown in the review prompt
+const maxReviewDiffLines = 40
+
+// fileReviewReply is the user's answer to a file change review
+type fileReviewReply struct {
+	accepted bool
+	// content replaces the proposed content when the user edited it
+	content *string
+}
+
+// fileReviewMsg asks the UI to confirm a file change before it is written.
+// The running turn blocks until a reply is sent.
+type fileReviewMsg struct {
+	change *fsedit.Change
+	diff   string
+	reply  chan<- fileReviewReply
+	events <-chan tea.Msg
+}
+
+// editorFinishedMsg is sent when the external editor opened from a review exits
+type editorFinishedMsg struct {
+	path string
+	err  error
+}
+
+// reviewFileChanges returns an fsedit.Reviewer that shows each change in the UI
+func reviewFileChanges(events chan tea.Msg) fsedit.Reviewer {
+	return func(ctx context.Context, c *fsedit.Change) (bool, error) {
+		reply := make(chan fileReviewReply, 1)
+		if !sendTurnEvent(ctx, events, fileReviewMsg{change: c, diff: c.Diff(), reply: reply, events: events}) {
+			return false, ctx.Err()
+		}
+
+		select {
+		case r := <-reply:
+			if r.accepted && r.content != nil {
+				c.New = *r.content
+			}
+			return r.accepted, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
+
+// handleReviewKey resolves the pending file review from a key press
+func (m InputModel) handleReviewKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		return m.resolveReview(fileReviewReply{accepted: true})
+	case "n", "N", "esc", "ctrl+c":
+		return m.resolveReview(fileReviewReply{accepted: false})
+	case "e", "E":
+		return m, m.editProposedChange()
+	}
+	return m, nil
+}
+
+// resolveReview answers the pending review and resumes the turn
+func (m InputModel) resolveReview(reply fileReviewReply) (tea.Model, tea.Cmd) {
+	pending := m.pendingReview
+	m.pendingReview = nil
+	m.reviewError = ""
+	pending.reply <- reply
+	return m, waitForTurnEvent(pending.events)
+}
+
+// editProposedChange opens the proposed content in the user's editor
+func (m InputModel) editProposedChange() tea.Cmd {
+	change := m.pendingReview.change
+	tmp, err := os.CreateTemp("", "magikarp-*-"+filepath.Base(change.Path))
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{err: err} }
+	}
+	_, err = tmp.WriteString(change.New)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return func() tea.Msg { return editorFinishedMsg{err: err} }
+	}
+
+	args := strings.Fields(editorCommand())
+	cmd := exec.Command(args[0], append(args[1:], tmp.Name())...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{path: tmp.Name(), err: err}
+	})
+}
+
+// handleEditorFinished applies the edited content and accepts the change
+func (m InputModel) handleEditorFinished(msg editorFinishedMsg) (tea.Model, tea.Cmd) {
+	if msg.path != "" {
+		defer os.Remove(msg.path)
+	}
+	if m.pendingReview == nil {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.reviewError = fmt.Sprintf("Editor failed: %v", msg.err)
+		return m, nil
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.reviewError = fmt.Sprintf("Could not read edited file: %v", err)
+		return m, nil
+	}
+	content := string(data)
+	return m.resolveReview(fileReviewReply{accepted: true, content: &content})
+}
+
+// editorCommand returns the user's preferred editor
+func editorCommand() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// renderFileReview renders the diff and review prompt shown above the input box
+func renderFileReview(req *fileReviewMsg, errMsg string) string {
+	action := "Apply changes to"
+	if !req.change.Exists {
+		action = "Create"
+	}
+	added, removed := fsedit.DiffStat(req.diff)
+	s := approvalTitleStyle.Render(fmt.Sprintf("%s %s? (+%d -%d)", action, req.change.Path, added, removed)) + "\n"
+
+	lines := strings.Split(strings.TrimRight(req.diff, "\n"), "\n")
+	hidden := 0
+	if len(lines) > maxReviewDiffLines {
+		hidden = len(lines) - maxReviewDiffLines
+		lines = lines[:maxReviewDiffLines]
+	}
+	for _, line := range lines {
+		s += "  " + diffLineStyle(line).Render(line) + "\n"
+	}
+	if hidden > 0 {
+		s += approvalParamsStyle.Render(fmt.Sprintf("  ... %d more diff lines", hidden)) + "\n"
+	}
+	if errMsg != "" {
+		s += diffRemovedStyle.Render(errMsg) + "\n"
+	}
+	s += helpStyle.Render("y: apply • n: reject • e: edit in " + filepath.Base(strings.Fields(editorCommand())[0]))
+	return s + "\n"
+}
+
+// diffLineStyle picks the color for a unified diff line
+func diffLineStyle(line string) lipgloss.Style {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return diffHeaderStyle
+	case strings.HasPrefix(line, "@@"):
+		return diffHunkStyle
+	case strings.HasPrefix(line, "+"):
+		return diffAddedStyle
+	case strings.HasPrefix(line, "-"):
+		return diffRemovedStyle
+	}
+	return approvalParamsStyle
+}
+
+// Diff styles
+var (
+	diffHeaderStyle  = lipgloss.NewStyle().Bold(true)
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#5FAFD7"))
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F5F"))
+)
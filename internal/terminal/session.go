@@ -0,0 +1,207 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/notes"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/session"
+)
+
+// maxResumeSessions is the number of recent sessions offered by /resume
+const maxResumeSessions = 20
+
+// persistSession checkpoints the current conversation to ~/.magikarp/sessions.
+// Slash command output and in-flight exchanges are not persisted.
+func (m *InputModel) persistSession() (string, error) {
+	if !m.syncSession() {
+		return "", nil
+	}
+	return m.session.Save()
+}
+
+// syncSession copies the completed exchanges of the conversation into m.session,
+// creating it when needed. It returns false when there is nothing to save yet.
+func (m *InputModel) syncSession() bool {
+	if m.session == nil {
+		m.session = session.New(m.provider)
+	}
+
+	var exchanges []session.Exchange
+	for _, pair := range m.conversation {
+		if pair.IsProcessing || strings.HasPrefix(pair.UserMessage, "/") {
+			continue
+		}
+		ex := session.Exchange{
+			User:      pair.UserMessage,
+			Assistant: pair.AIResponse,
+			Model:     pair.Model,
+			Reasoning: pair.Reasoning,
+			IsError:   pair.IsError,
+			Pinned:    pair.Pinned,
+			Time:      pair.Time,
+		}
+		for _, call := range pair.ToolCalls {
+			ex.ToolCalls = append(ex.ToolCalls, session.ToolCall{
+				Name:    call.Name,
+				Input:   call.Input,
+				Output:  call.Result.Content,
+				IsError: call.Result.IsError,
+				Denied:  call.Denied,
+			})
+		}
+		exchanges = append(exchanges, ex)
+	}
+	if len(exchanges) == 0 {
+		return false
+	}
+
+	m.session.Model = m.provider
+	m.session.Exchanges = exchanges
+	m.session.PinnedFiles = m.pinnedFiles
+	m.session.Notes = nil
+	if globalConfig != nil && globalConfig.Tools.Notes.Persist {
+		m.session.Notes = notes.Snapshot()
+	}
+	return true
+}
+
+// autoSaveSession persists the session after each completed response, logging failures
+func (m *InputModel) autoSaveSession() {
+	if _, err := m.persistSession(); err != nil {
+		inputDebugLog("Failed to save session: %v", err)
+	}
+}
+
+// restoreSession replaces the current conversation with a saved session
+func (m *InputModel) restoreSession(s *session.Session) {
+	m.session = s
+	m.branches, m.activeBranch = nil, 0
+	m.contextSummary = ""
+	m.summaryUpTo = 0
+	m.expandedTools = nil
+	m.pinnedFiles = s.PinnedFiles
+	notes.Restore(s.Notes)
+	m.conversation = make([]ConversationPair, 0, len(s.Exchanges))
+	for _, ex := range s.Exchanges {
+		pair := ConversationPair{
+			UserMessage: ex.User,
+			AIResponse:  ex.Assistant,
+			IsError:     ex.IsError,
+			Pinned:      ex.Pinned,
+			Model:       ex.Model,
+			Reasoning:   ex.Reasoning,
+			Time:        ex.Time,
+		}
+		for _, call := range ex.ToolCalls {
+			tc := orchestration.ToolCall{Name: call.Name, Input: call.Input, Denied: call.Denied}
+			tc.Result.Content = call.Output
+			tc.Result.IsError = call.IsError
+			pair.ToolCalls = append(pair.ToolCalls, tc)
+		}
+		m.conversation = append(m.conversation, pair)
+	}
+
+	// Continue with the model the session used when it is still available
+	if modelAvailable(s.Model) {
+		m.provider = s.Model
+	}
+}
+
+// SessionSelectModel is the full-screen picker shown by /resume
+type SessionSelectModel struct {
+	width    int
+	height   int
+	cursor   int
+	sessions []*session.Session
+	err      error
+	selected *session.Session
+	quitting bool
+}
+
+// NewSessionSelectModel lists the most recent saved sessions
+func NewSessionSelectModel() SessionSelectModel {
+	sessions, err := session.List(maxResumeSessions)
+	return SessionSelectModel{
+		width:    80,
+		height:   24,
+		sessions: sessions,
+		err:      err,
+	}
+}
+
+// Init initializes the session selection model
+func (m SessionSelectModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the session selection model
+func (m SessionSelectModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "up", "k":
+			if len(m.sessions) > 0 {
+				m.cursor = (m.cursor - 1 + len(m.sessions)) % len(m.sessions)
+			}
+		case "down", "j":
+			if len(m.sessions) > 0 {
+				m.cursor = (m.cursor + 1) % len(m.sessions)
+			}
+		case "enter":
+			if m.cursor < len(m.sessions) {
+				m.selected = m.sessions[m.cursor]
+			}
+			m.quitting = true
+			return m, tea.Quit
+		case "esc", "q":
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// GetSelectedSession returns the session chosen by the user, or nil
+func (m SessionSelectModel) GetSelectedSession() *session.Session {
+	return m.selected
+}
+
+// View renders the session selection screen
+func (m SessionSelectModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	s := renderWelcomeBox() + "\n\n"
+	s += " " + versionStyle.Render("Resume a session") + "\n\n"
+
+	switch {
+	case m.err != nil:
+		s += modelSelectNormalStyle.Render("  Failed to list sessions: "+m.err.Error()) + "\n"
+	case len(m.sessions) == 0:
+		s += modelSelectNormalStyle.Render("  No saved sessions yet.") + "\n"
+	default:
+		for i, sess := range m.sessions {
+			line := fmt.Sprintf("  %s  %-60s  %s (%d messages)",
+				sess.UpdatedAt.Format("2006-01-02 15:04"), sess.Title(), sess.Model, len(sess.Exchanges))
+			if sess.Branch != "" {
+				line += " ⑂ " + sess.Branch
+			}
+			if i == m.cursor {
+				s += modelSelectActiveStyle.Render(line) + "\n"
+			} else {
+				s += modelSelectNormalStyle.Render(line) + "\n"
+			}
+		}
+	}
+
+	s += "\n\n"
+	s += modelSelectHelpStyle.Render(" ↑/↓: navigate • enter: resume • esc: cancel") + "\n"
+	return s
+}
@@ -0,0 +1,108 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/redact"
+	"github.com/pprunty/magikarp/internal/share"
+)
+
+// pendingShare is a sanitized transcript waiting for the user to confirm the upload
+type pendingShare struct {
+	doc         share.Document
+	destination string
+	exchanges   int
+	// masked is the number of credentials masked in the transcript
+	masked int
+}
+
+// shareDoneMsg carries the link of an uploaded transcript, or why the upload failed
+type shareDoneMsg struct {
+	url string
+	err error
+}
+
+// runShareCommand handles "/share": the conversation is rendered as Markdown with
+// credentials masked and home paths shortened, and uploaded once the user confirms
+func (m *InputModel) runShareCommand() string {
+	if m.turnRunning() {
+		return busyReply
+	}
+	if !m.syncSession() {
+		return "System: Nothing to share yet"
+	}
+	raw := m.session.Markdown()
+	sanitized := m.session.Sanitized()
+	content := sanitized.Markdown()
+	m.pendingShare = &pendingShare{
+		doc: share.Document{
+			Title:   sanitized.Title(),
+			Name:    "magikarp-" + sanitized.ID + ".md",
+			Content: content,
+		},
+		destination: share.Destination(globalConfig),
+		exchanges:   len(sanitized.Exchanges),
+		masked:      max(0, strings.Count(content, redact.Mask)-strings.Count(raw, redact.Mask)),
+	}
+	return ""
+}
+
+// handleShareKey uploads the transcript once confirmed
+func (m InputModel) handleShareKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.pendingShare
+	switch msg.String() {
+	case "y", "Y", "enter":
+	case "n", "N", "esc", "ctrl+c":
+		m.pendingShare = nil
+		return m, nil
+	default:
+		return m, nil
+	}
+	m.pendingShare = nil
+
+	m.AddConversationPair("/share", "")
+	m.conversation[len(m.conversation)-1].Status = "Uploading to " + p.destination
+	m.transcript.scrolledUp = false
+	ctx := m.beginTurn()
+	return m, tea.Batch(
+		func() tea.Msg {
+			url, err := share.Upload(ctx, globalConfig, p.doc)
+			return shareDoneMsg{url: url, err: err}
+		},
+		spinnerTickCmd(),
+	)
+}
+
+// handleShareDone reports the link of the shared transcript and copies it
+func (m *InputModel) handleShareDone(msg shareDoneMsg) {
+	if !m.turnRunning() {
+		return // the upload was cancelled
+	}
+	m.endTurn()
+	if msg.err != nil {
+		m.SetAIResponse("System: Failed to share the conversation: " + msg.err.Error())
+		return
+	}
+	reply := "System: Shared the conversation at " + msg.url
+	if !clipboard.Unsupported && clipboard.WriteAll(msg.url) == nil {
+		reply += " (copied to the clipboard)"
+	}
+	m.SetAIResponse(reply)
+}
+
+// renderSharePrompt renders the confirmation of a share shown above the input box
+func renderSharePrompt(p *pendingShare, width int) string {
+	s := approvalTitleStyle.Render(wrapText("Share this conversation as "+p.destination+"?", width-6)) + "\n"
+	details := fmt.Sprintf("%d exchanges, %s of Markdown with tool calls and their output", p.exchanges, formatBytes(int64(len(p.doc.Content))))
+	s += approvalParamsStyle.Render("│ "+truncateCell(details, max(10, width-8))) + "\n"
+	masked := "No credentials found; home paths are shown as ~"
+	if p.masked > 0 {
+		masked = fmt.Sprintf("%d credentials masked; home paths are shown as ~", p.masked)
+	}
+	s += approvalParamsStyle.Render("│ "+truncateCell(masked, max(10, width-8))) + "\n"
+	s += helpDisplayStyle.Render("Anyone with the link can read it. Use /export to review the transcript first.") + "\n"
+	return s + helpStyle.Render("y/enter: upload • n/esc: cancel") + "\n"
+}
@@ -1,12 +1,10 @@
 package terminal
 
 import (
-	"os"
 	"strings"
 
 	cfg "github.com/pprunty/magikarp/internal/config"
 	"github.com/pprunty/magikarp/internal/orchestration"
-	"gopkg.in/yaml.v3"
 )
 
 // SlashCommand represents a slash command with its name and description
@@ -18,11 +16,44 @@ type SlashCommand struct {
 // GetAvailableCommands returns the list of available slash commands in alphabetical order
 func GetAvailableCommands() []SlashCommand {
 	return []SlashCommand{
+		{Name: "/branches", Description: "Switch between conversation branches (/branches [n|name])"},
+		{Name: "/checkpoint", Description: "Save a checkpoint of the working tree (/checkpoint [label], /checkpoint list)"},
+		{Name: "/commit", Description: "Write a commit message for the staged changes and commit them (/commit [hint])"},
+		{Name: "/compare", Description: "Ask several models the same prompt (/compare <model>,<model> <prompt>)"},
+		{Name: "/config", Description: "View and edit settings"},
+		{Name: "/copy", Description: "Copy a code block to the clipboard (/copy <n>, last by default)"},
+		{Name: "/debug", Description: "Show recent provider requests, tool calls and registry state (also F12)"},
+		{Name: "/delete", Description: "Remove a message and its answer from the conversation (/delete <n>, last by default)"},
+		{Name: "/dryrun", Description: "Show the edits and commands the model asks for without making them (/dryrun on|off)"},
+		{Name: "/edit", Description: "Edit a message and answer it again from there (/edit <n>, last by default)"},
 		{Name: "/exit", Description: "Exit Magikarp"},
+		{Name: "/expand", Description: "Show or hide a tool's output (/expand <n>, /expand all)"},
+		{Name: "/export", Description: "Export the conversation (/export <path>.md|.json|.html)"},
+		{Name: "/fork", Description: "Branch off the conversation to try another approach (/fork [name])"},
 		{Name: "/help", Description: "Show help information"},
+		{Name: "/isolate", Description: "Run the agent in a temporary git worktree until /merge"},
+		{Name: "/memory", Description: "Show project instructions (/memory edit to edit MAGIKARP.md)"},
+		{Name: "/merge", Description: "Review, apply or discard an isolated run (/merge [apply|discard])"},
 		{Name: "/model", Description: "Switch between AI models"},
-		{Name: "/speech", Description: "Toggle speech mode on/off"},
+		{Name: "/notes", Description: "Show the model's scratchpad notes (/notes [name], /notes clear)"},
+		{Name: "/paste", Description: "Send the clipboard as context with your next message (/paste [message] to send now)"},
+		{Name: "/pin", Description: "Keep a message or file verbatim when the history is compressed (/pin [n|file])"},
+		{Name: "/pins", Description: "List the pinned messages and files"},
+		{Name: "/plan", Description: "Plan with read-only tools before making changes (/plan run to execute)"},
+		{Name: "/resume", Description: "Resume a saved session"},
+		{Name: "/retry", Description: "Answer the last message again (/retry <model> to use another model)"},
+		{Name: "/rollback", Description: "Restore the working tree to a checkpoint (/rollback <n>, latest by default)"},
+		{Name: "/save", Description: "Save the current session"},
+		{Name: "/share", Description: "Upload the conversation, with credentials masked, as a gist or paste and copy the link"},
+		{Name: "/speech", Description: "Toggle speech mode (/speech on|off, /speech calibrate to measure background noise)"},
+		{Name: "/stats", Description: "Show usage statistics for this session and all time"},
+		{Name: "/template", Description: "Insert a prompt template (/template <name>)"},
+		{Name: "/think", Description: "Let models reason before they answer (/think [low|medium|high|off])"},
+		{Name: "/todo", Description: "Show or edit the task checklist (/todo add <task>, /todo done <n>, /todo clear)"},
 		{Name: "/tools", Description: "Toggle tools on/off"},
+		{Name: "/undo", Description: "Undo the last file change (/undo <n>, /undo list)"},
+		{Name: "/unpin", Description: "Unpin a message or file (/unpin [n|file|all])"},
+		{Name: "/voice", Description: "Read responses aloud (/voice on|off)"},
 	}
 }
 
@@ -35,21 +66,8 @@ type ConfigYAML struct {
 
 // GetAvailableModels returns the list of available AI models from config.yaml
 func GetAvailableModels() []string {
-	// Load configuration once
-	configPath := findConfigFile()
-	var conf *cfg.Config
-	if path := configPath; path != "" {
-		data, err := os.ReadFile(path)
-		if err == nil {
-			if err2 := yaml.Unmarshal(data, &conf); err2 == nil {
-				// Expand env vars using existing helper
-				// but easier: load via cfg.LoadConfig which handles expansion
-			}
-		}
-	}
-
-	// Simpler – just load via cfg.LoadConfig API which already does env expansion/validation
-	c, err := cfg.LoadConfig(configPath)
+	// Load configuration; cfg.Load handles env expansion
+	c, err := cfg.Load()
 	if err != nil {
 		// fallback to default list
 		return []string{"claude-3-5-sonnet-20240620", "gpt-4o", "gemini-pro"}
@@ -70,8 +88,7 @@ func GetAvailableModels() []string {
 // GetAvailableModelsByProvider returns models grouped by provider
 func GetAvailableModelsByProvider() map[string][]string {
 	// Load configuration
-	configPath := findConfigFile()
-	c, err := cfg.LoadConfig(configPath)
+	c, err := cfg.Load()
 	if err != nil {
 		// fallback to default grouping
 		return map[string][]string{
@@ -88,28 +105,45 @@ func GetAvailableModelsByProvider() map[string][]string {
 	return orchestration.ModelsByProvider(c)
 }
 
+// modelAvailable reports whether model can be selected: it has a registered provider, or
+// it is "auto" and routing is configured
+func modelAvailable(model string) bool {
+	if model == orchestration.AutoModel {
+		return orchestration.Routing() != nil
+	}
+	_, err := orchestration.ProviderFor(model)
+	return err == nil
+}
+
 // FilterCommands filters slash commands based on the input text
 func FilterCommands(input string) []SlashCommand {
 	if input == "/" || input == "" {
 		return GetAvailableCommands()
 	}
 
-	// Remove the leading "/" for filtering
+	// Remove the leading "/" and any arguments for filtering
 	filterText := strings.ToLower(strings.TrimPrefix(input, "/"))
+	if fields := strings.Fields(filterText); len(fields) > 0 {
+		filterText = fields[0]
+	}
 	allCommands := GetAvailableCommands()
-	var filtered []SlashCommand
+	// Commands whose name matches come before those matching only by description, so
+	// that e.g. "/edit" selects /edit rather than /config ("View and edit settings")
+	var byName, byDesc []SlashCommand
 
 	for _, cmd := range allCommands {
 		// Check if command name (without /) contains the filter text
 		cmdName := strings.ToLower(strings.TrimPrefix(cmd.Name, "/"))
 		cmdDesc := strings.ToLower(cmd.Description)
 
-		if strings.Contains(cmdName, filterText) || strings.Contains(cmdDesc, filterText) {
-			filtered = append(filtered, cmd)
+		if strings.Contains(cmdName, filterText) {
+			byName = append(byName, cmd)
+		} else if strings.Contains(cmdDesc, filterText) {
+			byDesc = append(byDesc, cmd)
 		}
 	}
 
-	return filtered
+	return append(byName, byDesc...)
 }
 
 // GetModelDisplayName returns the full model name for display
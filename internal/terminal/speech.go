@@ -0,0 +1,423 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/speech"
+)
+
+// Placeholders of the input box in speech mode
+const (
+	listeningPlaceholder    = "Listening..."
+	transcribingPlaceholder = "Transcribing..."
+	calibratingPlaceholder  = "Calibrating, stay quiet..."
+)
+
+// maxPendingUtterances bounds the utterances waiting to be transcribed; more are dropped
+const maxPendingUtterances = 8
+
+// recorderStopTimeout bounds how long stopListening waits for the recorder to exit
+const recorderStopTimeout = 2 * time.Second
+
+// The listener of speech mode runs until stopListening. It reports to the running
+// program, which is replaced whenever a full-screen view is opened.
+var (
+	listenMu     sync.Mutex
+	stopListener context.CancelFunc
+	listenerDone chan struct{}
+	program      atomic.Pointer[tea.Program]
+)
+
+// speechMsg reports what the listener of speech mode heard
+type speechMsg struct {
+	status string // shown in the input box, e.g. while transcribing
+	text   string // transcript of an utterance
+	err    error  // transcription failed
+	// stopped is set when the recorder ended; err says why
+	stopped bool
+	// awakeUntil is set when the wake word was heard on its own; the prompt is expected
+	// until then
+	awakeUntil time.Time
+}
+
+// speechCalibratedMsg reports the speech threshold measured by /speech calibrate
+type speechCalibratedMsg struct {
+	threshold float64
+	err       error
+}
+
+// sendToUI delivers msg to the running program; it is dropped between programs
+func sendToUI(msg tea.Msg) {
+	if p := program.Load(); p != nil {
+		p.Send(msg)
+	}
+}
+
+// startListening records the microphone and transcribes every utterance with the
+// backend of speech.backend until stopListening
+func startListening() error {
+	listenMu.Lock()
+	defer listenMu.Unlock()
+	if stopListener != nil || globalConfig == nil {
+		return nil
+	}
+	recognizer, err := speech.NewRecognizer(globalConfig.Speech)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopListener, listenerDone = cancel, make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		listen(ctx, recognizer)
+	}(listenerDone)
+	return nil
+}
+
+// stopListening stops the recorder and drops utterances not transcribed yet. It waits
+// for the recorder to exit, so that it does not outlive magikarp.
+func stopListening() {
+	listenMu.Lock()
+	defer listenMu.Unlock()
+	if stopListener == nil {
+		return
+	}
+	stopListener()
+	select {
+	case <-listenerDone:
+	case <-time.After(recorderStopTimeout):
+	}
+	stopListener, listenerDone = nil, nil
+}
+
+// listen runs the recorder and transcribes its utterances one at a time, so a slow
+// transcription does not hold up the recording
+func listen(ctx context.Context, recognizer *speech.Recognizer) {
+	utterances := make(chan []byte, maxPendingUtterances)
+	gate := speech.NewWakeGate(globalConfig.Speech, recognizer)
+	go func() {
+		for pcm := range utterances {
+			if gate == nil {
+				sendToUI(speechMsg{status: transcribingPlaceholder})
+				text, err := recognizer.Transcribe(ctx, pcm)
+				if ctx.Err() == nil {
+					sendToUI(speechMsg{text: text, err: err})
+				}
+				continue
+			}
+			// Only what follows the wake word is a prompt
+			heard, err := gate.Hear(ctx, pcm)
+			if ctx.Err() == nil {
+				sendToUI(speechMsg{text: heard.Prompt, awakeUntil: heard.AwakeUntil, err: err})
+			}
+		}
+	}()
+
+	err := speech.Listen(ctx, globalConfig.Speech, func(pcm []byte) {
+		if voiceBusy() {
+			return // the microphone hears the response being read aloud
+		}
+		select {
+		case utterances <- pcm:
+		default:
+		}
+	})
+	close(utterances)
+	if ctx.Err() == nil {
+		sendToUI(speechMsg{stopped: true, err: err})
+	}
+}
+
+// listeningPlaceholder is shown in the input box while speech mode listens
+func (m *InputModel) listeningPlaceholder() string {
+	if word := GetWakeWord(); word != "" && !m.awake() {
+		return fmt.Sprintf("Say %q and your prompt...", word)
+	}
+	return listeningPlaceholder
+}
+
+// awake reports whether speech mode heard the wake word and waits for the prompt
+func (m InputModel) awake() bool {
+	return time.Now().Before(m.awakeUntil)
+}
+
+// setSpeechMode turns speech mode on or off and returns an error reply when the
+// listener cannot start
+func (m *InputModel) setSpeechMode(on bool) string {
+	if on {
+		if err := startListening(); err != nil {
+			SetSpeechModeEnabled(false)
+			m.speechMode = false
+			m.textInput.Placeholder = ""
+			return "System: Cannot start speech mode: " + err.Error()
+		}
+	} else {
+		stopListening()
+	}
+	m.speechMode = on
+	SetSpeechModeEnabled(on)
+	m.textInput.Placeholder = ""
+	if on {
+		m.textInput.Placeholder = m.listeningPlaceholder()
+	}
+	return ""
+}
+
+// runSpeechCommand handles /speech: without arguments it toggles speech mode, and
+// calibrate measures the background noise to set speech.threshold
+func (m *InputModel) runSpeechCommand(args []string) (string, tea.Cmd) {
+	action := "toggle"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	switch action {
+	case "toggle":
+		return m.setSpeechMode(!m.speechMode), nil
+	case "on":
+		return m.setSpeechMode(true), nil
+	case "off":
+		return m.setSpeechMode(false), nil
+	case "calibrate":
+		if m.calibrating {
+			return "System: Calibration is already running", nil
+		}
+		if globalConfig == nil {
+			return "System: No configuration loaded", nil
+		}
+		// The listener would compete for the microphone; it restarts with the new threshold
+		stopListening()
+		m.calibrating = true
+		m.textInput.Placeholder = calibratingPlaceholder
+		conf := globalConfig.Speech
+		return fmt.Sprintf("System: Measuring the background noise for %s. Stay quiet...", speech.CalibrationTime), func() tea.Msg {
+			threshold, err := speech.Calibrate(context.Background(), conf)
+			return speechCalibratedMsg{threshold: threshold, err: err}
+		}
+	}
+	return "System: Usage: /speech [on|off|calibrate]", nil
+}
+
+// handleSpeechCalibrated applies the threshold measured by /speech calibrate for the
+// rest of the session and resumes listening
+func (m *InputModel) handleSpeechCalibrated(msg speechCalibratedMsg) {
+	m.calibrating = false
+	m.textInput.Placeholder = ""
+	reply := "System: Calibration failed: "
+	if msg.err != nil {
+		reply += msg.err.Error()
+	} else {
+		globalConfig.Speech.Threshold = msg.threshold
+		reply = fmt.Sprintf("System: Speech threshold set to %.0f for this session. To keep it, set speech.threshold: %.0f in config.yaml.", msg.threshold, msg.threshold)
+	}
+	if m.speechMode {
+		if failed := m.setSpeechMode(true); failed != "" {
+			reply += "\n" + failed
+		}
+	}
+	m.AddConversationPair("/speech calibrate", reply)
+}
+
+// handleSpeech takes in what the listener heard. A transcript is sent as a message when
+// the input box is empty and the model is idle; otherwise it is added to the input box.
+func (m *InputModel) handleSpeech(msg speechMsg) tea.Cmd {
+	if !m.speechMode {
+		return nil // heard before speech mode was turned off
+	}
+	switch {
+	case msg.stopped:
+		m.setSpeechMode(false)
+		reason := "the recorder exited"
+		if msg.err != nil {
+			reason = msg.err.Error()
+		}
+		m.AddConversationPair("/speech", "System: Speech mode stopped: "+reason)
+		return nil
+	case msg.status != "":
+		m.textInput.Placeholder = msg.status
+		return nil
+	case !msg.awakeUntil.IsZero():
+		m.awakeUntil = msg.awakeUntil
+		m.textInput.Placeholder = m.listeningPlaceholder()
+		// Drop the listening indicator once the prompt is no longer expected
+		return tea.Tick(time.Until(msg.awakeUntil), func(time.Time) tea.Msg { return speechMsg{} })
+	}
+	if msg.text != "" {
+		m.awakeUntil = time.Time{}
+	}
+
+	m.textInput.Placeholder = m.listeningPlaceholder()
+	if msg.err != nil {
+		m.AddConversationPair("/speech", "System: Transcription failed: "+msg.err.Error())
+		return nil
+	}
+	text := strings.TrimSpace(msg.text)
+	if text == "" {
+		return nil
+	}
+	if GetSpeechAutoSend() && strings.TrimSpace(m.textInput.Value()) == "" && !m.turnRunning() && m.pendingApproval == nil && m.pendingReview == nil {
+		return m.send(text)
+	}
+	if m.textInput.Value() != "" && !strings.HasSuffix(m.textInput.Value(), " ") {
+		text = " " + text
+	}
+	m.textInput.InsertString(text)
+	return nil
+}
+
+// Push-to-talk works with taps and with holding the key. Terminals report no key
+// releases, but a held key repeats: presses soon after the previous one are repeats,
+// and the key counts as released once they stop.
+const (
+	keyRepeatWindow = 700 * time.Millisecond
+	keyReleaseAfter = 300 * time.Millisecond
+	// minRecording is the shortest recording that is transcribed
+	minRecording = 300 * time.Millisecond
+)
+
+// pushToTalkState is the progress of a push-to-talk recording
+type pushToTalkState int
+
+const (
+	pushToTalkIdle pushToTalkState = iota
+	pushToTalkRecording
+	pushToTalkTranscribing
+)
+
+// pushToTalk is the push-to-talk recording of the input
+type pushToTalk struct {
+	state   pushToTalkState
+	id      int                // number of the latest recording
+	stop    context.CancelFunc // stops the recording
+	pressed time.Time          // last press of the key
+	held    bool               // the key repeats, so it is being held
+}
+
+// pushToTalkMsg carries the transcript of push-to-talk recording id
+type pushToTalkMsg struct {
+	id   int
+	text string
+	err  error
+}
+
+// pushToTalkTickMsg checks whether the held push-to-talk key was released
+type pushToTalkTickMsg struct{ id int }
+
+func pushToTalkTick(id int) tea.Cmd {
+	return tea.Tick(keyReleaseAfter/3, func(time.Time) tea.Msg { return pushToTalkTickMsg{id} })
+}
+
+// pressPushToTalk handles the push-to-talk key: it starts a recording, or stops the
+// running one and has it transcribed. The continuous listener of speech mode pauses
+// meanwhile.
+func (m *InputModel) pressPushToTalk() tea.Cmd {
+	now := time.Now()
+	last := m.ptt.pressed
+	m.ptt.pressed = now
+	switch m.ptt.state {
+	case pushToTalkRecording:
+		if now.Sub(last) < keyRepeatWindow {
+			m.ptt.held = true
+			return nil
+		}
+		m.stopPushToTalk()
+		return nil
+	case pushToTalkTranscribing:
+		return nil
+	}
+	if globalConfig == nil {
+		return nil
+	}
+	recognizer, err := speech.NewRecognizer(globalConfig.Speech)
+	if err != nil {
+		m.AddConversationPair("/speech", "System: Cannot record: "+err.Error())
+		return nil
+	}
+	if m.speechMode {
+		stopListening()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.ptt.id++
+	m.ptt.state, m.ptt.stop, m.ptt.held = pushToTalkRecording, cancel, false
+	m.textInput.Placeholder = "Recording... release or press " + GetPushToTalkKey() + " to stop, esc to cancel"
+	conf, id := globalConfig.Speech, m.ptt.id
+	record := func() tea.Msg {
+		pcm, err := speech.Record(ctx, conf)
+		if err != nil {
+			return pushToTalkMsg{id: id, err: err}
+		}
+		if speech.Duration(pcm) < minRecording {
+			return pushToTalkMsg{id: id}
+		}
+		text, err := recognizer.Transcribe(context.Background(), pcm)
+		return pushToTalkMsg{id: id, text: text, err: err}
+	}
+	return tea.Batch(record, pushToTalkTick(id))
+}
+
+// checkPushToTalkRelease stops the recording once a held key stops repeating
+func (m *InputModel) checkPushToTalkRelease(msg pushToTalkTickMsg) tea.Cmd {
+	if msg.id != m.ptt.id || m.ptt.state != pushToTalkRecording {
+		return nil
+	}
+	if m.ptt.held && time.Since(m.ptt.pressed) > keyReleaseAfter {
+		m.stopPushToTalk()
+		return nil
+	}
+	return pushToTalkTick(msg.id)
+}
+
+// stopPushToTalk ends the recording; its transcript arrives as a pushToTalkMsg
+func (m *InputModel) stopPushToTalk() {
+	m.ptt.stop()
+	m.ptt.state = pushToTalkTranscribing
+	m.textInput.Placeholder = transcribingPlaceholder
+}
+
+// cancelPushToTalk drops the push-to-talk recording, or its transcript
+func (m *InputModel) cancelPushToTalk() {
+	if m.ptt.stop != nil {
+		m.ptt.stop()
+	}
+	m.finishPushToTalk()
+}
+
+// finishPushToTalk returns to typing, and to listening in speech mode
+func (m *InputModel) finishPushToTalk() {
+	m.ptt.state, m.ptt.stop = pushToTalkIdle, nil
+	m.textInput.Placeholder = ""
+	if m.speechMode {
+		m.textInput.Placeholder = m.listeningPlaceholder()
+		if err := startListening(); err != nil {
+			m.setSpeechMode(false)
+			m.AddConversationPair("/speech", "System: Cannot resume speech mode: "+err.Error())
+		}
+	}
+}
+
+// handlePushToTalk puts the transcript of a push-to-talk recording in the input box at
+// the cursor, to be reviewed before it is sent
+func (m *InputModel) handlePushToTalk(msg pushToTalkMsg) {
+	if msg.id != m.ptt.id || m.ptt.state == pushToTalkIdle {
+		return // cancelled
+	}
+	m.finishPushToTalk()
+	if msg.err != nil {
+		m.AddConversationPair("/speech", "System: Push-to-talk failed: "+msg.err.Error())
+		return
+	}
+	text := strings.TrimSpace(msg.text)
+	if text == "" {
+		return
+	}
+	if v := m.textInput.Value(); v != "" && !strings.HasSuffix(v, " ") && !strings.HasSuffix(v, "\n") {
+		text = " " + text
+	}
+	m.textInput.InsertString(text)
+}
@@ -31,3 +31,61 @@ func SetSpeechModeEnabled(enabled bool) {
 func SpeechModeEnabled() bool {
 	return speechEnabled.Load()
 }
+
+// voice global flag: responses are read aloud
+var voiceEnabled atomic.Bool
+
+// SetVoiceEnabled sets the global voice flag
+func SetVoiceEnabled(enabled bool) {
+	voiceEnabled.Store(enabled)
+}
+
+// VoiceEnabled returns whether responses are read aloud (/voice)
+func VoiceEnabled() bool {
+	return voiceEnabled.Load()
+}
+
+// dry run global flag: file edits and commands are reported instead of made (/dryrun)
+var dryRunEnabled atomic.Bool
+
+// SetDryRunEnabled sets the global dry run flag
+func SetDryRunEnabled(enabled bool) {
+	dryRunEnabled.Store(enabled)
+}
+
+// DryRunEnabled returns whether tools report their changes instead of making them
+func DryRunEnabled() bool {
+	return dryRunEnabled.Load()
+}
+
+// plan mode global flag and the plan approved for execution
+var (
+	planEnabled  atomic.Bool
+	approvedPlan atomic.Value // string
+)
+
+// SetPlanModeEnabled sets the global plan mode flag
+func SetPlanModeEnabled(enabled bool) {
+	planEnabled.Store(enabled)
+}
+
+// PlanModeEnabled returns whether plan mode is on: tools are read-only and the model
+// answers with a plan instead of acting
+func PlanModeEnabled() bool {
+	return planEnabled.Load()
+}
+
+// SetApprovedPlan stores the plan being executed; empty clears it
+func SetApprovedPlan(plan string) {
+	approvedPlan.Store(plan)
+}
+
+// ApprovedPlan returns the plan being executed, or an empty string
+func ApprovedPlan() string {
+	if v := approvedPlan.Load(); v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/stats"
+)
+
+const (
+	// statsDays is the number of days charted in the all-time view
+	statsDays = 30
+	// statsRecentRequests is the number of requests charted in the session view
+	statsRecentRequests = 40
+	// statsTableRows limits the model and tool tables
+	statsTableRows = 10
+)
+
+// sparkBlocks are the bar heights of a sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// StatsModel is the full-screen usage dashboard shown by /stats
+type StatsModel struct {
+	width    int
+	height   int
+	allTime  bool
+	session  []stats.Event
+	history  []stats.Event
+	err      error
+	quitting bool
+}
+
+// NewStatsModel loads the usage of this session and the usage log
+func NewStatsModel() StatsModel {
+	history, err := stats.Load()
+	return StatsModel{
+		width:   80,
+		height:  24,
+		session: stats.SessionEvents(),
+		history: history,
+		err:     err,
+	}
+}
+
+// Init initializes the stats model
+func (m StatsModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages for the stats model
+func (m StatsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab", "shift+tab", "left", "right", "h", "l":
+			m.allTime = !m.allTime
+		case "enter", "esc", "q":
+			m.quitting = true
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+// View renders the dashboard
+func (m StatsModel) View() string {
+	if m.quitting {
+		return ""
+	}
+
+	s := renderWelcomeBox() + "\n\n"
+	sessionTab, allTimeTab := modelSelectActiveStyle.Render("[ This session ]"), modelSelectNormalStyle.Render("  All time  ")
+	if m.allTime {
+		sessionTab, allTimeTab = modelSelectNormalStyle.Render("  This session  "), modelSelectActiveStyle.Render("[ All time ]")
+	}
+	s += " " + versionStyle.Render("Usage statistics") + "   " + sessionTab + " " + allTimeTab + "\n\n"
+
+	events := m.session
+	if m.allTime {
+		events = m.history
+	}
+	summary := stats.Summarize(events)
+
+	switch {
+	case m.allTime && m.err != nil:
+		s += configErrorStyle.Render("  "+m.err.Error()) + "\n"
+	case summary.Requests == 0 && summary.ToolCalls == 0:
+		s += modelSelectNormalStyle.Render("  No usage recorded yet.") + "\n"
+	default:
+		s += m.renderTotals(summary) + "\n"
+		s += m.renderCharts(events) + "\n"
+		s += renderModelTable(summary.Models) + "\n"
+		if len(summary.Tools) > 0 {
+			s += renderToolTable(summary.Tools) + "\n"
+		}
+	}
+
+	if m.allTime {
+		if path, err := stats.Path(); err == nil {
+			s += modelSelectHelpStyle.Render(" Recorded in "+displayPath(path)) + "\n"
+		}
+	}
+	s += "\n" + modelSelectHelpStyle.Render(" tab: switch view • esc: close") + "\n"
+	return s
+}
+
+// renderTotals renders the headline numbers of summary
+func (m StatsModel) renderTotals(summary stats.Summary) string {
+	line := fmt.Sprintf("  %d requests • %s in / %s out • %s • %d tool calls",
+		summary.Requests, formatTokens(summary.Usage.InputTokens()), formatTokens(summary.Usage.CompletionTokens),
+		formatCost(summary.Cost), summary.ToolCalls)
+	if summary.Usage.CacheReadTokens+summary.Usage.CacheWriteTokens > 0 {
+		line += fmt.Sprintf(" • %s read from cache (%s hit rate)", formatTokens(summary.Usage.CacheReadTokens), formatHitRate(summary.Usage))
+	}
+	if m.allTime {
+		sessions := "sessions"
+		if summary.Sessions == 1 {
+			sessions = "session"
+		}
+		line += fmt.Sprintf(" • %d %s since %s", summary.Sessions, sessions, summary.Since.Local().Format("2006-01-02"))
+	}
+	return helpSectionStyle.Render(line) + "\n"
+}
+
+// renderCharts renders token and cost sparklines: per day for all time, per request
+// for the session
+func (m StatsModel) renderCharts(events []stats.Event) string {
+	var tokens, costs []float64
+	var label string
+	if m.allTime {
+		for _, day := range stats.Daily(events, statsDays, time.Now()) {
+			tokens = append(tokens, float64(day.Tokens))
+			costs = append(costs, day.Cost)
+		}
+		label = fmt.Sprintf("last %d days", statsDays)
+	} else {
+		for _, e := range events {
+			if e.Model != "" {
+				tokens = append(tokens, float64(e.Usage().TotalTokens()))
+				costs = append(costs, e.Cost)
+			}
+		}
+		if len(tokens) > statsRecentRequests {
+			tokens = tokens[len(tokens)-statsRecentRequests:]
+			costs = costs[len(costs)-statsRecentRequests:]
+		}
+		label = fmt.Sprintf("last %d requests", len(tokens))
+	}
+
+	s := "  " + helpSectionStyle.Render("Activity") + modelSelectHelpStyle.Render(" ("+label+")") + "\n"
+	s += fmt.Sprintf("  %s %s %s\n", modelSelectNormalStyle.Render(fmt.Sprintf("%-8s", "tokens")), sparklineStyle.Render(sparkline(tokens)),
+		modelSelectHelpStyle.Render("peak "+formatTokens(int(maxValue(tokens)))))
+	s += fmt.Sprintf("  %s %s %s\n", modelSelectNormalStyle.Render(fmt.Sprintf("%-8s", "cost")), sparklineStyle.Render(sparkline(costs)),
+		modelSelectHelpStyle.Render("peak "+formatCost(maxValue(costs))))
+	return s
+}
+
+// renderModelTable renders per-model usage, most expensive first
+func renderModelTable(models []stats.ModelStats) string {
+	s := helpSectionStyle.Render(fmt.Sprintf("  %-32s %9s %10s %10s %10s %10s", "Model", "Requests", "Tokens in", "Tokens out", "Cache hit", "Cost")) + "\n"
+	for i, entry := range models {
+		if i >= statsTableRows {
+			s += modelSelectHelpStyle.Render(fmt.Sprintf("  … %d more", len(models)-statsTableRows)) + "\n"
+			break
+		}
+		s += modelSelectNormalStyle.Render(fmt.Sprintf("  %-32s %9d %10s %10s %10s %10s",
+			truncateCell(entry.Model, 32), entry.Requests, formatTokens(entry.Usage.InputTokens()),
+			formatTokens(entry.Usage.CompletionTokens), formatHitRate(entry.Usage), formatCost(entry.Cost))) + "\n"
+	}
+	return s
+}
+
+// renderToolTable renders per-tool invocations, most used first
+func renderToolTable(tools []stats.ToolStats) string {
+	s := helpSectionStyle.Render(fmt.Sprintf("  %-32s %9s %10s", "Tool", "Calls", "Errors")) + "\n"
+	for i, tool := range tools {
+		if i >= statsTableRows {
+			s += modelSelectHelpStyle.Render(fmt.Sprintf("  … %d more", len(tools)-statsTableRows)) + "\n"
+			break
+		}
+		s += modelSelectNormalStyle.Render(fmt.Sprintf("  %-32s %9d %10d", truncateCell(tool.Name, 32), tool.Calls, tool.Errors)) + "\n"
+	}
+	return s
+}
+
+// sparkline renders values as a row of block characters scaled to the largest value.
+// Zero values are left blank so idle periods stand out.
+func sparkline(values []float64) string {
+	peak := maxValue(values)
+	var b strings.Builder
+	for _, v := range values {
+		if v <= 0 || peak <= 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		i := int(math.Ceil(v/peak*float64(len(sparkBlocks)))) - 1
+		b.WriteRune(sparkBlocks[max(0, min(i, len(sparkBlocks)-1))])
+	}
+	return b.String()
+}
+
+func maxValue(values []float64) float64 {
+	peak := 0.0
+	for _, v := range values {
+		peak = math.Max(peak, v)
+	}
+	return peak
+}
+
+// formatHitRate renders the prompt cache hit rate of usage, or "-" when nothing
+// was cached
+func formatHitRate(usage providers.Usage) string {
+	if usage.CacheReadTokens+usage.CacheWriteTokens == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", usage.CacheHitRate()*100)
+}
+
+// truncateCell shortens s to fit a table column of width runes
+func truncateCell(s string, width int) string {
+	if runes := []rune(s); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return s
+}
+
+var sparklineStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#9B59B6"))
@@ -0,0 +1,157 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	convctx "github.com/pprunty/magikarp/internal/context"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+// statusRefreshInterval is how often the git branch in the status bar is re-read
+const statusRefreshInterval = 5 * time.Second
+
+// gitBranchMsg carries the branch of the working directory, or "" outside a repository
+type gitBranchMsg string
+
+// readGitBranch reads the current branch in the background
+func readGitBranch() tea.Msg {
+	return gitBranchMsg(currentGitBranch())
+}
+
+// refreshGitBranch re-reads the branch after statusRefreshInterval, so checkouts made
+// by tools or in another terminal show up
+func refreshGitBranch() tea.Cmd {
+	return tea.Tick(statusRefreshInterval, func(time.Time) tea.Msg {
+		return readGitBranch()
+	})
+}
+
+// currentGitBranch returns the checked out branch, the short commit hash when HEAD is
+// detached, or "" when the working directory is not in a git repository
+func currentGitBranch() string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if out, err := gitexec.Run(ctx, "", "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		return strings.TrimSpace(out)
+	}
+	if out, err := gitexec.Run(ctx, "", "rev-parse", "--short", "HEAD"); err == nil {
+		return strings.TrimSpace(out)
+	}
+	return ""
+}
+
+// contextUsed estimates the share of the model's context window the next request
+// takes: the system prompt, the history sent with it and the message being typed
+func (m InputModel) contextUsed() float64 {
+	opts, _ := GetContextOptions()
+	pending := m.textInput.Value()
+	if globalConfig != nil {
+		pending = globalConfig.System + "\n" + pending
+	}
+	return convctx.UsedFraction(m.provider, m.history(), pending, opts)
+}
+
+// renderStatusBar renders the line below the input box: model, context window used,
+// session cost, git branch and the current toggles. It is cut to the terminal width.
+func (m InputModel) renderStatusBar() string {
+	dot := func(on bool) string {
+		if on {
+			return speechModeOnStyle.Render("•")
+		}
+		return speechModeOffStyle.Render("•")
+	}
+	item := func(text string) string {
+		return modelRunningStyle.Render(text)
+	}
+
+	segments := []string{item("• " + GetModelDisplayName(m.provider))}
+
+	// Context window, turning orange as it nears the compression threshold
+	used := m.contextUsed()
+	ctxText := fmt.Sprintf("ctx %d%%", int(used*100+0.5))
+	opts, compress := GetContextOptions()
+	threshold := opts.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = convctx.DefaultThreshold
+	}
+	if compress && used >= threshold*0.75 {
+		segments = append(segments, contextWarningStyle.Render("• "+ctxText))
+	} else {
+		segments = append(segments, item("• "+ctxText))
+	}
+
+	usage, cost := orchestration.Session().Totals()
+	costText := "• " + formatCost(cost)
+	if usage.TotalTokens() > 0 {
+		costText += " (" + formatTokens(usage.TotalTokens()) + " tokens)"
+	}
+	segments = append(segments, item(costText))
+
+	if m.gitBranch != "" {
+		segments = append(segments, item("• ⎇ "+m.gitBranch))
+	}
+	if name := m.branchName(); name != "" {
+		segments = append(segments, dot(true)+" "+item("⑂ "+name))
+	}
+
+	if GetToolsEnabled() {
+		segments = append(segments, dot(true)+" "+item("tools on"))
+	} else {
+		segments = append(segments, dot(false)+" "+item("tools off"))
+	}
+	if thinking := sessionThinking(); thinking.Enabled() {
+		segments = append(segments, dot(true)+" "+item("thinking "+thinking.Effort))
+	}
+	if m.worktree != nil {
+		segments = append(segments, dot(true)+" "+item("isolated"))
+	}
+	if PlanModeEnabled() {
+		segments = append(segments, dot(true)+" "+item("plan mode"))
+	}
+	if DryRunEnabled() {
+		segments = append(segments, dot(true)+" "+item("dry run"))
+	}
+	if m.pastedClipboard != "" {
+		segments = append(segments, dot(true)+" "+item("clipboard attached"))
+	}
+	switch m.ptt.state {
+	case pushToTalkRecording:
+		segments = append(segments, dot(true)+" "+item("recording"))
+	case pushToTalkTranscribing:
+		segments = append(segments, dot(true)+" "+item("transcribing"))
+	}
+	if VoiceEnabled() {
+		segments = append(segments, dot(true)+" "+item("voice on"))
+	}
+	if SpeechModeEnabled() && m.awake() {
+		segments = append(segments, dot(true)+" "+item("listening"))
+	} else if SpeechModeEnabled() && GetWakeWord() != "" {
+		segments = append(segments, dot(true)+" "+item(fmt.Sprintf("speech-to-text on (say %q)", GetWakeWord())))
+	} else if SpeechModeEnabled() {
+		segments = append(segments, dot(true)+" "+item("speech-to-text on"))
+	} else {
+		segments = append(segments, dot(false)+" "+item("speech-to-text off"))
+	}
+
+	if mode := m.textInput.VimMode(); mode != "" {
+		segments = append(segments, dot(true)+" "+item(mode))
+	}
+	if lines := m.textInput.LineCount(); lines > 1 {
+		segments = append(segments, dot(false)+" "+item(fmt.Sprintf("%d lines", lines)))
+	}
+
+	bar := strings.Join(segments, " ")
+	if m.width > 0 {
+		bar = lipgloss.NewStyle().MaxWidth(m.width).Render(bar)
+	}
+	return bar
+}
+
+var contextWarningStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FF6B35"))
@@ -0,0 +1,166 @@
+package terminal
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// chatStream holds the channels of a streaming response: answer deltas, reasoning
+// deltas for models that think out loud, and the error that ends the stream early
+type chatStream struct {
+	chunks    <-chan string
+	reasoning <-chan string
+	errs      <-chan error
+}
+
+// streamChunkMsg carries a single delta from a streaming response
+type streamChunkMsg struct {
+	delta     string
+	reasoning bool // delta is reasoning rather than answer text
+	stream    chatStream
+}
+
+// streamDoneMsg is sent once the streaming channel has been drained; err is the error
+// that ended the stream early, if any
+type streamDoneMsg struct {
+	err error
+}
+
+// turnTextMsg carries the text of a reply streamed during a turn with tools. reset
+// drops the text shown so far once the reply turns out to call tools.
+type turnTextMsg struct {
+	delta  string
+	reset  bool
+	events <-chan tea.Msg
+}
+
+// streamingEnabled reports whether replies of model are shown as they are generated;
+// providers can opt out individually via `stream: false` in config.yaml
+func streamingEnabled(model string) bool {
+	p, err := orchestration.ProviderFor(model)
+	if err != nil {
+		return false
+	}
+	return GetStreamingEnabled(p.Name())
+}
+
+// shouldStream reports whether the next message for model should be sent with
+// StreamChat. That is only done for plain chat, as StreamChat does not take tools;
+// turns with tools stream their replies through Turn.OnText where the provider can.
+func shouldStream(model string) bool {
+	if GetToolsEnabled() || PlanModeEnabled() {
+		return false
+	}
+	return streamingEnabled(model)
+}
+
+// streamTurnText returns the Turn.OnText callback that shows the replies of a turn as
+// they are generated, or nil when streaming is off for model
+func streamTurnText(ctx context.Context, model string, events chan tea.Msg) func(string) {
+	if !streamingEnabled(model) {
+		return nil
+	}
+	return func(delta string) {
+		sendTurnEvent(ctx, events, turnTextMsg{delta: delta, events: events})
+	}
+}
+
+// streamMessageAsync starts a streaming request and returns the first chunk as a tea.Msg.
+// If the provider cannot open a stream, it falls back to the blocking request path.
+// Cancelling ctx closes the stream.
+func streamMessageAsync(ctx context.Context, userMessage, model string, history []providers.ChatMessage) tea.Cmd {
+	return func() tea.Msg {
+		p, err := orchestration.ProviderFor(model)
+		if err != nil {
+			return aiResponseMsg{response: "Error getting provider: " + err.Error(), isError: true}
+		}
+
+		if err := hooks.Run(ctx, hooks.Event{Event: hooks.PrePrompt, Model: model, Prompt: userMessage}); err != nil {
+			return aiResponseMsg{response: "Error: " + err.Error(), isError: true}
+		}
+		ctx = hooks.WithRan(ctx, hooks.PrePrompt)
+
+		sysPrompt := systemPrompt()
+
+		messages := []providers.ChatMessage{{Role: providers.RoleSystem, Content: sysPrompt}}
+		messages = append(messages, history...)
+		messages = append(messages, providers.ChatMessage{Role: providers.RoleUser, Content: userMessage})
+		if err := orchestration.CheckContextWindow(ctx, model, messages, nil); err != nil {
+			return aiResponseMsg{response: "Error: " + err.Error(), isError: true}
+		}
+
+		SetCurrentModel(model)
+
+		temperature := 0.0
+		if globalConfig != nil {
+			temperature = globalConfig.GetEffectiveTemperature(p.Name())
+		}
+
+		reasoning := make(chan string, 100)
+		streamCtx := orchestration.WithSessionUsage(ctx, model)
+		streamCtx = orchestration.WithThinking(streamCtx, model, sessionThinking())
+		streamCtx = providers.WithReasoningRecorder(streamCtx, func(delta string) {
+			select {
+			case reasoning <- delta:
+			case <-ctx.Done():
+			}
+		})
+		// The provider reports the error before closing the stream, so it is buffered
+		// by the time the end of the stream is seen
+		errs := make(chan error, 1)
+		streamCtx = providers.WithStreamErrorRecorder(streamCtx, func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		})
+		chunks, err := p.StreamChat(streamCtx, model, messages, temperature)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil // cancelled before the stream opened
+			}
+			inputDebugLog("StreamChat failed for %s, falling back to blocking mode: %v", model, err)
+			return processMessageAsync(ctx, userMessage, model, history)()
+		}
+
+		return waitForStreamChunk(chatStream{chunks: chunks, reasoning: reasoning, errs: errs})()
+	}
+}
+
+// runPostResponseHooks runs the post_response hooks for a streamed answer
+func runPostResponseHooks(model string, pair ConversationPair) tea.Cmd {
+	if !hooks.Has(hooks.PostResponse) {
+		return nil
+	}
+	return func() tea.Msg {
+		ev := hooks.Event{Event: hooks.PostResponse, Model: model, Prompt: pair.UserMessage, Response: pair.AIResponse}
+		if err := hooks.Run(context.Background(), ev); err != nil {
+			inputDebugLog("%v", err)
+		}
+		return nil
+	}
+}
+
+// waitForStreamChunk blocks until the next answer or reasoning delta arrives on stream
+func waitForStreamChunk(stream chatStream) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case delta, ok := <-stream.chunks:
+			if !ok {
+				select {
+				case err := <-stream.errs:
+					return streamDoneMsg{err: err}
+				default:
+					return streamDoneMsg{}
+				}
+			}
+			return streamChunkMsg{delta: delta, stream: stream}
+		case delta := <-stream.reasoning:
+			return streamChunkMsg{delta: delta, reasoning: true, stream: stream}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package terminal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pprunty/magikarp/internal/tasks"
+)
+
+// maxPanelTasks is how many tasks the panel above the input shows; finished tasks
+// make room for the open ones first
+const maxPanelTasks = 8
+
+// todoUsage lists the forms of /todo
+const todoUsage = "System: Usage: /todo [add <task> | start <n> | done <n> | undo <n> | rm <n> | clear]"
+
+// runTodoCommand shows or changes the checklist (/todo, /todo add <task>, /todo start
+// <n>, /todo done <n>, /todo undo <n>, /todo rm <n>, /todo clear)
+func runTodoCommand(args []string) string {
+	if len(args) == 0 {
+		list := tasks.List()
+		if len(list) == 0 {
+			return "System: The checklist is empty. Add a task with /todo add <task>, or ask the model to plan the work."
+		}
+		done, total := tasks.Counts()
+		return fmt.Sprintf("System: %d of %d tasks done:\n%s", done, total, tasks.Format(list))
+	}
+
+	switch action := strings.ToLower(args[0]); action {
+	case "add":
+		n, err := tasks.Add(strings.Join(args[1:], " "))
+		if err != nil {
+			return "System: " + err.Error()
+		}
+		return fmt.Sprintf("System: Added task %d", n)
+	case "clear":
+		tasks.Clear()
+		return "System: Cleared the checklist"
+	case "start", "done", "undo", "rm":
+		if len(args) != 2 {
+			return todoUsage
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			return todoUsage
+		}
+		var t tasks.Task
+		switch action {
+		case "start":
+			t, err = tasks.SetStatus(n, tasks.InProgress)
+		case "done":
+			t, err = tasks.SetStatus(n, tasks.Done)
+		case "undo":
+			t, err = tasks.SetStatus(n, tasks.Pending)
+		case "rm":
+			t, err = tasks.Remove(n)
+		}
+		if err != nil {
+			return "System: " + err.Error()
+		}
+		if action == "rm" {
+			return fmt.Sprintf("System: Removed task %d: %s", n, t.Title)
+		}
+		return fmt.Sprintf("System: Task %d is %s: %s", n, strings.ReplaceAll(string(t.Status), "_", " "), t.Title)
+	}
+	return todoUsage
+}
+
+// renderTaskPanel renders the checklist shown above the input; "" when it is empty
+func renderTaskPanel(width int) string {
+	list := tasks.List()
+	if len(list) == 0 {
+		return ""
+	}
+	done, total := tasks.Counts()
+
+	// Hide the oldest finished tasks when the list is too long for the panel
+	shown := make([]bool, len(list))
+	hidden := max(0, len(list)-maxPanelTasks)
+	for i, t := range list {
+		if hidden > 0 && t.Status == tasks.Done {
+			hidden--
+			continue
+		}
+		shown[i] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(taskPanelTitleStyle.Render(fmt.Sprintf("  Tasks %d/%d", done, total)) + "\n")
+	count := 0
+	for i, t := range list {
+		if !shown[i] {
+			continue
+		}
+		if count == maxPanelTasks {
+			b.WriteString(helpDisplayStyle.Render(fmt.Sprintf("    … %d more", len(list)-i)) + "\n")
+			break
+		}
+		count++
+		line := fmt.Sprintf("    %s %s", taskMark(t.Status), truncateCell(t.Title, max(10, width-8)))
+		b.WriteString(taskStyle(t.Status).Render(line) + "\n")
+	}
+	return b.String()
+}
+
+// taskMark is the symbol of a status in the panel
+func taskMark(s tasks.Status) string {
+	switch s {
+	case tasks.Done:
+		return "✓"
+	case tasks.InProgress:
+		return "◐"
+	}
+	return "○"
+}
+
+// taskStyle colours a task by its status
+func taskStyle(s tasks.Status) lipgloss.Style {
+	switch s {
+	case tasks.Done:
+		return helpDisplayStyle
+	case tasks.InProgress:
+		return taskActiveStyle
+	}
+	return lipgloss.NewStyle()
+}
+
+var (
+	taskPanelTitleStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#04B575")).
+				Bold(true)
+
+	taskActiveStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#9B59B6"))
+)
@@ -0,0 +1,194 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/pprunty/magikarp/internal/templates"
+)
+
+// maxTemplateResults is the number of templates listed by the /template picker
+const maxTemplateResults = 8
+
+// templatePicker is the state of the /template picker: choosing a template, then
+// filling in its variables one at a time
+type templatePicker struct {
+	all     []templates.Template
+	query   string
+	matches []templates.Template
+	cursor  int
+
+	// selected is set once a template is chosen and its variables are being filled
+	selected *templates.Template
+	variable int
+	values   map[string]string
+	input    textinput.Model
+}
+
+// runTemplateCommand handles "/template [name]". Without a name the picker lists every
+// template; with one it goes straight to filling in that template's variables.
+// It returns a system reply when the picker cannot be opened.
+func (m *InputModel) runTemplateCommand(args []string) string {
+	dirs := templates.Dirs()
+	list, err := templates.Load(dirs...)
+	if err != nil {
+		return "System: " + err.Error()
+	}
+	if len(list) == 0 {
+		return "System: No prompt templates found. Add Markdown files with {{variable}} placeholders to " +
+			strings.Join(displayPaths(dirs), " or ")
+	}
+
+	m.templatePicker = &templatePicker{all: list}
+	m.updateTemplateMatches()
+	if len(args) > 0 {
+		name := strings.Join(args, " ")
+		for _, t := range list {
+			if t.Name == name {
+				m.selectTemplate(t)
+				return ""
+			}
+		}
+		m.templatePicker.query = name
+		m.updateTemplateMatches()
+	}
+	return ""
+}
+
+// displayPaths shortens each path with displayPath
+func displayPaths(paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = displayPath(p)
+	}
+	return out
+}
+
+// updateTemplateMatches filters the templates by the query typed in the picker
+func (m *InputModel) updateTemplateMatches() {
+	picker := m.templatePicker
+	picker.matches = nil
+	query := strings.ToLower(picker.query)
+	for _, t := range picker.all {
+		if strings.Contains(strings.ToLower(t.Name), query) || strings.Contains(strings.ToLower(t.Description), query) {
+			picker.matches = append(picker.matches, t)
+		}
+	}
+	picker.cursor = 0
+}
+
+// selectTemplate starts filling in the variables of t, or inserts it straight away
+// when it has none
+func (m *InputModel) selectTemplate(t templates.Template) {
+	picker := m.templatePicker
+	picker.selected = &t
+	picker.variable = -1
+	picker.values = map[string]string{}
+	picker.input = textinput.New()
+	picker.input.Prompt = ""
+	picker.input.Focus()
+	m.nextTemplateVariable()
+}
+
+// nextTemplateVariable moves to the next variable, inserting the rendered prompt into
+// the input box for review once all have values
+func (m *InputModel) nextTemplateVariable() {
+	picker := m.templatePicker
+	picker.variable++
+	if picker.variable >= len(picker.selected.Variables) {
+		m.textInput.SetValue(picker.selected.Render(picker.values))
+		m.textInput.CursorEnd()
+		m.templatePicker = nil
+		return
+	}
+	name := picker.selected.Variables[picker.variable]
+	picker.input.SetValue("")
+	picker.input.Placeholder = picker.selected.Defaults[name]
+}
+
+// handleTemplatePickerKey handles keys while the /template picker is open
+func (m InputModel) handleTemplatePickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.templatePicker
+	if msg.String() == "esc" || msg.String() == "ctrl+c" {
+		m.templatePicker = nil
+		return m, nil
+	}
+
+	if picker.selected != nil {
+		if msg.String() == "enter" {
+			name := picker.selected.Variables[picker.variable]
+			value := picker.input.Value()
+			if value == "" {
+				value = picker.selected.Defaults[name]
+			}
+			picker.values[name] = value
+			m.nextTemplateVariable()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		picker.input, cmd = picker.input.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		picker.query += string(msg.Runes)
+		m.updateTemplateMatches()
+		return m, nil
+	case tea.KeyBackspace:
+		if runes := []rune(picker.query); len(runes) > 0 {
+			picker.query = string(runes[:len(runes)-1])
+			m.updateTemplateMatches()
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+	case "down":
+		if picker.cursor < min(len(picker.matches), maxTemplateResults)-1 {
+			picker.cursor++
+		}
+	case "enter", "tab":
+		if len(picker.matches) > 0 {
+			m.selectTemplate(picker.matches[picker.cursor])
+		}
+	}
+	return m, nil
+}
+
+// renderTemplatePicker renders the template list, or the variable being filled in
+func (m InputModel) renderTemplatePicker() string {
+	picker := m.templatePicker
+	if t := picker.selected; t != nil {
+		name := t.Variables[picker.variable]
+		s := "\n" + slashCommandActiveStyle.Render(fmt.Sprintf("  Template %s (%d/%d)", t.Name, picker.variable+1, len(t.Variables))) + "\n"
+		return s + "  " + slashCommandNormalStyle.Render(name+": ") + picker.input.View() + "\n\n"
+	}
+
+	s := "\n" + slashCommandActiveStyle.Render(fmt.Sprintf("  Templates `%s':", picker.query)) + "\n"
+	if len(picker.matches) == 0 {
+		return s + slashCommandNormalStyle.Render("  No matching templates") + "\n\n"
+	}
+	for i, t := range picker.matches {
+		if i >= maxTemplateResults {
+			s += slashCommandNormalStyle.Render(fmt.Sprintf("  … %d more", len(picker.matches)-maxTemplateResults)) + "\n"
+			break
+		}
+		desc := t.Description
+		if width := max(20, m.width-len(t.Name)-10); len([]rune(desc)) > width {
+			desc = string([]rune(desc)[:width-1]) + "…"
+		}
+		if i == picker.cursor {
+			s += formatSlashCommand(slashCommandActiveStyle.Render(t.Name), slashCommandActiveStyle.Render(desc)) + "\n"
+		} else {
+			s += formatSlashCommand(slashCommandNormalStyle.Render(t.Name), slashCommandNormalStyle.Render(desc)) + "\n"
+		}
+	}
+	return s + "\n"
+}
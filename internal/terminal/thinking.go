@@ -0,0 +1,67 @@
+package terminal
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// lastThinkingEffort is the effort /think turns thinking back on with
+var lastThinkingEffort = providers.EffortMedium
+
+// sessionThinking returns the thinking asked of models in this session
+func sessionThinking() providers.Thinking {
+	return orchestration.ThinkingFromConfig(globalConfig)
+}
+
+// backgroundThinking reports whether sub-agents and compression think too
+func backgroundThinking() bool {
+	return globalConfig != nil && globalConfig.Thinking.Background
+}
+
+// setThinkingEffort sets the effort of this session; "off" disables thinking
+func setThinkingEffort(effort string) {
+	if globalConfig == nil {
+		return
+	}
+	if effort != "off" {
+		lastThinkingEffort = effort
+	}
+	globalConfig.Thinking.Effort = effort
+}
+
+// runThinkCommand turns extended thinking on or off, or sets its effort (/think,
+// /think low|medium|high, /think off)
+func (m *InputModel) runThinkCommand(args []string) string {
+	if len(args) > 1 {
+		return "System: Usage: /think [low|medium|high|off]"
+	}
+	effort := "toggle"
+	if len(args) == 1 {
+		effort = strings.ToLower(args[0])
+	}
+	switch {
+	case effort == "toggle" && sessionThinking().Enabled():
+		setThinkingEffort("off")
+	case effort == "toggle":
+		setThinkingEffort(lastThinkingEffort)
+	case effort == "on":
+		setThinkingEffort(lastThinkingEffort)
+	case effort == "off" || slices.Contains(providers.Efforts, effort):
+		setThinkingEffort(effort)
+	default:
+		return "System: Usage: /think [low|medium|high|off]"
+	}
+
+	thinking := sessionThinking()
+	if !thinking.Enabled() {
+		return "System: Thinking off"
+	}
+	reply := "System: Thinking on (" + thinking.Effort + " effort): models that support it reason before they answer, shown dimmed above the reply (ctrl+o to expand)"
+	if m.provider != orchestration.AutoModel && !orchestration.SupportsThinking(m.provider) {
+		reply += ". " + m.provider + " answers without thinking; pick a model that supports it with /model"
+	}
+	return reply
+}
@@ -0,0 +1,147 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// toolLabelKeys are the inputs that best describe a tool call, in order of preference
+var toolLabelKeys = []string{"command", "script", "path", "file_path", "pattern", "query", "url", "name"}
+
+// toolCallLabel describes a tool call in one line, e.g. "bash: go test ./..."
+func toolCallLabel(call orchestration.ToolCall) string {
+	detail := ""
+	for _, key := range toolLabelKeys {
+		if v, ok := call.Input[key].(string); ok && strings.TrimSpace(v) != "" {
+			detail = v
+			break
+		}
+	}
+	if detail == "" && len(call.Input) > 0 {
+		if b, err := json.Marshal(call.Input); err == nil {
+			detail = string(b)
+		}
+	}
+	detail = strings.Join(strings.Fields(detail), " ")
+	if detail == "" {
+		return call.Name
+	}
+	return call.Name + ": " + truncateCell(detail, 60)
+}
+
+// toolExpanded reports whether the output of tool block n is shown. Blocks follow
+// tools.output and ctrl+t until they are toggled one by one with /expand.
+func (m InputModel) toolExpanded(n int) bool {
+	if expanded, ok := m.expandedTools[n]; ok {
+		return expanded
+	}
+	return m.expandAllTools != GetToolsOutputEnabled()
+}
+
+// renderToolCalls renders the tool calls of an answer as numbered blocks, collapsed to
+// a header line unless expanded; toolNum counts blocks across the transcript
+func (m InputModel) renderToolCalls(calls []orchestration.ToolCall, width int, toolNum *int) string {
+	var b strings.Builder
+	for _, call := range calls {
+		*toolNum++
+		output := strings.TrimRight(call.Result.Content, "\n")
+		lines := strings.Split(output, "\n")
+		status := fmt.Sprintf("%d lines", len(lines))
+		switch {
+		case call.Denied:
+			status = "denied"
+		case output == "":
+			status = "no output"
+		case len(lines) == 1:
+			status = "1 line"
+		}
+		if call.Result.IsError && !call.Denied {
+			status += ", error"
+		}
+
+		expanded := m.toolExpanded(*toolNum) && !call.Denied && output != ""
+		marker := "▶"
+		if expanded {
+			marker = "▼"
+		}
+		header := fmt.Sprintf("  %s [%d] %s — %s", marker, *toolNum, toolCallLabel(call), status)
+		style := toolHeaderStyle
+		if call.Result.IsError || call.Denied {
+			style = toolErrorStyle
+		}
+		b.WriteString(style.Render(truncateCell(header, max(20, width))) + "\n")
+		if !expanded {
+			continue
+		}
+
+		shown := lines
+		if len(shown) > maxToolOutputLines {
+			shown = shown[:maxToolOutputLines]
+		}
+		for _, line := range shown {
+			line = strings.ReplaceAll(line, "\t", "    ")
+			b.WriteString(toolOutputStyle.Render("    │ "+truncateCell(line, max(10, width-6))) + "\n")
+		}
+		if len(lines) > len(shown) {
+			b.WriteString(toolOutputStyle.Render(fmt.Sprintf("    … %d more lines", len(lines)-len(shown))) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// countToolBlocks returns the number of tool blocks in the transcript
+func (m InputModel) countToolBlocks() int {
+	n := 0
+	for _, pair := range m.conversation {
+		n += len(pair.ToolCalls)
+	}
+	return n
+}
+
+// toggleAllTools expands or collapses every tool block (ctrl+t)
+func (m *InputModel) toggleAllTools() {
+	m.expandAllTools = !m.expandAllTools
+	m.expandedTools = nil
+}
+
+// runExpandCommand toggles the output of tool block n (/expand <n>), the last block when
+// no number is given, or every block with /expand all. It returns a reply only on error.
+func (m *InputModel) runExpandCommand(args []string) string {
+	total := m.countToolBlocks()
+	if total == 0 {
+		return "System: No tool output to expand"
+	}
+	n := total
+	if len(args) > 0 {
+		if args[0] == "all" {
+			m.toggleAllTools()
+			return ""
+		}
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 || n > total {
+			return fmt.Sprintf("System: Usage: /expand <n> where n is a tool block from 1 to %d, or /expand all", total)
+		}
+	}
+	if m.expandedTools == nil {
+		m.expandedTools = map[int]bool{}
+	}
+	m.expandedTools[n] = !m.toolExpanded(n)
+	return ""
+}
+
+// Tool block styles
+var (
+	toolHeaderStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#9B59B6"))
+
+	toolErrorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF6B35"))
+
+	toolOutputStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262"))
+)
@@ -0,0 +1,88 @@
+package terminal
+
+import (
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mouseWheelLines is how far one notch of the mouse wheel scrolls the transcript
+const mouseWheelLines = 3
+
+// transcript shows the conversation in a viewport above the input box. It follows new
+// output until the user scrolls up, and again once they scroll back to the bottom.
+type transcript struct {
+	viewport   viewport.Model
+	content    string
+	scrolledUp bool
+}
+
+// layout sizes the viewport to width and height and sets its content, keeping the
+// newest output in view unless the user has scrolled up
+func (t *transcript) layout(content string, width, height int) {
+	t.viewport.Width = max(1, width)
+	t.viewport.Height = max(1, height)
+	t.content = content
+	t.viewport.SetContent(content)
+	if !t.scrolledUp {
+		t.viewport.GotoBottom()
+	}
+}
+
+// View renders the visible lines. A conversation shorter than the viewport is rendered
+// as is, so the input box sits right below it.
+func (t transcript) View() string {
+	if t.viewport.TotalLineCount() <= t.viewport.Height {
+		return t.content
+	}
+	return t.viewport.View()
+}
+
+// scroll lays out the transcript as View would and applies move to it
+func (m *InputModel) scroll(move func(vp *viewport.Model)) {
+	m.transcript.layout(m.renderConversation(), m.width, m.height-lipgloss.Height(m.renderChrome()))
+	move(&m.transcript.viewport)
+	m.transcript.scrolledUp = !m.transcript.viewport.AtBottom()
+}
+
+// handleScrollKey scrolls the transcript for PgUp/PgDn, Ctrl+Home/Ctrl+End, and
+// Home/End while the input is empty; it reports whether the key was used
+func (m *InputModel) handleScrollKey(msg tea.KeyMsg) bool {
+	empty := m.textInput.Value() == ""
+	switch msg.String() {
+	case "pgup":
+		m.scroll(func(vp *viewport.Model) { vp.PageUp() })
+	case "pgdown":
+		m.scroll(func(vp *viewport.Model) { vp.PageDown() })
+	case "ctrl+home":
+		m.scroll(func(vp *viewport.Model) { vp.GotoTop() })
+	case "ctrl+end":
+		m.scroll(func(vp *viewport.Model) { vp.GotoBottom() })
+	case "home":
+		if !empty {
+			return false
+		}
+		m.scroll(func(vp *viewport.Model) { vp.GotoTop() })
+	case "end":
+		if !empty {
+			return false
+		}
+		m.scroll(func(vp *viewport.Model) { vp.GotoBottom() })
+	default:
+		return false
+	}
+	return true
+}
+
+// handleMouse scrolls the transcript with the mouse wheel
+func (m *InputModel) handleMouse(msg tea.MouseMsg) {
+	if msg.Action != tea.MouseActionPress {
+		return
+	}
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scroll(func(vp *viewport.Model) { vp.ScrollUp(mouseWheelLines) })
+	case tea.MouseButtonWheelDown:
+		m.scroll(func(vp *viewport.Model) { vp.ScrollDown(mouseWheelLines) })
+	}
+}
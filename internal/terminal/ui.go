@@ -1,14 +1,28 @@
 package terminal
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	cfg "github.com/pprunty/magikarp/internal/config"
+	convctx "github.com/pprunty/magikarp/internal/context"
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/hooks"
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/models"
 	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/redact"
+	"github.com/pprunty/magikarp/internal/session"
+	"github.com/pprunty/magikarp/internal/telemetry"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/search"
+	"github.com/pprunty/magikarp/internal/tools/wasm"
 )
 
 // Debug logging for UI
@@ -18,6 +32,9 @@ var uiDebugFile *os.File
 // Global config for runtime modifications
 var globalConfig *cfg.Config
 
+// globalPolicy decides which tool calls run, ask for approval or are refused
+var globalPolicy *permissions.Policy
+
 // ToggleTools toggles the tools enabled/disabled state in the global config
 func ToggleTools() {
 	if globalConfig != nil {
@@ -41,6 +58,129 @@ func GetToolsOutputEnabled() bool {
 	return false
 }
 
+// GetAutoCheckpoints returns whether the working tree is checkpointed before the agent
+// changes it (tools.checkpoints, on unless set to false)
+func GetAutoCheckpoints() bool {
+	return globalConfig == nil || globalConfig.Tools.Checkpoints == nil || *globalConfig.Tools.Checkpoints
+}
+
+// systemPrompt returns the system prompt from config.yaml, or a default one
+func systemPrompt() string {
+	if globalConfig != nil && globalConfig.System != "" {
+		return withPlanPrompt(globalConfig.System)
+	}
+	return withPlanPrompt("You are a helpful coding assistant.")
+}
+
+// GetStreamingEnabled returns whether responses from the given provider should be streamed
+func GetStreamingEnabled(providerName string) bool {
+	if globalConfig != nil {
+		return globalConfig.StreamingEnabled(providerName)
+	}
+	return false
+}
+
+// GetMaxToolIterations returns the maximum number of tool rounds per message
+func GetMaxToolIterations() int {
+	if globalConfig != nil {
+		return globalConfig.Tools.MaxIterations
+	}
+	return 0
+}
+
+// GetVimKeymap reports whether the input uses vi key bindings (terminal.keymap: vim)
+func GetVimKeymap() bool {
+	return globalConfig != nil && globalConfig.Terminal.Keymap == "vim"
+}
+
+// GetPushToTalkKey returns the key that starts and stops a push-to-talk recording
+// (speech.push_to_talk), or an empty string when push-to-talk is off
+func GetPushToTalkKey() string {
+	key := "ctrl+space"
+	if globalConfig != nil && globalConfig.Speech.PushToTalk != "" {
+		key = strings.ToLower(globalConfig.Speech.PushToTalk)
+	}
+	switch key {
+	case "off":
+		return ""
+	case "ctrl+space":
+		return "ctrl+@" // terminals send ctrl+space as NUL
+	}
+	return key
+}
+
+// GetSpeechAutoSend returns whether speech mode sends transcripts as messages rather
+// than putting them in the input box (speech.auto_send, on by default)
+func GetSpeechAutoSend() bool {
+	return globalConfig == nil || globalConfig.Speech.AutoSend == nil || *globalConfig.Speech.AutoSend
+}
+
+// GetWakeWord returns the wake word speech mode waits for (speech.wake_word), or an
+// empty string when it acts on everything it hears
+func GetWakeWord() string {
+	if globalConfig == nil {
+		return ""
+	}
+	return strings.TrimSpace(globalConfig.Speech.WakeWord)
+}
+
+// GetNotifySettings returns how and after how long a finished request is notified
+// (terminal.notify)
+func GetNotifySettings() (method string, after time.Duration) {
+	method, after = "auto", defaultNotifyAfter
+	if globalConfig == nil {
+		return method, after
+	}
+	if m := globalConfig.Terminal.Notify.Method; m != "" {
+		method = m
+	}
+	if a := globalConfig.Terminal.Notify.After; a > 0 {
+		after = a
+	}
+	return method, after
+}
+
+// GetFallbackModels returns the models tried when the active model's provider fails
+func GetFallbackModels() []string {
+	if globalConfig != nil {
+		return globalConfig.FallbackModels
+	}
+	return nil
+}
+
+// GetMaxHistory returns how many previous exchanges are sent to the model with each message
+func GetMaxHistory() int {
+	if globalConfig != nil && globalConfig.MaxHistory > 0 {
+		return globalConfig.MaxHistory
+	}
+	return cfg.DefaultMaxHistory
+}
+
+// GetContextOptions returns the history compression settings and whether compression is enabled
+func GetContextOptions() (convctx.Options, bool) {
+	if globalConfig == nil {
+		return convctx.Options{}, true
+	}
+	c := globalConfig.Context
+	return convctx.Options{Window: c.Window, Threshold: c.CompressAt, KeepRecent: c.KeepRecent}, !c.Disabled
+}
+
+// GetAutoContext returns the number of project index snippets added to each prompt
+func GetAutoContext() int {
+	if globalConfig == nil {
+		return 0
+	}
+	return globalConfig.Index.AutoContext
+}
+
+// GetDocumentTokens returns the token budget for each document attached to a prompt
+func GetDocumentTokens() int {
+	if globalConfig == nil || globalConfig.Context.DocumentTokens <= 0 {
+		return defaultDocumentTokens
+	}
+	return globalConfig.Context.DocumentTokens
+}
+
 func init() {
 	if uiDebug {
 		var err error
@@ -56,7 +196,7 @@ func init() {
 func uiDebugLog(format string, args ...interface{}) {
 	if uiDebug && uiDebugFile != nil {
 		timestamp := time.Now().Format("2006/01/02 15:04:05")
-		fmt.Fprintf(uiDebugFile, "%s [UI] "+format+"\n", append([]interface{}{timestamp}, args...)...)
+		fmt.Fprintf(uiDebugFile, "%s [UI] %s\n", timestamp, redact.String(fmt.Sprintf(format, args...)))
 		uiDebugFile.Sync()
 	}
 }
@@ -66,9 +206,15 @@ func StartUI() error {
 	// Show welcome box with version and start directly with default model (first configured)
 	fmt.Print(renderWelcomeBoxWithVersion() + "\n\n")
 
+	// Without any config file, start from a starter config and the keys in the environment
+	if path, err := cfg.CreateStarter(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else if path != "" {
+		fmt.Println(helpStyle.Render("Created a starter config at "+displayPath(path)+"; edit it to choose models and providers, or run magikarp doctor to check the setup") + "\n")
+	}
+
 	// Load configuration
-	cfgPath := "config.yaml"
-	conf, err := cfg.LoadConfig(cfgPath)
+	conf, err := cfg.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -78,33 +224,50 @@ func StartUI() error {
 		return fmt.Errorf("configuration error: %w", err)
 	}
 
+	policy, err := permissions.New(conf.Tools, "")
+	if err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if err := wasm.Register(conf.Tools); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if err := index.Configure(conf); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	search.Register()
+	tools.Configure(conf.Tools)
+	hooks.Configure(conf.Hooks)
+	github.Configure(conf.GitHub)
+	if err := telemetry.Start(conf.Telemetry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing is off: %v\n", err)
+	}
+
 	// Set global config for runtime modifications
 	globalConfig = conf
+	loaded := *conf
+	loadedConfig = &loaded
+	globalPolicy = policy
+	if conf.InstructionsPath != "" {
+		fmt.Println(helpStyle.Render("Using project instructions from "+displayPath(conf.InstructionsPath)) + "\n")
+	}
 
 	// Initialise provider registry
 	if err := orchestration.Init(conf); err != nil {
 		return fmt.Errorf("initialising providers: %w", err)
 	}
 
-	var defaultModel string
-	if conf.DefaultModel != "" {
-		if _, err := orchestration.ProviderFor(conf.DefaultModel); err == nil {
-			defaultModel = conf.DefaultModel
-		} else {
-			// Fallback to first available model if the configured one is not registered
-			defaultModel, err = orchestration.FirstModel()
-			if err != nil {
-				return err // bubble up – UI can't continue without provider
-			}
-		}
-	} else {
-		var err error
-		defaultModel, err = orchestration.FirstModel()
-		if err != nil {
-			return err // bubble up – UI can't continue without provider
+	if conf.Voice.Enabled {
+		if err := startVoice(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot read responses aloud: %v\n", err)
 		}
 	}
 
+	// Fallback to first available model if the configured one is not registered
+	defaultModel, err := orchestration.DefaultModel(conf)
+	if err != nil {
+		return err // bubble up – UI can't continue without provider
+	}
+
 	return startChatInput(defaultModel, conf)
 }
 
@@ -113,9 +276,15 @@ func startChatInput(provider string, conf *cfg.Config) error {
 	// Don't clear screen - let welcome box persist
 
 	inputModel := NewInputModel(provider)
+	defer stopListening()
+	if stop, err := watchConfig(conf); err == nil {
+		defer stop()
+	}
 
 	for {
-		p := tea.NewProgram(inputModel)
+		// Mouse reporting lets the wheel scroll the transcript; hold Shift to select text
+		p := tea.NewProgram(inputModel, tea.WithMouseCellMotion(), tea.WithReportFocus())
+		program.Store(p)
 
 		finalModel, err := p.Run()
 		if err != nil {
@@ -144,12 +313,63 @@ func startChatInput(provider string, conf *cfg.Config) error {
 				inputModel.triggerModelSelect = false
 				// Update provider if a model was selected
 				if selectedModel != "" {
+					if selectedModel != inputModel.provider {
+						SetApprovedPlan("")
+					}
 					inputModel.provider = selectedModel
 					provider = selectedModel
+					// Tools are offered with every message, so warn now rather than on the next one
+					if GetToolsEnabled() && selectedModel != orchestration.AutoModel {
+						if err := models.Require(selectedModel, models.ToolCalling); err != nil {
+							inputModel.AddConversationPair("/model", "System: "+err.Error())
+						}
+					}
+				}
+				continue
+			} else if m.ShouldTriggerConfig() {
+				// Show the settings editor; changes are applied to globalConfig as they are saved
+				if err := showConfigScreen(); err != nil {
+					return fmt.Errorf("failed to show config screen: %w", err)
+				}
+				inputModel = m
+				inputModel.triggerConfig = false
+				continue
+			} else if m.ShouldTriggerStats() {
+				if err := showStatsScreen(); err != nil {
+					return fmt.Errorf("failed to show stats screen: %w", err)
+				}
+				inputModel = m
+				inputModel.triggerStats = false
+				continue
+			} else if m.ShouldTriggerResume() {
+				// Show the session picker and load the chosen conversation
+				selected, err := showSessionSelectScreen()
+				if err != nil {
+					return fmt.Errorf("failed to show session selection screen: %w", err)
+				}
+				inputModel = m
+				inputModel.triggerResume = false
+				if selected != nil {
+					SetApprovedPlan("")
+					inputModel.restoreSession(selected)
+					provider = inputModel.provider
 				}
 				continue
 			} else if m.quitting {
 				// User wants to quit the session
+				if err := hooks.Run(context.Background(), hooks.Event{Event: hooks.SessionEnd, Model: m.provider, Messages: len(m.conversation)}); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+				if m.worktree != nil {
+					fmt.Println(helpStyle.Render(fmt.Sprintf("The isolated run is kept on branch %s in %s", m.worktree.Branch, m.worktree.Path)))
+				}
+				if exportPath != "" {
+					if path, err := m.exportConversation(exportPath); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to export conversation: %v\n", err)
+					} else if path != "" {
+						fmt.Println(helpStyle.Render("Conversation exported to " + displayPath(path)))
+					}
+				}
 				break
 			} else if m.message != "" {
 				// Message processing is now handled asynchronously in input.go
@@ -197,6 +417,39 @@ func showModelSelectScreen() (string, error) {
 	return "", nil
 }
 
+// showSessionSelectScreen displays the full-screen session picker used by /resume
+func showSessionSelectScreen() (*session.Session, error) {
+	p := tea.NewProgram(NewSessionSelectModel(), tea.WithAltScreen())
+
+	finalModel, err := p.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run session selection screen: %w", err)
+	}
+
+	if m, ok := finalModel.(SessionSelectModel); ok {
+		return m.GetSelectedSession(), nil
+	}
+	return nil, nil
+}
+
+// showConfigScreen displays the full-screen settings editor used by /config
+func showConfigScreen() error {
+	p := tea.NewProgram(NewConfigEditorModel(), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("failed to run config screen: %w", err)
+	}
+	return nil
+}
+
+// showStatsScreen displays the full-screen usage dashboard used by /stats
+func showStatsScreen() error {
+	p := tea.NewProgram(NewStatsModel(), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("failed to run stats screen: %w", err)
+	}
+	return nil
+}
+
 // StartUIWithoutAltScreen runs the UI without alternative screen mode
 // Useful for development or when you want to preserve terminal history
 func StartUIWithoutAltScreen() error {
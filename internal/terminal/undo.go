@@ -0,0 +1,69 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+// runUndoCommand handles "/undo" (revert the last change), "/undo <n>" (revert change n)
+// and "/undo list" and returns the system reply to show
+func runUndoCommand(args []string) string {
+	journal, err := fsedit.CurrentJournal()
+	if err != nil {
+		return "System: Undo unavailable: " + err.Error()
+	}
+
+	if len(args) > 0 && args[0] == "list" {
+		return "System: " + formatUndoJournal(journal.Entries())
+	}
+
+	id := 0
+	if len(args) > 0 {
+		id, err = strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			return "System: Usage: /undo [change number | list]"
+		}
+	}
+
+	entry, err := journal.Undo(id)
+	if err != nil {
+		return "System: Cannot undo: " + err.Error()
+	}
+	action := "Restored"
+	if !entry.Existed {
+		action = "Removed"
+	}
+	return fmt.Sprintf("System: Undid change #%d (%s): %s %s", entry.ID, entry.Tool, action, displayPath(entry.Path))
+}
+
+// formatUndoJournal lists the recorded changes, newest first
+func formatUndoJournal(entries []fsedit.Entry) string {
+	if len(entries) == 0 {
+		return "No file changes in this session"
+	}
+	lines := []string{"File changes in this session:"}
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		status := ""
+		if e.Undone {
+			status = " (undone)"
+		}
+		lines = append(lines, fmt.Sprintf("  #%d %s %s %s%s", e.ID, e.Time.Format("15:04:05"), e.Tool, displayPath(e.Path), status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// displayPath shows path relative to the working directory when it is inside it
+func displayPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+	}
+	return path
+}
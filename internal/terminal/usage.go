@@ -0,0 +1,50 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/orchestration"
+)
+
+// formatTokens renders a token count compactly (e.g. 950, 12.3k, 1.2M)
+func formatTokens(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// formatCost renders an estimated USD cost
+func formatCost(cost float64) string {
+	if cost > 0 && cost < 0.01 {
+		return fmt.Sprintf("$%.4f", cost)
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
+
+// renderUsageSummary returns the per-model usage report printed on exit
+func renderUsageSummary() string {
+	byModel := orchestration.Session().ByModel()
+	if len(byModel) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(helpDisplayStyle.Render("Session usage:") + "\n")
+	for _, entry := range byModel {
+		line := fmt.Sprintf("  %s: %d requests, %s in / %s out, %s",
+			entry.Model, entry.Requests,
+			formatTokens(entry.Usage.InputTokens()), formatTokens(entry.Usage.CompletionTokens),
+			formatCost(entry.Cost))
+		b.WriteString(helpDisplayStyle.Render(line) + "\n")
+	}
+	usage, cost := orchestration.Session().Totals()
+	b.WriteString(helpDisplayStyle.Render(fmt.Sprintf("  total: %s tokens, estimated cost %s",
+		formatTokens(usage.TotalTokens()), formatCost(cost))) + "\n")
+	return b.String()
+}
@@ -0,0 +1,369 @@
+package terminal
+
+import (
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// vimState is the vi editing state of the composer (terminal.keymap: vim)
+type vimState struct {
+	enabled  bool
+	normal   bool   // normal mode; insert mode otherwise
+	pending  string // operator waiting for a motion, e.g. "d" or "ci"
+	register string // text yanked or deleted last
+	linewise bool   // register holds whole lines
+}
+
+// VimMode returns the mode shown in the status line, or "" when vim keys are off
+func (c composer) VimMode() string {
+	switch {
+	case !c.vim.enabled:
+		return ""
+	case c.vim.normal && c.vim.pending != "":
+		return "NORMAL " + c.vim.pending
+	case c.vim.normal:
+		return "NORMAL"
+	}
+	return "INSERT"
+}
+
+// EnterInsertMode switches back to insert mode, e.g. after a message is sent
+func (c *composer) EnterInsertMode() {
+	c.vim.normal = false
+	c.vim.pending = ""
+}
+
+// HandleVimKey applies msg as a vi command. It returns false for keys the caller
+// should handle as usual: everything in insert mode except Esc, and keys such as
+// Enter, Ctrl+C and the arrows in normal mode.
+func (c *composer) HandleVimKey(msg tea.KeyMsg) bool {
+	if !c.vim.enabled {
+		return false
+	}
+	if !c.vim.normal {
+		if msg.Type == tea.KeyEsc {
+			c.vim.normal = true
+			// Like vi, leaving insert mode moves the cursor onto the last character typed
+			if pos := c.Position(); pos > 0 && []rune(c.Value())[pos-1] != '\n' {
+				c.SetCursor(pos - 1)
+			}
+			return true
+		}
+		return false
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		c.vim.pending = ""
+		return true
+	case tea.KeyBackspace:
+		c.motion("h")
+		return true
+	case tea.KeyRunes:
+		if len(msg.Runes) == 1 && !msg.Paste {
+			c.vimCommand(string(msg.Runes))
+		}
+		// Other text is swallowed so normal mode never inserts it
+		return true
+	case tea.KeySpace:
+		c.motion("l")
+		return true
+	}
+	return false
+}
+
+// vimCommand runs a single normal-mode key, combining it with a pending operator
+func (c *composer) vimCommand(key string) {
+	if pending := c.vim.pending; pending != "" {
+		c.vim.pending = ""
+		c.operator(pending, key)
+		return
+	}
+
+	runes, pos := []rune(c.Value()), c.Position()
+	start, end := lineBounds(runes, pos)
+	switch key {
+	case "i":
+		c.EnterInsertMode()
+	case "a":
+		c.EnterInsertMode()
+		if pos < end {
+			c.SetCursor(pos + 1)
+		}
+	case "I":
+		c.EnterInsertMode()
+		c.SetCursor(firstNonBlank(runes, start, end))
+	case "A":
+		c.EnterInsertMode()
+		c.SetCursor(end)
+	case "o":
+		c.edit(runes, end, end, "\n", end+1)
+		c.EnterInsertMode()
+	case "O":
+		c.edit(runes, start, start, "\n", start)
+		c.EnterInsertMode()
+	case "x":
+		if pos < end {
+			c.vim.register, c.vim.linewise = string(runes[pos]), false
+			c.edit(runes, pos, pos+1, "", pos)
+		}
+	case "D", "C":
+		c.vim.register, c.vim.linewise = string(runes[pos:end]), false
+		c.edit(runes, pos, end, "", pos)
+		if key == "C" {
+			c.EnterInsertMode()
+		}
+	case "p", "P":
+		c.put(runes, pos, start, end, key == "P")
+	case "d", "c", "y", "g":
+		c.vim.pending = key
+	default:
+		c.motion(key)
+	}
+}
+
+// motion moves the cursor; unknown keys are ignored
+func (c *composer) motion(key string) {
+	runes, pos := []rune(c.Value()), c.Position()
+	start, end := lineBounds(runes, pos)
+	switch key {
+	case "h":
+		if pos > start {
+			c.SetCursor(pos - 1)
+		}
+	case "l":
+		if pos < end-1 {
+			c.SetCursor(pos + 1)
+		}
+	case "0":
+		c.SetCursor(start)
+	case "^":
+		c.SetCursor(firstNonBlank(runes, start, end))
+	case "$":
+		c.SetCursor(max(start, end-1))
+	case "w":
+		c.SetCursor(min(nextWordStart(runes, pos), max(0, len(runes)-1)))
+	case "b":
+		c.SetCursor(prevWordStart(runes, pos))
+	case "e":
+		c.SetCursor(wordEnd(runes, pos))
+	case "j":
+		c.CursorDown()
+	case "k":
+		c.CursorUp()
+	case "G":
+		c.SetCursor(len(runes))
+	}
+}
+
+// operator applies a pending operator (d, c, y, g or their "i"/"a" forms) to a motion
+func (c *composer) operator(op, key string) {
+	runes, pos := []rune(c.Value()), c.Position()
+	start, end := lineBounds(runes, pos)
+
+	// Operators waiting for a text object: di, ci, yi, da, ca, ya
+	if key == "i" || key == "a" {
+		if op == "d" || op == "c" || op == "y" {
+			c.vim.pending = op + key
+		}
+		return
+	}
+
+	from, to := pos, pos
+	switch {
+	case op == "g":
+		if key == "g" {
+			c.SetCursor(0)
+		}
+		return
+	case key == op && len(op) == 1:
+		// dd, cc, yy work on the whole line
+		lineEnd := end
+		if lineEnd < len(runes) {
+			lineEnd++ // include the newline
+		} else if start > 0 && op == "d" {
+			start-- // last line: remove the newline before it instead
+		}
+		c.vim.register, c.vim.linewise = strings.TrimSuffix(string(runes[start:lineEnd]), "\n"), true
+		switch op {
+		case "d":
+			c.edit(runes, start, lineEnd, "", start)
+			s, _ := lineBounds([]rune(c.Value()), c.Position())
+			c.SetCursor(s)
+		case "c":
+			s, e := lineBounds(runes, pos)
+			c.edit(runes, s, e, "", s)
+			c.EnterInsertMode()
+		}
+		return
+	case len(op) == 2 && key == "w":
+		// Inner word (iw) or a word with its trailing space (aw)
+		from, to = wordBounds(runes, pos)
+		if op[1] == 'a' {
+			for to < len(runes) && (runes[to] == ' ' || runes[to] == '\t') {
+				to++
+			}
+		}
+		op = op[:1]
+	case len(op) == 1 && key == "w":
+		to = nextWordStart(runes, pos)
+		if op == "c" {
+			to = wordEnd(runes, pos) + 1 // cw changes to the end of the word, like vi
+		}
+		if lineEnd := end; to > lineEnd && pos < lineEnd {
+			to = lineEnd
+		}
+	case len(op) == 1 && key == "e":
+		to = wordEnd(runes, pos) + 1
+	case len(op) == 1 && key == "b":
+		from = prevWordStart(runes, pos)
+	case len(op) == 1 && key == "$":
+		to = end
+	case len(op) == 1 && key == "0":
+		from = start
+	default:
+		return
+	}
+
+	to = min(to, len(runes))
+	if from >= to {
+		return
+	}
+	c.vim.register, c.vim.linewise = string(runes[from:to]), false
+	switch op {
+	case "d":
+		c.edit(runes, from, to, "", from)
+	case "c":
+		c.edit(runes, from, to, "", from)
+		c.EnterInsertMode()
+	case "y":
+		c.SetCursor(from)
+	}
+}
+
+// put pastes the register after the cursor (p) or before it (P)
+func (c *composer) put(runes []rune, pos, start, end int, before bool) {
+	text := c.vim.register
+	if text == "" {
+		return
+	}
+	if c.vim.linewise {
+		if before {
+			c.edit(runes, start, start, text+"\n", start)
+		} else {
+			c.edit(runes, end, end, "\n"+text, end+1)
+		}
+		return
+	}
+	at := pos
+	if !before && pos < end {
+		at++
+	}
+	c.edit(runes, at, at, text, at+len([]rune(text))-1)
+}
+
+// edit replaces runes[from:to] with text and moves the cursor to cursor
+func (c *composer) edit(runes []rune, from, to int, text string, cursor int) {
+	value := string(runes[:from]) + text + string(runes[to:])
+	c.SetValue(value)
+	c.SetCursor(max(0, min(cursor, len([]rune(value)))))
+}
+
+// lineBounds returns the start and end (exclusive, before the newline) of the line at pos
+func lineBounds(runes []rune, pos int) (start, end int) {
+	pos = min(pos, len(runes))
+	start = pos
+	for start > 0 && runes[start-1] != '\n' {
+		start--
+	}
+	end = pos
+	for end < len(runes) && runes[end] != '\n' {
+		end++
+	}
+	return start, end
+}
+
+func firstNonBlank(runes []rune, start, end int) int {
+	for i := start; i < end; i++ {
+		if !unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return start
+}
+
+// runeClass groups runes like vi words: blanks, word characters and punctuation
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	}
+	return 2
+}
+
+// nextWordStart returns the start of the word after pos (vi "w")
+func nextWordStart(runes []rune, pos int) int {
+	i := pos
+	if i < len(runes) {
+		class := runeClass(runes[i])
+		for i < len(runes) && class != 0 && runeClass(runes[i]) == class {
+			i++
+		}
+	}
+	for i < len(runes) && runeClass(runes[i]) == 0 {
+		i++
+	}
+	return i
+}
+
+// prevWordStart returns the start of the word before pos (vi "b")
+func prevWordStart(runes []rune, pos int) int {
+	i := min(pos, len(runes))
+	for i > 0 && runeClass(runes[i-1]) == 0 {
+		i--
+	}
+	if i == 0 {
+		return 0
+	}
+	class := runeClass(runes[i-1])
+	for i > 0 && runeClass(runes[i-1]) == class {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the last rune of the word ending after pos (vi "e")
+func wordEnd(runes []rune, pos int) int {
+	i := pos + 1
+	for i < len(runes) && runeClass(runes[i]) == 0 {
+		i++
+	}
+	if i >= len(runes) {
+		return max(0, len(runes)-1)
+	}
+	class := runeClass(runes[i])
+	for i+1 < len(runes) && runeClass(runes[i+1]) == class {
+		i++
+	}
+	return i
+}
+
+// wordBounds returns the word (or run of blanks) under pos, for the iw text object
+func wordBounds(runes []rune, pos int) (from, to int) {
+	if pos >= len(runes) {
+		return pos, pos
+	}
+	class := runeClass(runes[pos])
+	from, to = pos, pos
+	for from > 0 && runeClass(runes[from-1]) == class && runes[from-1] != '\n' {
+		from--
+	}
+	for to < len(runes) && runeClass(runes[to]) == class && runes[to] != '\n' {
+		to++
+	}
+	return from, to
+}
@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/speech"
+)
+
+// voiceReader reads responses aloud while /voice is on; it is created on first use
+var (
+	voiceMu     sync.Mutex
+	voiceReader *speech.Reader
+)
+
+// startVoice turns on reading responses aloud with the configured backend
+func startVoice() error {
+	voiceMu.Lock()
+	defer voiceMu.Unlock()
+	if voiceReader == nil {
+		if globalConfig == nil {
+			return nil
+		}
+		speaker, err := speech.NewSpeaker(globalConfig.Voice)
+		if err != nil {
+			return err
+		}
+		voiceReader = speech.NewReader(speaker)
+		voiceReader.OnError = func(err error) {
+			inputDebugLog("Speech failed: %v", err)
+		}
+	}
+	SetVoiceEnabled(true)
+	return nil
+}
+
+// stopVoice stops reading aloud, including the response being read
+func stopVoice() {
+	SetVoiceEnabled(false)
+	silenceVoice()
+}
+
+// silenceVoice interrupts the response being read, e.g. when its request is cancelled
+func silenceVoice() {
+	voiceMu.Lock()
+	defer voiceMu.Unlock()
+	if voiceReader != nil {
+		voiceReader.Stop()
+	}
+}
+
+// voiceBusy reports whether a response is being read aloud
+func voiceBusy() bool {
+	voiceMu.Lock()
+	defer voiceMu.Unlock()
+	return voiceReader != nil && voiceReader.Busy()
+}
+
+// speak reads text of the response aloud when /voice is on; streamed text is read
+// sentence by sentence as it arrives, and done marks the end of the response
+func speak(text string, done bool) {
+	if !VoiceEnabled() {
+		return
+	}
+	voiceMu.Lock()
+	defer voiceMu.Unlock()
+	if voiceReader == nil {
+		return
+	}
+	voiceReader.Write(text)
+	if done {
+		voiceReader.Flush()
+	}
+}
+
+// runVoiceCommand turns reading responses aloud on or off (/voice, /voice on, /voice off)
+func runVoiceCommand(args []string) string {
+	action := "toggle"
+	if len(args) > 0 {
+		action = strings.ToLower(args[0])
+	}
+	if action == "toggle" {
+		action = "on"
+		if VoiceEnabled() {
+			action = "off"
+		}
+	}
+
+	switch action {
+	case "on":
+		if err := startVoice(); err != nil {
+			return "System: Cannot read responses aloud: " + err.Error()
+		}
+		return "System: Voice on: responses are read aloud. Esc stops the current one, /voice off stops reading."
+	case "off":
+		stopVoice()
+		return "System: Voice off"
+	}
+	return "System: Usage: /voice [on|off]"
+}
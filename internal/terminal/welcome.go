@@ -60,6 +60,11 @@ func getProviderStatus() string {
 		{"Gemini", "GEMINI_API_KEY"},
 		{"Mistral", "MISTRAL_API_KEY"},
 		{"Alibaba", "ALIBABA_API_KEY"},
+		{"Groq", "GROQ_API_KEY"},
+		{"DeepSeek", "DEEPSEEK_API_KEY"},
+		{"Azure", "AZURE_OPENAI_API_KEY"},
+		{"OpenRouter", "OPENROUTER_API_KEY"},
+		{"Ollama", "OLLAMA_BASE_URL"},
 	}
 
 	// Get actual provider initialization status
@@ -117,12 +122,7 @@ func getProviderStatus() string {
 // getActualProviderStatus gets the real initialization status from the registry
 func getActualProviderStatus() map[string]bool {
 	// Try to load config and get provider status
-	configPath := findConfigFile()
-	if configPath == "" {
-		return make(map[string]bool) // Return empty if no config
-	}
-	
-	cfg, err := config.LoadConfig(configPath)
+	cfg, err := config.Load()
 	if err != nil {
 		return make(map[string]bool) // Return empty if config load fails
 	}
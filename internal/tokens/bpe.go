@@ -0,0 +1,186 @@
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dlclark/regexp2"
+)
+
+// Encodings used by OpenAI models. Their rank files are published by OpenAI, e.g.
+// https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken
+const (
+	O200kBase  = "o200k_base"
+	Cl100kBase = "cl100k_base"
+)
+
+// patterns split text into the pieces each encoding merges into tokens
+var patterns = map[string]string{
+	O200kBase: strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+		`\s*[\r\n]+`,
+		`\s+(?!\S)`,
+		`\s+`,
+	}, "|"),
+	Cl100kBase: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+}
+
+// modelEncodings maps OpenAI model families to their encoding, matched by longest prefix
+var modelEncodings = map[string]string{
+	"gpt-5":          O200kBase,
+	"gpt-4.1":        O200kBase,
+	"gpt-4.5":        O200kBase,
+	"gpt-4o":         O200kBase,
+	"o1":             O200kBase,
+	"o3":             O200kBase,
+	"o4":             O200kBase,
+	"gpt-4":          Cl100kBase,
+	"gpt-3.5":        Cl100kBase,
+	"text-embedding": Cl100kBase,
+}
+
+// encoding is a byte pair encoding loaded from a tiktoken rank file
+type encoding struct {
+	name    string
+	pattern *regexp2.Regexp
+	ranks   map[string]int
+}
+
+var (
+	encodingsMu sync.Mutex
+	// encodings holds the loaded encodings by name; nil when the rank file is missing
+	encodings = map[string]*encoding{}
+)
+
+// Dir returns the directory tiktoken rank files are read from (~/.magikarp/tokenizers)
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".magikarp", "tokenizers"), nil
+}
+
+// EncodingName returns the tiktoken encoding model uses, or "" when it has none
+func EncodingName(model string) string {
+	model = strings.ToLower(model)
+	// Models served by aggregators are named like "openai/gpt-4o"
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	best := ""
+	for prefix := range modelEncodings {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	return modelEncodings[best]
+}
+
+// encodingFor returns the encoding of model, loading it on first use; nil when model
+// has none or its rank file is not installed
+func encodingFor(model string) *encoding {
+	name := EncodingName(model)
+	if name == "" {
+		return nil
+	}
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	enc, err := loadEncoding(name)
+	if err != nil {
+		enc = nil // counted by estimate until the file is installed and Magikarp restarted
+	}
+	encodings[name] = enc
+	return enc
+}
+
+// loadEncoding reads the rank file of the named encoding from Dir
+func loadEncoding(name string) (*encoding, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(dir, name+".tiktoken"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int, 200_000)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		token, rank, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %q in %s: %w", token, f.Name(), err)
+		}
+		r, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank %q in %s: %w", rank, f.Name(), err)
+		}
+		ranks[string(b)] = r
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	pattern, err := regexp2.Compile(patterns[name], regexp2.None)
+	if err != nil {
+		return nil, err
+	}
+	return &encoding{name: name, pattern: pattern, ranks: ranks}, nil
+}
+
+// count returns the number of tokens text encodes to
+func (e *encoding) count(text string) int {
+	n := 0
+	match, _ := e.pattern.FindStringMatch(text)
+	for match != nil {
+		piece := match.String()
+		if _, ok := e.ranks[piece]; ok {
+			n++
+		} else {
+			n += e.bytePairMerge([]byte(piece))
+		}
+		match, _ = e.pattern.FindNextMatch(match)
+	}
+	return n
+}
+
+// bytePairMerge merges the bytes of piece by rank, as tiktoken does, and returns the
+// number of tokens left
+func (e *encoding) bytePairMerge(piece []byte) int {
+	// bounds[i] is where the i-th part starts; the last entry is the end of piece
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, at := math.MaxInt, -1
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < best {
+				best, at = rank, i
+			}
+		}
+		if at < 0 {
+			break
+		}
+		bounds = append(bounds[:at+1], bounds[at+2:]...)
+	}
+	return len(bounds) - 1
+}
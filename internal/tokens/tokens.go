@@ -0,0 +1,116 @@
+// Package tokens counts the tokens of prompts for the model they are sent to: exactly
+// with the tiktoken encodings of OpenAI models when installed, otherwise with a
+// heuristic tuned per model family. Exact counts from a provider's counting endpoint
+// are obtained through providers.TokenCounter.
+package tokens
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// MessageOverhead approximates the tokens spent on the role markers and separators of
+// each message
+const MessageOverhead = 4
+
+// maxCached bounds the number of texts whose exact counts are remembered
+const maxCached = 4096
+
+// charsPerToken is the average text length of a token for model families whose
+// tokenizer is not available locally, matched by longest prefix
+var charsPerToken = map[string]float64{
+	"claude":    3.5,
+	"gemini":    4,
+	"mistral":   3.7,
+	"codestral": 3.7,
+	"deepseek":  3.8,
+	"qwen":      3.8,
+	"llama":     3.9,
+}
+
+// defaultCharsPerToken is used for models of no known family
+const defaultCharsPerToken = 4.0
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]int{}
+)
+
+// Count returns the number of tokens text takes for model. It is exact when model
+// uses a tiktoken encoding that is installed (see Exact) and estimated otherwise.
+func Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	enc := encodingFor(model)
+	if enc == nil {
+		return estimate(model, text)
+	}
+
+	key := enc.name + "\x00" + text
+	cacheMu.Lock()
+	n, ok := cache[key]
+	cacheMu.Unlock()
+	if ok {
+		return n
+	}
+	n = enc.count(text)
+	cacheMu.Lock()
+	if len(cache) >= maxCached {
+		clear(cache)
+	}
+	cache[key] = n
+	cacheMu.Unlock()
+	return n
+}
+
+// CountMessages returns the number of tokens messages take for model, including the
+// overhead of each message
+func CountMessages(model string, messages []providers.ChatMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += Count(model, msg.Content) + MessageOverhead
+		for _, call := range msg.ToolCalls {
+			total += Count(model, call.Name) + Count(model, string(call.Input))
+		}
+	}
+	return total
+}
+
+// Exact reports whether Count is exact for model rather than estimated
+func Exact(model string) bool {
+	return encodingFor(model) != nil
+}
+
+// Estimate approximates the tokens of text at about four characters per token,
+// without regard to a model
+func Estimate(text string) int {
+	return estimate("", text)
+}
+
+// estimate approximates the tokens of text from the average token length of model's
+// family. Letters of scripts without spaces between words, such as Chinese or
+// Japanese, mostly take a token each.
+func estimate(model, text string) int {
+	ratio := defaultCharsPerToken
+	model = strings.ToLower(model)
+	best := ""
+	for prefix, r := range charsPerToken {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, ratio = prefix, r
+		}
+	}
+
+	dense := 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			dense++
+		}
+	}
+	other := utf8.RuneCountInString(text) - dense
+	return dense + int((float64(other)+ratio-1)/ratio)
+}
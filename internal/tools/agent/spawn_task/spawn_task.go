@@ -0,0 +1,90 @@
+package spawn_task
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/orchestration"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var wrapper []byte
+
+type task struct {
+	Prompt string   `json:"prompt"`
+	Tools  []string `json:"tools,omitempty"`
+	Model  string   `json:"model,omitempty"`
+}
+
+type input struct {
+	Tasks []task `json:"tasks"`
+}
+
+// Definition returns the tool definition for spawn_task
+func Definition() providers.ToolDefinition {
+	var sch map[string]any
+	if err := json.Unmarshal(wrapper, &sch); err != nil {
+		fmt.Printf("Error unmarshaling spawn_task schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("spawn_task", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("spawn_task", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	tasks := make([]orchestration.SubTask, len(in.Tasks))
+	for i, t := range in.Tasks {
+		if strings.TrimSpace(t.Prompt) == "" {
+			return providers.NewToolResult("spawn_task", fmt.Sprintf("task %d has no prompt", i+1), true), nil
+		}
+		// Sub-agents cannot spawn sub-agents, so they are not offered this tool
+		var tools []string
+		for _, name := range t.Tools {
+			if name != "spawn_task" {
+				tools = append(tools, name)
+			}
+		}
+		tasks[i] = orchestration.SubTask{Prompt: t.Prompt, Tools: tools, Model: t.Model}
+	}
+
+	results, err := orchestration.SpawnTasks(ctx, tasks)
+	if err != nil {
+		return providers.NewToolResult("spawn_task", err.Error(), true), nil
+	}
+
+	var sb strings.Builder
+	failed := 0
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		summary := strings.Join(strings.Fields(r.Task.Prompt), " ")
+		if len(summary) > 80 {
+			summary = summary[:77] + "..."
+		}
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(&sb, "## Task %d failed: %s\n%v", i+1, summary, r.Err)
+			continue
+		}
+		fmt.Fprintf(&sb, "## Task %d (%s, %d tool calls): %s\n%s", i+1, r.Model, len(r.ToolCalls), summary, strings.TrimSpace(r.Text))
+	}
+	// The spawn only failed when no sub-agent finished
+	return providers.NewToolResult("spawn_task", sb.String(), failed == len(results)), nil
+}
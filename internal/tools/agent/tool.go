@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/agent/spawn_task"
+)
+
+type agentToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &agentToolbox{
+		BaseToolbox: tools.NewBaseToolbox("agent", "Delegate parts of a task to sub-agents"),
+	}
+	tb.AddTool(spawn_task.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
@@ -0,0 +1,49 @@
+package read_clipboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var schema []byte
+
+// maxBytes bounds the clipboard text returned to the model
+const maxBytes = 100 * 1024
+
+// Definition returns the tool definition for read_clipboard
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling read_clipboard schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	if clipboard.Unsupported {
+		return providers.NewToolResult("read_clipboard", "No clipboard is available: install xclip, xsel or wl-clipboard", true), nil
+	}
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return providers.NewToolResult("read_clipboard", fmt.Sprintf("Failed to read the clipboard: %v", err), true), nil
+	}
+	if strings.TrimSpace(text) == "" {
+		return providers.NewToolResult("read_clipboard", "The clipboard is empty", false), nil
+	}
+	if len(text) > maxBytes {
+		text = strings.ToValidUTF8(text[:maxBytes], "") + fmt.Sprintf("\n[clipboard truncated: showing %d of %d bytes]", maxBytes, len(text))
+	}
+	return providers.NewToolResult("read_clipboard", text, false), nil
+}
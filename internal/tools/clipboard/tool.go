@@ -0,0 +1,24 @@
+package clipboard
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/clipboard/read_clipboard"
+	"github.com/pprunty/magikarp/internal/tools/clipboard/write_clipboard"
+)
+
+type clipboardToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &clipboardToolbox{
+		BaseToolbox: tools.NewBaseToolbox("clipboard", "Read and write the system clipboard"),
+	}
+	tb.AddTool(read_clipboard.Definition())
+	tb.AddTool(write_clipboard.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
@@ -0,0 +1,55 @@
+package write_clipboard
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Text string `json:"text"`
+}
+
+// Definition returns the tool definition for write_clipboard
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling write_clipboard schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("write_clipboard", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("write_clipboard", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	if in.Text == "" {
+		return providers.NewToolResult("write_clipboard", "text must not be empty", true), nil
+	}
+	if clipboard.Unsupported {
+		return providers.NewToolResult("write_clipboard", "No clipboard is available: install xclip, xsel or wl-clipboard", true), nil
+	}
+	if err := clipboard.WriteAll(in.Text); err != nil {
+		return providers.NewToolResult("write_clipboard", fmt.Sprintf("Failed to write the clipboard: %v", err), true), nil
+	}
+	lines := strings.Count(strings.TrimRight(in.Text, "\n"), "\n") + 1
+	return providers.NewToolResult("write_clipboard", fmt.Sprintf("Copied %d lines (%d bytes) to the clipboard", lines, len(in.Text)), false), nil
+}
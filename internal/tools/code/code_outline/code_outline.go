@@ -0,0 +1,186 @@
+package code_outline
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var schema []byte
+
+const (
+	// maxFiles bounds how many files one call outlines
+	maxFiles = 200
+	// maxFileBytes skips generated or vendored files too large to be worth outlining
+	maxFileBytes = 1 << 20
+)
+
+type input struct {
+	Path         string `json:"path,omitempty"`
+	Recursive    bool   `json:"recursive,omitempty"`
+	ExportedOnly bool   `json:"exported_only,omitempty"`
+	Docs         bool   `json:"docs,omitempty"`
+	IncludeTests bool   `json:"include_tests,omitempty"`
+}
+
+// entry is a declaration in the outline; depth nests fields and methods under their type
+type entry struct {
+	line  int
+	depth int
+	text  string
+}
+
+// skipDirs are directories never outlined when walking recursively
+var skipDirs = map[string]bool{"vendor": true, "node_modules": true, "testdata": true, "target": true, "dist": true, "build": true, "__pycache__": true}
+
+// Definition returns the tool definition for code_outline
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling code_outline schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("code_outline", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("code_outline", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	if in.Path == "" {
+		in.Path = "."
+	}
+	if !filepath.IsLocal(in.Path) && filepath.Clean(in.Path) != "." {
+		return providers.NewToolResult("code_outline", "Path must be local for security reasons", true), nil
+	}
+
+	path := filepath.Clean(in.Path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return providers.NewToolResult("code_outline", fmt.Sprintf("Error accessing %s: %v", path, err), true), nil
+	}
+	var files []string
+	truncated := false
+	if info.IsDir() {
+		files, truncated, err = sourceFiles(ctx, path, in)
+		if err != nil {
+			return providers.NewToolResult("code_outline", fmt.Sprintf("Error listing %s: %v", path, err), true), nil
+		}
+		if len(files) == 0 {
+			return providers.NewToolResult("code_outline", fmt.Sprintf("No supported source files in %s", path), false), nil
+		}
+	} else {
+		if languageOf(path) == "" {
+			return providers.NewToolResult("code_outline", fmt.Sprintf("%s is not a supported source file", path), true), nil
+		}
+		files = []string{path}
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return providers.NewToolResult("code_outline", "Cancelled", true), nil
+		}
+		header, entries, err := outlineFile(file, in)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n\n", filepath.ToSlash(file), err)
+			continue
+		}
+		b.WriteString(filepath.ToSlash(file))
+		if header != "" {
+			b.WriteString(" — " + header)
+		}
+		b.WriteString("\n")
+		if len(entries) == 0 {
+			b.WriteString("  (no declarations)\n")
+		}
+		for _, e := range entries {
+			fmt.Fprintf(&b, "%6d  %s%s\n", e.line, strings.Repeat("  ", e.depth), e.text)
+		}
+		b.WriteString("\n")
+	}
+	if truncated {
+		fmt.Fprintf(&b, "[stopped after %d files; outline a subdirectory to see more]\n", maxFiles)
+	}
+	return providers.NewToolResult("code_outline", strings.TrimRight(b.String(), "\n"), false), nil
+}
+
+// outlineFile returns a header, such as the package, and the declarations of file
+func outlineFile(file string, in input) (string, []entry, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.Size() > maxFileBytes {
+		return "", nil, fmt.Errorf("skipped, larger than %d KB", maxFileBytes/1024)
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	lang := languageOf(file)
+	if lang == "go" {
+		return outlineGo(file, src, in)
+	}
+	return lang, outlineGeneric(lang, string(src)), nil
+}
+
+// sourceFiles lists the supported source files in dir, sorted, descending into
+// subdirectories when requested. Hidden entries and dependency directories are skipped.
+func sourceFiles(ctx context.Context, dir string, in input) ([]string, bool, error) {
+	var files []string
+	truncated := false
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if !in.Recursive || strings.HasPrefix(name, ".") || skipDirs[name] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".") || languageOf(path) == "" || (!in.IncludeTests && isTestFile(name)) {
+			return nil
+		}
+		if len(files) == maxFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		files = append(files, path)
+		return nil
+	})
+	sort.Strings(files)
+	return files, truncated, err
+}
+
+// isTestFile reports whether name follows a common test file naming convention
+func isTestFile(name string) bool {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.HasSuffix(base, "_test") || strings.HasPrefix(base, "test_") ||
+		strings.HasSuffix(base, ".test") || strings.HasSuffix(base, ".spec") || strings.HasSuffix(base, "Test")
+}
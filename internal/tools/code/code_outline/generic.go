@@ -0,0 +1,116 @@
+package code_outline
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// languages maps file extensions to the languages code_outline understands
+var languages = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".ts":   "typescript",
+	".tsx":  "typescript",
+	".rs":   "rust",
+	".java": "java",
+	".kt":   "kotlin",
+	".cs":   "csharp",
+	".rb":   "ruby",
+}
+
+// languageOf returns the language of a source file, or "" when it is not supported
+func languageOf(path string) string {
+	return languages[strings.ToLower(filepath.Ext(path))]
+}
+
+// declarations match the lines that declare something in each language other than
+// Go. Outlines of these languages are read from the lines alone, so a declaration
+// split over several lines shows only its first.
+var declarations = map[string]*regexp.Regexp{
+	"python": regexp.MustCompile(`^\s*(?:async\s+)?(?:def|class)\s+\w+`),
+	"javascript": regexp.MustCompile(`^\s*(?:export\s+(?:default\s+)?)?(?:` +
+		`(?:async\s+)?function\s*\*?\s*\w+|class\s+\w+|` +
+		`(?:const|let|var)\s+\w+\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|\w+\s*=>)|` +
+		`(?:static\s+)?(?:async\s+)?(?:get\s+|set\s+)?\*?[A-Za-z_$][\w$]*\s*\([^)]*\)\s*\{)`),
+	"typescript": regexp.MustCompile(`^\s*(?:export\s+(?:default\s+)?)?(?:declare\s+)?(?:` +
+		`(?:async\s+)?function\s*\*?\s*\w+|(?:abstract\s+)?class\s+\w+|interface\s+\w+|type\s+\w+|enum\s+\w+|namespace\s+\w+|` +
+		`(?:const|let|var)\s+\w+\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)(?:\s*:[^=]+)?\s*=>|\w+\s*=>)|` +
+		`(?:(?:public|private|protected|static|readonly|abstract|async|override)\s+)*(?:get\s+|set\s+)?\*?[A-Za-z_$][\w$]*\s*(?:<[^>]*>)?\([^)]*\)\s*(?::\s*[^{;]+)?\{)`),
+	"rust": regexp.MustCompile(`^\s*(?:pub(?:\([\w:]+\))?\s+)?(?:(?:const|async|unsafe|extern(?:\s+"\w+")?)\s+)*` +
+		`(?:fn|struct|enum|trait|impl|mod|type|union|macro_rules!)\b`),
+	"java": regexp.MustCompile(`^\s*(?:@\w+(?:\([^)]*\))?\s+)*(?:(?:public|private|protected|static|final|abstract|sealed|non-sealed|synchronized|native|default|strictfp)\s+)*` +
+		`(?:(?:class|interface|enum|record|@interface)\s+\w+|(?:<[^>]+>\s+)?[\w.<>\[\], ?]+\s+\w+\s*\([^;]*$)`),
+	"kotlin": regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|open|abstract|sealed|data|enum|inline|value|override|suspend|inner|annotation|companion)\s+)*` +
+		`(?:class|interface|object|fun|typealias)\b`),
+	"csharp": regexp.MustCompile(`^\s*(?:\[[^\]]*\]\s*)*(?:(?:public|private|protected|internal|static|sealed|abstract|virtual|override|async|partial|readonly|unsafe|extern|new)\s+)*` +
+		`(?:(?:class|interface|struct|enum|record|namespace)\s+[\w.]+|[\w.<>\[\], ?]+\s+\w+\s*(?:<[^>]*>)?\([^;]*$)`),
+	"ruby": regexp.MustCompile(`^\s*(?:def|class|module)\s+\S`),
+}
+
+// notDeclarations are statements that look like method declarations to the patterns
+// above, e.g. "if (x) {"
+var notDeclarations = regexp.MustCompile(`^\s*(?:if|else|for|foreach|while|switch|catch|return|new|throw|await|yield|do|try|using|lock|fixed|when|match|case|super|this)\b`)
+
+// outlineGeneric lists the declaration lines of a source file in lang, nested by
+// their indentation
+func outlineGeneric(lang, src string) []entry {
+	re := declarations[lang]
+	if re == nil {
+		return nil
+	}
+	var entries []entry
+	var indents []int
+	inBlockComment := false
+	for i, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if lang != "python" && lang != "ruby" {
+			if inBlockComment {
+				if strings.Contains(trimmed, "*/") {
+					inBlockComment = false
+				}
+				continue
+			}
+			if strings.HasPrefix(trimmed, "/*") && !strings.Contains(trimmed, "*/") {
+				inBlockComment = true
+				continue
+			}
+		}
+		if !re.MatchString(line) || notDeclarations.MatchString(line) {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for len(indents) > 0 && indents[len(indents)-1] >= indent {
+			indents = indents[:len(indents)-1]
+		}
+		entries = append(entries, entry{line: i + 1, depth: len(indents), text: signature(trimmed)})
+		indents = append(indents, indent)
+	}
+	return entries
+}
+
+// signature trims the body from a declaration line
+func signature(line string) string {
+	line = strings.TrimSpace(line)
+	for _, suffix := range []string{";", "{}", "{", ":"} {
+		line = strings.TrimSpace(strings.TrimSuffix(line, suffix))
+	}
+	if i := strings.Index(line, "=>"); i >= 0 && strings.Contains(line[:i], "(") {
+		line = strings.TrimSpace(line[:i+2])
+	}
+	return clip(line, 160)
+}
+
+// clip shortens s to at most n runes
+func clip(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,198 @@
+package code_outline
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+// outlineGo parses a Go file and returns its package clause and declarations. Files
+// with syntax errors are outlined as far as they parse.
+func outlineGo(file string, src []byte, in input) (string, []entry, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil {
+		return "", nil, err
+	}
+	o := goOutliner{fset: fset, in: in}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			o.funcDecl(d)
+		case *ast.GenDecl:
+			o.genDecl(d)
+		}
+	}
+	header := "package " + f.Name.Name
+	if err != nil {
+		header += " (has syntax errors)"
+	}
+	return header, o.entries, nil
+}
+
+type goOutliner struct {
+	fset    *token.FileSet
+	in      input
+	entries []entry
+}
+
+func (o *goOutliner) add(pos token.Pos, depth int, text string, doc *ast.CommentGroup) {
+	if o.in.Docs {
+		if summary := docSummary(doc); summary != "" {
+			text += "  // " + summary
+		}
+	}
+	o.entries = append(o.entries, entry{line: o.fset.Position(pos).Line, depth: depth, text: text})
+}
+
+func (o *goOutliner) funcDecl(d *ast.FuncDecl) {
+	if o.in.ExportedOnly && (!d.Name.IsExported() || !receiverExported(d)) {
+		return
+	}
+	sig := *d
+	sig.Doc, sig.Body = nil, nil
+	o.add(d.Pos(), 0, o.node(&sig), d.Doc)
+}
+
+func (o *goOutliner) genDecl(d *ast.GenDecl) {
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			o.typeSpec(d, s)
+		case *ast.ValueSpec:
+			doc := s.Doc
+			if doc == nil && len(d.Specs) == 1 {
+				doc = d.Doc
+			}
+			for i, name := range s.Names {
+				if name.Name == "_" || (o.in.ExportedOnly && !name.IsExported()) {
+					continue
+				}
+				text := d.Tok.String() + " " + name.Name
+				if s.Type != nil {
+					text += " " + o.node(s.Type)
+				} else if i < len(s.Values) {
+					text += " = " + o.short(s.Values[i])
+				}
+				o.add(name.Pos(), 0, text, doc)
+			}
+		}
+	}
+}
+
+func (o *goOutliner) typeSpec(d *ast.GenDecl, s *ast.TypeSpec) {
+	if o.in.ExportedOnly && !s.Name.IsExported() {
+		return
+	}
+	doc := s.Doc
+	if doc == nil && len(d.Specs) == 1 {
+		doc = d.Doc
+	}
+	name := "type " + s.Name.Name
+	if s.TypeParams != nil {
+		name += "[" + o.fieldList(s.TypeParams) + "]"
+	}
+	if s.Assign.IsValid() {
+		name += " ="
+	}
+
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		o.add(s.Pos(), 0, name+" struct", doc)
+		for _, field := range t.Fields.List {
+			typ := o.node(field.Type)
+			if len(field.Names) == 0 {
+				o.add(field.Pos(), 1, typ+" (embedded)", field.Doc)
+				continue
+			}
+			for _, n := range field.Names {
+				if o.in.ExportedOnly && !n.IsExported() {
+					continue
+				}
+				o.add(n.Pos(), 1, n.Name+" "+typ, field.Doc)
+			}
+		}
+	case *ast.InterfaceType:
+		o.add(s.Pos(), 0, name+" interface", doc)
+		for _, method := range t.Methods.List {
+			if len(method.Names) == 0 {
+				o.add(method.Pos(), 1, o.node(method.Type)+" (embedded)", method.Doc)
+				continue
+			}
+			for _, n := range method.Names {
+				if o.in.ExportedOnly && !n.IsExported() {
+					continue
+				}
+				text := n.Name
+				if ft, ok := method.Type.(*ast.FuncType); ok {
+					text += strings.TrimPrefix(o.node(ft), "func")
+				}
+				o.add(n.Pos(), 1, text, method.Doc)
+			}
+		}
+	default:
+		o.add(s.Pos(), 0, name+" "+o.node(s.Type), doc)
+	}
+}
+
+// fieldList renders the type parameters of a generic type
+func (o *goOutliner) fieldList(fl *ast.FieldList) string {
+	var parts []string
+	for _, f := range fl.List {
+		var names []string
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+o.node(f.Type))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// node prints n as Go source on one line
+func (o *goOutliner) node(n ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, o.fset, n); err != nil {
+		return "?"
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// short prints an expression, cut off when it is long
+func (o *goOutliner) short(n ast.Node) string {
+	return clip(o.node(n), 60)
+}
+
+// receiverExported reports whether the receiver type of a method is exported; true
+// for functions
+func receiverExported(d *ast.FuncDecl) bool {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return true
+	}
+	t := d.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return true
+		}
+	}
+}
+
+// docSummary returns the first sentence of a doc comment
+func docSummary(doc *ast.CommentGroup) string {
+	text := strings.Join(strings.Fields(doc.Text()), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		text = text[:i+1]
+	}
+	return clip(text, 120)
+}
@@ -0,0 +1,113 @@
+package find_references
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/code/gopls"
+)
+
+//go:embed tool.json
+var schema []byte
+
+// maxReferences bounds the references listed
+const maxReferences = 500
+
+// referenceRe matches a location printed by gopls: file:line:col-col or
+// file:line:col-line:col
+var referenceRe = regexp.MustCompile(`^(.+):(\d+):(\d+)(?:-[\d:]+)?$`)
+
+type input struct {
+	gopls.Position
+	IncludeDeclaration bool `json:"include_declaration,omitempty"`
+}
+
+// Definition returns the tool definition for find_references
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling find_references schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("find_references", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("find_references", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	pos, err := in.Resolve()
+	if err != nil {
+		return providers.NewToolResult("find_references", err.Error(), true), nil
+	}
+
+	args := []string{"references"}
+	if in.IncludeDeclaration {
+		args = append(args, "-d")
+	}
+	out, err := gopls.Run(ctx, append(args, pos)...)
+	if err != nil {
+		return providers.NewToolResult("find_references", err.Error(), true), nil
+	}
+
+	var b strings.Builder
+	files := map[string][]string{}
+	count := 0
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		m := referenceRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		count++
+		if count > maxReferences {
+			continue
+		}
+		path := gopls.Relative(m[1])
+		n, _ := strconv.Atoi(m[2])
+		if _, ok := files[path]; !ok {
+			files[path] = readLines(m[1])
+		}
+		text := ""
+		if lines := files[path]; n >= 1 && n <= len(lines) {
+			text = strings.TrimSpace(lines[n-1])
+		}
+		fmt.Fprintf(&b, "%s:%s:%s: %s\n", path, m[2], m[3], text)
+	}
+	if count == 0 {
+		return providers.NewToolResult("find_references", fmt.Sprintf("No references to %s found", in.Symbol), false), nil
+	}
+
+	summary := fmt.Sprintf("%d references to %s in %d files", count, in.Symbol, len(files))
+	if count == 1 {
+		summary = fmt.Sprintf("1 reference to %s", in.Symbol)
+	}
+	if count > maxReferences {
+		summary += fmt.Sprintf(" (showing the first %d)", maxReferences)
+	}
+	return providers.NewToolResult("find_references", summary+":\n"+strings.TrimRight(b.String(), "\n"), false), nil
+}
+
+// readLines returns the lines of a file, or nil when it cannot be read
+func readLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
+}
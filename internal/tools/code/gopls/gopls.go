@@ -0,0 +1,154 @@
+// Package gopls runs the Go language server's command line for the refactoring tools
+package gopls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Timeout bounds a gopls command, which type-checks the workspace first
+const Timeout = 2 * time.Minute
+
+// Position is a symbol in a Go file, as given to the tools
+type Position struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Symbol string `json:"symbol"`
+	// Column is the 1-based byte column of the symbol; 0 finds Symbol on the line
+	Column int `json:"column,omitempty"`
+}
+
+// Resolve checks p and returns the file:line:column argument gopls expects
+func (p Position) Resolve() (string, error) {
+	if p.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if !filepath.IsLocal(p.Path) {
+		return "", fmt.Errorf("path must be local for security reasons")
+	}
+	if filepath.Ext(p.Path) != ".go" {
+		return "", fmt.Errorf("%s is not a Go file", p.Path)
+	}
+	if p.Line < 1 {
+		return "", fmt.Errorf("line must be 1 or more")
+	}
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", p.Path, err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if p.Line > len(lines) {
+		return "", fmt.Errorf("%s has only %d lines", p.Path, len(lines))
+	}
+	line := lines[p.Line-1]
+
+	col := p.Column
+	if col == 0 {
+		if p.Symbol == "" {
+			return "", fmt.Errorf("symbol or column is required")
+		}
+		i := findIdent(line, p.Symbol)
+		if i < 0 {
+			return "", fmt.Errorf("%s not found on line %d of %s: %s", p.Symbol, p.Line, p.Path, strings.TrimSpace(line))
+		}
+		col = i + 1
+	}
+	if col < 1 || col > len(line)+1 {
+		return "", fmt.Errorf("column %d is outside line %d, which has %d bytes", col, p.Line, len(line))
+	}
+	abs, err := filepath.Abs(p.Path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", abs, p.Line, col), nil
+}
+
+// findIdent returns the byte offset of name as a whole identifier in line, or -1
+func findIdent(line, name string) int {
+	for from := 0; ; {
+		i := strings.Index(line[from:], name)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		before, _ := utf8.DecodeLastRuneInString(line[:i])
+		after, _ := utf8.DecodeRuneInString(line[i+len(name):])
+		if !isIdentRune(before) && !isIdentRune(after) {
+			return i
+		}
+		from = i + len(name)
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Run executes gopls with args in the working directory and returns its stdout
+func Run(ctx context.Context, args ...string) (string, error) {
+	bin, err := find()
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("gopls %s timed out after %s", args[0], Timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("gopls %s failed: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// find locates gopls on PATH or where go install puts it
+func find() (string, error) {
+	if bin, err := exec.LookPath("gopls"); err == nil {
+		return bin, nil
+	}
+	dirs := []string{os.Getenv("GOBIN")}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		dirs = append(dirs, filepath.Join(filepath.SplitList(gopath)[0], "bin"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "go", "bin"))
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		bin := filepath.Join(dir, "gopls")
+		if _, err := os.Stat(bin); err == nil {
+			return bin, nil
+		}
+	}
+	return "", fmt.Errorf("gopls is not installed; install it with: go install golang.org/x/tools/gopls@latest")
+}
+
+// Relative returns path relative to the working directory when it is inside it
+func Relative(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
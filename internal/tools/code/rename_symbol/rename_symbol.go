@@ -0,0 +1,86 @@
+package rename_symbol
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/code/gopls"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/apply_patch"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	gopls.Position
+	NewName string `json:"new_name"`
+}
+
+// Definition returns the tool definition for rename_symbol
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling rename_symbol schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("rename_symbol", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("rename_symbol", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	if !token.IsIdentifier(in.NewName) {
+		return providers.NewToolResult("rename_symbol", fmt.Sprintf("%q is not a valid Go identifier", in.NewName), true), nil
+	}
+	pos, err := in.Resolve()
+	if err != nil {
+		return providers.NewToolResult("rename_symbol", err.Error(), true), nil
+	}
+
+	// gopls only prints the diff; the files are written through apply_patch so the
+	// change is reviewed, can be undone and is skipped in a dry run
+	diff, err := gopls.Run(ctx, "rename", "-d", pos, in.NewName)
+	if err != nil {
+		return providers.NewToolResult("rename_symbol", err.Error(), true), nil
+	}
+	if strings.TrimSpace(diff) == "" {
+		return providers.NewToolResult("rename_symbol", "No changes: gopls found nothing to rename", false), nil
+	}
+
+	msg, isError := apply_patch.Patch(ctx, "rename_symbol", localPaths(diff))
+	if !isError && !tools.IsDryRun(ctx) {
+		msg = fmt.Sprintf("Renamed %s to %s. %s", in.Symbol, in.NewName, msg)
+	}
+	return providers.NewToolResult("rename_symbol", msg, isError), nil
+}
+
+// localPaths rewrites the file headers of a gopls diff, which name the original as
+// "<file>.orig" by absolute path, to paths relative to the working directory
+func localPaths(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		for _, prefix := range []string{"--- ", "+++ "} {
+			if name, ok := strings.CutPrefix(line, prefix); ok && filepath.IsAbs(name) {
+				lines[i] = prefix + gopls.Relative(strings.TrimSuffix(name, ".orig"))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,26 @@
+package code
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/code/code_outline"
+	"github.com/pprunty/magikarp/internal/tools/code/find_references"
+	"github.com/pprunty/magikarp/internal/tools/code/rename_symbol"
+)
+
+type codeToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &codeToolbox{
+		BaseToolbox: tools.NewBaseToolbox("code", "Understand and refactor source code"),
+	}
+	tb.AddTool(code_outline.Definition())
+	tb.AddTool(find_references.Definition())
+	tb.AddTool(rename_symbol.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
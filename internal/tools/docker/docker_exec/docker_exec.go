@@ -0,0 +1,77 @@
+package docker_exec
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/docker/dockerexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+const (
+	defaultTimeout = 120 * time.Second
+	maxTimeout     = 1800 * time.Second
+)
+
+type input struct {
+	Container string            `json:"container"`
+	Command   []string          `json:"command"`
+	WorkDir   string            `json:"workdir,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Timeout   int               `json:"timeout,omitempty"`
+}
+
+// Definition returns the tool definition for docker_exec
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling docker_exec schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := dockerexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("docker_exec", err.Error(), true), nil
+	}
+	if err := dockerexec.CheckName("container", in.Container); err != nil {
+		return providers.NewToolResult("docker_exec", err.Error(), true), nil
+	}
+	if len(in.Command) == 0 {
+		return providers.NewToolResult("docker_exec", "command must not be empty", true), nil
+	}
+
+	args := []string{"exec"}
+	if in.WorkDir != "" {
+		args = append(args, "--workdir", in.WorkDir)
+	}
+	names := make([]string, 0, len(in.Env))
+	for name := range in.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return providers.NewToolResult("docker_exec", fmt.Sprintf("invalid environment variable name %q", name), true), nil
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		args = append(args, "--env", name+"="+in.Env[name])
+	}
+	args = append(args, "--", in.Container)
+	args = append(args, in.Command...)
+
+	return dockerexec.Stream(ctx, "docker_exec", dockerexec.Timeout(in.Timeout, defaultTimeout, maxTimeout), args...), nil
+}
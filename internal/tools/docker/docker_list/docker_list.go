@@ -0,0 +1,142 @@
+package docker_list
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/docker/dockerexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Kind string `json:"kind,omitempty"`
+	All  bool   `json:"all,omitempty"`
+}
+
+// Container is a single entry returned for kind "containers"
+type Container struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	Command string `json:"command"`
+	State   string `json:"state"`
+	Status  string `json:"status"`
+	Ports   string `json:"ports,omitempty"`
+	// Magikarp is set for containers started by docker_run
+	Magikarp bool `json:"magikarp,omitempty"`
+}
+
+// Image is a single entry returned for kind "images"
+type Image struct {
+	ID         string `json:"id"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Size       string `json:"size"`
+	Created    string `json:"created"`
+}
+
+// Definition returns the tool definition for docker_list
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling docker_list schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := dockerexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("docker_list", err.Error(), true), nil
+	}
+
+	switch in.Kind {
+	case "", "containers":
+		args := []string{"ps", "--no-trunc", "--format", "{{json .}}"}
+		if in.All {
+			args = append(args, "--all")
+		}
+		out, err := dockerexec.Run(ctx, args...)
+		if err != nil {
+			return providers.NewToolResult("docker_list", err.Error(), true), nil
+		}
+		containers := []Container{}
+		for _, line := range jsonLines(out) {
+			var c struct {
+				ID, Names, Image, Command, State, Status, Ports, Labels string
+			}
+			if json.Unmarshal([]byte(line), &c) != nil {
+				continue
+			}
+			containers = append(containers, Container{
+				ID:       shortID(c.ID),
+				Name:     c.Names,
+				Image:    c.Image,
+				Command:  strings.Trim(c.Command, `"`),
+				State:    c.State,
+				Status:   c.Status,
+				Ports:    c.Ports,
+				Magikarp: strings.Contains(c.Labels, dockerexec.Label),
+			})
+		}
+		return dockerexec.JSONResult("docker_list", containers), nil
+	case "images":
+		args := []string{"images", "--format", "{{json .}}"}
+		if in.All {
+			args = append(args, "--all")
+		}
+		out, err := dockerexec.Run(ctx, args...)
+		if err != nil {
+			return providers.NewToolResult("docker_list", err.Error(), true), nil
+		}
+		images := []Image{}
+		for _, line := range jsonLines(out) {
+			var img struct {
+				ID, Repository, Tag, Size, CreatedSince string
+			}
+			if json.Unmarshal([]byte(line), &img) != nil {
+				continue
+			}
+			images = append(images, Image{
+				ID:         shortID(img.ID),
+				Repository: img.Repository,
+				Tag:        img.Tag,
+				Size:       img.Size,
+				Created:    img.CreatedSince,
+			})
+		}
+		return dockerexec.JSONResult("docker_list", images), nil
+	}
+	return providers.NewToolResult("docker_list", fmt.Sprintf("unknown kind %q; use containers or images", in.Kind), true), nil
+}
+
+// jsonLines splits the output of a --format '{{json .}}' listing into its objects
+func jsonLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// shortID shortens a container or image ID the way docker prints it
+func shortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,77 @@
+package docker_logs
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/docker/dockerexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+const (
+	defaultTail = 200
+	maxTail     = 5000
+)
+
+type input struct {
+	Container  string `json:"container"`
+	Tail       int    `json:"tail,omitempty"`
+	Since      string `json:"since,omitempty"`
+	Timestamps bool   `json:"timestamps,omitempty"`
+}
+
+// Definition returns the tool definition for docker_logs
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling docker_logs schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := dockerexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("docker_logs", err.Error(), true), nil
+	}
+	if err := dockerexec.CheckName("container", in.Container); err != nil {
+		return providers.NewToolResult("docker_logs", err.Error(), true), nil
+	}
+
+	tail := in.Tail
+	if tail <= 0 {
+		tail = defaultTail
+	}
+	tail = min(tail, maxTail)
+	args := []string{"logs", "--tail", strconv.Itoa(tail)}
+	if in.Since != "" {
+		args = append(args, "--since", in.Since)
+	}
+	if in.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	args = append(args, "--", in.Container)
+
+	// docker logs writes the container's stderr to its own, so both are combined
+	out, err := dockerexec.Output(ctx, args...)
+	if err != nil {
+		return providers.NewToolResult("docker_logs", err.Error(), true), nil
+	}
+	out = strings.TrimRight(out, "\n")
+	if out == "" {
+		out = "No output"
+	}
+	return providers.NewToolResult("docker_logs", out, false), nil
+}
@@ -0,0 +1,140 @@
+package docker_run
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/docker/dockerexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+const (
+	defaultTimeout = 300 * time.Second
+	maxTimeout     = 1800 * time.Second
+	// mountPoint is where the working directory is mounted in the container
+	mountPoint = "/workspace"
+	// pidsLimit bounds the processes a container may start
+	pidsLimit = "512"
+)
+
+type input struct {
+	Image        string            `json:"image"`
+	Command      []string          `json:"command,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	WorkDir      string            `json:"workdir,omitempty"`
+	MountWorkDir bool              `json:"mount_workdir,omitempty"`
+	Writable     bool              `json:"writable,omitempty"`
+	Network      bool              `json:"network,omitempty"`
+	Detach       bool              `json:"detach,omitempty"`
+	Timeout      int               `json:"timeout,omitempty"`
+}
+
+// Definition returns the tool definition for docker_run
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling docker_run schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := dockerexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("docker_run", err.Error(), true), nil
+	}
+	args, err := runArgs(in)
+	if err != nil {
+		return providers.NewToolResult("docker_run", err.Error(), true), nil
+	}
+
+	if in.Detach {
+		out, err := dockerexec.Run(ctx, args...)
+		if err != nil {
+			return providers.NewToolResult("docker_run", err.Error(), true), nil
+		}
+		id := strings.TrimSpace(out)
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		return providers.NewToolResult("docker_run", fmt.Sprintf("Started container %s from %s", id, in.Image), false), nil
+	}
+	return dockerexec.Stream(ctx, "docker_run", dockerexec.Timeout(in.Timeout, defaultTimeout, maxTimeout), args...), nil
+}
+
+// runArgs builds the docker run arguments for in. Only the flags below are ever
+// passed, so the model cannot widen what the container may access.
+func runArgs(in input) ([]string, error) {
+	if err := dockerexec.CheckName("image", in.Image); err != nil {
+		return nil, err
+	}
+	args := []string{"run", "--label", dockerexec.Label, "--security-opt", "no-new-privileges", "--pids-limit", pidsLimit}
+	if in.Detach {
+		args = append(args, "--detach")
+	} else {
+		args = append(args, "--rm")
+	}
+	if in.Name != "" {
+		if err := dockerexec.CheckName("name", in.Name); err != nil {
+			return nil, err
+		}
+		args = append(args, "--name", in.Name)
+	}
+	if !in.Network {
+		args = append(args, "--network", "none")
+	}
+
+	workDir := in.WorkDir
+	if in.MountWorkDir {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the working directory: %w", err)
+		}
+		if strings.Contains(cwd, ",") {
+			return nil, fmt.Errorf("cannot mount %s: the path contains a comma", cwd)
+		}
+		mount := "type=bind,source=" + cwd + ",target=" + mountPoint
+		if !in.Writable {
+			mount += ",readonly"
+		}
+		args = append(args, "--mount", mount)
+		if workDir == "" {
+			workDir = mountPoint
+		}
+	} else if in.Writable {
+		return nil, fmt.Errorf("writable requires mount_workdir")
+	}
+	if workDir != "" {
+		args = append(args, "--workdir", workDir)
+	}
+
+	names := make([]string, 0, len(in.Env))
+	for name := range in.Env {
+		if name == "" || strings.Contains(name, "=") {
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		args = append(args, "--env", name+"="+in.Env[name])
+	}
+
+	args = append(args, "--", in.Image)
+	return append(args, in.Command...), nil
+}
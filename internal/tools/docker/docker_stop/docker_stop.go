@@ -0,0 +1,56 @@
+package docker_stop
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/docker/dockerexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Container string `json:"container"`
+	Remove    bool   `json:"remove,omitempty"`
+}
+
+// Definition returns the tool definition for docker_stop
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling docker_stop schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := dockerexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("docker_stop", err.Error(), true), nil
+	}
+	if err := dockerexec.CheckName("container", in.Container); err != nil {
+		return providers.NewToolResult("docker_stop", err.Error(), true), nil
+	}
+
+	if _, err := dockerexec.Run(ctx, "stop", "--", in.Container); err != nil {
+		return providers.NewToolResult("docker_stop", err.Error(), true), nil
+	}
+	if !in.Remove {
+		return providers.NewToolResult("docker_stop", fmt.Sprintf("Stopped container %s", in.Container), false), nil
+	}
+	// Containers started with --rm are removed by docker once they stop
+	if _, err := dockerexec.Run(ctx, "rm", "--force", "--", in.Container); err != nil && !strings.Contains(err.Error(), "No such container") {
+		return providers.NewToolResult("docker_stop", fmt.Sprintf("Stopped container %s but could not remove it: %v", in.Container, err), true), nil
+	}
+	return providers.NewToolResult("docker_stop", fmt.Sprintf("Stopped and removed container %s", in.Container), false), nil
+}
@@ -0,0 +1,130 @@
+// Package dockerexec runs the docker CLI for the docker tools
+package dockerexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+// DefaultTimeout bounds the docker commands that only query the daemon
+const DefaultTimeout = 30 * time.Second
+
+// Label marks the containers started by docker_run
+const Label = "magikarp.tool=docker_run"
+
+// Run executes docker with args and returns its stdout. Failures include docker's
+// stderr so the model can see why the command failed.
+func Run(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, false, args)
+}
+
+// Output is like Run but returns stdout and stderr combined, e.g. for the output of
+// a container
+func Output(ctx context.Context, args ...string) (string, error) {
+	return run(ctx, true, args)
+}
+
+func run(ctx context.Context, combined bool, args []string) (string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", fmt.Errorf("docker is not installed or not on PATH")
+	}
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if combined {
+		cmd.Stderr = &stdout
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("docker %s timed out after %s", args[0], DefaultTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if combined {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("docker %s failed: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// Stream executes a long-running docker command, such as a build in a container, with
+// its output shown live in the UI, and returns a result for the model
+func Stream(ctx context.Context, tool string, timeout time.Duration, args ...string) *providers.ToolResult {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return providers.NewToolResult(tool, "docker is not installed or not on PATH", true)
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, "docker", args...)
+	out, aborted, err := tools.RunCommand(execCtx, tool, cmd)
+	output := strings.TrimRight(string(out), "\n")
+	var exitErr *exec.ExitError
+	switch {
+	case aborted:
+		return providers.NewToolResult(tool, "Command aborted by the user\n"+output, true)
+	case execCtx.Err() == context.DeadlineExceeded:
+		return providers.NewToolResult(tool, fmt.Sprintf("Command timed out after %s\n%s", timeout, output), true)
+	case err == nil:
+		return providers.NewToolResult(tool, output, false)
+	case errors.As(err, &exitErr):
+		return providers.NewToolResult(tool, fmt.Sprintf("Command exited with status %d\n%s", exitErr.ExitCode(), output), true)
+	default:
+		return providers.NewToolResult(tool, fmt.Sprintf("Execution failed: %v\n%s", err, output), true)
+	}
+}
+
+// CheckName rejects container and image names that docker would read as flags
+func CheckName(kind, name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%s must not be empty", kind)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("%s must not start with '-'", kind)
+	}
+	return nil
+}
+
+// DecodeInput converts the generic tool input map into the tool's input struct
+func DecodeInput(data map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error processing input parameters: %w", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("error parsing input parameters: %w", err)
+	}
+	return nil
+}
+
+// JSONResult encodes v as an indented JSON tool result
+func JSONResult(toolName string, v interface{}) *providers.ToolResult {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return providers.NewToolResult(toolName, fmt.Sprintf("Error encoding result: %v", err), true)
+	}
+	return providers.NewToolResult(toolName, string(out), false)
+}
+
+// Timeout returns the timeout requested in seconds, within the default and maximum
+func Timeout(seconds int, def, limit time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return min(time.Duration(seconds)*time.Second, limit)
+}
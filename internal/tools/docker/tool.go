@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/docker/docker_exec"
+	"github.com/pprunty/magikarp/internal/tools/docker/docker_list"
+	"github.com/pprunty/magikarp/internal/tools/docker/docker_logs"
+	"github.com/pprunty/magikarp/internal/tools/docker/docker_run"
+	"github.com/pprunty/magikarp/internal/tools/docker/docker_stop"
+)
+
+type dockerToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &dockerToolbox{
+		BaseToolbox: tools.NewBaseToolbox("docker", "Run and inspect Docker containers"),
+	}
+	tb.AddTool(docker_list.Definition())
+	tb.AddTool(docker_run.Definition())
+	tb.AddTool(docker_logs.Definition())
+	tb.AddTool(docker_exec.Definition())
+	tb.AddTool(docker_stop.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
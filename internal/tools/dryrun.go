@@ -0,0 +1,20 @@
+package tools
+
+import "context"
+
+// DryRunTools are the tools that, in a dry run, report the diff or command they would
+// run instead of changing anything
+var DryRunTools = []string{"edit_file", "write_file", "append_file", "apply_patch", "rename_symbol", "bash"}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context whose tool calls are dry runs
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether tool calls made with ctx must not change anything
+func IsDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
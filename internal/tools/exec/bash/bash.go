@@ -1,15 +1,19 @@
 package bash
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/pprunty/magikarp/internal/permissions"
 	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools"
 )
 
 //go:embed tool.json
@@ -20,6 +24,9 @@ type input struct {
 	Script  string `json:"script"`
 	Timeout int    `json:"timeout,omitempty"`
 	WorkDir string `json:"work_dir,omitempty"`
+	// Login and Env override tools.bash for this call; the shell can only be configured
+	Login *bool             `json:"login,omitempty"`
+	Env   map[string]string `json:"env,omitempty"`
 }
 
 // Definition returns the tool definition for the execute_command tool
@@ -37,29 +44,6 @@ func Definition() providers.ToolDefinition {
 	}
 }
 
-// List of potentially dangerous commands or command patterns that should be blocked
-var dangerousCommands = []string{
-	// File system destructive operations
-	"rm -rf", "rm -r", "rmdir", "mkfs", "dd", "shred", "truncate",
-
-	// System control commands
-	"shutdown", "reboot", "halt", "poweroff",
-
-	// Network-altering commands
-	"iptables", "ip6tables", "ufw",
-
-	// User management
-	"passwd", "useradd", "userdel", "groupadd", "groupdel",
-
-	// Privilege escalation
-	"sudo", "su", "doas",
-
-	// Other potentially harmful commands
-	"> /dev/null", ">/dev/null", "> /dev/", ">/dev/",
-	"> /proc/", ">/proc/", "> /sys/", ">/sys/",
-	"|", "||", "&&", ";", "$(", "`", // Command chaining
-}
-
 // run executes the command and returns the result
 func run(ctx context.Context, inputData map[string]interface{}) (*providers.ToolResult, error) {
 	// Convert generic input data to our structured input type
@@ -84,32 +68,54 @@ func run(ctx context.Context, inputData map[string]interface{}) (*providers.Tool
 		timeout = in.Timeout
 	}
 
-	// Security check: block potentially dangerous commands
-	commandLine := strings.ToLower(in.Script)
-	for _, dangerous := range dangerousCommands {
-		if strings.Contains(commandLine, dangerous) {
-			return providers.NewToolResult(
-				"bash",
-				fmt.Sprintf("Command rejected for security reasons: contains '%s'", dangerous),
-				true,
-			), nil
-		}
+	// Security check: risky commands were confirmed by the user through the permission
+	// policy before this runs, but forbidden ones are refused even when approved
+	conf := tools.Bash()
+	check := permissions.CheckBash(inputData, conf.Shell)
+	if check.Risk == permissions.Forbidden {
+		return providers.NewToolResult(
+			"bash",
+			"Command rejected for security reasons: "+strings.Join(check.Reasons, "; "),
+			true,
+		), nil
 	}
 
 	// Create a context with timeout
 	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	// Create command with the provided context (bash -c "script")
-	cmd := exec.CommandContext(execCtx, "bash", "-c", in.Script)
-
-	// Set working directory if specified
-	if in.WorkDir != "" {
-		cmd.Dir = in.WorkDir
+	// Prepare the script in the configured shell (bash -c "script" by default)
+	s, err := newScript(execCtx, in, conf)
+	if err != nil {
+		return providers.NewToolResult("bash", err.Error(), true), nil
+	}
+	if tools.IsDryRun(ctx) {
+		s.finish()
+		return providers.NewToolResult("bash", dryRun(s, in, check), false), nil
 	}
 
 	// Execute the command and capture output
-	out, err := cmd.CombinedOutput()
+	before := s.cmd.Dir
+	if before == "" {
+		before, _ = os.Getwd()
+	}
+	out, aborted, err := tools.RunCommand(execCtx, "bash", s.cmd)
+	if ended := s.finish(); ended != "" && (ended != before || ended != currentDir()) {
+		if ended == currentDir() {
+			out = fmt.Appendf(bytes.TrimRight(out, "\n"), "\n[working directory is now %s]", ended)
+		} else {
+			wd, _ := os.Getwd()
+			out = fmt.Appendf(bytes.TrimRight(out, "\n"), "\n[%s is outside the working directory and is not kept; the next script runs in %s]", ended, wd)
+		}
+	}
+
+	if aborted {
+		return providers.NewToolResult(
+			"bash",
+			fmt.Sprintf("Command aborted by the user\n%s", string(out)),
+			true,
+		), nil
+	}
 
 	// Check for timeout
 	if execCtx.Err() == context.DeadlineExceeded {
@@ -140,3 +146,16 @@ func run(ctx context.Context, inputData map[string]interface{}) (*providers.Tool
 	// Success case
 	return providers.NewToolResult("bash", strings.TrimSpace(string(out)), false), nil
 }
+
+// dryRun describes the script that would run, with the risks found in it
+func dryRun(s *script, in input, check permissions.ShellCheck) string {
+	dir := s.cmd.Dir
+	if dir == "" {
+		dir = "the working directory"
+	}
+	msg := fmt.Sprintf("Dry run: would run with %s in %s:\n%s", strings.Join(s.cmd.Args[:len(s.cmd.Args)-1], " "), dir, in.Script)
+	if len(check.Reasons) > 0 {
+		msg += "\nWould ask for approval: " + strings.Join(check.Reasons, "; ")
+	}
+	return msg + "\nThe command was not run."
+}
@@ -0,0 +1,192 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/config"
+)
+
+// cwdFileVar names the file a script writes its final directory to when the working
+// directory persists between calls
+const cwdFileVar = "MAGIKARP_CWD_FILE"
+
+// baseEnv are the variables passed to scripts even when an allowlist is configured, as
+// few commands work without them
+var baseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR", "LANG", "LC_*"}
+
+// cwd is the directory the last script ended in, when tools.bash.persist_cwd is set
+var (
+	cwdMu sync.Mutex
+	cwd   string
+)
+
+// currentDir returns the persisted working directory, or "" for the process's own
+func currentDir() string {
+	cwdMu.Lock()
+	defer cwdMu.Unlock()
+	return cwd
+}
+
+func setCurrentDir(dir string) {
+	cwdMu.Lock()
+	defer cwdMu.Unlock()
+	cwd = dir
+}
+
+// script is a prepared run of a script in a shell
+type script struct {
+	cmd *exec.Cmd
+	// cwdFile receives the final directory of the script; empty when it is not kept
+	cwdFile string
+}
+
+// newScript prepares the script of in to run in the configured shell, with the allowed
+// environment, in its working directory
+func newScript(ctx context.Context, in input, conf config.BashConfig) (*script, error) {
+	shell := conf.Shell
+	if shell == "" {
+		shell = "bash"
+	}
+	if !slices.Contains(config.Shells, shell) {
+		return nil, fmt.Errorf("unsupported shell %q; use one of %s", shell, strings.Join(config.Shells, ", "))
+	}
+	login := conf.Login
+	if in.Login != nil {
+		login = *in.Login
+	}
+
+	s := &script{}
+	body := in.Script
+	env := environ(conf.EnvAllowlist, in.Env)
+	if conf.PersistCwd {
+		f, err := os.CreateTemp("", "magikarp-cwd-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to track the working directory: %w", err)
+		}
+		f.Close()
+		s.cwdFile = f.Name()
+		env = append(env, cwdFileVar+"="+s.cwdFile)
+		body = withCwdTrailer(shell, body)
+	}
+
+	var args []string
+	if shell == "pwsh" {
+		if login {
+			args = append(args, "-Login")
+		}
+		args = append(args, "-NoLogo", "-NonInteractive", "-Command", body)
+	} else {
+		if login {
+			args = append(args, "-l")
+		}
+		args = append(args, "-c", body)
+	}
+
+	s.cmd = exec.CommandContext(ctx, shell, args...)
+	s.cmd.Env = env
+	s.cmd.Dir = workDir(in.WorkDir)
+	return s, nil
+}
+
+// finish persists the directory the script ended in, which it returns, and removes its
+// temporary file. Directories outside the working directory of magikarp are not kept,
+// so that the permission rules for paths outside it see where the next script runs;
+// it starts in the working directory again instead.
+func (s *script) finish() string {
+	if s.cwdFile == "" {
+		return ""
+	}
+	defer os.Remove(s.cwdFile)
+	data, err := os.ReadFile(s.cwdFile)
+	if err != nil {
+		return ""
+	}
+	dir := strings.TrimSpace(string(data))
+	if dir == "" {
+		return ""
+	}
+	if insideWorkDir(dir) {
+		setCurrentDir(dir)
+	} else {
+		setCurrentDir("")
+	}
+	return dir
+}
+
+// insideWorkDir reports whether dir is the working directory of magikarp or below it
+func insideWorkDir(dir string) bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	if real, err := filepath.EvalSymlinks(wd); err == nil {
+		wd = real
+	}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	rel, err := filepath.Rel(wd, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// withCwdTrailer makes the script record its final directory, even when it exits early
+func withCwdTrailer(shell, body string) string {
+	if shell == "pwsh" {
+		return "try {\n" + body + "\n} finally { (Get-Location).Path | Out-File -Encoding utf8 $env:" + cwdFileVar + " }"
+	}
+	return `trap 'pwd > "$` + cwdFileVar + `"' EXIT` + "\n" + body
+}
+
+// workDir resolves the directory a script runs in: dir relative to the persisted
+// working directory, or the persisted one itself
+func workDir(dir string) string {
+	base := currentDir()
+	switch {
+	case dir == "":
+		return base
+	case filepath.IsAbs(dir) || base == "":
+		return dir
+	default:
+		return filepath.Join(base, dir)
+	}
+}
+
+// environ returns the environment of a script: the process environment, limited to
+// the allowlist when one is configured, with extra set on top
+func environ(allowlist []string, extra map[string]string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if name == cwdFileVar || (len(allowlist) > 0 && !allowed(name, allowlist)) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		env = append(env, name+"="+extra[name])
+	}
+	return env
+}
+
+// allowed reports whether the variable name may be passed to scripts
+func allowed(name string, allowlist []string) bool {
+	for _, pattern := range slices.Concat(baseEnv, allowlist) {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package process
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxReturnedLines bounds the output lines included in a tool result
+const maxReturnedLines = 200
+
+// Status describes the state of the process in one line
+func (p *Process) Status() string {
+	if p.Running() {
+		return fmt.Sprintf("running for %s (pid %d)", time.Since(p.Started).Round(time.Second), p.cmd.Process.Pid)
+	}
+	code, ended, _ := p.ExitStatus()
+	ran := ended.Sub(p.Started).Round(100 * time.Millisecond)
+	if code < 0 {
+		return fmt.Sprintf("stopped by a signal after %s", ran)
+	}
+	return fmt.Sprintf("exited with status %d after %s", code, ran)
+}
+
+// Summary describes the process for listings
+func (p *Process) Summary() string {
+	summary := fmt.Sprintf("%s: %s — %s", p.ID, oneLine(p.Command), p.Status())
+	if n := p.Unread(); n > 0 {
+		summary += fmt.Sprintf(", %d unread lines", n)
+	}
+	return summary
+}
+
+// FormatOutput renders output lines for a tool result, keeping the most recent
+// lines when there are too many
+func FormatOutput(out Output) string {
+	lines := out.Lines
+	var b strings.Builder
+	omitted := out.Skipped
+	if len(lines) > maxReturnedLines {
+		omitted += len(lines) - maxReturnedLines
+		lines = lines[len(lines)-maxReturnedLines:]
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "… %d earlier lines omitted …\n", omitted)
+	}
+	if len(lines) == 0 {
+		b.WriteString("(no new output)")
+	}
+	b.WriteString(strings.Join(lines, "\n"))
+	return b.String()
+}
+
+// oneLine shortens a script to its first line for listings
+func oneLine(script string) string {
+	script = strings.TrimSpace(script)
+	if i := strings.IndexByte(script, '\n'); i >= 0 {
+		return script[:i] + " …"
+	}
+	return script
+}
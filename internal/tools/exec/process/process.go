@@ -0,0 +1,317 @@
+// Package process keeps track of the background processes started by the
+// start_process tool so later tool rounds can read their output and stop them.
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxRunning is the number of processes that may run at the same time
+	MaxRunning = 8
+	// maxBufferedLines is the number of output lines kept per process
+	maxBufferedLines = 2000
+	// stopGrace is how long a process has to exit after SIGTERM before it is killed
+	stopGrace = 5 * time.Second
+)
+
+// Process is a command running in the background
+type Process struct {
+	ID      string
+	Command string
+	Dir     string
+	Started time.Time
+
+	cmd  *exec.Cmd
+	done chan struct{}
+
+	mu       sync.Mutex
+	lines    []string
+	partial  []byte
+	dropped  int // lines discarded from the front of lines
+	read     int // index (counting dropped lines) of the first unread line
+	notify   chan struct{}
+	exitCode int
+	exitErr  error
+	ended    time.Time
+}
+
+// Output is a slice of a process' output
+type Output struct {
+	Lines []string
+	// Skipped counts the lines that were dropped from the buffer before they were read
+	Skipped int
+	// Matched is set when the output contained the pattern waited for
+	Matched bool
+}
+
+var (
+	mu        sync.Mutex
+	processes = map[string]*Process{}
+	nextID    = 1
+)
+
+// Start runs script with bash in dir and returns the new process
+func Start(script, dir string) (*Process, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	running := 0
+	for _, p := range processes {
+		if p.Running() {
+			running++
+		}
+	}
+	if running >= MaxRunning {
+		return nil, fmt.Errorf("%d processes are already running; stop one first", running)
+	}
+
+	p := &Process{
+		ID:      fmt.Sprintf("p%d", nextID),
+		Command: script,
+		Dir:     dir,
+		done:    make(chan struct{}),
+		notify:  make(chan struct{}),
+	}
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = p, p
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p.cmd = cmd
+	p.Started = time.Now()
+	nextID++
+	processes[p.ID] = p
+
+	go func() {
+		err := cmd.Wait()
+		p.mu.Lock()
+		p.flushPartial()
+		p.exitCode, p.exitErr = cmd.ProcessState.ExitCode(), err
+		p.ended = time.Now()
+		p.signal()
+		p.mu.Unlock()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Get returns the process with the given handle
+func Get(id string) (*Process, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	p, ok := processes[strings.TrimSpace(id)]
+	if !ok {
+		return nil, fmt.Errorf("no process with handle %q", id)
+	}
+	return p, nil
+}
+
+// List returns every process started in this session, oldest first
+func List() []*Process {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Process, 0, len(processes))
+	for _, p := range processes {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.Before(list[j].Started) })
+	return list
+}
+
+// Remove forgets a process that has exited
+func Remove(id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := processes[id]; ok && !p.Running() {
+		delete(processes, id)
+	}
+}
+
+// StopAll stops every running process; it is called when magikarp exits
+func StopAll() {
+	var wg sync.WaitGroup
+	for _, p := range List() {
+		if p.Running() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.Stop()
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// Write stores output of the process; it implements io.Writer for stdout and stderr
+func (p *Process) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := append(p.partial, b...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		p.appendLine(string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	p.partial = append([]byte(nil), data...)
+	p.signal()
+	return len(b), nil
+}
+
+// appendLine adds a line, dropping the oldest one when the buffer is full; p.mu must be held
+func (p *Process) appendLine(line string) {
+	p.lines = append(p.lines, line)
+	if len(p.lines) > maxBufferedLines {
+		p.lines = p.lines[1:]
+		p.dropped++
+	}
+}
+
+// flushPartial turns an unterminated last line into a line; p.mu must be held
+func (p *Process) flushPartial() {
+	if len(p.partial) > 0 {
+		p.appendLine(string(p.partial))
+		p.partial = nil
+	}
+}
+
+// signal wakes up readers waiting for output; p.mu must be held
+func (p *Process) signal() {
+	close(p.notify)
+	p.notify = make(chan struct{})
+}
+
+// Running reports whether the process has not exited yet
+func (p *Process) Running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// ExitStatus returns the exit code of a finished process, when it ended and the
+// error returned by waiting for it; the code is -1 when it was killed by a signal
+func (p *Process) ExitStatus() (code int, ended time.Time, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.exitCode, p.ended, p.exitErr
+}
+
+// Unread returns the number of output lines not returned by ReadNew yet
+func (p *Process) Unread() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.dropped + len(p.lines) - max(p.read, p.dropped)
+}
+
+// ReadNew returns the output written since the previous read. When wait is positive
+// it blocks until the process exits, wait passes, ctx is cancelled or, when until is
+// set, a new line matches until.
+func (p *Process) ReadNew(ctx context.Context, wait time.Duration, until *regexp.Regexp) Output {
+	deadline := time.After(wait)
+	for {
+		p.mu.Lock()
+		start := max(p.read, p.dropped)
+		fresh := p.lines[start-p.dropped:]
+		matched := false
+		if until != nil {
+			for _, line := range fresh {
+				if until.MatchString(line) {
+					matched = true
+					break
+				}
+			}
+		}
+		notify := p.notify
+		p.mu.Unlock()
+
+		if wait <= 0 || matched || !p.Running() {
+			return p.take(matched)
+		}
+		if until == nil && len(fresh) > 0 {
+			// Without a pattern any output ends the wait, after a moment for the rest of a burst
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-ctx.Done():
+			}
+			return p.take(false)
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return p.take(false)
+		case <-ctx.Done():
+			return p.take(false)
+		}
+	}
+}
+
+// take returns the unread lines and marks them read
+func (p *Process) take(matched bool) Output {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.Running() {
+		p.flushPartial()
+	}
+	out := Output{Skipped: max(p.dropped-p.read, 0), Matched: matched}
+	start := max(p.read, p.dropped)
+	out.Lines = append([]string(nil), p.lines[start-p.dropped:]...)
+	p.read = p.dropped + len(p.lines)
+	return out
+}
+
+// Tail returns the last n lines of output without changing what counts as read
+func (p *Process) Tail(n int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lines := p.lines
+	if len(p.partial) > 0 {
+		lines = append(append([]string(nil), lines...), string(p.partial))
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append([]string(nil), lines...)
+}
+
+// Stop terminates the process and everything it started, escalating to SIGKILL
+// when it has not exited after a grace period
+func (p *Process) Stop() error {
+	if !p.Running() {
+		return nil
+	}
+	if err := terminate(p.cmd, false); err != nil && p.Running() {
+		return err
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(stopGrace):
+	}
+	if err := terminate(p.cmd, true); err != nil && p.Running() {
+		return err
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(stopGrace):
+		return errors.New("process did not exit after SIGKILL")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package process
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate kills the process; Windows has no SIGTERM to try first
+func terminate(cmd *exec.Cmd, kill bool) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,90 @@
+package process_output
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/exec/process"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+const maxWait = 120 // seconds
+
+type input struct {
+	Handle string `json:"handle,omitempty"`
+	Wait   int    `json:"wait,omitempty"`
+	Until  string `json:"until,omitempty"`
+	Tail   int    `json:"tail,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling process_output schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "process_output",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("process_output", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("process_output", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if strings.TrimSpace(in.Handle) == "" {
+		list := process.List()
+		if len(list) == 0 {
+			return providers.NewToolResult("process_output", "No background processes have been started", false), nil
+		}
+		lines := make([]string, len(list))
+		for i, p := range list {
+			lines[i] = p.Summary()
+		}
+		return providers.NewToolResult("process_output", strings.Join(lines, "\n"), false), nil
+	}
+
+	p, err := process.Get(in.Handle)
+	if err != nil {
+		return providers.NewToolResult("process_output", err.Error(), true), nil
+	}
+
+	var out process.Output
+	if in.Tail > 0 {
+		out = process.Output{Lines: p.Tail(in.Tail)}
+	} else {
+		var until *regexp.Regexp
+		if in.Until != "" {
+			if until, err = regexp.Compile(in.Until); err != nil {
+				return providers.NewToolResult("process_output", fmt.Sprintf("Invalid until pattern: %v", err), true), nil
+			}
+		}
+		out = p.ReadNew(ctx, time.Duration(min(max(in.Wait, 0), maxWait))*time.Second, until)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Process %s: %s\n", p.ID, p.Status())
+	if in.Until != "" && in.Tail == 0 && !out.Matched {
+		b.WriteString("No new line matched until\n")
+	}
+	b.WriteString("\nOutput:\n")
+	b.WriteString(process.FormatOutput(out))
+	return providers.NewToolResult("process_output", b.String(), false), nil
+}
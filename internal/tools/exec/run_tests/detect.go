@@ -0,0 +1,120 @@
+package run_tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Frameworks supported by run_tests
+const (
+	frameworkGo     = "go"
+	frameworkPytest = "pytest"
+	frameworkNpm    = "npm"
+	frameworkCargo  = "cargo"
+)
+
+// pytestMarkers are files that identify a Python project using pytest
+var pytestMarkers = []string{"pytest.ini", "conftest.py", "pyproject.toml", "setup.cfg", "tox.ini", "setup.py"}
+
+// detectFramework picks the test framework of the project in dir from its manifest files
+func detectFramework(dir string) (string, error) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("go.mod"):
+		return frameworkGo, nil
+	case exists("Cargo.toml"):
+		return frameworkCargo, nil
+	case hasTestScript(filepath.Join(dir, "package.json")):
+		return frameworkNpm, nil
+	}
+	for _, marker := range pytestMarkers {
+		if exists(marker) {
+			return frameworkPytest, nil
+		}
+	}
+	return "", fmt.Errorf("could not detect the test framework in %s (looked for go.mod, Cargo.toml, package.json with a test script and pytest configuration); pass framework explicitly", dir)
+}
+
+// hasTestScript reports whether the package.json at path defines a "test" script
+func hasTestScript(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return false
+	}
+	return pkg.Scripts["test"] != ""
+}
+
+// npmClient returns the package manager whose lockfile is in dir
+func npmClient(dir string) string {
+	for _, lock := range []struct{ file, client string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			return lock.client
+		}
+	}
+	return "npm"
+}
+
+// testCommand builds the command line that runs the suite, narrowed to target (a
+// package, file or directory) and to tests matching filter
+func testCommand(framework, dir, target, filter string) ([]string, error) {
+	switch framework {
+	case frameworkGo:
+		if target == "" {
+			target = "./..."
+		}
+		args := []string{"go", "test", target}
+		if filter != "" {
+			args = append(args, "-run", filter)
+		}
+		return args, nil
+	case frameworkPytest:
+		args := []string{"python3", "-m", "pytest", "-q", "-rfE"}
+		if target != "" {
+			args = append(args, target)
+		}
+		if filter != "" {
+			args = append(args, "-k", filter)
+		}
+		return args, nil
+	case frameworkCargo:
+		args := []string{"cargo", "test"}
+		if target != "" {
+			args = append(args, "--package", target)
+		}
+		if filter != "" {
+			args = append(args, filter)
+		}
+		return args, nil
+	case frameworkNpm:
+		args := []string{npmClient(dir), "test"}
+		// Arguments after -- reach the test runner; jest and vitest treat them as file filters
+		var extra []string
+		if target != "" {
+			extra = append(extra, target)
+		}
+		if filter != "" {
+			extra = append(extra, "-t", filter)
+		}
+		if len(extra) > 0 {
+			args = append(append(args, "--"), extra...)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unsupported framework %q (want go, pytest, npm or cargo)", framework)
+}
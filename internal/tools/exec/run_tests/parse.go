@@ -0,0 +1,209 @@
+package run_tests
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxFailureDetail bounds the lines kept for each failure
+const maxFailureDetail = 15
+
+// failure is a single failing test or build error
+type failure struct {
+	Name   string
+	Detail []string
+}
+
+// report is what was learned from the test output
+type report struct {
+	Summary  string
+	Failures []failure
+}
+
+var (
+	goFailRe        = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	goPkgFailRe     = regexp.MustCompile(`^FAIL\s+(\S+)(?:\s+(\[.*\]|[\d.]+s))?$`)
+	goPkgOkRe       = regexp.MustCompile(`^ok\s+\S+`)
+	goBuildHeaderRe = regexp.MustCompile(`^# (\S+)`)
+	pytestFailedRe  = regexp.MustCompile(`^(?:FAILED|ERROR) (\S+)(?: - (.*))?$`)
+	pytestSummaryRe = regexp.MustCompile(`^=+ (.*\d+ (?:passed|failed|error|errors|skipped).*) =+$`)
+	cargoFailRe     = regexp.MustCompile(`^test (\S+) \.\.\. FAILED$`)
+	cargoSectionRe  = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+	cargoSummaryRe  = regexp.MustCompile(`^test result: (.*)$`)
+	jestFailRe      = regexp.MustCompile(`^\s*● (.+)$`)
+	vitestFailRe    = regexp.MustCompile(`^\s*(?:FAIL|×|✕)\s+(.+)$`)
+	jestSummaryRe   = regexp.MustCompile(`^\s*Tests:?\s+(.*\d+ (?:passed|failed).*)$`)
+)
+
+// parseOutput extracts failures and the summary line from the output of framework
+func parseOutput(framework string, lines []string) report {
+	switch framework {
+	case frameworkGo:
+		return parseGo(lines)
+	case frameworkPytest:
+		return parsePytest(lines)
+	case frameworkCargo:
+		return parseCargo(lines)
+	case frameworkNpm:
+		return parseJest(lines)
+	}
+	return report{}
+}
+
+func parseGo(lines []string) report {
+	var r report
+	ok, failed := 0, 0
+	var current *failure
+	for _, line := range lines {
+		switch {
+		case goFailRe.MatchString(line):
+			r.Failures = append(r.Failures, failure{Name: goFailRe.FindStringSubmatch(line)[1]})
+			current = &r.Failures[len(r.Failures)-1]
+		case goBuildHeaderRe.MatchString(line):
+			r.Failures = append(r.Failures, failure{Name: "build " + goBuildHeaderRe.FindStringSubmatch(line)[1]})
+			current = &r.Failures[len(r.Failures)-1]
+		case goPkgFailRe.MatchString(line):
+			failed++
+			current = nil
+		case goPkgOkRe.MatchString(line):
+			ok++
+			current = nil
+		case current != nil && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") || strings.Contains(current.Name, "build ")):
+			if line == "FAIL" || strings.HasPrefix(line, "=== ") {
+				current = nil
+				continue
+			}
+			addDetail(current, line)
+		default:
+			current = nil
+		}
+	}
+	if ok+failed > 0 {
+		r.Summary = fmt.Sprintf("packages: %d ok, %d failed", ok, failed)
+	}
+	return r
+}
+
+func parsePytest(lines []string) report {
+	var r report
+	for _, line := range lines {
+		if m := pytestFailedRe.FindStringSubmatch(line); m != nil {
+			f := failure{Name: m[1]}
+			if m[2] != "" {
+				f.Detail = []string{m[2]}
+			}
+			r.Failures = append(r.Failures, f)
+		}
+		if m := pytestSummaryRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			r.Summary = m[1]
+		}
+	}
+	return r
+}
+
+func parseCargo(lines []string) report {
+	var r report
+	byName := map[string]int{}
+	var current *failure
+	for _, line := range lines {
+		switch {
+		case cargoFailRe.MatchString(line):
+			name := cargoFailRe.FindStringSubmatch(line)[1]
+			if _, seen := byName[name]; !seen {
+				byName[name] = len(r.Failures)
+				r.Failures = append(r.Failures, failure{Name: name})
+			}
+		case cargoSectionRe.MatchString(line):
+			name := cargoSectionRe.FindStringSubmatch(line)[1]
+			i, seen := byName[name]
+			if !seen {
+				i = len(r.Failures)
+				byName[name] = i
+				r.Failures = append(r.Failures, failure{Name: name})
+			}
+			current = &r.Failures[i]
+		case cargoSummaryRe.MatchString(line):
+			r.Summary = cargoSummaryRe.FindStringSubmatch(line)[1]
+			current = nil
+		case line == "" || line == "failures:" || strings.HasPrefix(line, "note: "):
+			current = nil
+		case current != nil:
+			addDetail(current, line)
+		}
+	}
+	return r
+}
+
+func parseJest(lines []string) report {
+	var r report
+	var current *failure
+	for _, line := range lines {
+		switch {
+		case jestFailRe.MatchString(line):
+			r.Failures = append(r.Failures, failure{Name: strings.TrimSpace(jestFailRe.FindStringSubmatch(line)[1])})
+			current = &r.Failures[len(r.Failures)-1]
+		case vitestFailRe.MatchString(line):
+			r.Failures = append(r.Failures, failure{Name: strings.TrimSpace(vitestFailRe.FindStringSubmatch(line)[1])})
+			current = nil
+		case jestSummaryRe.MatchString(line):
+			r.Summary = strings.TrimSpace(jestSummaryRe.FindStringSubmatch(line)[1])
+			current = nil
+		case current != nil && strings.TrimSpace(line) != "":
+			addDetail(current, line)
+		}
+	}
+	return r
+}
+
+func addDetail(f *failure, line string) {
+	if len(f.Detail) < maxFailureDetail {
+		f.Detail = append(f.Detail, strings.TrimRight(line, " \t"))
+	}
+}
+
+// failureLineRe matches lines worth keeping when the log is truncated
+var failureLineRe = regexp.MustCompile(`(?i)(--- FAIL|^FAIL|FAILED|panic:|\berror\b|assert|✕|×|●|Traceback)`)
+
+// truncateLog keeps the head and tail of a long log and the lines around failures,
+// replacing the rest with a marker
+func truncateLog(lines []string, maxLines int) []string {
+	if len(lines) <= maxLines {
+		return lines
+	}
+	const head, tail, around = 10, 30, 5
+
+	keep := make([]bool, len(lines))
+	mark := func(from, to int) {
+		for i := max(0, from); i < min(len(lines), to); i++ {
+			keep[i] = true
+		}
+	}
+	mark(0, head)
+	mark(len(lines)-tail, len(lines))
+	budget := maxLines - head - tail
+	for i, line := range lines {
+		if budget <= 0 {
+			break
+		}
+		if failureLineRe.MatchString(line) {
+			mark(i-around, i+around*2)
+			budget -= around * 3
+		}
+	}
+
+	var out []string
+	skipped := 0
+	for i, line := range lines {
+		if keep[i] {
+			if skipped > 0 {
+				out = append(out, fmt.Sprintf("… %d lines omitted …", skipped))
+				skipped = 0
+			}
+			out = append(out, line)
+		} else {
+			skipped++
+		}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package run_tests
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+const (
+	defaultTimeout = 300 // seconds
+	maxTimeout     = 1800
+	// maxLogLines is the length of the log returned before it is truncated
+	maxLogLines = 200
+	// maxFailures is the number of failures listed in the result
+	maxFailures = 20
+)
+
+type input struct {
+	Path      string `json:"path,omitempty"`
+	Framework string `json:"framework,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Filter    string `json:"filter,omitempty"`
+	Timeout   int    `json:"timeout,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling run_tests schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "run_tests",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("run_tests", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("run_tests", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	dir := filepath.Clean(in.Path)
+	if in.Path == "" {
+		dir = "."
+	}
+	if !filepath.IsLocal(dir) && dir != "." {
+		return providers.NewToolResult("run_tests", "Path must be local for security reasons", true), nil
+	}
+	if strings.HasPrefix(in.Target, "-") || strings.HasPrefix(in.Filter, "-") {
+		return providers.NewToolResult("run_tests", "target and filter must not start with '-'", true), nil
+	}
+
+	framework := strings.ToLower(in.Framework)
+	if framework == "" {
+		if framework, err = detectFramework(dir); err != nil {
+			return providers.NewToolResult("run_tests", err.Error(), true), nil
+		}
+	}
+	args, err := testCommand(framework, dir, in.Target, in.Filter)
+	if err != nil {
+		return providers.NewToolResult("run_tests", err.Error(), true), nil
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return providers.NewToolResult("run_tests", fmt.Sprintf("%s is not installed or not on PATH", args[0]), true), nil
+	}
+
+	timeout := defaultTimeout
+	if in.Timeout > 0 {
+		timeout = min(in.Timeout, maxTimeout)
+	}
+	execCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, args[0], args[1:]...)
+	cmd.Dir = dir
+	// Test binaries may leave children holding the output pipe; don't wait on them forever
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	out, aborted, runErr := tools.RunCommand(execCtx, "run_tests", cmd)
+	elapsed := time.Since(start).Round(100 * time.Millisecond)
+
+	status := "PASSED"
+	var exitErr *exec.ExitError
+	switch {
+	case aborted:
+		status = "ABORTED by the user"
+	case execCtx.Err() == context.DeadlineExceeded:
+		status = fmt.Sprintf("TIMED OUT after %d seconds", timeout)
+	case ctx.Err() != nil:
+		status = "CANCELLED"
+	case errors.As(runErr, &exitErr):
+		status = fmt.Sprintf("FAILED (exit status %d)", exitErr.ExitCode())
+	case runErr != nil:
+		return providers.NewToolResult("run_tests", fmt.Sprintf("Failed to run %s: %v", strings.Join(args, " "), runErr), true), nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	parsed := parseOutput(framework, lines)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Framework: %s\nCommand: %s\nResult: %s in %s\n", framework, strings.Join(args, " "), status, elapsed)
+	if parsed.Summary != "" {
+		fmt.Fprintf(&b, "Summary: %s\n", parsed.Summary)
+	}
+	if len(parsed.Failures) > 0 {
+		fmt.Fprintf(&b, "\nFailures (%d):\n", len(parsed.Failures))
+		for i, f := range parsed.Failures {
+			if i >= maxFailures {
+				fmt.Fprintf(&b, "… %d more\n", len(parsed.Failures)-maxFailures)
+				break
+			}
+			fmt.Fprintf(&b, "- %s\n", f.Name)
+			for _, line := range f.Detail {
+				fmt.Fprintf(&b, "    %s\n", strings.TrimSpace(line))
+			}
+		}
+	}
+	if status != "PASSED" || len(lines) <= maxLogLines/4 {
+		// Passing runs only need the summary unless the log is short
+		b.WriteString("\nOutput:\n")
+		b.WriteString(strings.Join(truncateLog(lines, maxLogLines), "\n"))
+	}
+
+	return providers.NewToolResult("run_tests", strings.TrimRight(b.String(), "\n"), status != "PASSED"), nil
+}
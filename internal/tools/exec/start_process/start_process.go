@@ -0,0 +1,117 @@
+package start_process
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/permissions"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/exec/process"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+const (
+	defaultWait      = 2 // seconds
+	defaultReadyWait = 30
+	maxWait          = 120
+)
+
+type input struct {
+	Command      string `json:"command"`
+	WorkDir      string `json:"work_dir,omitempty"`
+	ReadyPattern string `json:"ready_pattern,omitempty"`
+	Wait         *int   `json:"wait,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling start_process schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "start_process",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("start_process", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("start_process", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if strings.TrimSpace(in.Command) == "" {
+		return providers.NewToolResult("start_process", "command parameter cannot be empty", true), nil
+	}
+	// Risky commands were confirmed through the permission policy; forbidden ones never run
+	if check := permissions.CheckScript(in.Command); check.Risk == permissions.Forbidden {
+		return providers.NewToolResult("start_process", "Command rejected for security reasons: "+strings.Join(check.Reasons, "; "), true), nil
+	}
+
+	var ready *regexp.Regexp
+	if in.ReadyPattern != "" {
+		if ready, err = regexp.Compile(in.ReadyPattern); err != nil {
+			return providers.NewToolResult("start_process", fmt.Sprintf("Invalid ready_pattern: %v", err), true), nil
+		}
+	}
+	wait := defaultWait
+	if ready != nil {
+		wait = defaultReadyWait
+	}
+	if in.Wait != nil {
+		wait = min(max(*in.Wait, 0), maxWait)
+	}
+
+	p, err := process.Start(in.Command, in.WorkDir)
+	if err != nil {
+		return providers.NewToolResult("start_process", fmt.Sprintf("Failed to start the process: %v", err), true), nil
+	}
+
+	var out process.Output
+	if ready != nil {
+		out = p.ReadNew(ctx, time.Duration(wait)*time.Second, ready)
+	} else {
+		// Collect everything printed during the startup window, not just the first burst
+		select {
+		case <-time.After(time.Duration(wait) * time.Second):
+		case <-ctx.Done():
+		}
+		out = p.ReadNew(ctx, 0, nil)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Started process %s\nStatus: %s\n", p.ID, p.Status())
+	switch {
+	case ready == nil:
+	case out.Matched:
+		b.WriteString("Ready: output matched ready_pattern\n")
+	case p.Running():
+		fmt.Fprintf(&b, "Ready: ready_pattern did not match within %d seconds; the process is still running\n", wait)
+	}
+	b.WriteString("\nOutput:\n")
+	b.WriteString(process.FormatOutput(out))
+
+	if p.Running() {
+		fmt.Fprintf(&b, "\n\nUse process_output with handle %q to read more output and stop_process to stop it.", p.ID)
+		return providers.NewToolResult("start_process", b.String(), false), nil
+	}
+	// The whole output has been returned, so there is nothing left to poll
+	process.Remove(p.ID)
+	code, _, _ := p.ExitStatus()
+	b.WriteString("\n\nThe process has already exited.")
+	return providers.NewToolResult("start_process", b.String(), code != 0), nil
+}
@@ -0,0 +1,84 @@
+package stop_process
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/exec/process"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+type input struct {
+	Handle string `json:"handle"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling stop_process schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "stop_process",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("stop_process", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("stop_process", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	handle := strings.TrimSpace(in.Handle)
+	if handle == "" {
+		return providers.NewToolResult("stop_process", "handle parameter cannot be empty", true), nil
+	}
+
+	if handle == "all" {
+		list := process.List()
+		process.StopAll()
+		if len(list) == 0 {
+			return providers.NewToolResult("stop_process", "No background processes have been started", false), nil
+		}
+		lines := make([]string, len(list))
+		for i, p := range list {
+			lines[i] = p.Summary()
+			process.Remove(p.ID)
+		}
+		return providers.NewToolResult("stop_process", "Stopped all background processes:\n"+strings.Join(lines, "\n"), false), nil
+	}
+
+	p, err := process.Get(handle)
+	if err != nil {
+		return providers.NewToolResult("stop_process", err.Error(), true), nil
+	}
+	wasRunning := p.Running()
+	if err := p.Stop(); err != nil {
+		return providers.NewToolResult("stop_process", fmt.Sprintf("Failed to stop process %s: %v", p.ID, err), true), nil
+	}
+	out := p.ReadNew(ctx, 0, nil)
+	process.Remove(p.ID)
+
+	var b strings.Builder
+	if wasRunning {
+		fmt.Fprintf(&b, "Stopped process %s: %s\n", p.ID, p.Status())
+	} else {
+		fmt.Fprintf(&b, "Process %s had already %s\n", p.ID, p.Status())
+	}
+	b.WriteString("\nOutput:\n")
+	b.WriteString(process.FormatOutput(out))
+	return providers.NewToolResult("stop_process", b.String(), false), nil
+}
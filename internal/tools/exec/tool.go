@@ -3,6 +3,10 @@ package exec
 import (
 	"github.com/pprunty/magikarp/internal/tools"
 	"github.com/pprunty/magikarp/internal/tools/exec/bash"
+	"github.com/pprunty/magikarp/internal/tools/exec/process_output"
+	"github.com/pprunty/magikarp/internal/tools/exec/run_tests"
+	"github.com/pprunty/magikarp/internal/tools/exec/start_process"
+	"github.com/pprunty/magikarp/internal/tools/exec/stop_process"
 )
 
 type execToolbox struct {
@@ -14,6 +18,10 @@ func New() tools.Toolbox {
 		BaseToolbox: tools.NewBaseToolbox("execution", "Execute shell commands"),
 	}
 	tb.AddTool(bash.Definition())
+	tb.AddTool(run_tests.Definition())
+	tb.AddTool(start_process.Definition())
+	tb.AddTool(process_output.Definition())
+	tb.AddTool(stop_process.Definition())
 	return tb
 }
 
@@ -0,0 +1,62 @@
+package append_file
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+type input struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// CreateIfMissing defaults to true when omitted
+	CreateIfMissing *bool `json:"create_if_missing,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling append_file schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "append_file",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("append_file", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("append_file", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if in.Content == "" {
+		return providers.NewToolResult("append_file", "content must not be empty", true), nil
+	}
+
+	change, err := fsedit.Load("append_file", in.Path)
+	if err != nil {
+		return providers.NewToolResult("append_file", err.Error(), true), nil
+	}
+	if !change.Exists && in.CreateIfMissing != nil && !*in.CreateIfMissing {
+		return providers.NewToolResult("append_file", fmt.Sprintf("File not found: %s", change.Path), true), nil
+	}
+	change.New = change.Old + in.Content
+
+	msg, isError := fsedit.Run(ctx, change)
+	return providers.NewToolResult("append_file", msg, isError), nil
+}
@@ -0,0 +1,220 @@
+package apply_patch
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+type input struct {
+	Patch string `json:"patch"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling apply_patch schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "apply_patch",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+// fileResult is the outcome of patching one file
+type fileResult struct {
+	path   string
+	change *fsedit.Change
+	notes  []string
+	err    error
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("apply_patch", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("apply_patch", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	msg, isError := Patch(ctx, "apply_patch", in.Patch)
+	return providers.NewToolResult("apply_patch", msg, isError), nil
+}
+
+// Patch applies a unified diff for tool as a whole: every hunk is validated first, each
+// change is reviewed and recorded for /undo, and in a dry run nothing is written. It
+// returns a message for the model and whether it is an error.
+func Patch(ctx context.Context, tool, patch string) (string, bool) {
+	patches, err := parsePatch(patch)
+	if err != nil {
+		return "Invalid patch: " + err.Error(), true
+	}
+
+	// Validate every hunk before touching the file system so the patch applies as a whole
+	results := validate(tool, patches)
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Sprintf("Patch not applied: %d of %d files failed validation. No files were changed.\n%s",
+			failed, len(results), report(results)), true
+	}
+
+	if tools.IsDryRun(ctx) {
+		return dryRun(results), false
+	}
+	return apply(ctx, results)
+}
+
+// validate applies the patches to the current file contents in memory. Patches for
+// the same file are applied one after another to a single change.
+func validate(tool string, patches []*filePatch) []*fileResult {
+	var results []*fileResult
+	byPath := map[string]*fileResult{}
+	for _, p := range patches {
+		r, seen := byPath[p.Path()]
+		if !seen {
+			r = &fileResult{path: p.Path()}
+			byPath[p.Path()] = r
+			results = append(results, r)
+			r.change, r.err = fsedit.Load(tool, p.Path())
+			if r.err == nil {
+				r.path = r.change.Path
+				r.change.New = r.change.Old
+			}
+		}
+		if r.err != nil {
+			continue
+		}
+
+		switch {
+		case p.NewPath == "":
+			r.err = fmt.Errorf("deleting files is not supported")
+			continue
+		case p.OldPath != "" && p.OldPath != p.NewPath:
+			r.err = fmt.Errorf("renaming files is not supported (from %s)", p.OldPath)
+			continue
+		case p.OldPath == "" && r.change.Exists && !seen:
+			r.err = fmt.Errorf("the patch creates the file but it already exists")
+			continue
+		case p.OldPath != "" && !r.change.Exists && r.change.New == "":
+			r.err = fmt.Errorf("file not found")
+			continue
+		}
+
+		r.change.New, r.notes, r.err = applyHunks(r.change.New, p)
+		if r.err == nil && len(r.change.New) > fsedit.MaxFileSize {
+			r.err = fmt.Errorf("the patched file would exceed the %d MB limit", fsedit.MaxFileSize>>20)
+		}
+	}
+	return results
+}
+
+// apply reviews each change and writes them all once every one is accepted. If a write
+// fails, the files already written are restored.
+func apply(ctx context.Context, results []*fileResult) (string, bool) {
+	var pending []*fileResult
+	for _, r := range results {
+		if r.change.Old == r.change.New && r.change.Exists {
+			r.notes = append(r.notes, "no changes")
+			continue
+		}
+		proposed := r.change.New
+		accepted, err := fsedit.Review(ctx, r.change)
+		if err != nil {
+			return fmt.Sprintf("Error reviewing change to %s: %v. No files were changed.", r.path, err), true
+		}
+		if !accepted {
+			return fmt.Sprintf("The user rejected the change to %s. No files were changed.", r.path), true
+		}
+		if r.change.New != proposed {
+			r.notes = append(r.notes, "edited by the user before it was applied")
+		}
+		pending = append(pending, r)
+	}
+	if len(pending) == 0 {
+		return "No changes: the files already have the patched content", false
+	}
+
+	journal, err := fsedit.CurrentJournal()
+	if err != nil {
+		return fmt.Sprintf("Failed to open change journal: %v. No files were changed.", err), true
+	}
+	var written []*fsedit.Entry
+	for _, r := range pending {
+		diff := r.change.Diff()
+		entry, err := fsedit.Apply(r.change)
+		if err != nil {
+			msg := fmt.Sprintf("Error writing %s: %v.", r.path, err)
+			for i := len(written) - 1; i >= 0; i-- {
+				if _, undoErr := journal.Undo(written[i].ID); undoErr != nil {
+					msg += fmt.Sprintf(" Failed to restore %s: %v.", written[i].Path, undoErr)
+				}
+			}
+			return msg + " Files written by this patch were restored.", true
+		}
+		written = append(written, entry)
+
+		added, removed := fsedit.DiffStat(diff)
+		verb := "updated"
+		if !r.change.Exists {
+			verb = "created"
+		}
+		r.notes = append([]string{fmt.Sprintf("%s (+%d -%d lines), change #%d", verb, added, removed, entry.ID)}, r.notes...)
+	}
+
+	files := "files"
+	if len(pending) == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("Applied patch to %d %s. The user can revert each change with /undo.\n%s", len(pending), files, report(results)), false
+}
+
+// dryRun describes the changes the patch would make without writing them
+func dryRun(results []*fileResult) string {
+	var reports []string
+	for _, r := range results {
+		if r.change.Old == r.change.New && r.change.Exists {
+			continue
+		}
+		reports = append(reports, fsedit.DryRunReport(r.change))
+	}
+	if len(reports) == 0 {
+		return "Dry run: no changes, the files already have the patched content"
+	}
+	return strings.Join(reports, "\n")
+}
+
+// report lists the outcome of each file
+func report(results []*fileResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(&b, "✗ %s: %v\n", r.path, r.err)
+			continue
+		}
+		fmt.Fprintf(&b, "✓ %s", r.path)
+		if len(r.notes) > 0 {
+			b.WriteString(": " + strings.Join(r.notes, "; "))
+		}
+		b.WriteByte('\n')
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,287 @@
+package apply_patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderRe matches "@@ -oldStart[,oldCount] +newStart[,newCount] @@"
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// filePatch is the part of a unified diff that changes a single file
+type filePatch struct {
+	OldPath string // empty for a new file
+	NewPath string // empty for a deleted file
+	Hunks   []hunk
+}
+
+// Path is the file the patch applies to
+func (f *filePatch) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// hunk is a single @@ section of a file patch
+type hunk struct {
+	Header   string
+	OldStart int
+	OldCount int // -1 when the header omits it
+	Lines    []hunkLine
+	// NoNewline is set when the new side ends without a trailing newline
+	NoNewline bool
+}
+
+type hunkLine struct {
+	Kind byte // ' ', '-' or '+'
+	Text string
+}
+
+// oldLines returns the lines the hunk expects in the file
+func (h *hunk) oldLines() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Kind != '+' {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// newLines returns the lines the hunk replaces them with
+func (h *hunk) newLines() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Kind != '-' {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// trimBlankTail drops empty context lines at the end of the hunk and reports whether
+// there were any
+func (h *hunk) trimBlankTail() bool {
+	n := len(h.Lines)
+	for n > 0 && h.Lines[n-1] == (hunkLine{Kind: ' '}) {
+		n--
+	}
+	trimmed := n < len(h.Lines)
+	h.Lines = h.Lines[:n]
+	return trimmed
+}
+
+// parsePatch splits a unified diff into per-file patches. Text outside file patches,
+// such as "diff --git" and "index" lines, is ignored.
+func parsePatch(patch string) ([]*filePatch, error) {
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(patch, "\r\n", "\n"), "\n"), "\n")
+
+	var files []*filePatch
+	var current *filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			current = &filePatch{
+				OldPath: patchPath(strings.TrimPrefix(line, "--- "), "a/"),
+				NewPath: patchPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/"),
+			}
+			if current.Path() == "" {
+				return nil, fmt.Errorf("line %d: missing file name", i+1)
+			}
+			files = append(files, current)
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			current.Hunks = append(current.Hunks, h)
+			i = next - 1
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found; the patch must contain ---/+++ headers and @@ hunks")
+	}
+	for _, f := range files {
+		if len(f.Hunks) == 0 && f.NewPath != "" {
+			return nil, fmt.Errorf("%s: no hunks", f.Path())
+		}
+	}
+	return files, nil
+}
+
+// parseHunk reads the hunk starting at lines[start] and returns the index of the
+// first line after it
+func parseHunk(lines []string, start int) (hunk, int, error) {
+	m := hunkHeaderRe.FindStringSubmatch(lines[start])
+	if m == nil {
+		return hunk{}, 0, fmt.Errorf("line %d: malformed hunk header %q", start+1, lines[start])
+	}
+	h := hunk{Header: m[0], OldCount: -1}
+	h.OldStart, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		h.OldCount, _ = strconv.Atoi(m[2])
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") ||
+			(strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")) {
+			break
+		}
+		if line == "" {
+			// Editors and models often strip the space of empty context lines
+			h.Lines = append(h.Lines, hunkLine{Kind: ' '})
+			continue
+		}
+		switch line[0] {
+		case ' ', '-', '+':
+			h.Lines = append(h.Lines, hunkLine{Kind: line[0], Text: line[1:]})
+		case '\\':
+			// "\ No newline at end of file" applies to the preceding line
+			if n := len(h.Lines); n > 0 && h.Lines[n-1].Kind != '-' {
+				h.NoNewline = true
+			}
+		default:
+			return hunk{}, 0, fmt.Errorf("line %d: unexpected line in hunk %s: %q", i+1, h.Header, line)
+		}
+	}
+
+	// Blank lines separating file patches are not part of the hunk
+	for h.OldCount >= 0 && len(h.oldLines()) > h.OldCount {
+		last := h.Lines[len(h.Lines)-1]
+		if last.Kind != ' ' || last.Text != "" {
+			break
+		}
+		h.Lines = h.Lines[:len(h.Lines)-1]
+	}
+	return h, i, nil
+}
+
+// patchPath extracts the file name from a ---/+++ header, dropping the a/ or b/
+// prefix added by git and any trailing timestamp. /dev/null yields "".
+func patchPath(header, prefix string) string {
+	name, _, _ := strings.Cut(header, "\t")
+	name = strings.TrimSpace(name)
+	if name == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// applyHunks applies the hunks of f to content, returning the new content and notes
+// about hunks that applied at an offset from their header
+func applyHunks(content string, f *filePatch) (string, []string, error) {
+	lines := []string{}
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+
+	var notes []string
+	// Hunks apply in order: each is searched for after the end of the previous one
+	minPos, delta := 0, 0
+	for n := range f.Hunks {
+		h := &f.Hunks[n]
+		old, repl := h.oldLines(), h.newLines()
+		want := h.OldStart - 1 + delta
+		if len(old) == 0 {
+			// Pure insertion: "-N,0" inserts after line N
+			want = h.OldStart + delta
+		}
+		pos, fuzzy := findHunk(lines, old, want, minPos)
+		if pos < 0 && h.trimBlankTail() {
+			// A blank line after the hunk may separate it from the next file rather
+			// than be an empty context line
+			old, repl = h.oldLines(), h.newLines()
+			pos, fuzzy = findHunk(lines, old, want, minPos)
+		}
+		if pos < 0 {
+			return "", nil, hunkMismatch(lines, old, want, n+1, h.Header)
+		}
+		if pos != want {
+			notes = append(notes, fmt.Sprintf("hunk %d applied at offset %+d", n+1, pos-want))
+		}
+		if fuzzy {
+			notes = append(notes, fmt.Sprintf("hunk %d matched ignoring trailing whitespace", n+1))
+		}
+
+		lines = append(lines[:pos], append(append([]string{}, repl...), lines[pos+len(old):]...)...)
+		minPos = pos + len(repl)
+		delta += len(repl) - len(old)
+		if pos+len(repl) == len(lines) {
+			// The hunk reaches the end of the file, so it decides the final newline
+			trailingNewline = !h.NoNewline
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", notes, nil
+	}
+	result := strings.Join(lines, "\n")
+	if trailingNewline {
+		result += "\n"
+	}
+	return result, notes, nil
+}
+
+// findHunk returns the position at or after minPos where old matches lines, preferring
+// the position closest to want. Exact matches win over ones that only match when
+// trailing whitespace is ignored, which is reported as fuzzy.
+func findHunk(lines, old []string, want, minPos int) (int, bool) {
+	for _, fuzzy := range []bool{false, true} {
+		for offset := 0; offset <= len(lines)+max(want, 0); offset++ {
+			for _, pos := range []int{want + offset, want - offset} {
+				if pos >= minPos && pos+len(old) <= len(lines) && matchAt(lines, old, pos, fuzzy) {
+					return pos, fuzzy
+				}
+				if offset == 0 {
+					break
+				}
+			}
+		}
+	}
+	return -1, false
+}
+
+func matchAt(lines, old []string, pos int, fuzzy bool) bool {
+	for i, want := range old {
+		got := lines[pos+i]
+		if fuzzy {
+			got, want = strings.TrimRight(got, " \t"), strings.TrimRight(want, " \t")
+		}
+		if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// hunkMismatch explains why a hunk does not apply, pointing at the first line that
+// differs at the position given in its header
+func hunkMismatch(lines, old []string, want, n int, header string) error {
+	if len(old) == 0 {
+		return fmt.Errorf("hunk %d (%s) inserts past the end of the file (%d lines)", n, header, len(lines))
+	}
+	for i, expected := range old {
+		pos := want + i
+		if pos < 0 || pos >= len(lines) {
+			return fmt.Errorf("hunk %d (%s) does not match: the file has %d lines, expected line %d to be %q",
+				n, header, len(lines), pos+1, expected)
+		}
+		if lines[pos] != expected {
+			return fmt.Errorf("hunk %d (%s) does not match: line %d is %q, expected %q",
+				n, header, pos+1, lines[pos], expected)
+		}
+	}
+	return fmt.Errorf("hunk %d (%s) does not match the file", n, header)
+}
@@ -0,0 +1,80 @@
+package edit_file
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+type input struct {
+	Path       string `json:"path"`
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling edit_file schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "edit_file",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("edit_file", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("edit_file", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if in.OldString == "" {
+		return providers.NewToolResult("edit_file", "old_string must not be empty; use write_file to create files", true), nil
+	}
+	if in.OldString == in.NewString {
+		return providers.NewToolResult("edit_file", "old_string and new_string are identical", true), nil
+	}
+
+	change, err := fsedit.Load("edit_file", in.Path)
+	if err != nil {
+		return providers.NewToolResult("edit_file", err.Error(), true), nil
+	}
+	if !change.Exists {
+		return providers.NewToolResult("edit_file", fmt.Sprintf("File not found: %s (use write_file to create it)", change.Path), true), nil
+	}
+
+	count := strings.Count(change.Old, in.OldString)
+	switch {
+	case count == 0:
+		return providers.NewToolResult("edit_file", fmt.Sprintf("old_string not found in %s", change.Path), true), nil
+	case count > 1 && !in.ReplaceAll:
+		return providers.NewToolResult("edit_file",
+			fmt.Sprintf("old_string occurs %d times in %s; include more surrounding context or set replace_all=true", count, change.Path), true), nil
+	}
+
+	if in.ReplaceAll {
+		change.New = strings.ReplaceAll(change.Old, in.OldString, in.NewString)
+	} else {
+		change.New = strings.Replace(change.Old, in.OldString, in.NewString, 1)
+	}
+
+	msg, isError := fsedit.Run(ctx, change)
+	return providers.NewToolResult("edit_file", msg, isError), nil
+}
@@ -0,0 +1,224 @@
+package fsedit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+// ReviewedTools are the file-modifying tools that ask for confirmation through the
+// Reviewer themselves, so callers do not need a separate approval prompt for them
+var ReviewedTools = map[string]bool{
+	"edit_file":     true,
+	"write_file":    true,
+	"append_file":   true,
+	"apply_patch":   true,
+	"rename_symbol": true,
+}
+
+// MaxFileSize is the largest file the tools write, in bytes. It keeps a runaway
+// generation from filling the disk or the change journal.
+const MaxFileSize = 5 << 20
+
+// Change is a pending modification of a single file
+type Change struct {
+	Tool string
+	// Path is the cleaned path of the file being changed
+	Path string
+	// Old is the current content; empty when the file does not exist yet
+	Old    string
+	Exists bool
+	// New is the content that will be written. A Reviewer may replace it.
+	New string
+}
+
+// Diff returns the unified diff of the change
+func (c *Change) Diff() string {
+	return UnifiedDiff(c.Path, c.Old, c.New)
+}
+
+// Reviewer is asked to confirm a change before it is written. It returns false to
+// reject the change and may edit c.New before accepting.
+type Reviewer func(ctx context.Context, c *Change) (bool, error)
+
+type reviewerKey struct{}
+
+// WithReviewer returns a context whose file changes are confirmed by review
+func WithReviewer(ctx context.Context, review Reviewer) context.Context {
+	return context.WithValue(ctx, reviewerKey{}, review)
+}
+
+// Review asks the reviewer attached to ctx to confirm c. Without a reviewer the change
+// is accepted, relying on the caller's tool approval instead.
+func Review(ctx context.Context, c *Change) (bool, error) {
+	if review, ok := ctx.Value(reviewerKey{}).(Reviewer); ok && review != nil {
+		return review(ctx, c)
+	}
+	return true, nil
+}
+
+// Load reads the current state of path into a Change for tool
+func Load(tool, path string) (*Change, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path parameter is required")
+	}
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("path must be local for security reasons")
+	}
+	c := &Change{Tool: tool, Path: filepath.Clean(path)}
+
+	info, err := os.Stat(c.Path)
+	switch {
+	case os.IsNotExist(err):
+		return c, nil
+	case err != nil:
+		return nil, fmt.Errorf("error accessing file: %w", err)
+	case info.IsDir():
+		return nil, fmt.Errorf("path points to a directory, not a file: %s", c.Path)
+	}
+
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	c.Old = string(data)
+	c.Exists = true
+	return c, nil
+}
+
+// Apply records the original state of the file in the session's change journal and
+// atomically writes c.New to c.Path. The returned entry can be passed to Journal.Undo.
+func Apply(c *Change) (*Entry, error) {
+	mode := os.FileMode(0644)
+	if c.Exists {
+		if info, err := os.Stat(c.Path); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	journal, err := CurrentJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open change journal: %w", err)
+	}
+	entry, err := journal.record(c, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record original of %s: %w", c.Path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		journal.forget(entry.ID)
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := atomicWrite(c.Path, []byte(c.New), mode); err != nil {
+		journal.forget(entry.ID)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// atomicWrite writes data to a temporary file next to path and renames it into place
+func atomicWrite(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+// Run reviews c and applies it when accepted, returning a message for the model
+func Run(ctx context.Context, c *Change) (string, bool) {
+	if c.Old == c.New && c.Exists {
+		return fmt.Sprintf("No changes: %s already has the requested content", c.Path), false
+	}
+
+	if len(c.New) > MaxFileSize {
+		return fmt.Sprintf("Refusing to write %s: the new content (%s) exceeds the %s limit", c.Path, formatSize(len(c.New)), formatSize(MaxFileSize)), true
+	}
+
+	if tools.IsDryRun(ctx) {
+		return DryRunReport(c), false
+	}
+
+	proposed := c.New
+	accepted, err := Review(ctx, c)
+	if err != nil {
+		return fmt.Sprintf("Error reviewing change: %v", err), true
+	}
+	if !accepted {
+		return fmt.Sprintf("The user rejected the change to %s. The file was not modified.", c.Path), true
+	}
+
+	if len(c.New) > MaxFileSize {
+		return fmt.Sprintf("Refusing to write %s: the edited content exceeds the %s limit", c.Path, formatSize(MaxFileSize)), true
+	}
+
+	dir := filepath.Dir(c.Path)
+	_, statErr := os.Stat(dir)
+	createdDir := os.IsNotExist(statErr)
+
+	diff := c.Diff()
+	entry, err := Apply(c)
+	if err != nil {
+		return fmt.Sprintf("Error writing %s: %v", c.Path, err), true
+	}
+
+	added, removed := DiffStat(diff)
+	verb := "Updated"
+	if !c.Exists {
+		verb = "Created"
+	}
+	msg := fmt.Sprintf("%s %s (+%d -%d lines). Recorded as change #%d; the user can revert it with /undo.", verb, c.Path, added, removed, entry.ID)
+	if createdDir {
+		msg += fmt.Sprintf("\nCreated the missing directory %s.", dir)
+	}
+	if c.New != proposed {
+		msg += "\nThe user edited the change before it was applied. Final diff:\n" + diff
+	}
+	return msg, false
+}
+
+// DryRunReport describes what applying c would do, for dry runs
+func DryRunReport(c *Change) string {
+	diff := c.Diff()
+	added, removed := DiffStat(diff)
+	verb := "update"
+	if !c.Exists {
+		verb = "create"
+	}
+	return fmt.Sprintf("Dry run: would %s %s (+%d -%d lines); nothing was written.\n%s", verb, c.Path, added, removed, diff)
+}
+
+// formatSize renders a byte count for messages
+func formatSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
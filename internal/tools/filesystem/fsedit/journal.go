@@ -0,0 +1,195 @@
+package fsedit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry records one file change so it can be undone
+type Entry struct {
+	ID   int       `json:"id"`
+	Tool string    `json:"tool"`
+	Path string    `json:"path"` // absolute path of the changed file
+	Time time.Time `json:"time"`
+	// Existed is false when the change created the file; undoing it removes the file
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode"`
+	// Backup holds the original content when the file existed
+	Backup string `json:"backup,omitempty"`
+	// NewHash is the SHA-256 of the content written, used to detect later modifications
+	NewHash string `json:"new_hash"`
+	Undone  bool   `json:"undone"`
+}
+
+// Journal is the change journal of a session, stored under UndoDir
+type Journal struct {
+	mu      sync.Mutex
+	dir     string
+	entries []Entry
+}
+
+var (
+	currentJournal     *Journal
+	currentJournalOnce sync.Once
+	currentJournalErr  error
+)
+
+// UndoDir returns the directory holding the change journals of all sessions
+func UndoDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".magikarp", "undo"), nil
+}
+
+// CurrentJournal returns the journal of the running session, creating it on first use
+func CurrentJournal() (*Journal, error) {
+	currentJournalOnce.Do(func() {
+		root, err := UndoDir()
+		if err != nil {
+			currentJournalErr = err
+			return
+		}
+		id := fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+		currentJournal = &Journal{dir: filepath.Join(root, id)}
+	})
+	return currentJournal, currentJournalErr
+}
+
+// Entries returns a copy of the recorded changes, oldest first
+func (j *Journal) Entries() []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Entry(nil), j.entries...)
+}
+
+// record stores the original state of c before it is written and returns the entry.
+// mode is the permission the file will have after the change.
+func (j *Journal) record(c *Change, mode os.FileMode) (*Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	abs, err := filepath.Abs(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	entry := Entry{
+		ID:      len(j.entries) + 1,
+		Tool:    c.Tool,
+		Path:    abs,
+		Time:    time.Now(),
+		Existed: c.Exists,
+		Mode:    mode,
+		NewHash: hashContent(c.New),
+	}
+	if c.Exists {
+		entry.Backup = filepath.Join(j.dir, fmt.Sprintf("%04d-%s.orig", entry.ID, filepath.Base(c.Path)))
+		if err := os.WriteFile(entry.Backup, []byte(c.Old), 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	j.entries = append(j.entries, entry)
+	if err := j.save(); err != nil {
+		j.entries = j.entries[:len(j.entries)-1]
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// forget drops the most recent entry when the write it was recorded for failed
+func (j *Journal) forget(id int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if n := len(j.entries); n > 0 && j.entries[n-1].ID == id {
+		if backup := j.entries[n-1].Backup; backup != "" {
+			os.Remove(backup)
+		}
+		j.entries = j.entries[:n-1]
+		_ = j.save()
+	}
+}
+
+// Undo reverts the change with the given ID, or the most recent change that has not
+// been undone when id is 0. It refuses when the file was modified after the change,
+// so changes to the same file must be undone newest first.
+func (j *Journal) Undo(id int) (*Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	idx := -1
+	if id == 0 {
+		for i := len(j.entries) - 1; i >= 0; i-- {
+			if !j.entries[i].Undone {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+	} else {
+		if id < 1 || id > len(j.entries) {
+			return nil, fmt.Errorf("no change #%d (this session has %d)", id, len(j.entries))
+		}
+		idx = id - 1
+		if j.entries[idx].Undone {
+			return nil, fmt.Errorf("change #%d has already been undone", id)
+		}
+	}
+	entry := &j.entries[idx]
+
+	current, err := os.ReadFile(entry.Path)
+	switch {
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("%s no longer exists", entry.Path)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", entry.Path, err)
+	case hashContent(string(current)) != entry.NewHash:
+		return nil, fmt.Errorf("%s was modified after change #%d; undo later changes first", entry.Path, entry.ID)
+	}
+
+	if entry.Existed {
+		original, err := os.ReadFile(entry.Backup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		if err := atomicWrite(entry.Path, original, entry.Mode); err != nil {
+			return nil, err
+		}
+	} else if err := os.Remove(entry.Path); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+	}
+
+	entry.Undone = true
+	if err := j.save(); err != nil {
+		return nil, fmt.Errorf("reverted %s but failed to update the journal: %w", entry.Path, err)
+	}
+	undone := *entry
+	return &undone, nil
+}
+
+// save writes the journal index atomically
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicWrite(filepath.Join(j.dir, "journal.json"), data, 0600)
+}
+
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,7 +2,12 @@ package filesystem
 
 import (
 	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/append_file"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/apply_patch"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/edit_file"
 	"github.com/pprunty/magikarp/internal/tools/filesystem/read_file"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/tree"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/write_file"
 )
 
 type fsToolbox struct {
@@ -14,6 +19,11 @@ func New() tools.Toolbox {
 		BaseToolbox: tools.NewBaseToolbox("filesystem", "File system operations"),
 	}
 	tb.AddTool(read_file.Definition())
+	tb.AddTool(tree.Definition())
+	tb.AddTool(edit_file.Definition())
+	tb.AddTool(write_file.Definition())
+	tb.AddTool(append_file.Definition())
+	tb.AddTool(apply_patch.Definition())
 	return tb
 }
 
@@ -0,0 +1,171 @@
+package tree
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled .gitignore pattern
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer holds the .gitignore rules that apply to a directory and its children.
+// Paths are slash-separated and relative to the repository root.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// findRepoRoot returns the nearest directory at or above dir that contains .git,
+// or "" when dir is not inside a repository
+func findRepoRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// loadFile adds the rules of the ignore file at file, whose patterns are relative
+// to base (a path relative to the repository root, "" for the root itself)
+func (ig *ignorer) loadFile(file, base string) {
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := compileIgnoreRule(scanner.Text(), base); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+}
+
+// with returns an ignorer that also applies the .gitignore in dir, if any. The
+// receiver is not modified so sibling directories do not see each other's rules.
+func (ig *ignorer) with(dir, base string) *ignorer {
+	if _, err := os.Stat(filepath.Join(dir, ".gitignore")); err != nil {
+		return ig
+	}
+	next := &ignorer{rules: append([]ignoreRule(nil), ig.rules...)}
+	next.loadFile(filepath.Join(dir, ".gitignore"), base)
+	return next
+}
+
+// ignored reports whether rel is excluded. The last matching rule wins.
+func (ig *ignorer) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// compileIgnoreRule converts a .gitignore line into a rule matching paths relative
+// to the repository root
+func compileIgnoreRule(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, "\\")
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// Patterns with a slash are relative to the .gitignore; others match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if base != "" {
+		re.WriteString(regexp.QuoteMeta(base) + "/")
+	}
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+	re.WriteString(globToRegexp(line))
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = compiled
+	return rule, true
+}
+
+// globToRegexp translates gitignore glob syntax (*, ?, [...] and **) to a regexp
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			if end := strings.IndexByte(glob[i+1:], ']'); end >= 0 {
+				class := glob[i+1 : i+1+end]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				b.WriteString("[" + class + "]")
+				i += end + 1
+			} else {
+				b.WriteString(`\[`)
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// relToRoot returns dir relative to root as a slash path, "" for root itself
+func relToRoot(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return path.Clean(filepath.ToSlash(rel))
+}
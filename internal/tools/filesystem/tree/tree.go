@@ -0,0 +1,232 @@
+package tree
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+const (
+	defaultDepth      = 3
+	maxDepth          = 10
+	defaultMaxEntries = 400
+	maxEntries        = 2000
+)
+
+type input struct {
+	Path       string `json:"path,omitempty"`
+	MaxDepth   int    `json:"max_depth,omitempty"`
+	MaxEntries int    `json:"max_entries,omitempty"`
+	ShowHidden bool   `json:"show_hidden,omitempty"`
+	DirsOnly   bool   `json:"dirs_only,omitempty"`
+	// Gitignore defaults to true when omitted
+	Gitignore *bool `json:"gitignore,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling tree schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "tree",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+// walker renders the tree and keeps the running totals
+type walker struct {
+	repoRoot  string
+	in        input
+	gitignore bool
+	b         strings.Builder
+	entries   int
+	dirs      int
+	files     int
+	truncated bool
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("tree", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("tree", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if in.Path == "" {
+		in.Path = "."
+	}
+	if !filepath.IsLocal(in.Path) && filepath.Clean(in.Path) != "." {
+		return providers.NewToolResult("tree", "Path must be local for security reasons", true), nil
+	}
+	if in.MaxDepth <= 0 {
+		in.MaxDepth = defaultDepth
+	}
+	in.MaxDepth = min(in.MaxDepth, maxDepth)
+	if in.MaxEntries <= 0 {
+		in.MaxEntries = defaultMaxEntries
+	}
+	in.MaxEntries = min(in.MaxEntries, maxEntries)
+
+	dir := filepath.Clean(in.Path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return providers.NewToolResult("tree", fmt.Sprintf("Error accessing %s: %v", dir, err), true), nil
+	}
+	if !info.IsDir() {
+		return providers.NewToolResult("tree", fmt.Sprintf("%s is not a directory", dir), true), nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return providers.NewToolResult("tree", fmt.Sprintf("Error resolving %s: %v", dir, err), true), nil
+	}
+
+	w := &walker{in: in, gitignore: in.Gitignore == nil || *in.Gitignore}
+	ig := &ignorer{}
+	if w.gitignore {
+		// Outside a repository only the .gitignore files below the listed directory apply
+		if w.repoRoot = findRepoRoot(abs); w.repoRoot == "" {
+			w.repoRoot = abs
+		}
+		ig.loadFile(filepath.Join(w.repoRoot, ".git", "info", "exclude"), "")
+		ig = ig.with(w.repoRoot, "")
+		// Rules from the repository root down to the listed directory all apply
+		if rel := relToRoot(w.repoRoot, abs); rel != "" {
+			parts := strings.Split(rel, "/")
+			for i := range parts {
+				base := strings.Join(parts[:i+1], "/")
+				ig = ig.with(filepath.Join(w.repoRoot, filepath.FromSlash(base)), base)
+			}
+		}
+	}
+
+	dirs, files := w.list(abs, ig)
+	fmt.Fprintf(&w.b, "%s/ %s\n", filepath.ToSlash(dir), counts(len(dirs), len(files)))
+	w.walk(ctx, abs, "", 1, ig, dirs, files)
+
+	summary := fmt.Sprintf("\n%s, %s shown", plural(w.dirs, "directory"), plural(w.files, "file"))
+	if w.in.DirsOnly {
+		summary = fmt.Sprintf("\n%s shown", plural(w.dirs, "directory"))
+	}
+	if w.truncated {
+		summary += fmt.Sprintf(" (stopped after %d entries; list a subdirectory or lower max_depth for the rest)", w.in.MaxEntries)
+	}
+	if ctx.Err() != nil {
+		summary += " (cancelled)"
+	}
+	return providers.NewToolResult("tree", w.b.String()+summary, false), nil
+}
+
+// walk writes the children of dir, descending until the maximum depth
+func (w *walker) walk(ctx context.Context, dir, prefix string, depth int, ig *ignorer, dirs, files []os.DirEntry) {
+	children := dirs
+	if !w.in.DirsOnly {
+		children = append(append([]os.DirEntry{}, dirs...), files...)
+	}
+
+	for i, entry := range children {
+		if ctx.Err() != nil {
+			return
+		}
+		if w.entries >= w.in.MaxEntries {
+			w.truncated = true
+			return
+		}
+		w.entries++
+
+		connector, childPrefix := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		if !entry.IsDir() {
+			w.files++
+			if entry.Type()&os.ModeSymlink != 0 {
+				if target, err := os.Readlink(path); err == nil {
+					name += " -> " + target
+				}
+			}
+			w.b.WriteString(prefix + connector + name + "\n")
+			continue
+		}
+
+		w.dirs++
+		childIg := ig
+		if w.gitignore {
+			childIg = ig.with(path, relToRoot(w.repoRoot, path))
+		}
+		subDirs, subFiles := w.list(path, childIg)
+		w.b.WriteString(prefix + connector + name + "/ " + counts(len(subDirs), len(subFiles)) + "\n")
+		if depth < w.in.MaxDepth {
+			w.walk(ctx, path, prefix+childPrefix, depth+1, childIg, subDirs, subFiles)
+		}
+	}
+}
+
+// list returns the visible subdirectories and files of dir, each sorted by name
+func (w *walker) list(dir string, ig *ignorer) (dirs, files []os.DirEntry) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == ".git" || (!w.in.ShowHidden && strings.HasPrefix(name, ".")) {
+			continue
+		}
+		if w.gitignore && ig.ignored(relToRoot(w.repoRoot, filepath.Join(dir, name)), entry.IsDir()) {
+			continue
+		}
+		if entry.IsDir() {
+			dirs = append(dirs, entry)
+		} else {
+			files = append(files, entry)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return dirs, files
+}
+
+// counts describes the number of entries in a directory
+func counts(dirs, files int) string {
+	var parts []string
+	if dirs > 0 {
+		parts = append(parts, plural(dirs, "dir"))
+	}
+	if files > 0 {
+		parts = append(parts, plural(files, "file"))
+	}
+	if len(parts) == 0 {
+		return "(empty)"
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func plural(n int, noun string) string {
+	switch {
+	case n == 1:
+		return "1 " + noun
+	case strings.HasSuffix(noun, "y"):
+		return fmt.Sprintf("%d %sies", n, strings.TrimSuffix(noun, "y"))
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,58 @@
+package write_file
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/filesystem/fsedit"
+)
+
+//go:embed tool.json
+var wrapper []byte // tool.json contains name/description/input_schema
+
+type input struct {
+	Path      string `json:"path"`
+	Content   string `json:"content"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+}
+
+func Definition() providers.ToolDefinition {
+	var w map[string]any
+	if err := json.Unmarshal(wrapper, &w); err != nil {
+		fmt.Printf("Error unmarshaling write_file schema: %v\n", err)
+	}
+
+	return providers.ToolDefinition{
+		Name:        "write_file",
+		Description: w["description"].(string),
+		InputSchema: w["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("write_file", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("write_file", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	change, err := fsedit.Load("write_file", in.Path)
+	if err != nil {
+		return providers.NewToolResult("write_file", err.Error(), true), nil
+	}
+	if change.Exists && !in.Overwrite {
+		return providers.NewToolResult("write_file",
+			fmt.Sprintf("%s already exists; set overwrite=true to replace it completely, or use edit_file or append_file to change part of it", change.Path), true), nil
+	}
+	change.New = in.Content
+
+	msg, isError := fsedit.Run(ctx, change)
+	return providers.NewToolResult("write_file", msg, isError), nil
+}
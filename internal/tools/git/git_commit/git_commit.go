@@ -0,0 +1,111 @@
+package git_commit
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Message string   `json:"message"`
+	Paths   []string `json:"paths,omitempty"`
+	All     bool     `json:"all,omitempty"`
+	WorkDir string   `json:"work_dir,omitempty"`
+}
+
+// FileStat holds the line counts for one file in the commit
+type FileStat struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// Commit is the structured result returned by git_commit
+type Commit struct {
+	Hash    string     `json:"hash"`
+	Branch  string     `json:"branch"`
+	Subject string     `json:"subject"`
+	Files   []FileStat `json:"files"`
+}
+
+// Definition returns the tool definition for git_commit
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling git_commit schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("git_commit", err.Error(), true), nil
+	}
+	if strings.TrimSpace(in.Message) == "" {
+		return providers.NewToolResult("git_commit", "message is required", true), nil
+	}
+
+	if len(in.Paths) > 0 {
+		args := append([]string{"add", "--"}, in.Paths...)
+		if _, err := gitexec.Run(ctx, in.WorkDir, args...); err != nil {
+			return providers.NewToolResult("git_commit", err.Error(), true), nil
+		}
+	}
+
+	args := []string{"commit", "--file=-"}
+	if in.All {
+		args = append(args, "--all")
+	}
+	if _, err := gitexec.RunWithStdin(ctx, in.WorkDir, in.Message, args...); err != nil {
+		return providers.NewToolResult("git_commit", err.Error(), true), nil
+	}
+
+	out, err := gitexec.Run(ctx, in.WorkDir, "show", "--no-color", "--numstat", "--pretty=format:%H%x1f%s", "HEAD")
+	if err != nil {
+		return providers.NewToolResult("git_commit", err.Error(), true), nil
+	}
+	commit := parseShow(out)
+
+	if branch, err := gitexec.Run(ctx, in.WorkDir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		commit.Branch = strings.TrimSpace(branch)
+	}
+
+	return gitexec.JSONResult("git_commit", commit), nil
+}
+
+// parseShow parses `git show --numstat --pretty=format:%H%x1f%s` output
+func parseShow(out string) Commit {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	commit := Commit{Files: []FileStat{}}
+	if len(lines) == 0 {
+		return commit
+	}
+	commit.Hash, commit.Subject, _ = strings.Cut(lines[0], "\x1f")
+
+	for _, line := range lines[1:] {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		stat := FileStat{Path: parts[2]}
+		stat.Additions, _ = strconv.Atoi(parts[0])
+		stat.Deletions, _ = strconv.Atoi(parts[1])
+		commit.Files = append(commit.Files, stat)
+	}
+	return commit
+}
@@ -0,0 +1,119 @@
+package git_diff
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Staged   bool     `json:"staged,omitempty"`
+	Ref      string   `json:"ref,omitempty"`
+	Paths    []string `json:"paths,omitempty"`
+	StatOnly bool     `json:"stat_only,omitempty"`
+	MaxBytes int      `json:"max_bytes,omitempty"`
+	WorkDir  string   `json:"work_dir,omitempty"`
+}
+
+// FileStat holds the line counts for one changed file
+type FileStat struct {
+	Path      string `json:"path"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// Diff is the structured result returned by git_diff
+type Diff struct {
+	Files     []FileStat `json:"files"`
+	Diff      string     `json:"diff,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// Definition returns the tool definition for git_diff
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling git_diff schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("git_diff", err.Error(), true), nil
+	}
+
+	if in.MaxBytes <= 0 {
+		in.MaxBytes = 50_000
+	} else if in.MaxBytes > 200_000 {
+		in.MaxBytes = 200_000
+	}
+	if strings.HasPrefix(in.Ref, "-") {
+		return providers.NewToolResult("git_diff", "ref must not start with '-'", true), nil
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if in.Staged {
+		args = append(args, "--staged")
+	}
+	if in.Ref != "" {
+		args = append(args, in.Ref)
+	}
+	pathArgs := append([]string{"--"}, in.Paths...)
+
+	numstat, err := gitexec.Run(ctx, in.WorkDir, append(append(append([]string{}, args...), "--numstat"), pathArgs...)...)
+	if err != nil {
+		return providers.NewToolResult("git_diff", err.Error(), true), nil
+	}
+	result := Diff{Files: parseNumstat(numstat)}
+
+	if !in.StatOnly && len(result.Files) > 0 {
+		text, err := gitexec.Run(ctx, in.WorkDir, append(args, pathArgs...)...)
+		if err != nil {
+			return providers.NewToolResult("git_diff", err.Error(), true), nil
+		}
+		if len(text) > in.MaxBytes {
+			text = text[:in.MaxBytes]
+			result.Truncated = true
+		}
+		result.Diff = text
+	}
+
+	return gitexec.JSONResult("git_diff", result), nil
+}
+
+// parseNumstat parses `git diff --numstat` output ("added<TAB>deleted<TAB>path")
+func parseNumstat(out string) []FileStat {
+	files := []FileStat{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		stat := FileStat{Path: parts[2]}
+		if parts[0] == "-" && parts[1] == "-" {
+			stat.Binary = true
+		} else {
+			stat.Additions, _ = strconv.Atoi(parts[0])
+			stat.Deletions, _ = strconv.Atoi(parts[1])
+		}
+		files = append(files, stat)
+	}
+	return files
+}
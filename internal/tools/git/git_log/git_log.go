@@ -0,0 +1,102 @@
+package git_log
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	MaxCount int    `json:"max_count,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	Path     string `json:"path,omitempty"`
+	WorkDir  string `json:"work_dir,omitempty"`
+}
+
+// Commit is a single entry returned by git_log
+type Commit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Email   string `json:"email"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+// logFormat separates fields with 0x1f and records with 0x1e so subjects can contain anything
+const logFormat = "--pretty=format:%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e"
+
+// Definition returns the tool definition for git_log
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling git_log schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("git_log", err.Error(), true), nil
+	}
+
+	if in.MaxCount <= 0 {
+		in.MaxCount = 10
+	} else if in.MaxCount > 200 {
+		in.MaxCount = 200
+	}
+	if strings.HasPrefix(in.Ref, "-") {
+		return providers.NewToolResult("git_log", "ref must not start with '-'", true), nil
+	}
+
+	args := []string{"log", fmt.Sprintf("--max-count=%d", in.MaxCount), logFormat}
+	if in.Ref != "" {
+		args = append(args, in.Ref)
+	}
+	if in.Path != "" {
+		args = append(args, "--", in.Path)
+	}
+
+	out, err := gitexec.Run(ctx, in.WorkDir, args...)
+	if err != nil {
+		return providers.NewToolResult("git_log", err.Error(), true), nil
+	}
+
+	return gitexec.JSONResult("git_log", parse(out)), nil
+}
+
+// parse splits git log output produced with logFormat into commits
+func parse(out string) []Commit {
+	commits := []Commit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    fields[3],
+			Subject: fields[4],
+		})
+	}
+	return commits
+}
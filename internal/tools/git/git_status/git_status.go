@@ -0,0 +1,140 @@
+package git_status
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+// FileChange is a path together with its git status code
+type FileChange struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// Status is the structured result returned by git_status
+type Status struct {
+	Branch     string       `json:"branch"`
+	Upstream   string       `json:"upstream,omitempty"`
+	Ahead      int          `json:"ahead"`
+	Behind     int          `json:"behind"`
+	Staged     []FileChange `json:"staged"`
+	Unstaged   []FileChange `json:"unstaged"`
+	Untracked  []string     `json:"untracked"`
+	Conflicted []string     `json:"conflicted"`
+	Clean      bool         `json:"clean"`
+}
+
+// Definition returns the tool definition for git_status
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling git_status schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("git_status", err.Error(), true), nil
+	}
+
+	out, err := gitexec.Run(ctx, in.WorkDir, "status", "--porcelain=v1", "--branch", "--untracked-files=all")
+	if err != nil {
+		return providers.NewToolResult("git_status", err.Error(), true), nil
+	}
+
+	return gitexec.JSONResult("git_status", parse(out)), nil
+}
+
+var aheadBehindRe = regexp.MustCompile(`(ahead|behind) (\d+)`)
+
+// statusNames maps porcelain status letters to readable names
+var statusNames = map[byte]string{
+	'M': "modified",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'T': "type changed",
+}
+
+// parse converts `git status --porcelain=v1 --branch` output into a Status
+func parse(out string) Status {
+	st := Status{
+		Staged:     []FileChange{},
+		Unstaged:   []FileChange{},
+		Untracked:  []string{},
+		Conflicted: []string{},
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 3 {
+			continue
+		}
+		if strings.HasPrefix(line, "## ") {
+			parseBranch(&st, strings.TrimPrefix(line, "## "))
+			continue
+		}
+
+		x, y, path := line[0], line[1], line[3:]
+		switch {
+		case x == '?' && y == '?':
+			st.Untracked = append(st.Untracked, path)
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			st.Conflicted = append(st.Conflicted, path)
+		default:
+			if name, ok := statusNames[x]; ok {
+				st.Staged = append(st.Staged, FileChange{Path: path, Status: name})
+			}
+			if name, ok := statusNames[y]; ok {
+				st.Unstaged = append(st.Unstaged, FileChange{Path: path, Status: name})
+			}
+		}
+	}
+
+	st.Clean = len(st.Staged) == 0 && len(st.Unstaged) == 0 && len(st.Untracked) == 0 && len(st.Conflicted) == 0
+	return st
+}
+
+// parseBranch parses the "## branch...upstream [ahead 1, behind 2]" header line
+func parseBranch(st *Status, header string) {
+	info := ""
+	if i := strings.Index(header, " ["); i >= 0 {
+		header, info = header[:i], header[i:]
+	}
+	header = strings.TrimPrefix(header, "No commits yet on ")
+	if branch, upstream, ok := strings.Cut(header, "..."); ok {
+		st.Branch, st.Upstream = branch, upstream
+	} else {
+		st.Branch = header
+	}
+	for _, m := range aheadBehindRe.FindAllStringSubmatch(info, -1) {
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "ahead" {
+			st.Ahead = n
+		} else {
+			st.Behind = n
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gitexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// DefaultTimeout bounds every git invocation made by the git tools
+const DefaultTimeout = 30 * time.Second
+
+// Run executes git with args in dir and returns its stdout.
+// Failures include git's stderr so the model can see why the command failed.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	return RunWithStdin(ctx, dir, "", args...)
+}
+
+// RunWithStdin is like Run but feeds stdin to the git process
+func RunWithStdin(ctx context.Context, dir, stdin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	// Never block waiting for an editor or credentials prompt
+	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_EDITOR=true")
+
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git %s timed out after %s", args[0], DefaultTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+// DecodeInput converts the generic tool input map into the tool's input struct
+func DecodeInput(data map[string]interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error processing input parameters: %w", err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("error parsing input parameters: %w", err)
+	}
+	return nil
+}
+
+// JSONResult encodes v as an indented JSON tool result
+func JSONResult(toolName string, v interface{}) *providers.ToolResult {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return providers.NewToolResult(toolName, fmt.Sprintf("Error encoding result: %v", err), true)
+	}
+	return providers.NewToolResult(toolName, string(out), false)
+}
@@ -0,0 +1,28 @@
+package git
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/git/git_commit"
+	"github.com/pprunty/magikarp/internal/tools/git/git_diff"
+	"github.com/pprunty/magikarp/internal/tools/git/git_log"
+	"github.com/pprunty/magikarp/internal/tools/git/git_status"
+)
+
+type gitToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &gitToolbox{
+		BaseToolbox: tools.NewBaseToolbox("git", "Inspect and commit changes in git repositories"),
+	}
+	tb.AddTool(git_status.Definition())
+	tb.AddTool(git_diff.Definition())
+	tb.AddTool(git_log.Definition())
+	tb.AddTool(git_commit.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
@@ -0,0 +1,40 @@
+package ghtool
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+// Repo returns the repository named by arg ("owner/name"), or else the one the origin
+// remote of the repository in workDir points to
+func Repo(ctx context.Context, arg, workDir string) (github.Repo, error) {
+	if arg != "" {
+		return github.ParseRepo(arg)
+	}
+	out, err := gitexec.Run(ctx, workDir, "remote", "get-url", "origin")
+	if err != nil {
+		return github.Repo{}, fmt.Errorf("cannot tell the repository from the origin remote (%v); pass repo as owner/name", err)
+	}
+	repo, ok := github.RepoFromRemote(out)
+	if !ok {
+		return github.Repo{}, fmt.Errorf("the origin remote %s is not a GitHub repository; pass repo as owner/name", strings.TrimSpace(out))
+	}
+	return repo, nil
+}
+
+// CurrentBranch returns the branch checked out in workDir
+func CurrentBranch(ctx context.Context, workDir string) (string, error) {
+	out, err := gitexec.Run(ctx, workDir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	branch := strings.TrimSpace(out)
+	if branch == "HEAD" {
+		return "", fmt.Errorf("HEAD is detached; check out a branch first")
+	}
+	return branch, nil
+}
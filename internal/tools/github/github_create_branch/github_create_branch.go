@@ -0,0 +1,74 @@
+package github_create_branch
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Name    string `json:"name"`
+	Base    string `json:"base,omitempty"`
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+// Branch is the result of github_create_branch
+type Branch struct {
+	Branch string `json:"branch"`
+	Base   string `json:"base"`
+	Commit string `json:"commit"`
+}
+
+// Definition returns the tool definition for github_create_branch
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling github_create_branch schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("github_create_branch", err.Error(), true), nil
+	}
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" || strings.HasPrefix(in.Name, "-") || strings.HasPrefix(in.Base, "-") {
+		return providers.NewToolResult("github_create_branch", "name must be a branch name and base a ref, neither starting with '-'", true), nil
+	}
+	if _, err := gitexec.Run(ctx, in.WorkDir, "check-ref-format", "--branch", in.Name); err != nil {
+		return providers.NewToolResult("github_create_branch", fmt.Sprintf("%q is not a valid branch name", in.Name), true), nil
+	}
+
+	args := []string{"switch", "--create", in.Name}
+	if in.Base != "" {
+		args = append(args, in.Base)
+	}
+	if _, err := gitexec.Run(ctx, in.WorkDir, args...); err != nil {
+		return providers.NewToolResult("github_create_branch", err.Error(), true), nil
+	}
+	commit, err := gitexec.Run(ctx, in.WorkDir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return providers.NewToolResult("github_create_branch", err.Error(), true), nil
+	}
+
+	base := in.Base
+	if base == "" {
+		base = "HEAD"
+	}
+	return gitexec.JSONResult("github_create_branch", Branch{Branch: in.Name, Base: base, Commit: strings.TrimSpace(commit)}), nil
+}
@@ -0,0 +1,101 @@
+package github_create_pull_request
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+	"github.com/pprunty/magikarp/internal/tools/github/ghtool"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Title   string `json:"title"`
+	Body    string `json:"body,omitempty"`
+	Head    string `json:"head,omitempty"`
+	Base    string `json:"base,omitempty"`
+	Draft   bool   `json:"draft,omitempty"`
+	Push    *bool  `json:"push,omitempty"`
+	Repo    string `json:"repo,omitempty"`
+	WorkDir string `json:"work_dir,omitempty"`
+}
+
+// PullRequest is the result of github_create_pull_request
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+	Draft  bool   `json:"draft,omitempty"`
+}
+
+// Definition returns the tool definition for github_create_pull_request
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling github_create_pull_request schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+	}
+	if strings.TrimSpace(in.Title) == "" {
+		return providers.NewToolResult("github_create_pull_request", "title must not be empty", true), nil
+	}
+	if strings.HasPrefix(in.Head, "-") {
+		return providers.NewToolResult("github_create_pull_request", "head must not start with '-'", true), nil
+	}
+
+	repo, err := ghtool.Repo(ctx, in.Repo, in.WorkDir)
+	if err != nil {
+		return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+	}
+	head := in.Head
+	if head == "" {
+		if head, err = ghtool.CurrentBranch(ctx, in.WorkDir); err != nil {
+			return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+		}
+	}
+	client, err := github.Default()
+	if err != nil {
+		return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+	}
+	base := in.Base
+	if base == "" {
+		r, err := client.Repository(ctx, repo)
+		if err != nil {
+			return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+		}
+		base = r.DefaultBranch
+	}
+	if head == base {
+		return providers.NewToolResult("github_create_pull_request", fmt.Sprintf("the head branch is the base branch %s; create a branch for the change first with github_create_branch", base), true), nil
+	}
+
+	if in.Push == nil || *in.Push {
+		if _, err := gitexec.Run(ctx, in.WorkDir, "push", "--set-upstream", "origin", head); err != nil {
+			return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+		}
+	}
+	pr, err := client.CreatePullRequest(ctx, repo, github.NewPullRequest{Title: in.Title, Body: in.Body, Head: head, Base: base, Draft: in.Draft})
+	if err != nil {
+		return providers.NewToolResult("github_create_pull_request", err.Error(), true), nil
+	}
+	return gitexec.JSONResult("github_create_pull_request", PullRequest{Number: pr.Number, URL: pr.HTMLURL, Head: head, Base: base, Draft: pr.Draft}), nil
+}
@@ -0,0 +1,98 @@
+package github_list_issues
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+	"github.com/pprunty/magikarp/internal/tools/github/ghtool"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Repo     string   `json:"repo,omitempty"`
+	State    string   `json:"state,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+	Assignee string   `json:"assignee,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+	WorkDir  string   `json:"work_dir,omitempty"`
+}
+
+// Issue is a single entry returned by github_list_issues
+type Issue struct {
+	Number   int      `json:"number"`
+	Title    string   `json:"title"`
+	State    string   `json:"state"`
+	Labels   []string `json:"labels,omitempty"`
+	Author   string   `json:"author"`
+	Comments int      `json:"comments"`
+	Updated  string   `json:"updated"`
+	URL      string   `json:"url"`
+}
+
+// Definition returns the tool definition for github_list_issues
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling github_list_issues schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("github_list_issues", err.Error(), true), nil
+	}
+	switch in.State {
+	case "", "open", "closed", "all":
+	default:
+		return providers.NewToolResult("github_list_issues", "state must be open, closed or all", true), nil
+	}
+	if in.Limit <= 0 {
+		in.Limit = 20
+	}
+
+	repo, err := ghtool.Repo(ctx, in.Repo, in.WorkDir)
+	if err != nil {
+		return providers.NewToolResult("github_list_issues", err.Error(), true), nil
+	}
+	client, err := github.Default()
+	if err != nil {
+		return providers.NewToolResult("github_list_issues", err.Error(), true), nil
+	}
+	found, err := client.ListIssues(ctx, repo, github.IssueFilter{State: in.State, Labels: in.Labels, Assignee: in.Assignee, Limit: in.Limit})
+	if err != nil {
+		return providers.NewToolResult("github_list_issues", err.Error(), true), nil
+	}
+
+	issues := make([]Issue, 0, len(found))
+	for _, issue := range found {
+		var labels []string
+		for _, l := range issue.Labels {
+			labels = append(labels, l.Name)
+		}
+		issues = append(issues, Issue{
+			Number:   issue.Number,
+			Title:    issue.Title,
+			State:    issue.State,
+			Labels:   labels,
+			Author:   issue.User.Login,
+			Comments: issue.Comments,
+			Updated:  issue.UpdatedAt.Format("2006-01-02"),
+			URL:      issue.HTMLURL,
+		})
+	}
+	return gitexec.JSONResult("github_list_issues", issues), nil
+}
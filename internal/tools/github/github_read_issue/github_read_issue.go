@@ -0,0 +1,115 @@
+package github_read_issue
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/github"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+	"github.com/pprunty/magikarp/internal/tools/github/ghtool"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Number   int    `json:"number"`
+	Repo     string `json:"repo,omitempty"`
+	Comments *int   `json:"comments,omitempty"`
+	WorkDir  string `json:"work_dir,omitempty"`
+}
+
+// Issue is the result of github_read_issue
+type Issue struct {
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	State         string    `json:"state"`
+	IsPullRequest bool      `json:"is_pull_request,omitempty"`
+	Labels        []string  `json:"labels,omitempty"`
+	Assignees     []string  `json:"assignees,omitempty"`
+	Author        string    `json:"author"`
+	Created       string    `json:"created"`
+	URL           string    `json:"url"`
+	Body          string    `json:"body"`
+	TotalComments int       `json:"total_comments"`
+	Comments      []Comment `json:"comments,omitempty"`
+}
+
+// Comment is a comment on the issue
+type Comment struct {
+	Author  string `json:"author"`
+	Created string `json:"created"`
+	Body    string `json:"body"`
+}
+
+// Definition returns the tool definition for github_read_issue
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling github_read_issue schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	var in input
+	if err := gitexec.DecodeInput(data, &in); err != nil {
+		return providers.NewToolResult("github_read_issue", err.Error(), true), nil
+	}
+	if in.Number <= 0 {
+		return providers.NewToolResult("github_read_issue", "number must be a positive issue number", true), nil
+	}
+	maxComments := 30
+	if in.Comments != nil {
+		maxComments = min(max(*in.Comments, 0), 100)
+	}
+
+	repo, err := ghtool.Repo(ctx, in.Repo, in.WorkDir)
+	if err != nil {
+		return providers.NewToolResult("github_read_issue", err.Error(), true), nil
+	}
+	client, err := github.Default()
+	if err != nil {
+		return providers.NewToolResult("github_read_issue", err.Error(), true), nil
+	}
+	issue, err := client.Issue(ctx, repo, in.Number)
+	if err != nil {
+		return providers.NewToolResult("github_read_issue", err.Error(), true), nil
+	}
+
+	out := Issue{
+		Number:        issue.Number,
+		Title:         issue.Title,
+		State:         issue.State,
+		IsPullRequest: issue.PullRequest != nil,
+		Author:        issue.User.Login,
+		Created:       issue.CreatedAt.Format("2006-01-02"),
+		URL:           issue.HTMLURL,
+		Body:          issue.Body,
+		TotalComments: issue.Comments,
+	}
+	for _, l := range issue.Labels {
+		out.Labels = append(out.Labels, l.Name)
+	}
+	for _, a := range issue.Assignees {
+		out.Assignees = append(out.Assignees, a.Login)
+	}
+	if maxComments > 0 && issue.Comments > 0 {
+		comments, err := client.IssueComments(ctx, repo, in.Number, maxComments)
+		if err != nil {
+			return providers.NewToolResult("github_read_issue", err.Error(), true), nil
+		}
+		for _, c := range comments {
+			out.Comments = append(out.Comments, Comment{Author: c.User.Login, Created: c.CreatedAt.Format("2006-01-02"), Body: c.Body})
+		}
+	}
+	return gitexec.JSONResult("github_read_issue", out), nil
+}
@@ -0,0 +1,28 @@
+package github
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/github/github_create_branch"
+	"github.com/pprunty/magikarp/internal/tools/github/github_create_pull_request"
+	"github.com/pprunty/magikarp/internal/tools/github/github_list_issues"
+	"github.com/pprunty/magikarp/internal/tools/github/github_read_issue"
+)
+
+type githubToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &githubToolbox{
+		BaseToolbox: tools.NewBaseToolbox("github", "Read issues, create branches and open pull requests on GitHub"),
+	}
+	tb.AddTool(github_list_issues.Definition())
+	tb.AddTool(github_read_issue.Definition())
+	tb.AddTool(github_create_branch.Definition())
+	tb.AddTool(github_create_pull_request.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// LiveOutput follows a command while it runs, e.g. to show its output in the UI
+type LiveOutput struct {
+	// Start is called once the command runs, with a function that stops it
+	Start func(tool string, abort func())
+	// Line is called with each line the command writes to stdout or stderr. It must
+	// not block, as the command waits for it.
+	Line func(line string)
+	// Done is called when the command has finished
+	Done func()
+}
+
+// outputWaitDelay is how long output is still read after the command exited
+const outputWaitDelay = 2 * time.Second
+
+type liveOutputKey struct{}
+
+// WithLiveOutput returns a context whose commands report their output to live
+func WithLiveOutput(ctx context.Context, live LiveOutput) context.Context {
+	return context.WithValue(ctx, liveOutputKey{}, live)
+}
+
+// RunCommand runs cmd with stdout and stderr combined and returns its output. When a
+// LiveOutput is attached to ctx it receives every line as it is written and can abort
+// the command, which aborted reports.
+func RunCommand(ctx context.Context, tool string, cmd *exec.Cmd) (out []byte, aborted bool, err error) {
+	live, ok := ctx.Value(liveOutputKey{}).(LiveOutput)
+	if !ok {
+		out, err = cmd.CombinedOutput()
+		return out, false, err
+	}
+
+	w := &lineWriter{line: live.Line}
+	cmd.Stdout, cmd.Stderr = w, w
+	if cmd.WaitDelay == 0 {
+		// Children of the command may keep its output open; stop reading it once the
+		// command itself exited
+		cmd.WaitDelay = outputWaitDelay
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, false, err
+	}
+	var stopped atomic.Bool
+	if live.Start != nil {
+		live.Start(tool, func() {
+			stopped.Store(true)
+			_ = cmd.Process.Kill()
+		})
+	}
+	err = cmd.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The command succeeded but left a background process writing to its output
+		err = nil
+	}
+	w.flush()
+	if live.Done != nil {
+		live.Done()
+	}
+	return w.all.Bytes(), stopped.Load(), err
+}
+
+// lineWriter keeps everything written and passes each complete line on. exec.Cmd
+// writes stdout and stderr from one goroutine when both are the same writer.
+type lineWriter struct {
+	all     bytes.Buffer
+	partial []byte
+	line    func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.all.Write(p)
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if w.line != nil {
+			w.line(string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		}
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush passes on the last line when it did not end with a newline
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 && w.line != nil {
+		w.line(string(w.partial))
+	}
+	w.partial = nil
+}
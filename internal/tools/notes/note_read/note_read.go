@@ -0,0 +1,69 @@
+package note_read
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/notes"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Name string `json:"name,omitempty"`
+}
+
+// Definition returns the tool definition for note_read
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling note_read schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("note_read", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("note_read", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+
+	if name := strings.TrimSpace(in.Name); name != "" {
+		text, ok := notes.Read(name)
+		if !ok {
+			msg := fmt.Sprintf("No note named %q", name)
+			if names := notes.Names(); len(names) > 0 {
+				msg += "; the notes are: " + strings.Join(names, ", ")
+			}
+			return providers.NewToolResult("note_read", msg, true), nil
+		}
+		return providers.NewToolResult("note_read", text, false), nil
+	}
+
+	list := notes.List()
+	if len(list) == 0 {
+		return providers.NewToolResult("note_read", "The scratchpad is empty", false), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d notes:\n", len(list))
+	for _, n := range list {
+		first, _, _ := strings.Cut(strings.TrimSpace(n.Text), "\n")
+		lines := strings.Count(strings.TrimRight(n.Text, "\n"), "\n") + 1
+		fmt.Fprintf(&b, "- %s (%d lines): %s\n", n.Name, lines, first)
+	}
+	return providers.NewToolResult("note_read", strings.TrimRight(b.String(), "\n"), false), nil
+}
@@ -0,0 +1,71 @@
+package note_write
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/notes"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// Definition returns the tool definition for note_write
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling note_write schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("note_write", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("note_write", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		name = notes.DefaultName
+	}
+
+	switch in.Mode {
+	case "delete":
+		if !notes.Delete(name) {
+			return providers.NewToolResult("note_write", fmt.Sprintf("No note named %q", name), true), nil
+		}
+		return providers.NewToolResult("note_write", fmt.Sprintf("Deleted note %q", name), false), nil
+	case "", "replace", "append":
+	default:
+		return providers.NewToolResult("note_write", fmt.Sprintf("unknown mode %q; use replace, append or delete", in.Mode), true), nil
+	}
+	if strings.TrimSpace(in.Text) == "" {
+		return providers.NewToolResult("note_write", "text must not be empty", true), nil
+	}
+
+	text, err := notes.Write(name, in.Text, in.Mode == "append")
+	if err != nil {
+		return providers.NewToolResult("note_write", err.Error(), true), nil
+	}
+	lines := strings.Count(strings.TrimRight(text, "\n"), "\n") + 1
+	return providers.NewToolResult("note_write", fmt.Sprintf("Saved note %q (%d lines)", name, lines), false), nil
+}
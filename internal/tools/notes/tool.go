@@ -0,0 +1,24 @@
+package notes
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/notes/note_read"
+	"github.com/pprunty/magikarp/internal/tools/notes/note_write"
+)
+
+type notesToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &notesToolbox{
+		BaseToolbox: tools.NewBaseToolbox("notes", "Keep a scratchpad of notes during the session"),
+	}
+	tb.AddTool(note_write.Definition())
+	tb.AddTool(note_read.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
@@ -0,0 +1,209 @@
+// Package plugins registers tools implemented as executables in ~/.magikarp/tools.
+// Each plugin is a directory holding a tool.json manifest, in the same format as the
+// built-in tools, and the executable it names. The executable receives the tool input
+// as a JSON object on stdin and writes a JSON result to stdout.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+// DirName is the plugin directory inside ~/.magikarp
+const DirName = "tools"
+
+const (
+	defaultTimeout = 60 // seconds
+	maxTimeout     = 1800
+	// maxStderr bounds the stderr quoted in error results
+	maxStderr = 2000
+)
+
+// nameRe matches the tool names accepted by the model APIs
+var nameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Manifest is the tool.json of a plugin
+type Manifest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+	// Command is the executable relative to the plugin directory; it defaults to a
+	// file named like the directory
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	// Timeout in seconds; defaults to 60
+	Timeout int `json:"timeout"`
+}
+
+// output is what a plugin writes to stdout
+type output struct {
+	Content *string `json:"content"`
+	IsError bool    `json:"is_error"`
+}
+
+// Dir returns the plugin directory (~/.magikarp/tools)
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".magikarp", DirName), nil
+}
+
+// Discover loads the plugins in dir, sorted by name. Plugins that cannot be loaded
+// are skipped and reported in the returned errors; a missing dir is not an error.
+func Discover(dir string) ([]providers.ToolDefinition, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+
+	var defs []providers.ToolDefinition
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		def, err := load(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", entry.Name(), err))
+			continue
+		}
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, errs
+}
+
+// ReadManifest reads and validates a tool manifest
+func ReadManifest(path string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	switch {
+	case !nameRe.MatchString(m.Name):
+		return m, fmt.Errorf("%s: name %q must be 1-64 letters, digits, '_' or '-'", filepath.Base(path), m.Name)
+	case strings.TrimSpace(m.Description) == "":
+		return m, fmt.Errorf("%s: description is required", filepath.Base(path))
+	case m.InputSchema == nil:
+		return m, fmt.Errorf("%s: input_schema is required", filepath.Base(path))
+	}
+	return m, nil
+}
+
+// load reads the manifest of the plugin in dir and checks its executable
+func load(dir string) (providers.ToolDefinition, error) {
+	m, err := ReadManifest(filepath.Join(dir, "tool.json"))
+	if err != nil {
+		return providers.ToolDefinition{}, err
+	}
+
+	command := m.Command
+	if command == "" {
+		command = filepath.Base(dir)
+	}
+	if !filepath.IsLocal(command) {
+		return providers.ToolDefinition{}, fmt.Errorf("tool.json: command %q must be inside the plugin directory", command)
+	}
+	path := filepath.Join(dir, command)
+	info, err := os.Stat(path)
+	if err != nil {
+		return providers.ToolDefinition{}, fmt.Errorf("executable: %w", err)
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return providers.ToolDefinition{}, fmt.Errorf("%s is not executable", path)
+	}
+
+	timeout := defaultTimeout
+	if m.Timeout > 0 {
+		timeout = min(m.Timeout, maxTimeout)
+	}
+	p := plugin{name: m.Name, dir: dir, path: path, args: m.Args, timeout: time.Duration(timeout) * time.Second}
+	return providers.ToolDefinition{
+		Name:        m.Name,
+		Description: m.Description,
+		InputSchema: m.InputSchema,
+		Function:    p.run,
+	}, nil
+}
+
+// plugin runs the executable of a loaded plugin
+type plugin struct {
+	name    string
+	dir     string
+	path    string
+	args    []string
+	timeout time.Duration
+}
+
+func (p plugin) run(ctx context.Context, input map[string]any) (*providers.ToolResult, error) {
+	if input == nil {
+		input = map[string]any{}
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return providers.NewToolResult(p.name, fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(execCtx, p.path, p.args...)
+	// Plugins run in the working directory so relative paths mean the same as for built-in tools
+	cmd.Env = append(os.Environ(), "MAGIKARP_PLUGIN_DIR="+p.dir)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.WaitDelay = 5 * time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	runErr := cmd.Run()
+	if execCtx.Err() == context.DeadlineExceeded {
+		return providers.NewToolResult(p.name, fmt.Sprintf("Plugin timed out after %s", p.timeout), true), nil
+	}
+
+	return Result(p.name, stdout.Bytes(), stderr.Bytes(), runErr), nil
+}
+
+// Result converts what a plugin wrote into a tool result. Stdout holds either a JSON
+// object with content and is_error or plain text; runErr marks the result as an error.
+func Result(name string, stdout, stderr []byte, runErr error) *providers.ToolResult {
+	var out output
+	if err := json.Unmarshal(bytes.TrimSpace(stdout), &out); err == nil && out.Content != nil {
+		return providers.NewToolResult(name, *out.Content, out.IsError || runErr != nil)
+	}
+
+	// Anything else is reported as plain text
+	content := strings.TrimSpace(string(stdout))
+	if runErr != nil {
+		msg := fmt.Sprintf("Tool failed: %v", runErr)
+		if errText := strings.TrimSpace(string(stderr)); errText != "" {
+			if len(errText) > maxStderr {
+				errText = errText[len(errText)-maxStderr:]
+			}
+			msg += "\n" + errText
+		}
+		if content != "" {
+			msg += "\n" + content
+		}
+		return providers.NewToolResult(name, msg, true)
+	}
+	return providers.NewToolResult(name, content, false)
+}
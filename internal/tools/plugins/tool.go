@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+type pluginToolbox struct {
+	*tools.BaseToolbox
+}
+
+// New returns a toolbox with the plugins found in ~/.magikarp/tools. Plugins that
+// cannot be loaded or whose names are taken by other tools are reported on stderr.
+func New() tools.Toolbox {
+	tb := &pluginToolbox{
+		BaseToolbox: tools.NewBaseToolbox("plugins", "User-provided tools from ~/.magikarp/tools"),
+	}
+	dir, err := Dir()
+	if err != nil {
+		return tb
+	}
+	defs, errs := Discover(dir)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	seen := map[string]bool{}
+	for _, def := range defs {
+		if _, taken := tools.GetToolByName(def.Name); taken || seen[def.Name] {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s: a tool with this name already exists\n", def.Name)
+			continue
+		}
+		seen[def.Name] = true
+		tb.AddTool(def)
+	}
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
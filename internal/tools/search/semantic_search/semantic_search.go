@@ -0,0 +1,73 @@
+package semantic_search
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+//go:embed tool.json
+var wrapper []byte
+
+type input struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// Definition returns the tool definition for semantic_search
+func Definition() providers.ToolDefinition {
+	var sch map[string]any
+	if err := json.Unmarshal(wrapper, &sch); err != nil {
+		fmt.Printf("Error unmarshaling semantic_search schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]any),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, inMap map[string]any) (*providers.ToolResult, error) {
+	var in input
+	inputBytes, err := json.Marshal(inMap)
+	if err != nil {
+		return providers.NewToolResult("semantic_search", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	if err := json.Unmarshal(inputBytes, &in); err != nil {
+		return providers.NewToolResult("semantic_search", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	if strings.TrimSpace(in.Query) == "" {
+		return providers.NewToolResult("semantic_search", "query is required", true), nil
+	}
+	if in.Limit <= 0 {
+		in.Limit = 5
+	}
+	in.Limit = min(in.Limit, 20)
+
+	ix := index.Default()
+	if ix == nil {
+		return providers.NewToolResult("semantic_search", "The project index is not configured (set index.provider in config.yaml)", true), nil
+	}
+	results, err := ix.Search(ctx, in.Query, in.Limit)
+	if err != nil {
+		return providers.NewToolResult("semantic_search", err.Error(), true), nil
+	}
+	if len(results) == 0 {
+		return providers.NewToolResult("semantic_search", "The index has no files", false), nil
+	}
+
+	var sb strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "%s (%s, score %.2f)\n%s", r.Path, r.Location, r.Score, r.Text)
+	}
+	return providers.NewToolResult("semantic_search", sb.String(), false), nil
+}
@@ -0,0 +1,24 @@
+package search
+
+import (
+	"github.com/pprunty/magikarp/internal/index"
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/search/semantic_search"
+)
+
+type searchToolbox struct {
+	*tools.BaseToolbox
+}
+
+// Register adds the "search" toolbox when the project index is configured. It must run
+// after index.Configure and before tools.Configure.
+func Register() {
+	if index.Default() == nil {
+		return
+	}
+	tb := &searchToolbox{
+		BaseToolbox: tools.NewBaseToolbox("search", "Search the project's files by meaning"),
+	}
+	tb.AddTool(semantic_search.Definition())
+	tools.Register(tb)
+}
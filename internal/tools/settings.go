@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/providers"
+)
+
+var (
+	settingsMu sync.RWMutex
+	settings   map[string]config.ToolSettings
+	bash       config.BashConfig
+	// slots holds a semaphore for every tool with a concurrency limit
+	slots map[string]chan struct{}
+)
+
+// Configure applies the tools.settings section of config.yaml. Tool definitions
+// returned afterwards leave out disabled tools and enforce the limits.
+func Configure(conf config.ToolsConfig) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	settings = conf.Settings
+	bash = conf.Bash
+	slots = map[string]chan struct{}{}
+	for _, tb := range registry {
+		for _, t := range tb.Tools() {
+			if s := resolve(tb, t.Name); s.MaxConcurrent > 0 {
+				slots[t.Name] = make(chan struct{}, s.MaxConcurrent)
+			}
+		}
+	}
+}
+
+// Bash returns the tools.bash section of config.yaml
+func Bash() config.BashConfig {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return bash
+}
+
+// resolve merges the settings for a tool from "*", its toolbox and its own entry;
+// settingsMu must be held
+func resolve(tb Toolbox, tool string) config.ToolSettings {
+	var out config.ToolSettings
+	for _, key := range []string{"*", tb.Name(), tool} {
+		s, ok := settings[key]
+		if !ok {
+			continue
+		}
+		if s.Enabled != nil {
+			out.Enabled = s.Enabled
+		}
+		if s.Timeout > 0 {
+			out.Timeout = s.Timeout
+		}
+		if s.MaxOutputBytes > 0 {
+			out.MaxOutputBytes = s.MaxOutputBytes
+		}
+		if s.MaxConcurrent > 0 {
+			out.MaxConcurrent = s.MaxConcurrent
+		}
+	}
+	return out
+}
+
+// configured returns the tools of a toolbox that are enabled, wrapped so that their
+// settings are enforced
+func configured(tb Toolbox) []providers.ToolDefinition {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	var out []providers.ToolDefinition
+	for _, t := range tb.Tools() {
+		s := resolve(tb, t.Name)
+		if s.Enabled != nil && !*s.Enabled {
+			continue
+		}
+		out = append(out, limit(t, s, slots[t.Name]))
+	}
+	return out
+}
+
+// limit wraps the function of a tool to apply its timeout, output size and
+// concurrency limits; sem is nil when concurrency is not limited
+func limit(def providers.ToolDefinition, s config.ToolSettings, sem chan struct{}) providers.ToolDefinition {
+	if s.Timeout <= 0 && s.MaxOutputBytes <= 0 && sem == nil {
+		return def
+	}
+	name, run := def.Name, def.Function
+	def.Function = func(ctx context.Context, input map[string]interface{}) (*providers.ToolResult, error) {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return providers.NewToolResult(name, "Cancelled while waiting for another call of this tool to finish", true), nil
+			}
+		}
+		if s.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+		}
+
+		type outcome struct {
+			res *providers.ToolResult
+			err error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			// The slot is held until the call really returns, even after a timeout
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			res, err := run(ctx, input)
+			done <- outcome{res, err}
+		}()
+
+		var o outcome
+		select {
+		case o = <-done:
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return providers.NewToolResult(name, fmt.Sprintf("Tool timed out after %s (tools.settings timeout)", s.Timeout), true), nil
+			}
+			return providers.NewToolResult(name, "Tool call cancelled", true), nil
+		}
+		if o.err == nil && o.res != nil && s.MaxOutputBytes > 0 {
+			o.res.Content = truncate(o.res.Content, s.MaxOutputBytes)
+		}
+		return o.res, o.err
+	}
+	return def
+}
+
+// truncate shortens content to at most maxBytes, cutting at a line break when one
+// is close, and says how much was left out
+func truncate(content string, maxBytes int) string {
+	if len(content) <= maxBytes {
+		return content
+	}
+	cut := maxBytes
+	if i := strings.LastIndexByte(content[:cut], '\n'); i > maxBytes*3/4 {
+		cut = i
+	}
+	kept := strings.ToValidUTF8(content[:cut], "")
+	return kept + fmt.Sprintf("\n… output truncated: %d of %d bytes shown (tools.settings max_output_bytes)", len(kept), len(content))
+}
@@ -0,0 +1,22 @@
+package tasks
+
+import (
+	"github.com/pprunty/magikarp/internal/tools"
+	"github.com/pprunty/magikarp/internal/tools/tasks/update_tasks"
+)
+
+type tasksToolbox struct {
+	*tools.BaseToolbox
+}
+
+func New() tools.Toolbox {
+	tb := &tasksToolbox{
+		BaseToolbox: tools.NewBaseToolbox("tasks", "Keep a checklist for the current objective"),
+	}
+	tb.AddTool(update_tasks.Definition())
+	return tb
+}
+
+func init() {
+	tools.Register(New())
+}
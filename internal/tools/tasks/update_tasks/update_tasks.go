@@ -0,0 +1,64 @@
+package update_tasks
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tasks"
+)
+
+//go:embed tool.json
+var schema []byte
+
+type input struct {
+	// Tasks is nil when the checklist is only read
+	Tasks *[]struct {
+		Title  string `json:"title"`
+		Status string `json:"status,omitempty"`
+	} `json:"tasks,omitempty"`
+}
+
+// Definition returns the tool definition for update_tasks
+func Definition() providers.ToolDefinition {
+	var sch map[string]interface{}
+	if err := json.Unmarshal(schema, &sch); err != nil {
+		fmt.Printf("Error unmarshaling update_tasks schema: %v\n", err)
+	}
+	return providers.ToolDefinition{
+		Name:        sch["name"].(string),
+		Description: sch["description"].(string),
+		InputSchema: sch["input_schema"].(map[string]interface{}),
+		Function:    run,
+	}
+}
+
+func run(ctx context.Context, data map[string]interface{}) (*providers.ToolResult, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return providers.NewToolResult("update_tasks", fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+	var in input
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return providers.NewToolResult("update_tasks", fmt.Sprintf("Error parsing input parameters: %v", err), true), nil
+	}
+	if in.Tasks == nil {
+		return providers.NewToolResult("update_tasks", tasks.Format(tasks.List()), false), nil
+	}
+
+	list := make([]tasks.Task, 0, len(*in.Tasks))
+	for i, t := range *in.Tasks {
+		status, err := tasks.ParseStatus(t.Status)
+		if err != nil {
+			return providers.NewToolResult("update_tasks", fmt.Sprintf("task %d: %v", i+1, err), true), nil
+		}
+		list = append(list, tasks.Task{Title: t.Title, Status: status})
+	}
+	if err := tasks.Set(list); err != nil {
+		return providers.NewToolResult("update_tasks", err.Error(), true), nil
+	}
+	done, total := tasks.Counts()
+	return providers.NewToolResult("update_tasks", fmt.Sprintf("Checklist updated (%d of %d done):\n%s", done, total, tasks.Format(list)), false), nil
+}
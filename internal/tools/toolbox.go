@@ -36,30 +36,30 @@ var registry []Toolbox
 // Register adds a toolbox to the global registry.
 func Register(tb Toolbox) { registry = append(registry, tb) }
 
-// GetAllTools returns every tool definition registered across all toolboxes.
+// GetAllTools returns every enabled tool definition registered across all toolboxes.
 func GetAllTools() []providers.ToolDefinition {
 	var out []providers.ToolDefinition
 	for _, tb := range registry {
-		out = append(out, tb.Tools()...)
+		out = append(out, configured(tb)...)
 	}
 	return out
 }
 
-// GetCoreTools returns tool definitions from the toolbox named "core".
+// GetCoreTools returns the enabled tool definitions from the toolbox named "core".
 func GetCoreTools() []providers.ToolDefinition {
 	var out []providers.ToolDefinition
 	for _, tb := range registry {
 		if tb.Name() == "core" {
-			out = append(out, tb.Tools()...)
+			out = append(out, configured(tb)...)
 		}
 	}
 	return out
 }
 
-// GetToolByName finds a tool by name.
+// GetToolByName finds an enabled tool by name.
 func GetToolByName(name string) (providers.ToolDefinition, bool) {
 	for _, tb := range registry {
-		for _, t := range tb.Tools() {
+		for _, t := range configured(tb) {
 			if t.Name == name {
 				return t, true
 			}
@@ -0,0 +1,37 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/tools"
+)
+
+type wasmToolbox struct {
+	*tools.BaseToolbox
+}
+
+// Register adds the WASM tools declared in tools.wasm of config.yaml as the "wasm"
+// toolbox. It must run before tools.Configure so that tool settings apply to them.
+func Register(conf config.ToolsConfig) error {
+	if len(conf.Wasm) == 0 {
+		return nil
+	}
+	tb := &wasmToolbox{
+		BaseToolbox: tools.NewBaseToolbox("wasm", "Sandboxed tools compiled to WebAssembly"),
+	}
+	seen := map[string]bool{}
+	for i, w := range conf.Wasm {
+		def, err := Load(w)
+		if err != nil {
+			return fmt.Errorf("tools.wasm[%d]: %w", i, err)
+		}
+		if _, taken := tools.GetToolByName(def.Name); taken || seen[def.Name] {
+			return fmt.Errorf("tools.wasm[%d]: a tool named %s already exists", i, def.Name)
+		}
+		seen[def.Name] = true
+		tb.AddTool(def)
+	}
+	tools.Register(tb)
+	return nil
+}
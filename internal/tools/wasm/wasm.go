@@ -0,0 +1,205 @@
+// Package wasm runs tools compiled to WebAssembly (WASI) with wazero. Modules get no
+// network access and see only the directories they are configured to mount.
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/config"
+	"github.com/pprunty/magikarp/internal/providers"
+	"github.com/pprunty/magikarp/internal/tools/plugins"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	// memoryLimitPages caps module memory at 256 MiB (64 KiB pages)
+	memoryLimitPages = 4096
+)
+
+var (
+	runtimeOnce sync.Once
+	runtime     wazero.Runtime
+	runtimeErr  error
+)
+
+// sharedRuntime returns the runtime all modules are compiled and run in
+func sharedRuntime() (wazero.Runtime, error) {
+	runtimeOnce.Do(func() {
+		ctx := context.Background()
+		rc := wazero.NewRuntimeConfig().
+			WithCloseOnContextDone(true).
+			WithMemoryLimitPages(memoryLimitPages)
+		// Compiled modules are cached on disk so later sessions start them quickly
+		if home, err := os.UserHomeDir(); err == nil {
+			if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(home, ".magikarp", "cache", "wasm")); err == nil {
+				rc = rc.WithCompilationCache(cache)
+			}
+		}
+		runtime = wazero.NewRuntimeWithConfig(ctx, rc)
+		_, runtimeErr = wasi_snapshot_preview1.Instantiate(ctx, runtime)
+	})
+	return runtime, runtimeErr
+}
+
+// tool is a configured WASM tool; the module is compiled on first use
+type tool struct {
+	name     string
+	path     string
+	mounts   []string
+	writable bool
+	env      map[string]string
+	timeout  time.Duration
+	workDir  string
+
+	compileOnce sync.Once
+	compiled    wazero.CompiledModule
+	compileErr  error
+}
+
+// Load reads the manifest of a configured module and returns its tool definition
+func Load(conf config.WasmTool) (providers.ToolDefinition, error) {
+	if conf.Module == "" {
+		return providers.ToolDefinition{}, errors.New("module is required")
+	}
+	path := expandHome(conf.Module)
+	if _, err := os.Stat(path); err != nil {
+		return providers.ToolDefinition{}, err
+	}
+	manifestPath := expandHome(conf.Manifest)
+	if manifestPath == "" {
+		manifestPath = strings.TrimSuffix(path, filepath.Ext(path)) + ".json"
+	}
+	m, err := plugins.ReadManifest(manifestPath)
+	if err != nil {
+		return providers.ToolDefinition{}, err
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		return providers.ToolDefinition{}, err
+	}
+	t := &tool{name: m.Name, path: path, writable: conf.Writable, env: conf.Env, timeout: conf.Timeout, workDir: workDir}
+	if t.timeout <= 0 {
+		t.timeout = defaultTimeout
+	}
+	for _, mount := range conf.Mounts {
+		abs, err := filepath.Abs(expandHome(mount))
+		if err != nil {
+			return providers.ToolDefinition{}, err
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return providers.ToolDefinition{}, fmt.Errorf("mount %s is not a directory", mount)
+		}
+		t.mounts = append(t.mounts, abs)
+	}
+
+	return providers.ToolDefinition{
+		Name:        m.Name,
+		Description: m.Description,
+		InputSchema: m.InputSchema,
+		Function:    t.run,
+	}, nil
+}
+
+// compile compiles the module once
+func (t *tool) compile() (wazero.CompiledModule, error) {
+	t.compileOnce.Do(func() {
+		var r wazero.Runtime
+		if r, t.compileErr = sharedRuntime(); t.compileErr != nil {
+			return
+		}
+		var code []byte
+		if code, t.compileErr = os.ReadFile(t.path); t.compileErr != nil {
+			return
+		}
+		t.compiled, t.compileErr = r.CompileModule(context.Background(), code)
+	})
+	return t.compiled, t.compileErr
+}
+
+func (t *tool) run(ctx context.Context, input map[string]any) (*providers.ToolResult, error) {
+	compiled, err := t.compile()
+	if err != nil {
+		return providers.NewToolResult(t.name, fmt.Sprintf("Error loading %s: %v", t.path, err), true), nil
+	}
+	r, err := sharedRuntime()
+	if err != nil {
+		return providers.NewToolResult(t.name, fmt.Sprintf("Error starting the WASM runtime: %v", err), true), nil
+	}
+	if input == nil {
+		input = map[string]any{}
+	}
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return providers.NewToolResult(t.name, fmt.Sprintf("Error processing input parameters: %v", err), true), nil
+	}
+
+	fsConfig := wazero.NewFSConfig()
+	for _, mount := range t.mounts {
+		if t.writable {
+			fsConfig = fsConfig.WithDirMount(mount, mount)
+		} else {
+			fsConfig = fsConfig.WithReadOnlyDirMount(mount, mount)
+		}
+	}
+	execCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	modConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(t.name).
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(&stdout).
+		WithStderr(&stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		// Sleeping modules must still be stopped at the timeout
+		WithNanosleep(func(ns int64) {
+			select {
+			case <-time.After(time.Duration(ns)):
+			case <-execCtx.Done():
+			}
+		}).
+		// Relative paths resolve against the working directory, as for built-in tools
+		WithEnv("PWD", t.workDir)
+	for k, v := range t.env {
+		modConfig = modConfig.WithEnv(k, v)
+	}
+
+	mod, runErr := r.InstantiateModule(execCtx, compiled, modConfig)
+	if mod != nil {
+		_ = mod.Close(context.Background())
+	}
+	if execCtx.Err() == context.DeadlineExceeded {
+		return providers.NewToolResult(t.name, fmt.Sprintf("WASM tool timed out after %s", t.timeout), true), nil
+	}
+	var exitErr *sys.ExitError
+	if errors.As(runErr, &exitErr) && exitErr.ExitCode() == 0 {
+		runErr = nil
+	}
+	return plugins.Result(t.name, stdout.Bytes(), stderr.Bytes(), runErr), nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,145 @@
+// Package worktree runs agent work in a temporary git worktree on a branch of its own,
+// so that edits reach the user's checkout only once they are reviewed and applied.
+package worktree
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pprunty/magikarp/internal/tools/git/gitexec"
+)
+
+// BranchPrefix starts the names of the branches of isolated runs
+const BranchPrefix = "magikarp/isolated-"
+
+// Worktree is a temporary worktree checked out from the user's HEAD
+type Worktree struct {
+	// Path is the root of the worktree
+	Path string
+	// Branch holds the isolated run's commits
+	Branch string
+	// Base is the commit the branch started from
+	Base string
+	// Origin is the root of the user's checkout
+	Origin string
+}
+
+// Dir returns the directory of the worktree where the user's dir is in their checkout,
+// so that an isolated run starts in the same subdirectory
+func (w *Worktree) Dir(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return w.Path
+	}
+	rel, err := filepath.Rel(w.Origin, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return w.Path
+	}
+	return filepath.Join(w.Path, rel)
+}
+
+// Root returns the directory holding the worktrees of isolated runs
+func Root() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".magikarp", "worktrees"), nil
+}
+
+// Create adds a worktree on a new branch from HEAD of the repository containing dir.
+// Uncommitted changes in the checkout are not carried over.
+func Create(ctx context.Context, dir string) (*Worktree, error) {
+	origin, err := gitexec.Run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("isolated runs need a git repository: %w", err)
+	}
+	w := &Worktree{Origin: strings.TrimSpace(origin)}
+	base, err := gitexec.Run(ctx, w.Origin, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("isolated runs need at least one commit: %w", err)
+	}
+	w.Base = strings.TrimSpace(base)
+
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	stamp := time.Now().Format("20060102-150405")
+	w.Branch = BranchPrefix + stamp
+	w.Path = filepath.Join(root, filepath.Base(w.Origin)+"-"+stamp)
+	if _, err := gitexec.Run(ctx, w.Origin, "worktree", "add", "-b", w.Branch, w.Path, w.Base); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Commit records every change in the worktree, including new files, on its branch. It
+// reports whether there was anything to commit.
+func (w *Worktree) Commit(ctx context.Context, message string) (bool, error) {
+	status, err := gitexec.Run(ctx, w.Path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(status) == "" {
+		return false, nil
+	}
+	if _, err := gitexec.Run(ctx, w.Path, "add", "-A"); err != nil {
+		return false, err
+	}
+	// The branch is scratch space: skip the repository's hooks and do not depend on a
+	// configured identity
+	_, err = gitexec.Run(ctx, w.Path, "-c", "user.name=magikarp", "-c", "user.email=magikarp@localhost",
+		"commit", "--no-verify", "-q", "-m", message)
+	return err == nil, err
+}
+
+// Diff commits pending changes and returns the combined diff of the run against its
+// base, and its summary (git diff --stat)
+func (w *Worktree) Diff(ctx context.Context) (diff, stat string, err error) {
+	if _, err := w.Commit(ctx, "magikarp: isolated run"); err != nil {
+		return "", "", err
+	}
+	if stat, err = gitexec.Run(ctx, w.Path, "diff", "--stat", w.Base, "HEAD"); err != nil {
+		return "", "", err
+	}
+	if diff, err = gitexec.Run(ctx, w.Path, "diff", w.Base, "HEAD"); err != nil {
+		return "", "", err
+	}
+	return diff, stat, nil
+}
+
+// Apply applies the combined diff of the run to the working tree of the user's
+// checkout, leaving it uncommitted. Nothing is changed when the diff does not apply.
+func (w *Worktree) Apply(ctx context.Context) error {
+	if _, err := w.Commit(ctx, "magikarp: isolated run"); err != nil {
+		return err
+	}
+	patch, err := gitexec.Run(ctx, w.Path, "diff", "--binary", w.Base, "HEAD")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(patch) == "" {
+		return nil
+	}
+	if _, err := gitexec.RunWithStdin(ctx, w.Origin, patch, "apply", "--binary", "--whitespace=nowarn"); err != nil {
+		return fmt.Errorf("%w; merge branch %s by hand instead", err, w.Branch)
+	}
+	return nil
+}
+
+// Remove deletes the worktree and its branch
+func (w *Worktree) Remove(ctx context.Context) error {
+	if _, err := gitexec.Run(ctx, w.Origin, "worktree", "remove", "--force", w.Path); err != nil {
+		return err
+	}
+	_, err := gitexec.Run(ctx, w.Origin, "branch", "-D", w.Branch)
+	return err
+}
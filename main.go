@@ -2,9 +2,19 @@ package main
 
 import (
 	"github.com/pprunty/magikarp/cmd"
+	_ "github.com/pprunty/magikarp/internal/tools/agent"
+	_ "github.com/pprunty/magikarp/internal/tools/clipboard"
+	_ "github.com/pprunty/magikarp/internal/tools/code"
 	_ "github.com/pprunty/magikarp/internal/tools/core"
+	_ "github.com/pprunty/magikarp/internal/tools/docker"
 	_ "github.com/pprunty/magikarp/internal/tools/exec"
 	_ "github.com/pprunty/magikarp/internal/tools/filesystem"
+	_ "github.com/pprunty/magikarp/internal/tools/git"
+	_ "github.com/pprunty/magikarp/internal/tools/github"
+	_ "github.com/pprunty/magikarp/internal/tools/notes"
+	_ "github.com/pprunty/magikarp/internal/tools/tasks"
+	// Plugins are registered last so they cannot replace built-in tools
+	_ "github.com/pprunty/magikarp/internal/tools/plugins"
 )
 
 func main() {